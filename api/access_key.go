@@ -22,6 +22,7 @@ type ListAccessKeysRequest struct {
 	Name        string `form:"name" note:"Name of the user" example:"john@example.com"`
 	ShowExpired bool   `form:"showExpired" note:"Whether to show expired access keys. Defaults to false" example:"true"`
 	PaginationRequest
+	CursorPaginationRequest
 }
 
 func (r ListAccessKeysRequest) ValidationRules() []validate.ValidationRule {
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type AccessRequest struct {
+	ID      uid.ID `json:"id" note:"ID of the access request" example:"4yJ3n3D8E2"`
+	Created Time   `json:"created"`
+	Updated Time   `json:"updated"`
+
+	User      uid.ID   `json:"user" note:"ID of the user who requested access" example:"6hNnjfjVcc"`
+	Privilege string   `json:"privilege" note:"a role or permission" example:"cluster-admin"`
+	Resource  string   `json:"resource" note:"a resource name in Infra's Universal Resource Notation" example:"production"`
+	Duration  Duration `json:"duration" note:"how long the grant will be valid once the request is approved"`
+	Reason    string   `json:"reason" note:"justification for the request" example:"investigating incident INC-123"`
+
+	Status         string `json:"status" note:"one of pending, approved, or denied" example:"pending"`
+	DecidedBy      uid.ID `json:"decidedBy,omitempty" note:"ID of the user who approved or denied the request"`
+	Decided        Time   `json:"decided" note:"time the request was approved or denied"`
+	DecisionReason string `json:"decisionReason" note:"reason given by the user who approved or denied the request"`
+	Grant          uid.ID `json:"grant,omitempty" note:"ID of the grant created when the request was approved"`
+}
+
+type ListAccessRequestsRequest struct {
+	UserID uid.ID `form:"userID" note:"ID of the user who requested access"`
+	Status string `form:"status" note:"Status of the request" example:"pending"`
+	PaginationRequest
+}
+
+func (r ListAccessRequestsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Enum("status", r.Status, []string{"pending", "approved", "denied"}),
+	}
+}
+
+func (r ListAccessRequestsRequest) SetPage(page int) Paginatable {
+	r.PaginationRequest.Page = page
+	return r
+}
+
+type CreateAccessRequestRequest struct {
+	Privilege string   `json:"privilege" example:"cluster-admin" note:"a role or permission"`
+	Resource  string   `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation"`
+	Duration  Duration `json:"duration" note:"how long the grant should be valid once the request is approved"`
+	Reason    string   `json:"reason" example:"investigating incident INC-123" note:"justification for the request"`
+}
+
+func (r CreateAccessRequestRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("privilege", r.Privilege),
+		validate.Required("resource", r.Resource),
+		validate.Required("duration", r.Duration),
+		validate.Required("reason", r.Reason),
+		validate.ValidatorFunc(func() *validate.Failure {
+			if r.Duration < 0 {
+				return validate.Fail("duration", "must be a positive duration")
+			}
+			return nil
+		}),
+	}
+}
+
+// DecideAccessRequestRequest is used to approve or deny an access request.
+type DecideAccessRequestRequest struct {
+	ID     uid.ID `uri:"id" json:"-"`
+	Reason string `json:"reason" note:"reason for approving or denying the request"`
+}
+
+func (r DecideAccessRequestRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
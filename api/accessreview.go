@@ -0,0 +1,119 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type AccessReview struct {
+	ID      uid.ID `json:"id" note:"ID of the access review" example:"4yJ3n3D8E2"`
+	Created Time   `json:"created"`
+	Updated Time   `json:"updated"`
+
+	Name         string   `json:"name" note:"name of the access review" example:"2022 Q4 production access"`
+	Destinations []string `json:"destinations" note:"the review includes grants on these destinations" example:"[\"production\"]"`
+	Groups       []uid.ID `json:"groups" note:"the review includes grants of these groups, the groups nested in them, and their members"`
+
+	Status    string `json:"status" note:"one of open or closed" example:"open"`
+	CreatedBy uid.ID `json:"createdBy" note:"ID of the user who started the review"`
+	ClosedBy  uid.ID `json:"closedBy,omitempty" note:"ID of the user who closed the review"`
+	Closed    Time   `json:"closed" note:"time the review was closed"`
+}
+
+type AccessReviewItem struct {
+	ID           uid.ID `json:"id" note:"ID of the review item" example:"5Ldp2Dtcrw"`
+	AccessReview uid.ID `json:"accessReview" note:"ID of the access review"`
+	Grant        uid.ID `json:"grant" note:"ID of the grant being reviewed"`
+
+	User      uid.ID `json:"user,omitempty" note:"ID of the user granted access" example:"6hNnjfjVcc"`
+	Group     uid.ID `json:"group,omitempty" note:"ID of the group granted access" example:"3zMaadcd2U"`
+	Privilege string `json:"privilege" note:"a role or permission" example:"cluster-admin"`
+	Resource  string `json:"resource" note:"a resource name in Infra's Universal Resource Notation" example:"production"`
+
+	Reviewer  uid.ID `json:"reviewer" note:"ID of the user assigned to review the grant"`
+	Decision  string `json:"decision" note:"one of pending, keep, revoke, or unreviewed" example:"pending"`
+	DecidedBy uid.ID `json:"decidedBy,omitempty" note:"ID of the user who decided to keep or revoke the grant"`
+	Decided   Time   `json:"decided" note:"time the decision was made"`
+	Reason    string `json:"reason" note:"reason given for the decision"`
+}
+
+type ListAccessReviewsRequest struct {
+	Status string `form:"status" note:"Status of the review" example:"open"`
+	PaginationRequest
+}
+
+func (r ListAccessReviewsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Enum("status", r.Status, []string{"open", "closed"}),
+	}
+}
+
+func (r ListAccessReviewsRequest) SetPage(page int) Paginatable {
+	r.PaginationRequest.Page = page
+	return r
+}
+
+type CreateAccessReviewRequest struct {
+	Name         string   `json:"name" example:"2022 Q4 production access" note:"name of the access review"`
+	Destinations []string `json:"destinations" example:"[\"production\"]" note:"review the grants on these destinations"`
+	Groups       []uid.ID `json:"groups" note:"review the grants of these groups, the groups nested in them, and their members. When destinations are also set, only the grants on those destinations are reviewed"`
+	Reviewer     uid.ID   `json:"reviewer" note:"ID of the user assigned to review the grants, defaults to the user who starts the review"`
+}
+
+func (r CreateAccessReviewRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("name", r.Name),
+		validate.RequireAnyOf(
+			validate.Field{Name: "destinations", Value: r.Destinations},
+			validate.Field{Name: "groups", Value: r.Groups},
+		),
+	}
+}
+
+type ListAccessReviewItemsRequest struct {
+	ID       uid.ID `uri:"id"`
+	Reviewer uid.ID `form:"reviewer" note:"ID of the user assigned to review the grants"`
+	Decision string `form:"decision" note:"Decision of the review items" example:"pending"`
+	PaginationRequest
+}
+
+func (r ListAccessReviewItemsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Enum("decision", r.Decision, []string{"pending", "keep", "revoke", "unreviewed"}),
+	}
+}
+
+func (r ListAccessReviewItemsRequest) SetPage(page int) Paginatable {
+	r.PaginationRequest.Page = page
+	return r
+}
+
+// DecideAccessReviewItemRequest is used to keep or revoke the grant of an
+// access review item.
+type DecideAccessReviewItemRequest struct {
+	ID     uid.ID `uri:"id" json:"-"`
+	Reason string `json:"reason" note:"reason for keeping or revoking the grant"`
+}
+
+func (r DecideAccessReviewItemRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
+
+// AccessReviewEvidence is a record of an access review and all of its items,
+// with the names of the users and groups, for use as evidence of the review.
+type AccessReviewEvidence struct {
+	AccessReview AccessReview               `json:"accessReview"`
+	Items        []AccessReviewEvidenceItem `json:"items"`
+	Exported     Time                       `json:"exported" note:"time the evidence was exported"`
+}
+
+type AccessReviewEvidenceItem struct {
+	AccessReviewItem `json:",inline"`
+
+	Subject       string `json:"subject" note:"name of the user or group granted access" example:"alice@example.com"`
+	ReviewerName  string `json:"reviewerName" note:"name of the user assigned to review the grant" example:"bob@example.com"`
+	DecidedByName string `json:"decidedByName,omitempty" note:"name of the user who decided to keep or revoke the grant"`
+}
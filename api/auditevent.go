@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/infrahq/infra/uid"
+)
+
+type AuditEvent struct {
+	ID      uid.ID `json:"id" note:"ID of the audit event" example:"4yJ3n3D8E2"`
+	Created Time   `json:"created"`
+
+	Actor      uid.ID `json:"actor" note:"ID of the user who performed the action" example:"6hNnjfjVcc"`
+	ActorName  string `json:"actorName" note:"name of the user who performed the action" example:"admin@example.com"`
+	Action     string `json:"action" note:"name of the API operation" example:"CreateGrant"`
+	TargetType string `json:"targetType" note:"kind of resource that was changed" example:"grants"`
+	Target     uid.ID `json:"target,omitempty" note:"ID of the resource that was changed" example:"3w9XyTrkzk"`
+	RequestID  uid.ID `json:"requestID" note:"ID of the API request that caused the event" example:"7Vz8fBbkP4"`
+	Before     string `json:"before" note:"JSON summary of the resource before the change, when available"`
+	After      string `json:"after" note:"JSON summary of the resource after the change"`
+}
+
+type ListAuditEventsRequest struct {
+	Actor      uid.ID `form:"actor" note:"ID of the user who performed the action"`
+	Action     string `form:"action" note:"Name of the API operation" example:"CreateGrant"`
+	TargetType string `form:"targetType" note:"Kind of resource that was changed" example:"grants"`
+	Target     uid.ID `form:"target" note:"ID of the resource that was changed"`
+	PaginationRequest
+}
+
+func (r ListAuditEventsRequest) SetPage(page int) Paginatable {
+	r.PaginationRequest.Page = page
+	return r
+}
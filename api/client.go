@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -19,7 +20,7 @@ import (
 	"github.com/infrahq/infra/uid"
 )
 
-var apiVersion = "0.18.1"
+var apiVersion = "0.18.2"
 
 var (
 	ErrTimeout            = errors.New("client timed out waiting for response from server")
@@ -236,6 +237,7 @@ func (c Client) ListUsers(ctx context.Context, req ListUsersRequest) (*ListRespo
 	return get[ListResponse[User]](ctx, c, "/api/users", Query{
 		"name": {req.Name}, "group": {req.Group.String()}, "ids": ids,
 		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+		"after":      {req.After},
 		"showSystem": {strconv.FormatBool(req.ShowSystem)},
 	})
 }
@@ -244,6 +246,10 @@ func (c Client) GetUser(ctx context.Context, id uid.ID) (*User, error) {
 	return get[User](ctx, c, fmt.Sprintf("/api/users/%s", id), Query{})
 }
 
+func (c Client) ListUserPermissions(ctx context.Context, id uid.ID) (*ListResponse[Permission], error) {
+	return get[ListResponse[Permission]](ctx, c, fmt.Sprintf("/api/users/%s/permissions", id), Query{})
+}
+
 func (c Client) CreateUser(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
 	return post[CreateUserResponse](ctx, c, "/api/users", req)
 }
@@ -256,6 +262,38 @@ func (c Client) DeleteUser(ctx context.Context, id uid.ID) error {
 	return delete(ctx, c, fmt.Sprintf("/api/users/%s", id), Query{})
 }
 
+func (c Client) StartTOTPEnrollment(ctx context.Context, userID uid.ID) (*StartTOTPEnrollmentResponse, error) {
+	return post[StartTOTPEnrollmentResponse](ctx, c, fmt.Sprintf("/api/users/%s/totp", userID), nil)
+}
+
+func (c Client) ConfirmTOTPEnrollment(ctx context.Context, req *ConfirmTOTPEnrollmentRequest) (*ConfirmTOTPEnrollmentResponse, error) {
+	return put[ConfirmTOTPEnrollmentResponse](ctx, c, fmt.Sprintf("/api/users/%s/totp", req.ID), req)
+}
+
+func (c Client) DisableTOTP(ctx context.Context, userID uid.ID) error {
+	return delete(ctx, c, fmt.Sprintf("/api/users/%s/totp", userID), Query{})
+}
+
+func (c Client) BeginWebAuthnRegistration(ctx context.Context, userID uid.ID) (*BeginWebAuthnRegistrationResponse, error) {
+	return post[BeginWebAuthnRegistrationResponse](ctx, c, fmt.Sprintf("/api/users/%s/webauthn/registration", userID), nil)
+}
+
+func (c Client) FinishWebAuthnRegistration(ctx context.Context, req *FinishWebAuthnRegistrationRequest) (*WebAuthnCredential, error) {
+	return put[WebAuthnCredential](ctx, c, fmt.Sprintf("/api/users/%s/webauthn/registration", req.ID), req)
+}
+
+func (c Client) ListWebAuthnCredentials(ctx context.Context, userID uid.ID) (*ListResponse[WebAuthnCredential], error) {
+	return get[ListResponse[WebAuthnCredential]](ctx, c, fmt.Sprintf("/api/users/%s/webauthn/credentials", userID), Query{})
+}
+
+func (c Client) DeleteWebAuthnCredential(ctx context.Context, req *DeleteWebAuthnCredentialRequest) error {
+	return delete(ctx, c, fmt.Sprintf("/api/users/%s/webauthn/credentials/%s", req.ID, req.CredentialID), Query{})
+}
+
+func (c Client) BeginWebAuthnLogin(ctx context.Context, req *BeginWebAuthnLoginRequest) (*BeginWebAuthnLoginResponse, error) {
+	return post[BeginWebAuthnLoginResponse](ctx, c, "/api/login/webauthn", req)
+}
+
 func (c Client) StartDeviceFlow(ctx context.Context) (*DeviceFlowResponse, error) {
 	return post[DeviceFlowResponse](ctx, c, "/api/device", nil)
 }
@@ -268,6 +306,7 @@ func (c Client) ListGroups(ctx context.Context, req ListGroupsRequest) (*ListRes
 	return get[ListResponse[Group]](ctx, c, "/api/groups", Query{
 		"name": {req.Name}, "userID": {req.UserID.String()},
 		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+		"after": {req.After},
 	})
 }
 
@@ -288,6 +327,36 @@ func (c Client) UpdateUsersInGroup(ctx context.Context, req *UpdateUsersInGroupR
 	return err
 }
 
+func (c Client) UpdateGroupsInGroup(ctx context.Context, req *UpdateGroupsInGroupRequest) error {
+	_, err := patch[EmptyResponse](ctx, c, fmt.Sprintf("/api/groups/%s/groups", req.GroupID), req)
+	return err
+}
+
+// ListNestedGroups returns the groups nested in the group, at any level of
+// nesting.
+func (c Client) ListNestedGroups(ctx context.Context, id uid.ID) (*ListResponse[Group], error) {
+	return get[ListResponse[Group]](ctx, c, fmt.Sprintf("/api/groups/%s/groups", id), Query{})
+}
+
+func (c Client) ListRoles(ctx context.Context, req ListRolesRequest) (*ListResponse[Role], error) {
+	return get[ListResponse[Role]](ctx, c, "/api/roles", Query{
+		"name": {req.Name},
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetRole(ctx context.Context, id uid.ID) (*Role, error) {
+	return get[Role](ctx, c, fmt.Sprintf("/api/roles/%s", id), Query{})
+}
+
+func (c Client) CreateRole(ctx context.Context, req *CreateRoleRequest) (*Role, error) {
+	return post[Role](ctx, c, "/api/roles", req)
+}
+
+func (c Client) DeleteRole(ctx context.Context, id uid.ID) error {
+	return delete(ctx, c, fmt.Sprintf("/api/roles/%s", id), Query{})
+}
+
 func (c Client) ListProviders(ctx context.Context, req ListProvidersRequest) (*ListResponse[Provider], error) {
 	return get[ListResponse[Provider]](ctx, c, "/api/providers", Query{
 		"name": {req.Name},
@@ -335,17 +404,103 @@ func (c Client) DeleteProvider(ctx context.Context, id uid.ID) error {
 
 func (c Client) ListGrants(ctx context.Context, req ListGrantsRequest) (*ListResponse[Grant], error) {
 	return get[ListResponse[Grant]](ctx, c, "/api/grants", Query{
-		"user":            {req.User.String()},
-		"group":           {req.Group.String()},
-		"resource":        {req.Resource},
+		"user":             {req.User.String()},
+		"group":            {req.Group.String()},
+		"resource":         {req.Resource},
+		"destination":      {req.Destination},
+		"privilege":        {req.Privilege},
+		"showInherited":    {strconv.FormatBool(req.ShowInherited)},
+		"showSystem":       {strconv.FormatBool(req.ShowSystem)},
+		"showSubjectNames": {strconv.FormatBool(req.ShowSubjectNames)},
+		"page":             {strconv.Itoa(req.Page)},
+		"limit":            {strconv.Itoa(req.Limit)},
+		"after":            {req.After},
+		"lastUpdateIndex":  {strconv.FormatInt(req.LastUpdateIndex, 10)},
+	})
+}
+
+// WatchGrants calls fn with each GrantEvent for the grants of a destination,
+// until the server ends the stream, ctx is done, or fn returns an error.
+// To resume the stream use the UpdateIndex of the last event as
+// req.LastUpdateIndex.
+func (c Client) WatchGrants(ctx context.Context, req WatchGrantsRequest, fn func(GrantEvent) error) error {
+	httpReq, err := c.buildRequest(ctx, http.MethodGet, "/api/grants/watch", Query{
 		"destination":     {req.Destination},
-		"privilege":       {req.Privilege},
-		"showInherited":   {strconv.FormatBool(req.ShowInherited)},
-		"showSystem":      {strconv.FormatBool(req.ShowSystem)},
-		"page":            {strconv.Itoa(req.Page)},
-		"limit":           {strconv.Itoa(req.Limit)},
 		"lastUpdateIndex": {strconv.FormatInt(req.LastUpdateIndex, 10)},
-	})
+	}, nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	start := time.Now()
+	resp, err := c.HTTP.Do(httpReq)
+
+	if c.ObserveFunc != nil {
+		c.ObserveFunc(start, httpReq, resp, err)
+	}
+
+	if resp != nil && resp.StatusCode == 401 && c.OnUnauthorized != nil {
+		defer c.OnUnauthorized()
+	}
+
+	if err != nil {
+		if connError := HandleConnError(err); connError != nil {
+			return connError
+		}
+		return fmt.Errorf("%s %q: %w", httpReq.Method, httpReq.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("reading response: %w", err)
+		}
+		if err := checkError(resp, body); err != nil {
+			return err
+		}
+	}
+
+	return readGrantEvents(resp.Body, fn)
+}
+
+// readGrantEvents reads server-sent events from r, and calls fn with each
+// event. Only the data field of the event is used, the update index is also
+// included in the data.
+func readGrantEvents(r io.Reader, fn func(GrantEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			// a blank line ends the event
+			if len(data) == 0 {
+				continue
+			}
+			var event GrantEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return fmt.Errorf("parsing grant event: %w. partial text: %q", err, partialText(data, 100))
+			}
+			data = data[:0]
+			if err := fn(event); err != nil {
+				return err
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			line = bytes.TrimPrefix(line[len("data:"):], []byte(" "))
+			data = append(data, line...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s", ErrTimeout, err)
+		}
+		return fmt.Errorf("reading grant events: %w", err)
+	}
+	return nil
 }
 
 func (c Client) GetGrant(ctx context.Context, id uid.ID) (*Grant, error) {
@@ -360,12 +515,117 @@ func (c Client) DeleteGrant(ctx context.Context, id uid.ID) error {
 	return delete(ctx, c, fmt.Sprintf("/api/grants/%s", id), Query{})
 }
 
+func (c Client) ListAccessRequests(ctx context.Context, req ListAccessRequestsRequest) (*ListResponse[AccessRequest], error) {
+	return get[ListResponse[AccessRequest]](ctx, c, "/api/access-requests", Query{
+		"userID": {req.UserID.String()}, "status": {req.Status},
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetAccessRequest(ctx context.Context, id uid.ID) (*AccessRequest, error) {
+	return get[AccessRequest](ctx, c, fmt.Sprintf("/api/access-requests/%s", id), Query{})
+}
+
+func (c Client) CreateAccessRequest(ctx context.Context, req *CreateAccessRequestRequest) (*AccessRequest, error) {
+	return post[AccessRequest](ctx, c, "/api/access-requests", req)
+}
+
+func (c Client) ApproveAccessRequest(ctx context.Context, req *DecideAccessRequestRequest) (*AccessRequest, error) {
+	return post[AccessRequest](ctx, c, fmt.Sprintf("/api/access-requests/%s/approve", req.ID), req)
+}
+
+func (c Client) DenyAccessRequest(ctx context.Context, req *DecideAccessRequestRequest) (*AccessRequest, error) {
+	return post[AccessRequest](ctx, c, fmt.Sprintf("/api/access-requests/%s/deny", req.ID), req)
+}
+
+func (c Client) ListAccessReviews(ctx context.Context, req ListAccessReviewsRequest) (*ListResponse[AccessReview], error) {
+	return get[ListResponse[AccessReview]](ctx, c, "/api/access-reviews", Query{
+		"status": {req.Status},
+		"page":   {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetAccessReview(ctx context.Context, id uid.ID) (*AccessReview, error) {
+	return get[AccessReview](ctx, c, fmt.Sprintf("/api/access-reviews/%s", id), Query{})
+}
+
+func (c Client) CreateAccessReview(ctx context.Context, req *CreateAccessReviewRequest) (*AccessReview, error) {
+	return post[AccessReview](ctx, c, "/api/access-reviews", req)
+}
+
+func (c Client) CloseAccessReview(ctx context.Context, id uid.ID) (*AccessReview, error) {
+	return post[AccessReview](ctx, c, fmt.Sprintf("/api/access-reviews/%s/close", id), &EmptyRequest{})
+}
+
+func (c Client) ListAccessReviewItems(ctx context.Context, req ListAccessReviewItemsRequest) (*ListResponse[AccessReviewItem], error) {
+	return get[ListResponse[AccessReviewItem]](ctx, c, fmt.Sprintf("/api/access-reviews/%s/items", req.ID), Query{
+		"reviewer": {req.Reviewer.String()}, "decision": {req.Decision},
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) ExportAccessReview(ctx context.Context, id uid.ID) (*AccessReviewEvidence, error) {
+	return get[AccessReviewEvidence](ctx, c, fmt.Sprintf("/api/access-reviews/%s/export", id), Query{})
+}
+
+func (c Client) KeepAccessReviewItem(ctx context.Context, req *DecideAccessReviewItemRequest) (*AccessReviewItem, error) {
+	return post[AccessReviewItem](ctx, c, fmt.Sprintf("/api/access-review-items/%s/keep", req.ID), req)
+}
+
+func (c Client) RevokeAccessReviewItem(ctx context.Context, req *DecideAccessReviewItemRequest) (*AccessReviewItem, error) {
+	return post[AccessReviewItem](ctx, c, fmt.Sprintf("/api/access-review-items/%s/revoke", req.ID), req)
+}
+
+func (c Client) ListAuditEvents(ctx context.Context, req ListAuditEventsRequest) (*ListResponse[AuditEvent], error) {
+	return get[ListResponse[AuditEvent]](ctx, c, "/api/audit-events", Query{
+		"actor":      {req.Actor.String()},
+		"action":     {req.Action},
+		"targetType": {req.TargetType},
+		"target":     {req.Target.String()},
+		"page":       {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) ListWebhooks(ctx context.Context, req ListWebhooksRequest) (*ListResponse[Webhook], error) {
+	return get[ListResponse[Webhook]](ctx, c, "/api/webhooks", Query{
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) CreateWebhook(ctx context.Context, req *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	return post[CreateWebhookResponse](ctx, c, "/api/webhooks", req)
+}
+
+func (c Client) UpdateWebhook(ctx context.Context, req UpdateWebhookRequest) (*Webhook, error) {
+	return put[Webhook](ctx, c, fmt.Sprintf("/api/webhooks/%s", req.ID.String()), &req)
+}
+
+func (c Client) DeleteWebhook(ctx context.Context, id uid.ID) error {
+	return delete(ctx, c, fmt.Sprintf("/api/webhooks/%s", id), Query{})
+}
+
+func (c Client) ListWebhookDeliveries(ctx context.Context, req ListWebhookDeliveriesRequest) (*ListResponse[WebhookDelivery], error) {
+	return get[ListResponse[WebhookDelivery]](ctx, c, fmt.Sprintf("/api/webhooks/%s/deliveries", req.ID), Query{
+		"status": {req.Status},
+		"page":   {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) CreateSSHCertificate(ctx context.Context, req *CreateSSHCertificateRequest) (*SSHCertificate, error) {
+	return post[SSHCertificate](ctx, c, "/api/ssh/certificates", req)
+}
+
+func (c Client) GetSSHCertificateAuthority(ctx context.Context) (*SSHCertificateAuthority, error) {
+	return get[SSHCertificateAuthority](ctx, c, "/api/ssh/certificate-authority", Query{})
+}
+
 func (c Client) ListDestinations(ctx context.Context, req ListDestinationsRequest) (*ListResponse[Destination], error) {
 	return get[ListResponse[Destination]](ctx, c, "/api/destinations", Query{
 		"name":      {req.Name},
 		"unique_id": {req.UniqueID},
 		"kind":      {req.Kind},
 		"page":      {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+		"after": {req.After},
 	})
 }
 
@@ -387,6 +647,7 @@ func (c Client) ListAccessKeys(ctx context.Context, req ListAccessKeysRequest) (
 		"name":         {req.Name},
 		"show_expired": {fmt.Sprint(req.ShowExpired)},
 		"page":         {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+		"after": {req.After},
 	})
 }
 
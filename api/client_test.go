@@ -11,6 +11,8 @@ import (
 	"testing"
 
 	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/uid"
 )
 
 func TestErrorStatusCode(t *testing.T) {
@@ -224,3 +226,65 @@ func TestListGrants(t *testing.T) {
 		assert.DeepEqual(t, apiError, expected)
 	})
 }
+
+func TestWatchGrants(t *testing.T) {
+	handler := func(resp http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/grants/watch" {
+			resp.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Query().Get("lastUpdateIndex") {
+		case "0":
+			resp.WriteHeader(http.StatusNotFound)
+			_, _ = resp.Write([]byte(`{"code": 404, "message": "not found"}`))
+		default:
+			resp.Header().Set("Content-Type", "text/event-stream")
+			resp.WriteHeader(http.StatusOK)
+			_, _ = resp.Write([]byte(`id: 11
+event: grant
+data: {"type":"add","updateIndex":11,"grant":{"id":"3w9XyTrkzk","privilege":"view","resource":"dest"}}
+
+id: 12
+event: grant
+data: {"type":"delete","updateIndex":12,"grant":{"id":"3w9XyTrkzk","privilege":"view","resource":"dest"}}
+
+id: 12
+event: sync
+data: {"type":"sync","updateIndex":12}
+
+`))
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	c := Client{URL: srv.URL, AccessKey: "the-access-key"}
+	ctx := context.Background()
+
+	t.Run("reads events", func(t *testing.T) {
+		var events []GrantEvent
+		err := c.WatchGrants(ctx, WatchGrantsRequest{Destination: "dest", LastUpdateIndex: 10}, func(event GrantEvent) error {
+			events = append(events, event)
+			return nil
+		})
+		assert.NilError(t, err)
+
+		id, err := uid.Parse([]byte("3w9XyTrkzk"))
+		assert.NilError(t, err)
+		grant := &Grant{ID: id, Privilege: "view", Resource: "dest"}
+		expected := []GrantEvent{
+			{Type: GrantEventAdd, UpdateIndex: 11, Grant: grant},
+			{Type: GrantEventDelete, UpdateIndex: 12, Grant: grant},
+			{Type: GrantEventSync, UpdateIndex: 12},
+		}
+		assert.DeepEqual(t, events, expected)
+	})
+	t.Run("error response", func(t *testing.T) {
+		err := c.WatchGrants(ctx, WatchGrantsRequest{Destination: "dest"}, func(event GrantEvent) error {
+			t.Fatalf("unexpected event: %v", event)
+			return nil
+		})
+		assert.Equal(t, ErrorStatusCode(err), int32(http.StatusNotFound))
+	})
+}
@@ -1,6 +1,14 @@
 package api
 
 import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/ssh"
+
 	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/uid"
 )
@@ -35,11 +43,65 @@ func (r DestinationConnection) ValidationRules() []validate.ValidationRule {
 	}
 }
 
+// SSHConnection is the address and host key of an SSH destination.
+type SSHConnection struct {
+	Host    string
+	Port    string
+	HostKey ssh.PublicKey
+}
+
+// ParseSSH parses the connection of an SSH destination. URL must be a host
+// name or IP address with an optional numeric port, and CA must be the host
+// key of the server in the authorized_keys format. The values are written to
+// ssh config files, so any other characters are rejected.
+func (r DestinationConnection) ParseSSH() (*SSHConnection, error) {
+	for _, value := range []string{r.URL, strings.TrimRight(string(r.CA), "\n")} {
+		if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			return nil, fmt.Errorf("connection must not contain control characters")
+		}
+	}
+
+	host, port, err := net.SplitHostPort(r.URL)
+	if err != nil {
+		host, port = r.URL, "22"
+	}
+	if !isHostName(host) && net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("connection url %q is not a valid host name or IP address", host)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return nil, fmt.Errorf("connection url port %q is not a valid port number", port)
+	}
+
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.CA))
+	if err != nil {
+		return nil, fmt.Errorf("connection ca is not a valid ssh host key: %w", err)
+	}
+	return &SSHConnection{Host: host, Port: port, HostKey: hostKey}, nil
+}
+
+// isHostName returns true if host contains only the letters, numbers, dots,
+// and dashes allowed in a DNS name.
+func isHostName(host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, c := range host {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 type ListDestinationsRequest struct {
 	Name     string `form:"name" note:"Name of the destination" example:"production-cluster"`
 	Kind     string `form:"kind" note:"Kind of destination. eg. kubernetes or ssh or postgres" example:"kubernetes"`
 	UniqueID string `form:"unique_id" note:"Unique ID generated by the connector" example:"94c2c570a20311180ec325fd56"`
 	PaginationRequest
+	CursorPaginationRequest
 }
 
 func (r ListDestinationsRequest) ValidationRules() []validate.ValidationRule {
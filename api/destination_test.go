@@ -0,0 +1,43 @@
+package api
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDestinationConnection_ParseSSH(t *testing.T) {
+	hostKey := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBnhlEzJfX3SaR5FRHqU1Xq0hbrnWcYt9bOKeH8WZHBC"
+
+	t.Run("valid", func(t *testing.T) {
+		conn := DestinationConnection{URL: "web-1.example.com:2222", CA: PEM(hostKey + " root@web-1\n")}
+		actual, err := conn.ParseSSH()
+		assert.NilError(t, err)
+		assert.Equal(t, actual.Host, "web-1.example.com")
+		assert.Equal(t, actual.Port, "2222")
+		assert.Equal(t, actual.HostKey.Type(), "ssh-ed25519")
+	})
+
+	t.Run("default port", func(t *testing.T) {
+		conn := DestinationConnection{URL: "10.0.0.1", CA: PEM(hostKey)}
+		actual, err := conn.ParseSSH()
+		assert.NilError(t, err)
+		assert.Equal(t, actual.Host, "10.0.0.1")
+		assert.Equal(t, actual.Port, "22")
+	})
+
+	invalid := map[string]DestinationConnection{
+		"newline in url":    {URL: "web-1:22\n    ProxyCommand sh", CA: PEM(hostKey)},
+		"invalid host":      {URL: "web 1:22", CA: PEM(hostKey)},
+		"non-numeric port":  {URL: "web-1:ssh", CA: PEM(hostKey)},
+		"port out of range": {URL: "web-1:70000", CA: PEM(hostKey)},
+		"newline in ca":     {URL: "web-1", CA: PEM(hostKey + "\nweb-2 " + hostKey)},
+		"not a host key":    {URL: "web-1", CA: "the-ca-or-fingerprint"},
+	}
+	for name, conn := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := conn.ParseSSH()
+			assert.Assert(t, err != nil)
+		})
+	}
+}
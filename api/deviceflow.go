@@ -4,6 +4,10 @@ import "github.com/infrahq/infra/internal/validate"
 
 type ApproveDeviceFlowRequest struct {
 	UserCode string `json:"userCode" example:"BDSD-HQMK"`
+	// WebAuthn is an optional passkey assertion from a login ceremony started
+	// with /api/login/webauthn. When set, the device is only approved if the
+	// assertion is valid for the user approving the request.
+	WebAuthn *LoginRequestWebAuthn `json:"webauthn,omitempty" note:"passkey assertion used to confirm the approval"`
 }
 
 func (adfr *ApproveDeviceFlowRequest) ValidationRules() []validate.ValidationRule {
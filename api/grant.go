@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/uid"
@@ -16,12 +17,21 @@ type Grant struct {
 	CreatedBy uid.ID `json:"created_by" note:"id of the user that created the grant"`
 	Updated   Time   `json:"updated"`
 
-	User      uid.ID `json:"user,omitempty" note:"UserID for a user being granted access" example:"6hNnjfjVcc"`
-	Group     uid.ID `json:"group,omitempty" note:"GroupID for a group being granted access" example:"3zMaadcd2U"`
-	Privilege string `json:"privilege" note:"a role or permission" example:"admin"`
-	Resource  string `json:"resource" note:"a resource name in Infra's Universal Resource Notation" example:"production.namespace"`
+	User        uid.ID `json:"user,omitempty" note:"UserID for a user being granted access" example:"6hNnjfjVcc"`
+	Group       uid.ID `json:"group,omitempty" note:"GroupID for a group being granted access" example:"3zMaadcd2U"`
+	SubjectName string `json:"subjectName,omitempty" note:"name of the user or group being granted access" example:"janedoe@example.com"`
+	SubjectKind string `json:"subjectKind,omitempty" note:"kind of the subject being granted access, either user or group" example:"user"`
+	Privilege   string `json:"privilege" note:"a role or permission" example:"admin"`
+	Resource    string `json:"resource" note:"a resource name in Infra's Universal Resource Notation" example:"production.namespace"`
+	Expires     Time   `json:"expires" note:"the grant no longer applies after this time, null if the grant does not expire"`
 }
 
+// Values for Grant.SubjectKind.
+const (
+	GrantSubjectKindUser  = "user"
+	GrantSubjectKindGroup = "group"
+)
+
 type CreateGrantResponse struct {
 	*Grant     `json:",inline"`
 	WasCreated bool `json:"wasCreated" note:"Indicates that grant was successfully created, false it already existed beforehand" example:"true"`
@@ -35,15 +45,17 @@ func (r *CreateGrantResponse) StatusCode() int {
 }
 
 type ListGrantsRequest struct {
-	User          uid.ID `form:"user" note:"ID of user granted access" example:"6TjWTAgYYu"`
-	Group         uid.ID `form:"group" note:"ID of group granted access" example:"6k3Eqcqu6B"`
-	Resource      string `form:"resource" example:"production.namespace" note:"a resource name"`
-	Destination   string `form:"destination" example:"production" note:"name of the destination where a connector is installed"`
-	Privilege     string `form:"privilege" example:"view" note:"a role or permission"`
-	ShowInherited bool   `form:"showInherited" note:"if true, this field includes grants that the user inherits through groups" example:"true"`
-	ShowSystem    bool   `form:"showSystem" note:"if true, this shows the connector and other internal grants" example:"false"`
+	User             uid.ID `form:"user" note:"ID of user granted access" example:"6TjWTAgYYu"`
+	Group            uid.ID `form:"group" note:"ID of group granted access" example:"6k3Eqcqu6B"`
+	Resource         string `form:"resource" example:"production.namespace" note:"a resource name"`
+	Destination      string `form:"destination" example:"production" note:"name of the destination where a connector is installed"`
+	Privilege        string `form:"privilege" example:"view" note:"a role or permission"`
+	ShowInherited    bool   `form:"showInherited" note:"if true, this field includes grants that the user or group inherits through groups" example:"true"`
+	ShowSystem       bool   `form:"showSystem" note:"if true, this shows the connector and other internal grants" example:"false"`
+	ShowSubjectNames bool   `form:"showSubjectNames" note:"if true, each grant includes the name and kind of the user or group" example:"true"`
 	BlockingRequest
 	PaginationRequest
+	CursorPaginationRequest
 }
 
 func (r ListGrantsRequest) ValidationRules() []validate.ValidationRule {
@@ -61,8 +73,8 @@ func (r ListGrantsRequest) ValidationRules() []validate.ValidationRule {
 		),
 		destNameRule,
 		validate.ValidatorFunc(func() *validate.Failure {
-			if r.ShowInherited && r.User == 0 {
-				return validate.Fail("showInherited", "requires a user ID")
+			if r.ShowInherited && r.User == 0 && r.Group == 0 {
+				return validate.Fail("showInherited", "requires a user or group ID")
 			}
 			return nil
 		}),
@@ -79,7 +91,7 @@ func (r ListGrantsRequest) validateLastUpdateIndex() *validate.Failure {
 	// query parameters can be set
 	switch {
 	case r.Destination != "":
-		if fields := r.fieldsWithValues("destination", "lastUpdateIndex"); len(fields) > 0 {
+		if fields := r.fieldsWithValues("destination", "lastUpdateIndex", "showSubjectNames"); len(fields) > 0 {
 			return validate.Fail("lastUpdateIndex",
 				fmt.Sprintf("can not be used with %v parameter(s)", strings.Join(fields, ",")))
 		}
@@ -125,6 +137,9 @@ func (r ListGrantsRequest) fieldsWithValues(ignored ...string) []string {
 	if r.ShowInherited && !ignore("showInherited") {
 		add("showInherited")
 	}
+	if r.ShowSubjectNames && !ignore("showSubjectNames") {
+		add("showSubjectNames")
+	}
 	if r.LastUpdateIndex != 0 && !ignore("lastUpdateIndex") {
 		add("lastUpdateIndex")
 	}
@@ -134,6 +149,9 @@ func (r ListGrantsRequest) fieldsWithValues(ignored ...string) []string {
 	if r.Limit != 0 && !ignore("limit") {
 		add("limit")
 	}
+	if r.After != "" && !ignore("after") {
+		add("after")
+	}
 	return result
 }
 
@@ -142,14 +160,62 @@ func (r ListGrantsRequest) SetPage(page int) Paginatable {
 	return r
 }
 
+// WatchGrantsRequest opens a stream of GrantEvent for the grants of a
+// destination. The stream ends when the request timeout is reached, and the
+// client is expected to reconnect with the UpdateIndex of the last event.
+type WatchGrantsRequest struct {
+	Destination     string `form:"destination" example:"production" note:"name of the destination where a connector is installed"`
+	LastUpdateIndex int64  `form:"lastUpdateIndex" note:"updateIndex of the last event received. Only changes after this index are sent. When 0, an add event is sent for every grant of the destination"`
+}
+
+func (r WatchGrantsRequest) ValidationRules() []validate.ValidationRule {
+	destNameRule := validateDestinationName(r.Destination)
+	destNameRule.Name = "destination"
+
+	return []validate.ValidationRule{
+		validate.Required("destination", r.Destination),
+		destNameRule,
+		validate.IntRule{
+			Name:  "lastUpdateIndex",
+			Value: int(r.LastUpdateIndex),
+			Min:   validate.Int(0),
+		},
+	}
+}
+
+// IsBlockingRequest is always true, so that the stream uses the longer
+// timeout for blocking requests.
+func (r WatchGrantsRequest) IsBlockingRequest() bool {
+	return true
+}
+
+const (
+	GrantEventAdd    = "add"
+	GrantEventDelete = "delete"
+	// GrantEventSync is sent after each set of add and delete events. It
+	// indicates the client has received every change up to UpdateIndex.
+	GrantEventSync = "sync"
+)
+
+// GrantEvent is sent by the watch grants endpoint each time a grant is created
+// or deleted. The Grant always includes the SubjectName and SubjectKind.
+type GrantEvent struct {
+	Type        string `json:"type" note:"add, delete, or sync" example:"add"`
+	UpdateIndex int64  `json:"updateIndex" note:"use as lastUpdateIndex to resume the stream after this event"`
+	Grant       *Grant `json:"grant,omitempty" note:"the grant that was added or deleted. Not set for sync events"`
+}
+
 // GrantRequest defines a grant request which can be used for creating or deleting grants
 type GrantRequest struct {
-	User      uid.ID `json:"user" note:"ID of the user granted access" example:"6kdoMDd6PA"`
-	Group     uid.ID `json:"group" note:"ID of the group granted access" example:"6Ti2p7r1h7"`
-	UserName  string `json:"userName" note:"Name of the user granted access" example:"admin@example.com"`
-	GroupName string `json:"groupName" note:"Name of the group granted access" example:"dev"`
-	Privilege string `json:"privilege" example:"view" note:"a role or permission"`
-	Resource  string `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation"`
+	User      uid.ID   `json:"user" note:"ID of the user granted access" example:"6kdoMDd6PA"`
+	Group     uid.ID   `json:"group" note:"ID of the group granted access" example:"6Ti2p7r1h7"`
+	UserName  string   `json:"userName" note:"Name of the user granted access" example:"admin@example.com"`
+	GroupName string   `json:"groupName" note:"Name of the group granted access" example:"dev"`
+	Privilege string   `json:"privilege" example:"view" note:"a role or permission"`
+	Resource  string   `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation"`
+	Expires   Time     `json:"expires" note:"time at which the grant expires. Can not be used with expiry"`
+	Expiry    Duration `json:"expiry" note:"duration after which the grant expires. Can not be used with expires"`
+	Force     bool     `json:"force" note:"create the grant even if the role is not reported by the destination. Only used when creating a single grant"`
 }
 
 func (r GrantRequest) ValidationRules() []validate.ValidationRule {
@@ -162,6 +228,22 @@ func (r GrantRequest) ValidationRules() []validate.ValidationRule {
 		),
 		validate.Required("privilege", r.Privilege),
 		validate.Required("resource", r.Resource),
+		validate.MutuallyExclusive(
+			validate.Field{Name: "expires", Value: r.Expires},
+			validate.Field{Name: "expiry", Value: r.Expiry},
+		),
+		validate.ValidatorFunc(func() *validate.Failure {
+			if r.Expiry < 0 {
+				return validate.Fail("expiry", "must be a positive duration")
+			}
+			return nil
+		}),
+		validate.ValidatorFunc(func() *validate.Failure {
+			if expires := r.Expires.Time(); !expires.IsZero() && expires.Before(time.Now()) {
+				return validate.Fail("expires", "must be in the future")
+			}
+			return nil
+		}),
 	}
 }
 
@@ -19,6 +19,7 @@ type ListGroupsRequest struct {
 	// UserID filters the results to only groups where this user is a member.
 	UserID uid.ID `form:"userID" note:"UserID of a user who is a member of the group"`
 	PaginationRequest
+	CursorPaginationRequest
 }
 
 func (r ListGroupsRequest) ValidationRules() []validate.ValidationRule {
@@ -49,6 +50,18 @@ func (r UpdateUsersInGroupRequest) ValidationRules() []validate.ValidationRule {
 	}
 }
 
+type UpdateGroupsInGroupRequest struct {
+	GroupID          uid.ID   `uri:"id" json:"-"`
+	GroupIDsToAdd    []uid.ID `json:"groupsToAdd" note:"IDs of groups to nest in the group"`
+	GroupIDsToRemove []uid.ID `json:"groupsToRemove" note:"IDs of nested groups to remove from the group"`
+}
+
+func (r UpdateGroupsInGroupRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.GroupID),
+	}
+}
+
 func (req ListGroupsRequest) SetPage(page int) Paginatable {
 
 	req.PaginationRequest.Page = page
@@ -30,10 +30,29 @@ func (r LoginRequestPasswordCredentials) ValidationRules() []validate.Validation
 	}
 }
 
+// LoginRequestLDAP is a login with the name and password of a user in an LDAP
+// directory.
+type LoginRequestLDAP struct {
+	ProviderID uid.ID `json:"providerID"`
+	Name       string `json:"name"`
+	Password   string `json:"password"`
+}
+
+func (r LoginRequestLDAP) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("providerID", r.ProviderID),
+		validate.Required("name", r.Name),
+		validate.Required("password", r.Password),
+	}
+}
+
 type LoginRequest struct {
 	AccessKey           string                           `json:"accessKey"`
 	PasswordCredentials *LoginRequestPasswordCredentials `json:"passwordCredentials"`
 	OIDC                *LoginRequestOIDC                `json:"oidc"`
+	MFA                 *LoginRequestMFA                 `json:"mfa"`
+	WebAuthn            *LoginRequestWebAuthn            `json:"webauthn"`
+	LDAP                *LoginRequestLDAP                `json:"ldap"`
 }
 
 func (r LoginRequest) ValidationRules() []validate.ValidationRule {
@@ -42,6 +61,9 @@ func (r LoginRequest) ValidationRules() []validate.ValidationRule {
 			validate.Field{Name: "accessKey", Value: r.AccessKey},
 			validate.Field{Name: "passwordCredentials", Value: r.PasswordCredentials},
 			validate.Field{Name: "oidc", Value: r.OIDC},
+			validate.Field{Name: "mfa", Value: r.MFA},
+			validate.Field{Name: "webauthn", Value: r.WebAuthn},
+			validate.Field{Name: "ldap", Value: r.LDAP},
 		),
 	}
 }
@@ -53,4 +75,12 @@ type LoginResponse struct {
 	PasswordUpdateRequired bool   `json:"passwordUpdateRequired,omitempty"`
 	Expires                Time   `json:"expires"`
 	OrganizationName       string `json:"organizationName,omitempty"`
+
+	// MFAChallenge is set when the user must login with a second factor. The
+	// login must be completed with a LoginRequest with MFA, and AccessKey is
+	// empty.
+	MFAChallenge string `json:"mfaChallenge,omitempty"`
+	// MFAEnrollmentRequired indicates that the organization requires MFA, and
+	// the access key can only be used to enroll in TOTP.
+	MFAEnrollmentRequired bool `json:"mfaEnrollmentRequired,omitempty"`
 }
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type StartTOTPEnrollmentResponse struct {
+	// Secret is the base32 encoded TOTP secret, for authenticator apps that
+	// do not support SecretURI.
+	Secret string `json:"secret"`
+	// SecretURI is an otpauth URI that can be used by authenticator apps to
+	// add the account, usually from a QR code.
+	SecretURI string `json:"secretURI"`
+}
+
+type ConfirmTOTPEnrollmentRequest struct {
+	ID   uid.ID `uri:"id" json:"-"`
+	Code string `json:"code" note:"A TOTP code from the secret of the enrollment"`
+}
+
+func (r ConfirmTOTPEnrollmentRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("code", r.Code),
+	}
+}
+
+type ConfirmTOTPEnrollmentResponse struct {
+	// RecoveryCodes can each be used once to login instead of a TOTP code.
+	// The recovery codes are only returned once.
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type LoginRequestMFA struct {
+	// Challenge is the MFAChallenge from the LoginResponse of the first step
+	// of the login.
+	Challenge string `json:"challenge"`
+	Code      string `json:"code" note:"A TOTP code, or a recovery code"`
+}
+
+func (r LoginRequestMFA) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("challenge", r.Challenge),
+		validate.Required("code", r.Code),
+	}
+}
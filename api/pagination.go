@@ -27,9 +27,20 @@ func (p PaginationRequest) ValidationRules() []validate.ValidationRule {
 	}
 }
 
+// CursorPaginationRequest selects a page of results using an opaque cursor
+// instead of a page number. The cursor for the next page is returned in
+// PaginationResponse.Next.
+type CursorPaginationRequest struct {
+	After string `form:"after" note:"Cursor returned as next by the previous page. Results start after this cursor" example:"NlRqV0FnWVl1"`
+}
+
 type PaginationResponse struct {
 	Page       int `json:"page" note:"Page number retrieved" example:"1"`
 	Limit      int `json:"limit" note:"Number of objects per page" example:"100"`
 	TotalPages int `json:"totalPages" note:"Total number of pages" example:"5"`
 	TotalCount int `json:"totalCount" note:"Total number of objects" example:"485"`
+
+	// Next is the cursor for the next page of results. It is only set for
+	// requests using cursor pagination, and is empty on the last page.
+	Next string `json:"next,omitempty" note:"Cursor to use as after to retrieve the next page of objects" example:"NlRqV0FnWVl1"`
 }
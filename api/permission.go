@@ -0,0 +1,36 @@
+package api
+
+// InfraPermissions are the permissions to perform an action on one type of
+// resource in the infra API. A grant of a permission on the infra resource
+// applies to all resources of that type. A grant of the grants:read,
+// grants:write, or destinations:write permission on a destination applies
+// only to that destination. Creating or deleting a grant on the infra resource
+// always requires the admin role. The providers:write permission only allows
+// deleting providers, creating or updating a provider requires the admin role.
+var InfraPermissions = []string{
+	"destinations:write",
+	"grants:read",
+	"grants:write",
+	"groups:read",
+	"groups:write",
+	"providers:write",
+	"users:read",
+	"users:write",
+}
+
+// IsInfraPermission returns true if privilege is one of InfraPermissions.
+func IsInfraPermission(privilege string) bool {
+	for _, p := range InfraPermissions {
+		if p == privilege {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission is an infra permission of a user, and the destination it is
+// limited to.
+type Permission struct {
+	Permission  string `json:"permission" example:"grants:write"`
+	Destination string `json:"destination,omitempty" note:"the destination the permission is limited to, empty for all destinations" example:"production"`
+}
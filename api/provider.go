@@ -19,16 +19,61 @@ func (r ProviderAPICredentials) ValidationRules() []validate.ValidationRule {
 	}
 }
 
+// ProviderSAML configures a SAML 2.0 identity provider.
+type ProviderSAML struct {
+	MetadataURL string `json:"metadataURL,omitempty" example:"https://adfs.example.com/FederationMetadata/2007-06/FederationMetadata.xml" note:"URL of the identity provider metadata"`
+	// Metadata is only set on requests, to import metadata that is not
+	// available from a URL.
+	Metadata        string `json:"metadata,omitempty" note:"XML metadata of the identity provider"`
+	NameAttribute   string `json:"nameAttribute,omitempty" example:"email" note:"Assertion attribute used as the user name, defaults to the subject NameID"`
+	GroupsAttribute string `json:"groupsAttribute,omitempty" example:"groups" note:"Assertion attribute that lists the groups of the user"`
+}
+
+func (r ProviderSAML) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.RequireOneOf(
+			validate.Field{Name: "metadataURL", Value: r.MetadataURL},
+			validate.Field{Name: "metadata", Value: r.Metadata},
+		),
+	}
+}
+
+// ProviderLDAP configures an LDAP or Active Directory identity provider.
+type ProviderLDAP struct {
+	// BindDN and BindPassword are the credentials of the service account used
+	// to search for users and groups.
+	BindDN string `json:"bindDN,omitempty" example:"cn=infra,ou=services,dc=example,dc=com" note:"Distinguished name of the service account used to search the directory"`
+	// BindPassword is only set on requests.
+	BindPassword string `json:"bindPassword,omitempty" note:"Password of the service account"`
+	StartTLS     bool   `json:"startTLS,omitempty" note:"Upgrade ldap:// connections to TLS with StartTLS"`
+
+	UserBaseDN        string `json:"userBaseDN" example:"ou=people,dc=example,dc=com" note:"Base DN of the user search"`
+	UserFilter        string `json:"userFilter,omitempty" example:"(&(objectClass=person)(uid={username}))" note:"Filter used to find a user by the name they login with, {username} is replaced by the name"`
+	UserNameAttribute string `json:"userNameAttribute,omitempty" example:"mail" note:"Attribute used as the user name"`
+
+	GroupBaseDN        string `json:"groupBaseDN,omitempty" example:"ou=groups,dc=example,dc=com" note:"Base DN of the group search, defaults to the user base DN"`
+	GroupFilter        string `json:"groupFilter,omitempty" example:"(&(objectClass=groupOfNames)(member={dn}))" note:"Filter used to find the groups of a user, {dn} is replaced by the DN of the user"`
+	GroupNameAttribute string `json:"groupNameAttribute,omitempty" example:"cn" note:"Attribute used as the group name"`
+}
+
+func (r ProviderLDAP) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("userBaseDN", r.UserBaseDN),
+	}
+}
+
 type Provider struct {
-	ID       uid.ID   `json:"id" note:"Provider ID"`
-	Name     string   `json:"name" example:"okta" note:"Name of the provider"`
-	Created  Time     `json:"created"`
-	Updated  Time     `json:"updated"`
-	URL      string   `json:"url" example:"infrahq.okta.com" note:"URL of the Infra Server"`
-	ClientID string   `json:"clientID" example:"0oapn0qwiQPiMIyR35d6" note:"Client ID for the OIDC provider"`
-	Kind     string   `json:"kind" example:"oidc" note:"Kind of provider"`
-	AuthURL  string   `json:"authURL" example:"https://example.com/oauth2/v1/authorize" note:"Authorize endpoint for the OIDC provider"`
-	Scopes   []string `json:"scopes" example:"['openid', 'email']" note:"Scopes set in the OIDC provider configuration"`
+	ID       uid.ID        `json:"id" note:"Provider ID"`
+	Name     string        `json:"name" example:"okta" note:"Name of the provider"`
+	Created  Time          `json:"created"`
+	Updated  Time          `json:"updated"`
+	URL      string        `json:"url" example:"infrahq.okta.com" note:"URL of the Infra Server"`
+	ClientID string        `json:"clientID" example:"0oapn0qwiQPiMIyR35d6" note:"Client ID for the OIDC provider"`
+	Kind     string        `json:"kind" example:"oidc" note:"Kind of provider"`
+	AuthURL  string        `json:"authURL" example:"https://example.com/oauth2/v1/authorize" note:"Authorize endpoint for the OIDC provider"`
+	Scopes   []string      `json:"scopes" example:"['openid', 'email']" note:"Scopes set in the OIDC provider configuration"`
+	SAML     *ProviderSAML `json:"saml,omitempty" note:"Configuration of a SAML provider"`
+	LDAP     *ProviderLDAP `json:"ldap,omitempty" note:"Configuration of an LDAP provider"`
 }
 
 type CreateProviderRequest struct {
@@ -38,11 +83,30 @@ type CreateProviderRequest struct {
 	ClientSecret string                  `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
 	Kind         string                  `json:"kind" example:"oidc"`
 	API          *ProviderAPICredentials `json:"api"`
+	SAML         *ProviderSAML           `json:"saml"`
+	LDAP         *ProviderLDAP           `json:"ldap"`
 }
 
-var kinds = []string{"oidc", "okta", "azure", "google"}
+var kinds = []string{"oidc", "okta", "azure", "google", "github", "gitlab", "saml", "ldap"}
 
 func (r CreateProviderRequest) ValidationRules() []validate.ValidationRule {
+	if r.Kind == "saml" {
+		// SAML providers are configured from metadata, instead of a client
+		return []validate.ValidationRule{
+			ValidateName(r.Name),
+			validate.Required("saml", r.SAML),
+			validate.Enum("kind", r.Kind, kinds),
+		}
+	}
+	if r.Kind == "ldap" {
+		// LDAP providers bind with a service account, instead of a client
+		return []validate.ValidationRule{
+			ValidateName(r.Name),
+			validate.Required("url", r.URL),
+			validate.Required("ldap", r.LDAP),
+			validate.Enum("kind", r.Kind, kinds),
+		}
+	}
 	return []validate.ValidationRule{
 		ValidateName(r.Name),
 		validate.Required("url", r.URL),
@@ -66,9 +130,30 @@ type UpdateProviderRequest struct {
 	ClientSecret string                  `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
 	Kind         string                  `json:"kind" example:"oidc"`
 	API          *ProviderAPICredentials `json:"api"`
+	SAML         *ProviderSAML           `json:"saml"`
+	LDAP         *ProviderLDAP           `json:"ldap"`
 }
 
 func (r UpdateProviderRequest) ValidationRules() []validate.ValidationRule {
+	if r.Kind == "saml" {
+		return []validate.ValidationRule{
+			ValidateName(r.Name),
+			validate.Required("id", r.ID),
+			validate.Required("name", r.Name),
+			validate.Required("saml", r.SAML),
+			validate.Enum("kind", r.Kind, kinds),
+		}
+	}
+	if r.Kind == "ldap" {
+		return []validate.ValidationRule{
+			ValidateName(r.Name),
+			validate.Required("id", r.ID),
+			validate.Required("name", r.Name),
+			validate.Required("url", r.URL),
+			validate.Required("ldap", r.LDAP),
+			validate.Enum("kind", r.Kind, kinds),
+		}
+	}
 	return []validate.ValidationRule{
 		ValidateName(r.Name),
 		validate.Required("id", r.ID),
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// RoleInfraRoles are the infra roles that may be included in a role.
+var RoleInfraRoles = []string{InfraAdminRole, InfraViewRole}
+
+// RolePermissions are the permissions that may be included in a role.
+var RolePermissions = append([]string{"connect"}, InfraPermissions...)
+
+// reservedRoleNames are the names of the built-in roles and permissions, which
+// can not be used as the name of a role. The infra permissions are also
+// reserved.
+var reservedRoleNames = []string{InfraAdminRole, InfraViewRole, InfraConnectorRole, "support-admin", "connect", "user"}
+
+// Role is a named set of privileges defined by an organization. A grant with
+// the name of the role as its privilege grants each of the privileges of the
+// role on the resource of the grant.
+type Role struct {
+	ID              uid.ID   `json:"id"`
+	Name            string   `json:"name" note:"Name of the role" example:"developer"`
+	Created         Time     `json:"created"`
+	Updated         Time     `json:"updated"`
+	KubernetesRoles []string `json:"kubernetesRoles" note:"Kubernetes ClusterRoles granted on destinations" example:"[\"edit\", \"logs\"]"`
+	InfraRoles      []string `json:"infraRoles" note:"infra roles granted when the resource is infra" example:"[\"view\"]"`
+	Permissions     []string `json:"permissions" note:"permissions granted on the resource" example:"[\"connect\"]"`
+}
+
+type ListRolesRequest struct {
+	Name string `form:"name" note:"Name of the role to retrieve" example:"developer"`
+	PaginationRequest
+}
+
+func (r ListRolesRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+func (r ListRolesRequest) SetPage(page int) Paginatable {
+	r.PaginationRequest.Page = page
+	return r
+}
+
+type CreateRoleRequest struct {
+	Name            string   `json:"name" example:"developer"`
+	KubernetesRoles []string `json:"kubernetesRoles" note:"Kubernetes ClusterRoles granted on destinations" example:"[\"edit\", \"logs\"]"`
+	InfraRoles      []string `json:"infraRoles" note:"infra roles granted when the resource is infra" example:"[\"view\"]"`
+	Permissions     []string `json:"permissions" note:"permissions granted on the resource" example:"[\"connect\"]"`
+}
+
+func (r CreateRoleRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("name", r.Name),
+		ValidateName(r.Name),
+		validate.ValidatorFunc(func() *validate.Failure {
+			for _, reserved := range reservedRoleNames {
+				if r.Name == reserved {
+					return validate.Fail("name", fmt.Sprintf("%q is a reserved role name", r.Name))
+				}
+			}
+			if IsInfraPermission(r.Name) {
+				return validate.Fail("name", fmt.Sprintf("%q is a reserved role name", r.Name))
+			}
+			return nil
+		}),
+		validateRoleValues("infraRoles", r.InfraRoles, RoleInfraRoles),
+		validateRoleValues("permissions", r.Permissions, RolePermissions),
+		validate.ValidatorFunc(func() *validate.Failure {
+			if len(r.KubernetesRoles)+len(r.InfraRoles)+len(r.Permissions) == 0 {
+				return validate.Fail("", "one of (kubernetesRoles, infraRoles, permissions) is required")
+			}
+			return nil
+		}),
+	}
+}
+
+func validateRoleValues(name string, values []string, allowed []string) validate.ValidationRule {
+	return validate.ValidatorFunc(func() *validate.Failure {
+		var problems []string
+	next:
+		for _, value := range values {
+			for _, ok := range allowed {
+				if value == ok {
+					continue next
+				}
+			}
+			problems = append(problems, fmt.Sprintf("%q must be one of (%v)", value, strings.Join(allowed, ", ")))
+		}
+		if len(problems) > 0 {
+			return validate.Fail(name, problems...)
+		}
+		return nil
+	})
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/json"
+
 	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/uid"
 )
@@ -26,6 +28,7 @@ const UserSchema = "urn:ietf:params:scim:schemas:core:2.0:User"
 
 type SCIMMetadata struct {
 	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
 }
 
 // SCIM user schema: https://www.rfc-editor.org/rfc/rfc7643.html#section-4.1
@@ -45,6 +48,8 @@ type SCIMParametersRequest struct {
 	StartIndex int    `form:"startIndex"`
 	Count      int    `form:"count"`
 	Filter     string `form:"filter"`
+	SortBy     string `form:"sortBy"`
+	SortOrder  string `form:"sortOrder"`
 }
 
 func (r SCIMParametersRequest) ValidationRules() []validate.ValidationRule {
@@ -59,6 +64,7 @@ func (r SCIMParametersRequest) ValidationRules() []validate.ValidationRule {
 			Value: r.Count,
 			Min:   validate.Int(0),
 		},
+		validate.Enum("sortOrder", r.SortOrder, []string{"ascending", "descending"}),
 	}
 }
 
@@ -128,3 +134,182 @@ func (r SCIMUserPatchRequest) ValidationRules() []validate.ValidationRule {
 		validate.Required("schemas", r.Schemas),
 	}
 }
+
+const GroupSchema = "urn:ietf:params:scim:schemas:core:2.0:Group"
+
+// SCIMGroupMember is a reference to a SCIM user that is a member of a group.
+// Value is the ID of the SCIM user.
+type SCIMGroupMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+func (r SCIMGroupMember) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("value", r.Value),
+	}
+}
+
+// SCIM group schema: https://www.rfc-editor.org/rfc/rfc7643.html#section-4.2
+type SCIMGroup struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMGroupMember `json:"members"`
+	Meta        SCIMMetadata      `json:"meta"`
+}
+
+// Query resources: https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2
+type ListProviderGroupsResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	Resources    []SCIMGroup `json:"Resources"` // intentionally capitalized
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+}
+
+// Creating resources: https://datatracker.ietf.org/doc/html/rfc7644#section-3.3
+type SCIMGroupCreateRequest struct {
+	Schemas     []string          `json:"schemas"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMGroupMember `json:"members"`
+}
+
+func (r SCIMGroupCreateRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("schemas", r.Schemas),
+		validate.Required("displayName", r.DisplayName),
+	}
+}
+
+// Replacing with PUT: https://datatracker.ietf.org/doc/html/rfc7644#section-3.5.1
+type SCIMGroupUpdateRequest struct {
+	ID          uid.ID            `uri:"id" json:"-"`
+	Schemas     []string          `json:"schemas"`
+	DisplayName string            `json:"displayName"`
+	Members     []SCIMGroupMember `json:"members"`
+}
+
+func (r SCIMGroupUpdateRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("schemas", r.Schemas),
+		validate.Required("displayName", r.DisplayName),
+	}
+}
+
+// SCIMGroupPatchOperation is a single operation of a group PATCH request. The
+// type of Value depends on Op and Path, so it is decoded by the handler.
+type SCIMGroupPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Modifying with PATCH: https://datatracker.ietf.org/doc/html/rfc7644#section-3.5.2
+type SCIMGroupPatchRequest struct {
+	ID         uid.ID                    `uri:"id" json:"-"`
+	Schemas    []string                  `json:"schemas"`
+	Operations []SCIMGroupPatchOperation `json:"Operations"` // json intentionally capitalized
+}
+
+func (r SCIMGroupPatchRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("schemas", r.Schemas),
+	}
+}
+
+const (
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ResourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+type SCIMSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type SCIMBulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type SCIMFilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type SCIMAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// Service provider configuration: https://www.rfc-editor.org/rfc/rfc7643.html#section-5
+type SCIMServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	DocumentationURI      string                     `json:"documentationUri,omitempty"`
+	Patch                 SCIMSupported              `json:"patch"`
+	Bulk                  SCIMBulkSupported          `json:"bulk"`
+	Filter                SCIMFilterSupported        `json:"filter"`
+	ChangePassword        SCIMSupported              `json:"changePassword"`
+	Sort                  SCIMSupported              `json:"sort"`
+	ETag                  SCIMSupported              `json:"etag"`
+	AuthenticationSchemes []SCIMAuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  SCIMMetadata               `json:"meta"`
+}
+
+// Resource type: https://www.rfc-editor.org/rfc/rfc7643.html#section-6
+type SCIMResourceType struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Endpoint    string       `json:"endpoint"`
+	Description string       `json:"description"`
+	Schema      string       `json:"schema"`
+	Meta        SCIMMetadata `json:"meta"`
+}
+
+type ListSCIMResourceTypesResponse struct {
+	Schemas      []string           `json:"schemas"`
+	TotalResults int                `json:"totalResults"`
+	Resources    []SCIMResourceType `json:"Resources"` // intentionally capitalized
+	StartIndex   int                `json:"startIndex"`
+	ItemsPerPage int                `json:"itemsPerPage"`
+}
+
+type SCIMSchemaAttribute struct {
+	Name          string                `json:"name"`
+	Type          string                `json:"type"`
+	MultiValued   bool                  `json:"multiValued"`
+	Description   string                `json:"description"`
+	Required      bool                  `json:"required"`
+	CaseExact     bool                  `json:"caseExact"`
+	Mutability    string                `json:"mutability"`
+	Returned      string                `json:"returned"`
+	Uniqueness    string                `json:"uniqueness"`
+	SubAttributes []SCIMSchemaAttribute `json:"subAttributes,omitempty"`
+}
+
+// Schema definition: https://www.rfc-editor.org/rfc/rfc7643.html#section-7
+type SCIMSchema struct {
+	Schemas     []string              `json:"schemas"`
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Attributes  []SCIMSchemaAttribute `json:"attributes"`
+	Meta        SCIMMetadata          `json:"meta"`
+}
+
+type ListSCIMSchemasResponse struct {
+	Schemas      []string     `json:"schemas"`
+	TotalResults int          `json:"totalResults"`
+	Resources    []SCIMSchema `json:"Resources"` // intentionally capitalized
+	StartIndex   int          `json:"startIndex"`
+	ItemsPerPage int          `json:"itemsPerPage"`
+}
+
+type SCIMDiscoveryRequest struct {
+	ID string `uri:"id"`
+}
@@ -2,6 +2,9 @@ package api
 
 type Settings struct {
 	PasswordRequirements PasswordRequirements `json:"passwordRequirements"`
+	// RequireMFA requires users who login with a password to use a second
+	// factor. Users who have not enrolled must enroll when they next login.
+	RequireMFA bool `json:"requireMFA"`
 }
 
 type PasswordRequirements struct {
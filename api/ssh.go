@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+)
+
+type CreateSSHCertificateRequest struct {
+	Destination string `json:"destination" example:"web-1" note:"name of the SSH destination"`
+	PublicKey   string `json:"publicKey" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBnhlEzJfX3SaR5FRHqU1Xq0hbrnWcYt9bOKeH8WZHBC" note:"public key to sign, in the OpenSSH authorized_keys format"`
+}
+
+func (r CreateSSHCertificateRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("destination", r.Destination),
+		validate.Required("publicKey", r.PublicKey),
+	}
+}
+
+type SSHCertificate struct {
+	Certificate string `json:"certificate" note:"the signed certificate, in the OpenSSH authorized_keys format"`
+	Username    string `json:"username" example:"alice-21i3v9" note:"name of the local user on the destination"`
+	Expires     Time   `json:"expires" note:"after this time the certificate is no longer valid"`
+}
+
+type SSHCertificateAuthority struct {
+	PublicKey string `json:"publicKey" note:"public key of the certificate authority that signs user certificates, in the OpenSSH authorized_keys format"`
+}
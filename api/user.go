@@ -30,6 +30,7 @@ type ListUsersRequest struct {
 	IDs        []uid.ID `form:"ids" note:"List of User IDs"`
 	ShowSystem bool     `form:"showSystem" note:"if true, this shows the connector and other internal users" example:"false"`
 	PaginationRequest
+	CursorPaginationRequest
 }
 
 func (r ListUsersRequest) ValidationRules() []validate.ValidationRule {
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// The WebAuthn types use the JSON encoding of the WebAuthn Level 3
+// specification. All binary values are base64url encoded strings.
+
+type WebAuthnCredential struct {
+	ID       uid.ID `json:"id" note:"ID of the credential" example:"4yJ3n3D8E2"`
+	Name     string `json:"name" note:"Name of the authenticator" example:"laptop"`
+	Created  Time   `json:"created"`
+	LastUsed Time   `json:"lastUsed"`
+}
+
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WebAuthnUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type WebAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type WebAuthnCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions are the options passed to navigator.credentials.create
+// to register a new credential.
+type WebAuthnCreationOptions struct {
+	RP                     WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUser                   `json:"user"`
+	Challenge              string                         `json:"challenge"`
+	PubKeyCredParams       []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                         `json:"attestation"`
+}
+
+// WebAuthnRequestOptions are the options passed to navigator.credentials.get
+// to login with a registered credential.
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	Timeout          int64                          `json:"timeout"`
+	RPID             string                         `json:"rpId"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+type BeginWebAuthnRegistrationResponse struct {
+	// SessionID must be sent with the FinishWebAuthnRegistrationRequest.
+	SessionID uid.ID                  `json:"sessionID"`
+	PublicKey WebAuthnCreationOptions `json:"publicKey"`
+}
+
+type WebAuthnAttestationResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AttestationObject string `json:"attestationObject"`
+}
+
+// WebAuthnAttestation is the credential returned by navigator.credentials.create.
+type WebAuthnAttestation struct {
+	ID       string                      `json:"id"`
+	Type     string                      `json:"type"`
+	Response WebAuthnAttestationResponse `json:"response"`
+}
+
+func (r WebAuthnAttestation) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("response.clientDataJSON", r.Response.ClientDataJSON),
+		validate.Required("response.attestationObject", r.Response.AttestationObject),
+	}
+}
+
+type FinishWebAuthnRegistrationRequest struct {
+	ID         uid.ID              `uri:"id" json:"-"`
+	SessionID  uid.ID              `json:"sessionID"`
+	Name       string              `json:"name" note:"Name of the authenticator" example:"laptop"`
+	Credential WebAuthnAttestation `json:"credential"`
+}
+
+func (r FinishWebAuthnRegistrationRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("sessionID", r.SessionID),
+		validate.StringRule{Name: "name", Value: r.Name, MaxLength: 256},
+	}
+}
+
+type DeleteWebAuthnCredentialRequest struct {
+	ID           uid.ID `uri:"id" json:"-"`
+	CredentialID uid.ID `uri:"credentialID" json:"-"`
+}
+
+func (r DeleteWebAuthnCredentialRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("credentialID", r.CredentialID),
+	}
+}
+
+type BeginWebAuthnLoginRequest struct {
+	// Name is optional. When it is empty the user must login with a
+	// discoverable credential (a passkey).
+	Name string `json:"name" example:"bob@example.com"`
+}
+
+func (r BeginWebAuthnLoginRequest) ValidationRules() []validate.ValidationRule {
+	return nil
+}
+
+type BeginWebAuthnLoginResponse struct {
+	// SessionID must be sent with the LoginRequestWebAuthn.
+	SessionID uid.ID                 `json:"sessionID"`
+	PublicKey WebAuthnRequestOptions `json:"publicKey"`
+}
+
+type WebAuthnAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle"`
+}
+
+// WebAuthnAssertion is the credential returned by navigator.credentials.get.
+type WebAuthnAssertion struct {
+	ID       string                    `json:"id"`
+	Type     string                    `json:"type"`
+	Response WebAuthnAssertionResponse `json:"response"`
+}
+
+type LoginRequestWebAuthn struct {
+	SessionID  uid.ID            `json:"sessionID"`
+	Credential WebAuthnAssertion `json:"credential"`
+}
+
+func (r LoginRequestWebAuthn) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("sessionID", r.SessionID),
+		validate.Required("credential.id", r.Credential.ID),
+		validate.Required("credential.response.clientDataJSON", r.Credential.Response.ClientDataJSON),
+		validate.Required("credential.response.authenticatorData", r.Credential.Response.AuthenticatorData),
+		validate.Required("credential.response.signature", r.Credential.Response.Signature),
+	}
+}
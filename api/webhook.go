@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// WebhookEventTypes are the types of events that may be delivered to a webhook.
+// A webhook may also subscribe to all the events for a resource using a
+// wildcard, ex: grant.*, or to all events with *.
+var WebhookEventTypes = []string{
+	"user.created", "user.updated", "user.deleted",
+	"group.created", "group.updated", "group.deleted",
+	"grant.created", "grant.updated", "grant.deleted",
+	"destination.created", "destination.updated", "destination.deleted",
+}
+
+// Headers sent with each webhook delivery. The signature is the hex encoded
+// HMAC-SHA256 of the timestamp, a period, and the request body, using the
+// webhook secret as the key. The signature is prefixed with sha256=.
+const (
+	HeaderWebhookEvent     = "Infra-Webhook-Event"
+	HeaderWebhookDelivery  = "Infra-Webhook-Delivery"
+	HeaderWebhookTimestamp = "Infra-Webhook-Timestamp"
+	HeaderWebhookSignature = "Infra-Webhook-Signature"
+)
+
+type Webhook struct {
+	ID      uid.ID   `json:"id" note:"ID of the webhook" example:"4yJ3n3D8E2"`
+	Created Time     `json:"created"`
+	Updated Time     `json:"updated"`
+	URL     string   `json:"url" note:"URL that receives events" example:"https://example.com/infra-events"`
+	Events  []string `json:"events" note:"types of events delivered to the webhook, all events when empty" example:"[\"grant.*\", \"user.deleted\"]"`
+}
+
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret" note:"key used to sign deliveries. It is only returned when the webhook is created"`
+}
+
+type ListWebhooksRequest struct {
+	PaginationRequest
+}
+
+func (r ListWebhooksRequest) SetPage(page int) Paginatable {
+	r.PaginationRequest.Page = page
+	return r
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" example:"https://example.com/infra-events" note:"URL that receives events"`
+	Events []string `json:"events" note:"types of events delivered to the webhook, all events when empty"`
+	Secret string   `json:"secret" note:"key used to sign deliveries. A random secret is generated when empty"`
+}
+
+func (r CreateWebhookRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("url", r.URL),
+		validateWebhookURL(r.URL),
+		validateWebhookEvents(r.Events),
+	}
+}
+
+type UpdateWebhookRequest struct {
+	ID     uid.ID   `uri:"id" json:"-"`
+	URL    string   `json:"url" example:"https://example.com/infra-events" note:"URL that receives events"`
+	Events []string `json:"events" note:"types of events delivered to the webhook, all events when empty"`
+}
+
+func (r UpdateWebhookRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("url", r.URL),
+		validateWebhookURL(r.URL),
+		validateWebhookEvents(r.Events),
+	}
+}
+
+func validateWebhookURL(value string) validate.ValidationRule {
+	return validate.ValidatorFunc(func() *validate.Failure {
+		if value == "" {
+			return nil
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return validate.Fail("url", "must be an http or https URL")
+		}
+		host := u.Hostname()
+		if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+			return validate.Fail("url", "must not be a loopback address")
+		}
+		if ip := net.ParseIP(host); ip != nil && !validate.IsPublicIP(ip) {
+			return validate.Fail("url", "must not be a loopback, private, or link-local address")
+		}
+		return nil
+	})
+}
+
+func validateWebhookEvents(events []string) validate.ValidationRule {
+	return validate.ValidatorFunc(func() *validate.Failure {
+		var problems []string
+		for _, event := range events {
+			if !isWebhookEventType(event) {
+				problems = append(problems, "unknown event type "+event)
+			}
+		}
+		if len(problems) > 0 {
+			return validate.Fail("events", problems...)
+		}
+		return nil
+	})
+}
+
+func isWebhookEventType(event string) bool {
+	if event == "*" {
+		return true
+	}
+	for _, t := range WebhookEventTypes {
+		resource, _, _ := strings.Cut(t, ".")
+		if event == t || event == resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is the body of the request sent to a webhook.
+type WebhookEvent struct {
+	ID           uid.ID          `json:"id" note:"ID of the event. For events other than grant events, the same as the ID of the audit event"`
+	Type         string          `json:"type" example:"grant.created"`
+	Created      Time            `json:"created"`
+	Organization uid.ID          `json:"organization"`
+	Actor        uid.ID          `json:"actor" note:"ID of the user who made the change"`
+	ActorName    string          `json:"actorName"`
+	Target       uid.ID          `json:"target,omitempty" note:"ID of the resource that was changed"`
+	Before       json.RawMessage `json:"before,omitempty" note:"summary of the resource before the change"`
+	After        json.RawMessage `json:"after,omitempty" note:"summary of the resource after the change"`
+}
+
+type WebhookDelivery struct {
+	ID             uid.ID `json:"id"`
+	Created        Time   `json:"created"`
+	Webhook        uid.ID `json:"webhook"`
+	Event          uid.ID `json:"event" note:"ID of the event that was delivered"`
+	EventType      string `json:"eventType" example:"grant.created"`
+	Status         string `json:"status" note:"one of pending, succeeded, or failed" example:"succeeded"`
+	Attempts       int    `json:"attempts" note:"number of attempts to deliver the event"`
+	LastAttempt    Time   `json:"lastAttempt"`
+	NextAttempt    Time   `json:"nextAttempt" note:"time of the next attempt, when the status is pending"`
+	ResponseStatus int    `json:"responseStatus" note:"HTTP status code of the response to the last attempt" example:"200"`
+	Error          string `json:"error" note:"error from the last attempt"`
+}
+
+type ListWebhookDeliveriesRequest struct {
+	ID     uid.ID `uri:"id" json:"-"`
+	Status string `form:"status" note:"Status of the deliveries" example:"failed"`
+	PaginationRequest
+}
+
+func (r ListWebhookDeliveriesRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Enum("status", r.Status, []string{"pending", "succeeded", "failed"}),
+	}
+}
+
+func (r ListWebhookDeliveriesRequest) SetPage(page int) Paginatable {
+	r.PaginationRequest.Page = page
+	return r
+}
@@ -30,6 +30,9 @@ type AuthorizationError struct {
 	Resource      string
 	Operation     string
 	RequiredRoles []string
+	// RequiredPermission is the name of the permission that also allows the
+	// operation, if any.
+	RequiredPermission string
 }
 
 func (e AuthorizationError) Error() string {
@@ -48,6 +51,10 @@ func (e AuthorizationError) Error() string {
 			}
 		}
 	}
+	if e.RequiredPermission != "" {
+		return fmt.Sprintf("you do not have permission to %v %v, requires role %v, or permission %v",
+			e.Operation, e.Resource, roles.String(), e.RequiredPermission)
+	}
 	return fmt.Sprintf("you do not have permission to %v %v, requires role %v",
 		e.Operation, e.Resource, roles.String())
 }
@@ -70,25 +77,56 @@ func HandleAuthErr(err error, resource, operation string, roles ...string) error
 
 // IsAuthorized checks if the request has permission to perform the action. The
 // request has permission if the user or one of the groups they belong to
-// has a grant with one of the required roles.
+// has a grant with one of the required roles, or a grant of a custom role
+// that includes one of the required roles.
 // The resource is always ResourceInfraAPI.
 func IsAuthorized(rCtx RequestContext, requiredRole ...string) error {
 	user := rCtx.Authenticated.User
 	if user == nil {
 		return fmt.Errorf("no authenticated user")
 	}
+
+	ok, err := hasInfraGrant(rCtx, requiredRole, ResourceInfraAPI)
+	if err != nil || ok {
+		return err
+	}
+
+	roles, err := data.ListRoles(rCtx.DBTxn, data.ListRolesOptions{})
+	if err != nil {
+		return fmt.Errorf("list roles: %w", err)
+	}
+	var customRoles []string
+	for _, role := range roles {
+		if role.HasInfraRole(requiredRole...) {
+			customRoles = append(customRoles, role.Name)
+		}
+	}
+	if len(customRoles) == 0 {
+		return ErrNotAuthorized
+	}
+
+	ok, err = hasInfraGrant(rCtx, customRoles, ResourceInfraAPI)
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		return ErrNotAuthorized
+	}
+	return nil
+}
+
+// hasInfraGrant returns true if the authenticated user, or one of the groups
+// they belong to, has a grant of one of the privileges on the resource.
+func hasInfraGrant(rCtx RequestContext, privileges []string, resource string) (bool, error) {
 	grants, err := data.ListGrants(rCtx.DBTxn, data.ListGrantsOptions{
 		Pagination:                 &data.Pagination{Limit: 1},
-		BySubject:                  uid.NewIdentityPolymorphicID(user.ID),
-		ByPrivileges:               requiredRole,
-		ByResource:                 ResourceInfraAPI,
+		BySubject:                  uid.NewIdentityPolymorphicID(rCtx.Authenticated.User.ID),
+		ByPrivileges:               privileges,
+		ByResource:                 resource,
 		IncludeInheritedFromGroups: true,
 	})
 	if err != nil {
-		return fmt.Errorf("has grants: %w", err)
+		return false, fmt.Errorf("has grants: %w", err)
 	}
-	if len(grants) == 0 {
-		return ErrNotAuthorized
-	}
-	return nil
+	return len(grants) > 0, nil
 }
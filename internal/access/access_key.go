@@ -96,6 +96,7 @@ func DeleteAccessKey(rCtx RequestContext, id uid.ID, name string) error {
 		return fmt.Errorf("%w: cannot delete the access key used by this request", internal.ErrBadRequest)
 	}
 
+	rCtx.Response.SetAuditBefore(key.ToAPI())
 	return data.DeleteAccessKeys(rCtx.DBTxn, data.DeleteAccessKeysOptions{ByID: key.ID})
 }
 
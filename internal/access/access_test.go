@@ -229,6 +229,16 @@ func TestAuthorizationError(t *testing.T) {
 		expected := "you do not have permission to add destination, requires role admin, view, or connector"
 		assert.Equal(t, err.Error(), expected)
 	})
+	t.Run("role or permission", func(t *testing.T) {
+		err := AuthorizationError{
+			Operation:          "create",
+			Resource:           "grant",
+			RequiredRoles:      []string{"admin"},
+			RequiredPermission: "grants:write on staging",
+		}
+		expected := "you do not have permission to create grant, requires role admin, or permission grants:write on staging"
+		assert.Equal(t, err.Error(), expected)
+	})
 	t.Run("is ErrNotAuthorized", func(t *testing.T) {
 		err := AuthorizationError{}
 		assert.Assert(t, errors.Is(err, ErrNotAuthorized))
@@ -0,0 +1,156 @@
+package access
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateAccessRequest creates an access request for the authenticated user.
+// Any authenticated user may request access.
+func CreateAccessRequest(c *gin.Context, req *models.AccessRequest) error {
+	rCtx := GetRequestContext(c)
+	user := rCtx.Authenticated.User
+	if user == nil {
+		return fmt.Errorf("%w: no authenticated user", ErrNotAuthorized)
+	}
+
+	if err := checkDestinationPrivilege(rCtx.DBTxn, req.Resource, req.Privilege); err != nil {
+		return err
+	}
+
+	req.UserID = user.ID
+	req.Status = models.AccessRequestStatusPending
+	return data.CreateAccessRequest(rCtx.DBTxn, req)
+}
+
+func GetAccessRequest(c *gin.Context, id uid.ID) (*models.AccessRequest, error) {
+	rCtx := GetRequestContext(c)
+
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	_, err := RequireInfraRole(c, roles...)
+	err = HandleAuthErr(err, "access request", "get", roles...)
+	if errors.Is(err, ErrNotAuthorized) {
+		req, getErr := data.GetAccessRequest(rCtx.DBTxn, data.GetAccessRequestOptions{ByID: id})
+		if getErr != nil {
+			return nil, getErr
+		}
+		// Allow an authenticated identity to view their own requests
+		if rCtx.Authenticated.User != nil && req.UserID == rCtx.Authenticated.User.ID {
+			return req, nil
+		}
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return data.GetAccessRequest(rCtx.DBTxn, data.GetAccessRequestOptions{ByID: id})
+}
+
+func ListAccessRequests(c *gin.Context, opts data.ListAccessRequestsOptions) ([]models.AccessRequest, error) {
+	rCtx := GetRequestContext(c)
+
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	_, err := RequireInfraRole(c, roles...)
+	err = HandleAuthErr(err, "access requests", "list", roles...)
+	if errors.Is(err, ErrNotAuthorized) {
+		// Allow an authenticated identity to view their own requests
+		user := rCtx.Authenticated.User
+		if user == nil || opts.ByUserID != user.ID {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return data.ListAccessRequests(rCtx.DBTxn, opts)
+}
+
+// ApproveAccessRequest approves a pending access request, and creates a grant
+// for the requesting user that expires after the requested duration.
+func ApproveAccessRequest(c *gin.Context, id uid.ID, reason string) (*models.AccessRequest, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access request", "approve", models.InfraAdminRole)
+	}
+	rCtx := GetRequestContext(c)
+
+	req, err := getPendingAccessRequest(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserID == rCtx.Authenticated.User.ID {
+		return nil, fmt.Errorf("%w: cannot approve your own access request", internal.ErrBadRequest)
+	}
+	if err := checkDestinationPrivilege(db, req.Resource, req.Privilege); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	grant := &models.Grant{
+		Subject:   uid.NewIdentityPolymorphicID(req.UserID),
+		Privilege: req.Privilege,
+		Resource:  req.Resource,
+		CreatedBy: rCtx.Authenticated.User.ID,
+		ExpiresAt: sql.NullTime{Time: now.Add(req.Duration), Valid: true},
+	}
+	if err := data.CreateGrant(db, grant); err != nil {
+		var ucerr data.UniqueConstraintError
+		if errors.As(err, &ucerr) {
+			return nil, fmt.Errorf("%w: the user already has this grant", internal.ErrBadRequest)
+		}
+		return nil, fmt.Errorf("create grant: %w", err)
+	}
+
+	req.Status = models.AccessRequestStatusApproved
+	req.DecidedBy = rCtx.Authenticated.User.ID
+	req.DecidedAt = now
+	req.DecisionReason = reason
+	req.GrantID = grant.ID
+	if err := data.UpdateAccessRequest(db, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// DenyAccessRequest denies a pending access request.
+func DenyAccessRequest(c *gin.Context, id uid.ID, reason string) (*models.AccessRequest, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access request", "deny", models.InfraAdminRole)
+	}
+	rCtx := GetRequestContext(c)
+
+	req, err := getPendingAccessRequest(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Status = models.AccessRequestStatusDenied
+	req.DecidedBy = rCtx.Authenticated.User.ID
+	req.DecidedAt = time.Now()
+	req.DecisionReason = reason
+	if err := data.UpdateAccessRequest(db, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func getPendingAccessRequest(tx data.ReadTxn, id uid.ID) (*models.AccessRequest, error) {
+	req, err := data.GetAccessRequest(tx, data.GetAccessRequestOptions{ByID: id})
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != models.AccessRequestStatusPending {
+		return nil, fmt.Errorf("%w: access request has already been %v", internal.ErrBadRequest, req.Status)
+	}
+	return req, nil
+}
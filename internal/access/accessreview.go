@@ -0,0 +1,405 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateAccessReview starts an access review, and creates a review item
+// assigned to reviewerID for each of the grants in the scope of the review.
+// When reviewerID is zero the items are assigned to the user who starts the
+// review.
+func CreateAccessReview(c *gin.Context, review *models.AccessReview, reviewerID uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "access review", "create", models.InfraAdminRole)
+	}
+	rCtx := GetRequestContext(c)
+
+	if reviewerID == 0 {
+		reviewerID = rCtx.Authenticated.User.ID
+	}
+	_, err = data.GetIdentity(db, data.GetIdentityOptions{ByID: reviewerID})
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return fmt.Errorf("%w: reviewer %v does not exist", internal.ErrBadRequest, reviewerID)
+	case err != nil:
+		return fmt.Errorf("get reviewer: %w", err)
+	}
+
+	grants, err := accessReviewGrants(db, review)
+	if err != nil {
+		return err
+	}
+
+	review.CreatedBy = rCtx.Authenticated.User.ID
+	review.Status = models.AccessReviewStatusOpen
+	if err := data.CreateAccessReview(db, review); err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		item := &models.AccessReviewItem{
+			AccessReviewID: review.ID,
+			GrantID:        grant.ID,
+			Subject:        grant.Subject,
+			Privilege:      grant.Privilege,
+			Resource:       grant.Resource,
+			ReviewerID:     reviewerID,
+		}
+		if err := data.CreateAccessReviewItem(db, item); err != nil {
+			return fmt.Errorf("create review item: %w", err)
+		}
+	}
+	return nil
+}
+
+// accessReviewGrants returns the grants in the scope of the review. When the
+// review has destinations, only grants on those destinations are included.
+// When the review has groups, only the grants of those groups, the groups
+// nested in them, and the members of any of those groups are included.
+func accessReviewGrants(tx data.ReadTxn, review *models.AccessReview) ([]models.Grant, error) {
+	destinations := make(map[string]bool, len(review.Destinations))
+	for _, name := range review.Destinations {
+		destinations[name] = true
+	}
+
+	var subjects map[uid.PolymorphicID]bool
+	if len(review.Groups) > 0 {
+		var err error
+		subjects, err = accessReviewSubjects(tx, review.Groups)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	grants, err := data.ListGrants(tx, data.ListGrantsOptions{ExcludeConnectorGrant: true})
+	if err != nil {
+		return nil, fmt.Errorf("list grants: %w", err)
+	}
+
+	var result []models.Grant
+	for _, grant := range grants {
+		destination, _, _ := strings.Cut(grant.Resource, ".")
+		if len(destinations) > 0 && !destinations[destination] {
+			continue
+		}
+		if subjects != nil && !subjects[grant.Subject] {
+			continue
+		}
+		result = append(result, grant)
+	}
+	return result, nil
+}
+
+// accessReviewSubjects returns the groups, the groups nested in them, and the
+// users who are members of any of those groups.
+func accessReviewSubjects(tx data.ReadTxn, rawGroupIDs []string) (map[uid.PolymorphicID]bool, error) {
+	groupIDs := make([]uid.ID, 0, len(rawGroupIDs))
+	for _, raw := range rawGroupIDs {
+		id, err := uid.Parse([]byte(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid group ID %q", internal.ErrBadRequest, raw)
+		}
+		groupIDs = append(groupIDs, id)
+	}
+
+	groups, err := data.ListGroups(tx, data.ListGroupsOptions{ByIDs: groupIDs})
+	if err != nil {
+		return nil, fmt.Errorf("list groups: %w", err)
+	}
+	if len(groups) != len(groupIDs) {
+		return nil, fmt.Errorf("%w: one or more groups do not exist", internal.ErrBadRequest)
+	}
+
+	for _, groupID := range groupIDs {
+		nested, err := data.ListGroups(tx, data.ListGroupsOptions{ByNestedInGroup: groupID})
+		if err != nil {
+			return nil, fmt.Errorf("list nested groups: %w", err)
+		}
+		groups = append(groups, nested...)
+	}
+
+	subjects := map[uid.PolymorphicID]bool{}
+	for _, group := range groups {
+		subjects[uid.NewGroupPolymorphicID(group.ID)] = true
+
+		users, err := data.ListIdentities(tx, data.ListIdentityOptions{ByGroupID: group.ID})
+		if err != nil {
+			return nil, fmt.Errorf("list group members: %w", err)
+		}
+		for _, user := range users {
+			subjects[uid.NewIdentityPolymorphicID(user.ID)] = true
+		}
+	}
+	return subjects, nil
+}
+
+func GetAccessReview(c *gin.Context, id uid.ID) (*models.AccessReview, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access review", "get", roles...)
+	}
+	return data.GetAccessReview(db, data.GetAccessReviewOptions{ByID: id})
+}
+
+func ListAccessReviews(c *gin.Context, opts data.ListAccessReviewsOptions) ([]models.AccessReview, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access reviews", "list", roles...)
+	}
+	return data.ListAccessReviews(db, opts)
+}
+
+func ListAccessReviewItems(c *gin.Context, opts data.ListAccessReviewItemsOptions) ([]models.AccessReviewItem, error) {
+	rCtx := GetRequestContext(c)
+
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	_, err := RequireInfraRole(c, roles...)
+	err = HandleAuthErr(err, "access review items", "list", roles...)
+	if errors.Is(err, ErrNotAuthorized) {
+		// Allow a reviewer to list the items assigned to them
+		user := rCtx.Authenticated.User
+		if user == nil || opts.ByReviewerID != user.ID {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, err := data.GetAccessReview(rCtx.DBTxn, data.GetAccessReviewOptions{ByID: opts.ByAccessReviewID}); err != nil {
+		return nil, err
+	}
+	return data.ListAccessReviewItems(rCtx.DBTxn, opts)
+}
+
+// DecideAccessReviewItem records the decision to keep or revoke the grant of
+// an access review item. The grant is not deleted until the review is closed,
+// and the decision can be changed while the review is open. Only the assigned
+// reviewer, or an admin, can decide.
+func DecideAccessReviewItem(c *gin.Context, id uid.ID, decision models.AccessReviewDecision, reason string) (*models.AccessReviewItem, error) {
+	rCtx := GetRequestContext(c)
+
+	item, err := data.GetAccessReviewItem(rCtx.DBTxn, data.GetAccessReviewItemOptions{ByID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = RequireInfraRole(c, models.InfraAdminRole)
+	err = HandleAuthErr(err, "access review item", string(decision), models.InfraAdminRole)
+	if errors.Is(err, ErrNotAuthorized) {
+		user := rCtx.Authenticated.User
+		if user == nil || item.ReviewerID != user.ID {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, err := getOpenAccessReview(rCtx.DBTxn, item.AccessReviewID); err != nil {
+		return nil, err
+	}
+
+	item.Decision = decision
+	item.DecidedBy = rCtx.Authenticated.User.ID
+	item.DecidedAt = time.Now()
+	item.Reason = reason
+	if err := data.UpdateAccessReviewItem(rCtx.DBTxn, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// CloseAccessReview closes an open access review. Items that were not
+// reviewed are marked as unreviewed, and the grants of revoked and unreviewed
+// items are deleted. When keepSystemGrants is true the grants created by the
+// system, which are managed by the server config, are not deleted. Closing a
+// review fails if it would delete the last admin grant on the infra resource.
+func CloseAccessReview(c *gin.Context, id uid.ID, keepSystemGrants bool) (*models.AccessReview, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access review", "close", models.InfraAdminRole)
+	}
+	rCtx := GetRequestContext(c)
+
+	review, err := getOpenAccessReview(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := data.ListAccessReviewItems(db, data.ListAccessReviewItemsOptions{ByAccessReviewID: review.ID})
+	if err != nil {
+		return nil, fmt.Errorf("list review items: %w", err)
+	}
+
+	var revoked []uid.ID
+	for i := range items {
+		item := &items[i]
+		switch item.Decision {
+		case models.AccessReviewDecisionPending:
+			item.Decision = models.AccessReviewDecisionUnreviewed
+			if err := data.UpdateAccessReviewItem(db, item); err != nil {
+				return nil, fmt.Errorf("update review item: %w", err)
+			}
+		case models.AccessReviewDecisionRevoke:
+		default:
+			continue
+		}
+
+		grant, err := data.GetGrant(db, data.GetGrantOptions{ByID: item.GrantID})
+		switch {
+		case errors.Is(err, internal.ErrNotFound):
+			continue // the grant was already deleted
+		case err != nil:
+			return nil, fmt.Errorf("get grant: %w", err)
+		}
+		if keepSystemGrants && grant.CreatedBy == models.CreatedBySystem {
+			continue
+		}
+		revoked = append(revoked, item.GrantID)
+	}
+
+	if len(revoked) > 0 {
+		if err := checkLastInfraAdminGrant(db, revoked, "closing the access review"); err != nil {
+			return nil, err
+		}
+		if err := data.DeleteGrants(db, data.DeleteGrantsOptions{ByIDs: revoked}); err != nil {
+			return nil, fmt.Errorf("delete grants: %w", err)
+		}
+	}
+
+	review.Status = models.AccessReviewStatusClosed
+	review.ClosedBy = rCtx.Authenticated.User.ID
+	review.ClosedAt = time.Now()
+	if err := data.UpdateAccessReview(db, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// checkLastInfraAdminGrant returns an error if deleting the grants with
+// grantIDs would delete every admin grant on the infra resource. Operation
+// describes the change in the error message.
+func checkLastInfraAdminGrant(tx data.ReadTxn, grantIDs []uid.ID, operation string) error {
+	admins, err := data.ListGrants(tx, data.ListGrantsOptions{
+		ByPrivileges: []string{models.InfraAdminRole},
+		ByResource:   ResourceInfraAPI,
+	})
+	if err != nil {
+		return fmt.Errorf("list infra admin grants: %w", err)
+	}
+
+	deleted := make(map[uid.ID]bool, len(grantIDs))
+	for _, id := range grantIDs {
+		deleted[id] = true
+	}
+	var remaining int
+	for _, grant := range admins {
+		if !deleted[grant.ID] {
+			remaining++
+		}
+	}
+	if len(admins) == 0 || remaining > 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %v would revoke the last infra admin grant, keep at least one",
+		internal.ErrBadRequest, operation)
+}
+
+func getOpenAccessReview(tx data.ReadTxn, id uid.ID) (*models.AccessReview, error) {
+	review, err := data.GetAccessReview(tx, data.GetAccessReviewOptions{ByID: id})
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != models.AccessReviewStatusOpen {
+		return nil, fmt.Errorf("%w: access review is %v", internal.ErrBadRequest, review.Status)
+	}
+	return review, nil
+}
+
+// AccessReviewEvidence is an access review, all of its items, and the names of
+// the users and groups referenced by the items.
+type AccessReviewEvidence struct {
+	AccessReview *models.AccessReview
+	Items        []models.AccessReviewItem
+	// Names maps the subjects of the items, and the users who reviewed them,
+	// to their names. Users and groups that have been deleted are not included.
+	Names map[uid.PolymorphicID]string
+}
+
+// ExportAccessReview returns the evidence of an access review.
+func ExportAccessReview(c *gin.Context, id uid.ID) (*AccessReviewEvidence, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access review", "export", roles...)
+	}
+
+	review, err := data.GetAccessReview(db, data.GetAccessReviewOptions{ByID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := data.ListAccessReviewItems(db, data.ListAccessReviewItemsOptions{ByAccessReviewID: review.ID})
+	if err != nil {
+		return nil, fmt.Errorf("list review items: %w", err)
+	}
+
+	userIDs := map[uid.ID]bool{}
+	groupIDs := map[uid.ID]bool{}
+	for _, item := range items {
+		userIDs[item.ReviewerID] = true
+		if item.DecidedBy != 0 {
+			userIDs[item.DecidedBy] = true
+		}
+		id, err := item.Subject.ID()
+		switch {
+		case err != nil:
+		case item.Subject.IsIdentity():
+			userIDs[id] = true
+		case item.Subject.IsGroup():
+			groupIDs[id] = true
+		}
+	}
+
+	names := map[uid.PolymorphicID]string{}
+	if len(userIDs) > 0 {
+		users, err := data.ListIdentities(db, data.ListIdentityOptions{ByIDs: mapKeys(userIDs)})
+		if err != nil {
+			return nil, fmt.Errorf("list users: %w", err)
+		}
+		for _, user := range users {
+			names[uid.NewIdentityPolymorphicID(user.ID)] = user.Name
+		}
+	}
+	if len(groupIDs) > 0 {
+		groups, err := data.ListGroups(db, data.ListGroupsOptions{ByIDs: mapKeys(groupIDs)})
+		if err != nil {
+			return nil, fmt.Errorf("list groups: %w", err)
+		}
+		for _, group := range groups {
+			names[uid.NewGroupPolymorphicID(group.ID)] = group.Name
+		}
+	}
+
+	return &AccessReviewEvidence{AccessReview: review, Items: items, Names: names}, nil
+}
+
+func mapKeys(m map[uid.ID]bool) []uid.ID {
+	result := make([]uid.ID, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
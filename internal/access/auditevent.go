@@ -0,0 +1,18 @@
+package access
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func ListAuditEvents(c *gin.Context, opts data.ListAuditEventsOptions) ([]models.AuditEvent, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "audit events", "list", roles...)
+	}
+
+	return data.ListAuditEvents(db, opts)
+}
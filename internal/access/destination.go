@@ -8,20 +8,46 @@ import (
 	"github.com/infrahq/infra/uid"
 )
 
+// destinationsWrite returns the permission to create, update, or delete the
+// destination with name.
+func destinationsWrite(name string) models.InfraPermission {
+	return models.InfraPermission{
+		Resource:    models.PermissionResourceDestinations,
+		Action:      models.PermissionActionWrite,
+		Destination: name,
+	}
+}
+
 func CreateDestination(c *gin.Context, destination *models.Destination) error {
 	roles := []string{models.InfraAdminRole, models.InfraConnectorRole}
-	db, err := RequireInfraRole(c, roles...)
+	perm := destinationsWrite(destination.Name)
+	db, err := RequirePermission(c, perm, roles...)
 	if err != nil {
-		return HandleAuthErr(err, "destination", "create", roles...)
+		return HandlePermissionErr(err, "destination", "create", perm, roles...)
 	}
 
 	return data.CreateDestination(db, destination)
 }
 
+// UpdateDestination updates the destination. The caller must be permitted to
+// write the destination with its stored name, and with the new name when the
+// update renames it.
 func UpdateDestination(rCtx RequestContext, destination *models.Destination) error {
+	stored, err := data.GetDestination(rCtx.DBTxn, data.GetDestinationOptions{ByID: destination.ID})
+	if err != nil {
+		return err
+	}
+
 	roles := []string{models.InfraAdminRole, models.InfraConnectorRole}
-	if err := IsAuthorized(rCtx, roles...); err != nil {
-		return HandleAuthErr(err, "destination", "update", roles...)
+	names := []string{stored.Name}
+	if destination.Name != stored.Name {
+		names = append(names, destination.Name)
+	}
+	for _, name := range names {
+		perm := destinationsWrite(name)
+		if err := IsPermitted(rCtx, perm, roles...); err != nil {
+			return HandlePermissionErr(err, "destination", "update", perm, roles...)
+		}
 	}
 
 	return data.UpdateDestination(rCtx.DBTxn, destination)
@@ -38,10 +64,16 @@ func ListDestinations(c *gin.Context, opts data.ListDestinationsOptions) ([]mode
 }
 
 func DeleteDestination(c *gin.Context, id uid.ID) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	rCtx := GetRequestContext(c)
+	destination, err := data.GetDestination(rCtx.DBTxn, data.GetDestinationOptions{ByID: id})
 	if err != nil {
-		return HandleAuthErr(err, "destination", "delete", models.InfraAdminRole)
+		return err
+	}
+
+	perm := destinationsWrite(destination.Name)
+	if err := IsPermitted(rCtx, perm, models.InfraAdminRole); err != nil {
+		return HandlePermissionErr(err, "destination", "delete", perm, models.InfraAdminRole)
 	}
 
-	return data.DeleteDestination(db, id)
+	return data.DeleteDestination(rCtx.DBTxn, id)
 }
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data"
@@ -17,12 +19,41 @@ import (
 )
 
 func GetGrant(c *gin.Context, id uid.ID) (*models.Grant, error) {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	rCtx := GetRequestContext(c)
+	grant, err := data.GetGrant(rCtx.DBTxn, data.GetGrantOptions{ByID: id})
 	if err != nil {
-		return nil, HandleAuthErr(err, "grant", "get", models.InfraAdminRole)
+		return nil, err
+	}
+
+	perm := grantsPermission(models.PermissionActionRead, grant.Resource)
+	if err := IsPermitted(rCtx, perm, models.InfraAdminRole); err != nil {
+		return nil, HandlePermissionErr(err, "grant", "get", perm, models.InfraAdminRole)
 	}
+	return grant, nil
+}
 
-	return data.GetGrant(db, data.GetGrantOptions{ByID: id})
+// grantsPermission returns the permission to perform action on the grants of
+// resource.
+func grantsPermission(action string, resource string) models.InfraPermission {
+	perm := models.InfraPermission{Resource: models.PermissionResourceGrants, Action: action}
+	if name, _, _ := strings.Cut(resource, "."); name != ResourceInfraAPI {
+		perm.Destination = name
+	}
+	return perm
+}
+
+// authorizeGrantWrite checks that the request has permission to create or
+// delete a grant on resource. Grants of the infra resource change the
+// permissions of users in the infra API, so they require the admin role.
+func authorizeGrantWrite(rCtx RequestContext, resource, operation string) error {
+	if name, _, _ := strings.Cut(resource, "."); name == ResourceInfraAPI {
+		err := IsAuthorized(rCtx, models.InfraAdminRole)
+		return HandleAuthErr(err, "grant", operation, models.InfraAdminRole)
+	}
+
+	perm := grantsPermission(models.PermissionActionWrite, resource)
+	err := IsPermitted(rCtx, perm, models.InfraAdminRole)
+	return HandlePermissionErr(err, "grant", operation, perm, models.InfraAdminRole)
 }
 
 type ListGrantsResponse struct {
@@ -34,9 +65,15 @@ func ListGrants(c *gin.Context, opts data.ListGrantsOptions, lastUpdateIndex int
 	rCtx := GetRequestContext(c)
 	subject := opts.BySubject
 
+	resource := opts.ByDestination
+	if resource == "" {
+		resource = opts.ByResource
+	}
+	perm := grantsPermission(models.PermissionActionRead, resource)
+
 	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
-	_, err := RequireInfraRole(c, roles...)
-	err = HandleAuthErr(err, "grants", "list", roles...)
+	err := IsPermitted(rCtx, perm, roles...)
+	err = HandlePermissionErr(err, "grants", "list", perm, roles...)
 	if errors.Is(err, ErrNotAuthorized) {
 		// Allow an authenticated identity to view their own grants
 		subjectID, _ := subject.ID() // zero value will never match a user
@@ -133,6 +170,90 @@ func listGrantsWithMaxUpdateIndex(rCtx RequestContext, opts data.ListGrantsOptio
 	return ListGrantsResponse{Grants: result, MaxUpdateIndex: maxUpdateIndex}, err
 }
 
+// GrantsWatcher returns the changes to the grants of a destination as they
+// happen. Use WatchGrants to create a GrantsWatcher.
+type GrantsWatcher struct {
+	rCtx        RequestContext
+	destination string
+	listener    *data.Listener
+}
+
+// WatchGrants starts listening for changes to the grants of destination. The
+// request scoped transaction is closed, because the watcher is expected to run
+// for much longer than a regular request.
+//
+// If error is nil the caller must call GrantsWatcher.Release.
+func WatchGrants(c *gin.Context, destination string) (*GrantsWatcher, error) {
+	rCtx := GetRequestContext(c)
+
+	perm := grantsPermission(models.PermissionActionRead, destination)
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	if err := IsPermitted(rCtx, perm, roles...); err != nil {
+		return nil, HandlePermissionErr(err, "grants", "watch", perm, roles...)
+	}
+
+	// Close the request scoped txn to avoid long-running transactions.
+	if err := rCtx.DBTxn.Rollback(); err != nil {
+		return nil, err
+	}
+
+	listenOpts := data.ListenForGrantsOptions{
+		ByDestination: destination,
+		OrgID:         rCtx.DBTxn.OrganizationID(),
+	}
+	listener, err := data.ListenForGrantsNotify(rCtx.Request.Context(), rCtx.DataDB, listenOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listen for notify: %w", err)
+	}
+	return &GrantsWatcher{rCtx: rCtx, destination: destination, listener: listener}, nil
+}
+
+// Next returns the grants that were created or deleted after lastUpdateIndex.
+// If there are no changes, Next blocks until there are changes, or until ctx
+// is done.
+func (w *GrantsWatcher) Next(ctx context.Context, lastUpdateIndex int64) ([]models.Grant, error) {
+	for {
+		// The listener was started before the query, so any change committed
+		// after the query is still delivered as a notification.
+		changes, err := w.listChanges(ctx, lastUpdateIndex)
+		if err != nil || len(changes) > 0 {
+			return changes, err
+		}
+
+		if err := w.listener.WaitForNotification(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (w *GrantsWatcher) listChanges(ctx context.Context, lastUpdateIndex int64) ([]models.Grant, error) {
+	tx, err := w.rCtx.DataDB.Begin(ctx, &sql.TxOptions{
+		ReadOnly:  true,
+		Isolation: sql.LevelRepeatableRead,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer logError(tx.Rollback, "failed to rollback transaction")
+	tx = tx.WithOrgID(w.rCtx.DBTxn.OrganizationID())
+
+	return data.ListGrantChanges(tx, data.ListGrantChangesOptions{
+		ByDestination:    w.destination,
+		SinceUpdateIndex: lastUpdateIndex,
+	})
+}
+
+// Release returns the database connection used by the watcher to the pool.
+func (w *GrantsWatcher) Release() {
+	// use a context with a separate deadline so that we still release
+	// when the request timeout is reached
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := w.listener.Release(ctx); err != nil {
+		logging.L.Error().Err(err).Msg("failed to release listener conn")
+	}
+}
+
 func logError(fn func() error, msg string) {
 	if err := fn(); err != nil {
 		logging.L.Warn().Err(err).Msg(msg)
@@ -153,18 +274,26 @@ func userInGroup(db data.ReadTxn, authnUserID uid.ID, groupID uid.ID) bool {
 	return false
 }
 
-func CreateGrant(c *gin.Context, grant *models.Grant) error {
+// CreateGrant creates the grant. The privilege must be a role reported by the
+// destination of the grant, unless force is true. Destinations only report
+// some of their roles, so force allows granting any other role.
+func CreateGrant(c *gin.Context, grant *models.Grant, force bool) error {
 	rCtx := GetRequestContext(c)
 
-	var err error
 	if grant.Privilege == models.InfraSupportAdminRole && grant.Resource == ResourceInfraAPI {
-		_, err = RequireInfraRole(c, models.InfraSupportAdminRole)
+		if _, err := RequireInfraRole(c, models.InfraSupportAdminRole); err != nil {
+			return HandleAuthErr(err, "grant", "create", grant.Privilege)
+		}
 	} else {
-		_, err = RequireInfraRole(c, models.InfraAdminRole)
+		if err := authorizeGrantWrite(rCtx, grant.Resource, "create"); err != nil {
+			return err
+		}
 	}
 
-	if err != nil {
-		return HandleAuthErr(err, "grant", "create", grant.Privilege)
+	if !force {
+		if err := checkDestinationPrivilege(rCtx.DBTxn, grant.Resource, grant.Privilege); err != nil {
+			return err
+		}
 	}
 
 	// TODO: CreatedBy should be set automatically
@@ -174,19 +303,87 @@ func CreateGrant(c *gin.Context, grant *models.Grant) error {
 }
 
 func DeleteGrant(c *gin.Context, id uid.ID) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	rCtx := GetRequestContext(c)
+	grant, err := data.GetGrant(rCtx.DBTxn, data.GetGrantOptions{ByID: id})
 	if err != nil {
-		return HandleAuthErr(err, "grant", "delete", models.InfraAdminRole)
+		return err
 	}
 
-	return data.DeleteGrants(db, data.DeleteGrantsOptions{ByID: id})
+	if err := authorizeGrantWrite(rCtx, grant.Resource, "delete"); err != nil {
+		return err
+	}
+
+	return data.DeleteGrants(rCtx.DBTxn, data.DeleteGrantsOptions{ByID: id})
 }
 
 func UpdateGrants(c *gin.Context, addGrants, rmGrants []*models.Grant) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
-	if err != nil {
-		return HandleAuthErr(err, "grant", "update", "")
+	rCtx := GetRequestContext(c)
+	db := rCtx.DBTxn
+
+	checked := map[string]bool{}
+	for _, grant := range append(append([]*models.Grant{}, addGrants...), rmGrants...) {
+		name, _, _ := strings.Cut(grant.Resource, ".")
+		if checked[name] {
+			continue
+		}
+		if err := authorizeGrantWrite(rCtx, grant.Resource, "update"); err != nil {
+			return err
+		}
+		checked[name] = true
+	}
+
+	for _, grant := range addGrants {
+		if err := checkDestinationPrivilege(db, grant.Resource, grant.Privilege); err != nil {
+			return err
+		}
 	}
 
 	return data.UpdateGrants(db, addGrants, rmGrants)
 }
+
+// checkDestinationPrivilege returns an error if privilege is not one of the
+// roles reported by the destination of resource. A namespaced role is reported
+// by the destination as namespace/role, and can only be granted on that
+// namespace. Grants for destinations that have not connected yet, or that
+// do not report any roles, are not checked.
+func checkDestinationPrivilege(tx data.ReadTxn, resource, privilege string) error {
+	if privilege == models.BasePermissionConnect {
+		return nil
+	}
+
+	name, namespace, _ := strings.Cut(resource, ".")
+	if name == ResourceInfraAPI {
+		return nil
+	}
+
+	if api.IsInfraPermission(privilege) {
+		if !isScopedPermission(privilege) {
+			return fmt.Errorf("%w: permission %q can only be granted on %q", internal.ErrBadRequest, privilege, ResourceInfraAPI)
+		}
+		return nil
+	}
+
+	destinations, err := data.ListDestinations(tx, data.ListDestinationsOptions{ByName: name})
+	if err != nil {
+		return fmt.Errorf("list destinations: %w", err)
+	}
+	if len(destinations) == 0 || len(destinations[0].Roles) == 0 {
+		return nil
+	}
+
+	// custom roles are defined by the organization, not by the destination
+	_, err = data.GetRole(tx, data.GetRoleOptions{ByName: privilege})
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, internal.ErrNotFound):
+		return fmt.Errorf("get role: %w", err)
+	}
+
+	for _, role := range destinations[0].Roles {
+		if role == privilege || (namespace != "" && role == namespace+"/"+privilege) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: role %q does not exist on destination %q", internal.ErrBadRequest, privilege, name)
+}
@@ -13,6 +13,11 @@ import (
 	"github.com/infrahq/infra/uid"
 )
 
+var (
+	groupsRead  = models.InfraPermission{Resource: models.PermissionResourceGroups, Action: models.PermissionActionRead}
+	groupsWrite = models.InfraPermission{Resource: models.PermissionResourceGroups, Action: models.PermissionActionWrite}
+)
+
 func ListGroups(c *gin.Context, name string, userID uid.ID, p *data.Pagination) ([]models.Group, error) {
 	rCtx := GetRequestContext(c)
 
@@ -23,11 +28,11 @@ func ListGroups(c *gin.Context, name string, userID uid.ID, p *data.Pagination)
 	}
 
 	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
-	_, err := RequireInfraRole(c, roles...)
+	err := IsPermitted(rCtx, groupsRead, roles...)
 	if err == nil {
 		return data.ListGroups(rCtx.DBTxn, opts)
 	}
-	err = HandleAuthErr(err, "groups", "list", roles...)
+	err = HandlePermissionErr(err, "groups", "list", groupsRead, roles...)
 
 	if errors.Is(err, ErrNotAuthorized) {
 		// Allow an authenticated identity to view their own groups
@@ -44,9 +49,9 @@ func ListGroups(c *gin.Context, name string, userID uid.ID, p *data.Pagination)
 }
 
 func CreateGroup(c *gin.Context, group *models.Group) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	db, err := RequirePermission(c, groupsWrite, models.InfraAdminRole)
 	if err != nil {
-		return HandleAuthErr(err, "group", "create", models.InfraAdminRole)
+		return HandlePermissionErr(err, "group", "create", groupsWrite, models.InfraAdminRole)
 	}
 
 	return data.CreateGroup(db, group)
@@ -55,8 +60,8 @@ func CreateGroup(c *gin.Context, group *models.Group) error {
 func GetGroup(c *gin.Context, opts data.GetGroupOptions) (*models.Group, error) {
 	rCtx := GetRequestContext(c)
 	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
-	_, err := RequireInfraRole(c, roles...)
-	err = HandleAuthErr(err, "group", "get", roles...)
+	err := IsPermitted(rCtx, groupsRead, roles...)
+	err = HandlePermissionErr(err, "group", "get", groupsRead, roles...)
 	if errors.Is(err, ErrNotAuthorized) {
 		// get the group, but only to check if the user is in it
 		group, err := data.GetGroup(rCtx.DBTxn, opts)
@@ -74,10 +79,16 @@ func GetGroup(c *gin.Context, opts data.GetGroupOptions) (*models.Group, error)
 }
 
 func DeleteGroup(c *gin.Context, id uid.ID) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	db, err := RequirePermission(c, groupsWrite, models.InfraAdminRole)
+	if err != nil {
+		return HandlePermissionErr(err, "group", "delete", groupsWrite, models.InfraAdminRole)
+	}
+
+	group, err := data.GetGroup(db, data.GetGroupOptions{ByID: id})
 	if err != nil {
-		return HandleAuthErr(err, "group", "delete", models.InfraAdminRole)
+		return err
 	}
+	GetRequestContext(c).Response.SetAuditBefore(group.ToAPI())
 	return data.DeleteGroup(db, id)
 }
 
@@ -112,16 +123,43 @@ func checkIdentitiesInList(db data.ReadTxn, ids []uid.ID) ([]uid.ID, error) {
 	return nil, fmt.Errorf("%w: %s", internal.ErrBadRequest, "Couldn't find UIDs: "+strings.Join(uidStrList, ","))
 }
 
+// requireAdminForInfraGroup returns an error unless the user is an infra admin
+// when the group, or any group it is nested in, has a grant on the infra
+// resource. Changing the members of those groups changes who has access to
+// infra, so the groups:write permission is not sufficient.
+func requireAdminForInfraGroup(rCtx RequestContext, groupID uid.ID) error {
+	grants, err := data.ListGrants(rCtx.DBTxn, data.ListGrantsOptions{
+		BySubject:                  uid.NewGroupPolymorphicID(groupID),
+		IncludeInheritedFromGroups: true,
+		ByResource:                 ResourceInfraAPI,
+		Pagination:                 &data.Pagination{Limit: 1},
+	})
+	if err != nil {
+		return fmt.Errorf("list infra grants: %w", err)
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+	err = IsAuthorized(rCtx, models.InfraAdminRole)
+	return HandleAuthErr(err, "group", "update", models.InfraAdminRole)
+}
+
 func UpdateUsersInGroup(c *gin.Context, groupID uid.ID, uidsToAdd []uid.ID, uidsToRemove []uid.ID) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	db, err := RequirePermission(c, groupsWrite, models.InfraAdminRole)
 	if err != nil {
-		return err
+		return HandlePermissionErr(err, "group", "update", groupsWrite, models.InfraAdminRole)
 	}
 
-	_, err = data.GetGroup(db, data.GetGroupOptions{ByID: groupID})
+	group, err := data.GetGroup(db, data.GetGroupOptions{ByID: groupID})
 	if err != nil {
 		return err
 	}
+	rCtx := GetRequestContext(c)
+	rCtx.Response.SetAuditBefore(group.ToAPI())
+
+	if err := requireAdminForInfraGroup(rCtx, groupID); err != nil {
+		return err
+	}
 
 	addIDList, err := checkIdentitiesInList(db, uidsToAdd)
 	if err != nil {
@@ -146,3 +184,79 @@ func UpdateUsersInGroup(c *gin.Context, groupID uid.ID, uidsToAdd []uid.ID, uids
 	}
 	return nil
 }
+
+// checkGroupsInList returns an error if any of the ids is not the ID of a group.
+func checkGroupsInList(db data.ReadTxn, ids []uid.ID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	groups, err := data.ListGroups(db, data.ListGroupsOptions{ByIDs: ids})
+	if err != nil {
+		return err
+	}
+	if len(groups) == len(ids) {
+		return nil
+	}
+
+	found := make(map[uid.ID]bool, len(groups))
+	for _, group := range groups {
+		found[group.ID] = true
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id.String())
+		}
+	}
+	return fmt.Errorf("%w: %s", internal.ErrBadRequest, "Couldn't find group IDs: "+strings.Join(missing, ","))
+}
+
+func UpdateGroupsInGroup(c *gin.Context, groupID uid.ID, idsToAdd []uid.ID, idsToRemove []uid.ID) error {
+	db, err := RequirePermission(c, groupsWrite, models.InfraAdminRole)
+	if err != nil {
+		return HandlePermissionErr(err, "group", "update", groupsWrite, models.InfraAdminRole)
+	}
+
+	group, err := data.GetGroup(db, data.GetGroupOptions{ByID: groupID})
+	if err != nil {
+		return err
+	}
+	rCtx := GetRequestContext(c)
+	rCtx.Response.SetAuditBefore(group.ToAPI())
+
+	if err := requireAdminForInfraGroup(rCtx, groupID); err != nil {
+		return err
+	}
+
+	if err := checkGroupsInList(db, idsToAdd); err != nil {
+		return err
+	}
+
+	if len(idsToAdd) > 0 {
+		if err := data.AddGroupsToGroup(db, groupID, idsToAdd); err != nil {
+			return err
+		}
+	}
+
+	if len(idsToRemove) > 0 {
+		if err := data.RemoveGroupsFromGroup(db, groupID, idsToRemove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListNestedGroups returns the groups nested in the group, at any level of
+// nesting.
+func ListNestedGroups(c *gin.Context, groupID uid.ID) ([]models.Group, error) {
+	// connectors list nested groups to bind the grants of a group to the
+	// groups nested in it
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	db, err := RequirePermission(c, groupsRead, roles...)
+	if err != nil {
+		return nil, HandlePermissionErr(err, "groups", "list", groupsRead, roles...)
+	}
+
+	return data.ListGroups(db, data.ListGroupsOptions{ByNestedInGroup: groupID})
+}
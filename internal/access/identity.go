@@ -14,6 +14,11 @@ import (
 	"github.com/infrahq/infra/uid"
 )
 
+var (
+	usersRead  = models.InfraPermission{Resource: models.PermissionResourceUsers, Action: models.PermissionActionRead}
+	usersWrite = models.InfraPermission{Resource: models.PermissionResourceUsers, Action: models.PermissionActionWrite}
+)
+
 // isIdentitySelf is used by authorization checks to see if the calling identity is requesting their own attributes
 func isIdentitySelf(rCtx RequestContext, opts data.GetIdentityOptions) bool {
 	identity := rCtx.Authenticated.User
@@ -37,9 +42,9 @@ func GetIdentity(c *gin.Context, opts data.GetIdentityOptions) (*models.Identity
 	// anyone can get their own user data
 	if !isIdentitySelf(rCtx, opts) {
 		roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
-		err := IsAuthorized(rCtx, roles...)
+		err := IsPermitted(rCtx, usersRead, roles...)
 		if err != nil {
-			return nil, HandleAuthErr(err, "user", "get", roles...)
+			return nil, HandlePermissionErr(err, "user", "get", usersRead, roles...)
 		}
 	}
 
@@ -47,9 +52,9 @@ func GetIdentity(c *gin.Context, opts data.GetIdentityOptions) (*models.Identity
 }
 
 func CreateIdentity(c *gin.Context, identity *models.Identity) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	db, err := RequirePermission(c, usersWrite, models.InfraAdminRole)
 	if err != nil {
-		return HandleAuthErr(err, "user", "create", models.InfraAdminRole)
+		return HandlePermissionErr(err, "user", "create", usersWrite, models.InfraAdminRole)
 	}
 
 	return data.CreateIdentity(db, identity)
@@ -65,9 +70,19 @@ func DeleteIdentity(c *gin.Context, id uid.ID) error {
 		return fmt.Errorf("%w: the connector user can not be deleted", internal.ErrBadRequest)
 	}
 
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	db, err := RequirePermission(c, usersWrite, models.InfraAdminRole)
 	if err != nil {
-		return HandleAuthErr(err, "user", "delete", models.InfraAdminRole)
+		return HandlePermissionErr(err, "user", "delete", usersWrite, models.InfraAdminRole)
+	}
+
+	identity, err := data.GetIdentity(db, data.GetIdentityOptions{ByID: id})
+	if err != nil {
+		return err
+	}
+	rCtx.Response.SetAuditBefore(identity.ToAPI())
+
+	if err := checkDeleteInfraIdentity(rCtx, id); err != nil {
+		return err
 	}
 
 	opts := data.DeleteIdentitiesOptions{
@@ -77,11 +92,42 @@ func DeleteIdentity(c *gin.Context, id uid.ID) error {
 	return data.DeleteIdentities(db, opts)
 }
 
+// checkDeleteInfraIdentity returns an error if the identity with ID identityID
+// has a grant on the infra resource, directly or from a group, and the user in
+// rCtx is not an infra admin. The users:write permission is not enough to
+// delete infra admins. Deleting the identity deletes its grants, so an error is
+// also returned if it has the last infra admin grant.
+func checkDeleteInfraIdentity(rCtx RequestContext, identityID uid.ID) error {
+	subject := uid.NewIdentityPolymorphicID(identityID)
+	grants, err := data.ListGrants(rCtx.DBTxn, data.ListGrantsOptions{
+		BySubject:                  subject,
+		IncludeInheritedFromGroups: true,
+		ByResource:                 ResourceInfraAPI,
+	})
+	if err != nil {
+		return fmt.Errorf("list infra grants: %w", err)
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+	if err := IsAuthorized(rCtx, models.InfraAdminRole); err != nil {
+		return HandleAuthErr(err, "user", "delete", models.InfraAdminRole)
+	}
+
+	var deleted []uid.ID
+	for _, grant := range grants {
+		if grant.Subject == subject {
+			deleted = append(deleted, grant.ID)
+		}
+	}
+	return checkLastInfraAdminGrant(rCtx.DBTxn, deleted, "deleting the user")
+}
+
 func ListIdentities(c *gin.Context, name string, groupID uid.ID, ids []uid.ID, showSystem bool, p *data.Pagination) ([]models.Identity, error) {
 	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
-	db, err := RequireInfraRole(c, roles...)
+	db, err := RequirePermission(c, usersRead, roles...)
 	if err != nil {
-		return nil, HandleAuthErr(err, "users", "list", roles...)
+		return nil, HandlePermissionErr(err, "users", "list", usersRead, roles...)
 	}
 
 	opts := data.ListIdentityOptions{
@@ -0,0 +1,117 @@
+package access
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// StartTOTPEnrollment generates a new TOTP secret for the user. The secret is
+// not used for login until the enrollment is confirmed with a valid code.
+func StartTOTPEnrollment(c *gin.Context, userID uid.ID) (string, error) {
+	rCtx := GetRequestContext(c)
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		return "", fmt.Errorf("%w: users can only enroll in mfa for themselves", internal.ErrBadRequest)
+	}
+
+	credential, err := data.GetCredentialByUserID(rCtx.DBTxn, userID)
+	if err != nil {
+		return "", fmt.Errorf("existing credential: %w", err)
+	}
+
+	if credential.TOTPEnabled {
+		return "", fmt.Errorf("%w: mfa is already enabled for this user", internal.ErrBadRequest)
+	}
+
+	secret, err := authn.GenerateTOTPSecret()
+	if err != nil {
+		return "", fmt.Errorf("generate totp secret: %w", err)
+	}
+
+	credential.TOTPSecret = models.EncryptedAtRest(secret)
+	if err := data.UpdateCredential(rCtx.DBTxn, credential); err != nil {
+		return "", fmt.Errorf("saving credentials: %w", err)
+	}
+	return secret, nil
+}
+
+// ConfirmTOTPEnrollment enables TOTP for the user once they have shown they
+// can generate a valid code. It returns the recovery codes for the user.
+func ConfirmTOTPEnrollment(c *gin.Context, userID uid.ID, code string) ([]string, error) {
+	rCtx := GetRequestContext(c)
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		return nil, fmt.Errorf("%w: users can only enroll in mfa for themselves", internal.ErrBadRequest)
+	}
+
+	db := rCtx.DBTxn
+	credential, err := data.GetCredentialByUserID(db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("existing credential: %w", err)
+	}
+
+	switch {
+	case credential.TOTPEnabled:
+		return nil, fmt.Errorf("%w: mfa is already enabled for this user", internal.ErrBadRequest)
+	case credential.TOTPSecret == "":
+		return nil, fmt.Errorf("%w: mfa enrollment has not been started", internal.ErrBadRequest)
+	}
+
+	counter, ok := authn.ValidateTOTP(string(credential.TOTPSecret), code, time.Now(), credential.TOTPLastCounter)
+	if !ok {
+		return nil, validate.Error{"code": []string{"invalid code"}}
+	}
+
+	codes, hashes, err := authn.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("generate recovery codes: %w", err)
+	}
+
+	credential.TOTPEnabled = true
+	credential.TOTPLastCounter = counter
+	credential.RecoveryCodes = hashes
+	if err := data.UpdateCredential(db, credential); err != nil {
+		return nil, fmt.Errorf("saving credentials: %w", err)
+	}
+
+	// remove the mfa-enrollment scope from our access key, now that we are enrolled
+	if accessKey := rCtx.Authenticated.AccessKey; accessKey != nil && accessKey.Scopes.Includes(models.ScopeMFAEnrollment) {
+		accessKey.Scopes = sliceWithoutElement(accessKey.Scopes, models.ScopeMFAEnrollment)
+		if err = data.UpdateAccessKey(db, accessKey); err != nil {
+			return nil, fmt.Errorf("updating access key: %w", err)
+		}
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the TOTP secret and recovery codes for the user. Users
+// can disable their own MFA, and admins can disable MFA for a user who has
+// lost their device.
+func DisableTOTP(c *gin.Context, userID uid.ID) error {
+	rCtx := GetRequestContext(c)
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		if err := IsAuthorized(rCtx, models.InfraAdminRole); err != nil {
+			return HandleAuthErr(err, "user", "update", models.InfraAdminRole)
+		}
+	}
+
+	credential, err := data.GetCredentialByUserID(rCtx.DBTxn, userID)
+	if err != nil {
+		return fmt.Errorf("existing credential: %w", err)
+	}
+
+	credential.TOTPSecret = ""
+	credential.TOTPEnabled = false
+	credential.RecoveryCodes = nil
+	if err := data.UpdateCredential(rCtx.DBTxn, credential); err != nil {
+		return fmt.Errorf("saving credentials: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// RequirePermission checks that the identity in the context has one of the
+// infra roles, or has been granted the permission.
+func RequirePermission(c *gin.Context, perm models.InfraPermission, oneOfRoles ...string) (*data.Transaction, error) {
+	rCtx := GetRequestContext(c)
+	if err := IsPermitted(rCtx, perm, oneOfRoles...); err != nil {
+		return nil, err
+	}
+	return rCtx.DBTxn, nil
+}
+
+// IsPermitted checks if the request has permission to perform the action. The
+// request has permission if the user is authorized by IsAuthorized with one of
+// the roles, or if the user or one of the groups they belong to has a grant of
+// the permission, or of a custom role that includes the permission. The grant
+// must be on the infra resource, or on perm.Destination when it is set.
+func IsPermitted(rCtx RequestContext, perm models.InfraPermission, oneOfRoles ...string) error {
+	err := IsAuthorized(rCtx, oneOfRoles...)
+	if !errors.Is(err, ErrNotAuthorized) {
+		return err
+	}
+
+	included := perm.Privileges()
+	privileges := included
+
+	roles, err := data.ListRoles(rCtx.DBTxn, data.ListRolesOptions{})
+	if err != nil {
+		return fmt.Errorf("list roles: %w", err)
+	}
+	for _, role := range roles {
+		if role.HasPermission(included...) {
+			privileges = append(privileges, role.Name)
+		}
+	}
+
+	resources := []string{ResourceInfraAPI}
+	if perm.Destination != "" {
+		resources = append(resources, perm.Destination)
+	}
+	for _, resource := range resources {
+		ok, err := hasInfraGrant(rCtx, privileges, resource)
+		if err != nil || ok {
+			return err
+		}
+	}
+	return ErrNotAuthorized
+}
+
+// HandlePermissionErr is HandleAuthErr for errors returned by RequirePermission.
+func HandlePermissionErr(err error, resource, operation string, perm models.InfraPermission, roles ...string) error {
+	if !errors.Is(err, ErrNotAuthorized) {
+		return err
+	}
+	required := perm.Name()
+	if perm.Destination != "" {
+		required += " on " + perm.Destination
+	}
+	return AuthorizationError{
+		Resource:           resource,
+		Operation:          operation,
+		RequiredRoles:      roles,
+		RequiredPermission: required,
+	}
+}
+
+// scopedPermissionResources are the types of resources that can be limited to a
+// single destination by granting the permission on the destination.
+var scopedPermissionResources = []string{
+	models.PermissionResourceDestinations,
+	models.PermissionResourceGrants,
+}
+
+// isScopedPermission returns true if the permission can be granted on a
+// destination.
+func isScopedPermission(name string) bool {
+	resource, _, _ := strings.Cut(name, ":")
+	for _, r := range scopedPermissionResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// ListUserPermissions returns the infra permissions of the user, from their
+// grants and the grants of the groups they belong to.
+func ListUserPermissions(c *gin.Context, userID uid.ID) ([]models.InfraPermission, error) {
+	rCtx := GetRequestContext(c)
+	// anyone can list their own permissions
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		perm := models.InfraPermission{Resource: models.PermissionResourceUsers, Action: models.PermissionActionRead}
+		roles := []string{models.InfraAdminRole, models.InfraViewRole}
+		if err := IsPermitted(rCtx, perm, roles...); err != nil {
+			return nil, HandlePermissionErr(err, "user permissions", "list", perm, roles...)
+		}
+	}
+
+	grants, err := data.ListGrants(rCtx.DBTxn, data.ListGrantsOptions{
+		BySubject:                  uid.NewIdentityPolymorphicID(userID),
+		IncludeInheritedFromGroups: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list grants: %w", err)
+	}
+
+	roles, err := data.ListRoles(rCtx.DBTxn, data.ListRolesOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	customRoles := make(map[string]models.Role, len(roles))
+	for _, role := range roles {
+		customRoles[role.Name] = role
+	}
+
+	// destinations of each permission, an empty destination is all destinations
+	permissions := map[string]map[string]bool{}
+	for _, grant := range grants {
+		destination, _, _ := strings.Cut(grant.Resource, ".")
+		if destination == ResourceInfraAPI {
+			destination = ""
+		}
+
+		var names []string
+		if destination == "" {
+			names = append(names, models.InfraPermissionsForRole(grant.Privilege)...)
+		}
+		if api.IsInfraPermission(grant.Privilege) {
+			names = append(names, grant.Privilege)
+		}
+		if role, ok := customRoles[grant.Privilege]; ok {
+			for _, p := range role.Permissions {
+				if api.IsInfraPermission(p) {
+					names = append(names, p)
+				}
+			}
+			if destination == "" {
+				for _, infraRole := range role.InfraRoles {
+					names = append(names, models.InfraPermissionsForRole(infraRole)...)
+				}
+			}
+		}
+
+		for _, name := range names {
+			if destination != "" && !isScopedPermission(name) {
+				continue
+			}
+			if permissions[name] == nil {
+				permissions[name] = map[string]bool{}
+			}
+			permissions[name][destination] = true
+		}
+	}
+
+	var result []models.InfraPermission
+	for name, destinations := range permissions {
+		resource, action, _ := strings.Cut(name, ":")
+		if destinations[""] {
+			// the permission applies to all destinations
+			result = append(result, models.InfraPermission{Resource: resource, Action: action})
+			continue
+		}
+		for destination := range destinations {
+			result = append(result, models.InfraPermission{Resource: resource, Action: action, Destination: destination})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name() != result[j].Name() {
+			return result[i].Name() < result[j].Name()
+		}
+		return result[i].Destination < result[j].Destination
+	})
+	return result, nil
+}
@@ -0,0 +1,138 @@
+package access
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestIsPermitted(t *testing.T) {
+	db := setupDB(t)
+	admin := &models.Identity{Name: "admin@example.com"}
+	assert.NilError(t, data.CreateIdentity(db, admin))
+
+	setup := func(t *testing.T, name string) RequestContext {
+		user := &models.Identity{Name: name}
+		assert.NilError(t, data.CreateIdentity(db, user))
+		return RequestContext{
+			DBTxn:         txnForTestCase(t, db),
+			Authenticated: Authenticated{User: user},
+		}
+	}
+
+	grantsWrite := func(destination string) models.InfraPermission {
+		return models.InfraPermission{
+			Resource:    models.PermissionResourceGrants,
+			Action:      models.PermissionActionWrite,
+			Destination: destination,
+		}
+	}
+
+	t.Run("with infra role", func(t *testing.T) {
+		rCtx := setup(t, "admin-role@example.com")
+		grant(t, rCtx.DBTxn, admin, rCtx.Authenticated.User.PolyID(), models.InfraAdminRole, ResourceInfraAPI)
+
+		err := IsPermitted(rCtx, grantsWrite("staging"), models.InfraAdminRole)
+		assert.NilError(t, err)
+	})
+
+	t.Run("permission granted on infra", func(t *testing.T) {
+		rCtx := setup(t, "all-grants@example.com")
+		grant(t, rCtx.DBTxn, admin, rCtx.Authenticated.User.PolyID(), "grants:write", ResourceInfraAPI)
+
+		assert.NilError(t, IsPermitted(rCtx, grantsWrite(""), models.InfraAdminRole))
+		assert.NilError(t, IsPermitted(rCtx, grantsWrite("staging"), models.InfraAdminRole))
+
+		// write includes read
+		read := models.InfraPermission{Resource: models.PermissionResourceGrants, Action: models.PermissionActionRead}
+		assert.NilError(t, IsPermitted(rCtx, read, models.InfraAdminRole))
+
+		usersWrite := models.InfraPermission{Resource: models.PermissionResourceUsers, Action: models.PermissionActionWrite}
+		err := IsPermitted(rCtx, usersWrite, models.InfraAdminRole)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+
+	t.Run("permission granted on a destination", func(t *testing.T) {
+		rCtx := setup(t, "staging-grants@example.com")
+		grant(t, rCtx.DBTxn, admin, rCtx.Authenticated.User.PolyID(), "grants:write", "staging")
+
+		assert.NilError(t, IsPermitted(rCtx, grantsWrite("staging"), models.InfraAdminRole))
+
+		err := IsPermitted(rCtx, grantsWrite("production"), models.InfraAdminRole)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+
+		err = IsPermitted(rCtx, grantsWrite(""), models.InfraAdminRole)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+
+	t.Run("permission included in a custom role", func(t *testing.T) {
+		rCtx := setup(t, "custom-role@example.com")
+		role := &models.Role{Name: "user-manager", Permissions: []string{"users:write"}}
+		assert.NilError(t, data.CreateRole(rCtx.DBTxn, role))
+		grant(t, rCtx.DBTxn, admin, rCtx.Authenticated.User.PolyID(), "user-manager", ResourceInfraAPI)
+
+		usersRead := models.InfraPermission{Resource: models.PermissionResourceUsers, Action: models.PermissionActionRead}
+		assert.NilError(t, IsPermitted(rCtx, usersRead, models.InfraAdminRole))
+
+		providersWrite := models.InfraPermission{Resource: models.PermissionResourceProviders, Action: models.PermissionActionWrite}
+		err := IsPermitted(rCtx, providersWrite, models.InfraAdminRole)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+	})
+}
+
+func TestListUserPermissions(t *testing.T) {
+	c, tx, _ := setupAccessTestContext(t)
+	admin := GetRequestContext(c).Authenticated.User
+
+	user := &models.Identity{Name: "delegate@example.com"}
+	assert.NilError(t, data.CreateIdentity(tx, user))
+
+	grant(t, tx, admin, user.PolyID(), models.InfraViewRole, ResourceInfraAPI)
+	grant(t, tx, admin, user.PolyID(), "grants:write", "staging.web")
+	grant(t, tx, admin, user.PolyID(), "grants:read", "production")
+	grant(t, tx, admin, user.PolyID(), "users:write", ResourceInfraAPI)
+	// users permissions can not be limited to a destination
+	grant(t, tx, admin, user.PolyID(), "users:write", "staging")
+
+	userCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	userCtx.Set(RequestContextKey, RequestContext{
+		DBTxn:         tx,
+		Authenticated: Authenticated{User: user},
+	})
+
+	actual, err := ListUserPermissions(userCtx, user.ID)
+	assert.NilError(t, err)
+
+	expected := []models.InfraPermission{
+		{Resource: "grants", Action: "read"},
+		{Resource: "grants", Action: "write", Destination: "staging"},
+		{Resource: "groups", Action: "read"},
+		{Resource: "users", Action: "read"},
+		{Resource: "users", Action: "write"},
+	}
+	assert.DeepEqual(t, actual, expected)
+
+	t.Run("permissions of another user", func(t *testing.T) {
+		other := &models.Identity{Name: "other@example.com"}
+		assert.NilError(t, data.CreateIdentity(tx, other))
+
+		otherCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		otherCtx.Set(RequestContextKey, RequestContext{
+			DBTxn:         tx,
+			Authenticated: Authenticated{User: other},
+		})
+
+		_, err := ListUserPermissions(otherCtx, user.ID)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+
+		// admins can list the permissions of any user
+		actual, err := ListUserPermissions(c, user.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, actual, expected)
+	})
+}
@@ -11,6 +11,12 @@ import (
 	"github.com/infrahq/infra/uid"
 )
 
+var providersWrite = models.InfraPermission{Resource: models.PermissionResourceProviders, Action: models.PermissionActionWrite}
+
+// CreateProvider requires the infra admin role, even when the user has the
+// providers:write permission. Users who log in with a provider are linked to
+// the existing user with the same name, so a user who can configure a provider
+// could log in as any user, including an infra admin.
 func CreateProvider(c *gin.Context, provider *models.Provider) error {
 	db, err := RequireInfraRole(c, models.InfraAdminRole)
 	if err != nil {
@@ -30,6 +36,8 @@ func ListProviders(c *gin.Context, opts data.ListProvidersOptions) ([]models.Pro
 	return data.ListProviders(rCtx.DBTxn, opts)
 }
 
+// SaveProvider requires the infra admin role for the same reason as
+// CreateProvider.
 func SaveProvider(c *gin.Context, provider *models.Provider) error {
 	db, err := RequireInfraRole(c, models.InfraAdminRole)
 	if err != nil {
@@ -43,9 +51,9 @@ func SaveProvider(c *gin.Context, provider *models.Provider) error {
 }
 
 func DeleteProvider(c *gin.Context, id uid.ID) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	db, err := RequirePermission(c, providersWrite, models.InfraAdminRole)
 	if err != nil {
-		return HandleAuthErr(err, "provider", "delete", models.InfraAdminRole)
+		return HandlePermissionErr(err, "provider", "delete", providersWrite, models.InfraAdminRole)
 	}
 	if data.InfraProvider(db).ID == id {
 		return fmt.Errorf("%w: the infra provider can not be deleted", internal.ErrBadRequest)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 const RequestContextKey = "requestContext"
@@ -23,6 +24,10 @@ type RequestContext struct {
 	// DataDB directly.
 	DataDB *data.DB
 
+	// RequestID uniquely identifies the request. It is included in the request
+	// log and in any audit events created by the request.
+	RequestID uid.ID
+
 	// Response is a mutable field. It can be modified by API handlers to add
 	// new response metadata.
 	Response *ResponseMetadata
@@ -42,6 +47,10 @@ type ResponseMetadata struct {
 	// logFields is a slice of function that can add fields to the API
 	// request log entry.
 	logFields []func(event *zerolog.Event)
+
+	// auditBefore is a summary of the resource before it was changed by the
+	// request. It is recorded in the audit event for the request.
+	auditBefore any
 }
 
 func (r *ResponseMetadata) AddLogFields(fn func(event *zerolog.Event)) {
@@ -59,3 +68,19 @@ func (r *ResponseMetadata) ApplyLogFields(event *zerolog.Event) {
 		fn(event)
 	}
 }
+
+// SetAuditBefore records the value of a resource before it is changed by the
+// request, so that it can be included in the audit event for the request.
+func (r *ResponseMetadata) SetAuditBefore(v any) {
+	if r == nil {
+		return
+	}
+	r.auditBefore = v
+}
+
+func (r *ResponseMetadata) AuditBefore() any {
+	if r == nil {
+		return nil
+	}
+	return r.auditBefore
+}
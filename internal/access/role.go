@@ -0,0 +1,65 @@
+package access
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateRole(c *gin.Context, role *models.Role) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "create", models.InfraAdminRole)
+	}
+
+	// a grant of the role is a grant of the name of the role, so the name must
+	// not already be granted with another meaning
+	grants, err := data.ListGrants(db, data.ListGrantsOptions{
+		ByPrivileges: []string{role.Name},
+		Pagination:   &data.Pagination{Limit: 1},
+	})
+	if err != nil {
+		return fmt.Errorf("list grants: %w", err)
+	}
+	if len(grants) > 0 {
+		return fmt.Errorf("%w: %q is the privilege of existing grants, and can not be used as the name of a role", internal.ErrBadRequest, role.Name)
+	}
+
+	role.CreatedBy = GetRequestContext(c).Authenticated.User.ID
+	return data.CreateRole(db, role)
+}
+
+func GetRole(c *gin.Context, id uid.ID) (*models.Role, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "role", "get", roles...)
+	}
+
+	return data.GetRole(db, data.GetRoleOptions{ByID: id})
+}
+
+func ListRoles(c *gin.Context, opts data.ListRolesOptions) ([]models.Role, error) {
+	// connectors list roles to expand the grants of a role into role bindings
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "roles", "list", roles...)
+	}
+
+	return data.ListRoles(db, opts)
+}
+
+func DeleteRole(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "delete", models.InfraAdminRole)
+	}
+
+	return data.DeleteRole(db, id)
+}
@@ -3,8 +3,10 @@ package access
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ssoroka/slice"
 
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/data"
@@ -107,6 +109,207 @@ func DeleteProviderUser(c *gin.Context, userID uid.ID) error {
 	return nil
 }
 
+func GetProviderGroup(c *gin.Context, id uid.ID) (*models.Group, error) {
+	ctx := GetRequestContext(c)
+	// restricted to only SCIM access keys
+	if err := checkKeyIdentityProvider(ctx); err != nil {
+		return nil, err
+	}
+	return getProvisionedGroup(ctx.DBTxn, ctx.Authenticated.AccessKey.IssuedFor, id)
+}
+
+func ListProviderGroups(c *gin.Context, p *data.SCIMParameters) ([]models.Group, error) {
+	ctx := GetRequestContext(c)
+	// restricted to only SCIM access keys
+	if err := checkKeyIdentityProvider(ctx); err != nil {
+		return nil, err
+	}
+	opts := data.ListGroupsOptions{
+		ByCreatedByProvider: ctx.Authenticated.AccessKey.IssuedFor,
+		SCIMParameters:      p,
+	}
+	groups, err := data.ListGroups(ctx.DBTxn, opts)
+	if err != nil {
+		return nil, fmt.Errorf("list provider groups: %w", err)
+	}
+	return groups, nil
+}
+
+// ListProviderGroupMembers returns the users that are members of a group
+// created by the provider of the SCIM access key.
+func ListProviderGroupMembers(c *gin.Context, groupID uid.ID) ([]models.Identity, error) {
+	ctx := GetRequestContext(c)
+	// restricted to only SCIM access keys
+	if err := checkKeyIdentityProvider(ctx); err != nil {
+		return nil, err
+	}
+	members, err := data.ListIdentities(ctx.DBTxn, data.ListIdentityOptions{ByGroupID: groupID})
+	if err != nil {
+		return nil, fmt.Errorf("list provider group members: %w", err)
+	}
+	return members, nil
+}
+
+func CreateProviderGroup(c *gin.Context, group *models.Group, memberIDs []uid.ID) error {
+	ctx := GetRequestContext(c)
+	// restricted to only SCIM access keys
+	if err := checkKeyIdentityProvider(ctx); err != nil {
+		return err
+	}
+	providerID := ctx.Authenticated.AccessKey.IssuedFor
+	if err := checkProviderGroupMembers(ctx.DBTxn, providerID, memberIDs); err != nil {
+		return err
+	}
+
+	group.CreatedByProvider = providerID
+	if err := data.CreateGroup(ctx.DBTxn, group); err != nil {
+		return fmt.Errorf("create provider group: %w", err)
+	}
+	if len(memberIDs) > 0 {
+		if err := data.AddUsersToGroup(ctx.DBTxn, group.ID, memberIDs); err != nil {
+			return fmt.Errorf("add provider group members: %w", err)
+		}
+	}
+	group.TotalUsers = len(memberIDs)
+	return nil
+}
+
+// UpdateProviderGroup replaces the name and the members of a group created by
+// the provider of the SCIM access key.
+func UpdateProviderGroup(c *gin.Context, group *models.Group, memberIDs []uid.ID) error {
+	ctx := GetRequestContext(c)
+	// restricted to only SCIM access keys
+	if err := checkKeyIdentityProvider(ctx); err != nil {
+		return err
+	}
+	providerID := ctx.Authenticated.AccessKey.IssuedFor
+	existing, err := getProvisionedGroup(ctx.DBTxn, providerID, group.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkProviderGroupMembers(ctx.DBTxn, providerID, memberIDs); err != nil {
+		return err
+	}
+
+	current, err := data.ListIdentities(ctx.DBTxn, data.ListIdentityOptions{ByGroupID: group.ID})
+	if err != nil {
+		return fmt.Errorf("list provider group members: %w", err)
+	}
+	currentIDs := make([]uid.ID, 0, len(current))
+	for _, member := range current {
+		currentIDs = append(currentIDs, member.ID)
+	}
+
+	existing.Name = group.Name
+	if err := updateProviderGroup(ctx.DBTxn, existing,
+		slice.Subtract(memberIDs, currentIDs), slice.Subtract(currentIDs, memberIDs)); err != nil {
+		return err
+	}
+	*group = *existing
+	return nil
+}
+
+// PatchProviderGroup renames a group created by the provider of the SCIM access
+// key when group.Name is set, and adds and removes members of the group.
+func PatchProviderGroup(c *gin.Context, group *models.Group, addIDs, removeIDs []uid.ID) (*models.Group, error) {
+	ctx := GetRequestContext(c)
+	// restricted to only SCIM access keys
+	if err := checkKeyIdentityProvider(ctx); err != nil {
+		return nil, err
+	}
+	providerID := ctx.Authenticated.AccessKey.IssuedFor
+	existing, err := getProvisionedGroup(ctx.DBTxn, providerID, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkProviderGroupMembers(ctx.DBTxn, providerID, addIDs); err != nil {
+		return nil, err
+	}
+
+	if group.Name != "" {
+		existing.Name = group.Name
+	}
+	if err := updateProviderGroup(ctx.DBTxn, existing, addIDs, removeIDs); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func DeleteProviderGroup(c *gin.Context, id uid.ID) error {
+	ctx := GetRequestContext(c)
+	// restricted to only SCIM access keys
+	if err := checkKeyIdentityProvider(ctx); err != nil {
+		return err
+	}
+	if _, err := getProvisionedGroup(ctx.DBTxn, ctx.Authenticated.AccessKey.IssuedFor, id); err != nil {
+		return err
+	}
+	if err := data.DeleteGroup(ctx.DBTxn, id); err != nil {
+		return fmt.Errorf("delete provider group: %w", err)
+	}
+	return nil
+}
+
+// getProvisionedGroup returns the group with id, but only if it was created by
+// the provider. Groups created by other providers or by users are not found.
+func getProvisionedGroup(tx data.ReadTxn, providerID, id uid.ID) (*models.Group, error) {
+	group, err := data.GetGroup(tx, data.GetGroupOptions{ByID: id})
+	if err != nil {
+		return nil, fmt.Errorf("get provider group: %w", err)
+	}
+	if group.CreatedByProvider != providerID {
+		return nil, fmt.Errorf("get provider group: %w", internal.ErrNotFound)
+	}
+	return group, nil
+}
+
+// checkProviderGroupMembers returns an error if any of the memberIDs are not
+// users provisioned by the provider.
+func checkProviderGroupMembers(tx data.ReadTxn, providerID uid.ID, memberIDs []uid.ID) error {
+	if len(memberIDs) == 0 {
+		return nil
+	}
+	users, err := data.ListProviderUsers(tx, data.ListProviderUsersOptions{
+		ByProviderID:  providerID,
+		ByIdentityIDs: memberIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("list provider group members: %w", err)
+	}
+
+	found := make(map[uid.ID]bool, len(users))
+	for _, user := range users {
+		found[user.IdentityID] = true
+	}
+	var missing []string
+	for _, id := range memberIDs {
+		if !found[id] {
+			missing = append(missing, id.String())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: unknown group members: %s", internal.ErrBadRequest, strings.Join(missing, ","))
+	}
+	return nil
+}
+
+func updateProviderGroup(tx data.WriteTxn, group *models.Group, addIDs, removeIDs []uid.ID) error {
+	if err := data.UpdateGroup(tx, group); err != nil {
+		return fmt.Errorf("update provider group: %w", err)
+	}
+	if len(addIDs) > 0 {
+		if err := data.AddUsersToGroup(tx, group.ID, addIDs); err != nil {
+			return fmt.Errorf("add provider group members: %w", err)
+		}
+	}
+	if len(removeIDs) > 0 {
+		if err := data.RemoveUsersFromGroup(tx, group.ID, removeIDs); err != nil {
+			return fmt.Errorf("remove provider group members: %w", err)
+		}
+	}
+	return nil
+}
+
 func checkKeyIdentityProvider(ctx RequestContext) error {
 	_, err := data.GetProvider(ctx.DBTxn,
 		data.GetProviderOptions{ByID: ctx.Authenticated.AccessKey.IssuedFor})
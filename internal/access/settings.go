@@ -10,18 +10,23 @@ import (
 	"github.com/infrahq/infra/internal/server/models"
 )
 
+// GetPublicJWK returns the public keys that verify the JWTs issued to users.
+// The active key is first, followed by any keys that were recently rotated.
 func GetPublicJWK(c RequestContext) ([]jose.JSONWebKey, error) {
-	settings, err := data.GetSettings(c.DBTxn)
+	keys, err := data.ListSigningKeys(c.DBTxn)
 	if err != nil {
 		return nil, fmt.Errorf("could not get JWKs: %w", err)
 	}
 
-	var pubKey jose.JSONWebKey
-	if err := pubKey.UnmarshalJSON(settings.PublicJWK); err != nil {
-		return nil, fmt.Errorf("could not get JWKs: %w", err)
+	result := make([]jose.JSONWebKey, 0, len(keys))
+	for _, key := range keys {
+		var pubKey jose.JSONWebKey
+		if err := pubKey.UnmarshalJSON(key.PublicJWK); err != nil {
+			return nil, fmt.Errorf("could not get JWKs: %w", err)
+		}
+		result = append(result, pubKey)
 	}
-
-	return []jose.JSONWebKey{pubKey}, nil
+	return result, nil
 }
 
 func GetSettings(c *gin.Context) (*models.Settings, error) {
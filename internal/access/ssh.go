@@ -0,0 +1,143 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/openssh"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// sshCertificateLifetime is the longest time a certificate for an SSH
+// destination is valid. Certificates are short-lived so that revoking a grant
+// quickly revokes access to the destination.
+const sshCertificateLifetime = time.Hour
+
+type SSHCertificate struct {
+	Certificate string
+	Username    string
+	ValidBefore time.Time
+}
+
+// CreateSSHCertificate signs a certificate that allows the authenticated user
+// to login to an SSH destination. The user must have a grant for one of the
+// SSH roles on the destination, or for a custom role with the connect
+// permission. The certificate expires before any of those grants.
+func CreateSSHCertificate(c *gin.Context, destinationName string, publicKey string) (*SSHCertificate, error) {
+	rCtx := GetRequestContext(c)
+	user := rCtx.Authenticated.User
+	if user == nil {
+		return nil, fmt.Errorf("%w: no authenticated user", ErrNotAuthorized)
+	}
+
+	destination, err := data.GetDestination(rCtx.DBTxn, data.GetDestinationOptions{ByName: destinationName})
+	if err != nil {
+		return nil, err
+	}
+	if destination.Kind != models.DestinationKindSSH {
+		return nil, fmt.Errorf("%w: %v is not an SSH destination", internal.ErrBadRequest, destinationName)
+	}
+
+	privileges, err := sshPrivileges(rCtx.DBTxn)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := data.ListGrants(rCtx.DBTxn, data.ListGrantsOptions{
+		BySubject:                  uid.NewIdentityPolymorphicID(user.ID),
+		ByDestination:              destinationName,
+		ByPrivileges:               privileges,
+		IncludeInheritedFromGroups: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(grants) == 0 {
+		return nil, fmt.Errorf("%w: no grants for destination %v", ErrNotAuthorized, destinationName)
+	}
+
+	validBefore := time.Now().Add(sshCertificateLifetime)
+	if expires, ok := latestGrantExpiry(grants); ok && expires.Before(validBefore) {
+		validBefore = expires
+	}
+
+	username := openssh.Username(user.ID, user.Name)
+	if username == "" {
+		return nil, fmt.Errorf("%w: user %v does not have a valid SSH username", internal.ErrBadRequest, user.Name)
+	}
+
+	settings, err := data.GetSSHCertificateAuthority(rCtx.DBTxn)
+	if err != nil {
+		return nil, fmt.Errorf("get certificate authority: %w", err)
+	}
+
+	cert, err := openssh.SignUserCertificate(string(settings.SSHCAPrivateKey), openssh.UserCertificateOptions{
+		PublicKey:   publicKey,
+		Username:    username,
+		KeyID:       user.Name,
+		ValidBefore: validBefore,
+	})
+	switch {
+	case errors.Is(err, openssh.ErrInvalidPublicKey):
+		return nil, fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	case err != nil:
+		return nil, err
+	}
+
+	return &SSHCertificate{Certificate: cert, Username: username, ValidBefore: validBefore}, nil
+}
+
+// latestGrantExpiry returns the latest expiry of grants. It returns false if
+// any of the grants does not expire.
+func latestGrantExpiry(grants []models.Grant) (time.Time, bool) {
+	var latest time.Time
+	for _, grant := range grants {
+		if !grant.ExpiresAt.Valid {
+			return time.Time{}, false
+		}
+		if grant.ExpiresAt.Time.After(latest) {
+			latest = grant.ExpiresAt.Time
+		}
+	}
+	return latest, true
+}
+
+// GetSSHCertificateAuthority returns the public key of the certificate
+// authority that signs certificates for SSH destinations. Any authenticated
+// user may read the public key, the connector uses it to configure sshd.
+func GetSSHCertificateAuthority(c *gin.Context) (string, error) {
+	rCtx := GetRequestContext(c)
+	if rCtx.Authenticated.User == nil {
+		return "", fmt.Errorf("%w: no authenticated user", ErrNotAuthorized)
+	}
+
+	settings, err := data.GetSSHCertificateAuthority(rCtx.DBTxn)
+	if err != nil {
+		return "", err
+	}
+	return settings.SSHCAPublicKey, nil
+}
+
+// sshPrivileges returns the privileges that grant access to an SSH
+// destination: the SSH roles, and the custom roles with the connect
+// permission.
+func sshPrivileges(tx data.ReadTxn) ([]string, error) {
+	roles, err := data.ListRoles(tx, data.ListRolesOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+
+	privileges := openssh.Roles()
+	for _, role := range roles {
+		if role.HasPermission(models.BasePermissionConnect) {
+			privileges = append(privileges, role.Name)
+		}
+	}
+	return privileges, nil
+}
@@ -0,0 +1,157 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// BeginWebAuthnRegistration starts the ceremony to register a new WebAuthn
+// credential for the user. It returns the session, and the credentials that
+// are already registered by the user.
+func BeginWebAuthnRegistration(c *gin.Context, userID uid.ID, rpID string) (*models.WebAuthnSession, []models.WebAuthnCredential, error) {
+	rCtx := GetRequestContext(c)
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		return nil, nil, fmt.Errorf("%w: users can only register webauthn credentials for themselves", internal.ErrBadRequest)
+	}
+
+	db := rCtx.DBTxn
+	// only users of the infra provider login with WebAuthn
+	if _, err := data.GetCredentialByUserID(db, userID); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: webauthn is only available to users with a password", internal.ErrBadRequest)
+		}
+		return nil, nil, fmt.Errorf("existing credential: %w", err)
+	}
+
+	existing, err := data.ListWebAuthnCredentials(db, data.ListWebAuthnCredentialsOptions{ByIdentityID: userID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+
+	session, err := createWebAuthnSession(db, userID, models.WebAuthnSessionRegistration, rpID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, existing, nil
+}
+
+// FinishWebAuthnRegistration verifies the response from the authenticator,
+// and saves the new credential.
+func FinishWebAuthnRegistration(c *gin.Context, userID, sessionID uid.ID, name string, clientDataJSON, attestationObject []byte) (*models.WebAuthnCredential, error) {
+	rCtx := GetRequestContext(c)
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		return nil, fmt.Errorf("%w: users can only register webauthn credentials for themselves", internal.ErrBadRequest)
+	}
+
+	db := rCtx.DBTxn
+	session, err := data.ClaimWebAuthnSession(db, sessionID, models.WebAuthnSessionRegistration)
+	if err != nil {
+		return nil, fmt.Errorf("%w: webauthn session: %v", internal.ErrBadRequest, err)
+	}
+	if session.IdentityID != userID {
+		return nil, fmt.Errorf("%w: webauthn session is for a different user", internal.ErrBadRequest)
+	}
+
+	credential, err := authn.VerifyWebAuthnRegistration(session, clientDataJSON, attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	}
+
+	credential.Name = name
+	if err := data.CreateWebAuthnCredential(db, credential); err != nil {
+		return nil, fmt.Errorf("create webauthn credential: %w", err)
+	}
+	return credential, nil
+}
+
+// ListWebAuthnCredentials returns the credentials registered by the user.
+func ListWebAuthnCredentials(c *gin.Context, userID uid.ID) ([]models.WebAuthnCredential, error) {
+	rCtx := GetRequestContext(c)
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		if err := IsAuthorized(rCtx, models.InfraAdminRole, models.InfraViewRole); err != nil {
+			return nil, HandleAuthErr(err, "webauthn credentials", "list", models.InfraAdminRole, models.InfraViewRole)
+		}
+	}
+	return data.ListWebAuthnCredentials(rCtx.DBTxn, data.ListWebAuthnCredentialsOptions{ByIdentityID: userID})
+}
+
+// DeleteWebAuthnCredential removes a credential of the user. Admins can remove
+// the credentials of any user, for example when an authenticator is lost.
+func DeleteWebAuthnCredential(c *gin.Context, userID, credentialID uid.ID) error {
+	rCtx := GetRequestContext(c)
+	if !isIdentitySelf(rCtx, data.GetIdentityOptions{ByID: userID}) {
+		if err := IsAuthorized(rCtx, models.InfraAdminRole); err != nil {
+			return HandleAuthErr(err, "webauthn credential", "delete", models.InfraAdminRole)
+		}
+	}
+
+	db := rCtx.DBTxn
+	credential, err := data.GetWebAuthnCredential(db, data.GetWebAuthnCredentialOptions{ByID: credentialID})
+	if err != nil {
+		return err
+	}
+	if credential.IdentityID != userID {
+		return fmt.Errorf("%w: webauthn credential not found", internal.ErrNotFound)
+	}
+	return data.DeleteWebAuthnCredentials(db, data.DeleteWebAuthnCredentialsOptions{ByID: credentialID})
+}
+
+// BeginWebAuthnLogin starts a WebAuthn login ceremony. When name is empty, or
+// does not match a user, the user must login with a discoverable credential.
+// It returns the session, and the credentials that may be used to login.
+func BeginWebAuthnLogin(c *gin.Context, name string, rpID string) (*models.WebAuthnSession, []models.WebAuthnCredential, error) {
+	// no authorization is setup yet
+	db := GetRequestContext(c).DBTxn
+
+	var userID uid.ID
+	var allowed []models.WebAuthnCredential
+	if name != "" {
+		identity, err := data.GetIdentity(db, data.GetIdentityOptions{ByName: name})
+		switch {
+		case errors.Is(err, internal.ErrNotFound):
+			// do not reveal that the user does not exist
+		case err != nil:
+			return nil, nil, fmt.Errorf("get user: %w", err)
+		default:
+			userID = identity.ID
+			allowed, err = data.ListWebAuthnCredentials(db, data.ListWebAuthnCredentialsOptions{ByIdentityID: userID})
+			if err != nil {
+				return nil, nil, fmt.Errorf("list webauthn credentials: %w", err)
+			}
+		}
+	}
+
+	session, err := createWebAuthnSession(db, userID, models.WebAuthnSessionLogin, rpID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, allowed, nil
+}
+
+func createWebAuthnSession(db data.WriteTxn, userID uid.ID, kind string, rpID string) (*models.WebAuthnSession, error) {
+	challenge, err := authn.NewWebAuthnChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("generate webauthn challenge: %w", err)
+	}
+
+	session := &models.WebAuthnSession{
+		IdentityID: userID,
+		Kind:       kind,
+		Challenge:  challenge,
+		RPID:       rpID,
+		ExpiresAt:  time.Now().Add(authn.WebAuthnTimeout).UTC(),
+	}
+	if err := data.CreateWebAuthnSession(db, session); err != nil {
+		return nil, fmt.Errorf("create webauthn session: %w", err)
+	}
+	return session, nil
+}
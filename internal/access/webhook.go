@@ -0,0 +1,70 @@
+package access
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateWebhook(c *gin.Context, webhook *models.Webhook) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "webhook", "create", models.InfraAdminRole)
+	}
+
+	webhook.CreatedBy = GetRequestContext(c).Authenticated.User.ID
+	return data.CreateWebhook(db, webhook)
+}
+
+func GetWebhook(c *gin.Context, id uid.ID) (*models.Webhook, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "webhook", "get", models.InfraAdminRole)
+	}
+
+	return data.GetWebhook(db, data.GetWebhookOptions{ByID: id})
+}
+
+func ListWebhooks(c *gin.Context, opts data.ListWebhooksOptions) ([]models.Webhook, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "webhooks", "list", models.InfraAdminRole)
+	}
+
+	return data.ListWebhooks(db, opts)
+}
+
+func UpdateWebhook(c *gin.Context, webhook *models.Webhook) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "webhook", "update", models.InfraAdminRole)
+	}
+
+	return data.UpdateWebhook(db, webhook)
+}
+
+func DeleteWebhook(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "webhook", "delete", models.InfraAdminRole)
+	}
+
+	if _, err := data.GetWebhook(db, data.GetWebhookOptions{ByID: id}); err != nil {
+		return err
+	}
+	return data.DeleteWebhook(db, id)
+}
+
+func ListWebhookDeliveries(c *gin.Context, opts data.ListWebhookDeliveriesOptions) ([]models.WebhookDelivery, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "webhook deliveries", "list", models.InfraAdminRole)
+	}
+
+	if _, err := data.GetWebhook(db, data.GetWebhookOptions{ByID: opts.ByWebhookID}); err != nil {
+		return nil, err
+	}
+	return data.ListWebhookDeliveries(db, opts)
+}
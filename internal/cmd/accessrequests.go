@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/format"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+func newGrantRequestCmd(cli *CLI) *cobra.Command {
+	var options struct {
+		Role     string
+		Duration time.Duration
+		Reason   string
+	}
+
+	cmd := &cobra.Command{
+		Use:   "request DESTINATION",
+		Short: "Request temporary access to a destination",
+		Long: `Request temporary access to a destination. An Infra admin must approve
+the request before the grant is created. The grant expires after --duration.`,
+		Example: `# Request cluster-admin access to a destination for 2 hours
+$ infra grants request production --role cluster-admin --duration 2h --reason "investigating INC-123"
+`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case options.Role == "":
+				return Error{Message: "A --role is required to request access"}
+			case options.Reason == "":
+				return Error{Message: "A --reason is required to request access"}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := &api.CreateAccessRequestRequest{
+				Privilege: options.Role,
+				Resource:  args[0],
+				Duration:  api.Duration(options.Duration),
+				Reason:    options.Reason,
+			}
+			logging.Debugf("call server: create access request %#v", req)
+			resp, err := client.CreateAccessRequest(context.Background(), req)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Requested %q access to %q for %s (request %s)",
+				resp.Privilege, resp.Resource, format.ExactDuration(options.Duration), resp.ID)
+			cli.Output("An Infra admin must approve the request before access is granted")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Role, "role", "", "Role to request")
+	cmd.Flags().DurationVar(&options.Duration, "duration", time.Hour, "How long access should last once approved")
+	cmd.Flags().StringVar(&options.Reason, "reason", "", "Why access is needed")
+	return cmd
+}
+
+func newAccessRequestsCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "access-requests",
+		Short:   "Manage requests for temporary access",
+		Aliases: []string{"access-request", "requests"},
+		GroupID: groupManagement,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newAccessRequestsListCmd(cli))
+	cmd.AddCommand(newAccessRequestsApproveCmd(cli))
+	cmd.AddCommand(newAccessRequestsDenyCmd(cli))
+
+	return cmd
+}
+
+func newAccessRequestsListCmd(cli *CLI) *cobra.Command {
+	var options struct {
+		Status string
+		Mine   bool
+	}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List access requests",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			listReq := api.ListAccessRequestsRequest{Status: options.Status}
+			if options.Mine {
+				config, err := currentHostConfig()
+				if err != nil {
+					return err
+				}
+				listReq.UserID = config.UserID
+			}
+
+			ctx := context.Background()
+			requests, err := listAll(ctx, client.ListAccessRequests, listReq)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list access requests: missing privileges for ListAccessRequests; use --mine to list your own requests",
+					}
+				}
+				return err
+			}
+
+			users := make(map[uid.ID]string)
+			type row struct {
+				ID       string `header:"ID"`
+				User     string `header:"USER"`
+				Role     string `header:"ROLE"`
+				Resource string `header:"DESTINATION"`
+				Duration string `header:"DURATION"`
+				Status   string `header:"STATUS"`
+				Reason   string `header:"REASON"`
+			}
+
+			var rows []row
+			for _, req := range requests {
+				name, ok := users[req.User]
+				if !ok {
+					name = req.User.String()
+					if user, err := client.GetUser(ctx, req.User); err == nil {
+						name = user.Name
+					}
+					users[req.User] = name
+				}
+
+				rows = append(rows, row{
+					ID:       req.ID.String(),
+					User:     name,
+					Role:     req.Privilege,
+					Resource: req.Resource,
+					Duration: format.ExactDuration(time.Duration(req.Duration)),
+					Status:   req.Status,
+					Reason:   req.Reason,
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No access requests found")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Status, "status", "pending", "Filter by status (pending, approved, denied); empty for all")
+	cmd.Flags().BoolVar(&options.Mine, "mine", false, "Only list your own access requests")
+	return cmd
+}
+
+func newAccessRequestsApproveCmd(cli *CLI) *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "approve ID",
+		Short: "Approve an access request and create a temporary grant",
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return decideAccessRequest(cli, args[0], reason, true)
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason for approving the request")
+	return cmd
+}
+
+func newAccessRequestsDenyCmd(cli *CLI) *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "deny ID",
+		Short: "Deny an access request",
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return decideAccessRequest(cli, args[0], reason, false)
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason for denying the request")
+	return cmd
+}
+
+func decideAccessRequest(cli *CLI, rawID, reason string, approve bool) error {
+	id, err := uid.Parse([]byte(rawID))
+	if err != nil {
+		return Error{Message: fmt.Sprintf("Invalid access request ID %q", rawID)}
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	req := &api.DecideAccessRequestRequest{ID: id, Reason: reason}
+
+	decide, action := client.DenyAccessRequest, "deny"
+	if approve {
+		decide, action = client.ApproveAccessRequest, "approve"
+	}
+
+	logging.Debugf("call server: %s access request %s", action, id)
+	resp, err := decide(ctx, req)
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			logging.Debugf("%s", err.Error())
+			return Error{
+				Message: fmt.Sprintf("Cannot %s access request: missing privileges", action),
+			}
+		}
+		return err
+	}
+
+	if approve {
+		cli.Output("Approved %q access to %q for %s", resp.Privilege, resp.Resource, format.ExactDuration(time.Duration(resp.Duration)))
+		return nil
+	}
+	cli.Output("Denied %q access to %q", resp.Privilege, resp.Resource)
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestGrantRequestCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	setup := func(t *testing.T) chan api.CreateAccessRequestRequest {
+		requestCh := make(chan api.CreateAccessRequestRequest, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			if !requestMatches(req, http.MethodPost, "/api/access-requests") {
+				resp.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			var createReq api.CreateAccessRequestRequest
+			err := json.NewDecoder(req.Body).Decode(&createReq)
+			assert.Check(t, err)
+			requestCh <- createReq
+
+			resp.WriteHeader(http.StatusCreated)
+			writeResponse(t, resp, api.AccessRequest{
+				ID:        uid.ID(7000),
+				Privilege: createReq.Privilege,
+				Resource:  createReq.Resource,
+				Duration:  createReq.Duration,
+				Status:    "pending",
+			})
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return requestCh
+	}
+
+	t.Run("request access", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "grants", "request", "production",
+			"--role", "cluster-admin", "--duration", "2h", "--reason", "incident")
+		assert.NilError(t, err)
+
+		actual := <-ch
+		expected := api.CreateAccessRequestRequest{
+			Privilege: "cluster-admin",
+			Resource:  "production",
+			Duration:  api.Duration(2 * time.Hour),
+			Reason:    "incident",
+		}
+		assert.DeepEqual(t, actual, expected)
+		assert.Assert(t, bufs.Stdout.String() != "")
+	})
+
+	t.Run("missing reason", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "grants", "request", "production", "--role", "cluster-admin")
+		assert.ErrorContains(t, err, "--reason is required")
+	})
+}
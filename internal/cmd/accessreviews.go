@@ -0,0 +1,510 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+func newAccessReviewsCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "access-reviews",
+		Short:   "Manage access reviews",
+		Long:    "Manage access reviews, campaigns to re-certify the grants on destinations or of groups",
+		Aliases: []string{"access-review", "reviews"},
+		GroupID: groupManagement,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newAccessReviewsListCmd(cli))
+	cmd.AddCommand(newAccessReviewsCreateCmd(cli))
+	cmd.AddCommand(newAccessReviewsItemsCmd(cli))
+	cmd.AddCommand(newAccessReviewsKeepCmd(cli))
+	cmd.AddCommand(newAccessReviewsRevokeCmd(cli))
+	cmd.AddCommand(newAccessReviewsCloseCmd(cli))
+	cmd.AddCommand(newAccessReviewsExportCmd(cli))
+
+	return cmd
+}
+
+func newAccessReviewsListCmd(cli *CLI) *cobra.Command {
+	var status string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List access reviews",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list access reviews")
+			reviews, err := listAll(context.Background(), client.ListAccessReviews, api.ListAccessReviewsRequest{Status: status})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list access reviews: missing privileges for ListAccessReviews",
+					}
+				}
+				return err
+			}
+
+			type row struct {
+				ID           string `header:"ID"`
+				Name         string `header:"NAME"`
+				Destinations string `header:"DESTINATIONS"`
+				Status       string `header:"STATUS"`
+			}
+
+			var rows []row
+			for _, review := range reviews {
+				rows = append(rows, row{
+					ID:           review.ID.String(),
+					Name:         review.Name,
+					Destinations: strings.Join(review.Destinations, ", "),
+					Status:       review.Status,
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No access reviews found")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&status, "status", "", "Filter by status (open, closed)")
+	return cmd
+}
+
+func newAccessReviewsCreateCmd(cli *CLI) *cobra.Command {
+	var options struct {
+		Destinations []string
+		Groups       []string
+		Reviewer     string
+	}
+
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Start an access review",
+		Long: `Start an access review of the grants on destinations, or of the grants of
+groups, the groups nested in them, and their members. Each grant becomes a
+review item assigned to the --reviewer. When the review is closed any grant that
+was revoked, or was not reviewed, is deleted.`,
+		Example: `# Review all access to the production destination
+$ infra access-reviews create "2022 Q4 production" --destination production --reviewer janedoe@example.com
+
+# Review the access of the developers group and its members
+$ infra access-reviews create "2022 Q4 developers" --group developers
+`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.Destinations) == 0 && len(options.Groups) == 0 {
+				return Error{Message: "A --destination or --group is required to start an access review"}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := &api.CreateAccessReviewRequest{
+				Name:         args[0],
+				Destinations: options.Destinations,
+			}
+			for _, name := range options.Groups {
+				group, err := getGroupByNameOrID(client, name)
+				if err != nil {
+					if errors.Is(err, ErrGroupNotFound) {
+						return Error{Message: fmt.Sprintf("unknown group %q", name)}
+					}
+					return err
+				}
+				req.Groups = append(req.Groups, group.ID)
+			}
+			if options.Reviewer != "" {
+				user, err := getUserByNameOrID(client, options.Reviewer)
+				if err != nil {
+					if errors.Is(err, ErrUserNotFound) {
+						return Error{Message: fmt.Sprintf("unknown user %q", options.Reviewer)}
+					}
+					return err
+				}
+				req.Reviewer = user.ID
+			}
+
+			logging.Debugf("call server: create access review %#v", req)
+			review, err := client.CreateAccessReview(context.Background(), req)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot start access review: missing privileges for CreateAccessReview",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Started access review %q (%s)", review.Name, review.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&options.Destinations, "destination", nil, "Review the grants on this destination")
+	cmd.Flags().StringSliceVar(&options.Groups, "group", nil, "Review the grants of this group and its members")
+	cmd.Flags().StringVar(&options.Reviewer, "reviewer", "", "User assigned to review the grants, defaults to you")
+	return cmd
+}
+
+func newAccessReviewsItemsCmd(cli *CLI) *cobra.Command {
+	var options struct {
+		Decision string
+		Mine     bool
+	}
+
+	cmd := &cobra.Command{
+		Use:   "items REVIEW",
+		Short: "List the grants in an access review",
+		Example: `# List the grants you have been assigned to review
+$ infra access-reviews items 4yJ3n3D8E2 --mine --decision pending
+`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reviewID, err := parseAccessReviewID(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			listReq := api.ListAccessReviewItemsRequest{ID: reviewID, Decision: options.Decision}
+			if options.Mine {
+				config, err := currentHostConfig()
+				if err != nil {
+					return err
+				}
+				listReq.Reviewer = config.UserID
+			}
+
+			ctx := context.Background()
+			logging.Debugf("call server: list access review items")
+			items, err := listAll(ctx, client.ListAccessReviewItems, listReq)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list access review items: missing privileges for ListAccessReviewItems; use --mine to list the items assigned to you",
+					}
+				}
+				return err
+			}
+
+			type row struct {
+				ID       string `header:"ID"`
+				Subject  string `header:"USER/GROUP"`
+				Role     string `header:"ROLE"`
+				Resource string `header:"RESOURCE"`
+				Decision string `header:"DECISION"`
+			}
+
+			names := subjectNames{client: client}
+			var rows []row
+			for _, item := range items {
+				rows = append(rows, row{
+					ID:       item.ID.String(),
+					Subject:  names.get(ctx, item),
+					Role:     item.Privilege,
+					Resource: item.Resource,
+					Decision: item.Decision,
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No access review items found")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Decision, "decision", "", "Filter by decision (pending, keep, revoke, unreviewed)")
+	cmd.Flags().BoolVar(&options.Mine, "mine", false, "Only list the items assigned to you")
+	return cmd
+}
+
+// subjectNames looks up and caches the names of the users and groups of
+// access review items.
+type subjectNames struct {
+	client *api.Client
+	cache  map[uid.ID]string
+}
+
+func (s *subjectNames) get(ctx context.Context, item api.AccessReviewItem) string {
+	if s.cache == nil {
+		s.cache = map[uid.ID]string{}
+	}
+
+	id := item.User
+	if id == 0 {
+		id = item.Group
+	}
+	if name, ok := s.cache[id]; ok {
+		return name
+	}
+
+	name := id.String()
+	switch {
+	case item.User != 0:
+		if user, err := s.client.GetUser(ctx, item.User); err == nil {
+			name = user.Name
+		}
+	case item.Group != 0:
+		if group, err := s.client.GetGroup(ctx, item.Group); err == nil {
+			name = group.Name
+		}
+	}
+	s.cache[id] = name
+	return name
+}
+
+func newAccessReviewsKeepCmd(cli *CLI) *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "keep ITEM",
+		Short: "Keep the grant of an access review item",
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return decideAccessReviewItem(cli, args[0], reason, true)
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason for keeping the grant")
+	return cmd
+}
+
+func newAccessReviewsRevokeCmd(cli *CLI) *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "revoke ITEM",
+		Short: "Revoke the grant of an access review item when the review is closed",
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return decideAccessReviewItem(cli, args[0], reason, false)
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason for revoking the grant")
+	return cmd
+}
+
+func decideAccessReviewItem(cli *CLI, rawID, reason string, keep bool) error {
+	id, err := uid.Parse([]byte(rawID))
+	if err != nil {
+		return Error{Message: fmt.Sprintf("Invalid access review item ID %q", rawID)}
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	req := &api.DecideAccessReviewItemRequest{ID: id, Reason: reason}
+
+	decide, action := client.RevokeAccessReviewItem, "revoke"
+	if keep {
+		decide, action = client.KeepAccessReviewItem, "keep"
+	}
+
+	logging.Debugf("call server: %s access review item %s", action, id)
+	item, err := decide(context.Background(), req)
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			logging.Debugf("%s", err.Error())
+			return Error{
+				Message: fmt.Sprintf("Cannot %s access review item: missing privileges", action),
+			}
+		}
+		return err
+	}
+
+	if keep {
+		cli.Output("Keeping %q access to %q", item.Privilege, item.Resource)
+		return nil
+	}
+	cli.Output("Revoking %q access to %q when the review is closed", item.Privilege, item.Resource)
+	return nil
+}
+
+func newAccessReviewsCloseCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "close REVIEW",
+		Short: "Close an access review, and delete revoked and unreviewed grants",
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseAccessReviewID(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: close access review %s", id)
+			review, err := client.CloseAccessReview(context.Background(), id)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot close access review: missing privileges for CloseAccessReview",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Closed access review %q", review.Name)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newAccessReviewsExportCmd(cli *CLI) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "export REVIEW",
+		Short: "Export an access review as evidence",
+		Example: `# Export an access review as a CSV file
+$ infra access-reviews export 4yJ3n3D8E2 > review.csv
+
+# Export an access review as JSON
+$ infra access-reviews export 4yJ3n3D8E2 --format json > review.json
+`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "csv" && format != "json" {
+				return Error{Message: fmt.Sprintf("Invalid --format %q, must be one of csv or json", format)}
+			}
+
+			id, err := parseAccessReviewID(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: export access review %s", id)
+			evidence, err := client.ExportAccessReview(context.Background(), id)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot export access review: missing privileges for ExportAccessReview",
+					}
+				}
+				return err
+			}
+
+			if format == "json" {
+				jsonOutput, err := json.MarshalIndent(evidence, "", "  ")
+				if err != nil {
+					return err
+				}
+				cli.Output(string(jsonOutput))
+				return nil
+			}
+			return writeAccessReviewCSV(cli, evidence)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "csv", "Output format [csv|json]")
+	return cmd
+}
+
+func writeAccessReviewCSV(cli *CLI, evidence *api.AccessReviewEvidence) error {
+	w := csv.NewWriter(cli.Stdout)
+	header := []string{
+		"review", "review status", "item", "grant", "user", "group", "role", "resource",
+		"reviewer", "decision", "decided by", "decided at", "reason",
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, item := range evidence.Items {
+		var user, group string
+		switch {
+		case item.User != 0:
+			user = item.Subject
+		case item.Group != 0:
+			group = item.Subject
+		}
+
+		var decided string
+		if !item.Decided.Time().IsZero() {
+			decided = item.Decided.Time().UTC().Format(time.RFC3339)
+		}
+
+		record := []string{
+			evidence.AccessReview.Name,
+			evidence.AccessReview.Status,
+			item.ID.String(),
+			item.Grant.String(),
+			user,
+			group,
+			item.Privilege,
+			item.Resource,
+			item.ReviewerName,
+			item.Decision,
+			item.DecidedByName,
+			decided,
+			item.Reason,
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func parseAccessReviewID(raw string) (uid.ID, error) {
+	id, err := uid.Parse([]byte(raw))
+	if err != nil {
+		return 0, Error{Message: fmt.Sprintf("Invalid access review ID %q", raw)}
+	}
+	return id, nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAccessReviewsCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	decided := time.Date(2022, 12, 20, 10, 30, 0, 0, time.UTC)
+	evidence := api.AccessReviewEvidence{
+		AccessReview: api.AccessReview{ID: uid.ID(300), Name: "2022 Q4", Status: "closed"},
+		Items: []api.AccessReviewEvidenceItem{
+			{
+				AccessReviewItem: api.AccessReviewItem{
+					ID: uid.ID(301), Grant: uid.ID(401), User: uid.ID(501),
+					Privilege: "view", Resource: "production",
+					Decision: "keep", Decided: api.Time(decided), Reason: "on call, needs access",
+				},
+				Subject:       "alice@example.com",
+				ReviewerName:  "reviewer@example.com",
+				DecidedByName: "reviewer@example.com",
+			},
+			{
+				AccessReviewItem: api.AccessReviewItem{
+					ID: uid.ID(302), Grant: uid.ID(402), Group: uid.ID(601),
+					Privilege: "cluster-admin", Resource: "production.kube-system",
+					Decision: "unreviewed",
+				},
+				Subject:      "developers",
+				ReviewerName: "reviewer@example.com",
+			},
+		},
+	}
+
+	type requests struct {
+		create chan api.CreateAccessReviewRequest
+		decide chan string
+	}
+
+	setup := func(t *testing.T) requests {
+		reqs := requests{
+			create: make(chan api.CreateAccessReviewRequest, 1),
+			decide: make(chan string, 1),
+		}
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/groups"):
+				writeResponse(t, resp, api.ListResponse[api.Group]{
+					Items: []api.Group{{ID: uid.ID(601), Name: "developers"}},
+					Count: 1,
+				})
+			case requestMatches(req, http.MethodPost, "/api/access-reviews"):
+				var createReq api.CreateAccessReviewRequest
+				assert.Check(t, json.NewDecoder(req.Body).Decode(&createReq))
+				reqs.create <- createReq
+
+				resp.WriteHeader(http.StatusCreated)
+				writeResponse(t, resp, api.AccessReview{ID: uid.ID(300), Name: createReq.Name, Status: "open"})
+			case requestMatches(req, http.MethodPost, "/api/access-review-items/"+uid.ID(301).String()+"/revoke"):
+				var decideReq api.DecideAccessReviewItemRequest
+				assert.Check(t, json.NewDecoder(req.Body).Decode(&decideReq))
+				reqs.decide <- decideReq.Reason
+
+				resp.WriteHeader(http.StatusCreated)
+				writeResponse(t, resp, api.AccessReviewItem{ID: uid.ID(301), Privilege: "view", Resource: "production", Decision: "revoke"})
+			case requestMatches(req, http.MethodGet, "/api/access-reviews/"+uid.ID(300).String()+"/export"):
+				writeResponse(t, resp, evidence)
+			default:
+				resp.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		assert.NilError(t, writeConfig(&cfg))
+		return reqs
+	}
+
+	t.Run("create", func(t *testing.T) {
+		reqs := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "access-reviews", "create", "2022 Q4", "--destination", "production", "--group", "developers")
+		assert.NilError(t, err)
+
+		expected := api.CreateAccessReviewRequest{
+			Name:         "2022 Q4",
+			Destinations: []string{"production"},
+			Groups:       []uid.ID{601},
+		}
+		assert.DeepEqual(t, <-reqs.create, expected)
+		assert.Equal(t, bufs.Stdout.String(), "Started access review \"2022 Q4\" ("+uid.ID(300).String()+")\n")
+	})
+
+	t.Run("create without a scope", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "access-reviews", "create", "2022 Q4")
+		assert.ErrorContains(t, err, "--destination or --group is required")
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		reqs := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "access-reviews", "revoke", uid.ID(301).String(), "--reason", "left the team")
+		assert.NilError(t, err)
+
+		assert.Equal(t, <-reqs.decide, "left the team")
+		assert.Equal(t, bufs.Stdout.String(), "Revoking \"view\" access to \"production\" when the review is closed\n")
+	})
+
+	t.Run("export csv", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "access-reviews", "export", uid.ID(300).String())
+		assert.NilError(t, err)
+		golden.Assert(t, bufs.Stdout.String(), t.Name())
+	})
+
+	t.Run("export json", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "access-reviews", "export", uid.ID(300).String(), "--format", "json")
+		assert.NilError(t, err)
+
+		var actual api.AccessReviewEvidence
+		assert.NilError(t, json.Unmarshal(bufs.Stdout.Bytes(), &actual))
+		assert.DeepEqual(t, actual, evidence)
+	})
+
+	t.Run("export invalid format", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "access-reviews", "export", uid.ID(300).String(), "--format", "yaml")
+		assert.ErrorContains(t, err, `Invalid --format "yaml"`)
+	})
+}
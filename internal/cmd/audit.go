@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infrahq/infra/api"
+	humanfmt "github.com/infrahq/infra/internal/format"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newAuditCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "audit",
+		Short:   "View the audit log of changes made in Infra",
+		GroupID: groupManagement,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newAuditListCmd(cli))
+
+	return cmd
+}
+
+func newAuditListCmd(cli *CLI) *cobra.Command {
+	var options struct {
+		User       string
+		Action     string
+		TargetType string
+		Limit      int
+		Format     string
+	}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List recent audit events",
+		Example: `# List the most recent changes
+$ infra audit list
+
+# List grants created by a user
+$ infra audit list --user admin@example.com --action CreateGrant`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := api.ListAuditEventsRequest{
+				Action:            options.Action,
+				TargetType:        options.TargetType,
+				PaginationRequest: api.PaginationRequest{Limit: options.Limit},
+			}
+			if options.User != "" {
+				user, err := getUserByNameOrID(client, options.User)
+				if err != nil {
+					return err
+				}
+				req.Actor = user.ID
+			}
+
+			logging.Debugf("call server: list audit events")
+			resp, err := client.ListAuditEvents(context.Background(), req)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list audit events: missing privileges for ListAuditEvents",
+					}
+				}
+				return err
+			}
+
+			switch options.Format {
+			case "json":
+				jsonOutput, err := json.Marshal(resp.Items)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(jsonOutput))
+			case "yaml":
+				yamlOutput, err := yaml.Marshal(resp.Items)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(yamlOutput))
+			default:
+				type row struct {
+					Time   string `header:"TIME"`
+					User   string `header:"USER"`
+					Action string `header:"ACTION"`
+					Type   string `header:"TYPE"`
+					Target string `header:"TARGET"`
+				}
+
+				var rows []row
+				for _, event := range resp.Items {
+					rows = append(rows, row{
+						Time:   humanfmt.HumanTime(event.Created.Time(), ""),
+						User:   event.ActorName,
+						Action: event.Action,
+						Type:   event.TargetType,
+						Target: event.Target.String(),
+					})
+				}
+
+				if len(rows) > 0 {
+					printTable(rows, cli.Stdout)
+				} else {
+					cli.Output("No audit events found")
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.User, "user", "", "Only list changes made by this user")
+	cmd.Flags().StringVar(&options.Action, "action", "", "Only list events for this action, ex: CreateGrant")
+	cmd.Flags().StringVar(&options.TargetType, "type", "", "Only list changes to this kind of resource, ex: grants")
+	cmd.Flags().IntVar(&options.Limit, "limit", 50, "Maximum number of events to list")
+	addFormatFlag(cmd.Flags(), &options.Format)
+	return cmd
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAuditListCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	setup := func(t *testing.T) chan *http.Request {
+		requestCh := make(chan *http.Request, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			query := req.URL.Query()
+
+			// the command does a lookup for user ID
+			if requestMatches(req, http.MethodGet, "/api/users") {
+				if query.Get("name") != "admin@example.com" {
+					resp.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				writeResponse(t, resp, api.ListResponse[api.User]{
+					Count: 1,
+					Items: []api.User{{ID: uid.ID(100), Name: "admin@example.com"}},
+				})
+				return
+			}
+
+			if !requestMatches(req, http.MethodGet, "/api/audit-events") {
+				resp.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			requestCh <- req
+
+			writeResponse(t, resp, api.ListResponse[api.AuditEvent]{
+				Count: 1,
+				Items: []api.AuditEvent{{
+					ID:         uid.ID(7000),
+					Created:    api.Time(time.Now().Add(-time.Hour)),
+					Actor:      uid.ID(100),
+					ActorName:  "admin@example.com",
+					Action:     "CreateGrant",
+					TargetType: "grants",
+					Target:     uid.ID(500),
+				}},
+			})
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return requestCh
+	}
+
+	t.Run("list", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "audit", "list")
+		assert.NilError(t, err)
+
+		req := <-ch
+		assert.Equal(t, req.URL.Query().Get("limit"), "50")
+
+		out := bufs.Stdout.String()
+		assert.Assert(t, strings.Contains(out, "admin@example.com  CreateGrant  grants  "+uid.ID(500).String()), out)
+	})
+
+	t.Run("filter by user and action", func(t *testing.T) {
+		ch := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "audit", "list", "--user", "admin@example.com", "--action", "CreateGrant")
+		assert.NilError(t, err)
+
+		query := (<-ch).URL.Query()
+		assert.Equal(t, query.Get("actor"), uid.ID(100).String())
+		assert.Equal(t, query.Get("action"), "CreateGrant")
+	})
+}
@@ -217,10 +217,14 @@ func NewRootCmd(cli *CLI) *cobra.Command {
 		// Management commands
 		newDestinationsCmd(cli),
 		newGrantsCmd(cli),
+		newRolesCmd(cli),
+		newAccessRequestsCmd(cli),
+		newAccessReviewsCmd(cli),
 		newUsersCmd(cli),
 		newGroupsCmd(cli),
 		newKeysCmd(cli),
 		newProvidersCmd(cli),
+		newAuditCmd(cli),
 
 		// Other commands
 		newInfoCmd(cli),
@@ -228,7 +232,7 @@ func NewRootCmd(cli *CLI) *cobra.Command {
 
 		// Hidden commands
 		newTokensCmd(cli),
-		newServerCmd(),
+		newServerCmd(cli),
 		newConnectorCmd(),
 		newAgentCmd())
 
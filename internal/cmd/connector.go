@@ -65,9 +65,11 @@ func newConnectorCmd() *cobra.Command {
 	cmd.Flags().StringP("server-url", "s", "", "Infra server hostname")
 	cmd.Flags().StringP("server-access-key", "a", "", "Infra access key (use file:// to load from a file)")
 	cmd.Flags().StringP("name", "n", "", "Destination name")
+	cmd.Flags().String("kind", "", "Kind of destination [kubernetes, ssh]")
 	cmd.Flags().String("ca-cert", "", "Path to CA certificate file")
 	cmd.Flags().String("ca-key", "", "Path to CA key file")
 	cmd.Flags().Bool("server-skip-tls-verify", false, "Skip verifying server TLS certificates")
+	cmd.Flags().Bool("dry-run", false, "Log changes to role bindings without applying them")
 
 	return cmd
 }
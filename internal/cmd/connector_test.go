@@ -264,6 +264,15 @@ func (f *fakeKubeAPI) handleGET(w http.ResponseWriter, req *http.Request) {
 		}
 		assert.Check(f.t, json.NewEncoder(w).Encode(result))
 
+	case req.URL.Path == "/apis/rbac.authorization.k8s.io/v1/roles":
+		headers.Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		result := rbacv1.RoleList{
+			Items: []rbacv1.Role{},
+		}
+		assert.Check(f.t, json.NewEncoder(w).Encode(result))
+
 	case req.URL.Path == "/apis/rbac.authorization.k8s.io/v1/rolebindings":
 		headers.Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
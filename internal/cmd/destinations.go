@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/connector"
 	humanfmt "github.com/infrahq/infra/internal/format"
+	"github.com/infrahq/infra/internal/kubernetes"
 	"github.com/infrahq/infra/internal/logging"
 )
 
@@ -35,6 +38,7 @@ func newDestinationsCmd(cli *CLI) *cobra.Command {
 
 	cmd.AddCommand(newDestinationsListCmd(cli))
 	cmd.AddCommand(newDestinationsRemoveCmd(cli))
+	cmd.AddCommand(newDestinationsDiffCmd(cli))
 
 	return cmd
 }
@@ -175,3 +179,141 @@ func newDestinationsRemoveCmd(cli *CLI) *cobra.Command {
 
 	return cmd
 }
+
+func newDestinationsDiffCmd(cli *CLI) *cobra.Command {
+	var format string
+	var kubeContext string
+
+	cmd := &cobra.Command{
+		Use:   "diff DESTINATION",
+		Short: "Show the role bindings the connector would change in a cluster",
+		Long: `Show the ClusterRoleBindings and RoleBindings that the connector would create,
+update, or delete in a Kubernetes cluster to match the current grants. No
+changes are made to the cluster.
+
+The role bindings in the cluster are read using a context from your kubeconfig.
+By default the context added by 'infra use DESTINATION' is used. To review
+grant changes before they are applied run the connector with --dry-run.`,
+		Example: `# Show the changes for the production cluster
+$ infra destinations diff production
+
+# Read the role bindings using a different kubeconfig context
+$ infra destinations diff production --context admin@production`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+
+			logging.Debugf("call server: list destinations named %q", name)
+			destinations, err := client.ListDestinations(ctx, api.ListDestinationsRequest{Name: name})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot diff destination: missing privileges for ListDestinations",
+					}
+				}
+				return err
+			}
+
+			if destinations.Count == 0 {
+				return Error{Message: fmt.Sprintf("Destination %q not connected", name)}
+			}
+			if kind := destinations.Items[0].Kind; kind != "" && kind != "kubernetes" {
+				return Error{Message: fmt.Sprintf("Destination %q is not a kubernetes cluster", name)}
+			}
+
+			logging.Debugf("call server: list grants for destination %q", name)
+			grants, err := listAll(ctx, client.ListGrants, api.ListGrantsRequest{Destination: name})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot diff destination: missing privileges for ListGrants",
+					}
+				}
+				return err
+			}
+
+			if kubeContext == "" {
+				kubeContext = "infra:" + name
+			}
+
+			kubeConfig, err := clientConfig().RawConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := kubeConfig.Contexts[kubeContext]; !ok {
+				return Error{Message: fmt.Sprintf(
+					"Kubernetes context %q not found, run 'infra use %s' or set --context", kubeContext, name)}
+			}
+
+			config, err := kubernetesRESTConfig(kubeContext)
+			if err != nil {
+				return err
+			}
+
+			k8s := &kubernetes.Kubernetes{Config: config}
+			changes, err := connector.RoleBindingChanges(ctx, client, k8s, grants)
+			if err != nil {
+				return err
+			}
+
+			switch format {
+			case "json":
+				jsonOutput, err := json.Marshal(changes)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(jsonOutput))
+			case "yaml":
+				yamlOutput, err := yaml.Marshal(changes)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(yamlOutput))
+			default:
+				type row struct {
+					Action    string `header:"ACTION"`
+					Kind      string `header:"KIND"`
+					Namespace string `header:"NAMESPACE"`
+					Name      string `header:"NAME"`
+					Role      string `header:"ROLE"`
+					Subjects  string `header:"SUBJECTS"`
+				}
+
+				var rows []row
+				for _, c := range changes {
+					subjects := make([]string, 0, len(c.Subjects))
+					for _, s := range c.Subjects {
+						subjects = append(subjects, s.Kind+":"+s.Name)
+					}
+
+					rows = append(rows, row{
+						Action:    c.Action,
+						Kind:      c.Kind,
+						Namespace: c.Namespace,
+						Name:      c.Name,
+						Role:      c.RoleRef.Kind + ":" + c.RoleRef.Name,
+						Subjects:  strings.Join(subjects, ", "),
+					})
+				}
+				if len(rows) > 0 {
+					printTable(rows, cli.Stdout)
+				} else {
+					cli.Output("No changes to role bindings in %q", name)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kubeContext, "context", "", "Kubernetes context used to read role bindings (default \"infra:DESTINATION\")")
+	addFormatFlag(cmd.Flags(), &format)
+	return cmd
+}
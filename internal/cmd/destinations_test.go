@@ -5,13 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/golden"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestDestinationsListCmd(t *testing.T) {
@@ -83,3 +88,90 @@ func TestDestinationsListCmd(t *testing.T) {
 
 	})
 }
+
+func TestDestinationsDiffCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	kubeConfigPath := filepath.Join(home, "kubeconfig")
+	t.Setenv("KUBECONFIG", kubeConfigPath)
+
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		switch {
+		case requestMatches(req, http.MethodGet, "/api/destinations"):
+			assert.Check(t, is.Equal(req.URL.Query().Get("name"), "testing"))
+			writeResponse(t, resp, api.ListResponse[api.Destination]{
+				Items: []api.Destination{{ID: 12, Name: "testing", Kind: "kubernetes"}},
+				Count: 1,
+			})
+		case requestMatches(req, http.MethodGet, "/api/grants"):
+			assert.Check(t, is.Equal(req.URL.Query().Get("destination"), "testing"))
+			writeResponse(t, resp, api.ListResponse[api.Grant]{
+				Items: []api.Grant{
+					{User: 31, Resource: "testing", Privilege: "view"},
+					{Group: 32, Resource: "testing.ns1", Privilege: "logs"},
+					{User: 31, Resource: "testing", Privilege: "connect"},
+				},
+				Count: 3,
+			})
+		case requestMatches(req, http.MethodGet, "/api/roles"):
+			writeResponse(t, resp, api.ListResponse[api.Role]{})
+		case requestMatches(req, http.MethodGet, "/api/users/"+uid.ID(31).String()):
+			writeResponse(t, resp, api.User{ID: 31, Name: "user1@example.com"})
+		case requestMatches(req, http.MethodGet, "/api/groups/"+uid.ID(32).String()):
+			writeResponse(t, resp, api.Group{ID: 32, Name: "group1"})
+		case requestMatches(req, http.MethodGet, "/api/groups/"+uid.ID(32).String()+"/groups"):
+			writeResponse(t, resp, api.ListResponse[api.Group]{})
+		default:
+			resp.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	assert.NilError(t, writeConfig(&cfg))
+
+	fakeKube := &fakeKubeAPI{t: t}
+	kubeSrv := httptest.NewTLSServer(fakeKube)
+	t.Cleanup(kubeSrv.Close)
+
+	kubeConfig := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"testing": {Server: kubeSrv.URL, InsecureSkipTLSVerify: true},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"admin": {Token: "the-token"},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"infra:testing": {Cluster: "testing", AuthInfo: "admin"},
+			"admin@testing": {Cluster: "testing", AuthInfo: "admin"},
+		},
+	}
+	assert.NilError(t, clientcmd.WriteToFile(kubeConfig, kubeConfigPath))
+
+	t.Run("default context", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "destinations", "diff", "testing")
+		assert.NilError(t, err)
+		golden.Assert(t, bufs.Stdout.String(), "TestDestinationsDiffCmd")
+		assert.Equal(t, len(fakeKube.writes), 0)
+	})
+
+	t.Run("context flag", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "destinations", "diff", "testing", "--context", "admin@testing")
+		assert.NilError(t, err)
+		golden.Assert(t, bufs.Stdout.String(), "TestDestinationsDiffCmd")
+	})
+
+	t.Run("missing context", func(t *testing.T) {
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "destinations", "diff", "testing", "--context", "other")
+		assert.ErrorContains(t, err, `Kubernetes context "other" not found`)
+	})
+}
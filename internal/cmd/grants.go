@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ssoroka/slice"
@@ -23,6 +24,7 @@ type grantsCmdOptions struct {
 	Role        string
 	Force       bool
 	Inherited   bool
+	Expires     string
 }
 
 func newGrantsCmd(cli *CLI) *cobra.Command {
@@ -42,6 +44,7 @@ func newGrantsCmd(cli *CLI) *cobra.Command {
 	cmd.AddCommand(newGrantsListCmd(cli))
 	cmd.AddCommand(newGrantAddCmd(cli))
 	cmd.AddCommand(newGrantRemoveCmd(cli))
+	cmd.AddCommand(newGrantRequestCmd(cli))
 
 	return cmd
 }
@@ -63,10 +66,11 @@ func newGrantsListCmd(cli *CLI) *cobra.Command {
 			ctx := context.Background()
 
 			listReq := api.ListGrantsRequest{
-				Privilege:     options.Role,
-				Resource:      options.Resource,
-				Destination:   options.Destination,
-				ShowInherited: options.Inherited,
+				Privilege:        options.Role,
+				Resource:         options.Resource,
+				Destination:      options.Destination,
+				ShowInherited:    options.Inherited,
+				ShowSubjectNames: true,
 			}
 
 			if options.UserName != "" && options.GroupName != "" {
@@ -83,9 +87,6 @@ func newGrantsListCmd(cli *CLI) *cobra.Command {
 			}
 
 			if options.GroupName != "" {
-				if options.Inherited {
-					cli.Output("Warning: using --inherited with a group does nothing")
-				}
 				group, err := getGroupByNameOrID(client, options.GroupName)
 				if err != nil {
 					return err
@@ -124,24 +125,26 @@ func newGrantsListCmd(cli *CLI) *cobra.Command {
 	cmd.Flags().StringVar(&options.Resource, "resource", "", "Filter by resource")
 	cmd.Flags().StringVar(&options.GroupName, "group", "", "Filter by group name or id")
 	cmd.Flags().StringVar(&options.UserName, "user", "", "Filter by user name or id")
-	cmd.Flags().BoolVar(&options.Inherited, "inherited", false, "Include grants a user inherited through a group")
+	cmd.Flags().BoolVar(&options.Inherited, "inherited", false, "Include grants a user or group inherited through a group")
 	cmd.Flags().StringVar(&options.Role, "role", "", "Filter by user role")
 	return cmd
 }
 
 func userGrants(ctx context.Context, cli *CLI, client *api.Client, grants *[]api.Grant) (int, error) {
-	users, err := listAll(ctx, client.ListUsers, api.ListUsersRequest{})
-	if err != nil {
-		return 0, err
-	}
+	items := slice.Select(*grants, func(g api.Grant) bool { return g.User != 0 })
 
+	// older servers do not include the SubjectName, so list the users instead
 	mapUsers := make(map[uid.ID]api.User)
-	for _, u := range users {
-		mapUsers[u.ID] = u
+	if missingSubjectNames(items) {
+		users, err := listAll(ctx, client.ListUsers, api.ListUsersRequest{})
+		if err != nil {
+			return 0, err
+		}
+		for _, u := range users {
+			mapUsers[u.ID] = u
+		}
 	}
 
-	items := slice.Select(*grants, func(g api.Grant) bool { return g.User != 0 })
-
 	type row struct {
 		User     string `header:"USER"`
 		Role     string `header:"ROLE"`
@@ -150,13 +153,17 @@ func userGrants(ctx context.Context, cli *CLI, client *api.Client, grants *[]api
 
 	rows := make([]row, 0, len(items))
 	for _, item := range items {
-		user, ok := mapUsers[item.User]
-		if !ok {
-			return 0, fmt.Errorf("unknown user for ID %v", item.ID)
+		name := item.SubjectName
+		if name == "" {
+			user, ok := mapUsers[item.User]
+			if !ok {
+				return 0, fmt.Errorf("unknown user for ID %v", item.ID)
+			}
+			name = user.Name
 		}
 
 		rows = append(rows, row{
-			User:     user.Name,
+			User:     name,
 			Role:     item.Privilege,
 			Resource: item.Resource,
 		})
@@ -170,18 +177,20 @@ func userGrants(ctx context.Context, cli *CLI, client *api.Client, grants *[]api
 }
 
 func groupGrants(ctx context.Context, cli *CLI, client *api.Client, grants *[]api.Grant) (int, error) {
-	groups, err := listAll(ctx, client.ListGroups, api.ListGroupsRequest{})
-	if err != nil {
-		return 0, err
-	}
+	items := slice.Select(*grants, func(g api.Grant) bool { return g.Group != 0 })
 
+	// older servers do not include the SubjectName, so list the groups instead
 	mapGroups := make(map[uid.ID]api.Group)
-	for _, u := range groups {
-		mapGroups[u.ID] = u
+	if missingSubjectNames(items) {
+		groups, err := listAll(ctx, client.ListGroups, api.ListGroupsRequest{})
+		if err != nil {
+			return 0, err
+		}
+		for _, u := range groups {
+			mapGroups[u.ID] = u
+		}
 	}
 
-	items := slice.Select(*grants, func(g api.Grant) bool { return g.Group != 0 })
-
 	type row struct {
 		Group    string `header:"GROUP"`
 		Role     string `header:"ROLE"`
@@ -190,13 +199,17 @@ func groupGrants(ctx context.Context, cli *CLI, client *api.Client, grants *[]ap
 
 	rows := make([]row, 0, len(items))
 	for _, item := range items {
-		group, ok := mapGroups[item.Group]
-		if !ok {
-			return 0, fmt.Errorf("unknown group for ID %v", item.ID)
+		name := item.SubjectName
+		if name == "" {
+			group, ok := mapGroups[item.Group]
+			if !ok {
+				return 0, fmt.Errorf("unknown group for ID %v", item.ID)
+			}
+			name = group.Name
 		}
 
 		rows = append(rows, row{
-			Group:    group.Name,
+			Group:    name,
 			Role:     item.Privilege,
 			Resource: item.Resource,
 		})
@@ -209,6 +222,15 @@ func groupGrants(ctx context.Context, cli *CLI, client *api.Client, grants *[]ap
 	return len(rows), nil
 }
 
+func missingSubjectNames(grants []api.Grant) bool {
+	for _, g := range grants {
+		if g.SubjectName == "" {
+			return true
+		}
+	}
+	return false
+}
+
 func newGrantRemoveCmd(cli *CLI) *cobra.Command {
 	var options grantsCmdOptions
 	var isGroup bool
@@ -335,8 +357,23 @@ $ infra grants add group-a staging --group
 # Grant access with fine-grained permissions
 $ infra grants add johndoe@example.com staging --role viewer
 
+# Grant access to a Kubernetes Role defined in the namespace web
+$ infra grants add johndoe@example.com staging.web --role deployer
+
 # Assign a user a role within Infra
 $ infra grants add johndoe@example.com infra --role admin
+
+# Allow a group to manage users, but not providers
+$ infra grants add group-a infra --group --role users:write
+
+# Allow a group to manage the grants of one destination
+$ infra grants add group-a staging --group --role grants:write
+
+# Grant a user access to a destination for 8 hours
+$ infra grants add johndoe@example.com staging --expires 8h
+
+# Grant a user access to a destination until a specific time
+$ infra grants add johndoe@example.com staging --expires 2022-12-01T17:00:00Z
 `,
 		Args: ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -353,10 +390,16 @@ $ infra grants add johndoe@example.com infra --role admin
 	cmd.Flags().BoolVarP(&isGroup, "group", "g", false, "When set, creates a grant for a group instead of a user")
 	cmd.Flags().StringVar(&options.Role, "role", models.BasePermissionConnect, "Type of access that the user or group will be given")
 	cmd.Flags().BoolVar(&options.Force, "force", false, "Create grant even if requested user, destination, or role are unknown")
+	cmd.Flags().StringVar(&options.Expires, "expires", "", "Duration (e.g. 8h) or RFC3339 time after which the grant expires")
 	return cmd
 }
 
 func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
+	expiry, expires, err := parseGrantExpires(cmdOptions.Expires)
+	if err != nil {
+		return err
+	}
+
 	client, err := defaultAPIClient()
 	if err != nil {
 		return err
@@ -419,6 +462,9 @@ func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 		Group:     groupID,
 		Privilege: cmdOptions.Role,
 		Resource:  cmdOptions.Resource,
+		Expiry:    expiry,
+		Expires:   expires,
+		Force:     cmdOptions.Force,
 	}
 	logging.Debugf("call server: create grant %#v", createGrantReq)
 	response, err := client.CreateGrant(ctx, createGrantReq)
@@ -431,15 +477,37 @@ func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 		}
 		return err
 	}
-	if response.WasCreated {
+	switch {
+	case response.WasCreated && !response.Expires.Time().IsZero():
+		cli.Output("Created grant to %q for %q, expires %s", cmdOptions.Resource, cmdOptions.UserName+cmdOptions.GroupName, response.Expires)
+	case response.WasCreated:
 		cli.Output("Created grant to %q for %q", cmdOptions.Resource, cmdOptions.UserName+cmdOptions.GroupName)
-	} else {
+	default:
 		cli.Output("%q grant to %q already exists for %q. Nothing changed", cmdOptions.Role, cmdOptions.Resource, cmdOptions.UserName+cmdOptions.GroupName)
 	}
 
 	return nil
 }
 
+// parseGrantExpires parses the value of the --expires flag, which may be
+// either a duration or an RFC3339 time.
+func parseGrantExpires(value string) (api.Duration, api.Time, error) {
+	if value == "" {
+		return 0, api.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return 0, api.Time{}, Error{Message: "--expires must be a positive duration"}
+		}
+		return api.Duration(d), api.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, api.Time{}, Error{Message: fmt.Sprintf("--expires %q must be a duration (e.g. 8h) or an RFC3339 time", value)}
+	}
+	return 0, api.Time(t), nil
+}
+
 // checkUserGroup returns the ID of the requested user or group if they exist. Otherwise it
 // returns an error
 func checkUserGroup(client *api.Client, user, group string) (userID uid.ID, groupID uid.ID, err error) {
@@ -514,9 +582,19 @@ func checkResourcesPrivileges(ctx context.Context, client *api.Client, resource,
 			}
 		}
 
-		if privilege != "connect" {
-			if _, ok := supportedRoles[privilege]; !ok {
-				return Error{Message: fmt.Sprintf("Role %q is not a known role for destination %q; to ignore, run with '--force'", privilege, destination)}
+		if privilege != "connect" && !api.IsInfraPermission(privilege) {
+			// namespaced roles are reported by the destination as namespace/role
+			_, isNamespacedRole := supportedRoles[subresource+"/"+privilege]
+			if _, ok := supportedRoles[privilege]; !ok && !(subresource != "" && isNamespacedRole) {
+				// custom roles are not reported by the destination
+				logging.Debugf("call server: list roles named %q", privilege)
+				roles, err := client.ListRoles(ctx, api.ListRolesRequest{Name: privilege})
+				if err != nil {
+					return err
+				}
+				if roles.Count == 0 {
+					return Error{Message: fmt.Sprintf("Role %q is not a known role for destination %q; to ignore, run with '--force'", privilege, destination)}
+				}
 			}
 		}
 	}
@@ -9,8 +9,10 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
 
 	"github.com/infrahq/infra/api"
 )
@@ -60,13 +62,23 @@ func TestGrantsAddCmd(t *testing.T) {
 			if requestMatches(req, http.MethodGet, "/api/destinations") {
 				resp.WriteHeader(http.StatusOK)
 				if query.Get("name") == "the-destination" {
-					writeResponse(t, resp, api.ListResponse[api.Destination]{Count: 1, Items: []api.Destination{{ID: 5000, Roles: []string{"role"}, Resources: []string{"default"}}}})
+					writeResponse(t, resp, api.ListResponse[api.Destination]{Count: 1, Items: []api.Destination{{ID: 5000, Roles: []string{"role", "default/deployer"}, Resources: []string{"default", "other"}}}})
 					return
 				}
 				writeResponse(t, resp, &api.ListResponse[api.Destination]{})
 				return
 			}
 
+			if requestMatches(req, http.MethodGet, "/api/roles") {
+				resp.WriteHeader(http.StatusOK)
+				if query.Get("name") == "developer" {
+					writeResponse(t, resp, api.ListResponse[api.Role]{Count: 1, Items: []api.Role{{ID: 6000, Name: "developer"}}})
+					return
+				}
+				writeResponse(t, resp, &api.ListResponse[api.Role]{})
+				return
+			}
+
 			if !requestMatches(req, http.MethodPost, "/api/grants") {
 				resp.WriteHeader(http.StatusInternalServerError)
 				return
@@ -170,6 +182,7 @@ func TestGrantsAddCmd(t *testing.T) {
 			User:      3002,
 			Privilege: "connect",
 			Resource:  "destination",
+			Force:     true,
 		}
 
 		assert.DeepEqual(t, actual, expected)
@@ -185,6 +198,7 @@ func TestGrantsAddCmd(t *testing.T) {
 			Group:     4001,
 			Privilege: "connect",
 			Resource:  "destination",
+			Force:     true,
 		}
 
 		assert.DeepEqual(t, actual, expected)
@@ -211,6 +225,40 @@ func TestGrantsAddCmd(t *testing.T) {
 		assert.ErrorContains(t, err, "not a known role")
 	})
 
+	t.Run("add namespaced role", func(t *testing.T) {
+		ch := setup(t)
+		err := Run(context.Background(), "grants", "add", "existing@example.com", "the-destination.default", "--role", "deployer")
+		assert.NilError(t, err)
+
+		actual := <-ch
+		expected := api.GrantRequest{
+			User:      3000,
+			Privilege: "deployer",
+			Resource:  "the-destination.default",
+		}
+		assert.DeepEqual(t, actual, expected)
+	})
+
+	t.Run("add custom role", func(t *testing.T) {
+		ch := setup(t)
+		err := Run(context.Background(), "grants", "add", "existing@example.com", "the-destination", "--role", "developer")
+		assert.NilError(t, err)
+
+		actual := <-ch
+		expected := api.GrantRequest{
+			User:      3000,
+			Privilege: "developer",
+			Resource:  "the-destination",
+		}
+		assert.DeepEqual(t, actual, expected)
+	})
+
+	t.Run("add namespaced role to a different namespace", func(t *testing.T) {
+		_ = setup(t)
+		err := Run(context.Background(), "grants", "add", "existing@example.com", "the-destination.other", "--role", "deployer")
+		assert.ErrorContains(t, err, "not a known role")
+	})
+
 	t.Run("force add grant for nonexistent destination", func(t *testing.T) {
 		ch := setup(t)
 		err := Run(context.Background(), "grants", "add", "existing@example.com", "nonexistent", "--force")
@@ -221,6 +269,7 @@ func TestGrantsAddCmd(t *testing.T) {
 			User:      3000,
 			Privilege: "connect",
 			Resource:  "nonexistent",
+			Force:     true,
 		}
 
 		assert.DeepEqual(t, actual, expected)
@@ -236,6 +285,7 @@ func TestGrantsAddCmd(t *testing.T) {
 			User:      3000,
 			Privilege: "connect",
 			Resource:  "the-destination.nonexistent",
+			Force:     true,
 		}
 
 		assert.DeepEqual(t, actual, expected)
@@ -251,10 +301,47 @@ func TestGrantsAddCmd(t *testing.T) {
 			User:      3000,
 			Privilege: "nonexistent",
 			Resource:  "the-destination",
+			Force:     true,
+		}
+
+		assert.DeepEqual(t, actual, expected)
+	})
+
+	t.Run("add grant with expiry duration", func(t *testing.T) {
+		ch := setup(t)
+		err := Run(context.Background(), "grants", "add", "existing@example.com", "the-destination", "--expires", "8h")
+		assert.NilError(t, err)
+
+		actual := <-ch
+		expected := api.GrantRequest{
+			User:      3000,
+			Privilege: "connect",
+			Resource:  "the-destination",
+			Expiry:    api.Duration(8 * time.Hour),
 		}
+		assert.DeepEqual(t, actual, expected)
+	})
+
+	t.Run("add grant with expiry time", func(t *testing.T) {
+		ch := setup(t)
+		err := Run(context.Background(), "grants", "add", "existing@example.com", "the-destination", "--expires", "2030-01-02T15:04:05Z")
+		assert.NilError(t, err)
 
+		actual := <-ch
+		expected := api.GrantRequest{
+			User:      3000,
+			Privilege: "connect",
+			Resource:  "the-destination",
+			Expires:   api.Time(time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)),
+		}
 		assert.DeepEqual(t, actual, expected)
 	})
+
+	t.Run("add grant with invalid expiry", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "grants", "add", "existing@example.com", "the-destination", "--expires", "tomorrow")
+		assert.ErrorContains(t, err, "must be a duration (e.g. 8h) or an RFC3339 time")
+	})
 }
 
 func writeResponse(t *testing.T, resp io.Writer, body interface{}) {
@@ -263,6 +350,73 @@ func writeResponse(t *testing.T, resp io.Writer, body interface{}) {
 	assert.Check(t, err, "failed to write API response")
 }
 
+func TestGrantsListCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	setup := func(t *testing.T, includeNames bool) chan string {
+		requestCh := make(chan string, 5)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			requestCh <- req.URL.Path
+
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/grants"):
+				assert.Check(t, req.URL.Query().Get("showSubjectNames") == "true")
+				grants := []api.Grant{
+					{ID: 5001, User: 3000, SubjectName: "user@example.com", Privilege: "view", Resource: "production"},
+					{ID: 5002, Group: 4000, SubjectName: "everyone", Privilege: "admin", Resource: "staging"},
+				}
+				if !includeNames {
+					for i := range grants {
+						grants[i].SubjectName = ""
+					}
+				}
+				writeResponse(t, resp, api.ListResponse[api.Grant]{Count: len(grants), Items: grants})
+			case requestMatches(req, http.MethodGet, "/api/users"):
+				writeResponse(t, resp, api.ListResponse[api.User]{
+					Count: 1,
+					Items: []api.User{{ID: 3000, Name: "user@example.com"}},
+				})
+			case requestMatches(req, http.MethodGet, "/api/groups"):
+				writeResponse(t, resp, api.ListResponse[api.Group]{
+					Count: 1,
+					Items: []api.Group{{ID: 4000, Name: "everyone"}},
+				})
+			default:
+				resp.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return requestCh
+	}
+
+	t.Run("with subject names", func(t *testing.T) {
+		ch := setup(t, true)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "grants", "list")
+		assert.NilError(t, err)
+
+		golden.Assert(t, bufs.Stdout.String(), t.Name())
+		assert.DeepEqual(t, readChan(ch), []string{"/api/grants"})
+	})
+	t.Run("server without subject names", func(t *testing.T) {
+		ch := setup(t, false)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "grants", "list")
+		assert.NilError(t, err)
+
+		golden.Assert(t, bufs.Stdout.String(), t.Name())
+		assert.DeepEqual(t, readChan(ch), []string{"/api/grants", "/api/users", "/api/groups"})
+	})
+}
+
 func TestGrantRemoveCmd(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
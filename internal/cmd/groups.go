@@ -54,9 +54,11 @@ func newGroupsCmd(cli *CLI) *cobra.Command {
 	}
 
 	cmd.AddCommand(newGroupsAddCmd(cli))
+	cmd.AddCommand(newGroupsAddGroupCmd(cli))
 	cmd.AddCommand(newGroupsAddUserCmd(cli))
 	cmd.AddCommand(newGroupsListCmd(cli))
 	cmd.AddCommand(newGroupsRemoveCmd(cli))
+	cmd.AddCommand(newGroupsRemoveGroupCmd(cli))
 	cmd.AddCommand(newGroupsRemoveUserCmd(cli))
 
 	return cmd
@@ -321,3 +323,119 @@ $ infra groups removeuser johndoe@example.com Engineering
 
 	return cmd
 }
+
+func newGroupsAddGroupCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "addgroup GROUP PARENT",
+		Short: "Add a group to another group",
+		Long: `Add a group to another group. The members of GROUP are members of PARENT,
+and inherit the grants of PARENT.`,
+		Args: ExactArgs(2),
+		Example: `# Add the sre-oncall group to the sre group
+$ infra groups addgroup sre-oncall sre
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			groupName := args[0]
+			parentName := args[1]
+
+			ctx := context.Background()
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			group, err := getGroupByNameOrID(client, groupName)
+			if err != nil {
+				if errors.Is(err, ErrGroupNotFound) {
+					return Error{Message: fmt.Sprintf("unknown group %q", groupName)}
+				}
+				return err
+			}
+
+			parent, err := getGroupByNameOrID(client, parentName)
+			if err != nil {
+				if errors.Is(err, ErrGroupNotFound) {
+					return Error{Message: fmt.Sprintf("unknown group %q", parentName)}
+				}
+				return err
+			}
+
+			req := &api.UpdateGroupsInGroupRequest{
+				GroupID:       parent.ID,
+				GroupIDsToAdd: []uid.ID{group.ID},
+			}
+			err = client.UpdateGroupsInGroup(ctx, req)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Added group %q to group %q", group.Name, parent.Name)
+
+			return nil
+		},
+	}
+}
+
+func newGroupsRemoveGroupCmd(cli *CLI) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:     "removegroup GROUP PARENT",
+		Short:   "Remove a group from another group",
+		Aliases: []string{"rmgroup"},
+		Args:    ExactArgs(2),
+		Example: `# Remove the sre-oncall group from the sre group
+$ infra groups removegroup sre-oncall sre
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			groupName := args[0]
+			parentName := args[1]
+
+			ctx := context.Background()
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			group, err := getGroupByNameOrID(client, groupName)
+			if err != nil {
+				if !force {
+					if errors.Is(err, ErrGroupNotFound) {
+						return Error{Message: fmt.Sprintf("unknown group %q", groupName)}
+					}
+					return err
+				}
+				return nil
+			}
+
+			parent, err := getGroupByNameOrID(client, parentName)
+			if err != nil {
+				if !force {
+					if errors.Is(err, ErrGroupNotFound) {
+						return Error{Message: fmt.Sprintf("unknown group %q", parentName)}
+					}
+					return err
+				}
+				return nil
+			}
+
+			req := &api.UpdateGroupsInGroupRequest{
+				GroupID:          parent.ID,
+				GroupIDsToRemove: []uid.ID{group.ID},
+			}
+			err = client.UpdateGroupsInGroup(ctx, req)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Removed group %q from group %q", groupName, parentName)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if either group does not exist")
+
+	return cmd
+}
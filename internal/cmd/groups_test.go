@@ -12,6 +12,7 @@ import (
 	"gotest.tools/v3/golden"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestGroupsAddCmd(t *testing.T) {
@@ -240,6 +241,81 @@ func TestGroupsAddAndRemoveUserCmds(t *testing.T) {
 
 }
 
+func TestGroupsAddAndRemoveGroupCmds(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	setup := func(t *testing.T) *[]api.UpdateGroupsInGroupRequest {
+		var updates []api.UpdateGroupsInGroupRequest
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/groups"):
+				groups := map[string]api.Group{
+					"sre":        {ID: 100, Name: "sre"},
+					"sre-oncall": {ID: 101, Name: "sre-oncall"},
+				}
+				result := api.ListResponse[api.Group]{Items: []api.Group{}}
+				if group, ok := groups[req.URL.Query().Get("name")]; ok {
+					result = api.ListResponse[api.Group]{Count: 1, Items: []api.Group{group}}
+				}
+				writeResponse(t, resp, result)
+			case requestMatches(req, http.MethodPatch, "/api/groups/"+uid.ID(100).String()+"/groups"):
+				var updateReq api.UpdateGroupsInGroupRequest
+				assert.Check(t, json.NewDecoder(req.Body).Decode(&updateReq))
+				updates = append(updates, updateReq)
+				writeResponse(t, resp, api.EmptyResponse{})
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		assert.NilError(t, writeConfig(&cfg))
+		return &updates
+	}
+
+	t.Run("add group", func(t *testing.T) {
+		updates := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "addgroup", "sre-oncall", "sre")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), `Added group "sre-oncall" to group "sre"`+"\n")
+
+		expected := []api.UpdateGroupsInGroupRequest{{GroupIDsToAdd: []uid.ID{101}}}
+		assert.DeepEqual(t, *updates, expected)
+	})
+
+	t.Run("remove group", func(t *testing.T) {
+		updates := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "removegroup", "sre-oncall", "sre")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), `Removed group "sre-oncall" from group "sre"`+"\n")
+
+		expected := []api.UpdateGroupsInGroupRequest{{GroupIDsToRemove: []uid.ID{101}}}
+		assert.DeepEqual(t, *updates, expected)
+	})
+
+	t.Run("add unknown group", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "addgroup", "sre-oncall", "nonexistent")
+		assert.ErrorContains(t, err, `unknown group "nonexistent"`)
+	})
+
+	t.Run("remove unknown group with force", func(t *testing.T) {
+		updates := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "removegroup", "nonexistent", "sre", "--force")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "")
+		assert.Equal(t, len(*updates), 0)
+	})
+}
+
 var expectedGroupsAddOutput = `Added group "Test"
 `
 
@@ -87,6 +87,28 @@ func info(cli *CLI) error {
 
 	fmt.Fprintf(w, "Groups:\t %s\n", groups)
 
+	userPermissions, err := client.ListUserPermissions(ctx, config.UserID)
+	switch {
+	case api.ErrorStatusCode(err) == 404:
+		// older servers do not report permissions
+	case err != nil:
+		return err
+	default:
+		permissions := "(none)"
+		if len(userPermissions.Items) > 0 {
+			p := make([]string, 0, len(userPermissions.Items))
+			for _, perm := range userPermissions.Items {
+				if perm.Destination != "" {
+					p = append(p, fmt.Sprintf("%s (%s)", perm.Permission, perm.Destination))
+					continue
+				}
+				p = append(p, perm.Permission)
+			}
+			permissions = strings.Join(p, ", ")
+		}
+		fmt.Fprintf(w, "Permissions:\t %s\n", permissions)
+	}
+
 	fmt.Fprintln(w)
 
 	return nil
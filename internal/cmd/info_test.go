@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestInfoCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	user := api.User{ID: uid.ID(12345), Name: "johndoe@example.com"}
+
+	setup := func(t *testing.T, permissionsStatus int) {
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/users/"+user.ID.String()):
+				writeResponse(t, resp, user)
+			case requestMatches(req, http.MethodGet, "/api/groups"):
+				writeResponse(t, resp, api.ListResponse[api.Group]{
+					Items: []api.Group{{Name: "developers"}},
+					Count: 1,
+				})
+			case requestMatches(req, http.MethodGet, "/api/users/"+user.ID.String()+"/permissions"):
+				if permissionsStatus != http.StatusOK {
+					resp.WriteHeader(permissionsStatus)
+					return
+				}
+				writeResponse(t, resp, api.ListResponse[api.Permission]{
+					Items: []api.Permission{
+						{Permission: "grants:write", Destination: "staging"},
+						{Permission: "users:write"},
+					},
+					Count: 2,
+				})
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, user)
+		assert.NilError(t, writeConfig(&cfg))
+	}
+
+	t.Run("with permissions", func(t *testing.T) {
+		setup(t, http.StatusOK)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "info")
+		assert.NilError(t, err)
+		assert.Assert(t, cmp.Contains(bufs.Stdout.String(), "Groups: developers\n"))
+		assert.Assert(t, cmp.Contains(bufs.Stdout.String(), "Permissions: grants:write (staging), users:write\n"))
+	})
+
+	t.Run("server does not report permissions", func(t *testing.T) {
+		setup(t, http.StatusNotFound)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "info")
+		assert.NilError(t, err)
+		assert.Assert(t, !strings.Contains(bufs.Stdout.String(), "Permissions:"))
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/goware/urlx"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
@@ -24,6 +25,16 @@ func clientConfig() clientcmd.ClientConfig {
 	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
 }
 
+// kubernetesRESTConfig returns the config used to connect to the cluster of
+// the named context in the kubeconfig.
+func kubernetesRESTConfig(contextName string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.WarnIfAllMissing = false
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
+
 func kubernetesSetContext(cluster, namespace string) error {
 	config := clientConfig()
 
@@ -113,7 +124,7 @@ func writeKubeconfig(user *api.User, destinations []api.Destination, grants []ap
 
 		var infraContext clusterContext
 		for _, d := range destinations {
-			if !isResourceForDestination(g.Resource, d.Name) {
+			if d.Kind == "ssh" || !isResourceForDestination(g.Resource, d.Name) {
 				continue
 			}
 
@@ -25,6 +25,7 @@ import (
 	"github.com/infrahq/infra/internal/cmd/types"
 	"github.com/infrahq/infra/internal/format"
 	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
 )
 
 type loginCmdOptions struct {
@@ -37,6 +38,8 @@ type loginCmdOptions struct {
 	NoAgent            bool
 	User               string
 	Password           string
+	MFACode            string
+	Provider           string
 }
 
 const DeviceFlowMinVersion = "0.16.0"
@@ -55,6 +58,9 @@ infra login example.infrahq.com
 # Login with username and password (prompt for password)
 infra login example.infrahq.com --user user@example.com
 
+# Login with the username and password of a user in an LDAP directory
+infra login example.infrahq.com --provider ad --user ada
+
 # Login with access key
 export INFRA_SERVER=example.infrahq.com
 export INFRA_ACCESS_KEY=2vrEbqFEUr.jtTlxkgYdvghJNdEa8YoUxN0
@@ -92,10 +98,12 @@ infra login`,
 
 	cmd.Flags().StringVar(&options.AccessKey, "key", "", "Login with an access key")
 	cmd.Flags().StringVar(&options.User, "user", "", "User email")
+	cmd.Flags().StringVar(&options.Provider, "provider", "", "Name of the LDAP identity provider used to login with --user")
 	cmd.Flags().BoolVar(&options.SkipTLSVerify, "skip-tls-verify", false, "Skip verifying server TLS certificates")
 	cmd.Flags().Var((*types.StringOrFile)(&options.TrustedCertificate), "tls-trusted-cert", "TLS certificate or CA used by the server")
 	cmd.Flags().StringVar(&options.TrustedFingerprint, "tls-trusted-fingerprint", "", "SHA256 fingerprint of the server TLS certificate")
 	cmd.Flags().BoolVar(&options.NoAgent, "no-agent", false, "Skip starting the Infra agent in the background")
+	cmd.Flags().StringVar(&options.MFACode, "mfa-code", "", "Authentication code from an authenticator app, or a recovery code")
 	addNonInteractiveFlag(cmd.Flags(), &options.NonInteractive)
 	return cmd
 }
@@ -151,10 +159,29 @@ func login(cli *CLI, options loginCmdOptions) error {
 			}
 		}
 
+		if options.Provider != "" {
+			provider, err := GetProviderByName(ctx, lc.APIClient, options.Provider)
+			if err != nil {
+				return err
+			}
+			if provider.Kind != "ldap" {
+				return Error{Message: fmt.Sprintf("Provider %q is not an LDAP provider, login with a browser instead", options.Provider)}
+			}
+
+			loginReq.LDAP = &api.LoginRequestLDAP{
+				ProviderID: provider.ID,
+				Name:       options.User,
+				Password:   options.Password,
+			}
+			break
+		}
+
 		loginReq.PasswordCredentials = &api.LoginRequestPasswordCredentials{
 			Name:     options.User,
 			Password: options.Password,
 		}
+	case options.Provider != "":
+		return Error{Message: "Login with a provider requires the --user flag"}
 
 	default:
 		if options.NonInteractive {
@@ -177,7 +204,7 @@ func login(cli *CLI, options loginCmdOptions) error {
 		}
 	}
 
-	return loginToInfra(cli, lc, loginReq, options.NoAgent)
+	return loginToInfra(cli, lc, loginReq, options)
 }
 
 func checkDeviceFlowCompatibility(ctx context.Context, api *api.Client) error {
@@ -217,19 +244,27 @@ func equalHosts(x, y string) bool {
 	return false
 }
 
-func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent bool) error {
+func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, options loginCmdOptions) error {
 	ctx := context.TODO()
 	loginRes, err := lc.APIClient.Login(ctx, loginReq)
 	if err != nil {
 		if api.ErrorStatusCode(err) == http.StatusUnauthorized || api.ErrorStatusCode(err) == http.StatusNotFound {
 			switch {
-			case loginReq.PasswordCredentials != nil:
+			case loginReq.PasswordCredentials != nil, loginReq.LDAP != nil:
 				return &LoginError{Message: "your username or password may be invalid"}
 			}
 		}
 
 		return err
 	}
+
+	if loginRes.MFAChallenge != "" {
+		loginRes, err = loginWithMFA(ctx, cli, lc, loginRes.MFAChallenge, options)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Update the API client with the new access key from login
 	lc.APIClient.AccessKey = loginRes.AccessKey
 
@@ -257,6 +292,12 @@ func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent
 		fmt.Fprintf(os.Stderr, "  Updated password\n")
 	}
 
+	if loginRes.MFAEnrollmentRequired {
+		if err := enrollMFA(ctx, cli, lc.APIClient, loginRes.UserID, options); err != nil {
+			return err
+		}
+	}
+
 	if err := updateInfraConfig(lc, loginReq, loginRes); err != nil {
 		return err
 	}
@@ -271,7 +312,7 @@ func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent
 		logging.Errorf("unable to check background agent: %v", err)
 	}
 
-	if !backgroundAgentRunning && !noAgent {
+	if !backgroundAgentRunning && !options.NoAgent {
 		// the agent is started in a separate command so that it continues after the login command has finished
 		if err := execAgent(); err != nil {
 			// user still has a valid session, so do not fail
@@ -283,6 +324,78 @@ func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent
 	return nil
 }
 
+// loginWithMFA completes a login that requires a second factor.
+func loginWithMFA(ctx context.Context, cli *CLI, lc loginClient, challenge string, options loginCmdOptions) (*api.LoginResponse, error) {
+	code := options.MFACode
+	if code == "" {
+		if options.NonInteractive {
+			return nil, Error{Message: "Non-interactive login requires setting the INFRA_MFA_CODE environment variable"}
+		}
+
+		if err := survey.AskOne(&survey.Password{Message: "Authentication code:"}, &code, cli.surveyIO, survey.WithValidator(survey.Required)); err != nil {
+			return nil, err
+		}
+	}
+
+	logging.Debugf("call server: login with mfa")
+	loginRes, err := lc.APIClient.Login(ctx, &api.LoginRequest{
+		MFA: &api.LoginRequestMFA{Challenge: challenge, Code: strings.TrimSpace(code)},
+	})
+	if err != nil {
+		if api.ErrorStatusCode(err) == http.StatusUnauthorized {
+			return nil, &LoginError{Message: "your authentication code may be invalid"}
+		}
+		return nil, err
+	}
+	return loginRes, nil
+}
+
+// enrollMFA enrolls the user in TOTP multi-factor authentication, which is
+// required by the organization before the user can do anything else.
+func enrollMFA(ctx context.Context, cli *CLI, client *api.Client, userID uid.ID, options loginCmdOptions) error {
+	if options.NonInteractive {
+		return Error{Message: "Your organization requires multi-factor authentication. Run 'infra login' interactively to enable it"}
+	}
+
+	logging.Debugf("call server: start totp enrollment for user %s", userID)
+	enrollment, err := client.StartTOTPEnrollment(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.Stderr, "  Your organization requires multi-factor authentication.\n")
+	fmt.Fprintf(cli.Stderr, "  Add this account to your authenticator app with the secret:\n\n    %s\n\n", enrollment.Secret)
+	fmt.Fprintf(cli.Stderr, "  or with the URI:\n\n    %s\n\n", enrollment.SecretURI)
+
+	for {
+		var code string
+		if err := survey.AskOne(&survey.Input{Message: "Authentication code:"}, &code, cli.surveyIO, survey.WithValidator(survey.Required)); err != nil {
+			return err
+		}
+
+		logging.Debugf("call server: confirm totp enrollment for user %s", userID)
+		resp, err := client.ConfirmTOTPEnrollment(ctx, &api.ConfirmTOTPEnrollmentRequest{
+			ID:   userID,
+			Code: strings.TrimSpace(code),
+		})
+		if err != nil {
+			if api.ErrorStatusCode(err) == http.StatusBadRequest {
+				fmt.Fprintf(cli.Stderr, "  Invalid authentication code, try again.\n")
+				continue
+			}
+			return err
+		}
+
+		fmt.Fprintf(cli.Stderr, "  Multi-factor authentication is enabled.\n")
+		fmt.Fprintf(cli.Stderr, "  Save these recovery codes, each can be used once if you lose your device:\n\n")
+		for _, recoveryCode := range resp.RecoveryCodes {
+			fmt.Fprintf(cli.Stderr, "    %s\n", recoveryCode)
+		}
+		fmt.Fprintln(cli.Stderr)
+		return nil
+	}
+}
+
 // Updates all configs with the current logged in session
 func updateInfraConfig(lc loginClient, loginReq *api.LoginRequest, loginRes *api.LoginResponse) error {
 	clientHostConfig := ClientHostConfig{
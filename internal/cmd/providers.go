@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"sigs.k8s.io/yaml"
 
 	"github.com/infrahq/infra/api"
@@ -179,6 +180,68 @@ func newProvidersListCmd(cli *CLI) *cobra.Command {
 	return cmd
 }
 
+type providerSAMLOptions struct {
+	MetadataURL     string
+	Metadata        string
+	NameAttribute   string
+	GroupsAttribute string
+}
+
+func (o providerSAMLOptions) Validate(providerKind string) error {
+	if providerKind != "saml" {
+		if o != (providerSAMLOptions{}) {
+			return fmt.Errorf("saml flags are only applicable to SAML identity providers")
+		}
+		return nil
+	}
+	if o.MetadataURL == "" && o.Metadata == "" {
+		return fmt.Errorf("one of saml-metadata-url or saml-metadata is required for SAML identity providers")
+	}
+	if o.MetadataURL != "" && o.Metadata != "" {
+		return fmt.Errorf("only one of saml-metadata-url or saml-metadata can be used")
+	}
+	return nil
+}
+
+type providerLDAPOptions struct {
+	BindDN             string
+	BindPassword       string
+	StartTLS           bool
+	UserBaseDN         string
+	UserFilter         string
+	UserNameAttribute  string
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupNameAttribute string
+}
+
+func (o providerLDAPOptions) Validate(providerKind string) error {
+	if providerKind != "ldap" {
+		if o != (providerLDAPOptions{}) {
+			return fmt.Errorf("ldap flags are only applicable to LDAP identity providers")
+		}
+		return nil
+	}
+	if o.UserBaseDN == "" {
+		return fmt.Errorf("missing value for required flags: ldap-user-base-dn")
+	}
+	return nil
+}
+
+func (o providerLDAPOptions) request() *api.ProviderLDAP {
+	return &api.ProviderLDAP{
+		BindDN:             o.BindDN,
+		BindPassword:       o.BindPassword,
+		StartTLS:           o.StartTLS,
+		UserBaseDN:         o.UserBaseDN,
+		UserFilter:         o.UserFilter,
+		UserNameAttribute:  o.UserNameAttribute,
+		GroupBaseDN:        o.GroupBaseDN,
+		GroupFilter:        o.GroupFilter,
+		GroupNameAttribute: o.GroupNameAttribute,
+	}
+}
+
 type providerAddOptions struct {
 	URL                string
 	ClientID           string
@@ -186,9 +249,29 @@ type providerAddOptions struct {
 	Kind               string
 	SCIM               bool
 	ProviderAPIOptions providerAPIOptions
+	SAMLOptions        providerSAMLOptions
+	LDAPOptions        providerLDAPOptions
 }
 
 func (o providerAddOptions) Validate() error {
+	if err := o.SAMLOptions.Validate(o.Kind); err != nil {
+		return err
+	}
+	if err := o.LDAPOptions.Validate(o.Kind); err != nil {
+		return err
+	}
+	switch o.Kind {
+	case "saml":
+		// SAML providers are configured from the metadata of the provider
+		return o.ProviderAPIOptions.Validate(o.Kind)
+	case "ldap":
+		// LDAP providers bind with a service account, instead of a client
+		if o.URL == "" {
+			return fmt.Errorf("missing value for required flags: url")
+		}
+		return o.ProviderAPIOptions.Validate(o.Kind)
+	}
+
 	var missing []string
 	if o.URL == "" {
 		missing = append(missing, "url")
@@ -217,7 +300,19 @@ PROVIDER is a short unique name of the identity provider being added (eg. okta)`
 $ infra providers add okta --url example.okta.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --kind okta
 
 # Connect Google to Infra with group sync
-$ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --service-account-key ~/client-123.json --workspace-domain-admin admin@example.com --kind google`,
+$ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --service-account-key ~/client-123.json --workspace-domain-admin admin@example.com --kind google
+
+# Connect GitHub to Infra, teams of the user become groups named org/team
+$ infra providers add github --url github.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --kind github
+
+# Connect a self-managed GitLab to Infra
+$ infra providers add gitlab --url gitlab.example.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --kind gitlab
+
+# Connect a SAML identity provider to Infra with group sync
+$ infra providers add corp --kind saml --saml-metadata-url https://idp.example.com/metadata --saml-groups-attribute groups
+
+# Connect Active Directory to Infra, the bind password is read from INFRA_PROVIDER_LDAP_BIND_PASSWORD
+$ infra providers add ad --kind ldap --url ldaps://ad.example.com --ldap-bind-dn "cn=infra,cn=Users,dc=example,dc=com" --ldap-user-base-dn "cn=Users,dc=example,dc=com" --ldap-user-filter "(&(objectClass=user)(sAMAccountName={username}))" --ldap-user-name-attribute userPrincipalName --ldap-group-filter "(&(objectClass=group)(member={dn}))"`,
 		Args: ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
@@ -239,8 +334,7 @@ $ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0
 				return err
 			}
 
-			logging.Debugf("call server: create provider named %q", args[0])
-			provider, err := client.CreateProvider(ctx, &api.CreateProviderRequest{
+			req := &api.CreateProviderRequest{
 				Name:         args[0],
 				URL:          opts.URL,
 				ClientID:     opts.ClientID,
@@ -251,7 +345,21 @@ $ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0
 					ClientEmail:      opts.ProviderAPIOptions.ClientEmail,
 					DomainAdminEmail: opts.ProviderAPIOptions.WorkspaceDomainAdminEmail,
 				},
-			})
+			}
+			if opts.Kind == "saml" {
+				req.SAML = &api.ProviderSAML{
+					MetadataURL:     opts.SAMLOptions.MetadataURL,
+					Metadata:        opts.SAMLOptions.Metadata,
+					NameAttribute:   opts.SAMLOptions.NameAttribute,
+					GroupsAttribute: opts.SAMLOptions.GroupsAttribute,
+				}
+			}
+			if opts.Kind == "ldap" {
+				req.LDAP = opts.LDAPOptions.request()
+			}
+
+			logging.Debugf("call server: create provider named %q", args[0])
+			provider, err := client.CreateProvider(ctx, req)
 			if err != nil {
 				if api.ErrorStatusCode(err) == 403 {
 					logging.Debugf("%s", err.Error())
@@ -262,7 +370,17 @@ $ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0
 				return err
 			}
 
-			cli.Output("Connected provider %q (%s) to infra", args[0], opts.URL)
+			if opts.Kind == "saml" {
+				cli.Output("Connected provider %q (%s) to infra", args[0], provider.URL)
+				samlURL := fmt.Sprintf("%s/api/providers/%s/saml", client.URL, provider.ID)
+				cli.Output("Configure the identity provider with the service provider metadata from %s/metadata", samlURL)
+				cli.Output("Users login at %s/login", samlURL)
+			} else if opts.Kind == "ldap" {
+				cli.Output("Connected provider %q (%s) to infra", args[0], opts.URL)
+				cli.Output("Users login with: infra login %s --provider %s --user USER", client.URL, args[0])
+			} else {
+				cli.Output("Connected provider %q (%s) to infra", args[0], opts.URL)
+			}
 
 			if opts.SCIM {
 				key, err := client.CreateAccessKey(ctx, &api.CreateAccessKeyRequest{
@@ -284,14 +402,31 @@ $ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0
 	cmd.Flags().StringVar(&opts.URL, "url", "", "Base URL of the domain of the OIDC identity provider (eg. acme.okta.com)")
 	cmd.Flags().StringVar(&opts.ClientID, "client-id", "", "OIDC client ID")
 	cmd.Flags().StringVar(&opts.ClientSecret, "client-secret", "", "OIDC client secret")
-	cmd.Flags().StringVar(&opts.Kind, "kind", "oidc", "The identity provider kind. One of 'oidc, okta, azure, or google'")
+	cmd.Flags().StringVar(&opts.Kind, "kind", "oidc", "The identity provider kind. One of 'oidc, okta, azure, google, github, gitlab, saml, or ldap'")
 	cmd.Flags().BoolVar(&opts.SCIM, "scim", false, "Create an access key for SCIM provisioning")
 	cmd.Flags().Var((*types.StringOrFile)(&opts.ProviderAPIOptions.PrivateKey), "service-account-key", "The private key used to make authenticated requests to Google's API, can be a file or the key string directly")
 	cmd.Flags().StringVar(&opts.ProviderAPIOptions.ClientEmail, "service-account-email", "", "The email assigned to the Infra service client in Google") // this is only needed with the private key is not a file
 	cmd.Flags().StringVar(&opts.ProviderAPIOptions.WorkspaceDomainAdminEmail, "workspace-domain-admin", "", "The email of your Google Workspace domain admin")
+	cmd.Flags().StringVar(&opts.SAMLOptions.MetadataURL, "saml-metadata-url", "", "URL of the metadata of the SAML identity provider")
+	cmd.Flags().Var((*types.StringOrFile)(&opts.SAMLOptions.Metadata), "saml-metadata", "The metadata XML of the SAML identity provider, can be a file or the XML directly")
+	cmd.Flags().StringVar(&opts.SAMLOptions.NameAttribute, "saml-name-attribute", "", "The SAML attribute used as the user name, defaults to the subject NameID")
+	cmd.Flags().StringVar(&opts.SAMLOptions.GroupsAttribute, "saml-groups-attribute", "", "The SAML attribute that lists the groups of the user")
+	addProviderLDAPFlags(cmd.Flags(), &opts.LDAPOptions)
 	return cmd
 }
 
+func addProviderLDAPFlags(flags *pflag.FlagSet, opts *providerLDAPOptions) {
+	flags.StringVar(&opts.BindDN, "ldap-bind-dn", "", "DN of the service account used to search the LDAP directory")
+	flags.StringVar(&opts.BindPassword, "ldap-bind-password", "", "Password of the LDAP service account")
+	flags.BoolVar(&opts.StartTLS, "ldap-start-tls", false, "Upgrade ldap:// connections to TLS with StartTLS")
+	flags.StringVar(&opts.UserBaseDN, "ldap-user-base-dn", "", "Base DN of the LDAP user search")
+	flags.StringVar(&opts.UserFilter, "ldap-user-filter", "", "LDAP filter used to find a user, {username} is replaced by the name used to login (default \"(&(objectClass=person)(uid={username}))\")")
+	flags.StringVar(&opts.UserNameAttribute, "ldap-user-name-attribute", "", "LDAP attribute used as the user name (default \"mail\")")
+	flags.StringVar(&opts.GroupBaseDN, "ldap-group-base-dn", "", "Base DN of the LDAP group search, defaults to the user base DN")
+	flags.StringVar(&opts.GroupFilter, "ldap-group-filter", "", "LDAP filter used to find the groups of a user, {dn} is replaced by the DN of the user (default \"(&(objectClass=groupOfNames)(member={dn}))\")")
+	flags.StringVar(&opts.GroupNameAttribute, "ldap-group-name-attribute", "", "LDAP attribute used as the group name (default \"cn\")")
+}
+
 func updateProvider(cli *CLI, name string, opts providerEditOptions) error {
 	client, err := defaultAPIClient()
 	if err != nil {
@@ -160,6 +160,102 @@ func TestProvidersAddCmd(t *testing.T) {
 		assert.DeepEqual(t, createProviderRequest, expected)
 	})
 
+	t.Run("saml provider", func(t *testing.T) {
+		ch, _ := setup(t)
+
+		err := Run(context.Background(),
+			"providers", "add", "corp",
+			"--kind", "saml",
+			"--saml-metadata-url", "https://idp.example.com/metadata",
+			"--saml-groups-attribute", "groups",
+		)
+		assert.NilError(t, err)
+
+		createProviderRequest := <-ch
+
+		expected := api.CreateProviderRequest{
+			Name: "corp",
+			Kind: "saml",
+			API:  &api.ProviderAPICredentials{},
+			SAML: &api.ProviderSAML{
+				MetadataURL:     "https://idp.example.com/metadata",
+				GroupsAttribute: "groups",
+			},
+		}
+		assert.DeepEqual(t, createProviderRequest, expected)
+	})
+
+	t.Run("saml provider without metadata", func(t *testing.T) {
+		setup(t)
+
+		err := Run(context.Background(), "providers", "add", "corp", "--kind", "saml")
+		assert.ErrorContains(t, err, "one of saml-metadata-url or saml-metadata is required")
+	})
+
+	t.Run("saml flags with an oidc provider", func(t *testing.T) {
+		setup(t)
+
+		err := Run(context.Background(),
+			"providers", "add", "okta",
+			"--url", "https://okta.com/path",
+			"--client-id", "okta-client-id",
+			"--client-secret", "okta-client-secret",
+			"--saml-groups-attribute", "groups",
+		)
+		assert.ErrorContains(t, err, "saml flags are only applicable to SAML identity providers")
+	})
+
+	t.Run("ldap provider", func(t *testing.T) {
+		ch, _ := setup(t)
+		t.Setenv("INFRA_PROVIDER_LDAP_BIND_PASSWORD", "service-password")
+
+		err := Run(context.Background(),
+			"providers", "add", "ad",
+			"--kind", "ldap",
+			"--url", "ldaps://ad.example.com",
+			"--ldap-bind-dn", "cn=infra,dc=example,dc=com",
+			"--ldap-user-base-dn", "dc=example,dc=com",
+			"--ldap-user-name-attribute", "userPrincipalName",
+		)
+		assert.NilError(t, err)
+
+		createProviderRequest := <-ch
+
+		expected := api.CreateProviderRequest{
+			Name: "ad",
+			URL:  "ldaps://ad.example.com",
+			Kind: "ldap",
+			API:  &api.ProviderAPICredentials{},
+			LDAP: &api.ProviderLDAP{
+				BindDN:            "cn=infra,dc=example,dc=com",
+				BindPassword:      "service-password",
+				UserBaseDN:        "dc=example,dc=com",
+				UserNameAttribute: "userPrincipalName",
+			},
+		}
+		assert.DeepEqual(t, createProviderRequest, expected)
+	})
+
+	t.Run("ldap provider without user base dn", func(t *testing.T) {
+		setup(t)
+
+		err := Run(context.Background(), "providers", "add", "ad", "--kind", "ldap", "--url", "ldap://ad.example.com")
+		assert.ErrorContains(t, err, "missing value for required flags: ldap-user-base-dn")
+	})
+
+	t.Run("ldap flags with an oidc provider", func(t *testing.T) {
+		setup(t)
+
+		err := Run(context.Background(),
+			"providers", "add", "okta",
+			"--url", "https://okta.com/path",
+			"--client-id", "okta-client-id",
+			"--client-secret", "okta-client-secret",
+			"--ldap-start-tls",
+		)
+		assert.ErrorContains(t, err, "ldap flags are only applicable to LDAP identity providers")
+	})
+
 	t.Run("google provider with no api flags", func(t *testing.T) {
 		ch, _ := setup(t)
 
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+)
+
+func newRolesCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "roles",
+		Short:   "Manage custom roles",
+		Aliases: []string{"role"},
+		GroupID: groupManagement,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newRolesAddCmd(cli))
+	cmd.AddCommand(newRolesListCmd(cli))
+	cmd.AddCommand(newRolesRemoveCmd(cli))
+
+	return cmd
+}
+
+func newRolesListCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List custom roles",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			roles, err := listAll(context.Background(), client.ListRoles, api.ListRolesRequest{})
+			if err != nil {
+				return err
+			}
+
+			type row struct {
+				Name            string `header:"Name"`
+				KubernetesRoles string `header:"Kubernetes Roles"`
+				InfraRoles      string `header:"Infra Roles"`
+				Permissions     string `header:"Permissions"`
+			}
+
+			var rows []row
+			for _, role := range roles {
+				rows = append(rows, row{
+					Name:            role.Name,
+					KubernetesRoles: strings.Join(role.KubernetesRoles, ", "),
+					InfraRoles:      strings.Join(role.InfraRoles, ", "),
+					Permissions:     strings.Join(role.Permissions, ", "),
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No roles found")
+			}
+
+			return nil
+		},
+	}
+}
+
+type rolesAddOptions struct {
+	KubernetesRoles []string
+	InfraRoles      []string
+	Permissions     []string
+}
+
+func newRolesAddCmd(cli *CLI) *cobra.Command {
+	var options rolesAddOptions
+
+	cmd := &cobra.Command{
+		Use:   "add ROLE",
+		Short: "Create a custom role",
+		Long: `Create a custom role from Kubernetes roles, infra roles, and permissions.
+
+Grant the role to a user or group with 'infra grants add USER DESTINATION --role ROLE'.
+The connector binds each of the Kubernetes roles of the role, and the infra
+roles of the role apply when it is granted on the infra resource.`,
+		Args: ExactArgs(1),
+		Example: `# Create a role that can edit and read logs in Kubernetes
+$ infra roles add developer --kubernetes-role edit,logs --permission connect
+
+# Create a role that can view infra and the Kubernetes cluster
+$ infra roles add auditor --infra-role view --kubernetes-role view`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := &api.CreateRoleRequest{
+				Name:            args[0],
+				KubernetesRoles: options.KubernetesRoles,
+				InfraRoles:      options.InfraRoles,
+				Permissions:     options.Permissions,
+			}
+			if _, err := client.CreateRole(context.Background(), req); err != nil {
+				return err
+			}
+			cli.Output("Added role %q", args[0])
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringSliceVar(&options.KubernetesRoles, "kubernetes-role", nil, "Kubernetes ClusterRole included in the role")
+	flags.StringSliceVar(&options.InfraRoles, "infra-role", nil, "Infra role included in the role [admin, view]")
+	flags.StringSliceVar(&options.Permissions, "permission", nil, "Permission included in the role [connect]")
+	return cmd
+}
+
+func newRolesRemoveCmd(cli *CLI) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:     "remove ROLE",
+		Aliases: []string{"rm"},
+		Short:   "Delete a custom role",
+		Long:    "Delete a custom role, and all of the grants of the role.",
+		Args:    ExactArgs(1),
+		Example: `# Delete a role
+$ infra roles remove developer`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+
+			roles, err := client.ListRoles(ctx, api.ListRolesRequest{Name: name})
+			if err != nil {
+				return err
+			}
+
+			if roles.Count == 0 && !force {
+				return Error{Message: fmt.Sprintf("unknown role %q", name)}
+			}
+
+			for _, role := range roles.Items {
+				if err := client.DeleteRole(ctx, role.ID); err != nil {
+					return err
+				}
+
+				cli.Output("Removed role %q", role.Name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if the role does not exist")
+
+	return cmd
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestRolesCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	type state struct {
+		created []api.CreateRoleRequest
+		deleted []string
+	}
+
+	setup := func(t *testing.T) *state {
+		s := &state{}
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/roles"):
+				listResp := api.ListResponse[api.Role]{
+					Items: []api.Role{
+						{ID: 100, Name: "developer", KubernetesRoles: []string{"edit", "logs"}, Permissions: []string{"connect"}},
+						{ID: 101, Name: "auditor", KubernetesRoles: []string{"view"}, InfraRoles: []string{"view"}},
+					},
+					Count: 2,
+				}
+				switch req.URL.Query().Get("name") {
+				case "":
+				case "developer":
+					listResp = api.ListResponse[api.Role]{Items: listResp.Items[:1], Count: 1}
+				default:
+					listResp = api.ListResponse[api.Role]{}
+				}
+				writeResponse(t, resp, listResp)
+			case requestMatches(req, http.MethodPost, "/api/roles"):
+				var createReq api.CreateRoleRequest
+				assert.Check(t, json.NewDecoder(req.Body).Decode(&createReq))
+				s.created = append(s.created, createReq)
+				writeResponse(t, resp, api.Role{ID: 102, Name: createReq.Name})
+			case requestMatchesPrefix(req, http.MethodDelete, "/api/roles/"):
+				s.deleted = append(s.deleted, req.URL.Path)
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		assert.NilError(t, writeConfig(&cfg))
+		return s
+	}
+
+	t.Run("add", func(t *testing.T) {
+		s := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "roles", "add", "developer",
+			"--kubernetes-role", "edit,logs", "--permission", "connect")
+		assert.NilError(t, err)
+
+		expected := []api.CreateRoleRequest{
+			{Name: "developer", KubernetesRoles: []string{"edit", "logs"}, Permissions: []string{"connect"}},
+		}
+		assert.DeepEqual(t, s.created, expected)
+		assert.Equal(t, bufs.Stdout.String(), `Added role "developer"`+"\n")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "roles", "list")
+		assert.NilError(t, err)
+
+		golden.Assert(t, bufs.Stdout.String(), t.Name())
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		s := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "roles", "remove", "developer")
+		assert.NilError(t, err)
+
+		assert.DeepEqual(t, s.deleted, []string{"/api/roles/" + uid.ID(100).String()})
+		assert.Equal(t, bufs.Stdout.String(), `Removed role "developer"`+"\n")
+	})
+
+	t.Run("remove unknown role", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "roles", "remove", "nonexistent")
+		assert.ErrorContains(t, err, `unknown role "nonexistent"`)
+	})
+
+	t.Run("remove unknown role with force", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "roles", "remove", "nonexistent", "--force")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "")
+	})
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,8 +18,9 @@ import (
 	"github.com/infrahq/infra/internal/server/redis"
 )
 
-func newServerCmd() *cobra.Command {
+func newServerCmd(cli *CLI) *cobra.Command {
 	var configFilename string
+	var validateConfig bool
 
 	cmd := &cobra.Command{
 		Use:    "server",
@@ -28,44 +30,85 @@ func newServerCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			logging.UseServerLogger()
 
-			if configFilename == "" {
-				configFilename = os.Getenv("INFRA_SERVER_CONFIG_FILE")
-			}
-
-			infraDir, err := infraHomeDir()
+			options, err := loadServerOptions(cmd, configFilename)
 			if err != nil {
 				return err
 			}
-			options := defaultServerOptions(infraDir)
 
-			if err := server.ApplyOptions(&options, configFilename, cmd.Flags()); err != nil {
-				return err
+			if validateConfig {
+				changes, err := planConfig(options)
+				switch {
+				case errors.Is(err, data.ErrMigrationsPending):
+					return fmt.Errorf("cannot validate config until the database is migrated: %w", err)
+				case err != nil:
+					return fmt.Errorf("invalid config: %w", err)
+				}
+				printConfigChanges(cli, changes)
+				return nil
 			}
 
-			tlsCache, err := canonicalPath(options.TLSCache)
+			srv, err := newServer(options)
 			if err != nil {
-				return err
+				return fmt.Errorf("creating server: %w", err)
 			}
+			return runServer(cmd.Context(), srv)
+		},
+	}
 
-			options.TLSCache = tlsCache
+	cmd.Flags().StringVarP(&configFilename, "config-file", "f", "", "Server configuration file")
+	cmd.Flags().BoolVar(&validateConfig, "validate-config", false, "Validate the configuration, and print the changes it would make without applying them. Database migrations must be applied first")
+	cmd.Flags().Bool("reconcile", false, "Manage providers, users, and grants only from the configuration, and remove anything else it created")
+	cmd.Flags().String("tls-cache", "", "Directory to cache TLS certificates")
+	addServerDBFlags(cmd)
+	cmd.Flags().Bool("enable-telemetry", false, "Enable telemetry")
+	cmd.Flags().Var(&types.URL{}, "ui-proxy-url", "Enable UI and proxy requests to this url")
+	cmd.Flags().Duration("session-duration", 0, "Maximum session duration per user login")
+	cmd.Flags().Duration("session-inactivity-timeout", 0, "A user must interact with Infra at least once within this amount of time for their session to remain valid")
+	cmd.Flags().Bool("enable-signup", false, "Enable one-time admin signup")
+	cmd.Flags().String("base-domain", "", "base-domain for the server, eg example.com")
+	cmd.Flags().String("google-client-id", "", "Client ID of the Google client used for social login")
+	cmd.Flags().String("google-client-secret", "", "Client secret of the Google client used for social login")
+
+	cmd.AddCommand(newServerRotateDBKeyCmd(cli))
+
+	return cmd
+}
+
+func newServerRotateDBKeyCmd(cli *CLI) *cobra.Command {
+	var configFilename string
 
-			dbEncryptionKey, err := canonicalPath(options.DBEncryptionKey)
+	cmd := &cobra.Command{
+		Use:   "rotate-db-key",
+		Short: "Rotate the key used to encrypt secrets in the database",
+		Long: `Rotate the key used to encrypt secrets in the database.
+
+A new database key is generated with the database encryption key provider, and
+every encrypted value is re-encrypted with the new key. Older keys are kept, so
+that running servers can continue to decrypt values while they are re-encrypted.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			logging.UseServerLogger()
+
+			options, err := loadServerOptions(cmd, configFilename)
 			if err != nil {
 				return err
 			}
 
-			options.DBEncryptionKey = dbEncryptionKey
-
-			srv, err := newServer(options)
+			count, err := rotateDBKey(options)
 			if err != nil {
-				return fmt.Errorf("creating server: %w", err)
+				return fmt.Errorf("rotate database key: %w", err)
 			}
-			return runServer(cmd.Context(), srv)
+			cli.Output("Rotated database key, re-encrypted %d values", count)
+			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&configFilename, "config-file", "f", "", "Server configuration file")
-	cmd.Flags().String("tls-cache", "", "Directory to cache TLS certificates")
+	addServerDBFlags(cmd)
+	return cmd
+}
+
+func addServerDBFlags(cmd *cobra.Command) {
 	cmd.Flags().String("db-name", "", "Database name")
 	cmd.Flags().String("db-host", "", "Database host")
 	cmd.Flags().Int("db-port", 0, "Database port")
@@ -74,16 +117,39 @@ func newServerCmd() *cobra.Command {
 	cmd.Flags().String("db-parameters", "", "Database additional connection parameters")
 	cmd.Flags().String("db-encryption-key", "", "Database encryption key")
 	cmd.Flags().String("db-encryption-key-provider", "", "Database encryption key provider")
-	cmd.Flags().Bool("enable-telemetry", false, "Enable telemetry")
-	cmd.Flags().Var(&types.URL{}, "ui-proxy-url", "Enable UI and proxy requests to this url")
-	cmd.Flags().Duration("session-duration", 0, "Maximum session duration per user login")
-	cmd.Flags().Duration("session-inactivity-timeout", 0, "A user must interact with Infra at least once within this amount of time for their session to remain valid")
-	cmd.Flags().Bool("enable-signup", false, "Enable one-time admin signup")
-	cmd.Flags().String("base-domain", "", "base-domain for the server, eg example.com")
-	cmd.Flags().String("google-client-id", "", "Client ID of the Google client used for social login")
-	cmd.Flags().String("google-client-secret", "", "Client secret of the Google client used for social login")
+}
 
-	return cmd
+// loadServerOptions loads the server options from the defaults, the config
+// file, environment variables, and the command line flags.
+func loadServerOptions(cmd *cobra.Command, configFilename string) (server.Options, error) {
+	if configFilename == "" {
+		configFilename = os.Getenv("INFRA_SERVER_CONFIG_FILE")
+	}
+
+	infraDir, err := infraHomeDir()
+	if err != nil {
+		return server.Options{}, err
+	}
+	options := defaultServerOptions(infraDir)
+
+	if err := server.ApplyOptions(&options, configFilename, cmd.Flags()); err != nil {
+		return server.Options{}, err
+	}
+
+	tlsCache, err := canonicalPath(options.TLSCache)
+	if err != nil {
+		return server.Options{}, err
+	}
+
+	options.TLSCache = tlsCache
+
+	dbEncryptionKey, err := canonicalPath(options.DBEncryptionKey)
+	if err != nil {
+		return server.Options{}, err
+	}
+
+	options.DBEncryptionKey = dbEncryptionKey
+	return options, nil
 }
 
 func defaultServerOptions(infraDir string) server.Options {
@@ -130,6 +196,24 @@ var runServer = func(ctx context.Context, srv *server.Server) error {
 // newServer is a shim for testing.
 var newServer = server.New
 
+// planConfig is a shim for testing.
+var planConfig = server.PlanConfig
+
+// rotateDBKey is a shim for testing.
+var rotateDBKey = server.RotateDBKey
+
+func printConfigChanges(cli *CLI, changes []server.ConfigChange) {
+	if len(changes) == 0 {
+		cli.Output("Configuration is valid, no changes to apply")
+		return
+	}
+
+	cli.Output("Configuration is valid, loading it will make these changes:")
+	for _, change := range changes {
+		cli.Output("  %v", change)
+	}
+}
+
 func canonicalPath(path string) (string, error) {
 	path = os.ExpandEnv(path)
 
@@ -39,7 +39,7 @@ func TestServerCmd_LoadOptions(t *testing.T) {
 		var actual server.Options
 		patchNewServer(t, &actual)
 
-		cmd := newServerCmd()
+		cmd := newServerCmd(newCLI(context.Background()))
 		cmd.SetArgs([]string{}) // prevent reading of os.Args
 		if tc.setup != nil {
 			tc.setup(t, cmd)
@@ -190,6 +190,8 @@ users:
     accessKey: access-key
     password: the-password
 
+reconcile: true
+
 redis:
   host: myredis
   username: myuser
@@ -290,6 +292,7 @@ api:
 								Password:  "the-password",
 							},
 						},
+						Reconcile: true,
 					},
 
 					DB: data.NewDBOptions{
@@ -381,6 +384,65 @@ func TestServerCmd_WithSecretsConfig(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestServerCmd_ValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir) // Windows
+
+	patchRunServer(t, func(context.Context, *server.Server) error {
+		t.Fatal("server should not run when validating config")
+		return nil
+	})
+
+	var actual server.Options
+	orig := planConfig
+	t.Cleanup(func() {
+		planConfig = orig
+	})
+	planConfig = func(options server.Options) ([]server.ConfigChange, error) {
+		actual = options
+		return []server.ConfigChange{
+			{Action: server.ConfigChangeCreate, Kind: "user", Name: "alice@example.com"},
+			{Action: server.ConfigChangeAdopt, Kind: "grant", Name: "alice@example.com admin infra"},
+			{Action: server.ConfigChangeDelete, Kind: "provider", Name: "okta"},
+		}, nil
+	}
+
+	content := `
+reconcile: true
+grants:
+  - user: alice@example.com
+    resource: infra
+    role: admin
+`
+	cfgDir := fs.NewDir(t, t.Name(), fs.WithFile("cfg.yaml", content))
+
+	ctx, bufs := PatchCLI(context.Background())
+	err := Run(ctx, "server", "--validate-config", "--config-file", cfgDir.Join("cfg.yaml"))
+	assert.NilError(t, err)
+
+	assert.Assert(t, actual.Reconcile)
+	assert.Equal(t, len(actual.Grants), 1)
+
+	expected := `Configuration is valid, loading it will make these changes:
+  create user "alice@example.com"
+  adopt grant "alice@example.com admin infra"
+  delete provider "okta"
+`
+	assert.Equal(t, bufs.Stdout.String(), expected)
+
+	t.Run("migrations pending", func(t *testing.T) {
+		planConfig = func(options server.Options) ([]server.ConfigChange, error) {
+			return nil, fmt.Errorf("open db: %w, 2 migrations have not been applied", data.ErrMigrationsPending)
+		}
+
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "server", "--validate-config", "--config-file", cfgDir.Join("cfg.yaml"))
+		assert.ErrorIs(t, err, data.ErrMigrationsPending)
+		assert.ErrorContains(t, err, "cannot validate config until the database is migrated")
+	})
+}
+
 func patchRunServer(t *testing.T, fn func(context.Context, *server.Server) error) {
 	orig := runServer
 	runServer = fn
@@ -406,7 +468,7 @@ func patchNewServer(t *testing.T, target *server.Options) {
 }
 
 func TestServerCmd_NoFlagDefaults(t *testing.T) {
-	cmd := newServerCmd()
+	cmd := newServerCmd(newCLI(context.Background()))
 	flags := cmd.Flags()
 	err := flags.Parse(nil)
 	assert.NilError(t, err)
@@ -465,3 +527,34 @@ func TestCanonicalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestServerCmd_RotateDBKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir) // Windows
+
+	var actual server.Options
+	orig := rotateDBKey
+	t.Cleanup(func() {
+		rotateDBKey = orig
+	})
+	rotateDBKey = func(options server.Options) (int, error) {
+		actual = options
+		return 12, nil
+	}
+
+	content := `
+dbEncryptionKey: /var/lib/infra/db.key
+dbEncryptionKeyProvider: native
+`
+	cfgDir := fs.NewDir(t, t.Name(), fs.WithFile("cfg.yaml", content))
+
+	ctx, bufs := PatchCLI(context.Background())
+	err := Run(ctx, "server", "rotate-db-key", "--config-file", cfgDir.Join("cfg.yaml"), "--db-host", "db.example.com")
+	assert.NilError(t, err)
+
+	assert.Equal(t, actual.DBEncryptionKey, "/var/lib/infra/db.key")
+	assert.Equal(t, actual.DBEncryptionKeyProvider, "native")
+	assert.Equal(t, actual.DBHost, "db.example.com")
+	assert.Equal(t, bufs.Stdout.String(), "Rotated database key, re-encrypted 12 values\n")
+}
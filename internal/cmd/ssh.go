@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+// useSSHDestination writes an ssh config entry for an SSH destination, with a
+// new key and a short-lived certificate signed by the Infra server. The files
+// are written to ~/.ssh/infra, and ~/.ssh/config is updated to include them.
+func useSSHDestination(cli *CLI, client *api.Client, destination api.Destination) error {
+	if destination.Connection.URL == "" {
+		return Error{Message: fmt.Sprintf("Destination %q is not connected", destination.Name)}
+	}
+	conn, err := destination.Connection.ParseSSH()
+	if err != nil {
+		return Error{Message: fmt.Sprintf("Destination %q has an invalid connection: %v", destination.Name, err)}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	sshDir := filepath.Join(homeDir, ".ssh")
+	infraSSHDir := filepath.Join(sshDir, "infra")
+	if err := os.MkdirAll(infraSSHDir, 0o700); err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	logging.Debugf("call server: create ssh certificate for %s", destination.Name)
+	cert, err := client.CreateSSHCertificate(context.Background(), &api.CreateSSHCertificateRequest{
+		Destination: destination.Name,
+		PublicKey:   string(ssh.MarshalAuthorizedKey(publicKey)),
+	})
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			logging.Debugf("%s", err.Error())
+			return Error{Message: fmt.Sprintf("You do not have access to %q", destination.Name)}
+		}
+		return err
+	}
+	if strings.IndexFunc(cert.Username, isNotSSHConfigValue) >= 0 {
+		return fmt.Errorf("invalid ssh username %q", cert.Username)
+	}
+
+	keyFile := filepath.Join(infraSSHDir, destination.Name)
+	certFile := keyFile + "-cert.pub"
+	knownHostsFile := keyFile + ".known_hosts"
+
+	files := []struct {
+		path    string
+		content []byte
+	}{
+		{path: keyFile, content: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey})},
+		{path: certFile, content: []byte(cert.Certificate)},
+		{path: knownHostsFile, content: []byte(knownHostsLine(conn.Host, conn.Port, conn.HostKey))},
+		{path: keyFile + ".conf", content: []byte(sshHostConfig(sshHostOptions{
+			Alias:          destination.Name,
+			HostName:       conn.Host,
+			Port:           conn.Port,
+			User:           cert.Username,
+			IdentityFile:   keyFile,
+			CertFile:       certFile,
+			KnownHostsFile: knownHostsFile,
+		}))},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.path, f.content, 0o600); err != nil {
+			return err
+		}
+	}
+
+	if err := includeInSSHConfig(filepath.Join(sshDir, "config"), filepath.Join(infraSSHDir, "*.conf")); err != nil {
+		return fmt.Errorf("update ssh config: %w", err)
+	}
+
+	cli.Output("Access to %q expires at %s, run 'infra use %s' again to renew it", destination.Name, cert.Expires, destination.Name)
+	cli.Output("Connect with: ssh %s", destination.Name)
+	return nil
+}
+
+type sshHostOptions struct {
+	Alias          string
+	HostName       string
+	Port           string
+	User           string
+	IdentityFile   string
+	CertFile       string
+	KnownHostsFile string
+}
+
+func sshHostConfig(opts sshHostOptions) string {
+	return fmt.Sprintf(`# This file is managed by Infra. Changes will be overwritten by 'infra use'.
+Host %v
+    HostName %v
+    Port %v
+    User %v
+    IdentityFile "%v"
+    CertificateFile "%v"
+    UserKnownHostsFile "%v"
+    IdentitiesOnly yes
+`, opts.Alias, opts.HostName, opts.Port, opts.User, opts.IdentityFile, opts.CertFile, opts.KnownHostsFile)
+}
+
+// isNotSSHConfigValue returns true for characters that would change the
+// meaning of an ssh config file when used in a value.
+func isNotSSHConfigValue(c rune) bool {
+	return unicode.IsControl(c) || unicode.IsSpace(c) || c == '"'
+}
+
+// knownHostsLine returns a known_hosts entry for the host key sent by the
+// connector in the destination connection CA.
+func knownHostsLine(host, port string, hostKey ssh.PublicKey) string {
+	if port != "" && port != "22" {
+		host = fmt.Sprintf("[%v]:%v", host, port)
+	}
+	return host + " " + string(ssh.MarshalAuthorizedKey(hostKey))
+}
+
+// includeInSSHConfig adds an Include directive for pattern to the top of the
+// ssh config file, unless it is already included. The Include must be at the
+// top of the file so that it does not become part of another Host block.
+func includeInSSHConfig(filename, pattern string) error {
+	include := fmt.Sprintf("Include \"%v\"\n", pattern)
+
+	existing, err := os.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case bytes.Contains(existing, []byte(include)):
+		return nil
+	}
+
+	content := append([]byte(include+"\n"), existing...)
+	return os.WriteFile(filename, content, 0o600)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestUse_SSHDestination(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // Windows
+
+	userID := uid.New()
+	hostKey := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBnhlEzJfX3SaR5FRHqU1Xq0hbrnWcYt9bOKeH8WZHBC"
+
+	connection := api.DestinationConnection{URL: "web-1.example.com:2222", CA: api.PEM(hostKey)}
+	var certRequest api.CreateSSHCertificateRequest
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		switch {
+		case requestMatches(req, http.MethodGet, "/api/destinations"):
+			writeResponse(t, resp, api.ListResponse[api.Destination]{
+				Count: 1,
+				Items: []api.Destination{{
+					ID:         uid.New(),
+					Name:       "web-1",
+					Kind:       "ssh",
+					Connection: connection,
+				}},
+			})
+		case requestMatches(req, http.MethodPost, "/api/ssh/certificates"):
+			assert.Check(t, json.NewDecoder(req.Body).Decode(&certRequest))
+			resp.WriteHeader(http.StatusCreated)
+			writeResponse(t, resp, api.SSHCertificate{
+				Certificate: "ssh-ed25519-cert-v01@openssh.com AAAA the-cert\n",
+				Username:    "alice",
+				Expires:     api.Time(time.Now().Add(time.Hour)),
+			})
+		default:
+			resp.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{ID: userID})
+	assert.NilError(t, writeConfig(&cfg))
+
+	sshConfig := filepath.Join(home, ".ssh", "config")
+	assert.NilError(t, os.MkdirAll(filepath.Dir(sshConfig), 0o700))
+	assert.NilError(t, os.WriteFile(sshConfig, []byte("Host other\n    User bob\n"), 0o600))
+
+	ctx, bufs := PatchCLI(context.Background())
+	err := Run(ctx, "use", "web-1")
+	assert.NilError(t, err)
+	assert.Equal(t, certRequest.Destination, "web-1")
+	assert.Assert(t, certRequest.PublicKey != "")
+	assert.Assert(t, bufs.Stdout.String() != "")
+
+	infraDir := filepath.Join(home, ".ssh", "infra")
+	expectedInclude := "Include \"" + filepath.Join(infraDir, "*.conf") + "\"\n\nHost other\n    User bob\n"
+	content, err := os.ReadFile(sshConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), expectedInclude)
+
+	content, err = os.ReadFile(filepath.Join(infraDir, "web-1.known_hosts"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "[web-1.example.com]:2222 "+hostKey+"\n")
+
+	content, err = os.ReadFile(filepath.Join(infraDir, "web-1-cert.pub"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "ssh-ed25519-cert-v01@openssh.com AAAA the-cert\n")
+
+	content, err = os.ReadFile(filepath.Join(infraDir, "web-1.conf"))
+	assert.NilError(t, err)
+	assert.Assert(t, len(content) > 0)
+
+	t.Run("include is only added once", func(t *testing.T) {
+		err := Run(context.Background(), "use", "web-1")
+		assert.NilError(t, err)
+
+		content, err := os.ReadFile(sshConfig)
+		assert.NilError(t, err)
+		assert.Equal(t, string(content), expectedInclude)
+	})
+
+	t.Run("invalid connection is rejected", func(t *testing.T) {
+		connection.URL = "web-1.example.com:2222\n    ProxyCommand touch /tmp/pwned"
+		t.Cleanup(func() {
+			connection.URL = "web-1.example.com:2222"
+		})
+
+		err := Run(context.Background(), "use", "web-1")
+		assert.ErrorContains(t, err, "invalid connection")
+
+		content, err := os.ReadFile(filepath.Join(infraDir, "web-1.conf"))
+		assert.NilError(t, err)
+		assert.Assert(t, !strings.Contains(string(content), "ProxyCommand"))
+	})
+}
+
+func TestSSHHostConfig(t *testing.T) {
+	actual := sshHostConfig(sshHostOptions{
+		Alias:          "web-1",
+		HostName:       "web-1.example.com",
+		Port:           "22",
+		User:           "alice",
+		IdentityFile:   "/home/alice/.ssh/infra/web-1",
+		CertFile:       "/home/alice/.ssh/infra/web-1-cert.pub",
+		KnownHostsFile: "/home/alice/.ssh/infra/web-1.known_hosts",
+	})
+	expected := `# This file is managed by Infra. Changes will be overwritten by 'infra use'.
+Host web-1
+    HostName web-1.example.com
+    Port 22
+    User alice
+    IdentityFile "/home/alice/.ssh/infra/web-1"
+    CertificateFile "/home/alice/.ssh/infra/web-1-cert.pub"
+    UserKnownHostsFile "/home/alice/.ssh/infra/web-1.known_hosts"
+    IdentitiesOnly yes
+`
+	assert.Equal(t, actual, expected)
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
 )
 
 func newUseCmd(cli *CLI) *cobra.Command {
@@ -15,7 +18,10 @@ func newUseCmd(cli *CLI) *cobra.Command {
 $ infra use development
 
 # Use a Kubernetes namespace context
-$ infra use development.kube-system`,
+$ infra use development.kube-system
+
+# Use an SSH destination, then connect with 'ssh web-1'
+$ infra use web-1`,
 		Args:              ExactArgs(1),
 		GroupID:           groupCore,
 		ValidArgsFunction: getUseCompletion,
@@ -33,6 +39,18 @@ $ infra use development.kube-system`,
 				return err
 			}
 
+			parts := strings.Split(destination, ".")
+
+			destinations, err := client.ListDestinations(context.Background(), api.ListDestinationsRequest{Name: parts[0]})
+			if err != nil {
+				return err
+			}
+			for _, d := range destinations.Items {
+				if d.Name == parts[0] && d.Kind == "ssh" {
+					return useSSHDestination(cli, client, d)
+				}
+			}
+
 			config, err := currentHostConfig()
 			if err != nil {
 				return err
@@ -43,8 +61,6 @@ $ infra use development.kube-system`,
 				return err
 			}
 
-			parts := strings.Split(destination, ".")
-
 			if len(parts) == 1 {
 				return kubernetesSetContext(destination, "")
 			}
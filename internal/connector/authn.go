@@ -19,7 +19,7 @@ import (
 
 type authenticator struct {
 	mu          sync.Mutex
-	key         *jose.JSONWebKey
+	keys        []jose.JSONWebKey
 	lastChecked time.Time
 
 	client          httpClient
@@ -42,6 +42,10 @@ func newAuthenticator(url string, options Options) *authenticator {
 
 var JWKCacheRefresh = 5 * time.Minute
 
+// JWKUnknownKeyRefresh is the minimum time between requests for the JWKs
+// that are made because a JWT was signed with a key that is not in the cache.
+var JWKUnknownKeyRefresh = 10 * time.Second
+
 func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
 	c := claims.Custom{}
 	authHeader := req.Header.Get("Authorization")
@@ -56,7 +60,12 @@ func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
 		return c, fmt.Errorf("invalid JWT signature: %w", err)
 	}
 
-	key, err := j.getJWK()
+	var kid string
+	if len(tok.Headers) > 0 {
+		kid = tok.Headers[0].KeyID
+	}
+
+	key, err := j.getJWK(kid)
 	if err != nil {
 		return c, fmt.Errorf("get JWK from server: %w", err)
 	}
@@ -84,33 +93,70 @@ func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
 	return allClaims.Custom, nil
 }
 
-func (j *authenticator) getJWK() (*jose.JSONWebKey, error) {
+// getJWK returns the JWK with the key ID kid. The JWKs are cached, and
+// are fetched again when the cache is stale, or when the server may have
+// rotated its key since the JWKs were cached.
+func (j *authenticator) getJWK(kid string) (*jose.JSONWebKey, error) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
-	if !j.lastChecked.IsZero() && time.Now().Before(j.lastChecked.Add(JWKCacheRefresh)) {
-		return j.key, nil
+	if j.lastChecked.IsZero() || time.Now().After(j.lastChecked.Add(JWKCacheRefresh)) {
+		if err := j.fetchJWKs(); err != nil {
+			return nil, err
+		}
+	}
+
+	if key := findJWK(j.keys, kid); key != nil {
+		return key, nil
+	}
+
+	if time.Now().Before(j.lastChecked.Add(JWKUnknownKeyRefresh)) {
+		return nil, fmt.Errorf("no JWK with key ID %q", kid)
+	}
+	if err := j.fetchJWKs(); err != nil {
+		return nil, err
+	}
+	if key := findJWK(j.keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no JWK with key ID %q", kid)
+}
+
+// findJWK returns the key with the key ID kid. JWTs issued by older versions
+// of the server do not have a key ID, and are verified with the first key,
+// which is the key the server is using to sign JWTs.
+func findJWK(keys []jose.JSONWebKey, kid string) *jose.JSONWebKey {
+	if kid == "" && len(keys) > 0 {
+		return &keys[0]
 	}
+	for i := range keys {
+		if keys[i].KeyID == kid {
+			return &keys[i]
+		}
+	}
+	return nil
+}
 
+func (j *authenticator) fetchJWKs() error {
 	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, fmt.Sprintf("%s/.well-known/jwks.json", j.baseURL), nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	req.Header.Set("Authorization", "Bearer "+j.serverAccessKey)
 
 	res, err := j.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return nil, fmt.Errorf("unexpected response: %v ", res.Status)
+		return fmt.Errorf("unexpected response: %v ", res.Status)
 	}
 
 	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var response struct {
@@ -119,15 +165,14 @@ func (j *authenticator) getJWK() (*jose.JSONWebKey, error) {
 
 	err = json.Unmarshal(data, &response)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if len(response.Keys) < 1 {
-		return nil, errors.New("no jwks provided by infra")
+		return errors.New("no jwks provided by infra")
 	}
 
 	j.lastChecked = time.Now().UTC()
-	j.key = &response.Keys[0]
-
-	return &response.Keys[0], nil
+	j.keys = response.Keys
+	return nil
 }
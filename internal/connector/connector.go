@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -47,7 +48,16 @@ type Options struct {
 	// Destination.Connection.URL.
 	EndpointAddr types.HostPort
 
+	// Kind is the kind of destination managed by the connector, either
+	// kubernetes or ssh. The default is kubernetes.
+	Kind string
+
 	Kubernetes KubernetesOptions
+	SSH        SSHOptions
+
+	// DryRun logs the changes the connector would make to the role bindings
+	// in the cluster, instead of applying them.
+	DryRun bool
 }
 
 type ServerOptions struct {
@@ -90,28 +100,47 @@ type connector struct {
 
 type apiClient interface {
 	ListGrants(ctx context.Context, req api.ListGrantsRequest) (*api.ListResponse[api.Grant], error)
+	WatchGrants(ctx context.Context, req api.WatchGrantsRequest, fn func(api.GrantEvent) error) error
+	ListUsers(ctx context.Context, req api.ListUsersRequest) (*api.ListResponse[api.User], error)
 	ListDestinations(ctx context.Context, req api.ListDestinationsRequest) (*api.ListResponse[api.Destination], error)
 	CreateDestination(ctx context.Context, req *api.CreateDestinationRequest) (*api.Destination, error)
 	UpdateDestination(ctx context.Context, req api.UpdateDestinationRequest) (*api.Destination, error)
+	ListRoles(ctx context.Context, req api.ListRolesRequest) (*api.ListResponse[api.Role], error)
+	ListNestedGroups(ctx context.Context, id uid.ID) (*api.ListResponse[api.Group], error)
 
-	// GetGroup and GetUser are used to retrieve the name of the group or user.
-	// TODO: we can remove these calls to GetGroup and GetUser by including
-	// the name of the group or user in the ListGrants response.
+	// GetGroup and GetUser are used to retrieve the name of the group or user
+	// when the server does not include Grant.SubjectName in the response.
 	GetGroup(ctx context.Context, id uid.ID) (*api.Group, error)
 	GetUser(ctx context.Context, id uid.ID) (*api.User, error)
+
+	GetSSHCertificateAuthority(ctx context.Context) (*api.SSHCertificateAuthority, error)
 }
 
 type kubeClient interface {
 	Namespaces() ([]string, error)
 	ClusterRoles() ([]string, error)
+	NamespacedRoles() ([]string, error)
 	IsServiceTypeClusterIP() (bool, error)
 	Endpoint() (string, int, error)
 
 	UpdateClusterRoleBindings(subjects map[string][]rbacv1.Subject) error
-	UpdateRoleBindings(subjects map[kubernetes.ClusterRoleNamespace][]rbacv1.Subject) error
+	UpdateRoleBindings(subjects map[kubernetes.RoleNamespace][]rbacv1.Subject) error
+	DiffClusterRoleBindings(subjects map[string][]rbacv1.Subject) ([]kubernetes.BindingChange, error)
+	DiffRoleBindings(subjects map[kubernetes.RoleNamespace][]rbacv1.Subject) ([]kubernetes.BindingChange, error)
 }
 
 func Run(ctx context.Context, options Options) error {
+	switch options.Kind {
+	case "", "kubernetes":
+		return runKubernetes(ctx, options)
+	case "ssh":
+		return runSSH(ctx, options)
+	default:
+		return fmt.Errorf("unsupported destination kind %q, must be one of kubernetes or ssh", options.Kind)
+	}
+}
+
+func runKubernetes(ctx context.Context, options Options) error {
 	k8s, err := kubernetes.NewKubernetes(
 		options.Kubernetes.AuthToken.String(),
 		options.Kubernetes.Addr,
@@ -141,13 +170,6 @@ func Run(ctx context.Context, options Options) error {
 		return certCache.Certificate()
 	}
 
-	u, err := urlx.Parse(options.Server.URL)
-	if err != nil {
-		return fmt.Errorf("invalid server url: %w", err)
-	}
-
-	u.Scheme = "https"
-
 	destination := &api.Destination{
 		Name:     options.Name,
 		UniqueID: checkSum,
@@ -163,46 +185,9 @@ func Run(ctx context.Context, options Options) error {
 	defer cancel()
 
 	promRegistry := metrics.NewRegistry(internal.FullVersion())
-	responseDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Namespace: "http_client",
-		Name:      "request_duration_seconds",
-		Help:      "A histogram of duration, in seconds, performing HTTP requests.",
-		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
-	}, []string{"host", "method", "path", "status"})
-	promRegistry.MustRegister(responseDuration)
-
-	client := &api.Client{
-		Name:      "connector",
-		Version:   internal.Version,
-		URL:       u.String(),
-		AccessKey: options.Server.AccessKey.String(),
-		HTTP: http.Client{
-			Transport: httpTransportFromOptions(options.Server),
-		},
-		Headers: http.Header{
-			"Infra-Destination": {checkSum},
-		},
-		OnUnauthorized: func() {
-			logging.Errorf("Unauthorized error; token invalid or expired. exiting.")
-			cancel()
-		},
-		ObserveFunc: func(start time.Time, request *http.Request, response *http.Response, err error) {
-			statusLabel := ""
-			if response != nil {
-				statusLabel = strconv.Itoa(response.StatusCode)
-			}
-
-			if err != nil {
-				statusLabel = "-1"
-			}
-
-			responseDuration.With(prometheus.Labels{
-				"host":   request.URL.Host,
-				"method": request.Method,
-				"path":   request.URL.Path,
-				"status": statusLabel,
-			}).Observe(time.Since(start).Seconds())
-		},
+	client, err := newAPIClient(options.Server, checkSum, promRegistry, cancel)
+	if err != nil {
+		return err
 	}
 
 	group, ctx := errgroup.WithContext(ctx)
@@ -305,7 +290,7 @@ func Run(ctx context.Context, options Options) error {
 		return err
 	})
 
-	authn := newAuthenticator(u.String(), options)
+	authn := newAuthenticator(client.URL, options)
 	router.Use(
 		metrics.Middleware(promRegistry),
 		proxyMiddleware(proxy, authn, k8s.Config.BearerToken),
@@ -352,6 +337,60 @@ func Run(ctx context.Context, options Options) error {
 	return err
 }
 
+// newAPIClient returns a client for the infra API that records the duration of
+// requests in promRegistry. onUnauthorized is called when the access key is
+// no longer valid.
+func newAPIClient(opts ServerOptions, uniqueID string, promRegistry *prometheus.Registry, onUnauthorized func()) (*api.Client, error) {
+	u, err := urlx.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+
+	u.Scheme = "https"
+
+	responseDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "http_client",
+		Name:      "request_duration_seconds",
+		Help:      "A histogram of duration, in seconds, performing HTTP requests.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"host", "method", "path", "status"})
+	promRegistry.MustRegister(responseDuration)
+
+	return &api.Client{
+		Name:      "connector",
+		Version:   internal.Version,
+		URL:       u.String(),
+		AccessKey: opts.AccessKey.String(),
+		HTTP: http.Client{
+			Transport: httpTransportFromOptions(opts),
+		},
+		Headers: http.Header{
+			"Infra-Destination": {uniqueID},
+		},
+		OnUnauthorized: func() {
+			logging.Errorf("Unauthorized error; token invalid or expired. exiting.")
+			onUnauthorized()
+		},
+		ObserveFunc: func(start time.Time, request *http.Request, response *http.Response, err error) {
+			statusLabel := ""
+			if response != nil {
+				statusLabel = strconv.Itoa(response.StatusCode)
+			}
+
+			if err != nil {
+				statusLabel = "-1"
+			}
+
+			responseDuration.With(prometheus.Labels{
+				"host":   request.URL.Host,
+				"method": request.Method,
+				"path":   request.URL.Path,
+				"status": statusLabel,
+			}).Observe(time.Since(start).Seconds())
+		},
+	}, nil
+}
+
 func httpTransportFromOptions(opts ServerOptions) *http.Transport {
 	roots, err := x509.SystemCertPool()
 	if err != nil {
@@ -409,6 +448,12 @@ func syncDestination(ctx context.Context, con connector) error {
 		return fmt.Errorf("could not get kubernetes cluster-roles: %w", err)
 	}
 
+	namespacedRoles, err := con.k8s.NamespacedRoles()
+	if err != nil {
+		return fmt.Errorf("could not get kubernetes roles: %w", err)
+	}
+	roles := append(clusterRoles, namespacedRoles...)
+
 	switch {
 	case con.destination.ID == 0:
 		// TODO: move this warning somewhere earlier in startup
@@ -427,8 +472,8 @@ func syncDestination(ctx context.Context, con connector) error {
 		con.destination.Resources = namespaces
 		fallthrough
 
-	case !slicesEqual(con.destination.Roles, clusterRoles):
-		con.destination.Roles = clusterRoles
+	case !slicesEqual(con.destination.Roles, roles):
+		con.destination.Roles = roles
 		fallthrough
 
 	case string(con.destination.Connection.CA) != string(con.options.CACert):
@@ -464,42 +509,45 @@ type waiter interface {
 }
 
 func syncGrantsToKubeBindings(ctx context.Context, con connector, waiter waiter) error {
-	var latestIndex int64 = 1
-
-	sync := func() error {
-		grants, err := con.client.ListGrants(ctx, api.ListGrantsRequest{
-			Destination:     con.destination.Name,
-			BlockingRequest: api.BlockingRequest{LastUpdateIndex: latestIndex},
-		})
-		var apiError api.Error
-		switch {
-		case errors.As(err, &apiError) && apiError.Code == http.StatusNotModified:
-			// not modified is expected when there are no changes
-			logging.L.Info().
-				Int64("updateIndex", latestIndex).
-				Msg("no updated grants from server")
+	return syncGrants(ctx, con.client, con.destination.Name, waiter, func(grants []api.Grant) error {
+		if con.options.DryRun {
+			changes, err := RoleBindingChanges(ctx, con.client, con.k8s, grants)
+			if err != nil {
+				return fmt.Errorf("diff roles: %w", err)
+			}
+			logBindingChanges(changes)
 			return nil
-		case err != nil:
-			return fmt.Errorf("list grants: %w", err)
 		}
-		logging.L.Info().
-			Int64("updateIndex", grants.LastUpdateIndex.Index).
-			Int("grants", len(grants.Items)).
-			Msg("received grants from server")
-
-		err = updateRoles(ctx, con.client, con.k8s, grants.Items)
-		if err != nil {
+		if err := updateRoles(ctx, con.client, con.k8s, grants); err != nil {
 			return fmt.Errorf("update roles: %w", err)
 		}
-
-		// Only update latestIndex once the entire operation was a success
-		latestIndex = grants.LastUpdateIndex.Index
 		return nil
+	})
+}
+
+// syncGrants calls update with the grants for the destination every time the
+// grants change, until ctx is cancelled.
+//
+// The grants are listed once, and then kept up to date by applying the add and
+// delete events from the watch grants stream. When the stream ends, it is
+// resumed from the update index of the last change that was applied. Servers
+// that do not support the stream are polled with a blocking ListGrants instead.
+//
+// The stream may miss a change when the transaction that made it commits after
+// a change with a higher update index was sent, so all the grants are listed
+// again every grantsResyncInterval.
+func syncGrants(ctx context.Context, client apiClient, destinationName string, waiter waiter, update func([]api.Grant) error) error {
+	s := &grantsSync{
+		client:          client,
+		destinationName: destinationName,
+		update:          update,
+		latestIndex:     1,
+		resyncInterval:  grantsResyncInterval,
 	}
 
 	for {
-		if err := sync(); err != nil {
-			logging.L.Error().Err(err).Msg("sync grants with kubernetes")
+		if err := s.sync(ctx); err != nil {
+			logging.L.Error().Err(err).Msg("sync grants with destination")
 		} else {
 			waiter.Reset()
 		}
@@ -513,60 +561,300 @@ func syncGrantsToKubeBindings(ctx context.Context, con connector, waiter waiter)
 	}
 }
 
+// grantsResyncInterval is the time between listing all the grants of the
+// destination when the grants are kept up to date by the watch grants stream.
+const grantsResyncInterval = 10 * time.Minute
+
+type grantsSync struct {
+	client          apiClient
+	destinationName string
+	update          func([]api.Grant) error
+
+	// grants are the grants of the destination, by ID, as of latestIndex. It
+	// is nil until the grants are listed for the first time.
+	grants      map[uid.ID]api.Grant
+	latestIndex int64
+	// listedAt is the time the grants were last listed, and resyncInterval
+	// is the time after which they are listed again.
+	listedAt       time.Time
+	resyncInterval time.Duration
+	// pollOnly is true when the server does not support watching grants.
+	pollOnly bool
+}
+
+func (s *grantsSync) sync(ctx context.Context) error {
+	if s.grants == nil || s.pollOnly {
+		return s.poll(ctx, s.latestIndex)
+	}
+	if time.Since(s.listedAt) > s.resyncInterval {
+		logging.L.Debug().Msg("listing all grants to resync with the server")
+		return s.poll(ctx, 1)
+	}
+
+	err := s.client.WatchGrants(ctx, api.WatchGrantsRequest{
+		Destination:     s.destinationName,
+		LastUpdateIndex: s.latestIndex,
+	}, s.applyEvent)
+	if api.ErrorStatusCode(err) == http.StatusNotFound {
+		logging.L.Info().Msg("server does not support watching grants, polling for changes")
+		s.pollOnly = true
+		return s.poll(ctx, s.latestIndex)
+	}
+	if err != nil {
+		return fmt.Errorf("watch grants: %w", err)
+	}
+	return nil
+}
+
+// poll lists all the grants for the destination, blocking until there are
+// changes after sinceIndex.
+func (s *grantsSync) poll(ctx context.Context, sinceIndex int64) error {
+	grants, err := s.client.ListGrants(ctx, api.ListGrantsRequest{
+		Destination:      s.destinationName,
+		ShowSubjectNames: true,
+		BlockingRequest:  api.BlockingRequest{LastUpdateIndex: sinceIndex},
+	})
+	var apiError api.Error
+	switch {
+	case errors.As(err, &apiError) && apiError.Code == http.StatusNotModified:
+		// not modified is expected when there are no changes
+		logging.L.Info().
+			Int64("updateIndex", sinceIndex).
+			Msg("no updated grants from server")
+		s.listedAt = time.Now()
+		return nil
+	case err != nil:
+		return fmt.Errorf("list grants: %w", err)
+	}
+	logging.L.Info().
+		Int64("updateIndex", grants.LastUpdateIndex.Index).
+		Int("grants", len(grants.Items)).
+		Msg("received grants from server")
+
+	if err := s.update(grants.Items); err != nil {
+		return err
+	}
+
+	// Only update latestIndex once the entire operation was a success
+	s.latestIndex = grants.LastUpdateIndex.Index
+	s.listedAt = time.Now()
+	s.grants = make(map[uid.ID]api.Grant, len(grants.Items))
+	for _, grant := range grants.Items {
+		s.grants[grant.ID] = grant
+	}
+	return nil
+}
+
+// applyEvent applies an add or delete event to the grants. Sync events call
+// update with all the grants. Events may be applied more than once when the
+// stream is resumed after a failed update.
+func (s *grantsSync) applyEvent(event api.GrantEvent) error {
+	switch event.Type {
+	case api.GrantEventAdd:
+		if event.Grant != nil {
+			s.grants[event.Grant.ID] = *event.Grant
+		}
+		return nil
+	case api.GrantEventDelete:
+		if event.Grant != nil {
+			delete(s.grants, event.Grant.ID)
+		}
+		return nil
+	case api.GrantEventSync:
+	default:
+		logging.L.Debug().Str("type", event.Type).Msg("unknown grant event")
+		return nil
+	}
+
+	grants := make([]api.Grant, 0, len(s.grants))
+	for _, grant := range s.grants {
+		grants = append(grants, grant)
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].ID < grants[j].ID
+	})
+
+	logging.L.Info().
+		Int64("updateIndex", event.UpdateIndex).
+		Int("grants", len(grants)).
+		Msg("received grant changes from server")
+
+	if err := s.update(grants); err != nil {
+		return err
+	}
+	s.latestIndex = event.UpdateIndex
+	return nil
+}
+
 // UpdateRoles converts infra grants to role-bindings in the current cluster
 func updateRoles(ctx context.Context, c apiClient, k kubeClient, grants []api.Grant) error {
 	logging.Debugf("syncing local grants from infra configuration")
 
-	crSubjects := make(map[string][]rbacv1.Subject)                           // cluster-role: subject
-	crnSubjects := make(map[kubernetes.ClusterRoleNamespace][]rbacv1.Subject) // cluster-role+namespace: subject
+	crSubjects, rnSubjects, err := roleSubjects(ctx, c, grants)
+	if err != nil {
+		return err
+	}
+
+	if err := k.UpdateClusterRoleBindings(crSubjects); err != nil {
+		return fmt.Errorf("update cluster role bindings: %w", err)
+	}
+
+	if err := k.UpdateRoleBindings(rnSubjects); err != nil {
+		return fmt.Errorf("update role bindings: %w", err)
+	}
+
+	return nil
+}
+
+// RoleBindingChanges returns the changes the connector would make to the
+// ClusterRoleBindings and RoleBindings in the cluster for grants, without
+// applying them.
+func RoleBindingChanges(ctx context.Context, c apiClient, k kubeClient, grants []api.Grant) ([]kubernetes.BindingChange, error) {
+	crSubjects, rnSubjects, err := roleSubjects(ctx, c, grants)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := k.DiffClusterRoleBindings(crSubjects)
+	if err != nil {
+		return nil, fmt.Errorf("diff cluster role bindings: %w", err)
+	}
+
+	rbChanges, err := k.DiffRoleBindings(rnSubjects)
+	if err != nil {
+		return nil, fmt.Errorf("diff role bindings: %w", err)
+	}
+
+	return append(changes, rbChanges...), nil
+}
+
+func logBindingChanges(changes []kubernetes.BindingChange) {
+	if len(changes) == 0 {
+		logging.L.Info().Msg("dry run: no changes to role bindings")
+		return
+	}
+
+	for _, change := range changes {
+		subjects := make([]string, 0, len(change.Subjects))
+		for _, s := range change.Subjects {
+			subjects = append(subjects, s.Kind+":"+s.Name)
+		}
+
+		logging.L.Info().
+			Str("action", change.Action).
+			Str("kind", change.Kind).
+			Str("namespace", change.Namespace).
+			Str("name", change.Name).
+			Str("role", change.RoleRef.Kind+":"+change.RoleRef.Name).
+			Strs("subjects", subjects).
+			Msg("dry run: role binding change not applied")
+	}
+}
+
+// grantSubjectName returns the name of the user or group of the grant. Older
+// servers do not include the SubjectName in grants, so the name is retrieved
+// from the API when it is missing.
+func grantSubjectName(ctx context.Context, c apiClient, g api.Grant) (string, error) {
+	switch {
+	case g.SubjectName != "":
+		return g.SubjectName, nil
+	case g.Group != 0:
+		group, err := c.GetGroup(ctx, g.Group)
+		if err != nil {
+			return "", err
+		}
+		return group.Name, nil
+	default:
+		user, err := c.GetUser(ctx, g.User)
+		if err != nil {
+			return "", err
+		}
+		return user.Name, nil
+	}
+}
+
+// roleSubjects returns the subjects of the ClusterRoleBindings and RoleBindings
+// for grants. A grant of a custom role is bound to each of the Kubernetes roles
+// of the custom role. A grant of a group is bound to the group, and to each of
+// the groups nested in the group.
+func roleSubjects(
+	ctx context.Context,
+	c apiClient,
+	grants []api.Grant,
+) (map[string][]rbacv1.Subject, map[kubernetes.RoleNamespace][]rbacv1.Subject, error) {
+	crSubjects := make(map[string][]rbacv1.Subject)                   // cluster-role: subject
+	rnSubjects := make(map[kubernetes.RoleNamespace][]rbacv1.Subject) // role+namespace: subject
+
+	customRoles, err := listCustomRoles(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	for _, g := range grants {
-		var name, kind string
+		var names []string
+		var kind string
 
-		if g.Privilege == "connect" {
+		// infra permissions apply to the infra API, not to the cluster
+		if g.Privilege == "connect" || api.IsInfraPermission(g.Privilege) {
 			continue
 		}
 
 		switch {
 		case g.Group != 0:
-			group, err := c.GetGroup(ctx, g.Group)
+			name, err := grantSubjectName(ctx, c, g)
 			if err != nil {
-				return err
+				return nil, nil, err
+			}
+
+			nested, err := listNestedGroupNames(ctx, c, g.Group)
+			if err != nil {
+				return nil, nil, err
 			}
 
-			name = group.Name
+			names = append([]string{name}, nested...)
 			kind = rbacv1.GroupKind
 		case g.User != 0:
-			user, err := c.GetUser(ctx, g.User)
+			name, err := grantSubjectName(ctx, c, g)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
 
-			name = user.Name
+			names = []string{name}
 			kind = rbacv1.UserKind
 		}
 
-		subj := rbacv1.Subject{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     kind,
-			Name:     name,
+		subjs := make([]rbacv1.Subject, 0, len(names))
+		for _, name := range names {
+			subjs = append(subjs, rbacv1.Subject{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     kind,
+				Name:     name,
+			})
 		}
 
-		parts := strings.Split(g.Resource, ".")
+		// only the Kubernetes roles of a custom role are bound. The infra roles
+		// and the permissions of the role apply to the infra API, and connect
+		// does not bind any role in the cluster.
+		roles := []string{g.Privilege}
+		if role, ok := customRoles[g.Privilege]; ok {
+			roles = role.KubernetesRoles
+		}
 
-		var crn kubernetes.ClusterRoleNamespace
+		parts := strings.Split(g.Resource, ".")
 
 		switch len(parts) {
 		// <cluster>
 		case 1:
-			crn.ClusterRole = g.Privilege
-			crSubjects[g.Privilege] = append(crSubjects[g.Privilege], subj)
+			for _, role := range roles {
+				crSubjects[role] = append(crSubjects[role], subjs...)
+			}
 
-		// <cluster>.<namespace>
+		// <cluster>.<namespace>, the privilege is a ClusterRole or a Role in the namespace
 		case 2:
-			crn.ClusterRole = g.Privilege
-			crn.Namespace = parts[1]
-			crnSubjects[crn] = append(crnSubjects[crn], subj)
+			for _, role := range roles {
+				rn := kubernetes.RoleNamespace{Role: role, Namespace: parts[1]}
+				rnSubjects[rn] = append(rnSubjects[rn], subjs...)
+			}
 
 		default:
 			logging.Warnf("invalid grant resource: %s", g.Resource)
@@ -574,15 +862,48 @@ func updateRoles(ctx context.Context, c apiClient, k kubeClient, grants []api.Gr
 		}
 	}
 
-	if err := k.UpdateClusterRoleBindings(crSubjects); err != nil {
-		return fmt.Errorf("update cluster role bindings: %w", err)
+	return crSubjects, rnSubjects, nil
+}
+
+// listCustomRoles returns each custom role of the organization, by the name of
+// the custom role.
+func listCustomRoles(ctx context.Context, c apiClient) (map[string]api.Role, error) {
+	roles, err := c.ListRoles(ctx, api.ListRolesRequest{
+		PaginationRequest: api.PaginationRequest{Limit: 1000},
+	})
+	if err != nil {
+		var apiError api.Error
+		if errors.As(err, &apiError) && apiError.Code == http.StatusNotFound {
+			// the server is older, and does not support custom roles
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list roles: %w", err)
 	}
 
-	if err := k.UpdateRoleBindings(crnSubjects); err != nil {
-		return fmt.Errorf("update role bindings: %w", err)
+	result := make(map[string]api.Role, len(roles.Items))
+	for _, role := range roles.Items {
+		result[role.Name] = role
 	}
+	return result, nil
+}
 
-	return nil
+// listNestedGroupNames returns the names of the groups nested in the group.
+func listNestedGroupNames(ctx context.Context, c apiClient, groupID uid.ID) ([]string, error) {
+	groups, err := c.ListNestedGroups(ctx, groupID)
+	if err != nil {
+		var apiError api.Error
+		if errors.As(err, &apiError) && apiError.Code == http.StatusNotFound {
+			// the server is older, and does not support nested groups
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list nested groups: %w", err)
+	}
+
+	names := make([]string, 0, len(groups.Items))
+	for _, group := range groups.Items {
+		names = append(names, group.Name)
+	}
+	return names, nil
 }
 
 // createOrUpdateDestination creates a destination in the infra server if it does not exist and updates it if it does
@@ -601,9 +922,14 @@ func createOrUpdateDestination(ctx context.Context, client apiClient, local *api
 		return updateDestination(ctx, client, local)
 	}
 
+	kind := local.Kind
+	if kind == "" {
+		kind = "kubernetes"
+	}
+
 	request := &api.CreateDestinationRequest{
 		Name:       local.Name,
-		Kind:       "kubernetes",
+		Kind:       kind,
 		UniqueID:   local.UniqueID,
 		Version:    internal.FullVersion(),
 		Connection: local.Connection,
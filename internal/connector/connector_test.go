@@ -24,6 +24,7 @@ import (
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/internal/cmd/types"
 	"github.com/infrahq/infra/internal/kubernetes"
 	"github.com/infrahq/infra/internal/server"
 	"github.com/infrahq/infra/uid"
@@ -143,6 +144,78 @@ func TestAuthenticator_Authenticate(t *testing.T) {
 	}
 }
 
+func TestAuthenticator_KeyRotation(t *testing.T) {
+	first, firstPriv := generateJWK(t)
+	second, secondPriv := generateJWK(t)
+
+	client := &rotatingClient{keys: []jose.JSONWebKey{*first}}
+	authn := newAuthenticator("https://127.0.0.1:12345", Options{
+		Server: ServerOptions{SkipTLSVerify: true, AccessKey: "the-access-key"},
+	})
+	authn.client = client
+
+	authenticate := func(t *testing.T, priv *jose.JSONWebKey) error {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/apis", nil)
+		j := generateJWT(t, priv, "test@example.com", time.Now().Add(time.Hour))
+		req.Header.Set("Authorization", "Bearer "+j)
+		_, err := authn.Authenticate(req)
+		return err
+	}
+
+	assert.NilError(t, authenticate(t, firstPriv))
+	assert.Equal(t, client.requests, 1)
+
+	// the server rotates the key, and publishes both keys
+	client.keys = []jose.JSONWebKey{*second, *first}
+
+	t.Run("unknown key is not fetched again too soon", func(t *testing.T) {
+		err := authenticate(t, secondPriv)
+		assert.ErrorContains(t, err, "no JWK with key ID")
+		assert.Equal(t, client.requests, 1)
+	})
+
+	t.Run("unknown key refreshes the cache", func(t *testing.T) {
+		authn.lastChecked = time.Now().Add(-JWKUnknownKeyRefresh)
+
+		assert.NilError(t, authenticate(t, secondPriv))
+		assert.Equal(t, client.requests, 2)
+
+		// the previous key is still valid
+		assert.NilError(t, authenticate(t, firstPriv))
+		assert.Equal(t, client.requests, 2)
+	})
+
+	t.Run("unknown key after refresh", func(t *testing.T) {
+		_, otherPriv := generateJWK(t)
+		authn.lastChecked = time.Now().Add(-JWKUnknownKeyRefresh)
+
+		err := authenticate(t, otherPriv)
+		assert.ErrorContains(t, err, "no JWK with key ID")
+		assert.Equal(t, client.requests, 3)
+	})
+}
+
+type rotatingClient struct {
+	keys     []jose.JSONWebKey
+	requests int
+}
+
+func (f *rotatingClient) Do(*http.Request) (*http.Response, error) {
+	f.requests++
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(server.WellKnownJWKResponse{Keys: f.keys}); err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     http.StatusText(http.StatusOK),
+		Body:       ioutil.NopCloser(&buf),
+	}
+	return resp, nil
+}
+
 func generateJWK(t *testing.T) (pub *jose.JSONWebKey, priv *jose.JSONWebKey) {
 	t.Helper()
 	pubkey, key, err := ed25519.GenerateKey(rand.Reader)
@@ -259,6 +332,7 @@ func TestSyncGrantsToKubeBindings(t *testing.T) {
 		fakeAPI                  *fakeAPIClient
 		fakeKube                 *fakeKubeClient
 		expectedListGrantIndexes []int64
+		expectedRoleBindings     map[kubernetes.RoleNamespace][]rbacv1.Subject
 		successCount             int
 	}
 
@@ -279,6 +353,9 @@ func TestSyncGrantsToKubeBindings(t *testing.T) {
 
 		assert.Equal(t, len(waiter.resets), tc.successCount)
 		assert.DeepEqual(t, tc.fakeAPI.listGrantsIndexes, tc.expectedListGrantIndexes)
+		if tc.expectedRoleBindings != nil {
+			assert.DeepEqual(t, tc.fakeKube.updateRoleBindingsArgs[0], tc.expectedRoleBindings)
+		}
 	}
 
 	testCases := []testCase{
@@ -294,6 +371,107 @@ func TestSyncGrantsToKubeBindings(t *testing.T) {
 				},
 			},
 			expectedListGrantIndexes: []int64{1, 42},
+			expectedRoleBindings: map[kubernetes.RoleNamespace][]rbacv1.Subject{
+				{Role: "logs", Namespace: "ns1"}: {
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.UserKind, Name: "theuser@example.com"},
+				},
+			},
+			successCount: 2,
+		},
+		{
+			name: "grant of a custom role",
+			fakeAPI: &fakeAPIClient{
+				listGrantsResult: &api.ListResponse[api.Grant]{
+					Items: []api.Grant{
+						{User: uid.ID(124), Resource: "the-test.ns1", Privilege: "developer"},
+					},
+					LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+				},
+				listRolesResult: []api.Role{
+					{Name: "developer", KubernetesRoles: []string{"edit", "logs"}, Permissions: []string{"connect"}},
+				},
+			},
+			expectedListGrantIndexes: []int64{1, 42},
+			expectedRoleBindings: map[kubernetes.RoleNamespace][]rbacv1.Subject{
+				{Role: "edit", Namespace: "ns1"}: {
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.UserKind, Name: "theuser@example.com"},
+				},
+				{Role: "logs", Namespace: "ns1"}: {
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.UserKind, Name: "theuser@example.com"},
+				},
+			},
+			successCount: 2,
+		},
+		{
+			name: "grant of a group with nested groups",
+			fakeAPI: &fakeAPIClient{
+				listGrantsResult: &api.ListResponse[api.Grant]{
+					Items: []api.Grant{
+						{Group: uid.ID(124), Resource: "the-test.ns1", Privilege: "view"},
+					},
+					LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+				},
+				nestedGroups: []api.Group{{Name: "the-nested-group"}},
+			},
+			expectedListGrantIndexes: []int64{1, 42},
+			expectedRoleBindings: map[kubernetes.RoleNamespace][]rbacv1.Subject{
+				{Role: "view", Namespace: "ns1"}: {
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.GroupKind, Name: "the-group"},
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.GroupKind, Name: "the-nested-group"},
+				},
+			},
+			successCount: 2,
+		},
+		{
+			name: "grant of a group, server does not support nested groups",
+			fakeAPI: &fakeAPIClient{
+				listGrantsResult: &api.ListResponse[api.Grant]{
+					Items: []api.Grant{
+						{Group: uid.ID(124), Resource: "the-test.ns1", Privilege: "view"},
+					},
+					LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+				},
+				nestedGroupsErr: api.Error{Code: http.StatusNotFound},
+			},
+			expectedListGrantIndexes: []int64{1, 42},
+			expectedRoleBindings: map[kubernetes.RoleNamespace][]rbacv1.Subject{
+				{Role: "view", Namespace: "ns1"}: {
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.GroupKind, Name: "the-group"},
+				},
+			},
+			successCount: 2,
+		},
+		{
+			name: "grants include subject names",
+			fakeAPI: &fakeAPIClient{
+				listGrantsResult: &api.ListResponse[api.Grant]{
+					Items: []api.Grant{
+						{User: uid.ID(123), SubjectName: "named@example.com", Resource: "the-test.ns1", Privilege: "view"},
+						{Group: uid.ID(124), SubjectName: "named-group", Resource: "the-test.ns1", Privilege: "view"},
+					},
+					LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+				},
+			},
+			expectedListGrantIndexes: []int64{1, 42},
+			expectedRoleBindings: map[kubernetes.RoleNamespace][]rbacv1.Subject{
+				{Role: "view", Namespace: "ns1"}: {
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.UserKind, Name: "named@example.com"},
+					{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.GroupKind, Name: "named-group"},
+				},
+			},
+			successCount: 2,
+		},
+		{
+			name: "grant of an infra permission",
+			fakeAPI: &fakeAPIClient{
+				listGrantsResult: &api.ListResponse[api.Grant]{
+					Items: []api.Grant{
+						{User: uid.ID(124), Resource: "the-test", Privilege: "grants:write"},
+					},
+					LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+				},
+			},
+			expectedListGrantIndexes: []int64{1, 42},
 			successCount:             2,
 		},
 		{
@@ -335,6 +513,177 @@ func TestSyncGrantsToKubeBindings(t *testing.T) {
 	}
 }
 
+func TestSyncGrantsToKubeBindings_WatchGrants(t *testing.T) {
+	first := api.Grant{ID: 1001, User: uid.ID(123), Resource: "the-test.ns1", Privilege: "view"}
+	second := api.Grant{ID: 1002, User: uid.ID(124), Resource: "the-test.ns1", Privilege: "logs"}
+	third := api.Grant{ID: 1003, User: uid.ID(123), Resource: "the-test.ns2", Privilege: "edit"}
+
+	fakeAPI := &fakeAPIClient{
+		listGrantsResult: &api.ListResponse[api.Grant]{
+			Items:           []api.Grant{first, second},
+			LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+		},
+		watchGrantsEvents: [][]api.GrantEvent{
+			{
+				{Type: api.GrantEventDelete, UpdateIndex: 43, Grant: &first},
+				{Type: api.GrantEventAdd, UpdateIndex: 44, Grant: &third},
+				{Type: api.GrantEventSync, UpdateIndex: 44},
+				// the stream ends before the sync event of the next change
+				{Type: api.GrantEventDelete, UpdateIndex: 45, Grant: &second},
+			},
+			{
+				{Type: api.GrantEventDelete, UpdateIndex: 45, Grant: &second},
+				{Type: api.GrantEventSync, UpdateIndex: 45},
+			},
+		},
+	}
+	fakeKube := &fakeKubeClient{}
+	con := connector{
+		k8s:         fakeKube,
+		client:      fakeAPI,
+		destination: &api.Destination{Name: "the-dest"},
+	}
+
+	waiter := &fakeWaiter{endAtIndex: 3}
+	err := syncGrantsToKubeBindings(context.Background(), con, waiter)
+	assert.ErrorIs(t, err, errDone)
+
+	assert.Equal(t, len(waiter.resets), 4)
+	assert.DeepEqual(t, fakeAPI.listGrantsIndexes, []int64{1})
+	assert.DeepEqual(t, fakeAPI.watchGrantsIndexes, []int64{42, 44, 45})
+
+	subject := func(name string) rbacv1.Subject {
+		return rbacv1.Subject{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.UserKind, Name: name}
+	}
+	user := subject("theuser@example.com")
+	expected := []map[kubernetes.RoleNamespace][]rbacv1.Subject{
+		{
+			{Role: "view", Namespace: "ns1"}: {user},
+			{Role: "logs", Namespace: "ns1"}: {user},
+		},
+		{
+			{Role: "logs", Namespace: "ns1"}: {user},
+			{Role: "edit", Namespace: "ns2"}: {user},
+		},
+		{
+			{Role: "edit", Namespace: "ns2"}: {user},
+		},
+	}
+	assert.DeepEqual(t, fakeKube.updateRoleBindingsArgs, expected)
+}
+
+func TestGrantsSync_Resync(t *testing.T) {
+	first := api.Grant{ID: 1001, User: uid.ID(123), Resource: "the-test.ns1", Privilege: "view"}
+	second := api.Grant{ID: 1002, User: uid.ID(124), Resource: "the-test.ns1", Privilege: "logs"}
+
+	fakeAPI := &fakeAPIClient{
+		listGrantsResult: &api.ListResponse[api.Grant]{
+			Items:           []api.Grant{first},
+			LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+		},
+		watchGrantsEvents: [][]api.GrantEvent{
+			{{Type: api.GrantEventSync, UpdateIndex: 42}},
+		},
+	}
+
+	var updates [][]api.Grant
+	s := &grantsSync{
+		client:          fakeAPI,
+		destinationName: "the-dest",
+		update: func(grants []api.Grant) error {
+			updates = append(updates, grants)
+			return nil
+		},
+		latestIndex:    1,
+		resyncInterval: time.Hour,
+	}
+
+	ctx := context.Background()
+	assert.NilError(t, s.sync(ctx))
+	assert.NilError(t, s.sync(ctx))
+	assert.DeepEqual(t, fakeAPI.listGrantsIndexes, []int64{1})
+	assert.DeepEqual(t, fakeAPI.watchGrantsIndexes, []int64{42})
+
+	// a change with a lower update index was committed after the stream
+	// sent index 42, and is only found by listing all the grants
+	fakeAPI.listGrantsResult = &api.ListResponse[api.Grant]{
+		Items:           []api.Grant{first, second},
+		LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+	}
+	s.listedAt = time.Now().Add(-2 * time.Hour)
+	assert.NilError(t, s.sync(ctx))
+	assert.DeepEqual(t, fakeAPI.listGrantsIndexes, []int64{1, 1})
+	assert.DeepEqual(t, fakeAPI.watchGrantsIndexes, []int64{42})
+
+	expected := [][]api.Grant{{first}, {first}, {first, second}}
+	assert.DeepEqual(t, updates, expected)
+}
+
+func TestSyncGrantsToKubeBindings_DryRun(t *testing.T) {
+	fakeAPI := &fakeAPIClient{
+		listGrantsResult: &api.ListResponse[api.Grant]{
+			Items: []api.Grant{
+				{User: uid.ID(123), Resource: "the-test", Privilege: "view"},
+				{Group: uid.ID(124), Resource: "the-test.ns1", Privilege: "logs"},
+			},
+			LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+		},
+	}
+	fakeKube := &fakeKubeClient{}
+	con := connector{
+		k8s:         fakeKube,
+		client:      fakeAPI,
+		destination: &api.Destination{Name: "the-dest"},
+		options:     Options{DryRun: true},
+	}
+
+	err := syncGrantsToKubeBindings(context.Background(), con, &fakeWaiter{})
+	assert.ErrorIs(t, err, errDone)
+
+	assert.Equal(t, len(fakeKube.updateClusterRoleBindingsArgs), 0)
+	assert.Equal(t, len(fakeKube.updateRoleBindingsArgs), 0)
+
+	expectedCR := map[string][]rbacv1.Subject{
+		"view": {{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.UserKind, Name: "theuser@example.com"}},
+	}
+	assert.DeepEqual(t, fakeKube.diffClusterRoleBindingsArgs, []map[string][]rbacv1.Subject{expectedCR})
+	expectedRN := map[kubernetes.RoleNamespace][]rbacv1.Subject{
+		{Role: "logs", Namespace: "ns1"}: {{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.GroupKind, Name: "the-group"}},
+	}
+	assert.DeepEqual(t, fakeKube.diffRoleBindingsArgs, []map[kubernetes.RoleNamespace][]rbacv1.Subject{expectedRN})
+}
+
+func TestSyncDestination_ReportsNamespacedRoles(t *testing.T) {
+	testCACertPEM, err := os.ReadFile("./_testdata/test-ca-cert.pem")
+	assert.NilError(t, err)
+	testCAKeyPEM, err := os.ReadFile("./_testdata/test-ca-key.pem")
+	assert.NilError(t, err)
+
+	fakeAPI := &fakeAPIClient{}
+	con := connector{
+		k8s: &fakeKubeClient{
+			namespaces:      []string{"default", "team-a"},
+			clusterRoles:    []string{"admin", "view"},
+			namespacedRoles: []string{"team-a/deployer"},
+		},
+		client:      fakeAPI,
+		destination: &api.Destination{Name: "the-dest", UniqueID: "abcd"},
+		certCache:   NewCertCache(testCACertPEM, testCAKeyPEM),
+		options: Options{
+			CACert:       types.StringOrFile(testCACertPEM),
+			EndpointAddr: types.HostPort{Host: "127.0.0.1", Port: 443},
+		},
+	}
+
+	err = syncDestination(context.Background(), con)
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(fakeAPI.createDestinationArgs), 1)
+	created := fakeAPI.createDestinationArgs[0]
+	assert.DeepEqual(t, created.Roles, []string{"admin", "view", "team-a/deployer"})
+	assert.DeepEqual(t, created.Resources, []string{"default", "team-a"})
+}
+
 type fakeWaiter struct {
 	index      int
 	resets     []int
@@ -364,6 +713,17 @@ type fakeAPIClient struct {
 	listGrantsResult  *api.ListResponse[api.Grant]
 	listGrantsError   error
 	listGrantsIndexes []int64
+
+	// watchGrantsEvents are the events sent by each call to WatchGrants. When
+	// nil, WatchGrants responds the same as a server without the endpoint.
+	watchGrantsEvents  [][]api.GrantEvent
+	watchGrantsIndexes []int64
+
+	listRolesResult []api.Role
+	nestedGroups    []api.Group
+	nestedGroupsErr error
+
+	createDestinationArgs []api.CreateDestinationRequest
 }
 
 func (f *fakeAPIClient) ListGrants(ctx context.Context, req api.ListGrantsRequest) (*api.ListResponse[api.Grant], error) {
@@ -371,6 +731,42 @@ func (f *fakeAPIClient) ListGrants(ctx context.Context, req api.ListGrantsReques
 	return f.listGrantsResult, f.listGrantsError
 }
 
+func (f *fakeAPIClient) WatchGrants(ctx context.Context, req api.WatchGrantsRequest, fn func(api.GrantEvent) error) error {
+	if f.watchGrantsEvents == nil {
+		return api.Error{Code: http.StatusNotFound}
+	}
+	f.watchGrantsIndexes = append(f.watchGrantsIndexes, req.LastUpdateIndex)
+	if len(f.watchGrantsIndexes) > len(f.watchGrantsEvents) {
+		return nil
+	}
+	for _, event := range f.watchGrantsEvents[len(f.watchGrantsIndexes)-1] {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeAPIClient) ListRoles(ctx context.Context, req api.ListRolesRequest) (*api.ListResponse[api.Role], error) {
+	return &api.ListResponse[api.Role]{Items: f.listRolesResult, Count: len(f.listRolesResult)}, nil
+}
+
+func (f *fakeAPIClient) ListNestedGroups(ctx context.Context, id uid.ID) (*api.ListResponse[api.Group], error) {
+	if f.nestedGroupsErr != nil {
+		return nil, f.nestedGroupsErr
+	}
+	return &api.ListResponse[api.Group]{Items: f.nestedGroups, Count: len(f.nestedGroups)}, nil
+}
+
+func (f *fakeAPIClient) ListDestinations(ctx context.Context, req api.ListDestinationsRequest) (*api.ListResponse[api.Destination], error) {
+	return &api.ListResponse[api.Destination]{}, nil
+}
+
+func (f *fakeAPIClient) CreateDestination(ctx context.Context, req *api.CreateDestinationRequest) (*api.Destination, error) {
+	f.createDestinationArgs = append(f.createDestinationArgs, *req)
+	return &api.Destination{ID: uid.ID(7)}, nil
+}
+
 func (f *fakeAPIClient) GetGroup(ctx context.Context, id uid.ID) (*api.Group, error) {
 	return &api.Group{Name: "the-group"}, nil
 }
@@ -383,7 +779,29 @@ type fakeKubeClient struct {
 	kubernetes.Kubernetes
 	updateBindingsError           error
 	updateClusterRoleBindingsArgs []map[string][]rbacv1.Subject
-	updateRoleBindingsArgs        []map[kubernetes.ClusterRoleNamespace][]rbacv1.Subject
+	updateRoleBindingsArgs        []map[kubernetes.RoleNamespace][]rbacv1.Subject
+	diffClusterRoleBindingsArgs   []map[string][]rbacv1.Subject
+	diffRoleBindingsArgs          []map[kubernetes.RoleNamespace][]rbacv1.Subject
+
+	namespaces      []string
+	clusterRoles    []string
+	namespacedRoles []string
+}
+
+func (f *fakeKubeClient) Namespaces() ([]string, error) {
+	return f.namespaces, nil
+}
+
+func (f *fakeKubeClient) ClusterRoles() ([]string, error) {
+	return f.clusterRoles, nil
+}
+
+func (f *fakeKubeClient) NamespacedRoles() ([]string, error) {
+	return f.namespacedRoles, nil
+}
+
+func (f *fakeKubeClient) IsServiceTypeClusterIP() (bool, error) {
+	return false, nil
 }
 
 func (f *fakeKubeClient) UpdateClusterRoleBindings(subjects map[string][]rbacv1.Subject) error {
@@ -391,7 +809,17 @@ func (f *fakeKubeClient) UpdateClusterRoleBindings(subjects map[string][]rbacv1.
 	return f.updateBindingsError
 }
 
-func (f *fakeKubeClient) UpdateRoleBindings(subjects map[kubernetes.ClusterRoleNamespace][]rbacv1.Subject) error {
+func (f *fakeKubeClient) UpdateRoleBindings(subjects map[kubernetes.RoleNamespace][]rbacv1.Subject) error {
 	f.updateRoleBindingsArgs = append(f.updateRoleBindingsArgs, subjects)
 	return f.updateBindingsError
 }
+
+func (f *fakeKubeClient) DiffClusterRoleBindings(subjects map[string][]rbacv1.Subject) ([]kubernetes.BindingChange, error) {
+	f.diffClusterRoleBindingsArgs = append(f.diffClusterRoleBindingsArgs, subjects)
+	return []kubernetes.BindingChange{{Action: kubernetes.BindingCreate, Kind: "ClusterRoleBinding"}}, nil
+}
+
+func (f *fakeKubeClient) DiffRoleBindings(subjects map[kubernetes.RoleNamespace][]rbacv1.Subject) ([]kubernetes.BindingChange, error) {
+	f.diffRoleBindingsArgs = append(f.diffRoleBindingsArgs, subjects)
+	return []kubernetes.BindingChange{{Action: kubernetes.BindingDelete, Kind: "RoleBinding"}}, nil
+}
@@ -0,0 +1,300 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/openssh"
+	"github.com/infrahq/infra/internal/repeat"
+	"github.com/infrahq/infra/metrics"
+	"github.com/infrahq/infra/uid"
+)
+
+type SSHOptions struct {
+	// ConfigDir is the directory where the connector writes the certificate
+	// authority and the principals files used by sshd. Defaults to
+	// /etc/ssh/infra.
+	ConfigDir string
+	// SudoersFile is the sudoers file that lists the users granted the admin
+	// role. Defaults to /etc/sudoers.d/infra.
+	SudoersFile string
+	// HostKeyFile is the public host key of sshd. It is sent to the infra API
+	// server so that clients can verify the host. Defaults to
+	// /etc/ssh/ssh_host_ed25519_key.pub.
+	HostKeyFile string
+}
+
+func (o *SSHOptions) setDefaults() {
+	if o.ConfigDir == "" {
+		o.ConfigDir = "/etc/ssh/infra"
+	}
+	if o.SudoersFile == "" {
+		o.SudoersFile = "/etc/sudoers.d/infra"
+	}
+	if o.HostKeyFile == "" {
+		o.HostKeyFile = "/etc/ssh/ssh_host_ed25519_key.pub"
+	}
+}
+
+type sshHost interface {
+	WriteCertificateAuthority(publicKey string) error
+	UpdateUsers(users map[string]string) error
+}
+
+// sshConnector stores all the dependencies for the connector operations on an
+// SSH destination.
+type sshConnector struct {
+	client      apiClient
+	host        sshHost
+	destination *api.Destination
+	// caPublicKey is the last certificate authority written to the host.
+	caPublicKey string
+}
+
+func runSSH(ctx context.Context, options Options) error {
+	options.SSH.setDefaults()
+
+	hostKey, err := os.ReadFile(options.SSH.HostKeyFile)
+	if err != nil {
+		return fmt.Errorf("read host key: %w", err)
+	}
+	checksum := sha256.Sum256(hostKey)
+	uniqueID := hex.EncodeToString(checksum[:])
+
+	if options.Name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("destination name is required: %w", err)
+		}
+		// destination names can not contain a dot
+		options.Name, _, _ = strings.Cut(hostname, ".")
+	}
+
+	endpoint := options.EndpointAddr
+	if endpoint.Host == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("endpointAddr is required: %w", err)
+		}
+		endpoint.Host = hostname
+	}
+	if endpoint.Port == 0 {
+		endpoint.Port = 22
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	promRegistry := metrics.NewRegistry(internal.FullVersion())
+	client, err := newAPIClient(options.Server, uniqueID, promRegistry, cancel)
+	if err != nil {
+		return err
+	}
+
+	host := openssh.NewHost(options.SSH.ConfigDir, options.SSH.SudoersFile)
+	con := &sshConnector{
+		client: client,
+		host:   host,
+		destination: &api.Destination{
+			Name:     options.Name,
+			Kind:     "ssh",
+			UniqueID: uniqueID,
+			Roles:    openssh.Roles(),
+			Connection: api.DestinationConnection{
+				URL: endpoint.String(),
+				CA:  api.PEM(hostKey),
+			},
+		},
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		backOff := &backoff.ExponentialBackOff{
+			InitialInterval:     2 * time.Second,
+			MaxInterval:         time.Minute,
+			RandomizationFactor: 0.2,
+			Multiplier:          1.5,
+		}
+		waiter := repeat.NewWaiter(backOff)
+		return syncGrantsToSSHUsers(ctx, con, waiter)
+	})
+	group.Go(func() error {
+		waiter := repeat.NewWaiter(backoff.NewConstantBackOff(30 * time.Second))
+		for {
+			if err := syncSSHDestination(ctx, con); err != nil {
+				logging.Errorf("failed to update destination in infra: %v", err)
+			} else {
+				waiter.Reset()
+			}
+			if err := waiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+	})
+
+	metricsServer := &http.Server{
+		ReadHeaderTimeout: 30 * time.Second,
+		ReadTimeout:       60 * time.Second,
+		Addr:              options.Addr.Metrics,
+		Handler:           metrics.NewHandler(promRegistry),
+		ErrorLog:          log.New(logging.NewFilteredHTTPLogger(), "", 0),
+	}
+	group.Go(func() error {
+		err := metricsServer.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	})
+
+	logging.Infof("starting infra connector (%s) for ssh destination %s - metrics:%s", internal.FullVersion(), options.Name, metricsServer.Addr)
+	logging.Infof("sshd_config must include:\n%s", host.SSHDConfig())
+
+	<-ctx.Done()
+	if err := metricsServer.Close(); err != nil {
+		logging.L.Warn().Err(err).Msgf("shutdown metrics server")
+	}
+
+	err = group.Wait()
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// syncSSHDestination registers the destination with the infra API server, and
+// writes the certificate authority that signs user certificates to the host.
+func syncSSHDestination(ctx context.Context, con *sshConnector) error {
+	if con.destination.ID == 0 {
+		if err := createOrUpdateDestination(ctx, con.client, con.destination); err != nil {
+			return fmt.Errorf("create or update destination: %w", err)
+		}
+	}
+
+	ca, err := con.client.GetSSHCertificateAuthority(ctx)
+	if err != nil {
+		return fmt.Errorf("get certificate authority: %w", err)
+	}
+	if ca.PublicKey == con.caPublicKey {
+		return nil
+	}
+	if err := con.host.WriteCertificateAuthority(ca.PublicKey); err != nil {
+		return fmt.Errorf("write certificate authority: %w", err)
+	}
+	con.caPublicKey = ca.PublicKey
+	return nil
+}
+
+func syncGrantsToSSHUsers(ctx context.Context, con *sshConnector, waiter waiter) error {
+	return syncGrants(ctx, con.client, con.destination.Name, waiter, func(grants []api.Grant) error {
+		users, err := sshUsersFromGrants(ctx, con.client, con.destination.Name, grants)
+		if err != nil {
+			return err
+		}
+		if err := con.host.UpdateUsers(users); err != nil {
+			return fmt.Errorf("update users: %w", err)
+		}
+		return nil
+	})
+}
+
+// sshUsersFromGrants returns a map of local username to the role granted to
+// the user. Grants to a group apply to every member of the group. A grant of a
+// custom role with the connect permission grants the user role. When a user
+// has more than one grant, the admin role takes precedence.
+func sshUsersFromGrants(ctx context.Context, client apiClient, destinationName string, grants []api.Grant) (map[string]string, error) {
+	customRoles, err := listCustomRoles(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]string)
+	add := func(id uid.ID, name, role string) {
+		username := openssh.Username(id, name)
+		if username == "" {
+			logging.Warnf("user %v does not have a valid SSH username", name)
+			return
+		}
+		if users[username] != openssh.RoleAdmin {
+			users[username] = role
+		}
+	}
+
+	for _, g := range grants {
+		if g.Resource != destinationName {
+			logging.Warnf("invalid grant resource for ssh destination: %s", g.Resource)
+			continue
+		}
+		role := sshRoleForPrivilege(g.Privilege, customRoles)
+		if role == "" {
+			continue
+		}
+
+		switch {
+		case g.Group != 0:
+			members, err := listGroupMembers(ctx, client, g.Group)
+			if err != nil {
+				return nil, err
+			}
+			for _, member := range members {
+				add(member.ID, member.Name, role)
+			}
+		case g.User != 0:
+			name, err := grantSubjectName(ctx, client, g)
+			if err != nil {
+				return nil, err
+			}
+			add(g.User, name, role)
+		}
+	}
+	return users, nil
+}
+
+// sshRoleForPrivilege returns the SSH role granted by a grant of the
+// privilege, or an empty string if the privilege does not grant access to the
+// destination.
+func sshRoleForPrivilege(privilege string, customRoles map[string]api.Role) string {
+	switch privilege {
+	case openssh.RoleAdmin, openssh.RoleUser:
+		return privilege
+	}
+	if role, ok := customRoles[privilege]; ok {
+		for _, p := range role.Permissions {
+			if p == "connect" {
+				return openssh.RoleUser
+			}
+		}
+	}
+	return ""
+}
+
+func listGroupMembers(ctx context.Context, client apiClient, groupID uid.ID) ([]api.User, error) {
+	var members []api.User
+	for page := 1; ; page++ {
+		resp, err := client.ListUsers(ctx, api.ListUsersRequest{
+			Group:             groupID,
+			PaginationRequest: api.PaginationRequest{Page: page},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list users in group %v: %w", groupID, err)
+		}
+		members = append(members, resp.Items...)
+		if page >= resp.TotalPages {
+			return members, nil
+		}
+	}
+}
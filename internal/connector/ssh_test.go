@@ -0,0 +1,127 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/openssh"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestSyncGrantsToSSHUsers(t *testing.T) {
+	ctx := context.Background()
+	fakeAPI := &fakeSSHAPIClient{
+		fakeAPIClient: fakeAPIClient{
+			listGrantsResult: &api.ListResponse[api.Grant]{
+				Items: []api.Grant{
+					{User: uid.ID(123), Resource: "web-1", Privilege: "user"},
+					{Group: uid.ID(200), Resource: "web-1", Privilege: "admin"},
+					{User: uid.ID(124), Resource: "web-1", Privilege: "view"},
+					{User: uid.ID(125), Resource: "web-1.other", Privilege: "user"},
+					{User: uid.ID(127), Resource: "web-1", Privilege: "ssh-users"},
+					{User: uid.ID(128), Resource: "web-1", Privilege: "cluster-viewers"},
+				},
+				LastUpdateIndex: api.LastUpdateIndex{Index: 42},
+			},
+			listRolesResult: []api.Role{
+				{Name: "ssh-users", Permissions: []string{"connect"}},
+				{Name: "cluster-viewers", KubernetesRoles: []string{"view"}},
+			},
+		},
+		users: map[uid.ID]api.User{
+			123: {ID: 123, Name: "alice@example.com"},
+			124: {ID: 124, Name: "viewer@example.com"},
+			127: {ID: 127, Name: "carol@example.com"},
+			128: {ID: 128, Name: "dave@example.com"},
+		},
+		groupMembers: map[uid.ID][]api.User{
+			200: {{ID: 123, Name: "alice@example.com"}, {ID: 126, Name: "bob@example.com"}},
+		},
+	}
+	host := &fakeSSHHost{}
+	con := &sshConnector{
+		client:      fakeAPI,
+		host:        host,
+		destination: &api.Destination{Name: "web-1"},
+	}
+
+	err := syncGrantsToSSHUsers(ctx, con, &fakeWaiter{endAtIndex: 0})
+	assert.ErrorIs(t, err, errDone)
+
+	expected := []map[string]string{
+		{
+			openssh.Username(123, "alice@example.com"): "admin",
+			openssh.Username(126, "bob@example.com"):   "admin",
+			openssh.Username(127, "carol@example.com"): "user",
+		},
+	}
+	assert.DeepEqual(t, host.updateUsersArgs, expected)
+	assert.DeepEqual(t, fakeAPI.listGrantsIndexes, []int64{1})
+}
+
+func TestSyncSSHDestination(t *testing.T) {
+	ctx := context.Background()
+	fakeAPI := &fakeSSHAPIClient{
+		fakeAPIClient: fakeAPIClient{},
+		caPublicKey:   "ssh-ed25519 AAAA the-ca",
+	}
+	host := &fakeSSHHost{}
+	con := &sshConnector{
+		client:      fakeAPI,
+		host:        host,
+		destination: &api.Destination{ID: uid.ID(300), Name: "web-1", Kind: "ssh"},
+	}
+
+	err := syncSSHDestination(ctx, con)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, host.caPublicKeys, []string{"ssh-ed25519 AAAA the-ca"})
+
+	// the certificate authority is only written when it changes
+	err = syncSSHDestination(ctx, con)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, host.caPublicKeys, []string{"ssh-ed25519 AAAA the-ca"})
+}
+
+type fakeSSHAPIClient struct {
+	fakeAPIClient
+
+	users        map[uid.ID]api.User
+	groupMembers map[uid.ID][]api.User
+	caPublicKey  string
+}
+
+func (f *fakeSSHAPIClient) GetUser(ctx context.Context, id uid.ID) (*api.User, error) {
+	user := f.users[id]
+	return &user, nil
+}
+
+func (f *fakeSSHAPIClient) ListUsers(ctx context.Context, req api.ListUsersRequest) (*api.ListResponse[api.User], error) {
+	members := f.groupMembers[req.Group]
+	return &api.ListResponse[api.User]{
+		Items:              members,
+		Count:              len(members),
+		PaginationResponse: api.PaginationResponse{Page: 1, TotalPages: 1},
+	}, nil
+}
+
+func (f *fakeSSHAPIClient) GetSSHCertificateAuthority(ctx context.Context) (*api.SSHCertificateAuthority, error) {
+	return &api.SSHCertificateAuthority{PublicKey: f.caPublicKey}, nil
+}
+
+type fakeSSHHost struct {
+	caPublicKeys    []string
+	updateUsersArgs []map[string]string
+}
+
+func (f *fakeSSHHost) WriteCertificateAuthority(publicKey string) error {
+	f.caPublicKeys = append(f.caPublicKeys, publicKey)
+	return nil
+}
+
+func (f *fakeSSHHost) UpdateUsers(users map[string]string) error {
+	f.updateUsersArgs = append(f.updateUsersArgs, users)
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -64,12 +65,40 @@ func NewKubernetes(authToken, addr, ca string) (*Kubernetes, error) {
 	return k, nil
 }
 
-// namespaceRole is used as a tuple to pair namespaces and grants as a map key
-type ClusterRoleNamespace struct {
-	ClusterRole string
-	Namespace   string
+// RoleNamespace is used as a tuple to pair namespaces and roles as a map key.
+// Role is the name of a ClusterRole, or the name of a Role in the namespace.
+type RoleNamespace struct {
+	Role      string
+	Namespace string
 }
 
+// BindingChange is a change to a ClusterRoleBinding or RoleBinding managed by
+// infra. The changes are computed from the grants for the cluster, and may be
+// shown to a user before they are applied.
+type BindingChange struct {
+	// Action is one of create, update, or delete.
+	Action string `json:"action"`
+	// Kind is either ClusterRoleBinding or RoleBinding.
+	Kind string `json:"kind"`
+	// Namespace is the namespace of a RoleBinding. It is empty for a
+	// ClusterRoleBinding.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	// RoleRef and Subjects are the new values of the binding, or the existing
+	// values of a binding that will be deleted.
+	RoleRef  rbacv1.RoleRef   `json:"roleRef"`
+	Subjects []rbacv1.Subject `json:"subjects,omitempty"`
+}
+
+const (
+	BindingCreate = "create"
+	BindingUpdate = "update"
+	BindingDelete = "delete"
+)
+
+const managedByInfraSelector = "app.kubernetes.io/managed-by=infra"
+
 // UpdateClusterRoleBindings generates ClusterRoleBindings for GrantMappings
 func (k *Kubernetes) UpdateClusterRoleBindings(subjects map[string][]rbacv1.Subject) error {
 	clientset, err := kubernetes.NewForConfig(k.Config)
@@ -77,73 +106,119 @@ func (k *Kubernetes) UpdateClusterRoleBindings(subjects map[string][]rbacv1.Subj
 		return err
 	}
 
+	changes, err := diffClusterRoleBindings(context.TODO(), clientset, subjects)
+	if err != nil {
+		return err
+	}
+	return applyClusterRoleBindings(context.TODO(), clientset, changes)
+}
+
+// DiffClusterRoleBindings returns the changes UpdateClusterRoleBindings would
+// make to the ClusterRoleBindings in the cluster, without applying them.
+func (k *Kubernetes) DiffClusterRoleBindings(subjects map[string][]rbacv1.Subject) ([]BindingChange, error) {
+	clientset, err := kubernetes.NewForConfig(k.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffClusterRoleBindings(context.TODO(), clientset, subjects)
+}
+
+func diffClusterRoleBindings(ctx context.Context, clientset kubernetes.Interface, subjects map[string][]rbacv1.Subject) ([]BindingChange, error) {
 	// store which cluster-roles currently exist locally
 	validClusterRoles := make(map[string]bool)
 
-	crs, err := clientset.RbacV1().ClusterRoles().List(context.TODO(), metav1.ListOptions{})
+	crs, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, cr := range crs.Items {
 		validClusterRoles[cr.Name] = true
 	}
 
-	crbs := []*rbacv1.ClusterRoleBinding{}
+	existingInfraCrbs, err := clientset.RbacV1().ClusterRoleBindings().List(ctx,
+		metav1.ListOptions{LabelSelector: managedByInfraSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]rbacv1.ClusterRoleBinding)
+	for _, crb := range existingInfraCrbs.Items {
+		existing[crb.Name] = crb
+	}
 
+	var changes []BindingChange
 	for cr, subjs := range subjects {
 		if !validClusterRoles[cr] {
 			logging.Warnf("skipping bindings for cluster role %s that does not exist", cr)
 			continue
 		}
 
-		crbs = append(crbs, &rbacv1.ClusterRoleBinding{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: fmt.Sprintf("infra:%s", cr),
-				Labels: map[string]string{
-					"app.kubernetes.io/managed-by": "infra",
-				},
-			},
+		change := BindingChange{
+			Kind:     "ClusterRoleBinding",
+			Name:     fmt.Sprintf("infra:%s", cr),
 			Subjects: subjs,
 			RoleRef: rbacv1.RoleRef{
 				APIGroup: "rbac.authorization.k8s.io",
 				Kind:     "ClusterRole",
 				Name:     cr,
 			},
-		})
-	}
+		}
 
-	existingInfraCrbs, err := clientset.RbacV1().ClusterRoleBindings().List(
-		context.Background(),
-		metav1.ListOptions{LabelSelector: "app.kubernetes.io/managed-by=infra"})
-	if err != nil {
-		return err
+		current, ok := existing[change.Name]
+		delete(existing, change.Name)
+		switch {
+		case !ok:
+			change.Action = BindingCreate
+		case current.RoleRef != change.RoleRef || !sameSubjects(current.Subjects, change.Subjects):
+			change.Action = BindingUpdate
+		default:
+			continue
+		}
+		changes = append(changes, change)
 	}
 
-	toDelete := make(map[string]bool)
-	for _, existingCrb := range existingInfraCrbs.Items {
-		toDelete[existingCrb.Name] = true
+	for _, crb := range existing {
+		changes = append(changes, BindingChange{
+			Action:   BindingDelete,
+			Kind:     "ClusterRoleBinding",
+			Name:     crb.Name,
+			RoleRef:  crb.RoleRef,
+			Subjects: crb.Subjects,
+		})
 	}
 
-	// Create or update CRBs for users
-	for _, crb := range crbs {
-		_, err = clientset.RbacV1().ClusterRoleBindings().Update(context.Background(), crb, metav1.UpdateOptions{})
-		if err != nil {
-			if k8sErrors.IsNotFound(err) {
-				_, err = clientset.RbacV1().ClusterRoleBindings().Create(context.Background(), crb, metav1.CreateOptions{})
-				if err != nil {
-					return err
-				}
-			} else {
+	sortBindingChanges(changes)
+	return changes, nil
+}
+
+func applyClusterRoleBindings(ctx context.Context, clientset kubernetes.Interface, changes []BindingChange) error {
+	client := clientset.RbacV1().ClusterRoleBindings()
+
+	for _, change := range changes {
+		if change.Action == BindingDelete {
+			if err := client.Delete(ctx, change.Name, metav1.DeleteOptions{}); err != nil {
 				return err
 			}
+			continue
 		}
 
-		delete(toDelete, crb.Name)
-	}
+		crb := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: change.Name,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "infra",
+				},
+			},
+			Subjects: change.Subjects,
+			RoleRef:  change.RoleRef,
+		}
 
-	for name := range toDelete {
-		err := clientset.RbacV1().ClusterRoleBindings().Delete(context.Background(), name, metav1.DeleteOptions{})
+		_, err := client.Update(ctx, crb, metav1.UpdateOptions{})
+		if k8sErrors.IsNotFound(err) {
+			_, err = client.Create(ctx, crb, metav1.CreateOptions{})
+		}
 		if err != nil {
 			return err
 		}
@@ -152,55 +227,70 @@ func (k *Kubernetes) UpdateClusterRoleBindings(subjects map[string][]rbacv1.Subj
 	return nil
 }
 
-func (k *Kubernetes) UpdateRoleBindings(subjects map[ClusterRoleNamespace][]rbacv1.Subject) error {
+func (k *Kubernetes) UpdateRoleBindings(subjects map[RoleNamespace][]rbacv1.Subject) error {
 	clientset, err := kubernetes.NewForConfig(k.Config)
 	if err != nil {
 		return err
 	}
 
+	changes, err := diffRoleBindings(context.TODO(), clientset, subjects)
+	if err != nil {
+		return err
+	}
+	return applyRoleBindings(context.TODO(), clientset, changes)
+}
+
+// DiffRoleBindings returns the changes UpdateRoleBindings would make to the
+// RoleBindings in the cluster, without applying them.
+func (k *Kubernetes) DiffRoleBindings(subjects map[RoleNamespace][]rbacv1.Subject) ([]BindingChange, error) {
+	clientset, err := kubernetes.NewForConfig(k.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffRoleBindings(context.TODO(), clientset, subjects)
+}
+
+func diffRoleBindings(ctx context.Context, clientset kubernetes.Interface, subjects map[RoleNamespace][]rbacv1.Subject) ([]BindingChange, error) {
 	// store which cluster-roles currently exist locally
 	validClusterRoles := make(map[string]bool)
 
-	crs, err := clientset.RbacV1().ClusterRoles().List(context.TODO(), metav1.ListOptions{})
+	crs, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, cr := range crs.Items {
 		validClusterRoles[cr.Name] = true
 	}
 
-	// create the namespaced role bindings for all the users of each of the role assignments
-	rbs := []*rbacv1.RoleBinding{}
+	// store which namespaced roles currently exist locally
+	validRoles := make(map[RoleNamespace]bool)
 
-	for crn, subjs := range subjects {
-		if !validClusterRoles[crn.ClusterRole] {
-			logging.Warnf("cluster role binding %s skipped, it does not exist", crn.ClusterRole)
-			continue
-		}
+	roles, err := clientset.RbacV1().Roles("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
 
-		rbs = append(rbs, &rbacv1.RoleBinding{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: fmt.Sprintf("infra:%s", crn.ClusterRole),
-				Labels: map[string]string{
-					"app.kubernetes.io/managed-by": "infra",
-				},
-				Namespace: crn.Namespace,
-			},
-			Subjects: subjs,
-			RoleRef: rbacv1.RoleRef{
-				APIGroup: "rbac.authorization.k8s.io",
-				Kind:     "ClusterRole",
-				Name:     crn.ClusterRole,
-			},
-		})
+	for _, r := range roles.Items {
+		validRoles[RoleNamespace{Role: r.Name, Namespace: r.Namespace}] = true
 	}
 
-	existingInfraRbs, err := clientset.RbacV1().RoleBindings("").List(
-		context.TODO(),
-		metav1.ListOptions{LabelSelector: "app.kubernetes.io/managed-by=infra"})
+	validNamespaces := make(map[string]bool)
+
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	for _, n := range namespaces.Items {
+		validNamespaces[n.Name] = true
+	}
+
+	existingInfraRbs, err := clientset.RbacV1().RoleBindings("").List(ctx,
+		metav1.ListOptions{LabelSelector: managedByInfraSelector})
+	if err != nil {
+		return nil, err
 	}
 
 	type rbIdentifier struct {
@@ -208,44 +298,116 @@ func (k *Kubernetes) UpdateRoleBindings(subjects map[ClusterRoleNamespace][]rbac
 		name      string
 	}
 
-	toDelete := make(map[rbIdentifier]rbacv1.RoleBinding)
+	existing := make(map[rbIdentifier]rbacv1.RoleBinding)
+	for _, rb := range existingInfraRbs.Items {
+		existing[rbIdentifier{namespace: rb.Namespace, name: rb.Name}] = rb
+	}
+
+	// create the namespaced role bindings for all the users of each of the role assignments
+	var changes []BindingChange
+	for rn, subjs := range subjects {
+		if !validNamespaces[rn.Namespace] {
+			// the role mapping is just not applicable to this cluster
+			logging.Warnf("skipping unapplicable namespace for this cluster: %s", rn.Namespace)
+			continue
+		}
+
+		// a ClusterRole takes precedence over a Role with the same name, so
+		// that existing grants keep the same meaning when a Role is added
+		var kind string
+		switch {
+		case validClusterRoles[rn.Role]:
+			kind = "ClusterRole"
+		case validRoles[rn]:
+			kind = "Role"
+		default:
+			logging.Warnf("role binding %s skipped, no cluster role or role in namespace %s", rn.Role, rn.Namespace)
+			continue
+		}
+
+		change := BindingChange{
+			Kind:      "RoleBinding",
+			Namespace: rn.Namespace,
+			Name:      fmt.Sprintf("infra:%s", rn.Role),
+			Subjects:  subjs,
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     kind,
+				Name:     rn.Role,
+			},
+		}
 
-	for _, existingRb := range existingInfraRbs.Items {
-		rbID := rbIdentifier{
-			namespace: existingRb.Namespace,
-			name:      existingRb.Name,
+		id := rbIdentifier{namespace: change.Namespace, name: change.Name}
+		current, ok := existing[id]
+		delete(existing, id)
+		switch {
+		case !ok:
+			change.Action = BindingCreate
+		case current.RoleRef != change.RoleRef || !sameSubjects(current.Subjects, change.Subjects):
+			change.Action = BindingUpdate
+		default:
+			continue
 		}
-		toDelete[rbID] = existingRb
+		changes = append(changes, change)
 	}
 
-	// Create or update RoleBindings for users/groups
-	for _, rb := range rbs {
-		_, err = clientset.RbacV1().RoleBindings(rb.Namespace).Update(context.TODO(), rb, metav1.UpdateOptions{})
-		if err != nil {
-			if k8sErrors.IsNotFound(err) {
-				_, err = clientset.RbacV1().RoleBindings(rb.Namespace).Create(context.TODO(), rb, metav1.CreateOptions{})
-				if err != nil {
-					if k8sErrors.IsNotFound(err) {
-						// the namespace does not exist
-						// we can proceed in this case, the role mapping is just not applicable to this cluster
-						logging.Warnf("skipping unapplicable namespace for this cluster: %s %s", rb.Namespace, err.Error())
-						continue
-					}
-
-					return err
-				}
-			} else {
+	// Delete any RoleBindings managed by infra that aren't in the grants.
+	// Do not need to worry about deleted namespaces as they will also delete all their resources
+	for _, rb := range existing {
+		changes = append(changes, BindingChange{
+			Action:    BindingDelete,
+			Kind:      "RoleBinding",
+			Namespace: rb.Namespace,
+			Name:      rb.Name,
+			RoleRef:   rb.RoleRef,
+			Subjects:  rb.Subjects,
+		})
+	}
+
+	sortBindingChanges(changes)
+	return changes, nil
+}
+
+func applyRoleBindings(ctx context.Context, clientset kubernetes.Interface, changes []BindingChange) error {
+	for _, change := range changes {
+		client := clientset.RbacV1().RoleBindings(change.Namespace)
+
+		if change.Action == BindingDelete {
+			if err := client.Delete(ctx, change.Name, metav1.DeleteOptions{}); err != nil {
 				return err
 			}
+			continue
 		}
-		// remove anything we update or create from the previous RoleBindings that will be deleted
-		delete(toDelete, rbIdentifier{namespace: rb.Namespace, name: rb.Name})
-	}
 
-	// Delete any Role-kind RoleBindings managed by infra that aren't in the config
-	// Do not need to worry about deleted namespaces as they will also delete all their resources
-	for _, td := range toDelete {
-		err := clientset.RbacV1().RoleBindings(td.Namespace).Delete(context.TODO(), td.Name, metav1.DeleteOptions{})
+		rb := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: change.Name,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "infra",
+				},
+				Namespace: change.Namespace,
+			},
+			Subjects: change.Subjects,
+			RoleRef:  change.RoleRef,
+		}
+
+		_, err := client.Update(ctx, rb, metav1.UpdateOptions{})
+		if k8sErrors.IsInvalid(err) {
+			// the roleRef of a RoleBinding can not be changed, so the binding
+			// must be recreated when the role changes from a Role to a ClusterRole
+			err = client.Delete(ctx, rb.Name, metav1.DeleteOptions{})
+			if err == nil {
+				err = k8sErrors.NewNotFound(rbacv1.Resource("rolebindings"), rb.Name)
+			}
+		}
+		if k8sErrors.IsNotFound(err) {
+			_, err = client.Create(ctx, rb, metav1.CreateOptions{})
+			if k8sErrors.IsNotFound(err) {
+				// the namespace was deleted after the changes were computed
+				logging.Warnf("skipping unapplicable namespace for this cluster: %s %s", rb.Namespace, err.Error())
+				continue
+			}
+		}
 		if err != nil {
 			return err
 		}
@@ -254,6 +416,34 @@ func (k *Kubernetes) UpdateRoleBindings(subjects map[ClusterRoleNamespace][]rbac
 	return nil
 }
 
+// sameSubjects returns true if a and b contain the same subjects, in any order.
+func sameSubjects(a, b []rbacv1.Subject) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	count := make(map[rbacv1.Subject]int, len(a))
+	for _, s := range a {
+		count[s]++
+	}
+	for _, s := range b {
+		if count[s] == 0 {
+			return false
+		}
+		count[s]--
+	}
+	return true
+}
+
+func sortBindingChanges(changes []BindingChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Namespace != changes[j].Namespace {
+			return changes[i].Namespace < changes[j].Namespace
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
+
 func (k *Kubernetes) Namespaces() ([]string, error) {
 	clientset, err := kubernetes.NewForConfig(k.Config)
 	if err != nil {
@@ -714,3 +904,28 @@ func (k *Kubernetes) ClusterRoles() ([]string, error) {
 
 	return results, nil
 }
+
+// NamespacedRoles returns the Roles defined in each namespace, formatted as
+// namespace/role. Roles in kube- namespaces and system: roles are excluded.
+func (k *Kubernetes) NamespacedRoles() ([]string, error) {
+	clientset, err := kubernetes.NewForConfig(k.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := clientset.RbacV1().Roles("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, 0, len(roles.Items))
+	for _, r := range roles.Items {
+		if strings.HasPrefix(r.Namespace, "kube-") || strings.HasPrefix(r.Name, "system:") {
+			continue
+		}
+
+		results = append(results, r.Namespace+"/"+r.Name)
+	}
+
+	return results, nil
+}
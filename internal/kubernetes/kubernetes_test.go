@@ -0,0 +1,181 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var infraLabels = map[string]string{"app.kubernetes.io/managed-by": "infra"}
+
+func user(name string) rbacv1.Subject {
+	return rbacv1.Subject{APIGroup: "rbac.authorization.k8s.io", Kind: rbacv1.UserKind, Name: name}
+}
+
+func roleRef(kind, name string) rbacv1.RoleRef {
+	return rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: kind, Name: name}
+}
+
+func clusterRole(name string) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func TestDiffClusterRoleBindings(t *testing.T) {
+	objects := []runtime.Object{
+		clusterRole("view"),
+		clusterRole("edit"),
+		clusterRole("admin"),
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra:view", Labels: infraLabels},
+			RoleRef:    roleRef("ClusterRole", "view"),
+			Subjects:   []rbacv1.Subject{user("b@example.com"), user("a@example.com")},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra:edit", Labels: infraLabels},
+			RoleRef:    roleRef("ClusterRole", "edit"),
+			Subjects:   []rbacv1.Subject{user("a@example.com")},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra:old", Labels: infraLabels},
+			RoleRef:    roleRef("ClusterRole", "old"),
+			Subjects:   []rbacv1.Subject{user("a@example.com")},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-managed"},
+			RoleRef:    roleRef("ClusterRole", "view"),
+		},
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+
+	subjects := map[string][]rbacv1.Subject{
+		"view":    {user("a@example.com"), user("b@example.com")},
+		"edit":    {user("c@example.com")},
+		"admin":   {user("a@example.com")},
+		"missing": {user("a@example.com")},
+	}
+
+	ctx := context.Background()
+	changes, err := diffClusterRoleBindings(ctx, clientset, subjects)
+	assert.NilError(t, err)
+
+	expected := []BindingChange{
+		{
+			Action:   BindingCreate,
+			Kind:     "ClusterRoleBinding",
+			Name:     "infra:admin",
+			RoleRef:  roleRef("ClusterRole", "admin"),
+			Subjects: []rbacv1.Subject{user("a@example.com")},
+		},
+		{
+			Action:   BindingUpdate,
+			Kind:     "ClusterRoleBinding",
+			Name:     "infra:edit",
+			RoleRef:  roleRef("ClusterRole", "edit"),
+			Subjects: []rbacv1.Subject{user("c@example.com")},
+		},
+		{
+			Action:   BindingDelete,
+			Kind:     "ClusterRoleBinding",
+			Name:     "infra:old",
+			RoleRef:  roleRef("ClusterRole", "old"),
+			Subjects: []rbacv1.Subject{user("a@example.com")},
+		},
+	}
+	assert.DeepEqual(t, changes, expected)
+
+	t.Run("no changes after apply", func(t *testing.T) {
+		err := applyClusterRoleBindings(ctx, clientset, changes)
+		assert.NilError(t, err)
+
+		changes, err := diffClusterRoleBindings(ctx, clientset, subjects)
+		assert.NilError(t, err)
+		assert.Equal(t, len(changes), 0)
+
+		_, err = clientset.RbacV1().ClusterRoleBindings().Get(ctx, "not-managed", metav1.GetOptions{})
+		assert.NilError(t, err)
+	})
+}
+
+func TestDiffRoleBindings(t *testing.T) {
+	objects := []runtime.Object{
+		namespace("ns1"),
+		namespace("ns2"),
+		clusterRole("view"),
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "reader", Namespace: "ns1"}},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra:view", Namespace: "ns1", Labels: infraLabels},
+			RoleRef:    roleRef("ClusterRole", "view"),
+			Subjects:   []rbacv1.Subject{user("a@example.com")},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra:reader", Namespace: "ns1", Labels: infraLabels},
+			RoleRef:    roleRef("ClusterRole", "reader"),
+			Subjects:   []rbacv1.Subject{user("a@example.com")},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra:view", Namespace: "ns2", Labels: infraLabels},
+			RoleRef:    roleRef("ClusterRole", "view"),
+			Subjects:   []rbacv1.Subject{user("a@example.com")},
+		},
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+
+	subjects := map[RoleNamespace][]rbacv1.Subject{
+		{Role: "view", Namespace: "ns1"}:    {user("a@example.com")},
+		{Role: "reader", Namespace: "ns1"}:  {user("a@example.com")},
+		{Role: "reader", Namespace: "ns2"}:  {user("a@example.com")},
+		{Role: "view", Namespace: "absent"}: {user("a@example.com")},
+	}
+
+	ctx := context.Background()
+	changes, err := diffRoleBindings(ctx, clientset, subjects)
+	assert.NilError(t, err)
+
+	expected := []BindingChange{
+		{
+			Action:    BindingUpdate,
+			Kind:      "RoleBinding",
+			Namespace: "ns1",
+			Name:      "infra:reader",
+			RoleRef:   roleRef("Role", "reader"),
+			Subjects:  []rbacv1.Subject{user("a@example.com")},
+		},
+		{
+			Action:    BindingDelete,
+			Kind:      "RoleBinding",
+			Namespace: "ns2",
+			Name:      "infra:view",
+			RoleRef:   roleRef("ClusterRole", "view"),
+			Subjects:  []rbacv1.Subject{user("a@example.com")},
+		},
+	}
+	assert.DeepEqual(t, changes, expected)
+
+	t.Run("no changes after apply", func(t *testing.T) {
+		err := applyRoleBindings(ctx, clientset, changes)
+		assert.NilError(t, err)
+
+		changes, err := diffRoleBindings(ctx, clientset, subjects)
+		assert.NilError(t, err)
+		assert.Equal(t, len(changes), 0)
+	})
+}
+
+func TestSameSubjects(t *testing.T) {
+	a, b := user("a@example.com"), user("b@example.com")
+
+	assert.Assert(t, sameSubjects(nil, nil))
+	assert.Assert(t, sameSubjects([]rbacv1.Subject{a, b}, []rbacv1.Subject{b, a}))
+	assert.Assert(t, !sameSubjects([]rbacv1.Subject{a, a}, []rbacv1.Subject{a, b}))
+	assert.Assert(t, !sameSubjects([]rbacv1.Subject{a}, []rbacv1.Subject{a, b}))
+}
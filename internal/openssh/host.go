@@ -0,0 +1,231 @@
+package openssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Roles that can be granted on an SSH destination.
+const (
+	// RoleUser allows a user to login to the destination.
+	RoleUser = "user"
+	// RoleAdmin allows a user to login to the destination and use sudo.
+	RoleAdmin = "admin"
+)
+
+// Roles returns the roles that can be granted on an SSH destination.
+func Roles() []string {
+	return []string{RoleAdmin, RoleUser}
+}
+
+// Host manages the local users and the sshd configuration files used to
+// authorize Infra users on an SSH destination. sshd must be configured to
+// use the files written by Host, see SSHDConfig.
+type Host struct {
+	// ConfigDir is the directory where the certificate authority and
+	// principals files are written.
+	ConfigDir string
+	// SudoersFile is the path of the sudoers file that lists the users
+	// granted the admin role.
+	SudoersFile string
+
+	// lookupUser and runCommand are used to create local users. They can be
+	// replaced in tests.
+	lookupUser func(name string) (*user.User, error)
+	runCommand func(name string, args ...string) error
+}
+
+// managedUserComment is the GECOS comment of the local users created by Host.
+// Existing users without this comment are never used for Infra users.
+const managedUserComment = "Infra managed user"
+
+// minManagedUID is the lowest uid of a local user that Host will authorize.
+// Lower uids are reserved for system users.
+const minManagedUID = 1000
+
+// reservedUsernames are the names of system users that are never authorized,
+// even if a user with the name does not exist on the host.
+var reservedUsernames = map[string]bool{
+	"root": true, "daemon": true, "bin": true, "sys": true, "sync": true,
+	"games": true, "man": true, "lp": true, "mail": true, "news": true,
+	"uucp": true, "proxy": true, "www-data": true, "backup": true,
+	"list": true, "irc": true, "nobody": true, "sshd": true, "admin": true,
+	"operator": true, "halt": true, "shutdown": true, "adm": true,
+}
+
+func NewHost(configDir, sudoersFile string) *Host {
+	return &Host{
+		ConfigDir:   configDir,
+		SudoersFile: sudoersFile,
+		lookupUser:  user.Lookup,
+		runCommand: func(name string, args ...string) error {
+			out, err := exec.Command(name, args...).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("%v: %w: %s", name, err, out)
+			}
+			return nil
+		},
+	}
+}
+
+func (h *Host) caFile() string {
+	return filepath.Join(h.ConfigDir, "ca.pub")
+}
+
+func (h *Host) principalsDir() string {
+	return filepath.Join(h.ConfigDir, "principals")
+}
+
+// SSHDConfig returns the sshd_config settings that trust certificates signed by
+// the Infra certificate authority for the users managed by Host.
+func (h *Host) SSHDConfig() string {
+	return fmt.Sprintf("TrustedUserCAKeys %v\nAuthorizedPrincipalsFile %v/%%u\n",
+		h.caFile(), h.principalsDir())
+}
+
+// WriteCertificateAuthority writes the public key of the certificate authority
+// that signs user certificates.
+func (h *Host) WriteCertificateAuthority(publicKey string) error {
+	if err := os.MkdirAll(h.ConfigDir, 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(h.caFile(), []byte(publicKey), 0o644)
+}
+
+// UpdateUsers makes the local users match users, which maps a username to
+// the role granted to that user. Missing users are created. Users that are no
+// longer granted access have their principals file removed, which prevents
+// them from logging in with an Infra certificate. Local users are never
+// deleted, so that their home directories are preserved.
+func (h *Host) UpdateUsers(users map[string]string) error {
+	dir := h.principalsDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var errs []error
+	var admins []string
+	for name, role := range users {
+		if err := h.ensureUser(name); err != nil {
+			errs = append(errs, err)
+			// never leave a principals file for a user that was rejected
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			delete(users, name)
+			continue
+		}
+		if err := writeFileAtomic(filepath.Join(dir, name), []byte(name+"\n"), 0o644); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if role == RoleAdmin {
+			admins = append(admins, name)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, ok := users[entry.Name()]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := h.writeSudoers(admins); err != nil {
+		errs = append(errs, err)
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return fmt.Errorf("%w (and %d more errors)", errs[0], len(errs)-1)
+	}
+}
+
+// ensureUser creates the local user if it does not exist. An existing user is
+// only used if it was created by Host, so that an Infra user can never login
+// as a system user, or as a local user created by someone else.
+func (h *Host) ensureUser(name string) error {
+	if reservedUsernames[name] {
+		return fmt.Errorf("user %v: reserved username", name)
+	}
+
+	existing, err := h.lookupUser(name)
+	if err == nil {
+		return checkManagedUser(existing)
+	}
+	var unknown user.UnknownUserError
+	if !errors.As(err, &unknown) {
+		return fmt.Errorf("lookup user %v: %w", name, err)
+	}
+
+	err = h.runCommand("useradd", "--create-home", "--shell", "/bin/bash",
+		"--comment", managedUserComment, name)
+	if err != nil {
+		return fmt.Errorf("create user %v: %w", name, err)
+	}
+	return nil
+}
+
+func checkManagedUser(u *user.User) error {
+	if u.Name != managedUserComment {
+		return fmt.Errorf("user %v: existing user was not created by Infra", u.Username)
+	}
+	id, err := strconv.Atoi(u.Uid)
+	if err != nil || id < minManagedUID {
+		return fmt.Errorf("user %v: uid %v is reserved for system users", u.Username, u.Uid)
+	}
+	return nil
+}
+
+func (h *Host) writeSudoers(admins []string) error {
+	if h.SudoersFile == "" {
+		return nil
+	}
+	sort.Strings(admins)
+
+	var b strings.Builder
+	b.WriteString("# This file is managed by the Infra connector. Changes will be overwritten.\n")
+	for _, name := range admins {
+		fmt.Fprintf(&b, "%v ALL=(ALL) NOPASSWD:ALL\n", name)
+	}
+	return writeFileAtomic(h.SudoersFile, []byte(b.String()), 0o440)
+}
+
+// writeFileAtomic writes the file to a temporary path and renames it, so that
+// sshd and sudo never read a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
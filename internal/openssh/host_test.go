@@ -0,0 +1,96 @@
+package openssh
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestHost_UpdateUsers(t *testing.T) {
+	dir := t.TempDir()
+	host := NewHost(filepath.Join(dir, "infra"), filepath.Join(dir, "sudoers"))
+
+	existing := map[string]*user.User{
+		"alice":  {Username: "alice", Uid: "1001", Name: managedUserComment},
+		"bin":    {Username: "bin", Uid: "2", Name: managedUserComment},
+		"carol":  {Username: "carol", Uid: "1002", Name: "Carol"},
+		"daemon": {Username: "daemon", Uid: "1003", Name: managedUserComment},
+	}
+	var created []string
+	host.lookupUser = func(name string) (*user.User, error) {
+		if u, ok := existing[name]; ok {
+			return u, nil
+		}
+		return nil, user.UnknownUserError(name)
+	}
+	host.runCommand = func(name string, args ...string) error {
+		assert.Equal(t, name, "useradd")
+		assert.Assert(t, is.Contains(args, managedUserComment))
+		username := args[len(args)-1]
+		created = append(created, username)
+		existing[username] = &user.User{Username: username, Uid: "1010", Name: managedUserComment}
+		return nil
+	}
+
+	err := host.UpdateUsers(map[string]string{"alice": RoleUser, "bob": RoleAdmin})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, created, []string{"bob"})
+
+	t.Run("users not created by infra are rejected", func(t *testing.T) {
+		for _, name := range []string{"bin", "carol", "daemon", "root"} {
+			users := map[string]string{"alice": RoleUser, "bob": RoleAdmin, name: RoleAdmin}
+			err := host.UpdateUsers(users)
+			assert.ErrorContains(t, err, "user "+name, name)
+
+			_, err = os.Stat(filepath.Join(dir, "infra/principals", name))
+			assert.Assert(t, os.IsNotExist(err), name)
+		}
+		assert.DeepEqual(t, created, []string{"bob"})
+	})
+
+	expected := fs.Expected(t,
+		fs.WithDir("infra",
+			fs.WithDir("principals",
+				fs.WithFile("alice", "alice\n", fs.WithMode(0o644)),
+				fs.WithFile("bob", "bob\n", fs.WithMode(0o644)),
+			),
+		),
+		fs.WithFile("sudoers",
+			"# This file is managed by the Infra connector. Changes will be overwritten.\nbob ALL=(ALL) NOPASSWD:ALL\n",
+			fs.WithMode(0o440)),
+		fs.MatchAnyFileMode,
+	)
+	assert.Assert(t, fs.Equal(dir, expected))
+
+	t.Run("users without a grant are removed", func(t *testing.T) {
+		err := host.UpdateUsers(map[string]string{"bob": RoleUser})
+		assert.NilError(t, err)
+
+		_, err = os.Stat(filepath.Join(dir, "infra/principals/alice"))
+		assert.Assert(t, os.IsNotExist(err))
+
+		sudoers, err := os.ReadFile(filepath.Join(dir, "sudoers"))
+		assert.NilError(t, err)
+		assert.Equal(t, string(sudoers), "# This file is managed by the Infra connector. Changes will be overwritten.\n")
+	})
+}
+
+func TestHost_WriteCertificateAuthority(t *testing.T) {
+	dir := t.TempDir()
+	host := NewHost(filepath.Join(dir, "infra"), "")
+
+	err := host.WriteCertificateAuthority("ssh-ed25519 AAAA the-key\n")
+	assert.NilError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "infra/ca.pub"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "ssh-ed25519 AAAA the-key\n")
+
+	expected := "TrustedUserCAKeys " + dir + "/infra/ca.pub\nAuthorizedPrincipalsFile " + dir + "/infra/principals/%u\n"
+	assert.Equal(t, host.SSHDConfig(), expected)
+}
@@ -0,0 +1,155 @@
+// Package openssh provides the pieces used to grant access to SSH
+// destinations: a certificate authority that signs short-lived user
+// certificates, and the mapping from Infra users to local Linux users.
+package openssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/infrahq/infra/uid"
+)
+
+// Username returns the name of the local Linux user for an Infra user. The
+// name is the local part of an email address, lowercased, with any characters
+// that are not valid in a Linux username replaced by an underscore, followed
+// by a dash and the user ID in base 36. The user ID makes the name unique, so
+// two users with the same local part, or a user named root@example.com, never
+// map to the same local user.
+//
+// The connector and the server must use the same mapping, because the
+// username is the principal in the certificates signed by the server.
+func Username(id uid.ID, name string) string {
+	if id <= 0 {
+		return ""
+	}
+	suffix := "-" + strconv.FormatInt(int64(id), 36)
+
+	if i := strings.Index(name, "@"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.ToLower(name)
+
+	var b strings.Builder
+	for i, r := range name {
+		if b.Len() >= maxUsernameLength-len(suffix) {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+		case (r >= '0' && r <= '9') || r == '-' || r == '.':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		b.WriteString("user")
+	}
+	return b.String() + suffix
+}
+
+// maxUsernameLength is the longest username accepted by useradd.
+const maxUsernameLength = 32
+
+// GenerateCertificateAuthority creates a new ed25519 key pair used to sign user
+// certificates. The private key is returned as a PKCS8 PEM block, and the
+// public key in the authorized_keys format.
+func GenerateCertificateAuthority() (privateKeyPEM string, publicKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	raw, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", "", err
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return "", "", err
+	}
+
+	privateKeyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: raw}))
+	return privateKeyPEM, string(ssh.MarshalAuthorizedKey(sshPub)), nil
+}
+
+// ErrInvalidPublicKey is returned by SignUserCertificate when the public key
+// can not be parsed.
+var ErrInvalidPublicKey = errors.New("invalid public key")
+
+// UserCertificateOptions are the values used to sign a user certificate.
+type UserCertificateOptions struct {
+	// PublicKey is the public key of the user, in the authorized_keys format.
+	PublicKey string
+	// Username is the only principal of the certificate.
+	Username string
+	// KeyID is recorded in the sshd log when the certificate is used.
+	KeyID string
+	// ValidBefore is the time when the certificate expires.
+	ValidBefore time.Time
+}
+
+// SignUserCertificate signs a user certificate with the certificate authority
+// private key. The certificate is returned in the authorized_keys format.
+func SignUserCertificate(caPrivateKeyPEM string, opts UserCertificateOptions) (string, error) {
+	block, _ := pem.Decode([]byte(caPrivateKeyPEM))
+	if block == nil {
+		return "", errors.New("invalid certificate authority private key")
+	}
+	caKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("parse certificate authority private key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		return "", err
+	}
+
+	userKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(opts.PublicKey))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+
+	var serial [8]byte
+	if _, err := rand.Read(serial[:]); err != nil {
+		return "", err
+	}
+
+	cert := &ssh.Certificate{
+		Key:             userKey,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        ssh.UserCert,
+		KeyId:           opts.KeyID,
+		ValidPrincipals: []string{opts.Username},
+		// allow for some clock skew between the server and the destination
+		ValidAfter:  uint64(time.Now().Add(-5 * time.Minute).Unix()),
+		ValidBefore: uint64(opts.ValidBefore.Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-agent-forwarding": "",
+				"permit-port-forwarding":  "",
+				"permit-pty":              "",
+				"permit-user-rc":          "",
+			},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		return "", fmt.Errorf("sign certificate: %w", err)
+	}
+	return string(ssh.MarshalAuthorizedKey(cert)), nil
+}
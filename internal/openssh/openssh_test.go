@@ -0,0 +1,105 @@
+package openssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/uid"
+)
+
+func TestUsername(t *testing.T) {
+	id := uid.ID(123456789)
+	type testCase struct {
+		id       uid.ID
+		name     string
+		expected string
+	}
+	testCases := []testCase{
+		{id: id, name: "alice@example.com", expected: "alice-21i3v9"},
+		{id: id, name: "Bob.Smith@example.com", expected: "bob.smith-21i3v9"},
+		{id: id, name: "root@example.com", expected: "root-21i3v9"},
+		{id: id, name: "1password@example.com", expected: "_1password-21i3v9"},
+		{id: id, name: "o'brien@example.com", expected: "o_brien-21i3v9"},
+		{id: id, name: "", expected: "user-21i3v9"},
+		{id: id, name: "name+tag@example.com", expected: "name_tag-21i3v9"},
+		{id: id, name: "averyveryveryverylongusernamethatistoolong", expected: "averyveryveryverylonguser-21i3v9"},
+		{id: 0, name: "alice@example.com", expected: ""},
+	}
+	for _, tc := range testCases {
+		actual := Username(tc.id, tc.name)
+		assert.Equal(t, actual, tc.expected, tc.name)
+		assert.Assert(t, len(actual) <= maxUsernameLength, tc.name)
+	}
+
+	t.Run("same local part on different domains", func(t *testing.T) {
+		first := Username(uid.ID(1001), "alice@example.com")
+		second := Username(uid.ID(1002), "alice@example.org")
+		assert.Assert(t, first != second)
+	})
+}
+
+func TestSignUserCertificate(t *testing.T) {
+	caPrivateKey, caPublicKey, err := GenerateCertificateAuthority()
+	assert.NilError(t, err)
+
+	userPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	userKey, err := ssh.NewPublicKey(userPub)
+	assert.NilError(t, err)
+
+	validBefore := time.Now().Add(time.Hour).Truncate(time.Second)
+	raw, err := SignUserCertificate(caPrivateKey, UserCertificateOptions{
+		PublicKey:   string(ssh.MarshalAuthorizedKey(userKey)),
+		Username:    "alice",
+		KeyID:       "alice@example.com",
+		ValidBefore: validBefore,
+	})
+	assert.NilError(t, err)
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(raw))
+	assert.NilError(t, err)
+	cert, ok := pub.(*ssh.Certificate)
+	assert.Assert(t, ok, "expected a certificate, got %T", pub)
+
+	assert.Equal(t, cert.CertType, uint32(ssh.UserCert))
+	assert.DeepEqual(t, cert.ValidPrincipals, []string{"alice"})
+	assert.Equal(t, cert.KeyId, "alice@example.com")
+	assert.Equal(t, cert.ValidBefore, uint64(validBefore.Unix()))
+
+	ca, _, _, _, err := ssh.ParseAuthorizedKey([]byte(caPublicKey))
+	assert.NilError(t, err)
+
+	checker := ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(ca.Marshal())
+		},
+	}
+	_, err = checker.Authenticate(connMetadata{user: "alice"}, cert)
+	assert.NilError(t, err)
+
+	_, err = checker.Authenticate(connMetadata{user: "root"}, cert)
+	assert.ErrorContains(t, err, "not in the set of valid principals")
+
+	t.Run("invalid public key", func(t *testing.T) {
+		_, err := SignUserCertificate(caPrivateKey, UserCertificateOptions{
+			PublicKey:   "not a key",
+			Username:    "alice",
+			ValidBefore: validBefore,
+		})
+		assert.ErrorIs(t, err, ErrInvalidPublicKey)
+	})
+}
+
+type connMetadata struct {
+	ssh.ConnMetadata
+	user string
+}
+
+func (c connMetadata) User() string {
+	return c.user
+}
@@ -11,7 +11,10 @@ import (
 )
 
 func (a *API) ListAccessKeys(c *gin.Context, r *api.ListAccessKeysRequest) (*api.ListResponse[api.AccessKey], error) {
-	p := PaginationFromRequest(r.PaginationRequest)
+	p, err := CursorPaginationFromRequest(r.CursorPaginationRequest, r.PaginationRequest)
+	if err != nil {
+		return nil, err
+	}
 	accessKeys, err := access.ListAccessKeys(c, r.UserID, r.Name, r.ShowExpired, &p)
 	if err != nil {
 		return nil, err
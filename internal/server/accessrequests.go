@@ -0,0 +1,67 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func (a *API) ListAccessRequests(c *gin.Context, r *api.ListAccessRequestsRequest) (*api.ListResponse[api.AccessRequest], error) {
+	p := PaginationFromRequest(r.PaginationRequest)
+	opts := data.ListAccessRequestsOptions{
+		ByUserID:   r.UserID,
+		ByStatus:   models.AccessRequestStatus(r.Status),
+		Pagination: &p,
+	}
+	requests, err := access.ListAccessRequests(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(requests, PaginationToResponse(p), func(req models.AccessRequest) api.AccessRequest {
+		return *req.ToAPI()
+	})
+	return result, nil
+}
+
+func (a *API) GetAccessRequest(c *gin.Context, r *api.Resource) (*api.AccessRequest, error) {
+	req, err := access.GetAccessRequest(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	return req.ToAPI(), nil
+}
+
+func (a *API) CreateAccessRequest(c *gin.Context, r *api.CreateAccessRequestRequest) (*api.AccessRequest, error) {
+	req := &models.AccessRequest{
+		Privilege: r.Privilege,
+		Resource:  r.Resource,
+		Duration:  time.Duration(r.Duration),
+		Reason:    r.Reason,
+	}
+	if err := access.CreateAccessRequest(c, req); err != nil {
+		return nil, err
+	}
+	return req.ToAPI(), nil
+}
+
+func (a *API) ApproveAccessRequest(c *gin.Context, r *api.DecideAccessRequestRequest) (*api.AccessRequest, error) {
+	req, err := access.ApproveAccessRequest(c, r.ID, r.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return req.ToAPI(), nil
+}
+
+func (a *API) DenyAccessRequest(c *gin.Context, r *api.DecideAccessRequestRequest) (*api.AccessRequest, error) {
+	req, err := access.DenyAccessRequest(c, r.ID, r.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return req.ToAPI(), nil
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_AccessRequests(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	userKey, user := createAccessKey(t, srv.DB(), "oncall@example.com")
+	otherKey, _ := createAccessKey(t, srv.DB(), "other@example.com")
+
+	do := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createRequest := func(t *testing.T) api.AccessRequest {
+		t.Helper()
+		body := api.CreateAccessRequestRequest{
+			Privilege: "cluster-admin",
+			Resource:  "production",
+			Duration:  api.Duration(time.Hour),
+			Reason:    "investigating an incident",
+		}
+		resp := do(t, http.MethodPost, "/api/access-requests", userKey, body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.AccessRequest
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&created))
+		return created
+	}
+
+	t.Run("create", func(t *testing.T) {
+		created := createRequest(t)
+		assert.Equal(t, created.User, user.ID)
+		assert.Equal(t, created.Status, "pending")
+		assert.Equal(t, created.Duration, api.Duration(time.Hour))
+	})
+
+	t.Run("missing reason", func(t *testing.T) {
+		body := api.CreateAccessRequestRequest{
+			Privilege: "cluster-admin",
+			Resource:  "production",
+			Duration:  api.Duration(time.Hour),
+		}
+		resp := do(t, http.MethodPost, "/api/access-requests", userKey, body)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("list own requests", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/access-requests?userID="+user.ID.String(), userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("list requests of other users is forbidden", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/access-requests?userID="+user.ID.String(), otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("approve requires admin", func(t *testing.T) {
+		created := createRequest(t)
+		resp := do(t, http.MethodPost, "/api/access-requests/"+created.ID.String()+"/approve", otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("approve creates a grant that expires", func(t *testing.T) {
+		created := createRequest(t)
+		body := api.DecideAccessRequestRequest{Reason: "approved for INC-1"}
+		resp := do(t, http.MethodPost, "/api/access-requests/"+created.ID.String()+"/approve", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var approved api.AccessRequest
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&approved))
+		assert.Equal(t, approved.Status, "approved")
+		assert.Equal(t, approved.DecisionReason, "approved for INC-1")
+		assert.Assert(t, approved.Grant != 0)
+
+		grant, err := data.GetGrant(srv.DB(), data.GetGrantOptions{ByID: approved.Grant})
+		assert.NilError(t, err)
+		assert.Equal(t, grant.Subject, uid.NewIdentityPolymorphicID(user.ID))
+		assert.Equal(t, grant.Privilege, "cluster-admin")
+		assert.Assert(t, grant.ExpiresAt.Valid)
+		assert.Assert(t, grant.ExpiresAt.Time.Before(time.Now().Add(time.Hour+time.Minute)))
+
+		// can not decide the same request twice
+		resp = do(t, http.MethodPost, "/api/access-requests/"+created.ID.String()+"/deny", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		created := createRequest(t)
+		body := api.DecideAccessRequestRequest{Reason: "not on call"}
+		resp := do(t, http.MethodPost, "/api/access-requests/"+created.ID.String()+"/deny", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var denied api.AccessRequest
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&denied))
+		assert.Equal(t, denied.Status, "denied")
+		assert.Equal(t, denied.Grant, uid.ID(0))
+
+		resp = do(t, http.MethodGet, "/api/access-requests?status=denied", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.AccessRequest]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&list))
+		assert.Equal(t, len(list.Items), 1)
+		assert.Equal(t, list.Items[0].ID, denied.ID)
+	})
+}
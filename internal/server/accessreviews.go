@@ -0,0 +1,118 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func (a *API) ListAccessReviews(c *gin.Context, r *api.ListAccessReviewsRequest) (*api.ListResponse[api.AccessReview], error) {
+	p := PaginationFromRequest(r.PaginationRequest)
+	opts := data.ListAccessReviewsOptions{
+		ByStatus:   models.AccessReviewStatus(r.Status),
+		Pagination: &p,
+	}
+	reviews, err := access.ListAccessReviews(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(reviews, PaginationToResponse(p), func(review models.AccessReview) api.AccessReview {
+		return *review.ToAPI()
+	})
+	return result, nil
+}
+
+func (a *API) GetAccessReview(c *gin.Context, r *api.Resource) (*api.AccessReview, error) {
+	review, err := access.GetAccessReview(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	return review.ToAPI(), nil
+}
+
+func (a *API) CreateAccessReview(c *gin.Context, r *api.CreateAccessReviewRequest) (*api.AccessReview, error) {
+	review := &models.AccessReview{
+		Name:         r.Name,
+		Destinations: r.Destinations,
+	}
+	for _, id := range r.Groups {
+		review.Groups = append(review.Groups, id.String())
+	}
+	if err := access.CreateAccessReview(c, review, r.Reviewer); err != nil {
+		return nil, err
+	}
+	return review.ToAPI(), nil
+}
+
+func (a *API) CloseAccessReview(c *gin.Context, r *api.Resource) (*api.AccessReview, error) {
+	// grants managed by the config are kept, the next reconcile would restore them
+	review, err := access.CloseAccessReview(c, r.ID, a.configManagesOrg(c))
+	if err != nil {
+		return nil, err
+	}
+	return review.ToAPI(), nil
+}
+
+func (a *API) ListAccessReviewItems(c *gin.Context, r *api.ListAccessReviewItemsRequest) (*api.ListResponse[api.AccessReviewItem], error) {
+	p := PaginationFromRequest(r.PaginationRequest)
+	opts := data.ListAccessReviewItemsOptions{
+		ByAccessReviewID: r.ID,
+		ByReviewerID:     r.Reviewer,
+		ByDecision:       models.AccessReviewDecision(r.Decision),
+		Pagination:       &p,
+	}
+	items, err := access.ListAccessReviewItems(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(items, PaginationToResponse(p), func(item models.AccessReviewItem) api.AccessReviewItem {
+		return *item.ToAPI()
+	})
+	return result, nil
+}
+
+func (a *API) KeepAccessReviewItem(c *gin.Context, r *api.DecideAccessReviewItemRequest) (*api.AccessReviewItem, error) {
+	item, err := access.DecideAccessReviewItem(c, r.ID, models.AccessReviewDecisionKeep, r.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return item.ToAPI(), nil
+}
+
+func (a *API) RevokeAccessReviewItem(c *gin.Context, r *api.DecideAccessReviewItemRequest) (*api.AccessReviewItem, error) {
+	item, err := access.DecideAccessReviewItem(c, r.ID, models.AccessReviewDecisionRevoke, r.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return item.ToAPI(), nil
+}
+
+func (a *API) ExportAccessReview(c *gin.Context, r *api.Resource) (*api.AccessReviewEvidence, error) {
+	evidence, err := access.ExportAccessReview(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &api.AccessReviewEvidence{
+		AccessReview: *evidence.AccessReview.ToAPI(),
+		Items:        make([]api.AccessReviewEvidenceItem, 0, len(evidence.Items)),
+		Exported:     api.Time(time.Now()),
+	}
+	for _, item := range evidence.Items {
+		result.Items = append(result.Items, api.AccessReviewEvidenceItem{
+			AccessReviewItem: *item.ToAPI(),
+			Subject:          evidence.Names[item.Subject],
+			ReviewerName:     evidence.Names[uid.NewIdentityPolymorphicID(item.ReviewerID)],
+			DecidedByName:    evidence.Names[uid.NewIdentityPolymorphicID(item.DecidedBy)],
+		})
+	}
+	return result, nil
+}
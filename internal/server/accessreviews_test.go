@@ -0,0 +1,263 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_AccessReviews(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+	db := srv.DB()
+
+	reviewerKey, reviewer := createAccessKey(t, db, "reviewer@example.com")
+	otherKey, _ := createAccessKey(t, db, "other@example.com")
+	_, alice := createAccessKey(t, db, "alice@example.com")
+	_, bob := createAccessKey(t, db, "bob@example.com")
+
+	developers := &models.Group{Name: "developers"}
+	assert.NilError(t, data.CreateGroup(db, developers))
+	assert.NilError(t, data.AddUsersToGroup(db, developers.ID, []uid.ID{bob.ID}))
+
+	createGrant := func(t *testing.T, subject uid.PolymorphicID, resource string) *models.Grant {
+		t.Helper()
+		grant := &models.Grant{Subject: subject, Privilege: "view", Resource: resource}
+		assert.NilError(t, data.CreateGrant(db, grant))
+		return grant
+	}
+	aliceProd := createGrant(t, uid.NewIdentityPolymorphicID(alice.ID), "production")
+	aliceStaging := createGrant(t, uid.NewIdentityPolymorphicID(alice.ID), "staging.default")
+	bobProd := createGrant(t, uid.NewIdentityPolymorphicID(bob.ID), "production.kube-system")
+	groupStaging := createGrant(t, uid.NewGroupPolymorphicID(developers.ID), "staging")
+
+	do := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	listItems := func(t *testing.T, reviewID uid.ID) []api.AccessReviewItem {
+		t.Helper()
+		resp := do(t, http.MethodGet, "/api/access-reviews/"+reviewID.String()+"/items", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.AccessReviewItem]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&list))
+		return list.Items
+	}
+
+	itemGrants := func(items []api.AccessReviewItem) []uid.ID {
+		var result []uid.ID
+		for _, item := range items {
+			result = append(result, item.Grant)
+		}
+		return result
+	}
+
+	var review api.AccessReview
+
+	t.Run("create requires admin", func(t *testing.T) {
+		body := api.CreateAccessReviewRequest{Name: "q4", Destinations: []string{"production"}}
+		resp := do(t, http.MethodPost, "/api/access-reviews", otherKey, body)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("create requires a scope", func(t *testing.T) {
+		body := api.CreateAccessReviewRequest{Name: "q4"}
+		resp := do(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("scoped to groups", func(t *testing.T) {
+		body := api.CreateAccessReviewRequest{Name: "developers", Groups: []uid.ID{developers.ID}}
+		resp := do(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.AccessReview
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&created))
+		assert.DeepEqual(t, created.Groups, []uid.ID{developers.ID})
+
+		items := listItems(t, created.ID)
+		assert.DeepEqual(t, itemGrants(items), []uid.ID{bobProd.ID, groupStaging.ID})
+	})
+
+	t.Run("scoped to destinations", func(t *testing.T) {
+		body := api.CreateAccessReviewRequest{
+			Name:         "production",
+			Destinations: []string{"production"},
+			Reviewer:     reviewer.ID,
+		}
+		resp := do(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&review))
+		assert.Equal(t, review.Status, "open")
+
+		items := listItems(t, review.ID)
+		assert.DeepEqual(t, itemGrants(items), []uid.ID{aliceProd.ID, bobProd.ID})
+		for _, item := range items {
+			assert.Equal(t, item.Reviewer, reviewer.ID)
+			assert.Equal(t, item.Decision, "pending")
+		}
+	})
+
+	t.Run("reviewer lists their items", func(t *testing.T) {
+		path := "/api/access-reviews/" + review.ID.String() + "/items?reviewer=" + reviewer.ID.String()
+		resp := do(t, http.MethodGet, path, reviewerKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = do(t, http.MethodGet, path, otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("only the reviewer can decide", func(t *testing.T) {
+		items := listItems(t, review.ID)
+		resp := do(t, http.MethodPost, "/api/access-review-items/"+items[0].ID.String()+"/keep", otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("keep and close", func(t *testing.T) {
+		items := listItems(t, review.ID)
+		body := api.DecideAccessReviewItemRequest{Reason: "still on the team"}
+		resp := do(t, http.MethodPost, "/api/access-review-items/"+items[0].ID.String()+"/keep", reviewerKey, body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var kept api.AccessReviewItem
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&kept))
+		assert.Equal(t, kept.Decision, "keep")
+		assert.Equal(t, kept.DecidedBy, reviewer.ID)
+		assert.Equal(t, kept.Reason, "still on the team")
+
+		resp = do(t, http.MethodPost, "/api/access-reviews/"+review.ID.String()+"/close", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var closed api.AccessReview
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&closed))
+		assert.Equal(t, closed.Status, "closed")
+
+		// the kept grant remains, the unreviewed grant is deleted
+		_, err := data.GetGrant(db, data.GetGrantOptions{ByID: aliceProd.ID})
+		assert.NilError(t, err)
+		_, err = data.GetGrant(db, data.GetGrantOptions{ByID: bobProd.ID})
+		assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+		// grants outside the scope are not changed
+		_, err = data.GetGrant(db, data.GetGrantOptions{ByID: aliceStaging.ID})
+		assert.NilError(t, err)
+
+		items = listItems(t, review.ID)
+		assert.Equal(t, items[1].Decision, "unreviewed")
+
+		// can not decide after the review is closed
+		resp = do(t, http.MethodPost, "/api/access-review-items/"+items[0].ID.String()+"/revoke", reviewerKey, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("revoke and export", func(t *testing.T) {
+		body := api.CreateAccessReviewRequest{Name: "staging", Destinations: []string{"staging"}}
+		resp := do(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.AccessReview
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+		items := listItems(t, created.ID)
+		assert.DeepEqual(t, itemGrants(items), []uid.ID{aliceStaging.ID, groupStaging.ID})
+
+		decide := api.DecideAccessReviewItemRequest{Reason: "left the team"}
+		resp = do(t, http.MethodPost, "/api/access-review-items/"+items[0].ID.String()+"/revoke", adminAccessKey(srv), decide)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		// the grant is not deleted until the review is closed
+		_, err := data.GetGrant(db, data.GetGrantOptions{ByID: aliceStaging.ID})
+		assert.NilError(t, err)
+
+		resp = do(t, http.MethodPost, "/api/access-reviews/"+created.ID.String()+"/close", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		_, err = data.GetGrant(db, data.GetGrantOptions{ByID: aliceStaging.ID})
+		assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+
+		resp = do(t, http.MethodGet, "/api/access-reviews/"+created.ID.String()+"/export", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var evidence api.AccessReviewEvidence
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&evidence))
+		assert.Equal(t, evidence.AccessReview.ID, created.ID)
+		assert.Equal(t, len(evidence.Items), 2)
+		assert.Equal(t, evidence.Items[0].Subject, "alice@example.com")
+		assert.Equal(t, evidence.Items[0].Decision, "revoke")
+		assert.Equal(t, evidence.Items[0].Reason, "left the team")
+		assert.Equal(t, evidence.Items[1].Subject, "developers")
+		assert.Equal(t, evidence.Items[1].Decision, "unreviewed")
+	})
+
+	t.Run("keeps the last infra admin grant", func(t *testing.T) {
+		body := api.CreateAccessReviewRequest{Name: "infra", Destinations: []string{"infra"}}
+		resp := do(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.AccessReview
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+		resp = do(t, http.MethodPost, "/api/access-reviews/"+created.ID.String()+"/close", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		for _, item := range listItems(t, created.ID) {
+			if item.Privilege != models.InfraAdminRole {
+				continue
+			}
+			resp = do(t, http.MethodPost, "/api/access-review-items/"+item.ID.String()+"/keep", adminAccessKey(srv), nil)
+			assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		}
+
+		resp = do(t, http.MethodPost, "/api/access-reviews/"+created.ID.String()+"/close", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("keeps grants managed by the config", func(t *testing.T) {
+		srv.options.Reconcile = true
+		t.Cleanup(func() {
+			srv.options.Reconcile = false
+		})
+
+		configGrant := &models.Grant{
+			Subject:   uid.NewIdentityPolymorphicID(alice.ID),
+			Privilege: "view",
+			Resource:  "testing",
+			CreatedBy: models.CreatedBySystem,
+		}
+		assert.NilError(t, data.CreateGrant(db, configGrant))
+		userGrant := createGrant(t, uid.NewIdentityPolymorphicID(bob.ID), "testing")
+
+		body := api.CreateAccessReviewRequest{Name: "testing", Destinations: []string{"testing"}}
+		resp := do(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.AccessReview
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+		resp = do(t, http.MethodPost, "/api/access-reviews/"+created.ID.String()+"/close", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		_, err := data.GetGrant(db, data.GetGrantOptions{ByID: configGrant.ID})
+		assert.NilError(t, err)
+		_, err = data.GetGrant(db, data.GetGrantOptions{ByID: userGrant.ID})
+		assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+}
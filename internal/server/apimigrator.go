@@ -139,13 +139,13 @@ func rebuildRequest(c *gin.Context, newReqObj interface{}) {
 					query.Add(fieldName, f.String())
 				case reflect.Slice:
 					// only type that does this is []uid.ID
-					switch f.Type() {
+					switch f.Type().Elem() {
 					case reflect.TypeOf(uid.ID(0)):
 						for j := 0; j < f.Len(); j++ {
 							query.Add(fieldName, uid.ID(f.Index(j).Int()).String())
 						}
 					default:
-						panic("unexpected type " + f.Elem().Type().Name())
+						panic("unexpected type " + f.Type().String())
 					}
 				case reflect.Int, reflect.Int64:
 					query.Add(fieldName, fmt.Sprintf("%d", f.Int()))
@@ -467,3 +467,45 @@ func TestRedirectWithARequestRewriteToQueryParameter(t *testing.T) {
 	})
 
 }
+
+func TestRequestRewrite_ListUsersDefaultsToFirstPage(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+
+	a := &API{server: srv}
+	router := gin.New()
+	a.addRequestRewrites()
+
+	ids := []uid.ID{uid.New(), uid.New()}
+	var actual api.ListUsersRequest
+	get(a, rg(router.Group("/")), "/api/users", func(c *gin.Context, req *api.ListUsersRequest) (*api.EmptyResponse, error) {
+		actual = *req
+		return nil, nil
+	})
+
+	path := "/api/users?name=a&ids=" + ids[0].String() + "&ids=" + ids[1].String()
+
+	t.Run("old client", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Add("Infra-Version", "0.18.1")
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Result().StatusCode, 200, resp.Body.String())
+		expected := api.ListUsersRequest{
+			Name:              "a",
+			IDs:               ids,
+			PaginationRequest: api.PaginationRequest{Page: 1},
+		}
+		assert.DeepEqual(t, actual, expected)
+	})
+	t.Run("new client", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Add("Infra-Version", "0.18.2")
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Result().StatusCode, 200, resp.Body.String())
+		expected := api.ListUsersRequest{Name: "a", IDs: ids}
+		assert.DeepEqual(t, actual, expected)
+	})
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func (a *API) ListAuditEvents(c *gin.Context, r *api.ListAuditEventsRequest) (*api.ListResponse[api.AuditEvent], error) {
+	p := PaginationFromRequest(r.PaginationRequest)
+	opts := data.ListAuditEventsOptions{
+		ByActorID:    r.Actor,
+		ByAction:     r.Action,
+		ByTargetType: r.TargetType,
+		ByTargetID:   r.Target,
+		Pagination:   &p,
+	}
+	events, err := access.ListAuditEvents(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(events, PaginationToResponse(p), func(event models.AuditEvent) api.AuditEvent {
+		return *event.ToAPI()
+	})
+	return result, nil
+}
+
+// shouldAudit returns true if a successful request to the route should be
+// recorded as an audit event. Only routes that change state, and requests
+// from an authenticated user in an organization, are audited.
+func shouldAudit(routeID routeIdentifier, route routeSettings, authned access.Authenticated) bool {
+	switch {
+	case routeID.method == http.MethodGet, route.omitFromAudit:
+		return false
+	case authned.User == nil, authned.Organization == nil:
+		return false
+	}
+	return true
+}
+
+// recordAuditEvent stores an audit event for the request in the request
+// transaction, so that the event is only saved if the change is committed.
+// Webhook deliveries for the event are stored in the same transaction.
+func recordAuditEvent(c *gin.Context, rCtx access.RequestContext, routeID routeIdentifier, action string, req, resp any) error {
+	event := &models.AuditEvent{
+		ActorID:    rCtx.Authenticated.User.ID,
+		ActorName:  rCtx.Authenticated.User.Name,
+		Action:     action,
+		TargetType: auditTargetType(routeID.path),
+		TargetID:   auditTargetID(c, resp),
+		RequestID:  rCtx.RequestID,
+	}
+
+	var err error
+	if before := rCtx.Response.AuditBefore(); before != nil {
+		if event.BeforeSummary, err = auditSummary(before); err != nil {
+			return err
+		}
+	}
+
+	after := resp
+	if isNil(after) {
+		after = req
+	}
+	if event.AfterSummary, err = auditSummary(after); err != nil {
+		return err
+	}
+
+	if err := data.CreateAuditEvent(rCtx.DBTxn, event); err != nil {
+		return fmt.Errorf("create audit event: %w", err)
+	}
+	return createWebhookDeliveries(rCtx.DBTxn, event)
+}
+
+// auditTargetType returns the kind of resource changed by a route, which is
+// the part of the path between /api/ and the first path parameter.
+//
+//	/api/grants/:id          => grants
+//	/api/scim/v2/Users/:id   => scim/v2/Users
+func auditTargetType(path string) string {
+	path = strings.TrimPrefix(path, "/api/")
+	if i := strings.Index(path, "/:"); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+// auditTargetID returns the ID from the id path parameter, or the ID of the
+// resource in the response when the path has no id parameter.
+func auditTargetID(c *gin.Context, resp any) uid.ID {
+	if param := c.Param("id"); param != "" {
+		if id, err := uid.Parse([]byte(param)); err == nil {
+			return id
+		}
+	}
+
+	if isNil(resp) {
+		return 0
+	}
+	v := reflect.Indirect(reflect.ValueOf(resp))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	field, ok := v.Type().FieldByName("ID")
+	if !ok {
+		return 0
+	}
+	// FieldByIndexErr avoids a panic when ID is promoted from a nil embedded pointer
+	f, err := v.FieldByIndexErr(field.Index)
+	if err != nil {
+		return 0
+	}
+	id, _ := f.Interface().(uid.ID)
+	return id
+}
+
+// redactedFields are the lowercase substrings of JSON field names that are
+// never written to an audit event.
+var redactedFields = []string{"password", "secret", "accesskey", "privatekey", "token", "code"}
+
+// auditSummary returns a JSON summary of v with any sensitive fields redacted.
+func auditSummary(v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("audit summary: %w", err)
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("audit summary: %w", err)
+	}
+
+	raw, err = json.Marshal(redact(value))
+	if err != nil {
+		return "", fmt.Errorf("audit summary: %w", err)
+	}
+	return string(raw), nil
+}
+
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if isRedactedField(key) {
+				v[key] = "[redacted]"
+				continue
+			}
+			v[key] = redact(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
+
+func isRedactedField(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range redactedFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_ListAuditEvents(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	userKey, user := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	do := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createReq := api.GrantRequest{
+		User:      user.ID,
+		Privilege: "view",
+		Resource:  "production",
+	}
+	resp := do(t, http.MethodPost, "/api/grants", adminAccessKey(srv), createReq)
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var grant api.CreateGrantResponse
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&grant))
+
+	resp = do(t, http.MethodDelete, "/api/grants/"+grant.ID.String(), adminAccessKey(srv), nil)
+	assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+	t.Run("list events for a target", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/audit-events?target="+grant.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.AuditEvent]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&list))
+		assert.Equal(t, len(list.Items), 2)
+
+		deleted, created := list.Items[0], list.Items[1]
+		assert.Equal(t, created.Action, "CreateGrant")
+		assert.Equal(t, created.TargetType, "grants")
+		assert.Equal(t, created.Target, grant.ID)
+		assert.Equal(t, created.ActorName, "admin@example.com")
+		assert.Equal(t, created.Before, "")
+		assert.Assert(t, created.RequestID != 0)
+
+		assert.Equal(t, deleted.Action, "DeleteGrant")
+		assert.Equal(t, deleted.Target, grant.ID)
+		assert.Assert(t, deleted.Before != "")
+	})
+
+	t.Run("delete group records before", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/groups", adminAccessKey(srv), api.CreateGroupRequest{Name: "audited"})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		var group api.Group
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&group))
+
+		resp = do(t, http.MethodDelete, "/api/groups/"+group.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = do(t, http.MethodGet, "/api/audit-events?target="+group.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.AuditEvent]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&list))
+		assert.Equal(t, len(list.Items), 2)
+		deleted := list.Items[0]
+		assert.Equal(t, deleted.Action, "DeleteGroup")
+		assert.Assert(t, strings.Contains(deleted.Before, `"audited"`), deleted.Before)
+	})
+
+	t.Run("failed requests are not recorded", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/grants", userKey, createReq)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = do(t, http.MethodGet, "/api/audit-events?actor="+user.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.AuditEvent]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&list))
+		assert.Equal(t, len(list.Items), 0)
+	})
+
+	t.Run("requires admin or view role", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/audit-events", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
+
+func TestAuditTargetType(t *testing.T) {
+	testCases := map[string]string{
+		"/api/grants":                      "grants",
+		"/api/grants/:id":                  "grants",
+		"/api/groups/:id/users":            "groups",
+		"/api/access-requests/:id/approve": "access-requests",
+		"/api/scim/v2/Users/:id":           "scim/v2/Users",
+		"/api/settings":                    "settings",
+	}
+	for path, expected := range testCases {
+		assert.Equal(t, auditTargetType(path), expected, path)
+	}
+}
+
+func TestAuditSummary(t *testing.T) {
+	resp := api.CreateAccessKeyResponse{
+		ID:        uid.ID(1234),
+		Name:      "the-key",
+		AccessKey: "abcdefghij.0123456789abcdefghijklmn",
+	}
+	actual, err := auditSummary(resp)
+	assert.NilError(t, err)
+
+	var summary map[string]any
+	assert.NilError(t, json.Unmarshal([]byte(actual), &summary))
+	assert.Equal(t, summary["name"], "the-key")
+	assert.Equal(t, summary["accessKey"], "[redacted]")
+
+	provider := api.UpdateProviderRequest{
+		Name:         "okta",
+		ClientSecret: "the-secret",
+		API:          &api.ProviderAPICredentials{PrivateKey: "the-private-key"},
+	}
+	actual, err = auditSummary(provider)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(actual, "the-secret"), actual)
+	assert.Assert(t, !strings.Contains(actual, "the-private-key"), actual)
+}
@@ -17,6 +17,9 @@ type AuthenticatedIdentity struct {
 	// CredentialUpdateRequired indicates that the login used credentials that
 	// must be updated because they will no longer be valid after this login.
 	CredentialUpdateRequired bool
+	// MFARequired indicates that the user must also authenticate with a
+	// second factor before an access key is issued.
+	MFARequired bool
 }
 
 type LoginMethod interface {
@@ -26,6 +29,9 @@ type LoginMethod interface {
 
 type AuthScope struct {
 	PasswordResetOnly bool
+	// MFAEnrollmentOnly restricts the access key to enrolling in MFA, because
+	// the organization requires MFA and the user has not enrolled.
+	MFAEnrollmentOnly bool
 }
 
 type LoginResult struct {
@@ -34,6 +40,11 @@ type LoginResult struct {
 	User                     *models.Identity
 	CredentialUpdateRequired bool
 	OrganizationName         string
+
+	// MFAChallenge is set instead of Bearer when the login must be completed
+	// with a second factor.
+	MFAChallenge          string
+	MFAEnrollmentRequired bool
 }
 
 func Login(
@@ -49,6 +60,10 @@ func Login(
 		return LoginResult{}, fmt.Errorf("failed to login: %w", err)
 	}
 
+	if authenticated.MFARequired {
+		return createMFAChallenge(db, authenticated)
+	}
+
 	// login authentication was successful, create an access key for the user
 
 	accessKey := &models.AccessKey{
@@ -64,6 +79,9 @@ func Login(
 	if authenticated.AuthScope.PasswordResetOnly {
 		accessKey.Scopes = append(accessKey.Scopes, models.ScopePasswordReset)
 	}
+	if authenticated.AuthScope.MFAEnrollmentOnly {
+		accessKey.Scopes = append(accessKey.Scopes, models.ScopeMFAEnrollment)
+	}
 
 	bearer, err := data.CreateAccessKey(db, accessKey)
 	if err != nil {
@@ -71,7 +89,7 @@ func Login(
 	}
 
 	authenticated.Identity.LastSeenAt = time.Now().UTC()
-	if err := data.UpdateIdentity(db, authenticated.Identity); err != nil {
+	if err := data.UpdateIdentityLastSeenAt(db, authenticated.Identity); err != nil {
 		return LoginResult{}, fmt.Errorf("login failed to update last seen: %w", err)
 	}
 
@@ -86,5 +104,6 @@ func Login(
 		User:                     authenticated.Identity,
 		CredentialUpdateRequired: authenticated.CredentialUpdateRequired,
 		OrganizationName:         org.Name,
+		MFAEnrollmentRequired:    authenticated.AuthScope.MFAEnrollmentOnly,
 	}, nil
 }
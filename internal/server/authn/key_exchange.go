@@ -7,6 +7,7 @@ import (
 
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
 )
 
 // keyExchangeAuthn allows exchanging a valid access key for new access key with a shorter lifetime
@@ -25,6 +26,9 @@ func (a *keyExchangeAuthn) Authenticate(_ context.Context, db *data.Transaction,
 	if err != nil {
 		return AuthenticatedIdentity{}, fmt.Errorf("invalid access key in exchange: %w", err)
 	}
+	if validatedRequestKey.Scopes.Includes(models.ScopeMFAChallenge) || validatedRequestKey.Scopes.Includes(models.ScopeMFAEnrollment) {
+		return AuthenticatedIdentity{}, fmt.Errorf("invalid access key in exchange: key is restricted to mfa")
+	}
 
 	sessionExpiry := requestedExpiry
 
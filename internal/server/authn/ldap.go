@@ -0,0 +1,75 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+type ldapAuthn struct {
+	Provider *models.Provider
+	Client   *providers.LDAPClient
+	Username string
+	Password string
+}
+
+// NewLDAPAuthentication returns a LoginMethod that authenticates a user by
+// binding to the directory of an LDAP provider with their password.
+func NewLDAPAuthentication(provider *models.Provider, username, password string) (LoginMethod, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("nil provider in ldap authentication")
+	}
+	if provider.Kind != models.ProviderKindLDAP {
+		return nil, fmt.Errorf("%w: provider %v is not an ldap provider", internal.ErrBadRequest, provider.Name)
+	}
+	return &ldapAuthn{
+		Provider: provider,
+		Client:   providers.NewLDAPClient(*provider),
+		Username: username,
+		Password: password,
+	}, nil
+}
+
+func (a *ldapAuthn) Authenticate(_ context.Context, db *data.Transaction, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	user, err := a.Client.Authenticate(a.Username, a.Password)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("ldap login: %w", err)
+	}
+
+	identity, err := data.GetIdentity(db, data.GetIdentityOptions{ByName: user.Name, LoadGroups: true})
+	if err != nil {
+		if !errors.Is(err, internal.ErrNotFound) {
+			return AuthenticatedIdentity{}, fmt.Errorf("get user: %w", err)
+		}
+
+		identity = &models.Identity{Name: user.Name}
+
+		if err := data.CreateIdentity(db, identity); err != nil {
+			return AuthenticatedIdentity{}, fmt.Errorf("create user: %w", err)
+		}
+	}
+
+	if _, err := data.CreateProviderUser(db, a.Provider, identity); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("add user for provider login: %w", err)
+	}
+
+	if err := data.AssignIdentityToGroups(db, identity, a.Provider, user.Groups); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("assign identity to groups: %w", err)
+	}
+
+	return AuthenticatedIdentity{
+		Identity:      identity,
+		Provider:      a.Provider,
+		SessionExpiry: requestedExpiry,
+	}, nil
+}
+
+func (a *ldapAuthn) Name() string {
+	return "ldap"
+}
@@ -0,0 +1,89 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/ldap"
+)
+
+func TestLDAPAuthenticate(t *testing.T) {
+	db := setupDB(t)
+	server := ldap.NewServer(t,
+		ldap.Entry{
+			DN:         "cn=infra,dc=example,dc=com",
+			Attributes: map[string][]string{"userPassword": {"service-password"}},
+		},
+		ldap.Entry{
+			DN: "uid=ada,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass":  {"person"},
+				"uid":          {"ada"},
+				"mail":         {"ada@example.com"},
+				"userPassword": {"password"},
+			},
+		},
+		ldap.Entry{
+			DN: "cn=developers,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"developers"},
+				"member":      {"uid=ada,ou=people,dc=example,dc=com"},
+			},
+		},
+	)
+
+	provider := &models.Provider{
+		Name:             "ldap",
+		Kind:             models.ProviderKindLDAP,
+		URL:              server.URL,
+		LDAPBindDN:       "cn=infra,dc=example,dc=com",
+		LDAPBindPassword: "service-password",
+		LDAPUserBaseDN:   "dc=example,dc=com",
+	}
+	assert.NilError(t, data.CreateProvider(db, provider))
+	sessionExpiry := time.Now().Add(time.Hour)
+
+	t.Run("not an ldap provider", func(t *testing.T) {
+		_, err := NewLDAPAuthentication(&models.Provider{Name: "okta", Kind: models.ProviderKindOkta}, "ada", "password")
+		assert.ErrorContains(t, err, "provider okta is not an ldap provider")
+	})
+
+	t.Run("new user with groups", func(t *testing.T) {
+		authn, err := NewLDAPAuthentication(provider, "ada", "password")
+		assert.NilError(t, err)
+
+		authnIdentity, err := authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.NilError(t, err)
+		assert.Equal(t, authnIdentity.Identity.Name, "ada@example.com")
+		assert.Equal(t, authnIdentity.Provider.ID, provider.ID)
+		assert.Assert(t, authnIdentity.SessionExpiry.Equal(sessionExpiry))
+
+		pu, err := data.GetProviderUser(db, provider.ID, authnIdentity.Identity.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"developers"})
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		authn, err := NewLDAPAuthentication(provider, "ada", "wrong")
+		assert.NilError(t, err)
+
+		_, err = authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.Assert(t, errors.Is(err, internal.ErrUnauthorized), err)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		authn, err := NewLDAPAuthentication(provider, "ada", "")
+		assert.NilError(t, err)
+
+		_, err = authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.Assert(t, errors.Is(err, internal.ErrUnauthorized), err)
+	})
+}
@@ -0,0 +1,131 @@
+package authn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// mfaChallengeExpiry is the time a user has to complete a login with their
+// second factor.
+const mfaChallengeExpiry = 5 * time.Minute
+
+// mfaAuthn completes a login that requires a second factor, by presenting the
+// challenge from the first step of the login and a TOTP or recovery code.
+type mfaAuthn struct {
+	Challenge string
+	Code      string
+}
+
+func NewMFAAuthentication(challenge, code string) LoginMethod {
+	return &mfaAuthn{
+		Challenge: challenge,
+		Code:      code,
+	}
+}
+
+func (a *mfaAuthn) Authenticate(_ context.Context, db *data.Transaction, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	challenge, err := data.ValidateRequestAccessKey(db, a.Challenge)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("invalid mfa challenge: %w", err)
+	}
+	if !challenge.Scopes.Includes(models.ScopeMFAChallenge) {
+		return AuthenticatedIdentity{}, fmt.Errorf("invalid mfa challenge: access key is not a challenge")
+	}
+	if challenge.OrganizationID != db.OrganizationID() {
+		return AuthenticatedIdentity{}, fmt.Errorf("invalid mfa challenge: organization does not match")
+	}
+
+	identity, err := data.GetIdentity(db, data.GetIdentityOptions{ByID: challenge.IssuedFor})
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("user is not valid: %w", err)
+	}
+
+	credential, err := data.GetCredentialByUserID(db, identity.ID)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("get credential: %w", err)
+	}
+
+	if err := verifySecondFactor(db, credential, a.Code); err != nil {
+		return AuthenticatedIdentity{}, err
+	}
+
+	// a challenge can only be used once
+	if err := data.DeleteAccessKeys(db, data.DeleteAccessKeysOptions{ByID: challenge.ID}); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("delete mfa challenge: %w", err)
+	}
+
+	authnIdentity := AuthenticatedIdentity{
+		Identity:      identity,
+		Provider:      data.InfraProvider(db),
+		SessionExpiry: requestedExpiry,
+	}
+	if credential.OneTimePassword {
+		authnIdentity.AuthScope.PasswordResetOnly = true
+		authnIdentity.CredentialUpdateRequired = true
+	}
+	return authnIdentity, nil
+}
+
+func (a *mfaAuthn) Name() string {
+	return "mfa"
+}
+
+// verifySecondFactor checks that code is an unused TOTP code, or an unused
+// recovery code, for the credential. The time step of a TOTP code is stored,
+// and a recovery code is removed from the credential, when it is used.
+func verifySecondFactor(db data.WriteTxn, credential *models.Credential, code string) error {
+	if !credential.TOTPEnabled {
+		return fmt.Errorf("user is not enrolled in mfa")
+	}
+
+	if counter, ok := ValidateTOTP(string(credential.TOTPSecret), code, time.Now(), credential.TOTPLastCounter); ok {
+		credential.TOTPLastCounter = counter
+		if err := data.UpdateCredential(db, credential); err != nil {
+			return fmt.Errorf("update totp counter: %w", err)
+		}
+		return nil
+	}
+
+	hash := HashRecoveryCode(code)
+	for i, recoveryCode := range credential.RecoveryCodes {
+		if recoveryCode != hash {
+			continue
+		}
+
+		remaining := append(models.CommaSeparatedStrings{}, credential.RecoveryCodes[:i]...)
+		credential.RecoveryCodes = append(remaining, credential.RecoveryCodes[i+1:]...)
+		if err := data.UpdateCredential(db, credential); err != nil {
+			return fmt.Errorf("update recovery codes: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid mfa code")
+}
+
+// createMFAChallenge creates the access key that is used to complete a login
+// with a second factor. The key can not be used for any other request.
+func createMFAChallenge(db *data.Transaction, authenticated AuthenticatedIdentity) (LoginResult, error) {
+	expires := time.Now().UTC().Add(mfaChallengeExpiry)
+	challenge := &models.AccessKey{
+		IssuedFor:     authenticated.Identity.ID,
+		IssuedForName: authenticated.Identity.Name,
+		ProviderID:    authenticated.Provider.ID,
+		ExpiresAt:     expires,
+		Scopes:        models.CommaSeparatedStrings{models.ScopeMFAChallenge},
+	}
+
+	bearer, err := data.CreateAccessKey(db, challenge)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("failed to create mfa challenge: %w", err)
+	}
+
+	return LoginResult{
+		AccessKey:    challenge,
+		User:         authenticated.Identity,
+		MFAChallenge: bearer,
+	}, nil
+}
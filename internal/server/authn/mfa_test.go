@@ -0,0 +1,131 @@
+package authn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestMFAAuthentication(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+
+	secret, err := GenerateTOTPSecret()
+	assert.NilError(t, err)
+	codes, hashes, err := GenerateRecoveryCodes()
+	assert.NilError(t, err)
+
+	user := &models.Identity{Name: "krillin@example.com"}
+	assert.NilError(t, data.CreateIdentity(db, user))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	assert.NilError(t, err)
+	credential := &models.Credential{
+		IdentityID:    user.ID,
+		PasswordHash:  hash,
+		TOTPSecret:    models.EncryptedAtRest(secret),
+		TOTPEnabled:   true,
+		RecoveryCodes: hashes,
+	}
+	assert.NilError(t, data.CreateCredential(db, credential))
+
+	currentCode := func(t *testing.T) string {
+		code, err := GenerateTOTPCode(secret, time.Now())
+		assert.NilError(t, err)
+		return code
+	}
+
+	challenge := func(t *testing.T) string {
+		t.Helper()
+		result, err := Login(ctx, db, NewPasswordCredentialAuthentication(user.Name, "password123"), expiry, time.Hour)
+		assert.NilError(t, err)
+		assert.Equal(t, result.Bearer, "")
+		assert.Assert(t, result.MFAChallenge != "")
+		assert.DeepEqual(t, result.AccessKey.Scopes, models.CommaSeparatedStrings{models.ScopeMFAChallenge})
+		return result.MFAChallenge
+	}
+
+	t.Run("password login returns a challenge", func(t *testing.T) {
+		challenge(t)
+	})
+
+	t.Run("totp code", func(t *testing.T) {
+		mfaChallenge := challenge(t)
+		result, err := Login(ctx, db, NewMFAAuthentication(mfaChallenge, currentCode(t)), expiry, time.Hour)
+		assert.NilError(t, err)
+		assert.Assert(t, result.Bearer != "")
+		assert.Equal(t, result.User.ID, user.ID)
+		assert.Equal(t, len(result.AccessKey.Scopes), 0)
+
+		t.Run("challenge can only be used once", func(t *testing.T) {
+			_, err := Login(ctx, db, NewMFAAuthentication(mfaChallenge, currentCode(t)), expiry, time.Hour)
+			assert.ErrorContains(t, err, "invalid mfa challenge")
+		})
+
+		t.Run("totp code can only be used once", func(t *testing.T) {
+			_, err := Login(ctx, db, NewMFAAuthentication(challenge(t), currentCode(t)), expiry, time.Hour)
+			assert.ErrorContains(t, err, "invalid mfa code")
+		})
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		result, err := Login(ctx, db, NewMFAAuthentication(challenge(t), codes[3]), expiry, time.Hour)
+		assert.NilError(t, err)
+		assert.Assert(t, result.Bearer != "")
+
+		updated, err := data.GetCredentialByUserID(db, user.ID)
+		assert.NilError(t, err)
+		assert.Equal(t, len(updated.RecoveryCodes), len(codes)-1)
+
+		t.Run("recovery code can only be used once", func(t *testing.T) {
+			_, err := Login(ctx, db, NewMFAAuthentication(challenge(t), codes[3]), expiry, time.Hour)
+			assert.ErrorContains(t, err, "invalid mfa code")
+		})
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		_, err := Login(ctx, db, NewMFAAuthentication(challenge(t), "000000"), expiry, time.Hour)
+		assert.ErrorContains(t, err, "invalid mfa code")
+	})
+
+	t.Run("access key is not a challenge", func(t *testing.T) {
+		key := &models.AccessKey{
+			IssuedFor:  user.ID,
+			ProviderID: data.InfraProvider(db).ID,
+			ExpiresAt:  time.Now().Add(time.Minute),
+		}
+		bearer, err := data.CreateAccessKey(db, key)
+		assert.NilError(t, err)
+
+		_, err = Login(ctx, db, NewMFAAuthentication(bearer, currentCode(t)), expiry, time.Hour)
+		assert.ErrorContains(t, err, "access key is not a challenge")
+	})
+
+	t.Run("challenge can not be exchanged", func(t *testing.T) {
+		_, err := Login(ctx, db, NewKeyExchangeAuthentication(challenge(t)), time.Now().Add(time.Minute), time.Hour)
+		assert.ErrorContains(t, err, "key is restricted to mfa")
+	})
+
+	t.Run("org requires mfa", func(t *testing.T) {
+		settings, err := data.GetSettings(db)
+		assert.NilError(t, err)
+		settings.RequireMFA = true
+		assert.NilError(t, data.UpdateSettings(db, settings))
+
+		other := &models.Identity{Name: "yamcha@example.com"}
+		assert.NilError(t, data.CreateIdentity(db, other))
+		assert.NilError(t, data.CreateCredential(db, &models.Credential{IdentityID: other.ID, PasswordHash: hash}))
+
+		result, err := Login(ctx, db, NewPasswordCredentialAuthentication(other.Name, "password123"), expiry, time.Hour)
+		assert.NilError(t, err)
+		assert.Assert(t, result.MFAEnrollmentRequired)
+		assert.DeepEqual(t, result.AccessKey.Scopes, models.CommaSeparatedStrings{models.ScopeMFAEnrollment})
+	})
+}
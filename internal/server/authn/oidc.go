@@ -88,6 +88,8 @@ func (a *oidcAuthn) Authenticate(ctx context.Context, db *data.Transaction, requ
 	providerUser.AccessToken = models.EncryptedAtRest(idpAuth.AccessToken)
 	providerUser.RefreshToken = models.EncryptedAtRest(idpAuth.RefreshToken)
 	providerUser.ExpiresAt = idpAuth.AccessTokenExpiry
+	// a successful login means the identity provider knows this user again
+	providerUser.Active = true
 	err = data.UpdateProviderUser(db, providerUser)
 	if err != nil {
 		return AuthenticatedIdentity{}, fmt.Errorf("UpdateProviderUser: %w", err)
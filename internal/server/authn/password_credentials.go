@@ -51,12 +51,27 @@ func (a *passwordCredentialAuthn) Authenticate(ctx context.Context, db *data.Tra
 		SessionExpiry: requestedExpiry,
 	}
 
+	if userCredential.TOTPEnabled {
+		// the login is completed by NewMFAAuthentication
+		authnIdentity.MFARequired = true
+		return authnIdentity, nil
+	}
+
 	if userCredential.OneTimePassword {
 		// scope the login down to Password Reset Only
 		authnIdentity.AuthScope.PasswordResetOnly = true
 		authnIdentity.CredentialUpdateRequired = true
 	}
 
+	settings, err := data.GetSettings(db)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("get settings: %w", err)
+	}
+	if settings.RequireMFA {
+		// the user must enroll before they can do anything else
+		authnIdentity.AuthScope.MFAEnrollmentOnly = true
+	}
+
 	// authentication was a success
 	return authnIdentity, nil // password login is always for infra users
 }
@@ -0,0 +1,111 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+type samlAuthn struct {
+	Provider        *models.Provider
+	ServiceProvider providers.SAMLServiceProvider
+	Response        string
+}
+
+// NewSAMLAuthentication returns a LoginMethod that authenticates a user with
+// the base64 encoded SAML response sent by the identity provider to the
+// assertion consumer service.
+func NewSAMLAuthentication(provider *models.Provider, sp providers.SAMLServiceProvider, response string) (LoginMethod, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("nil provider in saml authentication")
+	}
+	if provider.Kind != models.ProviderKindSAML {
+		return nil, fmt.Errorf("%w: provider %v is not a saml provider", internal.ErrBadRequest, provider.Name)
+	}
+	return &samlAuthn{
+		Provider:        provider,
+		ServiceProvider: sp,
+		Response:        response,
+	}, nil
+}
+
+func (a *samlAuthn) Authenticate(_ context.Context, db *data.Transaction, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	idp, err := providers.ParseSAMLMetadata([]byte(a.Provider.SAMLMetadata))
+	if err != nil {
+		return AuthenticatedIdentity{}, err
+	}
+
+	assertion, err := a.ServiceProvider.ParseResponse(idp, a.Response, time.Now())
+	if err != nil {
+		return AuthenticatedIdentity{}, err
+	}
+
+	// the response must be for a login started by this server, and each login
+	// can only be completed once
+	if err := data.UseSAMLRequest(db, a.Provider.ID, assertion.InResponseTo); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("saml response is not for a login request from this server: %w", err)
+	}
+
+	// an assertion can only be used once, until it expires
+	if err := data.RecordSAMLAssertion(db, a.Provider.ID, assertion.ID, assertion.ExpiresAt); err != nil {
+		return AuthenticatedIdentity{}, err
+	}
+
+	name := assertion.NameID
+	if a.Provider.SAMLNameAttribute != "" {
+		name = firstValue(assertion.Attributes[a.Provider.SAMLNameAttribute])
+	}
+	if name == "" {
+		return AuthenticatedIdentity{}, fmt.Errorf("saml assertion is missing the user name")
+	}
+
+	identity, err := data.GetIdentity(db, data.GetIdentityOptions{ByName: name, LoadGroups: true})
+	if err != nil {
+		if !errors.Is(err, internal.ErrNotFound) {
+			return AuthenticatedIdentity{}, fmt.Errorf("get user: %w", err)
+		}
+
+		identity = &models.Identity{Name: name}
+
+		if err := data.CreateIdentity(db, identity); err != nil {
+			return AuthenticatedIdentity{}, fmt.Errorf("create user: %w", err)
+		}
+	}
+
+	if _, err := data.CreateProviderUser(db, a.Provider, identity); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("add user for provider login: %w", err)
+	}
+
+	// SAML has no way to look up a user after login, so groups are only
+	// updated from the attributes of each assertion
+	var groups []string
+	if a.Provider.SAMLGroupsAttribute != "" {
+		groups = assertion.Attributes[a.Provider.SAMLGroupsAttribute]
+	}
+	if err := data.AssignIdentityToGroups(db, identity, a.Provider, groups); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("assign identity to groups: %w", err)
+	}
+
+	return AuthenticatedIdentity{
+		Identity:      identity,
+		Provider:      a.Provider,
+		SessionExpiry: requestedExpiry,
+	}, nil
+}
+
+func (a *samlAuthn) Name() string {
+	return "saml"
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,119 @@
+package authn
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/internal/testing/saml"
+)
+
+func TestSAMLAuthenticate(t *testing.T) {
+	db := setupDB(t)
+	idp := saml.NewIdP(t)
+
+	provider := &models.Provider{
+		Name:                "saml",
+		Kind:                models.ProviderKindSAML,
+		SAMLMetadata:        idp.Metadata(),
+		SAMLGroupsAttribute: "groups",
+	}
+	assert.NilError(t, data.CreateProvider(db, provider))
+
+	sp := providers.SAMLServiceProvider{
+		EntityID: "https://infra.example.com/api/providers/1/saml/metadata",
+		ACSURL:   "https://infra.example.com/api/providers/1/saml/acs",
+	}
+	responseTo := func(requestID string, id string, groups ...string) string {
+		raw := idp.Response(t, saml.Assertion{
+			ID:            id,
+			NameID:        "ada@example.com",
+			Audience:      sp.EntityID,
+			Recipient:     sp.ACSURL,
+			InResponseTo:  requestID,
+			Attributes:    []saml.Attribute{{Name: "groups", Values: groups}},
+			SignAssertion: true,
+		})
+		return base64.StdEncoding.EncodeToString([]byte(raw))
+	}
+	// response returns a response to a new AuthnRequest
+	var requests int
+	response := func(id string, groups ...string) string {
+		requests++
+		requestID := fmt.Sprintf("_request%d", requests)
+		assert.NilError(t, data.CreateSAMLRequest(db, provider.ID, requestID, time.Now().Add(time.Minute)))
+		return responseTo(requestID, id, groups...)
+	}
+	sessionExpiry := time.Now().Add(time.Hour)
+
+	t.Run("nil provider", func(t *testing.T) {
+		_, err := NewSAMLAuthentication(nil, sp, "")
+		assert.ErrorContains(t, err, "nil provider in saml authentication")
+	})
+
+	t.Run("not a saml provider", func(t *testing.T) {
+		_, err := NewSAMLAuthentication(&models.Provider{Name: "okta", Kind: models.ProviderKindOkta}, sp, "")
+		assert.ErrorContains(t, err, "provider okta is not a saml provider")
+	})
+
+	t.Run("new user with groups", func(t *testing.T) {
+		authn, err := NewSAMLAuthentication(provider, sp, response("_first", "developers", "admins"))
+		assert.NilError(t, err)
+
+		authnIdentity, err := authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.NilError(t, err)
+		assert.Equal(t, authnIdentity.Identity.Name, "ada@example.com")
+		assert.Equal(t, authnIdentity.Provider.ID, provider.ID)
+		assert.Assert(t, authnIdentity.SessionExpiry.Equal(sessionExpiry))
+
+		pu, err := data.GetProviderUser(db, provider.ID, authnIdentity.Identity.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"developers", "admins"})
+	})
+
+	t.Run("existing user, groups are updated", func(t *testing.T) {
+		authn, err := NewSAMLAuthentication(provider, sp, response("_second", "developers"))
+		assert.NilError(t, err)
+
+		authnIdentity, err := authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.NilError(t, err)
+
+		var groups []string
+		for _, g := range authnIdentity.Identity.Groups {
+			groups = append(groups, g.Name)
+		}
+		assert.DeepEqual(t, groups, []string{"developers"})
+	})
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		authn, err := NewSAMLAuthentication(provider, sp, response("_first", "developers"))
+		assert.NilError(t, err)
+
+		_, err = authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.ErrorIs(t, err, data.ErrSAMLAssertionReplayed)
+	})
+
+	t.Run("response to a request that was not issued", func(t *testing.T) {
+		authn, err := NewSAMLAuthentication(provider, sp, responseTo("_unknown", "_third", "developers"))
+		assert.NilError(t, err)
+
+		_, err = authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		authn, err := NewSAMLAuthentication(provider, sp, "not-a-response")
+		assert.NilError(t, err)
+
+		_, err = authn.Authenticate(context.Background(), db, sessionExpiry)
+		assert.ErrorContains(t, err, "saml response")
+	})
+}
@@ -0,0 +1,129 @@
+package authn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505, SHA1 is the default algorithm for TOTP, and is supported by all authenticator apps
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/infrahq/infra/internal/generate"
+)
+
+const (
+	// totpPeriod is the number of seconds that a TOTP code is valid.
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is the number of periods before and after the current period
+	// that are accepted, to allow for clock drift.
+	totpSkew = 1
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random secret for generating TOTP codes,
+// encoded as base32 without padding.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPProvisioningURI returns an otpauth URI for the secret. Authenticator apps
+// use the URI, usually from a QR code, to add the account.
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// ValidateTOTP returns the time step of code if code is a valid TOTP code for
+// the secret at time now. Codes from a time step at or before lastCounter
+// have already been used, and are rejected. The caller must store the
+// returned time step, so that the code can not be used again.
+func ValidateTOTP(secret, code string, now time.Time, lastCounter int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	counter := now.Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		step := counter + int64(i)
+		if step <= lastCounter {
+			continue
+		}
+		expected := totpCode(key, uint64(step))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateTOTPCode returns the TOTP code for the secret at time now.
+func GenerateTOTPCode(secret string, now time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return totpCode(key, uint64(now.Unix()/totpPeriod)), nil
+}
+
+// totpCode returns the code for the counter, as described by RFC 4226.
+func totpCode(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// GenerateRecoveryCodes returns new recovery codes, and the hashes of the codes
+// to store.
+func GenerateRecoveryCodes() (codes []string, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generate.CryptoRandom(recoveryCodeLength, generate.CharsetAlphaNumeric)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the hash of a recovery code, as it is stored. The
+// codes are random, so they do not need a slow password hash.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+package authn
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// test vectors from RFC 6238, Appendix B, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	type testCase struct {
+		code        string
+		now         time.Time
+		lastCounter int64
+		expected    bool
+	}
+
+	cases := map[string]testCase{
+		"valid code": {
+			code:     "287082",
+			now:      time.Unix(59, 0),
+			expected: true,
+		},
+		"valid code at a later time": {
+			code:     "081804",
+			now:      time.Unix(1111111109, 0),
+			expected: true,
+		},
+		"code from previous period": {
+			code:     "287082",
+			now:      time.Unix(59+30, 0),
+			expected: true,
+		},
+		"code from too long ago": {
+			code:     "287082",
+			now:      time.Unix(59+90, 0),
+			expected: false,
+		},
+		"code was already used": {
+			code:        "287082",
+			now:         time.Unix(59, 0),
+			lastCounter: 1,
+			expected:    false,
+		},
+		"code from before the last used code": {
+			code:        "287082",
+			now:         time.Unix(59+30, 0),
+			lastCounter: 2,
+			expected:    false,
+		},
+		"wrong code": {
+			code:     "287083",
+			now:      time.Unix(59, 0),
+			expected: false,
+		},
+		"wrong length": {
+			code:     "2870820",
+			now:      time.Unix(59, 0),
+			expected: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			counter, ok := ValidateTOTP(secret, tc.code, tc.now, tc.lastCounter)
+			assert.Equal(t, ok, tc.expected)
+			if tc.expected {
+				assert.Assert(t, counter > tc.lastCounter)
+			}
+		})
+	}
+
+	t.Run("returns the time step of the code", func(t *testing.T) {
+		counter, ok := ValidateTOTP(secret, "287082", time.Unix(59+30, 0), 0)
+		assert.Assert(t, ok)
+		assert.Equal(t, counter, int64(1))
+	})
+
+	t.Run("invalid secret", func(t *testing.T) {
+		_, ok := ValidateTOTP("not base32!", "287082", time.Unix(59, 0), 0)
+		assert.Assert(t, !ok)
+	})
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NilError(t, err)
+	assert.Equal(t, len(secret), 32)
+
+	now := time.Now()
+	code, err := GenerateTOTPCode(secret, now)
+	assert.NilError(t, err)
+	_, ok := ValidateTOTP(secret, code, now, 0)
+	assert.Assert(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Infra", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+	u, err := url.Parse(uri)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Scheme, "otpauth")
+	assert.Equal(t, u.Host, "totp")
+	assert.Equal(t, u.Path, "/Infra:alice@example.com")
+	assert.Equal(t, u.Query().Get("secret"), "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, u.Query().Get("issuer"), "Infra")
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	assert.NilError(t, err)
+	assert.Equal(t, len(codes), recoveryCodeCount)
+	assert.Equal(t, len(hashes), recoveryCodeCount)
+
+	for i, code := range codes {
+		assert.Equal(t, len(code), recoveryCodeLength)
+		assert.Equal(t, HashRecoveryCode(code), hashes[i])
+	}
+}
@@ -0,0 +1,403 @@
+package authn
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// WebAuthnTimeout is the time a user has to complete a WebAuthn ceremony.
+const WebAuthnTimeout = 5 * time.Minute
+
+// COSE algorithm identifiers, from the IANA COSE Algorithms registry.
+const (
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+// WebAuthnAlgorithms are the COSE algorithms supported for credentials, in
+// order of preference.
+var WebAuthnAlgorithms = []int{coseAlgES256, coseAlgEdDSA, coseAlgRS256}
+
+const (
+	authenticatorFlagUserPresent            = 0x01
+	authenticatorFlagUserVerified           = 0x04
+	authenticatorFlagAttestedCredentialData = 0x40
+)
+
+var cborHandle = &codec.CborHandle{}
+
+// NewWebAuthnChallenge returns a new random challenge for a WebAuthn ceremony,
+// encoded as base64url.
+func NewWebAuthnChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return EncodeWebAuthnBase64(challenge), nil
+}
+
+// EncodeWebAuthnBase64 encodes b as base64url without padding, which is the
+// encoding used for binary values in the WebAuthn JSON types.
+func EncodeWebAuthnBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeWebAuthnBase64 decodes a base64url value, with or without padding.
+func DecodeWebAuthnBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// WebAuthnUserHandle returns the user handle of the user, which is stored by
+// discoverable credentials.
+func WebAuthnUserHandle(userID uid.ID) string {
+	return EncodeWebAuthnBase64([]byte(userID.String()))
+}
+
+// VerifyWebAuthnRegistration verifies the response to a registration ceremony,
+// and returns the new credential. The attestation statement is not verified,
+// the server always requests "none" attestation.
+func VerifyWebAuthnRegistration(session *models.WebAuthnSession, clientDataJSON, attestationObject []byte) (*models.WebAuthnCredential, error) {
+	if err := verifyClientData(clientDataJSON, "webauthn.create", session); err != nil {
+		return nil, err
+	}
+
+	var attestation struct {
+		Fmt      string `codec:"fmt"`
+		AuthData []byte `codec:"authData"`
+	}
+	if err := codec.NewDecoderBytes(attestationObject, cborHandle).Decode(&attestation); err != nil {
+		return nil, fmt.Errorf("decode attestation object: %w", err)
+	}
+
+	authData, err := parseAuthenticatorData(attestation.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := authData.verify(session.RPID); err != nil {
+		return nil, err
+	}
+	if len(authData.CredentialID) == 0 {
+		return nil, fmt.Errorf("authenticator data is missing the attested credential")
+	}
+	if _, _, err := parseCOSEKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &models.WebAuthnCredential{
+		IdentityID:   session.IdentityID,
+		CredentialID: EncodeWebAuthnBase64(authData.CredentialID),
+		PublicKey:    authData.PublicKey,
+		SignCount:    int64(authData.SignCount),
+	}, nil
+}
+
+// verifyWebAuthnAssertion verifies the response to a login ceremony, and
+// returns the new value of the signature counter.
+func verifyWebAuthnAssertion(
+	session *models.WebAuthnSession,
+	credential *models.WebAuthnCredential,
+	clientDataJSON, rawAuthData, signature []byte,
+) (uint32, error) {
+	if err := verifyClientData(clientDataJSON, "webauthn.get", session); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := authData.verify(session.RPID); err != nil {
+		return 0, err
+	}
+
+	key, alg, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if err := verifySignature(key, alg, signed, signature); err != nil {
+		return 0, err
+	}
+
+	// authenticators that do not implement a counter always return 0
+	if authData.SignCount != 0 || credential.SignCount != 0 {
+		if int64(authData.SignCount) <= credential.SignCount {
+			return 0, fmt.Errorf("signature counter did not increase, the authenticator may be cloned")
+		}
+	}
+	return authData.SignCount, nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func verifyClientData(raw []byte, ceremony string, session *models.WebAuthnSession) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("decode client data: %w", err)
+	}
+
+	if cd.Type != ceremony {
+		return fmt.Errorf("client data type %q does not match %q", cd.Type, ceremony)
+	}
+	challenge := strings.TrimRight(cd.Challenge, "=")
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(session.Challenge)) != 1 {
+		return fmt.Errorf("client data challenge does not match")
+	}
+
+	origin, err := url.Parse(cd.Origin)
+	if err != nil {
+		return fmt.Errorf("invalid origin: %w", err)
+	}
+	// the origin may be the relying party, or a subdomain of the relying party
+	host := origin.Hostname()
+	switch {
+	case host != session.RPID && !strings.HasSuffix(host, "."+session.RPID):
+		return fmt.Errorf("origin %q does not match relying party %q", cd.Origin, session.RPID)
+	case origin.Scheme == "https":
+	case origin.Scheme == "http" && host == "localhost":
+	default:
+		return fmt.Errorf("origin %q must use https", cd.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	// PublicKey is the COSE encoded public key of the attested credential.
+	PublicKey []byte
+}
+
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < 37 {
+		return authenticatorData{}, fmt.Errorf("authenticator data is too short")
+	}
+	authData := authenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if authData.Flags&authenticatorFlagAttestedCredentialData == 0 {
+		return authData, nil
+	}
+
+	// aaguid (16 bytes), credential ID length (2 bytes), credential ID, public key
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return authenticatorData{}, fmt.Errorf("attested credential data is too short")
+	}
+	length := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < length {
+		return authenticatorData{}, fmt.Errorf("attested credential data is too short")
+	}
+	authData.CredentialID = rest[:length]
+	rest = rest[length:]
+
+	// the public key may be followed by extensions, so decode it to find the end
+	var key map[int64]interface{}
+	decoder := codec.NewDecoderBytes(rest, cborHandle)
+	if err := decoder.Decode(&key); err != nil {
+		return authenticatorData{}, fmt.Errorf("decode credential public key: %w", err)
+	}
+	authData.PublicKey = rest[:decoder.NumBytesRead()]
+	return authData, nil
+}
+
+func (a authenticatorData) verify(rpID string) error {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(a.RPIDHash, rpIDHash[:]) {
+		return fmt.Errorf("authenticator data is for a different relying party")
+	}
+	if a.Flags&authenticatorFlagUserPresent == 0 {
+		return fmt.Errorf("user was not present")
+	}
+	if a.Flags&authenticatorFlagUserVerified == 0 {
+		return fmt.Errorf("user was not verified")
+	}
+	return nil
+}
+
+// parseCOSEKey parses a COSE_Key, and returns the public key and the algorithm.
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int64, error) {
+	var key map[int64]interface{}
+	if err := codec.NewDecoderBytes(raw, cborHandle).Decode(&key); err != nil {
+		return nil, 0, fmt.Errorf("decode public key: %w", err)
+	}
+
+	kty, _ := coseInt(key[1])
+	alg, _ := coseInt(key[3])
+	switch alg {
+	case coseAlgES256:
+		crv, _ := coseInt(key[-1])
+		x, _ := key[-2].([]byte)
+		y, _ := key[-3].([]byte)
+		if kty != 2 || crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("invalid ES256 public key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, fmt.Errorf("invalid ES256 public key")
+		}
+		return pub, alg, nil
+	case coseAlgEdDSA:
+		crv, _ := coseInt(key[-1])
+		x, _ := key[-2].([]byte)
+		if kty != 1 || crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("invalid EdDSA public key")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case coseAlgRS256:
+		n, _ := key[-1].([]byte)
+		e, _ := key[-2].([]byte)
+		if kty != 3 || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("invalid RS256 public key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, alg, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported public key algorithm %d", alg)
+	}
+}
+
+func coseInt(v interface{}) (int64, bool) {
+	switch i := v.(type) {
+	case int64:
+		return i, true
+	case uint64:
+		return int64(i), true
+	}
+	return 0, false
+}
+
+func verifySignature(key crypto.PublicKey, alg int64, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	var valid bool
+	switch alg {
+	case coseAlgES256:
+		valid = ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], signature)
+	case coseAlgEdDSA:
+		valid = ed25519.Verify(key.(ed25519.PublicKey), signed, signature)
+	case coseAlgRS256:
+		valid = rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+type webAuthnAuthn struct {
+	SessionID         uid.ID
+	CredentialID      string
+	ClientDataJSON    string
+	AuthenticatorData string
+	Signature         string
+	UserHandle        string
+}
+
+// NewWebAuthnAuthentication returns a LoginMethod that completes a WebAuthn
+// login ceremony. The binary values are base64url encoded. The user must be
+// verified by the authenticator, so the login is multi-factor.
+func NewWebAuthnAuthentication(sessionID uid.ID, credentialID, clientDataJSON, authenticatorData, signature, userHandle string) LoginMethod {
+	return &webAuthnAuthn{
+		SessionID:         sessionID,
+		CredentialID:      credentialID,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authenticatorData,
+		Signature:         signature,
+		UserHandle:        userHandle,
+	}
+}
+
+func (a *webAuthnAuthn) Authenticate(_ context.Context, db *data.Transaction, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	session, err := data.ClaimWebAuthnSession(db, a.SessionID, models.WebAuthnSessionLogin)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("webauthn session: %w", err)
+	}
+
+	credential, err := data.GetWebAuthnCredential(db, data.GetWebAuthnCredentialOptions{
+		ByCredentialID: strings.TrimRight(a.CredentialID, "="),
+	})
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("webauthn credential: %w", err)
+	}
+
+	if session.IdentityID != 0 && session.IdentityID != credential.IdentityID {
+		return AuthenticatedIdentity{}, fmt.Errorf("webauthn credential does not belong to the user")
+	}
+	if a.UserHandle != "" && strings.TrimRight(a.UserHandle, "=") != WebAuthnUserHandle(credential.IdentityID) {
+		return AuthenticatedIdentity{}, fmt.Errorf("webauthn user handle does not match the credential")
+	}
+
+	clientDataJSON, err := DecodeWebAuthnBase64(a.ClientDataJSON)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("decode client data: %w", err)
+	}
+	authData, err := DecodeWebAuthnBase64(a.AuthenticatorData)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("decode authenticator data: %w", err)
+	}
+	signature, err := DecodeWebAuthnBase64(a.Signature)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("decode signature: %w", err)
+	}
+
+	signCount, err := verifyWebAuthnAssertion(session, credential, clientDataJSON, authData, signature)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("webauthn assertion: %w", err)
+	}
+
+	credential.SignCount = int64(signCount)
+	credential.LastUsedAt = time.Now().UTC()
+	if err := data.UpdateWebAuthnCredential(db, credential); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("update webauthn credential: %w", err)
+	}
+
+	identity, err := data.GetIdentity(db, data.GetIdentityOptions{ByID: credential.IdentityID})
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("user is not valid: %w", err)
+	}
+
+	return AuthenticatedIdentity{
+		Identity:      identity,
+		Provider:      data.InfraProvider(db),
+		SessionExpiry: requestedExpiry,
+	}, nil
+}
+
+func (a *webAuthnAuthn) Name() string {
+	return "webauthn"
+}
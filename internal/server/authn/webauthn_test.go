@@ -0,0 +1,338 @@
+package authn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ugorji/go/codec"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// testAuthenticator implements the parts of a WebAuthn authenticator needed to
+// test the ceremonies, using an ES256 key.
+type testAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	flags        byte
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	return &testAuthenticator{
+		t:            t,
+		key:          key,
+		credentialID: []byte("credential-" + t.Name()),
+		flags:        authenticatorFlagUserPresent | authenticatorFlagUserVerified,
+	}
+}
+
+func (a *testAuthenticator) encodeCBOR(v interface{}) []byte {
+	var out []byte
+	assert.NilError(a.t, codec.NewEncoderBytes(&out, cborHandle).Encode(v))
+	return out
+}
+
+func (a *testAuthenticator) publicKey() []byte {
+	return a.encodeCBOR(map[int64]interface{}{
+		1:  2,
+		3:  coseAlgES256,
+		-1: 1,
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+func (a *testAuthenticator) authData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append([]byte{}, rpIDHash[:]...)
+
+	flags := a.flags
+	if attested {
+		flags |= authenticatorFlagAttestedCredentialData
+	}
+	authData = append(authData, flags)
+	var signCount [4]byte
+	binary.BigEndian.PutUint32(signCount[:], a.signCount)
+	authData = append(authData, signCount[:]...)
+
+	if attested {
+		authData = append(authData, make([]byte, 16)...) // aaguid
+		var length [2]byte
+		binary.BigEndian.PutUint16(length[:], uint16(len(a.credentialID)))
+		authData = append(authData, length[:]...)
+		authData = append(authData, a.credentialID...)
+		authData = append(authData, a.publicKey()...)
+	}
+	return authData
+}
+
+func clientDataJSON(t *testing.T, ceremony, challenge, origin string) []byte {
+	raw, err := json.Marshal(clientData{Type: ceremony, Challenge: challenge, Origin: origin})
+	assert.NilError(t, err)
+	return raw
+}
+
+func (a *testAuthenticator) register(session *models.WebAuthnSession, origin string) (clientData, attestationObject []byte) {
+	clientData = clientDataJSON(a.t, "webauthn.create", session.Challenge, origin)
+	attestationObject = a.encodeCBOR(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(session.RPID, true),
+	})
+	return clientData, attestationObject
+}
+
+func (a *testAuthenticator) assert(session *models.WebAuthnSession, origin string) (clientData, authData, signature []byte) {
+	a.signCount++
+	clientData = clientDataJSON(a.t, "webauthn.get", session.Challenge, origin)
+	authData = a.authData(session.RPID, false)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	assert.NilError(a.t, err)
+	return clientData, authData, signature
+}
+
+func newTestWebAuthnSession(t *testing.T, kind string) *models.WebAuthnSession {
+	challenge, err := NewWebAuthnChallenge()
+	assert.NilError(t, err)
+	return &models.WebAuthnSession{Kind: kind, Challenge: challenge, RPID: "infra.example.com"}
+}
+
+func TestVerifyWebAuthnRegistration(t *testing.T) {
+	origin := "https://infra.example.com"
+
+	t.Run("valid", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		clientData, attestationObject := authenticator.register(session, origin)
+
+		credential, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.NilError(t, err)
+		assert.Equal(t, credential.CredentialID, EncodeWebAuthnBase64(authenticator.credentialID))
+		key, alg, err := parseCOSEKey(credential.PublicKey)
+		assert.NilError(t, err)
+		assert.Equal(t, alg, int64(coseAlgES256))
+		assert.Assert(t, authenticator.key.PublicKey.Equal(key))
+	})
+
+	t.Run("wrong challenge", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		_, attestationObject := authenticator.register(session, origin)
+		clientData := clientDataJSON(t, "webauthn.create", "other", origin)
+
+		_, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.ErrorContains(t, err, "challenge does not match")
+	})
+
+	t.Run("wrong ceremony", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		_, attestationObject := authenticator.register(session, origin)
+		clientData := clientDataJSON(t, "webauthn.get", session.Challenge, origin)
+
+		_, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.ErrorContains(t, err, `client data type "webauthn.get" does not match`)
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		clientData, attestationObject := authenticator.register(session, "https://evil.example.com")
+
+		_, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.ErrorContains(t, err, "does not match relying party")
+	})
+
+	t.Run("origin is a subdomain of the relying party", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		clientData, attestationObject := authenticator.register(session, "https://acme.infra.example.com")
+
+		_, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.NilError(t, err)
+	})
+
+	t.Run("origin with the relying party as a suffix", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		clientData, attestationObject := authenticator.register(session, "https://evilinfra.example.com")
+
+		_, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.ErrorContains(t, err, "does not match relying party")
+	})
+
+	t.Run("origin without https", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		clientData, attestationObject := authenticator.register(session, "http://infra.example.com")
+
+		_, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.ErrorContains(t, err, "must use https")
+	})
+
+	t.Run("user not verified", func(t *testing.T) {
+		authenticator := newTestAuthenticator(t)
+		authenticator.flags = authenticatorFlagUserPresent
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+		clientData, attestationObject := authenticator.register(session, origin)
+
+		_, err := VerifyWebAuthnRegistration(session, clientData, attestationObject)
+		assert.ErrorContains(t, err, "user was not verified")
+	})
+}
+
+func TestVerifyWebAuthnAssertion(t *testing.T) {
+	origin := "https://infra.example.com"
+	authenticator := newTestAuthenticator(t)
+	credential := &models.WebAuthnCredential{PublicKey: authenticator.publicKey()}
+
+	t.Run("valid", func(t *testing.T) {
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionLogin)
+		clientData, authData, signature := authenticator.assert(session, origin)
+
+		signCount, err := verifyWebAuthnAssertion(session, credential, clientData, authData, signature)
+		assert.NilError(t, err)
+		assert.Equal(t, signCount, authenticator.signCount)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionLogin)
+		clientData, authData, _ := authenticator.assert(session, origin)
+		_, _, signature := authenticator.assert(newTestWebAuthnSession(t, models.WebAuthnSessionLogin), origin)
+
+		_, err := verifyWebAuthnAssertion(session, credential, clientData, authData, signature)
+		assert.ErrorContains(t, err, "invalid signature")
+	})
+
+	t.Run("signature counter did not increase", func(t *testing.T) {
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionLogin)
+		clientData, authData, signature := authenticator.assert(session, origin)
+
+		cloned := &models.WebAuthnCredential{PublicKey: credential.PublicKey, SignCount: int64(authenticator.signCount)}
+		_, err := verifyWebAuthnAssertion(session, cloned, clientData, authData, signature)
+		assert.ErrorContains(t, err, "authenticator may be cloned")
+	})
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+
+	t.Run("EdDSA", func(t *testing.T) {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		assert.NilError(t, err)
+		raw := authenticator.encodeCBOR(map[int64]interface{}{1: 1, 3: coseAlgEdDSA, -1: 6, -2: []byte(pub)})
+
+		key, alg, err := parseCOSEKey(raw)
+		assert.NilError(t, err)
+		assert.Equal(t, alg, int64(coseAlgEdDSA))
+		assert.DeepEqual(t, key, pub)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		raw := authenticator.encodeCBOR(map[int64]interface{}{1: 2, 3: -35})
+		_, _, err := parseCOSEKey(raw)
+		assert.ErrorContains(t, err, "unsupported public key algorithm -35")
+	})
+
+	t.Run("point not on curve", func(t *testing.T) {
+		raw := authenticator.encodeCBOR(map[int64]interface{}{1: 2, 3: coseAlgES256, -1: 1, -2: make([]byte, 32), -3: make([]byte, 32)})
+		_, _, err := parseCOSEKey(raw)
+		assert.ErrorContains(t, err, "invalid ES256 public key")
+	})
+}
+
+func TestWebAuthnAuthentication(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	origin := "https://infra.example.com"
+	expiry := time.Now().Add(time.Hour)
+
+	user := &models.Identity{Name: "gohan@example.com"}
+	assert.NilError(t, data.CreateIdentity(db, user))
+
+	authenticator := newTestAuthenticator(t)
+	registration := newTestWebAuthnSession(t, models.WebAuthnSessionRegistration)
+	registration.IdentityID = user.ID
+	clientData, attestationObject := authenticator.register(registration, origin)
+	credential, err := VerifyWebAuthnRegistration(registration, clientData, attestationObject)
+	assert.NilError(t, err)
+	assert.NilError(t, data.CreateWebAuthnCredential(db, credential))
+
+	newLogin := func(t *testing.T) (*models.WebAuthnSession, LoginMethod) {
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionLogin)
+		session.ExpiresAt = time.Now().Add(time.Minute)
+		assert.NilError(t, data.CreateWebAuthnSession(db, session))
+
+		clientData, authData, signature := authenticator.assert(session, origin)
+		method := NewWebAuthnAuthentication(
+			session.ID,
+			EncodeWebAuthnBase64(authenticator.credentialID),
+			EncodeWebAuthnBase64(clientData),
+			EncodeWebAuthnBase64(authData),
+			EncodeWebAuthnBase64(signature),
+			WebAuthnUserHandle(user.ID),
+		)
+		return session, method
+	}
+
+	t.Run("success", func(t *testing.T) {
+		_, method := newLogin(t)
+		result, err := Login(ctx, db, method, expiry, time.Hour)
+		assert.NilError(t, err)
+		assert.Equal(t, result.User.ID, user.ID)
+		assert.Assert(t, result.Bearer != "")
+
+		updated, err := data.GetWebAuthnCredential(db, data.GetWebAuthnCredentialOptions{ByID: credential.ID})
+		assert.NilError(t, err)
+		assert.Equal(t, updated.SignCount, int64(authenticator.signCount))
+		assert.Assert(t, !updated.LastUsedAt.IsZero())
+	})
+
+	t.Run("session can only be used once", func(t *testing.T) {
+		_, method := newLogin(t)
+		_, err := Login(ctx, db, method, expiry, time.Hour)
+		assert.NilError(t, err)
+
+		_, err = Login(ctx, db, method, expiry, time.Hour)
+		assert.ErrorContains(t, err, "webauthn session")
+	})
+
+	t.Run("session for a different user", func(t *testing.T) {
+		other := &models.Identity{Name: "videl@example.com"}
+		assert.NilError(t, data.CreateIdentity(db, other))
+
+		session := newTestWebAuthnSession(t, models.WebAuthnSessionLogin)
+		session.IdentityID = other.ID
+		session.ExpiresAt = time.Now().Add(time.Minute)
+		assert.NilError(t, data.CreateWebAuthnSession(db, session))
+
+		clientData, authData, signature := authenticator.assert(session, origin)
+		method := NewWebAuthnAuthentication(
+			session.ID,
+			EncodeWebAuthnBase64(authenticator.credentialID),
+			EncodeWebAuthnBase64(clientData),
+			EncodeWebAuthnBase64(authData),
+			EncodeWebAuthnBase64(signature),
+			"",
+		)
+		_, err := Login(ctx, db, method, expiry, time.Hour)
+		assert.ErrorContains(t, err, "does not belong to the user")
+	})
+}
@@ -17,23 +17,71 @@ import (
 // transaction passed to this job will not have an OrganizationID.
 type BackgroundJobFunc func(ctx context.Context, tx *data.Transaction) error
 
+// BackgroundDBJobFunc is like BackgroundJobFunc, but the job manages its own
+// transactions. It is used by jobs that make network requests, which should
+// not hold a transaction open, or that perform independent units of work
+// which should be committed separately.
+type BackgroundDBJobFunc func(ctx context.Context, db *data.DB) error
+
 func (s *Server) SetupBackgroundJobs(ctx context.Context) {
 	s.registerJob(ctx, jobs.RemoveOldDeviceFlowRequests, 10*time.Minute)
 	s.registerJob(ctx, jobs.RemoveExpiredAccessKeys, 12*time.Hour)
 	s.registerJob(ctx, jobs.RemoveExpiredPasswordResetTokens, 15*time.Minute)
+	s.registerJob(ctx, jobs.RemoveExpiredWebAuthnSessions, 15*time.Minute)
+	s.registerJob(ctx, jobs.RemoveExpiredSAMLAssertions, 15*time.Minute)
+	s.registerJob(ctx, jobs.RemoveExpiredSAMLRequests, 15*time.Minute)
+	s.registerJob(ctx, jobs.RemoveExpiredGrants, time.Minute)
+	s.registerDBJob(ctx, jobs.DeliverWebhooks, 10*time.Second)
+	s.registerDBJob(ctx, jobs.SyncLDAPGroups, 15*time.Minute)
+	s.registerDBJob(ctx, jobs.SyncOIDCProviderUsers, 15*time.Minute)
+	s.registerJob(ctx, jobs.RotateSigningKeys, time.Hour)
 }
 
 func (s *Server) registerJob(ctx context.Context, job BackgroundJobFunc, every time.Duration) {
 	s.routines = append(s.routines, routine{
-		run:  jobWrapper(ctx, s.db, job, every),
+		run:  jobWrapper(ctx, getFuncName(job), inTransaction(ctx, s.db, job), every),
 		stop: func() {}, // uses the context to stop
 	})
 }
 
-func jobWrapper(ctx context.Context, db *data.DB, job BackgroundJobFunc, every time.Duration) func() error {
+// inTransaction returns a function that runs job in a new transaction. The
+// transaction is committed if the job succeeds, and rolled back otherwise.
+func inTransaction(ctx context.Context, db *data.DB, job BackgroundJobFunc) func() error {
+	return func() error {
+		tx, err := db.Begin(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to start transaction :%w", err)
+		}
+		if err := job(ctx, tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+}
+
+func (s *Server) registerDBJob(ctx context.Context, job BackgroundDBJobFunc, every time.Duration) {
+	run := func() error {
+		return job(ctx, s.db)
+	}
+	s.routines = append(s.routines, routine{
+		run:  jobWrapper(ctx, getFuncName(job), run, every),
+		stop: func() {}, // uses the context to stop
+	})
+}
+
+// frequentJobInterval is the longest interval of jobs that run often enough
+// that logging every successful run at info level would flood the logs.
+const frequentJobInterval = time.Minute
+
+func jobWrapper(ctx context.Context, funcName string, job func() error, every time.Duration) func() error {
+	logSuccess := logging.Infof
+	if every <= frequentJobInterval {
+		logSuccess = logging.Debugf
+	}
+
 	return func() error {
 		t := time.NewTicker(every)
-		funcName := getFuncName(job)
 
 		jobWithRescue := func() error {
 			if ctx.Err() != nil {
@@ -44,16 +92,7 @@ func jobWrapper(ctx context.Context, db *data.DB, job BackgroundJobFunc, every t
 					logging.Errorf("background job %s panic: %s", funcName, err)
 				}
 			}()
-
-			tx, err := db.Begin(ctx, nil)
-			if err != nil {
-				return fmt.Errorf("failed to start transaction :%w", err)
-			}
-			if err := job(ctx, tx); err != nil {
-				_ = tx.Rollback()
-				return err
-			}
-			return tx.Commit()
+			return job()
 		}
 
 		for {
@@ -64,7 +103,7 @@ func jobWrapper(ctx context.Context, db *data.DB, job BackgroundJobFunc, every t
 				if err := jobWithRescue(); err != nil {
 					logging.Errorf("background job %s error: %s", funcName, err.Error())
 				} else {
-					logging.Infof("background job %s successful, elapsed: %s", funcName, time.Since(startAt))
+					logSuccess("background job %s successful, elapsed: %s", funcName, time.Since(startAt))
 				}
 			case <-ctx.Done():
 				t.Stop()
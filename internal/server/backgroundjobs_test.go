@@ -58,7 +58,7 @@ func TestJobWrapper(t *testing.T) {
 	}
 
 	g := errgroup.Group{}
-	fn := jobWrapper(ctx, db, job, time.Millisecond)
+	fn := jobWrapper(ctx, "job", inTransaction(ctx, db, job), time.Millisecond)
 	g.Go(fn)
 	<-chReady
 
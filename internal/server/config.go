@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/infrahq/secrets"
 	"golang.org/x/crypto/bcrypt"
 
@@ -81,6 +82,12 @@ type Config struct {
 	Providers                 []Provider
 	Grants                    []Grant
 	Users                     []User
+
+	// Reconcile makes the config the source of truth for the providers, users,
+	// and grants in the default organization. Existing resources that match
+	// the config are adopted and managed by the config, and requests to change
+	// or delete a resource managed by the config are rejected.
+	Reconcile bool
 }
 
 func (c Config) ValidationRules() []validate.ValidationRule {
@@ -597,25 +604,78 @@ func getKindFromUnstructured(data interface{}) string {
 	return ""
 }
 
+// ConfigChange is a change to a resource in the default organization, made by
+// loading the server config.
+type ConfigChange struct {
+	Action string
+	Kind   string
+	Name   string
+}
+
+const (
+	ConfigChangeCreate = "create"
+	ConfigChangeUpdate = "update"
+	ConfigChangeDelete = "delete"
+	// ConfigChangeAdopt is an existing resource, created outside of the
+	// config, that is now managed by the config.
+	ConfigChangeAdopt = "adopt"
+)
+
+func (c ConfigChange) String() string {
+	return fmt.Sprintf("%s %s %q", c.Action, c.Kind, c.Name)
+}
+
+// configPlan records the changes made by loading the server config.
+type configPlan struct {
+	reconcile bool
+	changes   []ConfigChange
+}
+
+func (p *configPlan) add(action, kind, name string) {
+	p.changes = append(p.changes, ConfigChange{Action: action, Kind: kind, Name: name})
+}
+
 func (s Server) loadConfig(config Config) error {
-	if err := validate.Validate(config); err != nil {
+	changes, err := s.reconcileConfig(config, true)
+	if err != nil {
 		return err
 	}
+	for _, change := range changes {
+		logging.Infof("server config: %v", change)
+	}
+	return nil
+}
 
-	org := s.db.DefaultOrg
+// planConfig returns the changes that loading config would make, without
+// applying them.
+func (s Server) planConfig(config Config) ([]ConfigChange, error) {
+	return s.reconcileConfig(config, false)
+}
+
+func (s Server) reconcileConfig(config Config, commit bool) ([]ConfigChange, error) {
+	if err := validate.Validate(config); err != nil {
+		return nil, err
+	}
+
+	// copy the org so that the default org is only changed when the
+	// transaction is committed
+	org := *s.db.DefaultOrg
 
 	tx, err := s.db.Begin(context.Background(), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer logError(tx.Rollback, "failed to rollback loadConfig transaction")
 	tx = tx.WithOrgID(org.ID)
 
+	plan := &configPlan{reconcile: config.Reconcile}
+
 	if config.DefaultOrganizationDomain != org.Domain {
 		org.Domain = config.DefaultOrganizationDomain
-		if err := data.UpdateOrganization(tx, org); err != nil {
-			return fmt.Errorf("update default org domain: %w", err)
+		if err := data.UpdateOrganization(tx, &org); err != nil {
+			return nil, fmt.Errorf("update default org domain: %w", err)
 		}
+		plan.add(ConfigChangeUpdate, "organization domain", org.Domain)
 	}
 
 	// inject internal infra provider
@@ -634,8 +694,8 @@ func (s Server) loadConfig(config Config) error {
 		Resource: "infra",
 	})
 
-	if err := s.loadProviders(tx, config.Providers); err != nil {
-		return fmt.Errorf("load providers: %w", err)
+	if err := s.loadProviders(tx, plan, config.Providers); err != nil {
+		return nil, fmt.Errorf("load providers: %w", err)
 	}
 
 	// extract users from grants and add them to users
@@ -646,22 +706,29 @@ func (s Server) loadConfig(config Config) error {
 		}
 	}
 
-	if err := s.loadUsers(tx, config.Users); err != nil {
-		return fmt.Errorf("load users: %w", err)
+	if err := s.loadUsers(tx, plan, config.Users); err != nil {
+		return nil, fmt.Errorf("load users: %w", err)
 	}
 
-	if err := s.loadGrants(tx, config.Grants); err != nil {
-		return fmt.Errorf("load grants: %w", err)
+	if err := s.loadGrants(tx, plan, config.Grants); err != nil {
+		return nil, fmt.Errorf("load grants: %w", err)
 	}
 
-	return tx.Commit()
+	if !commit {
+		return plan.changes, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.db.DefaultOrg.Domain = org.Domain
+	return plan.changes, nil
 }
 
-func (s Server) loadProviders(db data.WriteTxn, providers []Provider) error {
+func (s Server) loadProviders(db data.WriteTxn, plan *configPlan, providers []Provider) error {
 	keep := []uid.ID{}
 
 	for _, p := range providers {
-		provider, err := s.loadProvider(db, p)
+		provider, err := s.loadProvider(db, plan, p)
 		if err != nil {
 			return err
 		}
@@ -670,6 +737,17 @@ func (s Server) loadProviders(db data.WriteTxn, providers []Provider) error {
 	}
 
 	// remove any provider previously defined by config
+	removed, err := data.ListProviders(db, data.ListProvidersOptions{
+		CreatedBy: models.CreatedBySystem,
+		NotIDs:    keep,
+	})
+	if err != nil {
+		return err
+	}
+	for _, provider := range removed {
+		plan.add(ConfigChangeDelete, "provider", provider.Name)
+	}
+
 	if err := data.DeleteProviders(db, data.DeleteProvidersOptions{
 		CreatedBy: models.CreatedBySystem,
 		NotIDs:    keep,
@@ -680,12 +758,16 @@ func (s Server) loadProviders(db data.WriteTxn, providers []Provider) error {
 	return nil
 }
 
-func (s Server) loadProvider(db data.WriteTxn, input Provider) (*models.Provider, error) {
+func (s Server) loadProvider(db data.WriteTxn, plan *configPlan, input Provider) (*models.Provider, error) {
 	// provider kind is an optional field
 	kind, err := models.ParseProviderKind(input.Kind)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse provider in config load: %w", err)
 	}
+	switch kind {
+	case models.ProviderKindSAML, models.ProviderKindLDAP:
+		return nil, fmt.Errorf("%v provider %v can not be loaded from config, create it with the API instead", kind, input.Name)
+	}
 
 	clientSecret, err := secrets.GetSecret(input.ClientSecret, s.secrets)
 	if err != nil {
@@ -743,14 +825,36 @@ func (s Server) loadProvider(db data.WriteTxn, input Provider) (*models.Provider
 			return nil, err
 		}
 
+		plan.add(ConfigChangeCreate, "provider", provider.Name)
 		return provider, nil
 	}
 
 	// provider already exists, update it
+	changed := provider.URL != input.URL ||
+		provider.ClientID != input.ClientID ||
+		string(provider.ClientSecret) != clientSecret ||
+		provider.Kind != kind ||
+		string(provider.PrivateKey) != input.PrivateKey ||
+		provider.ClientEmail != input.ClientEmail ||
+		provider.DomainAdminEmail != input.DomainAdminEmail
+
 	provider.URL = input.URL
 	provider.ClientID = input.ClientID
 	provider.ClientSecret = models.EncryptedAtRest(clientSecret)
 	provider.Kind = kind
+	provider.PrivateKey = models.EncryptedAtRest(input.PrivateKey)
+	provider.ClientEmail = input.ClientEmail
+	provider.DomainAdminEmail = input.DomainAdminEmail
+
+	switch {
+	case plan.reconcile && provider.CreatedBy != models.CreatedBySystem:
+		provider.CreatedBy = models.CreatedBySystem
+		plan.add(ConfigChangeAdopt, "provider", provider.Name)
+	case changed:
+		plan.add(ConfigChangeUpdate, "provider", provider.Name)
+	default:
+		return provider, nil
+	}
 
 	if err := data.UpdateProvider(db, provider); err != nil {
 		return nil, err
@@ -759,11 +863,11 @@ func (s Server) loadProvider(db data.WriteTxn, input Provider) (*models.Provider
 	return provider, nil
 }
 
-func (s Server) loadGrants(db data.WriteTxn, grants []Grant) error {
+func (s Server) loadGrants(db data.WriteTxn, plan *configPlan, grants []Grant) error {
 	keep := make([]uid.ID, 0, len(grants))
 
 	for _, g := range grants {
-		grant, err := s.loadGrant(db, g)
+		grant, err := s.loadGrant(db, plan, g)
 		if err != nil {
 			return err
 		}
@@ -772,6 +876,17 @@ func (s Server) loadGrants(db data.WriteTxn, grants []Grant) error {
 	}
 
 	// remove any grant previously defined by config
+	removed, err := data.ListGrants(db, data.ListGrantsOptions{
+		ByCreatedBy: models.CreatedBySystem,
+		NotIDs:      keep,
+	})
+	if err != nil {
+		return err
+	}
+	for _, grant := range removed {
+		plan.add(ConfigChangeDelete, "grant", configGrantName(db, grant))
+	}
+
 	if err := data.DeleteGrants(db, data.DeleteGrantsOptions{
 		NotIDs:      keep,
 		ByCreatedBy: models.CreatedBySystem,
@@ -782,8 +897,9 @@ func (s Server) loadGrants(db data.WriteTxn, grants []Grant) error {
 	return nil
 }
 
-func (Server) loadGrant(db data.WriteTxn, input Grant) (*models.Grant, error) {
+func (Server) loadGrant(db data.WriteTxn, plan *configPlan, input Grant) (*models.Grant, error) {
 	var id uid.PolymorphicID
+	var subjectName string
 
 	switch {
 	case input.User != "":
@@ -793,6 +909,7 @@ func (Server) loadGrant(db data.WriteTxn, input Grant) (*models.Grant, error) {
 		}
 
 		id = uid.NewIdentityPolymorphicID(user.ID)
+		subjectName = user.Name
 
 	case input.Group != "":
 		group, err := data.GetGroup(db, data.GetGroupOptions{ByName: input.Group})
@@ -812,9 +929,11 @@ func (Server) loadGrant(db data.WriteTxn, input Grant) (*models.Grant, error) {
 			if err := data.CreateGroup(db, group); err != nil {
 				return nil, err
 			}
+			plan.add(ConfigChangeCreate, "group", group.Name)
 		}
 
 		id = uid.NewGroupPolymorphicID(group.ID)
+		subjectName = group.Name
 
 	default:
 		return nil, errors.New("invalid grant: missing identity")
@@ -824,6 +943,8 @@ func (Server) loadGrant(db data.WriteTxn, input Grant) (*models.Grant, error) {
 		input.Role = models.BasePermissionConnect
 	}
 
+	name := grantName(subjectName, input.Role, input.Resource)
+
 	grant, err := data.GetGrant(db, data.GetGrantOptions{
 		BySubject:   id,
 		ByResource:  input.Resource,
@@ -844,16 +965,50 @@ func (Server) loadGrant(db data.WriteTxn, input Grant) (*models.Grant, error) {
 		if err := data.CreateGrant(db, grant); err != nil {
 			return nil, err
 		}
+
+		plan.add(ConfigChangeCreate, "grant", name)
+		return grant, nil
+	}
+
+	if plan.reconcile && grant.CreatedBy != models.CreatedBySystem {
+		grant.CreatedBy = models.CreatedBySystem
+		if err := data.UpdateGrant(db, grant); err != nil {
+			return nil, err
+		}
+		plan.add(ConfigChangeAdopt, "grant", name)
 	}
 
 	return grant, nil
 }
 
-func (s Server) loadUsers(db data.WriteTxn, users []User) error {
+func grantName(subject, privilege, resource string) string {
+	return fmt.Sprintf("%s %s %s", subject, privilege, resource)
+}
+
+// configGrantName returns the name of grant used to describe config changes.
+// The subject ID is used when the name of the subject can not be found.
+func configGrantName(db data.ReadTxn, grant models.Grant) string {
+	subject := grant.Subject.String()
+	id, err := grant.Subject.ID()
+	switch {
+	case err != nil:
+	case grant.Subject.IsIdentity():
+		if user, err := data.GetIdentity(db, data.GetIdentityOptions{ByID: id}); err == nil {
+			subject = user.Name
+		}
+	case grant.Subject.IsGroup():
+		if group, err := data.GetGroup(db, data.GetGroupOptions{ByID: id}); err == nil {
+			subject = group.Name
+		}
+	}
+	return grantName(subject, grant.Privilege, grant.Resource)
+}
+
+func (s Server) loadUsers(db data.WriteTxn, plan *configPlan, users []User) error {
 	keep := make([]uid.ID, 0, len(users)+1)
 
 	for _, i := range users {
-		user, err := s.loadUser(db, i)
+		user, err := s.loadUser(db, plan, i)
 		if err != nil {
 			return err
 		}
@@ -862,6 +1017,17 @@ func (s Server) loadUsers(db data.WriteTxn, users []User) error {
 	}
 
 	// remove any users previously defined by config
+	removed, err := data.ListIdentities(db, data.ListIdentityOptions{
+		ByNotIDs:  keep,
+		CreatedBy: models.CreatedBySystem,
+	})
+	if err != nil {
+		return err
+	}
+	for _, user := range removed {
+		plan.add(ConfigChangeDelete, "user", user.Name)
+	}
+
 	opts := data.DeleteIdentitiesOptions{
 		ByProviderID: data.InfraProvider(db).ID,
 		ByNotIDs:     keep,
@@ -874,13 +1040,10 @@ func (s Server) loadUsers(db data.WriteTxn, users []User) error {
 	return nil
 }
 
-func (s Server) loadUser(db data.WriteTxn, input User) (*models.Identity, error) {
+func (s Server) loadUser(db data.WriteTxn, plan *configPlan, input User) (*models.Identity, error) {
 	identity, err := data.GetIdentity(db, data.GetIdentityOptions{ByName: input.Name})
-	if err != nil {
-		if !errors.Is(err, internal.ErrNotFound) {
-			return nil, err
-		}
-
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
 		if input.Name != models.InternalInfraConnectorIdentityName {
 			_, err := mail.ParseAddress(input.Name)
 			if err != nil {
@@ -902,6 +1065,17 @@ func (s Server) loadUser(db data.WriteTxn, input User) (*models.Identity, error)
 			return nil, err
 		}
 
+		plan.add(ConfigChangeCreate, "user", identity.Name)
+
+	case err != nil:
+		return nil, err
+
+	case plan.reconcile && identity.CreatedBy != models.CreatedBySystem:
+		identity.CreatedBy = models.CreatedBySystem
+		if err := data.UpdateIdentity(db, identity); err != nil {
+			return nil, err
+		}
+		plan.add(ConfigChangeAdopt, "user", identity.Name)
 	}
 
 	if err := s.loadCredential(db, identity, input.Password); err != nil {
@@ -1016,3 +1190,44 @@ func (s Server) loadAccessKey(db data.WriteTxn, identity *models.Identity, key s
 
 	return nil
 }
+
+// checkConfigManaged returns an error if the server is reconciling its config,
+// and the resource created by createdBy is managed by the config. Changes to
+// resources managed by the config must be made by changing the config.
+func (a *API) checkConfigManaged(c *gin.Context, kind, name string, createdBy uid.ID) error {
+	if createdBy != models.CreatedBySystem || !a.configManagesOrg(c) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s %q is managed by the server config, update the config to change it",
+		internal.ErrBadRequest, kind, name)
+}
+
+// configManagesOrg returns true if the server is reconciling its config, and
+// the organization of the request is managed by the config.
+func (a *API) configManagesOrg(c *gin.Context) bool {
+	if !a.server.options.Reconcile {
+		return false
+	}
+	// only the default organization is managed by the config
+	return getRequestContext(c).DBTxn.OrganizationID() == a.server.db.DefaultOrg.ID
+}
+
+// checkConfigManagedGrant is checkConfigManaged for a grant that may not
+// exist, identified by its subject, privilege, and resource.
+func (a *API) checkConfigManagedGrant(c *gin.Context, grant *models.Grant) error {
+	if !a.server.options.Reconcile {
+		return nil
+	}
+	existing, err := data.GetGrant(getRequestContext(c).DBTxn, data.GetGrantOptions{
+		BySubject:   grant.Subject,
+		ByPrivilege: grant.Privilege,
+		ByResource:  grant.Resource,
+	})
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+	return a.checkConfigManaged(c, "grant", existing.ID.String(), existing.CreatedBy)
+}
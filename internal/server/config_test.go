@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -974,3 +976,130 @@ func getTestDefaultOrgUserDetails(t *testing.T, server *Server, name string) (*m
 
 	return user, credential, accessKey
 }
+
+func TestLoadConfig_Reconcile(t *testing.T) {
+	s := setupServer(t, withAdminUser)
+	tx := txnForTestCase(t, s.db, s.db.DefaultOrg.ID)
+
+	// created outside of the config
+	alice := &models.Identity{Name: "alice@example.com"}
+	assert.NilError(t, data.CreateIdentity(tx, alice))
+	aliceGrant := &models.Grant{
+		Subject:   uid.NewIdentityPolymorphicID(alice.ID),
+		Privilege: "view",
+		Resource:  "production",
+		CreatedBy: uid.ID(44),
+	}
+	assert.NilError(t, data.CreateGrant(tx, aliceGrant))
+	assert.NilError(t, tx.Commit())
+
+	config := s.options.Config
+	config.Reconcile = true
+	config.Grants = append(config.Grants, Grant{
+		User:     "alice@example.com",
+		Role:     "view",
+		Resource: "production",
+	}, Grant{
+		User:     "bob@example.com",
+		Role:     "edit",
+		Resource: "staging",
+	})
+
+	t.Run("plan does not apply changes", func(t *testing.T) {
+		changes, err := s.planConfig(config)
+		assert.NilError(t, err)
+
+		expected := []ConfigChange{
+			{Action: ConfigChangeAdopt, Kind: "user", Name: "alice@example.com"},
+			{Action: ConfigChangeCreate, Kind: "user", Name: "bob@example.com"},
+			{Action: ConfigChangeAdopt, Kind: "grant", Name: "alice@example.com view production"},
+			{Action: ConfigChangeCreate, Kind: "grant", Name: "bob@example.com edit staging"},
+		}
+		assert.DeepEqual(t, changes, expected)
+
+		_, err = data.GetIdentity(s.db, data.GetIdentityOptions{ByName: "bob@example.com"})
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("load adopts existing resources", func(t *testing.T) {
+		assert.NilError(t, s.loadConfig(config))
+
+		user, err := data.GetIdentity(s.db, data.GetIdentityOptions{ByID: alice.ID})
+		assert.NilError(t, err)
+		assert.Equal(t, user.CreatedBy, uid.ID(models.CreatedBySystem))
+
+		grant, err := data.GetGrant(s.db, data.GetGrantOptions{ByID: aliceGrant.ID})
+		assert.NilError(t, err)
+		assert.Equal(t, grant.CreatedBy, uid.ID(models.CreatedBySystem))
+
+		// loading the same config again makes no changes
+		changes, err := s.planConfig(config)
+		assert.NilError(t, err)
+		assert.Equal(t, len(changes), 0)
+	})
+
+	t.Run("adopted resources are removed from the config", func(t *testing.T) {
+		config.Grants = config.Grants[:len(config.Grants)-2]
+
+		changes, err := s.planConfig(config)
+		assert.NilError(t, err)
+
+		expected := []ConfigChange{
+			{Action: ConfigChangeDelete, Kind: "user", Name: "alice@example.com"},
+			{Action: ConfigChangeDelete, Kind: "user", Name: "bob@example.com"},
+		}
+		assert.DeepEqual(t, changes, expected)
+	})
+}
+
+func TestAPI_ReconcileRejectsChangesToConfig(t *testing.T) {
+	s := setupServer(t, withAdminUser, func(_ *testing.T, opts *Options) {
+		opts.Reconcile = true
+		opts.Users = append(opts.Users, User{Name: "config@example.com"})
+	})
+	routes := s.GenerateRoutes()
+
+	tx := txnForTestCase(t, s.db, s.db.DefaultOrg.ID)
+	admin, err := data.GetIdentity(tx, data.GetIdentityOptions{ByName: "admin@example.com"})
+	assert.NilError(t, err)
+	grant, err := data.GetGrant(tx, data.GetGrantOptions{
+		BySubject:   uid.NewIdentityPolymorphicID(admin.ID),
+		ByPrivilege: "admin",
+		ByResource:  "infra",
+	})
+	assert.NilError(t, err)
+	configUser, err := data.GetIdentity(tx, data.GetIdentityOptions{ByName: "config@example.com"})
+	assert.NilError(t, err)
+
+	other := &models.Grant{
+		Subject:   uid.NewIdentityPolymorphicID(admin.ID),
+		Privilege: "view",
+		Resource:  "production",
+		CreatedBy: admin.ID,
+	}
+	assert.NilError(t, data.CreateGrant(tx, other))
+	assert.NilError(t, tx.Commit())
+
+	do := func(t *testing.T, method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(s))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do(t, http.MethodDelete, "/api/grants/"+grant.ID.String())
+	assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	assert.Assert(t, is.Contains(resp.Body.String(), "is managed by the server config"))
+
+	resp = do(t, http.MethodDelete, "/api/users/"+configUser.ID.String())
+	assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	assert.Assert(t, is.Contains(resp.Body.String(), `user \"config@example.com\" is managed by the server config`))
+
+	// grants that are not managed by the config can be deleted
+	resp = do(t, http.MethodDelete, "/api/grants/"+other.ID.String())
+	assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+}
@@ -141,7 +141,7 @@ func ListAccessKeys(tx ReadTxn, opts ListAccessKeyOptions) ([]models.AccessKey,
 	query := querybuilder.New("SELECT")
 	query.B(columnsForSelect(table))
 	query.B(", identities.name")
-	if opts.Pagination != nil {
+	if opts.Pagination.countRows() {
 		query.B(", count(*) OVER()")
 	}
 	query.B("FROM access_keys INNER JOIN identities")
@@ -161,7 +161,14 @@ func ListAccessKeys(tx ReadTxn, opts ListAccessKeyOptions) ([]models.AccessKey,
 	if opts.ByName != "" {
 		query.B("AND access_keys.name = ?", opts.ByName)
 	}
-	query.B("ORDER BY access_keys.name ASC")
+	if opts.Pagination.usesCursor() {
+		if opts.Pagination.After != 0 {
+			query.B("AND access_keys.id > ?", opts.Pagination.After)
+		}
+		query.B("ORDER BY access_keys.id ASC")
+	} else {
+		query.B("ORDER BY access_keys.name ASC")
+	}
 	if opts.Pagination != nil {
 		opts.Pagination.PaginateQuery(query)
 	}
@@ -170,13 +177,20 @@ func ListAccessKeys(tx ReadTxn, opts ListAccessKeyOptions) ([]models.AccessKey,
 	if err != nil {
 		return nil, err
 	}
-	return scanRows(rows, func(key *models.AccessKey) []any {
+	result, err := scanRows(rows, func(key *models.AccessKey) []any {
 		fields := append((*accessKeyTable)(key).ScanFields(), &key.IssuedForName)
-		if opts.Pagination != nil {
+		if opts.Pagination.countRows() {
 			fields = append(fields, &opts.Pagination.TotalCount)
 		}
 		return fields
 	})
+	if err != nil {
+		return nil, err
+	}
+	setNextCursor(opts.Pagination, result, func(key models.AccessKey) uid.ID {
+		return key.ID
+	})
+	return result, nil
 }
 
 // GetAccessKeyByKeyID using the keyID. Note that the keyID is globally unique,
@@ -243,7 +257,8 @@ type DeleteAccessKeysOptions struct {
 	// ByIssuedForID instructs DeleteAccessKeys to delete keys issued for this user.
 	ByIssuedForID uid.ID
 	// ByProviderID instructs DeleteAccessKeys to delete keys issued by this
-	// provider.
+	// provider. When used with ByIssuedForID only the keys for that user issued
+	// by this provider are deleted.
 	ByProviderID uid.ID
 }
 
@@ -253,6 +268,8 @@ func DeleteAccessKeys(tx WriteTxn, opts DeleteAccessKeysOptions) error {
 	switch {
 	case opts.ByID != 0:
 		query.B("id = ?", opts.ByID)
+	case opts.ByIssuedForID != 0 && opts.ByProviderID != 0:
+		query.B("issued_for = ? AND provider_id = ?", opts.ByIssuedForID, opts.ByProviderID)
 	case opts.ByIssuedForID != 0:
 		query.B("issued_for = ?", opts.ByIssuedForID)
 	case opts.ByProviderID != 0:
@@ -0,0 +1,225 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+type accessReviewsTable models.AccessReview
+
+func (a accessReviewsTable) Table() string {
+	return "access_reviews"
+}
+
+func (a accessReviewsTable) Columns() []string {
+	return []string{"closed_at", "closed_by", "created_at", "created_by", "deleted_at", "destinations", "groups", "id", "name", "organization_id", "status", "updated_at"}
+}
+
+func (a accessReviewsTable) Values() []any {
+	return []any{a.ClosedAt, a.ClosedBy, a.CreatedAt, a.CreatedBy, a.DeletedAt, a.Destinations, a.Groups, a.ID, a.Name, a.OrganizationID, a.Status, a.UpdatedAt}
+}
+
+func (a *accessReviewsTable) ScanFields() []any {
+	return []any{&a.ClosedAt, &a.ClosedBy, &a.CreatedAt, &a.CreatedBy, &a.DeletedAt, &a.Destinations, &a.Groups, &a.ID, &a.Name, &a.OrganizationID, &a.Status, &a.UpdatedAt}
+}
+
+type accessReviewItemsTable models.AccessReviewItem
+
+func (a accessReviewItemsTable) Table() string {
+	return "access_review_items"
+}
+
+func (a accessReviewItemsTable) Columns() []string {
+	return []string{"access_review_id", "created_at", "decided_at", "decided_by", "decision", "deleted_at", "grant_id", "id", "organization_id", "privilege", "reason", "resource", "reviewer_id", "subject", "updated_at"}
+}
+
+func (a accessReviewItemsTable) Values() []any {
+	return []any{a.AccessReviewID, a.CreatedAt, a.DecidedAt, a.DecidedBy, a.Decision, a.DeletedAt, a.GrantID, a.ID, a.OrganizationID, a.Privilege, a.Reason, a.Resource, a.ReviewerID, a.Subject, a.UpdatedAt}
+}
+
+func (a *accessReviewItemsTable) ScanFields() []any {
+	return []any{&a.AccessReviewID, &a.CreatedAt, &a.DecidedAt, &a.DecidedBy, &a.Decision, &a.DeletedAt, &a.GrantID, &a.ID, &a.OrganizationID, &a.Privilege, &a.Reason, &a.Resource, &a.ReviewerID, &a.Subject, &a.UpdatedAt}
+}
+
+func CreateAccessReview(tx WriteTxn, review *models.AccessReview) error {
+	switch {
+	case review.Name == "":
+		return fmt.Errorf("name is required")
+	case review.CreatedBy == 0:
+		return fmt.Errorf("createdBy is required")
+	}
+	if review.Status == "" {
+		review.Status = models.AccessReviewStatusOpen
+	}
+	return insert(tx, (*accessReviewsTable)(review))
+}
+
+type GetAccessReviewOptions struct {
+	// ByID instructs GetAccessReview to return the access review with this ID.
+	ByID uid.ID
+}
+
+func GetAccessReview(tx ReadTxn, opts GetAccessReviewOptions) (*models.AccessReview, error) {
+	if opts.ByID == 0 {
+		return nil, fmt.Errorf("GetAccessReview requires an ID")
+	}
+
+	table := &accessReviewsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM access_reviews")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("AND id = ?", opts.ByID)
+
+	err := tx.QueryRow(query.String(), query.Args...).Scan(table.ScanFields()...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return (*models.AccessReview)(table), nil
+}
+
+type ListAccessReviewsOptions struct {
+	// ByStatus instructs ListAccessReviews to return only the access reviews
+	// with this status.
+	ByStatus models.AccessReviewStatus
+
+	Pagination *Pagination
+}
+
+func ListAccessReviews(tx ReadTxn, opts ListAccessReviewsOptions) ([]models.AccessReview, error) {
+	table := &accessReviewsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	if opts.Pagination != nil {
+		query.B(", count(*) OVER()")
+	}
+	query.B("FROM access_reviews")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+
+	if opts.ByStatus != "" {
+		query.B("AND status = ?", opts.ByStatus)
+	}
+
+	query.B("ORDER BY id DESC")
+	if opts.Pagination != nil {
+		opts.Pagination.PaginateQuery(query)
+	}
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(review *models.AccessReview) []any {
+		fields := (*accessReviewsTable)(review).ScanFields()
+		if opts.Pagination != nil {
+			fields = append(fields, &opts.Pagination.TotalCount)
+		}
+		return fields
+	})
+}
+
+func UpdateAccessReview(tx WriteTxn, review *models.AccessReview) error {
+	return update(tx, (*accessReviewsTable)(review))
+}
+
+func CreateAccessReviewItem(tx WriteTxn, item *models.AccessReviewItem) error {
+	switch {
+	case item.AccessReviewID == 0:
+		return fmt.Errorf("accessReviewID is required")
+	case item.GrantID == 0:
+		return fmt.Errorf("grantID is required")
+	case item.ReviewerID == 0:
+		return fmt.Errorf("reviewerID is required")
+	}
+	if item.Decision == "" {
+		item.Decision = models.AccessReviewDecisionPending
+	}
+	return insert(tx, (*accessReviewItemsTable)(item))
+}
+
+type GetAccessReviewItemOptions struct {
+	// ByID instructs GetAccessReviewItem to return the review item with this ID.
+	ByID uid.ID
+}
+
+func GetAccessReviewItem(tx ReadTxn, opts GetAccessReviewItemOptions) (*models.AccessReviewItem, error) {
+	if opts.ByID == 0 {
+		return nil, fmt.Errorf("GetAccessReviewItem requires an ID")
+	}
+
+	table := &accessReviewItemsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM access_review_items")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("AND id = ?", opts.ByID)
+
+	err := tx.QueryRow(query.String(), query.Args...).Scan(table.ScanFields()...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return (*models.AccessReviewItem)(table), nil
+}
+
+type ListAccessReviewItemsOptions struct {
+	// ByAccessReviewID instructs ListAccessReviewItems to return only the
+	// items of this access review.
+	ByAccessReviewID uid.ID
+	// ByReviewerID instructs ListAccessReviewItems to return only the items
+	// assigned to this reviewer.
+	ByReviewerID uid.ID
+	// ByDecision instructs ListAccessReviewItems to return only the items
+	// with this decision.
+	ByDecision models.AccessReviewDecision
+
+	Pagination *Pagination
+}
+
+func ListAccessReviewItems(tx ReadTxn, opts ListAccessReviewItemsOptions) ([]models.AccessReviewItem, error) {
+	table := &accessReviewItemsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	if opts.Pagination != nil {
+		query.B(", count(*) OVER()")
+	}
+	query.B("FROM access_review_items")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+
+	if opts.ByAccessReviewID != 0 {
+		query.B("AND access_review_id = ?", opts.ByAccessReviewID)
+	}
+	if opts.ByReviewerID != 0 {
+		query.B("AND reviewer_id = ?", opts.ByReviewerID)
+	}
+	if opts.ByDecision != "" {
+		query.B("AND decision = ?", opts.ByDecision)
+	}
+
+	query.B("ORDER BY id ASC")
+	if opts.Pagination != nil {
+		opts.Pagination.PaginateQuery(query)
+	}
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(item *models.AccessReviewItem) []any {
+		fields := (*accessReviewItemsTable)(item).ScanFields()
+		if opts.Pagination != nil {
+			fields = append(fields, &opts.Pagination.TotalCount)
+		}
+		return fields
+	})
+}
+
+func UpdateAccessReviewItem(tx WriteTxn, item *models.AccessReviewItem) error {
+	return update(tx, (*accessReviewItemsTable)(item))
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+type auditEventsTable models.AuditEvent
+
+func (a auditEventsTable) Table() string {
+	return "audit_events"
+}
+
+func (a auditEventsTable) Columns() []string {
+	return []string{"action", "actor_id", "actor_name", "after_summary", "before_summary", "created_at", "deleted_at", "id", "organization_id", "request_id", "target_id", "target_type", "updated_at"}
+}
+
+func (a auditEventsTable) Values() []any {
+	return []any{a.Action, a.ActorID, a.ActorName, a.AfterSummary, a.BeforeSummary, a.CreatedAt, a.DeletedAt, a.ID, a.OrganizationID, a.RequestID, a.TargetID, a.TargetType, a.UpdatedAt}
+}
+
+func (a *auditEventsTable) ScanFields() []any {
+	return []any{&a.Action, &a.ActorID, &a.ActorName, &a.AfterSummary, &a.BeforeSummary, &a.CreatedAt, &a.DeletedAt, &a.ID, &a.OrganizationID, &a.RequestID, &a.TargetID, &a.TargetType, &a.UpdatedAt}
+}
+
+func CreateAuditEvent(tx WriteTxn, event *models.AuditEvent) error {
+	switch {
+	case event.ActorID == 0:
+		return fmt.Errorf("actorID is required")
+	case event.Action == "":
+		return fmt.Errorf("action is required")
+	}
+	return insert(tx, (*auditEventsTable)(event))
+}
+
+type ListAuditEventsOptions struct {
+	// ByActorID instructs ListAuditEvents to return only the events for
+	// actions performed by this user.
+	ByActorID uid.ID
+	// ByAction instructs ListAuditEvents to return only the events with this
+	// action.
+	ByAction string
+	// ByTargetType instructs ListAuditEvents to return only the events that
+	// changed this kind of resource.
+	ByTargetType string
+	// ByTargetID instructs ListAuditEvents to return only the events that
+	// changed the resource with this ID.
+	ByTargetID uid.ID
+
+	Pagination *Pagination
+}
+
+// ListAuditEvents returns audit events ordered from newest to oldest.
+func ListAuditEvents(tx ReadTxn, opts ListAuditEventsOptions) ([]models.AuditEvent, error) {
+	table := &auditEventsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	if opts.Pagination != nil {
+		query.B(", count(*) OVER()")
+	}
+	query.B("FROM audit_events")
+	query.B("WHERE organization_id = ?", tx.OrganizationID())
+
+	if opts.ByActorID != 0 {
+		query.B("AND actor_id = ?", opts.ByActorID)
+	}
+	if opts.ByAction != "" {
+		query.B("AND action = ?", opts.ByAction)
+	}
+	if opts.ByTargetType != "" {
+		query.B("AND target_type = ?", opts.ByTargetType)
+	}
+	if opts.ByTargetID != 0 {
+		query.B("AND target_id = ?", opts.ByTargetID)
+	}
+
+	query.B("ORDER BY id DESC")
+	if opts.Pagination != nil {
+		opts.Pagination.PaginateQuery(query)
+	}
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(event *models.AuditEvent) []any {
+		fields := (*auditEventsTable)(event).ScanFields()
+		if opts.Pagination != nil {
+			fields = append(fields, &opts.Pagination.TotalCount)
+		}
+		return fields
+	})
+}
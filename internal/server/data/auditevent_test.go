@@ -0,0 +1,94 @@
+package data
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestListAuditEvents(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		otherOrg := &models.Organization{Name: "other", Domain: "other.example.org"}
+		assert.NilError(t, CreateOrganization(db, otherOrg))
+
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+		createGrant := &models.AuditEvent{
+			ActorID:    uid.ID(100),
+			ActorName:  "admin@example.com",
+			Action:     "CreateGrant",
+			TargetType: "grants",
+			TargetID:   uid.ID(500),
+		}
+		deleteGrant := &models.AuditEvent{
+			ActorID:    uid.ID(101),
+			ActorName:  "other@example.com",
+			Action:     "DeleteGrant",
+			TargetType: "grants",
+			TargetID:   uid.ID(500),
+		}
+		createUser := &models.AuditEvent{
+			ActorID:    uid.ID(100),
+			ActorName:  "admin@example.com",
+			Action:     "CreateUser",
+			TargetType: "users",
+			TargetID:   uid.ID(501),
+		}
+		for _, event := range []*models.AuditEvent{createGrant, deleteGrant, createUser} {
+			assert.NilError(t, CreateAuditEvent(tx, event))
+		}
+
+		otherOrgEvent := &models.AuditEvent{ActorID: uid.ID(100), Action: "CreateGrant"}
+		assert.NilError(t, CreateAuditEvent(tx.WithOrgID(otherOrg.ID), otherOrgEvent))
+
+		t.Run("all", func(t *testing.T) {
+			actual, err := ListAuditEvents(tx, ListAuditEventsOptions{})
+			assert.NilError(t, err)
+			expected := []models.AuditEvent{
+				{Model: models.Model{ID: createUser.ID}},
+				{Model: models.Model{ID: deleteGrant.ID}},
+				{Model: models.Model{ID: createGrant.ID}},
+			}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+		})
+		t.Run("by actor", func(t *testing.T) {
+			actual, err := ListAuditEvents(tx, ListAuditEventsOptions{ByActorID: uid.ID(100)})
+			assert.NilError(t, err)
+			expected := []models.AuditEvent{
+				{Model: models.Model{ID: createUser.ID}},
+				{Model: models.Model{ID: createGrant.ID}},
+			}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+		})
+		t.Run("by action", func(t *testing.T) {
+			actual, err := ListAuditEvents(tx, ListAuditEventsOptions{ByAction: "DeleteGrant"})
+			assert.NilError(t, err)
+			expected := []models.AuditEvent{
+				{Model: models.Model{ID: deleteGrant.ID}},
+			}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+		})
+		t.Run("by target", func(t *testing.T) {
+			actual, err := ListAuditEvents(tx, ListAuditEventsOptions{
+				ByTargetType: "grants",
+				ByTargetID:   uid.ID(500),
+			})
+			assert.NilError(t, err)
+			expected := []models.AuditEvent{
+				{Model: models.Model{ID: deleteGrant.ID}},
+				{Model: models.Model{ID: createGrant.ID}},
+			}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+		})
+		t.Run("with pagination", func(t *testing.T) {
+			p := &Pagination{Page: 1, Limit: 2}
+			actual, err := ListAuditEvents(tx, ListAuditEventsOptions{Pagination: p})
+			assert.NilError(t, err)
+			assert.Equal(t, len(actual), 2)
+			assert.Equal(t, p.TotalCount, 3)
+		})
+	})
+}
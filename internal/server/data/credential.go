@@ -16,15 +16,15 @@ func (credentialsTable) Table() string {
 }
 
 func (c credentialsTable) Columns() []string {
-	return []string{"created_at", "deleted_at", "id", "identity_id", "one_time_password", "organization_id", "password_hash", "updated_at"}
+	return []string{"created_at", "deleted_at", "id", "identity_id", "one_time_password", "organization_id", "password_hash", "recovery_codes", "totp_enabled", "totp_last_counter", "totp_secret", "updated_at"}
 }
 
 func (c credentialsTable) Values() []any {
-	return []any{c.CreatedAt, c.DeletedAt, c.ID, c.IdentityID, c.OneTimePassword, c.OrganizationID, c.PasswordHash, c.UpdatedAt}
+	return []any{c.CreatedAt, c.DeletedAt, c.ID, c.IdentityID, c.OneTimePassword, c.OrganizationID, c.PasswordHash, c.RecoveryCodes, c.TOTPEnabled, c.TOTPLastCounter, c.TOTPSecret, c.UpdatedAt}
 }
 
 func (c *credentialsTable) ScanFields() []any {
-	return []any{&c.CreatedAt, &c.DeletedAt, &c.ID, &c.IdentityID, &c.OneTimePassword, &c.OrganizationID, &c.PasswordHash, &c.UpdatedAt}
+	return []any{&c.CreatedAt, &c.DeletedAt, &c.ID, &c.IdentityID, &c.OneTimePassword, &c.OrganizationID, &c.PasswordHash, &c.RecoveryCodes, &c.TOTPEnabled, &c.TOTPLastCounter, &c.TOTPSecret, &c.UpdatedAt}
 }
 
 func validateCredential(c *models.Credential) error {
@@ -15,6 +15,8 @@ import (
 	"github.com/jackc/pgerrcode"
 	"github.com/rs/zerolog"
 
+	"github.com/infrahq/secrets"
+
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data/migrator"
@@ -71,9 +73,86 @@ func NewDB(dbOpts NewDBOptions) (*DB, error) {
 		return nil, fmt.Errorf("initialize database: %w", err)
 	}
 
+	if dbOpts.EncryptionKeyProvider != nil {
+		// load versions of the key that were created by another process
+		// after this process loaded the key.
+		models.LoadDecryptionKey = func(keyID int32) (*secrets.SymmetricKey, error) {
+			return loadDecryptionKey(dataDB, dbOpts.EncryptionKeyProvider, dbOpts.RootKeyID, keyID)
+		}
+	}
+
 	return dataDB, nil
 }
 
+// ErrMigrationsPending is returned by OpenDB when the database schema has
+// migrations that have not been applied.
+var ErrMigrationsPending = errors.New("the database schema is out of date")
+
+// OpenDB connects to an existing database without changing it. Unlike NewDB,
+// OpenDB does not run migrations, create the database key, or create the
+// default organization. OpenDB returns an error if the database schema has
+// pending migrations, because the queries used by the server may not work
+// with an older schema.
+func OpenDB(dbOpts NewDBOptions) (*DB, error) {
+	db, err := newRawDB(dbOpts)
+	if err != nil {
+		return nil, fmt.Errorf("db conn: %w", err)
+	}
+	dataDB := &DB{DB: db}
+	if err := loadExistingDB(dataDB, dbOpts); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return dataDB, nil
+}
+
+func loadExistingDB(db *DB, dbOpts NewDBOptions) error {
+	tx, err := db.Begin(context.TODO(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	m := migrator.New(tx, migrator.Options{InitSchema: initializeSchema}, migrations())
+	pending, err := m.Pending()
+	if err != nil {
+		return fmt.Errorf("check migrations: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w, %d migrations have not been applied. Start the server to apply the migrations", ErrMigrationsPending, len(pending))
+	}
+
+	if dbOpts.EncryptionKeyProvider != nil {
+		keys, err := ListEncryptionKeys(tx, dbKeyName)
+		if err != nil {
+			return fmt.Errorf("load key: %w", err)
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("the database key does not exist. Start the server to create it")
+		}
+		if err := loadDBKey(tx, dbOpts.EncryptionKeyProvider, dbOpts.RootKeyID); err != nil {
+			return fmt.Errorf("load key: %w", err)
+		}
+		models.LoadDecryptionKey = func(keyID int32) (*secrets.SymmetricKey, error) {
+			return loadDecryptionKey(db, dbOpts.EncryptionKeyProvider, dbOpts.RootKeyID, keyID)
+		}
+	}
+
+	org, err := GetOrganization(tx, GetOrganizationOptions{ByID: defaultOrganizationID})
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return fmt.Errorf("the default organization does not exist. Start the server to create it")
+	case err != nil:
+		return fmt.Errorf("failed to get default organization: %w", err)
+	}
+	db.DefaultOrg = org
+	db.DefaultOrgSettings, err = getSettingsForOrg(tx, org.ID)
+	if err != nil {
+		return fmt.Errorf("getting settings: %w", err)
+	}
+	return nil
+}
+
 // DB wraps the underlying database and provides access to the default org,
 // and settings.
 type DB struct {
@@ -172,6 +251,9 @@ type Transaction struct {
 
 	orgID     uid.ID
 	completed *atomic.Bool
+
+	actorID   uid.ID
+	actorName string
 }
 
 func (t *Transaction) OrganizationID() uid.ID {
@@ -236,6 +318,21 @@ func (t *Transaction) WithOrgID(orgID uid.ID) *Transaction {
 	return &newTxn
 }
 
+// WithActor returns a shallow copy of the Transaction that records the user
+// making changes with the transaction. The actor is included in the webhook
+// events created by the data layer. Like WithOrgID, the underlying database
+// transaction is shared with the new copy.
+func (t *Transaction) WithActor(id uid.ID, name string) *Transaction {
+	newTxn := *t
+	newTxn.actorID = id
+	newTxn.actorName = name
+	return &newTxn
+}
+
+func (t *Transaction) actor() (uid.ID, string) {
+	return t.actorID, t.actorName
+}
+
 // newRawDB creates a new database connection without running migrations.
 func newRawDB(options NewDBOptions) (*sql.DB, error) {
 	if options.DSN == "" {
@@ -367,6 +464,7 @@ func handleError(err error) error {
 				"idx_identities_verified":     "verificationToken",
 				"idx_groups_name":             "name",
 				"idx_providers_name":          "name",
+				"idx_roles_name":              "name",
 				"idx_access_keys_name":        "name",
 				"idx_destinations_unique_id":  "uniqueID",
 				"idx_access_keys_key_id":      "keyId",
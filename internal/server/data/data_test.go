@@ -10,6 +10,7 @@ import (
 
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data/migrator"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/internal/testing/database"
 	"github.com/infrahq/infra/internal/testing/patch"
@@ -108,6 +109,31 @@ func TestPaginationSelector(t *testing.T) {
 		for i, user := range actual {
 			assert.Equal(t, user.Name, alphabeticalIdentities[i])
 		}
+
+		t.Run("with cursor", func(t *testing.T) {
+			p := &Pagination{Cursor: true, Limit: 10}
+			opts := ListIdentityOptions{
+				Pagination: p,
+				ByNotName:  models.InternalInfraConnectorIdentityName,
+			}
+
+			var names []string
+			for pages := 1; ; pages++ {
+				actual, err := ListIdentities(db, opts)
+				assert.NilError(t, err)
+				assert.Equal(t, p.TotalCount, 0)
+				for _, user := range actual {
+					names = append(names, user.Name)
+				}
+				if p.Next == 0 {
+					assert.Equal(t, pages, 3)
+					break
+				}
+				assert.Equal(t, p.Next, actual[len(actual)-1].ID)
+				p.After, p.Next = p.Next, 0
+			}
+			assert.DeepEqual(t, names, alphabeticalIdentities)
+		})
 	})
 }
 
@@ -129,6 +155,47 @@ func TestNewDB(t *testing.T) {
 	})
 }
 
+func TestOpenDB(t *testing.T) {
+	patch.ModelsSymmetricKey(t)
+	logging.PatchLogger(t, zerolog.NewTestWriter(t))
+	dsn := database.PostgresDriver(t, "_data").DSN
+
+	t.Run("not migrated", func(t *testing.T) {
+		_, err := OpenDB(NewDBOptions{DSN: dsn})
+		assert.ErrorIs(t, err, ErrMigrationsPending)
+		assert.ErrorContains(t, err, "the database schema is out of date")
+
+		db, err := newRawDB(NewDBOptions{DSN: dsn})
+		assert.NilError(t, err)
+		defer db.Close()
+		// OpenDB must not create any tables
+		assert.Assert(t, !migrator.HasTable(db, "migrations"))
+	})
+
+	migrated, err := NewDB(NewDBOptions{DSN: dsn})
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		assert.NilError(t, migrated.Close())
+	})
+
+	t.Run("migrated", func(t *testing.T) {
+		db, err := OpenDB(NewDBOptions{DSN: dsn})
+		assert.NilError(t, err)
+		defer db.Close()
+		assert.DeepEqual(t, db.DefaultOrg, migrated.DefaultOrg, cmpTimeWithDBPrecision)
+		assert.Equal(t, db.DefaultOrgSettings.ID, migrated.DefaultOrgSettings.ID)
+	})
+
+	t.Run("pending migrations", func(t *testing.T) {
+		last := migrations()[len(migrations())-1]
+		_, err := migrated.Exec("DELETE FROM migrations WHERE id = ?", last.ID)
+		assert.NilError(t, err)
+
+		_, err = OpenDB(NewDBOptions{DSN: dsn})
+		assert.ErrorContains(t, err, "1 migrations have not been applied")
+	})
+}
+
 func TestDB_Begin(t *testing.T) {
 	runDBTests(t, func(t *testing.T, db *DB) {
 		t.Run("rollback", func(t *testing.T) {
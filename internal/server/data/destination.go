@@ -99,7 +99,7 @@ func ListDestinations(tx ReadTxn, opts ListDestinationsOptions) ([]models.Destin
 	table := destinationsTable{}
 	query := querybuilder.New("SELECT")
 	query.B(columnsForSelect(table))
-	if opts.Pagination != nil {
+	if opts.Pagination.countRows() {
 		query.B(", count(*) OVER()")
 	}
 	query.B("FROM destinations")
@@ -116,7 +116,14 @@ func ListDestinations(tx ReadTxn, opts ListDestinationsOptions) ([]models.Destin
 		query.B("AND kind = ?", opts.ByKind)
 	}
 
-	query.B("ORDER BY name")
+	if opts.Pagination.usesCursor() {
+		if opts.Pagination.After != 0 {
+			query.B("AND id > ?", opts.Pagination.After)
+		}
+		query.B("ORDER BY id ASC")
+	} else {
+		query.B("ORDER BY name")
+	}
 	if opts.Pagination != nil {
 		opts.Pagination.PaginateQuery(query)
 	}
@@ -125,13 +132,20 @@ func ListDestinations(tx ReadTxn, opts ListDestinationsOptions) ([]models.Destin
 	if err != nil {
 		return nil, err
 	}
-	return scanRows(rows, func(d *models.Destination) []any {
+	result, err := scanRows(rows, func(d *models.Destination) []any {
 		fields := (*destinationsTable)(d).ScanFields()
-		if opts.Pagination != nil {
+		if opts.Pagination.countRows() {
 			fields = append(fields, &opts.Pagination.TotalCount)
 		}
 		return fields
 	})
+	if err != nil {
+		return nil, err
+	}
+	setNextCursor(opts.Pagination, result, func(d models.Destination) uid.ID {
+		return d.ID
+	})
+	return result, nil
 }
 
 func DeleteDestination(tx WriteTxn, id uid.ID) error {
@@ -1,16 +1,18 @@
 package data
 
 import (
-	"errors"
+	"bytes"
+	"context"
 	"fmt"
 	mathrand "math/rand"
 
 	"github.com/infrahq/secrets"
 
-	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data/migrator"
 	"github.com/infrahq/infra/internal/server/data/querybuilder"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 type encryptionKeysTable models.EncryptionKey
@@ -45,7 +47,7 @@ func CreateEncryptionKey(tx StdlibTxn, key *models.EncryptionKey) error {
 	case key.Algorithm == "":
 		return fmt.Errorf("an algorithm is required for EncryptionKey")
 	}
-	if key.KeyID == 0 {
+	for key.KeyID == 0 {
 		// not a security issue; just an identifier
 		key.KeyID = mathrand.Int31() // nolint:gosec
 	}
@@ -63,6 +65,7 @@ func CreateEncryptionKey(tx StdlibTxn, key *models.EncryptionKey) error {
 	return handleError(err)
 }
 
+// GetEncryptionKeyByName returns the most recently created key with name.
 func GetEncryptionKeyByName(tx StdlibTxn, name string) (*models.EncryptionKey, error) {
 	table := &encryptionKeysTable{}
 	query := querybuilder.New("SELECT")
@@ -70,6 +73,7 @@ func GetEncryptionKeyByName(tx StdlibTxn, name string) (*models.EncryptionKey, e
 	query.B("FROM encryption_keys")
 	query.B("WHERE deleted_at is null")
 	query.B("AND name = ?", name)
+	query.B("ORDER BY id DESC LIMIT 1")
 
 	row := tx.QueryRow(query.String(), query.Args...)
 	if err := row.Scan(table.ScanFields()...); err != nil {
@@ -78,6 +82,41 @@ func GetEncryptionKeyByName(tx StdlibTxn, name string) (*models.EncryptionKey, e
 	return (*models.EncryptionKey)(table), nil
 }
 
+func GetEncryptionKeyByKeyID(tx StdlibTxn, keyID int32) (*models.EncryptionKey, error) {
+	table := &encryptionKeysTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM encryption_keys")
+	query.B("WHERE deleted_at is null")
+	query.B("AND key_id = ?", keyID)
+
+	row := tx.QueryRow(query.String(), query.Args...)
+	if err := row.Scan(table.ScanFields()...); err != nil {
+		return nil, handleError(err)
+	}
+	return (*models.EncryptionKey)(table), nil
+}
+
+// ListEncryptionKeys returns every version of the key with name, ordered from
+// the oldest to the newest version.
+func ListEncryptionKeys(tx StdlibTxn, name string) ([]models.EncryptionKey, error) {
+	table := &encryptionKeysTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM encryption_keys")
+	query.B("WHERE deleted_at is null")
+	query.B("AND name = ?", name)
+	query.B("ORDER BY id ASC")
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(key *models.EncryptionKey) []any {
+		return (*encryptionKeysTable)(key).ScanFields()
+	})
+}
+
 type EncryptionKeyProvider interface {
 	GenerateDataKey(rootKeyID string) (*secrets.SymmetricKey, error)
 	DecryptDataKey(rootKeyID string, keyData []byte) (*secrets.SymmetricKey, error)
@@ -85,28 +124,54 @@ type EncryptionKeyProvider interface {
 
 var dbKeyName = "dbkey"
 
+// loadDBKey loads every version of the database key. The newest version is
+// used to encrypt fields, and all versions can decrypt fields. Fields that
+// were encrypted before the key was versioned have no key ID, and are
+// decrypted with the oldest version.
 func loadDBKey(tx StdlibTxn, provider EncryptionKeyProvider, rootKeyId string) error {
-	keyRec, err := GetEncryptionKeyByName(tx, dbKeyName)
+	keyRecs, err := ListEncryptionKeys(tx, dbKeyName)
 	if err != nil {
-		if errors.Is(err, internal.ErrNotFound) {
-			return createDBKey(tx, provider, rootKeyId)
-		}
 		return err
 	}
-
-	sKey, err := provider.DecryptDataKey(rootKeyId, keyRec.Encrypted)
-	if err != nil {
+	if len(keyRecs) == 0 {
+		_, err := createDBKey(tx, provider, rootKeyId)
 		return err
 	}
 
-	models.SymmetricKey = sKey
+	for i, keyRec := range keyRecs {
+		sKey, err := provider.DecryptDataKey(rootKeyId, keyRec.Encrypted)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			models.AddDecryptionKey(0, sKey)
+		}
+		models.AddDecryptionKey(keyRec.KeyID, sKey)
+		models.SymmetricKey = sKey
+		models.SymmetricKeyID = keyRec.KeyID
+	}
 	return nil
 }
 
-func createDBKey(tx StdlibTxn, provider EncryptionKeyProvider, rootKeyId string) error {
+// loadDecryptionKey returns the version of the database key with keyID.
+func loadDecryptionKey(tx StdlibTxn, provider EncryptionKeyProvider, rootKeyId string, keyID int32) (*secrets.SymmetricKey, error) {
+	keyRec, err := GetEncryptionKeyByKeyID(tx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if keyRec.Name != dbKeyName {
+		return nil, fmt.Errorf("key %d is not a database key", keyID)
+	}
+	return provider.DecryptDataKey(rootKeyId, keyRec.Encrypted)
+}
+
+// createDBKey creates a new version of the database key, and uses it to
+// encrypt fields.
+func createDBKey(tx StdlibTxn, provider EncryptionKeyProvider, rootKeyId string) (*models.EncryptionKey, error) {
 	sKey, err := provider.GenerateDataKey(rootKeyId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	key := &models.EncryptionKey{
@@ -116,9 +181,218 @@ func createDBKey(tx StdlibTxn, provider EncryptionKeyProvider, rootKeyId string)
 		RootKeyID: sKey.RootKeyID,
 	}
 	if err = CreateEncryptionKey(tx, key); err != nil {
-		return err
+		return nil, err
 	}
 
+	models.AddDecryptionKey(key.KeyID, sKey)
 	models.SymmetricKey = sKey
-	return nil
+	models.SymmetricKeyID = key.KeyID
+	return key, nil
+}
+
+// encryptedColumn is a column that stores a models.EncryptedAtRest value.
+// The queries select and update the column in batches, ordered by the columns
+// that identify a row. All of the identifying columns are bigint.
+type encryptedColumn struct {
+	name string
+	keys int
+	// selectBatch selects the identifying columns and the encrypted column, of
+	// the rows that come after the identifying columns in the arguments.
+	// The last argument is the size of the batch.
+	selectBatch string
+	// update sets the encrypted column to the first argument, for the row with
+	// the identifying columns in the remaining arguments.
+	update string
+}
+
+var encryptedColumns = []encryptedColumn{
+	{
+		name:        "credentials.totp_secret",
+		keys:        1,
+		selectBatch: "SELECT id, totp_secret FROM credentials WHERE totp_secret IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE credentials SET totp_secret = ? WHERE id = ?",
+	},
+	{
+		name:        "device_flow_auth_requests.access_key_token",
+		keys:        1,
+		selectBatch: "SELECT id, access_key_token FROM device_flow_auth_requests WHERE access_key_token IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE device_flow_auth_requests SET access_key_token = ? WHERE id = ?",
+	},
+	{
+		name:        "provider_users.access_token",
+		keys:        2,
+		selectBatch: "SELECT provider_id, identity_id, access_token FROM provider_users WHERE access_token IS NOT NULL AND (provider_id, identity_id) > (?, ?) ORDER BY provider_id, identity_id LIMIT ?",
+		update:      "UPDATE provider_users SET access_token = ? WHERE provider_id = ? AND identity_id = ?",
+	},
+	{
+		name:        "provider_users.refresh_token",
+		keys:        2,
+		selectBatch: "SELECT provider_id, identity_id, refresh_token FROM provider_users WHERE refresh_token IS NOT NULL AND (provider_id, identity_id) > (?, ?) ORDER BY provider_id, identity_id LIMIT ?",
+		update:      "UPDATE provider_users SET refresh_token = ? WHERE provider_id = ? AND identity_id = ?",
+	},
+	{
+		name:        "providers.client_secret",
+		keys:        1,
+		selectBatch: "SELECT id, client_secret FROM providers WHERE client_secret IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE providers SET client_secret = ? WHERE id = ?",
+	},
+	{
+		name:        "providers.private_key",
+		keys:        1,
+		selectBatch: "SELECT id, private_key FROM providers WHERE private_key IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE providers SET private_key = ? WHERE id = ?",
+	},
+	{
+		name:        "providers.ldap_bind_password",
+		keys:        1,
+		selectBatch: "SELECT id, ldap_bind_password FROM providers WHERE ldap_bind_password IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE providers SET ldap_bind_password = ? WHERE id = ?",
+	},
+	{
+		name:        "settings.ssh_ca_private_key",
+		keys:        1,
+		selectBatch: "SELECT id, ssh_ca_private_key FROM settings WHERE ssh_ca_private_key IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE settings SET ssh_ca_private_key = ? WHERE id = ?",
+	},
+	{
+		name:        "signing_keys.private_jwk",
+		keys:        1,
+		selectBatch: "SELECT id, private_jwk FROM signing_keys WHERE private_jwk IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE signing_keys SET private_jwk = ? WHERE id = ?",
+	},
+	{
+		name:        "webhooks.secret",
+		keys:        1,
+		selectBatch: "SELECT id, secret FROM webhooks WHERE secret IS NOT NULL AND id > ? ORDER BY id LIMIT ?",
+		update:      "UPDATE webhooks SET secret = ? WHERE id = ?",
+	},
+}
+
+// RotateDBKeyBatchSize is the number of rows that are re-encrypted in each
+// transaction by RotateDBKey.
+var RotateDBKeyBatchSize = 500
+
+// RotateDBKey creates a new version of the database key, and re-encrypts every
+// encrypted column with the new version. Each batch of rows is re-encrypted in
+// its own transaction. Fields encrypted with an older version can be decrypted
+// until they are re-encrypted, and the older versions are kept so that servers
+// that have not yet loaded the new version can continue to encrypt fields.
+//
+// RotateDBKey returns the number of fields that were re-encrypted.
+func RotateDBKey(db *DB, provider EncryptionKeyProvider, rootKeyId string) (int, error) {
+	tx, err := db.Begin(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	key, err := createDBKey(tx, provider, rootKeyId)
+	if err != nil {
+		return 0, fmt.Errorf("create key: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	logging.Infof("created database key version %d", key.KeyID)
+
+	var total int
+	for _, column := range encryptedColumns {
+		count, err := reEncryptColumn(db, column, RotateDBKeyBatchSize)
+		total += count
+		if err != nil {
+			return total, fmt.Errorf("re-encrypt %v: %w", column.name, err)
+		}
+		logging.Infof("re-encrypted %d values of %v", count, column.name)
+	}
+	return total, nil
+}
+
+func reEncryptColumn(db *DB, column encryptedColumn, batchSize int) (int, error) {
+	after := make([]any, column.keys)
+	for i := range after {
+		after[i] = uid.ID(0)
+	}
+
+	var total int
+	for {
+		tx, err := db.Begin(context.Background(), nil)
+		if err != nil {
+			return total, err
+		}
+		count, last, err := reEncryptBatch(tx, column, after, batchSize)
+		if err != nil {
+			_ = tx.Rollback()
+			return total, err
+		}
+		if err := tx.Commit(); err != nil {
+			return total, err
+		}
+		total += count
+		if last == nil {
+			return total, nil
+		}
+		after = last
+	}
+}
+
+// reEncryptBatch re-encrypts the values in one batch of rows that are not
+// already encrypted with the current key. It returns the number of values that
+// were re-encrypted, and the identifying columns of the last row in the batch,
+// or nil if this was the last batch.
+func reEncryptBatch(tx *Transaction, column encryptedColumn, after []any, batchSize int) (int, []any, error) {
+	type row struct {
+		keys  []any
+		value []byte
+	}
+
+	rows, err := tx.Query(column.selectBatch, append(after, batchSize)...)
+	if err != nil {
+		return 0, nil, err
+	}
+	var batch []row
+	for rows.Next() {
+		r := row{keys: make([]any, column.keys)}
+		fields := make([]any, 0, column.keys+1)
+		ids := make([]uid.ID, column.keys)
+		for i := range ids {
+			fields = append(fields, &ids[i])
+		}
+		fields = append(fields, &r.value)
+		if err := rows.Scan(fields...); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		for i := range ids {
+			r.keys[i] = ids[i]
+		}
+		batch = append(batch, r)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	prefix := []byte(models.KeyIDPrefix(models.SymmetricKeyID))
+	var count int
+	for _, r := range batch {
+		if len(r.value) == 0 || bytes.HasPrefix(r.value, prefix) {
+			continue
+		}
+
+		var value models.EncryptedAtRest
+		if err := value.Scan(r.value); err != nil {
+			return count, nil, err
+		}
+		if _, err := tx.Exec(column.update, append([]any{value}, r.keys...)...); err != nil {
+			return count, nil, err
+		}
+		count++
+	}
+
+	if len(batch) < batchSize {
+		return count, nil, nil
+	}
+	return count, batch[len(batch)-1].keys, nil
 }
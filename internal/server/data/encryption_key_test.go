@@ -1,13 +1,20 @@
 package data
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/infrahq/secrets"
+	"github.com/rs/zerolog"
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/database"
+	"github.com/infrahq/infra/internal/testing/patch"
 	"github.com/infrahq/infra/uid"
 )
 
@@ -75,3 +82,107 @@ func TestEncryptionKeys(t *testing.T) {
 		})
 	})
 }
+
+func TestRotateDBKey(t *testing.T) {
+	patch.ModelsSymmetricKey(t)
+
+	sp := secrets.NewFileSecretProviderFromConfig(secrets.FileConfig{Path: t.TempDir()})
+	provider := secrets.NewNativeKeyProvider(sp)
+	rootKeyID := "db_at_rest"
+
+	db, err := NewDB(NewDBOptions{
+		DSN:                   database.PostgresDriver(t, "_data").DSN,
+		EncryptionKeyProvider: provider,
+		RootKeyID:             rootKeyID,
+	})
+	assert.NilError(t, err)
+	logging.PatchLogger(t, zerolog.NewTestWriter(t))
+
+	firstKeyID := models.SymmetricKeyID
+	assert.Assert(t, firstKeyID != 0)
+
+	var providers []models.Provider
+	for i := 0; i < 3; i++ {
+		p := models.Provider{
+			Name:         fmt.Sprintf("okta-%d", i),
+			URL:          "example.com",
+			Kind:         models.ProviderKindOkta,
+			ClientSecret: models.EncryptedAtRest(fmt.Sprintf("secret-%d", i)),
+		}
+		assert.NilError(t, CreateProvider(db, &p))
+		providers = append(providers, p)
+	}
+
+	// a value encrypted before keys were versioned
+	_, err = db.Exec(`UPDATE providers SET client_secret = ? WHERE id = ?`,
+		sealWithoutKeyID(t, "legacy"), providers[0].ID)
+	assert.NilError(t, err)
+
+	orig := RotateDBKeyBatchSize
+	RotateDBKeyBatchSize = 2
+	t.Cleanup(func() {
+		RotateDBKeyBatchSize = orig
+	})
+
+	count, err := RotateDBKey(db, provider, rootKeyID)
+	assert.NilError(t, err)
+	// the provider secrets, and the private keys of the organization
+	assert.Assert(t, count > len(providers), count)
+	assert.Assert(t, models.SymmetricKeyID != firstKeyID)
+
+	keys, err := ListEncryptionKeys(db, dbKeyName)
+	assert.NilError(t, err)
+	assert.Equal(t, len(keys), 2)
+	assert.Equal(t, keys[0].KeyID, firstKeyID)
+	assert.Equal(t, keys[1].KeyID, models.SymmetricKeyID)
+
+	prefix := models.KeyIDPrefix(models.SymmetricKeyID)
+	for i, p := range providers {
+		var raw string
+		err := db.QueryRow(`SELECT client_secret FROM providers WHERE id = ?`, p.ID).Scan(&raw)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.HasPrefix(raw, prefix), raw)
+
+		actual, err := GetProvider(db, GetProviderOptions{ByID: p.ID})
+		assert.NilError(t, err)
+		expected := fmt.Sprintf("secret-%d", i)
+		if i == 0 {
+			expected = "legacy"
+		}
+		assert.Equal(t, string(actual.ClientSecret), expected)
+	}
+
+	t.Run("rotate again", func(t *testing.T) {
+		again, err := RotateDBKey(db, provider, rootKeyID)
+		assert.NilError(t, err)
+		assert.Equal(t, again, count)
+	})
+
+	t.Run("load all versions", func(t *testing.T) {
+		models.ResetDecryptionKeys()
+		assert.NilError(t, loadDBKey(db, provider, rootKeyID))
+
+		keys, err := ListEncryptionKeys(db, dbKeyName)
+		assert.NilError(t, err)
+		assert.Equal(t, models.SymmetricKeyID, keys[len(keys)-1].KeyID)
+
+		actual, err := GetProvider(db, GetProviderOptions{ByID: providers[1].ID})
+		assert.NilError(t, err)
+		assert.Equal(t, string(actual.ClientSecret), "secret-1")
+	})
+}
+
+// sealWithoutKeyID encrypts value with the oldest version of the key, and
+// without a key ID, like values that were encrypted before keys were versioned.
+func sealWithoutKeyID(t *testing.T, value string) string {
+	t.Helper()
+	keyID := models.SymmetricKeyID
+	models.SymmetricKeyID = 0
+	defer func() {
+		models.SymmetricKeyID = keyID
+	}()
+
+	encrypted, err := models.EncryptedAtRest(value).Encrypt()
+	assert.NilError(t, err)
+	return encrypted
+}
@@ -27,15 +27,15 @@ func (g grantsTable) Table() string {
 }
 
 func (g grantsTable) Columns() []string {
-	return []string{"created_at", "created_by", "deleted_at", "id", "organization_id", "privilege", "resource", "subject", "updated_at"}
+	return []string{"created_at", "created_by", "deleted_at", "expires_at", "id", "organization_id", "privilege", "resource", "subject", "updated_at"}
 }
 
 func (g grantsTable) Values() []any {
-	return []any{g.CreatedAt, g.CreatedBy, g.DeletedAt, g.ID, g.OrganizationID, g.Privilege, g.Resource, g.Subject, g.UpdatedAt}
+	return []any{g.CreatedAt, g.CreatedBy, g.DeletedAt, g.ExpiresAt, g.ID, g.OrganizationID, g.Privilege, g.Resource, g.Subject, g.UpdatedAt}
 }
 
 func (g *grantsTable) ScanFields() []any {
-	return []any{&g.CreatedAt, &g.CreatedBy, &g.DeletedAt, &g.ID, &g.OrganizationID, &g.Privilege, &g.Resource, &g.Subject, &g.UpdatedAt}
+	return []any{&g.CreatedAt, &g.CreatedBy, &g.DeletedAt, &g.ExpiresAt, &g.ID, &g.OrganizationID, &g.Privilege, &g.Resource, &g.Subject, &g.UpdatedAt}
 }
 
 func CreateGrant(tx WriteTxn, grant *models.Grant) error {
@@ -62,6 +62,11 @@ func CreateGrant(tx WriteTxn, grant *models.Grant) error {
 		}
 	}
 
+	if err := deleteExpiredDuplicateGrants(tx, []*models.Grant{grant}); err != nil {
+		_, _ = tx.Exec("ROLLBACK TO SAVEPOINT beforeCreate")
+		return err
+	}
+
 	table := (*grantsTable)(grant)
 	query := querybuilder.New("INSERT INTO grants (")
 	query.B(columnsForInsert(table))
@@ -75,7 +80,7 @@ func CreateGrant(tx WriteTxn, grant *models.Grant) error {
 		return handleError(err)
 	}
 	_, _ = tx.Exec("RELEASE SAVEPOINT beforeCreate")
-	return nil
+	return createGrantWebhookDeliveries(tx, grantCreatedEvent, []models.Grant{*grant})
 }
 
 func isPgErrorCode(err error, code string) bool {
@@ -107,6 +112,7 @@ func GetGrant(tx ReadTxn, opts GetGrantOptions) (*models.Grant, error) {
 	query.B("FROM grants")
 	query.B("WHERE organization_id = ?", tx.OrganizationID())
 	query.B("AND deleted_at is null")
+	query.B("AND (expires_at is null OR expires_at > ?)", time.Now())
 
 	switch {
 	case opts.ByID != 0:
@@ -134,28 +140,73 @@ type ListGrantsOptions struct {
 	ByDestination string
 
 	// IncludeInheritedFromGroups instructs ListGrants to include grants from
-	// groups where the user is a member. This option can only be used when
-	// BySubject is a non-zero userID.
+	// groups where the user is a member, or when BySubject is a group, from the
+	// groups that the group is nested in. Groups nested in other groups inherit
+	// the grants of those groups, at any level of nesting. This option can only
+	// be used when BySubject is a non-zero user or group ID.
 	IncludeInheritedFromGroups bool
 
 	// ExcludeConnectorGrant instructs ListGrants to exclude grants where
 	// privilege=connector and resource=infra.
 	ExcludeConnectorGrant bool
 
+	// IncludeSubjectNames instructs ListGrants to set Grant.SubjectName to the
+	// name of the user or group, using a join instead of a query per grant.
+	IncludeSubjectNames bool
+
+	// ByCreatedBy instructs ListGrants to return only the grants that were
+	// created by this user. Can be used with NotIDs.
+	ByCreatedBy uid.ID
+	// NotIDs instructs ListGrants to exclude any grants with these IDs.
+	// Can only be used with ByCreatedBy.
+	NotIDs []uid.ID
+
 	Pagination *Pagination
 }
 
+// inheritedGroupIDs returns the IDs of the groups that subject inherits grants
+// from.
+func inheritedGroupIDs(tx ReadTxn, subject uid.PolymorphicID) ([]uid.ID, error) {
+	id, err := subject.ID()
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("IncludeInheritedFromGroups requires a user or group subject: %w", err)
+	case subject.IsIdentity():
+		// FIXME: store userID and groupID as a field on the grants table so
+		// that we can replace this with a sub-select or join.
+		return ListGroupIDsForUser(tx, id)
+	case subject.IsGroup():
+		parents, err := nestedGroupDepths(tx, id, false)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]uid.ID, 0, len(parents))
+		for parentID := range parents {
+			result = append(result, parentID)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("IncludeInheritedFromGroups requires a user or group subject")
+}
+
 func ListGrants(tx ReadTxn, opts ListGrantsOptions) ([]models.Grant, error) {
 	table := grantsTable{}
 	query := querybuilder.New("SELECT")
 	query.B(columnsForSelect(table))
 	query.B(", update_index")
-	if opts.Pagination != nil {
+	if opts.IncludeSubjectNames {
+		query.B(", COALESCE(identities.name, groups.name, '')")
+	}
+	if opts.Pagination.countRows() {
 		query.B(", count(*) OVER()")
 	}
 	query.B("FROM grants")
-	query.B("WHERE deleted_at is null")
-	query.B("AND organization_id = ?", tx.OrganizationID())
+	if opts.IncludeSubjectNames {
+		joinGrantSubjects(query)
+	}
+	query.B("WHERE grants.deleted_at is null")
+	query.B("AND grants.organization_id = ?", tx.OrganizationID())
+	query.B("AND (expires_at is null OR expires_at > ?)", time.Now())
 
 	if opts.BySubject != "" {
 		if !opts.IncludeInheritedFromGroups {
@@ -163,13 +214,7 @@ func ListGrants(tx ReadTxn, opts ListGrantsOptions) ([]models.Grant, error) {
 		} else {
 			subjects := []string{opts.BySubject.String()}
 
-			userID, err := opts.BySubject.ID()
-			if err != nil || !opts.BySubject.IsIdentity() {
-				return nil, fmt.Errorf("IncludeInheritedFromGroups requires a userId subject")
-			}
-			// FIXME: store userID and groupID as a field on the grants table so
-			// that we can replace this with a sub-select or join.
-			groupIDs, err := ListGroupIDsForUser(tx, userID)
+			groupIDs, err := inheritedGroupIDs(tx, opts.BySubject)
 			if err != nil {
 				return nil, err
 			}
@@ -193,8 +238,19 @@ func ListGrants(tx ReadTxn, opts ListGrantsOptions) ([]models.Grant, error) {
 	if opts.ExcludeConnectorGrant {
 		query.B("AND NOT (privilege = 'connector' AND resource = 'infra')")
 	}
+	if opts.ByCreatedBy != 0 {
+		query.B("AND grants.created_by = ?", opts.ByCreatedBy)
+		if len(opts.NotIDs) > 0 {
+			query.B("AND grants.id NOT IN")
+			queryInClause(query, opts.NotIDs)
+		}
+	}
 
-	query.B("ORDER BY id ASC")
+	if opts.Pagination.usesCursor() && opts.Pagination.After != 0 {
+		query.B("AND grants.id > ?", opts.Pagination.After)
+	}
+
+	query.B("ORDER BY grants.id ASC")
 	if opts.Pagination != nil {
 		opts.Pagination.PaginateQuery(query)
 	}
@@ -203,19 +259,39 @@ func ListGrants(tx ReadTxn, opts ListGrantsOptions) ([]models.Grant, error) {
 	if err != nil {
 		return nil, err
 	}
-	return scanRows(rows, func(grant *models.Grant) []any {
+	result, err := scanRows(rows, func(grant *models.Grant) []any {
 		fields := append((*grantsTable)(grant).ScanFields(), &grant.UpdateIndex)
-		if opts.Pagination != nil {
+		if opts.IncludeSubjectNames {
+			fields = append(fields, &grant.SubjectName)
+		}
+		if opts.Pagination.countRows() {
 			fields = append(fields, &opts.Pagination.TotalCount)
 		}
 		return fields
 	})
+	if err != nil {
+		return nil, err
+	}
+	setNextCursor(opts.Pagination, result, func(grant models.Grant) uid.ID {
+		return grant.ID
+	})
+	return result, nil
 }
 
 func grantsByDestination(query *querybuilder.Query, destination string) {
 	query.B("AND (resource = ? OR resource LIKE ?)", destination, destination+".%")
 }
 
+// joinGrantSubjects joins the identities and groups tables to the grants, so
+// that the name of the subject can be selected from either table. Column names
+// that are shared by these tables must be qualified in the rest of the query.
+func joinGrantSubjects(query *querybuilder.Query) {
+	query.B(`LEFT JOIN identities ON identities.id = CASE WHEN grants.subject LIKE 'i:%'
+		THEN uidstrtoint(substring(grants.subject FROM 3)) END`)
+	query.B(`LEFT JOIN groups ON groups.id = CASE WHEN grants.subject LIKE 'g:%'
+		THEN uidstrtoint(substring(grants.subject FROM 3)) END`)
+}
+
 type GrantsMaxUpdateIndexOptions struct {
 	ByDestination string
 }
@@ -243,6 +319,57 @@ func GrantsMaxUpdateIndex(tx ReadTxn, opts GrantsMaxUpdateIndexOptions) (int64,
 	return *result, err
 }
 
+type ListGrantChangesOptions struct {
+	// ByDestination instructs ListGrantChanges to return only the grants for
+	// this destination.
+	ByDestination string
+	// SinceUpdateIndex instructs ListGrantChanges to return only the grants
+	// that were created or deleted after this update index. When zero, only
+	// grants that have not been deleted are returned.
+	SinceUpdateIndex int64
+}
+
+// ListGrantChanges returns the grants that were created or deleted after
+// opts.SinceUpdateIndex, ordered by update_index. Deleted grants are included,
+// and have DeletedAt set. SubjectName is set to the name of the user or group.
+// Use Grant.UpdateIndex of the last grant as SinceUpdateIndex to query for the
+// next set of changes.
+//
+// ListGrantChanges should be called from a transaction with an isolation level
+// of repeatable read, so that the changes are consistent with each other.
+//
+// The update index is assigned when a grant is written, not when the
+// transaction commits, so a change may become visible after a change with a
+// higher update index. Callers that resume from the last update index must
+// list all the grants periodically to find those changes.
+func ListGrantChanges(tx ReadTxn, opts ListGrantChangesOptions) ([]models.Grant, error) {
+	table := grantsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B(", update_index")
+	query.B(", COALESCE(identities.name, groups.name, '')")
+	query.B("FROM grants")
+	joinGrantSubjects(query)
+	query.B("WHERE grants.organization_id = ?", tx.OrganizationID())
+	query.B("AND update_index > ?", opts.SinceUpdateIndex)
+	if opts.SinceUpdateIndex == 0 {
+		query.B("AND grants.deleted_at is null")
+		query.B("AND (expires_at is null OR expires_at > ?)", time.Now())
+	}
+	if opts.ByDestination != "" {
+		grantsByDestination(query, opts.ByDestination)
+	}
+	query.B("ORDER BY update_index ASC")
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(grant *models.Grant) []any {
+		return append((*grantsTable)(grant).ScanFields(), &grant.UpdateIndex, &grant.SubjectName)
+	})
+}
+
 type Listener struct {
 	sqlDB   *sql.DB
 	pgxConn *pgx.Conn
@@ -348,13 +475,32 @@ type grantJSON struct {
 	Resource string
 }
 
+// UpdateGrant updates the fields of an existing grant. The update_index of
+// the grant is not changed, so UpdateGrant must not be used to change the
+// subject, privilege, or resource of a grant.
+func UpdateGrant(tx WriteTxn, grant *models.Grant) error {
+	if err := update(tx, (*grantsTable)(grant)); err != nil {
+		return err
+	}
+	return createGrantWebhookDeliveries(tx, grantUpdatedEvent, []models.Grant{*grant})
+}
+
 type DeleteGrantsOptions struct {
 	// ByID instructs DeleteGrants to delete the grant with this ID. When set
 	// all other fields on this struct are ignored.
 	ByID uid.ID
+	// ByIDs instructs DeleteGrants to delete the grants with these IDs. When
+	// set other fields below this on this struct are ignored.
+	ByIDs []uid.ID
 	// BySubject instructs DeleteGrants to delete all grants that match this
 	// subject. When set other fields below this on this struct are ignored.
 	BySubject uid.PolymorphicID
+	// ByPrivilege instructs DeleteGrants to delete all grants of this
+	// privilege. When set other fields below this on this struct are ignored.
+	ByPrivilege string
+	// CreatedSince instructs DeleteGrants to exclude grants created before
+	// this time. Can only be used with ByPrivilege.
+	CreatedSince time.Time
 
 	// ByCreatedBy instructs DeleteGrants to delete all the grants that were
 	// created by this user. Can be used with NotIDs
@@ -376,8 +522,16 @@ func DeleteGrants(tx WriteTxn, opts DeleteGrantsOptions) error {
 	switch {
 	case opts.ByID != 0:
 		query.B("id = ?", opts.ByID)
+	case len(opts.ByIDs) > 0:
+		query.B("id IN")
+		queryInClause(query, opts.ByIDs)
 	case opts.BySubject != "":
 		query.B("subject = ?", opts.BySubject)
+	case opts.ByPrivilege != "":
+		query.B("privilege = ?", opts.ByPrivilege)
+		if !opts.CreatedSince.IsZero() {
+			query.B("AND created_at >= ?", opts.CreatedSince)
+		}
 	case opts.ByCreatedBy != 0:
 		query.B("created_by = ?", opts.ByCreatedBy)
 		if len(opts.NotIDs) > 0 {
@@ -388,8 +542,63 @@ func DeleteGrants(tx WriteTxn, opts DeleteGrantsOptions) error {
 		return fmt.Errorf("DeleteGrants requires an ID to delete")
 	}
 
-	_, err := tx.Exec(query.String(), query.Args...)
-	return err
+	return execGrantsChange(tx, query, grantDeletedEvent)
+}
+
+// DeleteExpiredGrants deletes all grants, in every organization, that have
+// expired. The update_index of each deleted grant is incremented so that any
+// connector listening for changes is notified of the deletion.
+func DeleteExpiredGrants(tx WriteTxn) error {
+	query := querybuilder.New("UPDATE grants")
+	query.B("SET deleted_at = ?,", time.Now())
+	query.B("update_index = nextval('seq_update_index')")
+	query.B("WHERE deleted_at is null")
+	query.B("AND expires_at <= ?", time.Now())
+
+	return execGrantsChange(tx, query, grantDeletedEvent)
+}
+
+// deleteExpiredDuplicateGrants deletes any expired grants with the same
+// subject, privilege, and resource as one of grants. Expired grants may not
+// have been deleted by the background job yet, and would otherwise prevent
+// the grant from being created again.
+func deleteExpiredDuplicateGrants(tx WriteTxn, grants []*models.Grant) error {
+	query := querybuilder.New("UPDATE grants")
+	query.B("SET deleted_at = ?,", time.Now())
+	query.B("update_index = nextval('seq_update_index')")
+	query.B("WHERE organization_id = ?", tx.OrganizationID())
+	query.B("AND deleted_at is null")
+	query.B("AND expires_at <= ?", time.Now())
+	query.B("AND (subject, privilege, resource) IN (")
+	for i, g := range grants {
+		query.B("(?, ?, ?)", g.Subject, g.Privilege, g.Resource)
+		if i+1 != len(grants) {
+			query.B(",")
+		}
+	}
+	query.B(")")
+
+	return execGrantsChange(tx, query, grantDeletedEvent)
+}
+
+// execGrantsChange runs a query that inserts or updates grants, and creates
+// webhook deliveries of eventType for every grant changed by the query.
+func execGrantsChange(tx WriteTxn, query *querybuilder.Query, eventType string) error {
+	table := &grantsTable{}
+	query.B("RETURNING")
+	query.B(columnsForSelect(table))
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return err
+	}
+	grants, err := scanRows(rows, func(grant *models.Grant) []any {
+		return (*grantsTable)(grant).ScanFields()
+	})
+	if err != nil {
+		return err
+	}
+	return createGrantWebhookDeliveries(tx, eventType, grants)
 }
 
 func UpdateGrants(tx WriteTxn, addGrants, rmGrants []*models.Grant) error {
@@ -442,6 +651,10 @@ func createGrantsBulk(tx WriteTxn, grants []*models.Grant) error {
 		setOrg(tx, g)
 	}
 
+	if err := deleteExpiredDuplicateGrants(tx, grants); err != nil {
+		return err
+	}
+
 	table := &grantsTable{}
 	query := querybuilder.New("INSERT INTO grants")
 	query.B("(")
@@ -461,8 +674,7 @@ func createGrantsBulk(tx WriteTxn, grants []*models.Grant) error {
 	}
 	query.B("ON CONFLICT DO NOTHING")
 
-	_, err := tx.Exec(query.String(), query.Args...)
-	return err
+	return execGrantsChange(tx, query, grantCreatedEvent)
 }
 
 func deleteGrantsBulk(tx WriteTxn, grants []*models.Grant) error {
@@ -497,8 +709,7 @@ func deleteGrantsBulk(tx WriteTxn, grants []*models.Grant) error {
 	}
 	query.B(")")
 
-	_, err := tx.Exec(query.String(), query.Args...)
-	return err
+	return execGrantsChange(tx, query, grantDeletedEvent)
 }
 
 func CountAllGrants(tx ReadTxn) (int64, error) {
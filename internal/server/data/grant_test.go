@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
 	"time"
@@ -81,6 +82,34 @@ func TestCreateGrant(t *testing.T) {
 			err = CreateGrant(tx, &g3)
 			assert.NilError(t, err)
 		})
+		t.Run("replaces expired grant", func(t *testing.T) {
+			tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+			expired := models.Grant{
+				Subject:   "i:1234567",
+				Privilege: "view",
+				Resource:  "infra",
+				ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+			}
+			err := CreateGrant(tx, &expired)
+			assert.NilError(t, err)
+
+			g := models.Grant{
+				Subject:   "i:1234567",
+				Privilege: "view",
+				Resource:  "infra",
+			}
+			err = CreateGrant(tx, &g)
+			assert.NilError(t, err)
+
+			actual, err := GetGrant(tx, GetGrantOptions{
+				BySubject:   "i:1234567",
+				ByPrivilege: "view",
+				ByResource:  "infra",
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, actual.ID, g.ID)
+		})
 		t.Run("notify", func(t *testing.T) {
 			ctx := context.Background()
 			listener, err := ListenForGrantsNotify(ctx, db, ListenForGrantsOptions{
@@ -235,6 +264,53 @@ func TestDeleteGrants(t *testing.T) {
 	})
 }
 
+func TestDeleteExpiredGrants(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		otherOrg := &models.Organization{Name: "other", Domain: "other.example.org"}
+		assert.NilError(t, CreateOrganization(db, otherOrg))
+
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+		expired := sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+		grant1 := &models.Grant{Subject: "i:any1", Privilege: "view", Resource: "any", ExpiresAt: expired}
+		toKeep1 := &models.Grant{Subject: "i:any2", Privilege: "view", Resource: "any"}
+		toKeep2 := &models.Grant{
+			Subject:   "i:any3",
+			Privilege: "view",
+			Resource:  "any",
+			ExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+		}
+		createGrants(t, tx, grant1, toKeep1, toKeep2)
+
+		otherOrgGrant := &models.Grant{Subject: "i:any1", Privilege: "view", Resource: "any", ExpiresAt: expired}
+		createGrants(t, tx.WithOrgID(otherOrg.ID), otherOrgGrant)
+
+		// expired grants are excluded from the list before they are deleted
+		actual, err := ListGrants(tx, ListGrantsOptions{ByDestination: "any"})
+		assert.NilError(t, err)
+		expected := []models.Grant{
+			{Model: models.Model{ID: toKeep1.ID}},
+			{Model: models.Model{ID: toKeep2.ID}},
+		}
+		assert.DeepEqual(t, actual, expected, cmpModelByID)
+
+		startUpdateIndex, err := GrantsMaxUpdateIndex(tx, GrantsMaxUpdateIndexOptions{ByDestination: "any"})
+		assert.NilError(t, err)
+
+		err = DeleteExpiredGrants(tx)
+		assert.NilError(t, err)
+
+		_, err = GetGrant(tx, GetGrantOptions{ByID: grant1.ID})
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+		_, err = GetGrant(tx.WithOrgID(otherOrg.ID), GetGrantOptions{ByID: otherOrgGrant.ID})
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+
+		maxIndex, err := GrantsMaxUpdateIndex(tx, GrantsMaxUpdateIndexOptions{ByDestination: "any"})
+		assert.NilError(t, err)
+		assert.Assert(t, maxIndex > startUpdateIndex)
+	})
+}
+
 func TestUpdateGrants(t *testing.T) {
 	runDBTests(t, func(t *testing.T, db *DB) {
 		otherOrg := &models.Organization{Name: "other", Domain: "other.example.org"}
@@ -423,6 +499,18 @@ func TestGetGrant(t *testing.T) {
 			_, err := GetGrant(tx, GetGrantOptions{ByID: uid.ID(1234)})
 			assert.ErrorIs(t, err, internal.ErrNotFound)
 		})
+		t.Run("not found expired", func(t *testing.T) {
+			expired := &models.Grant{
+				Subject:   "i:expired",
+				Privilege: "view",
+				Resource:  "any",
+				ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+			}
+			createGrants(t, tx, expired)
+
+			_, err := GetGrant(tx, GetGrantOptions{ByID: expired.ID})
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+		})
 		t.Run("wrong org", func(t *testing.T) {
 			_, err := GetGrant(tx, GetGrantOptions{ByID: uid.ID(other.ID)})
 			assert.ErrorIs(t, err, internal.ErrNotFound)
@@ -655,6 +743,26 @@ func TestListGrants(t *testing.T) {
 			expectedPagination := &Pagination{Page: 2, Limit: 3, TotalCount: 9}
 			assert.DeepEqual(t, pagination, expectedPagination)
 		})
+		t.Run("with cursor pagination", func(t *testing.T) {
+			pagination := &Pagination{Cursor: true, Limit: 3}
+			opts := ListGrantsOptions{Pagination: pagination, ExcludeConnectorGrant: true}
+			actual, err := ListGrants(tx, opts)
+			assert.NilError(t, err)
+
+			expected := []models.Grant{*grant1, *grant2, *grant3}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+			expectedPagination := &Pagination{Cursor: true, Limit: 3, Next: grant3.ID}
+			assert.DeepEqual(t, pagination, expectedPagination)
+
+			pagination.After, pagination.Next = pagination.Next, 0
+			actual, err = ListGrants(tx, opts)
+			assert.NilError(t, err)
+
+			expected = []models.Grant{*grant4, *grant5, *gGrant1}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+			expectedPagination = &Pagination{Cursor: true, Limit: 3, After: grant3.ID, Next: gGrant1.ID}
+			assert.DeepEqual(t, pagination, expectedPagination)
+		})
 		t.Run("by resource with pagination", func(t *testing.T) {
 			pagination := &Pagination{Page: 1, Limit: 2}
 			actual, err := ListGrants(tx, ListGrantsOptions{
@@ -678,6 +786,77 @@ func TestListGrants(t *testing.T) {
 			expected := []models.Grant{*grant1, *grant2, *grant3, *grant5}
 			assert.DeepEqual(t, actual, expected, cmpModelByID)
 		})
+		t.Run("include subject names", func(t *testing.T) {
+			group := &models.Group{Name: "the-group"}
+			createGroups(t, tx, group)
+
+			userGrant := &models.Grant{
+				Subject:   uid.NewIdentityPolymorphicID(user.ID),
+				Privilege: "view",
+				Resource:  "named",
+			}
+			groupGrant := &models.Grant{
+				Subject:   uid.NewGroupPolymorphicID(group.ID),
+				Privilege: "view",
+				Resource:  "named",
+			}
+			createGrants(t, tx, userGrant, groupGrant)
+
+			actual, err := ListGrants(tx, ListGrantsOptions{
+				ByDestination:       "named",
+				IncludeSubjectNames: true,
+				Pagination:          &Pagination{Limit: 10},
+			})
+			assert.NilError(t, err)
+
+			expected := []models.Grant{*userGrant, *groupGrant}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+			assert.Equal(t, actual[0].SubjectName, "usera@example.com")
+			assert.Equal(t, actual[1].SubjectName, "the-group")
+		})
+	})
+}
+
+func TestListGrants_InheritedFromNestedGroups(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+		sre := &models.Group{Name: "sre"}
+		oncall := &models.Group{Name: "sre-oncall"}
+		createGroups(t, tx, sre, oncall)
+		assert.NilError(t, AddGroupsToGroup(tx, sre.ID, []uid.ID{oncall.ID}))
+
+		user := &models.Identity{Name: "oncall@example.com", Groups: []models.Group{*oncall}}
+		createIdentities(t, tx, user)
+
+		sreGrant := &models.Grant{Subject: sre.PolyID(), Privilege: "view", Resource: "production"}
+		oncallGrant := &models.Grant{Subject: oncall.PolyID(), Privilege: "edit", Resource: "production"}
+		createGrants(t, tx, sreGrant, oncallGrant)
+
+		t.Run("user inherits grants of parent groups", func(t *testing.T) {
+			actual, err := ListGrants(tx, ListGrantsOptions{
+				BySubject:                  user.PolyID(),
+				IncludeInheritedFromGroups: true,
+			})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Grant{*sreGrant, *oncallGrant}, cmpModelByID)
+		})
+
+		t.Run("group inherits grants of parent groups", func(t *testing.T) {
+			actual, err := ListGrants(tx, ListGrantsOptions{
+				BySubject:                  oncall.PolyID(),
+				IncludeInheritedFromGroups: true,
+			})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Grant{*sreGrant, *oncallGrant}, cmpModelByID)
+
+			actual, err = ListGrants(tx, ListGrantsOptions{
+				BySubject:                  sre.PolyID(),
+				IncludeInheritedFromGroups: true,
+			})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Grant{*sreGrant}, cmpModelByID)
+		})
 	})
 }
 
@@ -693,6 +872,72 @@ func TestGrantsMaxUpdateIndex(t *testing.T) {
 	})
 }
 
+func TestListGrantChanges(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+		first := &models.Grant{Subject: "i:first", Privilege: "view", Resource: "mydest"}
+		second := &models.Grant{Subject: "i:second", Privilege: "edit", Resource: "mydest.ns1"}
+		other := &models.Grant{Subject: "i:first", Privilege: "view", Resource: "otherdest"}
+		createGrants(t, tx, first, second, other)
+
+		t.Run("from the start", func(t *testing.T) {
+			actual, err := ListGrantChanges(tx, ListGrantChangesOptions{ByDestination: "mydest"})
+			assert.NilError(t, err)
+
+			expected := []models.Grant{*first, *second}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+		})
+
+		initial, err := ListGrantChanges(tx, ListGrantChangesOptions{ByDestination: "mydest"})
+		assert.NilError(t, err)
+		lastIndex := initial[len(initial)-1].UpdateIndex
+
+		assert.NilError(t, DeleteGrants(tx, DeleteGrantsOptions{ByID: first.ID}))
+		third := &models.Grant{Subject: "i:third", Privilege: "view", Resource: "mydest"}
+		createGrants(t, tx, third)
+
+		t.Run("since an update index", func(t *testing.T) {
+			actual, err := ListGrantChanges(tx, ListGrantChangesOptions{
+				ByDestination:    "mydest",
+				SinceUpdateIndex: lastIndex,
+			})
+			assert.NilError(t, err)
+
+			expected := []models.Grant{*first, *third}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+			assert.Assert(t, actual[0].DeletedAt.Valid)
+			assert.Assert(t, !actual[1].DeletedAt.Valid)
+			assert.Assert(t, actual[0].UpdateIndex > lastIndex)
+			assert.Assert(t, actual[1].UpdateIndex > actual[0].UpdateIndex)
+		})
+		t.Run("deleted grants are excluded from the start", func(t *testing.T) {
+			actual, err := ListGrantChanges(tx, ListGrantChangesOptions{ByDestination: "mydest"})
+			assert.NilError(t, err)
+
+			expected := []models.Grant{*second, *third}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+		})
+		t.Run("includes subject names", func(t *testing.T) {
+			user := &models.Identity{Name: "named@example.com"}
+			createIdentities(t, tx, user)
+			named := &models.Grant{
+				Subject:   uid.NewIdentityPolymorphicID(user.ID),
+				Privilege: "view",
+				Resource:  "namedest",
+			}
+			createGrants(t, tx, named)
+
+			actual, err := ListGrantChanges(tx, ListGrantChangesOptions{ByDestination: "namedest"})
+			assert.NilError(t, err)
+
+			expected := []models.Grant{*named}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+			assert.Equal(t, actual[0].SubjectName, "named@example.com")
+		})
+	})
+}
+
 func TestListenForGrantsNotify(t *testing.T) {
 	type operation struct {
 		name        string
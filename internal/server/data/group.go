@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/data/querybuilder"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -28,7 +29,17 @@ func (g *groupsTable) ScanFields() []any {
 }
 
 func CreateGroup(tx WriteTxn, group *models.Group) error {
-	return insert(tx, (*groupsTable)(group))
+	if err := insert(tx, (*groupsTable)(group)); err != nil {
+		return err
+	}
+	return createGroupWebhookDeliveries(tx, groupCreatedEvent, group)
+}
+
+func UpdateGroup(tx WriteTxn, group *models.Group) error {
+	if err := update(tx, (*groupsTable)(group)); err != nil {
+		return err
+	}
+	return createGroupWebhookDeliveries(tx, groupUpdatedEvent, group)
 }
 
 type GetGroupOptions struct {
@@ -75,25 +86,51 @@ type ListGroupsOptions struct {
 	// ByGroupMember instructs ListGroups to return groups where this user ID
 	// is a member of the group.
 	ByGroupMember uid.ID
+	// IncludeNestedGroups instructs ListGroups to also return the groups that
+	// contain the groups of ByGroupMember, at any level of nesting. It can
+	// only be used with ByGroupMember.
+	IncludeNestedGroups bool
+
+	// ByNestedInGroup instructs ListGroups to return the groups that are
+	// members of this group, at any level of nesting.
+	ByNestedInGroup uid.ID
+
+	// ByCreatedByProvider instructs ListGroups to return groups that were
+	// created by this provider.
+	ByCreatedByProvider uid.ID
 
 	Pagination *Pagination
+	// SCIMParameters filters and paginates the groups using SCIM parameters.
+	// It can not be used with Pagination.
+	SCIMParameters *SCIMParameters
 }
 
 func ListGroups(tx ReadTxn, opts ListGroupsOptions) ([]models.Group, error) {
 	table := groupsTable{}
 	query := querybuilder.New("SELECT")
 	query.B(columnsForSelect(table))
-	if opts.Pagination != nil {
+	if opts.Pagination.countRows() || opts.SCIMParameters != nil {
 		query.B(", count(*) OVER()")
 	}
 	query.B("FROM groups")
-	if opts.ByGroupMember != 0 {
+	if opts.ByGroupMember != 0 && !opts.IncludeNestedGroups {
 		query.B("JOIN identities_groups ON groups.id = identities_groups.group_id")
 		query.B("AND identities_groups.identity_id = ?", opts.ByGroupMember)
 	}
 	query.B("WHERE deleted_at is null")
 	query.B("AND organization_id = ?", tx.OrganizationID())
 
+	if opts.ByGroupMember != 0 && opts.IncludeNestedGroups {
+		query.B("AND groups.id IN (")
+		queryGroupIDsForUser(query, opts.ByGroupMember)
+		query.B(")")
+	}
+	if opts.ByNestedInGroup != 0 {
+		query.B("AND groups.id IN (")
+		queryNestedGroups(query, opts.ByNestedInGroup, true)
+		query.B("SELECT group_id FROM nested)")
+	}
+
 	if opts.ByName != "" {
 		query.B("AND name = ?", opts.ByName)
 	}
@@ -101,11 +138,37 @@ func ListGroups(tx ReadTxn, opts ListGroupsOptions) ([]models.Group, error) {
 		query.B("AND groups.id IN")
 		queryInClause(query, opts.ByIDs)
 	}
+	if opts.ByCreatedByProvider != 0 {
+		query.B("AND created_by_provider = ?", opts.ByCreatedByProvider)
+	}
+	if opts.SCIMParameters != nil && opts.SCIMParameters.Filter != nil {
+		query.B("AND (")
+		if err := filterSQL(opts.SCIMParameters.Filter, groupSCIMAttributes, query); err != nil {
+			return nil, fmt.Errorf("%w: apply filter: %v", internal.ErrBadRequest, err)
+		}
+		query.B(")")
+	}
 
-	query.B("ORDER BY name ASC")
+	if opts.Pagination.usesCursor() {
+		if opts.Pagination.After != 0 {
+			query.B("AND groups.id > ?", opts.Pagination.After)
+		}
+		query.B("ORDER BY groups.id ASC")
+	} else if err := sortSQL(opts.SCIMParameters, groupSCIMAttributes, "name", query); err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	}
 	if opts.Pagination != nil {
 		opts.Pagination.PaginateQuery(query)
 	}
+	if opts.SCIMParameters != nil {
+		if opts.SCIMParameters.Count != 0 {
+			query.B("LIMIT ?", opts.SCIMParameters.Count)
+		}
+		if opts.SCIMParameters.StartIndex > 0 {
+			offset := opts.SCIMParameters.StartIndex - 1 // start index begins at 1, not 0
+			query.B("OFFSET ?", offset)
+		}
+	}
 
 	rows, err := tx.Query(query.String(), query.Args...)
 	if err != nil {
@@ -113,14 +176,23 @@ func ListGroups(tx ReadTxn, opts ListGroupsOptions) ([]models.Group, error) {
 	}
 	result, err := scanRows(rows, func(group *models.Group) []any {
 		fields := (*groupsTable)(group).ScanFields()
-		if opts.Pagination != nil {
+		switch {
+		case opts.Pagination.countRows():
 			fields = append(fields, &opts.Pagination.TotalCount)
+		case opts.SCIMParameters != nil:
+			fields = append(fields, &opts.SCIMParameters.TotalCount)
 		}
 		return fields
 	})
 	if err != nil {
 		return nil, err
 	}
+	if opts.SCIMParameters != nil && opts.SCIMParameters.Count == 0 {
+		opts.SCIMParameters.Count = opts.SCIMParameters.TotalCount
+	}
+	setNextCursor(opts.Pagination, result, func(group models.Group) uid.ID {
+		return group.ID
+	})
 
 	// TODO: do this in a single query
 	for i := range result {
@@ -133,9 +205,46 @@ func ListGroups(tx ReadTxn, opts ListGroupsOptions) ([]models.Group, error) {
 	return result, nil
 }
 
+// MaxGroupNestingDepth is the maximum number of levels of groups that can be
+// nested in a group.
+const MaxGroupNestingDepth = 5
+
+// queryGroupIDsForUser adds a query for the IDs of the groups where the user is
+// a member, either directly, or by being a member of a group nested in the group.
+func queryGroupIDsForUser(query *querybuilder.Query, userID uid.ID) {
+	query.B(`WITH RECURSIVE member_of(group_id, depth) AS (`)
+	query.B(`SELECT group_id, 0 FROM identities_groups WHERE identity_id = ?`, userID)
+	query.B(`UNION`)
+	query.B(`SELECT groups_groups.group_id, member_of.depth + 1`)
+	query.B(`FROM groups_groups JOIN member_of ON groups_groups.member_group_id = member_of.group_id`)
+	query.B(`WHERE member_of.depth < ?`, MaxGroupNestingDepth)
+	query.B(`) SELECT DISTINCT group_id FROM member_of`)
+}
+
+// queryNestedGroups adds a common table expression named nested with the
+// groups nested in groupID when members is true, or the groups that groupID is
+// nested in when members is false. The depth of each group is the number of
+// levels of nesting between the group and groupID.
+func queryNestedGroups(query *querybuilder.Query, groupID uid.ID, members bool) {
+	from, to := "group_id", "member_group_id"
+	if !members {
+		from, to = to, from
+	}
+	query.B(`WITH RECURSIVE nested(group_id, depth) AS (`)
+	query.B(`SELECT `+to+`, 1 FROM groups_groups WHERE `+from+` = ?`, groupID)
+	query.B(`UNION`)
+	query.B(`SELECT groups_groups.` + to + `, nested.depth + 1`)
+	query.B(`FROM groups_groups JOIN nested ON groups_groups.` + from + ` = nested.group_id`)
+	// allow one extra level so that exceeding the limit can be detected
+	query.B(`WHERE nested.depth <= ?)`, MaxGroupNestingDepth)
+}
+
+// ListGroupIDsForUser returns the IDs of the groups where the user is a
+// member, including the groups that contain those groups.
 func ListGroupIDsForUser(tx ReadTxn, userID uid.ID) ([]uid.ID, error) {
-	stmt := `SELECT DISTINCT group_id FROM identities_groups WHERE identity_id = ?`
-	rows, err := tx.Query(stmt, userID)
+	query := querybuilder.New("")
+	queryGroupIDsForUser(query, userID)
+	rows, err := tx.Query(query.String(), query.Args...)
 	if err != nil {
 		return nil, err
 	}
@@ -163,14 +272,35 @@ func DeleteGroup(tx WriteTxn, id uid.ID) error {
 		return fmt.Errorf("remove users from group: %w", err)
 	}
 
-	stmt := `
-		UPDATE groups
-		SET deleted_at = ?
-		WHERE id = ?
-		AND deleted_at is null
-		AND organization_id = ?`
-	_, err = tx.Exec(stmt, time.Now(), id, tx.OrganizationID())
-	return handleError(err)
+	_, err = tx.Exec(`DELETE from groups_groups WHERE group_id = ? OR member_group_id = ?`, id, id)
+	if err != nil {
+		return fmt.Errorf("remove nested groups: %w", err)
+	}
+
+	table := &groupsTable{}
+	query := querybuilder.New("UPDATE groups")
+	query.B("SET deleted_at = ?", time.Now())
+	query.B("WHERE id = ?", id)
+	query.B("AND deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("RETURNING")
+	query.B(columnsForSelect(table))
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return handleError(err)
+	}
+	deleted, err := scanRows(rows, func(group *models.Group) []any {
+		return (*groupsTable)(group).ScanFields()
+	})
+	if err != nil {
+		return handleError(err)
+	}
+	for i := range deleted {
+		if err := createGroupWebhookDeliveries(tx, groupDeletedEvent, &deleted[i]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func AddUsersToGroup(tx WriteTxn, groupID uid.ID, idsToAdd []uid.ID) error {
@@ -184,8 +314,10 @@ func AddUsersToGroup(tx WriteTxn, groupID uid.ID, idsToAdd []uid.ID) error {
 	}
 	query.B("ON CONFLICT DO NOTHING")
 
-	_, err := tx.Exec(query.String(), query.Args...)
-	return handleError(err)
+	if _, err := tx.Exec(query.String(), query.Args...); err != nil {
+		return handleError(err)
+	}
+	return createGroupUpdatedWebhookDeliveries(tx, groupID)
 }
 
 // RemoveUsersFromGroup removes any user ID listed in idsToRemove from the group
@@ -196,8 +328,92 @@ func RemoveUsersFromGroup(tx WriteTxn, groupID uid.ID, idsToRemove []uid.ID) err
 	query.B(`WHERE group_id = ?`, groupID)
 	query.B(`AND identity_id IN`)
 	queryInClause(query, idsToRemove)
-	_, err := tx.Exec(query.String(), query.Args...)
-	return handleError(err)
+	if _, err := tx.Exec(query.String(), query.Args...); err != nil {
+		return handleError(err)
+	}
+	return createGroupUpdatedWebhookDeliveries(tx, groupID)
+}
+
+// nestedGroupDepths returns the groups nested in groupID when members is true,
+// or the groups that groupID is nested in when members is false, with the
+// maximum number of levels of nesting between each group and groupID.
+func nestedGroupDepths(tx ReadTxn, groupID uid.ID, members bool) (map[uid.ID]int, error) {
+	query := querybuilder.New("")
+	queryNestedGroups(query, groupID, members)
+	query.B("SELECT group_id, max(depth) FROM nested GROUP BY group_id")
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[uid.ID]int{}
+	for rows.Next() {
+		var id uid.ID
+		var depth int
+		if err := rows.Scan(&id, &depth); err != nil {
+			return nil, err
+		}
+		result[id] = depth
+	}
+	return result, rows.Err()
+}
+
+// maxDepth returns the largest depth in depths, or 0 if depths is empty.
+func maxDepth(depths map[uid.ID]int) int {
+	var result int
+	for _, depth := range depths {
+		if depth > result {
+			result = depth
+		}
+	}
+	return result
+}
+
+// AddGroupsToGroup adds each group in idsToAdd as a member of the group with
+// ID groupID. The users of a member group are members of the group. Returns an
+// error if adding a group would create a cycle, or would nest groups deeper
+// than MaxGroupNestingDepth.
+func AddGroupsToGroup(tx WriteTxn, groupID uid.ID, idsToAdd []uid.ID) error {
+	parents, err := nestedGroupDepths(tx, groupID, false)
+	if err != nil {
+		return fmt.Errorf("list parent groups: %w", err)
+	}
+
+	for _, id := range idsToAdd {
+		if _, isParent := parents[id]; isParent || id == groupID {
+			return fmt.Errorf("%w: group %v can not be a member of itself, or of a group nested in it",
+				internal.ErrBadRequest, id)
+		}
+
+		members, err := nestedGroupDepths(tx, id, true)
+		if err != nil {
+			return fmt.Errorf("list member groups: %w", err)
+		}
+		if depth := maxDepth(parents) + 1 + maxDepth(members); depth > MaxGroupNestingDepth {
+			return fmt.Errorf("%w: groups can not be nested more than %d levels deep",
+				internal.ErrBadRequest, MaxGroupNestingDepth)
+		}
+
+		stmt := `INSERT INTO groups_groups(group_id, member_group_id) VALUES (?, ?) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(stmt, groupID, id); err != nil {
+			return handleError(err)
+		}
+	}
+	return createGroupUpdatedWebhookDeliveries(tx, groupID)
+}
+
+// RemoveGroupsFromGroup removes any group ID listed in idsToRemove from the
+// members of the group with ID groupID.
+func RemoveGroupsFromGroup(tx WriteTxn, groupID uid.ID, idsToRemove []uid.ID) error {
+	query := querybuilder.New(`DELETE FROM groups_groups`)
+	query.B(`WHERE group_id = ?`, groupID)
+	query.B(`AND member_group_id IN`)
+	queryInClause(query, idsToRemove)
+	if _, err := tx.Exec(query.String(), query.Args...); err != nil {
+		return handleError(err)
+	}
+	return createGroupUpdatedWebhookDeliveries(tx, groupID)
 }
 
 func countUsersInGroup(tx ReadTxn, groupID uid.ID) (int64, error) {
@@ -2,11 +2,13 @@ package data
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	gocmp "github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/scim2/filter-parser/v2"
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/internal"
@@ -215,6 +217,41 @@ func TestListGroups(t *testing.T) {
 			}
 			assert.DeepEqual(t, actual, expected, cmpGroupShallow)
 		})
+		t.Run("by created by provider with SCIM filter", func(t *testing.T) {
+			provisioned := models.Group{Name: "Provisioned", CreatedByProvider: 4321}
+			other := models.Group{Name: "Provisioned Other", CreatedByProvider: 4321}
+			createGroups(t, db, &provisioned, &other)
+
+			exp, err := filter.ParseFilter([]byte(`displayName eq "Provisioned"`))
+			assert.NilError(t, err)
+			params := SCIMParameters{Filter: exp}
+			actual, err := ListGroups(db, ListGroupsOptions{
+				ByCreatedByProvider: 4321,
+				SCIMParameters:      &params,
+			})
+			assert.NilError(t, err)
+			expected := []models.Group{
+				{Name: "Provisioned", TotalUsers: 0},
+			}
+			assert.DeepEqual(t, actual, expected, cmpGroupShallow)
+			assert.Equal(t, params.TotalCount, 1)
+			assert.Equal(t, params.Count, 1)
+		})
+	})
+}
+
+func TestUpdateGroup(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		group := &models.Group{Name: "Everyone", CreatedByProvider: 4321}
+		createGroups(t, db, group)
+
+		group.Name = "Everybody"
+		assert.NilError(t, UpdateGroup(db, group))
+
+		actual, err := GetGroup(db, GetGroupOptions{ByID: group.ID})
+		assert.NilError(t, err)
+		assert.Equal(t, actual.Name, "Everybody")
+		assert.Equal(t, actual.CreatedByProvider, uid.ID(4321))
 	})
 }
 
@@ -395,6 +432,88 @@ func TestRemoveUsersFromGroup(t *testing.T) {
 	})
 }
 
+func TestAddGroupsToGroup(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+		sre := models.Group{Name: "sre"}
+		oncall := models.Group{Name: "sre-oncall"}
+		primary := models.Group{Name: "sre-oncall-primary"}
+		other := models.Group{Name: "other"}
+		createGroups(t, tx, &sre, &oncall, &primary, &other)
+
+		user := models.Identity{Name: "oncall@example.com", Groups: []models.Group{primary}}
+		createIdentities(t, tx, &user)
+
+		assert.NilError(t, AddGroupsToGroup(tx, sre.ID, []uid.ID{oncall.ID}))
+		assert.NilError(t, AddGroupsToGroup(tx, oncall.ID, []uid.ID{primary.ID}))
+
+		t.Run("list groups nested in a group", func(t *testing.T) {
+			actual, err := ListGroups(tx, ListGroupsOptions{ByNestedInGroup: sre.ID})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, groupNames(actual), []string{"sre-oncall", "sre-oncall-primary"})
+		})
+
+		t.Run("list groups of a user includes parent groups", func(t *testing.T) {
+			actual, err := ListGroups(tx, ListGroupsOptions{ByGroupMember: user.ID, IncludeNestedGroups: true})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, groupNames(actual), []string{"sre", "sre-oncall", "sre-oncall-primary"})
+
+			actual, err = ListGroups(tx, ListGroupsOptions{ByGroupMember: user.ID})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, groupNames(actual), []string{"sre-oncall-primary"})
+		})
+
+		t.Run("cycles are not allowed", func(t *testing.T) {
+			err := AddGroupsToGroup(tx, primary.ID, []uid.ID{sre.ID})
+			assert.ErrorIs(t, err, internal.ErrBadRequest)
+
+			err = AddGroupsToGroup(tx, sre.ID, []uid.ID{sre.ID})
+			assert.ErrorIs(t, err, internal.ErrBadRequest)
+		})
+
+		t.Run("depth is limited", func(t *testing.T) {
+			parent := other
+			for i := 0; i < MaxGroupNestingDepth-2; i++ {
+				group := models.Group{Name: fmt.Sprintf("level-%d", i)}
+				createGroups(t, tx, &group)
+				assert.NilError(t, AddGroupsToGroup(tx, parent.ID, []uid.ID{group.ID}))
+				parent = group
+			}
+
+			// sre has 2 levels of nested groups, which is one too many
+			err := AddGroupsToGroup(tx, parent.ID, []uid.ID{sre.ID})
+			assert.ErrorIs(t, err, internal.ErrBadRequest)
+
+			assert.NilError(t, AddGroupsToGroup(tx, parent.ID, []uid.ID{primary.ID}))
+		})
+
+		t.Run("remove a nested group", func(t *testing.T) {
+			assert.NilError(t, RemoveGroupsFromGroup(tx, oncall.ID, []uid.ID{primary.ID}))
+
+			actual, err := ListGroups(tx, ListGroupsOptions{ByNestedInGroup: sre.ID})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, groupNames(actual), []string{"sre-oncall"})
+		})
+
+		t.Run("delete group removes nesting", func(t *testing.T) {
+			assert.NilError(t, DeleteGroup(tx, oncall.ID))
+
+			actual, err := ListGroups(tx, ListGroupsOptions{ByNestedInGroup: sre.ID})
+			assert.NilError(t, err)
+			assert.Equal(t, len(actual), 0)
+		})
+	})
+}
+
+func groupNames(groups []models.Group) []string {
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return names
+}
+
 func TestCountAllGroups(t *testing.T) {
 	runDBTests(t, func(t *testing.T, db *DB) {
 		createGroups(t, db,
@@ -49,6 +49,10 @@ func AssignIdentityToGroups(tx WriteTxn, user *models.Identity, provider *models
 		return fmt.Errorf("save: %w", err)
 	}
 
+	// groups the user was added to or removed from, which are updated
+	// for webhook events. Groups created here have a group.created event.
+	var changedGroupIDs []uid.ID
+
 	// remove user from groups
 	if len(groupsToBeRemoved) > 0 {
 		query := querybuilder.New(`DELETE FROM identities_groups`)
@@ -58,9 +62,18 @@ func AssignIdentityToGroups(tx WriteTxn, user *models.Identity, provider *models
 		query.B(`AND name IN`)
 		queryInClause(query, groupsToBeRemoved)
 		query.B(`)`)
-		if _, err := tx.Exec(query.String(), query.Args...); err != nil {
+		query.B(`RETURNING group_id`)
+		rows, err := tx.Query(query.String(), query.Args...)
+		if err != nil {
+			return err
+		}
+		removedIDs, err := scanRows(rows, func(item *uid.ID) []any {
+			return []any{item}
+		})
+		if err != nil {
 			return err
 		}
+		changedGroupIDs = append(changedGroupIDs, removedIDs...)
 		for _, name := range groupsToBeRemoved {
 			for i, g := range user.Groups {
 				if g.Name == name {
@@ -132,19 +145,25 @@ func AssignIdentityToGroups(tx WriteTxn, user *models.Identity, provider *models
 			if err != nil {
 				return fmt.Errorf("insert: %w", handleError(err))
 			}
+			if found {
+				changedGroupIDs = append(changedGroupIDs, groupID)
+			}
 		}
 
 		user.Groups = append(user.Groups, models.Group{Model: models.Model{ID: groupID}, Name: name})
 	}
 
-	return nil
+	return createGroupUpdatedWebhookDeliveries(tx, changedGroupIDs...)
 }
 
 func CreateIdentity(tx WriteTxn, identity *models.Identity) error {
 	if identity.VerificationToken == "" {
 		identity.VerificationToken = generate.MathRandom(10, generate.CharsetAlphaNumeric)
 	}
-	return insert(tx, (*identitiesTable)(identity))
+	if err := insert(tx, (*identitiesTable)(identity)); err != nil {
+		return err
+	}
+	return createIdentityWebhookDeliveries(tx, userCreatedEvent, identity)
 }
 
 type GetIdentityOptions struct {
@@ -241,7 +260,7 @@ func ListIdentities(tx ReadTxn, opts ListIdentityOptions) ([]models.Identity, er
 	identities := &identitiesTable{}
 	query := querybuilder.New("SELECT")
 	query.B(columnsForSelect(identities))
-	if opts.Pagination != nil {
+	if opts.Pagination.countRows() {
 		query.B(", count(*) OVER()")
 	}
 	query.B("FROM")
@@ -273,7 +292,14 @@ func ListIdentities(tx ReadTxn, opts ListIdentityOptions) ([]models.Identity, er
 			queryInClause(query, opts.ByNotIDs)
 		}
 	}
-	query.B("ORDER BY name ASC")
+	if opts.Pagination.usesCursor() {
+		if opts.Pagination.After != 0 {
+			query.B("AND id > ?", opts.Pagination.After)
+		}
+		query.B("ORDER BY id ASC")
+	} else {
+		query.B("ORDER BY name ASC")
+	}
 	if opts.Pagination != nil {
 		opts.Pagination.PaginateQuery(query)
 	}
@@ -284,7 +310,7 @@ func ListIdentities(tx ReadTxn, opts ListIdentityOptions) ([]models.Identity, er
 	}
 	result, err := scanRows(rows, func(identity *models.Identity) []any {
 		fields := (*identitiesTable)(identity).ScanFields()
-		if opts.Pagination != nil {
+		if opts.Pagination.countRows() {
 			fields = append(fields, &opts.Pagination.TotalCount)
 		}
 		return fields
@@ -292,6 +318,9 @@ func ListIdentities(tx ReadTxn, opts ListIdentityOptions) ([]models.Identity, er
 	if err != nil {
 		return nil, err
 	}
+	setNextCursor(opts.Pagination, result, func(identity models.Identity) uid.ID {
+		return identity.ID
+	})
 
 	if len(result) == 0 {
 		// return without attempting pre-loads
@@ -433,6 +462,16 @@ func loadIdentitiesProviders(tx ReadTxn, identities []models.Identity) error {
 }
 
 func UpdateIdentity(tx WriteTxn, identity *models.Identity) error {
+	if err := update(tx, (*identitiesTable)(identity)); err != nil {
+		return err
+	}
+	return createIdentityWebhookDeliveries(tx, userUpdatedEvent, identity)
+}
+
+// UpdateIdentityLastSeenAt saves the identity after its LastSeenAt changed.
+// Unlike UpdateIdentity it does not create a user.updated webhook event,
+// because the identity is updated every time the user logs in or uses the API.
+func UpdateIdentityLastSeenAt(tx WriteTxn, identity *models.Identity) error {
 	return update(tx, (*identitiesTable)(identity))
 }
 
@@ -471,11 +510,18 @@ func DeleteIdentities(tx WriteTxn, opts DeleteIdentitiesOptions) error {
 		queryInClause(query, ids)
 		query.B("AND organization_id = ?", tx.OrganizationID())
 
-		_, err := tx.Exec(query.String(), query.Args...)
-		return err
+		if _, err := tx.Exec(query.String(), query.Args...); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	var deleted []*models.Identity
+	for i := range toDelete {
+		if slice.Contains(ids, toDelete[i].ID) {
+			deleted = append(deleted, &toDelete[i])
+		}
+	}
+	return createIdentityWebhookDeliveries(tx, userDeletedEvent, deleted...)
 }
 
 func deleteReferencesToIdentities(tx WriteTxn, providerID uid.ID, toDelete []models.Identity) (unreferencedIdentityIDs []uid.ID, err error) {
@@ -495,6 +541,9 @@ func deleteReferencesToIdentities(tx WriteTxn, providerID uid.ID, toDelete []mod
 					return nil, fmt.Errorf("delete identity creds: %w", err)
 				}
 			}
+			if err := DeleteWebAuthnCredentials(tx, DeleteWebAuthnCredentialsOptions{ByIdentityID: i.ID}); err != nil {
+				return nil, fmt.Errorf("delete identity webauthn credentials: %w", err)
+			}
 		}
 		if err := DeleteProviderUsers(tx, DeleteProviderUsersOptions{ByIdentityID: i.ID, ByProviderID: providerID}); err != nil {
 			return nil, fmt.Errorf("remove provider user: %w", err)
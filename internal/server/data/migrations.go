@@ -78,6 +78,21 @@ func migrations() []*migrator.Migration {
 		modifyAccessKeysIndex(),
 		moveAllowedDomainsToOrganizationsTable(),
 		updateAccessKeysTimeoutColumn(),
+		addExpiresAtToGrants(),
+		addAccessRequestsTable(),
+		addAuditEventsTable(),
+		addSSHCertificateAuthorityToSettings(),
+		addWebhooksTables(),
+		addMFAColumns(),
+		addWebAuthnTables(),
+		addSAMLProviders(),
+		addLDAPProviders(),
+		addRolesTable(),
+		addGroupsGroupsTable(),
+		addAccessReviewsTables(),
+		addSigningKeysTable(),
+		addTOTPLastCounterColumn(),
+		addSAMLRequestsTable(),
 		// next one here
 	}
 }
@@ -964,3 +979,408 @@ func updateAccessKeysTimeoutColumn() *migrator.Migration {
 		},
 	}
 }
+
+func addExpiresAtToGrants() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-11-28T10:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				ALTER TABLE grants ADD COLUMN IF NOT EXISTS expires_at timestamp with time zone;
+				CREATE INDEX IF NOT EXISTS idx_grants_expires_at ON grants (expires_at) WHERE (deleted_at IS NULL);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addAccessRequestsTable() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-11-29T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS access_requests (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					user_id bigint,
+					privilege text,
+					resource text,
+					duration bigint,
+					reason text,
+					status text,
+					decided_by bigint,
+					decided_at timestamp with time zone,
+					decision_reason text,
+					grant_id bigint,
+					CONSTRAINT access_requests_pkey PRIMARY KEY (id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_access_requests_status ON access_requests (organization_id, status) WHERE (deleted_at IS NULL);
+				CREATE INDEX IF NOT EXISTS idx_access_requests_user_id ON access_requests (organization_id, user_id) WHERE (deleted_at IS NULL);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addAuditEventsTable() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-11-30T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS audit_events (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					actor_id bigint,
+					actor_name text,
+					action text,
+					target_type text,
+					target_id bigint,
+					request_id bigint,
+					before_summary text,
+					after_summary text,
+					CONSTRAINT audit_events_pkey PRIMARY KEY (id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (organization_id, actor_id);
+				CREATE INDEX IF NOT EXISTS idx_audit_events_target_id ON audit_events (organization_id, target_id);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addSSHCertificateAuthorityToSettings() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-01T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				ALTER TABLE settings ADD COLUMN IF NOT EXISTS ssh_ca_private_key text DEFAULT '';
+				ALTER TABLE settings ADD COLUMN IF NOT EXISTS ssh_ca_public_key text DEFAULT '';`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addWebhooksTables() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-02T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS webhooks (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					url text,
+					events text,
+					secret text,
+					created_by bigint,
+					CONSTRAINT webhooks_pkey PRIMARY KEY (id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_webhooks_organization_id ON webhooks (organization_id) WHERE (deleted_at IS NULL);
+
+				CREATE TABLE IF NOT EXISTS webhook_deliveries (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					webhook_id bigint,
+					event_id bigint,
+					event_type text,
+					payload text,
+					status text,
+					attempts bigint DEFAULT 0,
+					next_attempt_at timestamp with time zone,
+					last_attempt_at timestamp with time zone,
+					response_status bigint DEFAULT 0,
+					last_error text,
+					CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at) WHERE (status = 'pending');
+				CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (organization_id, webhook_id);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addMFAColumns() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-05T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				ALTER TABLE credentials ADD COLUMN IF NOT EXISTS totp_secret text DEFAULT '';
+				ALTER TABLE credentials ADD COLUMN IF NOT EXISTS totp_enabled boolean DEFAULT false;
+				ALTER TABLE credentials ADD COLUMN IF NOT EXISTS recovery_codes text DEFAULT '';
+				ALTER TABLE settings ADD COLUMN IF NOT EXISTS require_mfa boolean DEFAULT false;`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addWebAuthnTables() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-06T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS webauthn_credentials (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					identity_id bigint,
+					name text,
+					credential_id text,
+					public_key bytea,
+					sign_count bigint DEFAULT 0,
+					last_used_at timestamp with time zone,
+					CONSTRAINT webauthn_credentials_pkey PRIMARY KEY (id)
+				);
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_webauthn_credentials_credential_id ON webauthn_credentials (organization_id, credential_id) WHERE (deleted_at IS NULL);
+				CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_identity_id ON webauthn_credentials (organization_id, identity_id) WHERE (deleted_at IS NULL);
+
+				CREATE TABLE IF NOT EXISTS webauthn_sessions (
+					id bigint NOT NULL,
+					organization_id bigint,
+					identity_id bigint,
+					kind text,
+					challenge text,
+					rp_id text,
+					expires_at timestamp with time zone,
+					CONSTRAINT webauthn_sessions_pkey PRIMARY KEY (id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_webauthn_sessions_expires_at ON webauthn_sessions (expires_at);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addSAMLProviders() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-08T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS saml_metadata_url text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS saml_metadata text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS saml_name_attribute text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS saml_groups_attribute text DEFAULT '';
+
+				CREATE TABLE IF NOT EXISTS saml_assertions (
+					organization_id bigint NOT NULL,
+					provider_id bigint NOT NULL,
+					assertion_id text NOT NULL,
+					expires_at timestamp with time zone NOT NULL
+				);
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_saml_assertions_assertion_id ON saml_assertions (organization_id, provider_id, assertion_id);
+				CREATE INDEX IF NOT EXISTS idx_saml_assertions_expires_at ON saml_assertions (expires_at);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addLDAPProviders() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-12T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_bind_dn text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_bind_password text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_start_tls boolean DEFAULT false;
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_user_base_dn text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_user_filter text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_user_name_attribute text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_group_base_dn text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_group_filter text DEFAULT '';
+				ALTER TABLE providers ADD COLUMN IF NOT EXISTS ldap_group_name_attribute text DEFAULT '';`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addRolesTable() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-13T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS roles (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					name text,
+					kubernetes_roles text DEFAULT '',
+					infra_roles text DEFAULT '',
+					permissions text DEFAULT '',
+					created_by bigint,
+					CONSTRAINT roles_pkey PRIMARY KEY (id)
+				);
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name ON roles (organization_id, name) WHERE (deleted_at IS NULL);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addGroupsGroupsTable() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-15T10:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS groups_groups (
+					group_id bigint NOT NULL,
+					member_group_id bigint NOT NULL,
+					CONSTRAINT groups_groups_pkey PRIMARY KEY (group_id, member_group_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_groups_groups_member_group_id ON groups_groups (member_group_id);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addAccessReviewsTables() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-19T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS access_reviews (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					name text,
+					destinations text,
+					groups text,
+					status text,
+					created_by bigint,
+					closed_by bigint,
+					closed_at timestamp with time zone,
+					CONSTRAINT access_reviews_pkey PRIMARY KEY (id)
+				);
+
+				CREATE TABLE IF NOT EXISTS access_review_items (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					access_review_id bigint,
+					grant_id bigint,
+					subject text,
+					privilege text,
+					resource text,
+					reviewer_id bigint,
+					decision text,
+					decided_by bigint,
+					decided_at timestamp with time zone,
+					reason text,
+					CONSTRAINT access_review_items_pkey PRIMARY KEY (id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_access_review_items_access_review_id ON access_review_items (organization_id, access_review_id) WHERE (deleted_at IS NULL);
+				CREATE INDEX IF NOT EXISTS idx_access_review_items_reviewer_id ON access_review_items (organization_id, reviewer_id) WHERE (deleted_at IS NULL);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+// addSigningKeysTable moves the JWK from settings to a new table, so that an
+// organization can have more than one key while the key is rotated.
+func addSigningKeysTable() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-12-21T10:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS signing_keys (
+					id bigint NOT NULL,
+					created_at timestamp with time zone,
+					updated_at timestamp with time zone,
+					deleted_at timestamp with time zone,
+					organization_id bigint,
+					key_id text,
+					private_jwk bytea,
+					public_jwk bytea,
+					expires_at timestamp with time zone,
+					CONSTRAINT signing_keys_pkey PRIMARY KEY (id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_signing_keys_organization_id ON signing_keys (organization_id) WHERE (deleted_at IS NULL);`
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+
+			if migrator.HasColumn(tx, "settings", "private_jwk") {
+				stmt = `
+					INSERT INTO signing_keys(id, created_at, updated_at, organization_id, key_id, private_jwk, public_jwk)
+					SELECT id, created_at, updated_at, organization_id,
+						convert_from(public_jwk, 'UTF8')::jsonb->>'kid', private_jwk, public_jwk
+					FROM settings
+					WHERE deleted_at IS NULL AND private_jwk IS NOT NULL;
+
+					ALTER TABLE settings DROP COLUMN IF EXISTS private_jwk;
+					ALTER TABLE settings DROP COLUMN IF EXISTS public_jwk;`
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// addTOTPLastCounterColumn stores the time step of the last TOTP code used by
+// each credential, so that a code can not be used more than once.
+func addTOTPLastCounterColumn() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2023-01-09T10:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `ALTER TABLE credentials ADD COLUMN IF NOT EXISTS totp_last_counter bigint DEFAULT 0;`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addSAMLRequestsTable() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2023-01-10T09:00",
+		Migrate: func(tx migrator.DB) error {
+			stmt := `
+				CREATE TABLE IF NOT EXISTS saml_requests (
+					organization_id bigint NOT NULL,
+					provider_id bigint NOT NULL,
+					request_id text NOT NULL,
+					expires_at timestamp with time zone NOT NULL
+				);
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_saml_requests_request_id ON saml_requests (organization_id, provider_id, request_id);
+				CREATE INDEX IF NOT EXISTS idx_saml_requests_expires_at ON saml_requests (expires_at);`
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
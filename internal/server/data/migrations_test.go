@@ -876,6 +876,116 @@ INSERT INTO providers(id, name) VALUES (12345, 'okta');
 				// schema changes are tested with schema comparison
 			},
 		},
+		{
+			label: testCaseLine(addExpiresAtToGrants().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addAccessRequestsTable().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addAuditEventsTable().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addSSHCertificateAuthorityToSettings().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addWebhooksTables().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addMFAColumns().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addWebAuthnTables().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addSAMLProviders().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addLDAPProviders().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addRolesTable().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addGroupsGroupsTable().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addAccessReviewsTables().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addSigningKeysTable().ID),
+			setup: func(t *testing.T, db WriteTxn) {
+				stmt := `INSERT INTO settings(id, created_at, organization_id, private_jwk, public_jwk) VALUES(2, ?, 2001, ?, ?);`
+				_, err := db.Exec(stmt, time.Now(), []byte("the-private-key"), []byte(`{"kid":"the-kid","kty":"OKP"}`))
+				assert.NilError(t, err)
+			},
+			cleanup: func(t *testing.T, db WriteTxn) {
+				_, err := db.Exec(`DELETE FROM signing_keys WHERE id=2;`)
+				assert.NilError(t, err)
+				_, err = db.Exec(`DELETE FROM settings WHERE id=2;`)
+				assert.NilError(t, err)
+			},
+			expected: func(t *testing.T, db WriteTxn) {
+				var orgID uid.ID
+				var keyID string
+				var privateJWK, publicJWK []byte
+				row := db.QueryRow(`SELECT organization_id, key_id, private_jwk, public_jwk FROM signing_keys WHERE id = 2`)
+				assert.NilError(t, row.Scan(&orgID, &keyID, &privateJWK, &publicJWK))
+
+				assert.Equal(t, orgID, uid.ID(2001))
+				assert.Equal(t, keyID, "the-kid")
+				assert.Equal(t, string(privateJWK), "the-private-key")
+				assert.Equal(t, string(publicJWK), `{"kid":"the-kid","kty":"OKP"}`)
+			},
+		},
+		{
+			label: testCaseLine(addTOTPLastCounterColumn().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine(addSAMLRequestsTable().ID),
+			expected: func(t *testing.T, db WriteTxn) {
+				// schema changes are tested with schema comparison
+			},
+		},
 	}
 
 	ids := make(map[string]struct{}, len(testCases))
@@ -953,6 +1063,8 @@ in ./migrations.go, add a test case to this test, and run the tests again.
 		assert.NilError(t, err)
 		_, err = db.Exec(`DELETE FROM settings where organization_id = ?`, defaultOrganizationID)
 		assert.NilError(t, err)
+		_, err = db.Exec(`DELETE FROM signing_keys where organization_id = ?`, defaultOrganizationID)
+		assert.NilError(t, err)
 
 		data := dumpSchema(t, os.Getenv("POSTGRESQL_CONNECTION"),
 			"--section=data",
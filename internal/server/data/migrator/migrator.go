@@ -101,6 +101,39 @@ func (g *Migrator) Migrate() error {
 	return nil
 }
 
+// Pending returns the IDs of the migrations that have not been applied to the
+// database, in the order they would be run by Migrate. When the initial schema
+// has not been applied Pending returns only the ID of the initial schema.
+// Unlike Migrate, Pending does not change the database.
+func (g *Migrator) Pending() ([]string, error) {
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	if !HasTable(g.tx, "migrations") {
+		return []string{initSchemaMigrationID}, nil
+	}
+
+	initSchema, err := g.mustInitializeSchema()
+	switch {
+	case err != nil:
+		return nil, err
+	case initSchema:
+		return []string{initSchemaMigrationID}, nil
+	}
+
+	var pending []string
+	for _, migration := range g.migrations {
+		migrationRan, err := g.migrationRan(migration)
+		if err != nil {
+			return nil, err
+		}
+		if !migrationRan {
+			pending = append(pending, migration.ID)
+		}
+	}
+	return pending, nil
+}
+
 func (g *Migrator) validate() error {
 	lookup := make(map[string]struct{}, len(g.migrations))
 
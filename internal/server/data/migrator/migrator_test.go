@@ -381,3 +381,24 @@ var DefaultOptions = Options{
 		return nil
 	},
 }
+
+func TestPending(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db DB) {
+		m := New(db, DefaultOptions, migrations)
+		pending, err := m.Pending()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pending, []string{initSchemaMigrationID})
+		assert.Assert(t, !HasTable(db, "migrations"))
+
+		initEmptyMigrations(t, db)
+		pending, err = m.Pending()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pending, []string{"201608301400", "201608301430"})
+
+		assert.NilError(t, m.Migrate())
+		pending, err = New(db, DefaultOptions, extendedMigrations).Pending()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pending, []string{"201807221927"})
+		assert.DeepEqual(t, migrationIDs(t, db), []string{initSchemaMigrationID, "201608301400", "201608301430"})
+	})
+}
@@ -1,12 +1,24 @@
 package data
 
-import "github.com/infrahq/infra/internal/server/data/querybuilder"
+import (
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/uid"
+)
 
 // Internal Pagination Data
 type Pagination struct {
 	Page       int
 	Limit      int
 	TotalCount int
+
+	// Cursor selects keyset pagination instead of Page. The rows are ordered
+	// by ID, only rows with an ID greater than After are returned, and
+	// TotalCount is not counted.
+	Cursor bool
+	After  uid.ID
+	// Next is set to the ID of the last row when Cursor is true and the page is
+	// full. It is used as After to select the next page.
+	Next uid.ID
 }
 
 func (p *Pagination) SetTotalCount(count int) {
@@ -19,9 +31,32 @@ func (p *Pagination) PaginateQuery(query *querybuilder.Query) {
 	if p.Limit == 0 {
 		return
 	}
+	if p.Cursor {
+		query.B("LIMIT ?", p.Limit)
+		return
+	}
 	if p.Page == 0 {
 		p.Page = 1
 	}
 	offset := p.Limit * (p.Page - 1)
 	query.B("LIMIT ? OFFSET ?", p.Limit, offset)
 }
+
+// countRows returns true if the query should count the total number of rows.
+func (p *Pagination) countRows() bool {
+	return p != nil && !p.Cursor
+}
+
+// usesCursor returns true if the query should use keyset pagination.
+func (p *Pagination) usesCursor() bool {
+	return p != nil && p.Cursor
+}
+
+// setNextCursor sets p.Next to the ID of the last item when the page of items
+// is full.
+func setNextCursor[T any](p *Pagination, items []T, id func(item T) uid.ID) {
+	if !p.usesCursor() || p.Limit == 0 || len(items) < p.Limit {
+		return
+	}
+	p.Next = id(items[len(items)-1])
+}
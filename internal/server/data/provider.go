@@ -18,15 +18,15 @@ func (p providersTable) Table() string {
 }
 
 func (p providersTable) Columns() []string {
-	return []string{"auth_url", "client_email", "client_id", "client_secret", "created_at", "created_by", "deleted_at", "domain_admin_email", "id", "kind", "name", "organization_id", "private_key", "scopes", "updated_at", "url"}
+	return []string{"auth_url", "client_email", "client_id", "client_secret", "created_at", "created_by", "deleted_at", "domain_admin_email", "id", "kind", "ldap_bind_dn", "ldap_bind_password", "ldap_group_base_dn", "ldap_group_filter", "ldap_group_name_attribute", "ldap_start_tls", "ldap_user_base_dn", "ldap_user_filter", "ldap_user_name_attribute", "name", "organization_id", "private_key", "saml_groups_attribute", "saml_metadata", "saml_metadata_url", "saml_name_attribute", "scopes", "updated_at", "url"}
 }
 
 func (p providersTable) Values() []any {
-	return []any{p.AuthURL, p.ClientEmail, p.ClientID, p.ClientSecret, p.CreatedAt, p.CreatedBy, p.DeletedAt, p.DomainAdminEmail, p.ID, p.Kind, p.Name, p.OrganizationID, p.PrivateKey, p.Scopes, p.UpdatedAt, p.URL}
+	return []any{p.AuthURL, p.ClientEmail, p.ClientID, p.ClientSecret, p.CreatedAt, p.CreatedBy, p.DeletedAt, p.DomainAdminEmail, p.ID, p.Kind, p.LDAPBindDN, p.LDAPBindPassword, p.LDAPGroupBaseDN, p.LDAPGroupFilter, p.LDAPGroupNameAttribute, p.LDAPStartTLS, p.LDAPUserBaseDN, p.LDAPUserFilter, p.LDAPUserNameAttribute, p.Name, p.OrganizationID, p.PrivateKey, p.SAMLGroupsAttribute, p.SAMLMetadata, p.SAMLMetadataURL, p.SAMLNameAttribute, p.Scopes, p.UpdatedAt, p.URL}
 }
 
 func (p *providersTable) ScanFields() []any {
-	return []any{&p.AuthURL, &p.ClientEmail, &p.ClientID, &p.ClientSecret, &p.CreatedAt, &p.CreatedBy, &p.DeletedAt, &p.DomainAdminEmail, &p.ID, &p.Kind, &p.Name, &p.OrganizationID, &p.PrivateKey, &p.Scopes, &p.UpdatedAt, &p.URL}
+	return []any{&p.AuthURL, &p.ClientEmail, &p.ClientID, &p.ClientSecret, &p.CreatedAt, &p.CreatedBy, &p.DeletedAt, &p.DomainAdminEmail, &p.ID, &p.Kind, &p.LDAPBindDN, &p.LDAPBindPassword, &p.LDAPGroupBaseDN, &p.LDAPGroupFilter, &p.LDAPGroupNameAttribute, &p.LDAPStartTLS, &p.LDAPUserBaseDN, &p.LDAPUserFilter, &p.LDAPUserNameAttribute, &p.Name, &p.OrganizationID, &p.PrivateKey, &p.SAMLGroupsAttribute, &p.SAMLMetadata, &p.SAMLMetadataURL, &p.SAMLNameAttribute, &p.Scopes, &p.UpdatedAt, &p.URL}
 }
 
 func validateProvider(p *models.Provider) error {
@@ -146,6 +146,28 @@ func ListProviders(tx ReadTxn, opts ListProvidersOptions) ([]models.Provider, er
 	})
 }
 
+// ListAllProvidersByKind returns the providers of the kinds from all
+// organizations. It is used by background jobs that run outside of the
+// scope of an organization.
+func ListAllProvidersByKind(tx ReadTxn, kinds ...models.ProviderKind) ([]models.Provider, error) {
+	table := providersTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM providers")
+	query.B("WHERE deleted_at is null")
+	query.B("AND kind IN")
+	queryInClause(query, kinds)
+	query.B("ORDER BY organization_id, id")
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(provider *models.Provider) []any {
+		return (*providersTable)(provider).ScanFields()
+	})
+}
+
 func UpdateProvider(tx WriteTxn, provider *models.Provider) error {
 	if err := validateProvider(provider); err != nil {
 		return err
@@ -248,6 +248,13 @@ func TestListProviders(t *testing.T) {
 			assert.DeepEqual(t, expected, actual, cmpModelByID)
 			assert.Equal(t, page.TotalCount, 3)
 		})
+		t.Run("all organizations by kind", func(t *testing.T) {
+			actual, err := ListAllProvidersByKind(db, models.ProviderKindOkta)
+			assert.NilError(t, err)
+
+			expected := []models.Provider{*providerDev, *providerProd, *otherOrgProvider}
+			assert.DeepEqual(t, expected, actual, cmpModelByID)
+		})
 	})
 }
 
@@ -118,14 +118,25 @@ func UpdateProviderUser(tx WriteTxn, providerUser *models.ProviderUser) error {
 		}
 	}
 
+	stored, err := GetProviderUser(tx, providerUser.ProviderID, providerUser.IdentityID)
+	if err != nil {
+		return fmt.Errorf("get provider user: %w", err)
+	}
+
 	pu := (*providerUserTable)(providerUser)
 	query := querybuilder.New("UPDATE")
 	query.B(pu.Table())
 	query.B("SET")
 	query.B(columnsForUpdate(pu), pu.Values()...)
 	query.B("WHERE provider_id = ? AND identity_id = ?;", providerUser.ProviderID, providerUser.IdentityID)
-	_, err = tx.Exec(query.String(), query.Args...)
-	return handleError(err)
+	if _, err = tx.Exec(query.String(), query.Args...); err != nil {
+		return handleError(err)
+	}
+
+	if stored.Active != providerUser.Active {
+		return createIdentityUpdatedWebhookDeliveries(tx, providerUser.IdentityID)
+	}
+	return nil
 }
 
 type ListProviderUsersOptions struct {
@@ -165,14 +176,16 @@ func ListProviderUsers(tx ReadTxn, opts ListProviderUsersOptions) ([]models.Prov
 	}
 	if opts.SCIMParameters != nil && opts.SCIMParameters.Filter != nil {
 		query.B("AND (")
-		err := filterSQL(opts.SCIMParameters.Filter, query)
+		err := filterSQL(opts.SCIMParameters.Filter, providerUserSCIMAttributes, query)
 		if err != nil {
-			return nil, fmt.Errorf("apply filter: %w", err)
+			return nil, fmt.Errorf("%w: apply filter: %v", internal.ErrBadRequest, err)
 		}
 		query.B(")")
 	}
 
-	query.B("ORDER BY email ASC")
+	if err := sortSQL(opts.SCIMParameters, providerUserSCIMAttributes, "email", query); err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	}
 
 	if opts.SCIMParameters != nil {
 		// apply scim parameters
@@ -226,6 +239,9 @@ func PatchProviderUserActiveStatus(tx WriteTxn, providerUser *models.ProviderUse
 	if err != nil {
 		return nil, handleError(err)
 	}
+	if err := createIdentityUpdatedWebhookDeliveries(tx, pu.IdentityID); err != nil {
+		return nil, err
+	}
 	return (*models.ProviderUser)(pu), nil
 }
 
@@ -272,6 +288,7 @@ func ProvisionProviderUser(tx WriteTxn, user *models.ProviderUser) error {
 		ByName: user.Email,
 	}
 	identity, err := GetIdentity(tx, opts)
+	created := false
 	if err != nil {
 		if !errors.Is(err, internal.ErrNotFound) {
 			return fmt.Errorf("get existing user on provision: %w", err)
@@ -282,6 +299,7 @@ func ProvisionProviderUser(tx WriteTxn, user *models.ProviderUser) error {
 		if err := CreateIdentity(tx, identity); err != nil {
 			return fmt.Errorf("create identity on provision: %w", err)
 		}
+		created = true
 	}
 
 	user.IdentityID = identity.ID
@@ -290,7 +308,14 @@ func ProvisionProviderUser(tx WriteTxn, user *models.ProviderUser) error {
 		return fmt.Errorf("validate provisioning: %w", err)
 	}
 
-	return insert(tx, (*providerUserTable)(user))
+	if err := insert(tx, (*providerUserTable)(user)); err != nil {
+		return err
+	}
+	if created {
+		return nil
+	}
+	// the existing identity is now provisioned by another provider
+	return createIdentityUpdatedWebhookDeliveries(tx, identity.ID)
 }
 
 func SyncProviderUser(ctx context.Context, tx WriteTxn, user *models.Identity, provider *models.Provider, oidcClient providers.OIDCClient) error {
@@ -329,9 +354,38 @@ func SyncProviderUser(ctx context.Context, tx WriteTxn, user *models.Identity, p
 	return nil
 }
 
+type providerUsersSyncLag struct {
+	Kind    string
+	Seconds float64
+}
+
+// ProviderUsersSyncLagByKind returns the number of seconds since the least
+// recently updated provider user was synced, for each kind of provider. Only
+// active provider users with a refresh token are counted, because those are
+// the users that can be synced from the identity provider.
+func ProviderUsersSyncLagByKind(tx ReadTxn) ([]providerUsersSyncLag, error) {
+	rows, err := tx.Query(`
+		SELECT providers.kind, EXTRACT(EPOCH FROM now() - MIN(provider_users.last_update))
+		FROM provider_users
+		INNER JOIN providers ON provider_users.provider_id = providers.id
+		WHERE providers.deleted_at IS NULL
+		AND providers.kind NOT IN ('infra', 'saml', 'ldap')
+		AND provider_users.active
+		AND provider_users.refresh_token <> ''
+		GROUP BY providers.kind`)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(item *providerUsersSyncLag) []any {
+		return []any{&item.Kind, &item.Seconds}
+	})
+}
+
 type SCIMParameters struct {
 	Count      int               // the number of items to return
 	StartIndex int               // the offset to start counting from
 	TotalCount int               // the total number of items that match the query
 	Filter     filter.Expression // a filter to apply to the results
+	SortBy     string            // the attribute to sort the results by
+	SortOrder  string            // the order to sort the results, either ascending or descending
 }
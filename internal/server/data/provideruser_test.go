@@ -360,6 +360,36 @@ func TestPatchProviderUser(t *testing.T) {
 	})
 }
 
+func TestProviderUsersSyncLagByKind(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		okta := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+		assert.NilError(t, CreateProvider(db, okta))
+
+		create := func(t *testing.T, name, refreshToken string, lastUpdate time.Time) {
+			t.Helper()
+			user := &models.Identity{Name: name}
+			assert.NilError(t, CreateIdentity(db, user))
+			pu, err := CreateProviderUser(db, okta, user)
+			assert.NilError(t, err)
+
+			pu.RefreshToken = models.EncryptedAtRest(refreshToken)
+			assert.NilError(t, UpdateProviderUser(db, pu))
+			_, err = db.Exec(`UPDATE provider_users SET last_update = ? WHERE identity_id = ?`, lastUpdate, user.ID)
+			assert.NilError(t, err)
+		}
+		create(t, "recent@example.com", "refresh", time.Now().Add(-time.Minute))
+		create(t, "stale@example.com", "refresh", time.Now().Add(-time.Hour))
+		// users without a refresh token are not synced, so are not counted
+		create(t, "notoken@example.com", "", time.Now().Add(-48*time.Hour))
+
+		results, err := ProviderUsersSyncLagByKind(db)
+		assert.NilError(t, err)
+		assert.Equal(t, len(results), 1)
+		assert.Equal(t, results[0].Kind, "okta")
+		assert.Assert(t, results[0].Seconds >= 3600 && results[0].Seconds < 3700, results[0].Seconds)
+	})
+}
+
 func TestUpdateProviderUser(t *testing.T) {
 	runDBTests(t, func(t *testing.T, db *DB) {
 		t.Run("existing user can be updated", func(t *testing.T) {
@@ -566,6 +596,25 @@ func TestListProviderUsers(t *testing.T) {
 				return provider.ID, &SCIMParameters{Filter: filter}, []models.ProviderUser{pu}, 1
 			},
 		},
+		{
+			name: "userName filter ignores case",
+			setup: func(t *testing.T, tx *Transaction) (providerID uid.ID, p *SCIMParameters, expected []models.ProviderUser, totalCount int) {
+				provider := &models.Provider{
+					Name: "mockta",
+					Kind: models.ProviderKindOkta,
+				}
+
+				err := CreateProvider(tx, provider)
+				assert.NilError(t, err)
+
+				_ = createTestProviderUser(t, tx, provider, "david@example.com")
+				pu := createTestProviderUser(t, tx, provider, "lucy@example.com")
+				filter, err := filter.ParseFilter([]byte(`userName eq "Lucy@Example.com"`))
+				assert.NilError(t, err)
+
+				return provider.ID, &SCIMParameters{Filter: filter}, []models.ProviderUser{pu}, 1
+			},
+		},
 		{
 			name: "present filter",
 			setup: func(t *testing.T, tx *Transaction) (providerID uid.ID, p *SCIMParameters, expected []models.ProviderUser, totalCount int) {
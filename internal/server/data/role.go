@@ -0,0 +1,130 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+type rolesTable models.Role
+
+func (r rolesTable) Table() string {
+	return "roles"
+}
+
+func (r rolesTable) Columns() []string {
+	return []string{"created_at", "created_by", "deleted_at", "id", "infra_roles", "kubernetes_roles", "name", "organization_id", "permissions", "updated_at"}
+}
+
+func (r rolesTable) Values() []any {
+	return []any{r.CreatedAt, r.CreatedBy, r.DeletedAt, r.ID, r.InfraRoles, r.KubernetesRoles, r.Name, r.OrganizationID, r.Permissions, r.UpdatedAt}
+}
+
+func (r *rolesTable) ScanFields() []any {
+	return []any{&r.CreatedAt, &r.CreatedBy, &r.DeletedAt, &r.ID, &r.InfraRoles, &r.KubernetesRoles, &r.Name, &r.OrganizationID, &r.Permissions, &r.UpdatedAt}
+}
+
+func CreateRole(tx WriteTxn, role *models.Role) error {
+	if role.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	return insert(tx, (*rolesTable)(role))
+}
+
+type GetRoleOptions struct {
+	// ByID instructs GetRole to return the role with this ID.
+	ByID uid.ID
+	// ByName instructs GetRole to return the role with this name.
+	ByName string
+}
+
+func GetRole(tx ReadTxn, opts GetRoleOptions) (*models.Role, error) {
+	table := &rolesTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM roles")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+
+	switch {
+	case opts.ByID != 0:
+		query.B("AND id = ?", opts.ByID)
+	case opts.ByName != "":
+		query.B("AND name = ?", opts.ByName)
+	default:
+		return nil, fmt.Errorf("GetRole requires an ID or name")
+	}
+
+	err := tx.QueryRow(query.String(), query.Args...).Scan(table.ScanFields()...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return (*models.Role)(table), nil
+}
+
+type ListRolesOptions struct {
+	// ByName instructs ListRoles to return only the role with this name.
+	ByName string
+
+	Pagination *Pagination
+}
+
+func ListRoles(tx ReadTxn, opts ListRolesOptions) ([]models.Role, error) {
+	table := &rolesTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	if opts.Pagination != nil {
+		query.B(", count(*) OVER()")
+	}
+	query.B("FROM roles")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	if opts.ByName != "" {
+		query.B("AND name = ?", opts.ByName)
+	}
+
+	query.B("ORDER BY name ASC")
+	if opts.Pagination != nil {
+		opts.Pagination.PaginateQuery(query)
+	}
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(role *models.Role) []any {
+		fields := (*rolesTable)(role).ScanFields()
+		if opts.Pagination != nil {
+			fields = append(fields, &opts.Pagination.TotalCount)
+		}
+		return fields
+	})
+}
+
+// DeleteRole deletes the role, and all of the grants of the role. Only grants
+// created after the role are deleted, because a grant of the same privilege
+// from before the role existed refers to another role with the same name, ex:
+// a Kubernetes ClusterRole.
+func DeleteRole(tx WriteTxn, id uid.ID) error {
+	role, err := GetRole(tx, GetRoleOptions{ByID: id})
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+		UPDATE roles SET deleted_at = ?
+		WHERE id = ? AND organization_id = ? AND deleted_at is null
+	`
+	if _, err := tx.Exec(stmt, time.Now(), id, tx.OrganizationID()); err != nil {
+		return handleError(err)
+	}
+
+	opts := DeleteGrantsOptions{ByPrivilege: role.Name, CreatedSince: role.CreatedAt}
+	if err := DeleteGrants(tx, opts); err != nil {
+		return fmt.Errorf("delete grants: %w", err)
+	}
+	return nil
+}
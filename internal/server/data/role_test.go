@@ -0,0 +1,110 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestRoles(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		otherOrg := &models.Organization{Name: "other", Domain: "other.example.org"}
+		assert.NilError(t, CreateOrganization(db, otherOrg))
+
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+		role := &models.Role{
+			Name:            "developer",
+			KubernetesRoles: []string{"edit", "logs"},
+			InfraRoles:      []string{"view"},
+			Permissions:     []string{"connect"},
+			CreatedBy:       uid.ID(100),
+		}
+		assert.NilError(t, CreateRole(tx, role))
+
+		other := &models.Role{Name: "developer", KubernetesRoles: []string{"view"}}
+		assert.NilError(t, CreateRole(tx.WithOrgID(otherOrg.ID), other))
+
+		t.Run("duplicate name", func(t *testing.T) {
+			tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+			err := CreateRole(tx, &models.Role{Name: "developer", InfraRoles: []string{"admin"}})
+			var ucErr UniqueConstraintError
+			assert.Assert(t, errors.As(err, &ucErr))
+			assert.DeepEqual(t, ucErr, UniqueConstraintError{Table: "roles", Column: "name"})
+		})
+
+		t.Run("get", func(t *testing.T) {
+			actual, err := GetRole(tx, GetRoleOptions{ByID: role.ID})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, role, cmpTimeWithDBPrecision)
+
+			actual, err = GetRole(tx, GetRoleOptions{ByName: "developer"})
+			assert.NilError(t, err)
+			assert.Equal(t, actual.ID, role.ID)
+
+			_, err = GetRole(tx, GetRoleOptions{ByID: other.ID})
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+		})
+
+		t.Run("list", func(t *testing.T) {
+			ops := &models.Role{Name: "ops", KubernetesRoles: []string{"cluster-admin"}}
+			assert.NilError(t, CreateRole(tx, ops))
+
+			actual, err := ListRoles(tx, ListRolesOptions{})
+			assert.NilError(t, err)
+			expected := []models.Role{{Model: models.Model{ID: role.ID}}, {Model: models.Model{ID: ops.ID}}}
+			assert.DeepEqual(t, actual, expected, cmpModelByID)
+
+			actual, err = ListRoles(tx, ListRolesOptions{ByName: "ops"})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Role{{Model: models.Model{ID: ops.ID}}}, cmpModelByID)
+		})
+
+		t.Run("delete removes grants of the role", func(t *testing.T) {
+			grant := &models.Grant{Subject: uid.NewIdentityPolymorphicID(uid.ID(200)), Privilege: "developer", Resource: "prod"}
+			assert.NilError(t, CreateGrant(tx, grant))
+			unrelated := &models.Grant{Subject: uid.NewIdentityPolymorphicID(uid.ID(200)), Privilege: "view", Resource: "prod"}
+			assert.NilError(t, CreateGrant(tx, unrelated))
+
+			assert.NilError(t, DeleteRole(tx, role.ID))
+
+			_, err := GetRole(tx, GetRoleOptions{ByID: role.ID})
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+
+			_, err = GetGrant(tx, GetGrantOptions{ByID: grant.ID})
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+			_, err = GetGrant(tx, GetGrantOptions{ByID: unrelated.ID})
+			assert.NilError(t, err)
+
+			// the role with the same name in the other org is not deleted
+			_, err = GetRole(tx.WithOrgID(otherOrg.ID), GetRoleOptions{ByID: other.ID})
+			assert.NilError(t, err)
+		})
+
+		t.Run("delete keeps grants from before the role was created", func(t *testing.T) {
+			// a grant of the Kubernetes ClusterRole named edit
+			clusterRole := &models.Grant{Subject: uid.NewIdentityPolymorphicID(uid.ID(200)), Privilege: "edit", Resource: "prod"}
+			assert.NilError(t, CreateGrant(tx, clusterRole))
+
+			edit := &models.Role{Name: "edit", KubernetesRoles: []string{"edit"}}
+			edit.CreatedAt = clusterRole.CreatedAt.Add(time.Second)
+			assert.NilError(t, CreateRole(tx, edit))
+			grant := &models.Grant{Subject: uid.NewIdentityPolymorphicID(uid.ID(201)), Privilege: "edit", Resource: "prod"}
+			grant.CreatedAt = edit.CreatedAt.Add(time.Second)
+			assert.NilError(t, CreateGrant(tx, grant))
+
+			assert.NilError(t, DeleteRole(tx, edit.ID))
+
+			_, err := GetGrant(tx, GetGrantOptions{ByID: grant.ID})
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+			_, err = GetGrant(tx, GetGrantOptions{ByID: clusterRole.ID})
+			assert.NilError(t, err)
+		})
+	})
+}
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/uid"
+)
+
+var ErrSAMLAssertionReplayed = errors.New("saml assertion has already been used")
+
+// RecordSAMLAssertion stores the ID of an assertion that was used to login, so
+// that the assertion can not be used again before it expires. Returns
+// ErrSAMLAssertionReplayed if the assertion was already used.
+func RecordSAMLAssertion(tx WriteTxn, providerID uid.ID, assertionID string, expiresAt time.Time) error {
+	query := querybuilder.New("INSERT INTO saml_assertions")
+	query.B("(organization_id, provider_id, assertion_id, expires_at)")
+	query.B("VALUES (?, ?, ?, ?)", tx.OrganizationID(), providerID, assertionID, expiresAt)
+	query.B("ON CONFLICT DO NOTHING")
+
+	result, err := tx.Exec(query.String(), query.Args...)
+	if err != nil {
+		return handleError(err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSAMLAssertionReplayed
+	}
+	return nil
+}
+
+func RemoveExpiredSAMLAssertions(tx WriteTxn) error {
+	query := querybuilder.New("DELETE FROM saml_assertions")
+	query.B("WHERE expires_at <= ?", time.Now().UTC())
+
+	_, err := tx.Exec(query.String(), query.Args...)
+	return err
+}
+
+// CreateSAMLRequest stores the ID of an AuthnRequest sent to the identity
+// provider, so that the response can be checked against the requests issued
+// by the server.
+func CreateSAMLRequest(tx WriteTxn, providerID uid.ID, requestID string, expiresAt time.Time) error {
+	query := querybuilder.New("INSERT INTO saml_requests")
+	query.B("(organization_id, provider_id, request_id, expires_at)")
+	query.B("VALUES (?, ?, ?, ?)", tx.OrganizationID(), providerID, requestID, expiresAt)
+
+	_, err := tx.Exec(query.String(), query.Args...)
+	return handleError(err)
+}
+
+// UseSAMLRequest removes the AuthnRequest with requestID, so that only one
+// response can be used for each request. Returns internal.ErrNotFound if the
+// request was not issued by the server, was already used, or has expired.
+func UseSAMLRequest(tx WriteTxn, providerID uid.ID, requestID string) error {
+	query := querybuilder.New("DELETE FROM saml_requests")
+	query.B("WHERE organization_id = ?", tx.OrganizationID())
+	query.B("AND provider_id = ?", providerID)
+	query.B("AND request_id = ?", requestID)
+	query.B("AND expires_at > ?", time.Now().UTC())
+
+	result, err := tx.Exec(query.String(), query.Args...)
+	if err != nil {
+		return handleError(err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: saml request %q", internal.ErrNotFound, requestID)
+	}
+	return nil
+}
+
+func RemoveExpiredSAMLRequests(tx WriteTxn) error {
+	query := querybuilder.New("DELETE FROM saml_requests")
+	query.B("WHERE expires_at <= ?", time.Now().UTC())
+
+	_, err := tx.Exec(query.String(), query.Args...)
+	return err
+}
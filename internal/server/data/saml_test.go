@@ -0,0 +1,78 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestRecordSAMLAssertion(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+		providerID := uid.New()
+		expires := time.Now().Add(time.Minute)
+
+		err := RecordSAMLAssertion(tx, providerID, "_assertion-1", expires)
+		assert.NilError(t, err)
+
+		err = RecordSAMLAssertion(tx, providerID, "_assertion-1", expires)
+		assert.ErrorIs(t, err, ErrSAMLAssertionReplayed)
+
+		// the same ID from another provider is a different assertion
+		err = RecordSAMLAssertion(tx, uid.New(), "_assertion-1", expires)
+		assert.NilError(t, err)
+	})
+}
+
+func TestRemoveExpiredSAMLAssertions(t *testing.T) {
+	tx := setupDB(t)
+	providerID := uid.New()
+
+	err := RecordSAMLAssertion(tx, providerID, "_expired", time.Now().Add(-time.Second))
+	assert.NilError(t, err)
+	err = RecordSAMLAssertion(tx, providerID, "_active", time.Now().Add(time.Minute))
+	assert.NilError(t, err)
+
+	assert.NilError(t, RemoveExpiredSAMLAssertions(tx))
+
+	// the expired assertion was removed, so it can be recorded again
+	err = RecordSAMLAssertion(tx, providerID, "_expired", time.Now().Add(time.Minute))
+	assert.NilError(t, err)
+	err = RecordSAMLAssertion(tx, providerID, "_active", time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, ErrSAMLAssertionReplayed)
+}
+
+func TestUseSAMLRequest(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+		providerID := uid.New()
+
+		err := CreateSAMLRequest(tx, providerID, "_request-1", time.Now().Add(time.Minute))
+		assert.NilError(t, err)
+		err = CreateSAMLRequest(tx, providerID, "_expired", time.Now().Add(-time.Second))
+		assert.NilError(t, err)
+
+		// the request is from another provider
+		err = UseSAMLRequest(tx, uid.New(), "_request-1")
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+
+		err = UseSAMLRequest(tx, providerID, "_request-1")
+		assert.NilError(t, err)
+
+		// a request can only be used once
+		err = UseSAMLRequest(tx, providerID, "_request-1")
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+
+		err = UseSAMLRequest(tx, providerID, "_expired")
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+
+		err = UseSAMLRequest(tx, providerID, "_never-issued")
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+
+		assert.NilError(t, RemoveExpiredSAMLRequests(tx))
+	})
+}
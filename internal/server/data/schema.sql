@@ -106,6 +106,73 @@ CREATE TABLE access_keys (
     organization_id bigint
 );
 
+CREATE TABLE access_requests (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    user_id bigint,
+    privilege text,
+    resource text,
+    duration bigint,
+    reason text,
+    status text,
+    decided_by bigint,
+    decided_at timestamp with time zone,
+    decision_reason text,
+    grant_id bigint
+);
+
+CREATE TABLE access_review_items (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    access_review_id bigint,
+    grant_id bigint,
+    subject text,
+    privilege text,
+    resource text,
+    reviewer_id bigint,
+    decision text,
+    decided_by bigint,
+    decided_at timestamp with time zone,
+    reason text
+);
+
+CREATE TABLE access_reviews (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    destinations text,
+    groups text,
+    status text,
+    created_by bigint,
+    closed_by bigint,
+    closed_at timestamp with time zone
+);
+
+CREATE TABLE audit_events (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    actor_id bigint,
+    actor_name text,
+    action text,
+    target_type text,
+    target_id bigint,
+    request_id bigint,
+    before_summary text,
+    after_summary text
+);
+
 CREATE TABLE credentials (
     id bigint NOT NULL,
     created_at timestamp with time zone,
@@ -114,7 +181,11 @@ CREATE TABLE credentials (
     identity_id bigint,
     password_hash bytea,
     one_time_password boolean,
-    organization_id bigint
+    organization_id bigint,
+    totp_secret text DEFAULT ''::text,
+    totp_enabled boolean DEFAULT false,
+    recovery_codes text DEFAULT ''::text,
+    totp_last_counter bigint DEFAULT 0
 );
 
 CREATE TABLE destinations (
@@ -168,7 +239,8 @@ CREATE TABLE grants (
     resource text,
     created_by bigint,
     organization_id bigint,
-    update_index bigint
+    update_index bigint,
+    expires_at timestamp with time zone
 );
 
 CREATE TABLE groups (
@@ -182,6 +254,11 @@ CREATE TABLE groups (
     organization_id bigint
 );
 
+CREATE TABLE groups_groups (
+    group_id bigint NOT NULL,
+    member_group_id bigint NOT NULL
+);
+
 CREATE TABLE identities (
     id bigint NOT NULL,
     created_at timestamp with time zone,
@@ -250,7 +327,47 @@ CREATE TABLE providers (
     private_key text,
     client_email text,
     domain_admin_email text,
-    organization_id bigint
+    organization_id bigint,
+    saml_metadata_url text DEFAULT ''::text,
+    saml_metadata text DEFAULT ''::text,
+    saml_name_attribute text DEFAULT ''::text,
+    saml_groups_attribute text DEFAULT ''::text,
+    ldap_bind_dn text DEFAULT ''::text,
+    ldap_bind_password text DEFAULT ''::text,
+    ldap_start_tls boolean DEFAULT false,
+    ldap_user_base_dn text DEFAULT ''::text,
+    ldap_user_filter text DEFAULT ''::text,
+    ldap_user_name_attribute text DEFAULT ''::text,
+    ldap_group_base_dn text DEFAULT ''::text,
+    ldap_group_filter text DEFAULT ''::text,
+    ldap_group_name_attribute text DEFAULT ''::text
+);
+
+CREATE TABLE roles (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    kubernetes_roles text DEFAULT ''::text,
+    infra_roles text DEFAULT ''::text,
+    permissions text DEFAULT ''::text,
+    created_by bigint
+);
+
+CREATE TABLE saml_assertions (
+    organization_id bigint NOT NULL,
+    provider_id bigint NOT NULL,
+    assertion_id text NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+CREATE TABLE saml_requests (
+    organization_id bigint NOT NULL,
+    provider_id bigint NOT NULL,
+    request_id text NOT NULL,
+    expires_at timestamp with time zone NOT NULL
 );
 
 CREATE SEQUENCE seq_update_index
@@ -265,19 +382,98 @@ CREATE TABLE settings (
     created_at timestamp with time zone,
     updated_at timestamp with time zone,
     deleted_at timestamp with time zone,
-    private_jwk bytea,
-    public_jwk bytea,
     lowercase_min bigint DEFAULT 0,
     uppercase_min bigint DEFAULT 0,
     number_min bigint DEFAULT 0,
     symbol_min bigint DEFAULT 0,
     length_min bigint DEFAULT 8,
-    organization_id bigint
+    organization_id bigint,
+    ssh_ca_private_key text DEFAULT ''::text,
+    ssh_ca_public_key text DEFAULT ''::text,
+    require_mfa boolean DEFAULT false
+);
+
+CREATE TABLE signing_keys (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    key_id text,
+    private_jwk bytea,
+    public_jwk bytea,
+    expires_at timestamp with time zone
+);
+
+CREATE TABLE webauthn_credentials (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    identity_id bigint,
+    name text,
+    credential_id text,
+    public_key bytea,
+    sign_count bigint DEFAULT 0,
+    last_used_at timestamp with time zone
+);
+
+CREATE TABLE webauthn_sessions (
+    id bigint NOT NULL,
+    organization_id bigint,
+    identity_id bigint,
+    kind text,
+    challenge text,
+    rp_id text,
+    expires_at timestamp with time zone
+);
+
+CREATE TABLE webhook_deliveries (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    webhook_id bigint,
+    event_id bigint,
+    event_type text,
+    payload text,
+    status text,
+    attempts bigint DEFAULT 0,
+    next_attempt_at timestamp with time zone,
+    last_attempt_at timestamp with time zone,
+    response_status bigint DEFAULT 0,
+    last_error text
+);
+
+CREATE TABLE webhooks (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    url text,
+    events text,
+    secret text,
+    created_by bigint
 );
 
 ALTER TABLE ONLY access_keys
     ADD CONSTRAINT access_keys_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY access_requests
+    ADD CONSTRAINT access_requests_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY access_review_items
+    ADD CONSTRAINT access_review_items_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY access_reviews
+    ADD CONSTRAINT access_reviews_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY audit_events
+    ADD CONSTRAINT audit_events_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY credentials
     ADD CONSTRAINT credentials_pkey PRIMARY KEY (id);
 
@@ -293,6 +489,9 @@ ALTER TABLE ONLY grants
 ALTER TABLE ONLY groups
     ADD CONSTRAINT groups_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY groups_groups
+    ADD CONSTRAINT groups_groups_pkey PRIMARY KEY (group_id, member_group_id);
+
 ALTER TABLE ONLY identities_groups
     ADD CONSTRAINT identities_groups_pkey PRIMARY KEY (identity_id, group_id);
 
@@ -311,15 +510,45 @@ ALTER TABLE ONLY provider_users
 ALTER TABLE ONLY providers
     ADD CONSTRAINT providers_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY roles
+    ADD CONSTRAINT roles_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY settings
     ADD CONSTRAINT settings_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY signing_keys
+    ADD CONSTRAINT signing_keys_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY webauthn_credentials
+    ADD CONSTRAINT webauthn_credentials_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY webauthn_sessions
+    ADD CONSTRAINT webauthn_sessions_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+
 CREATE INDEX idx_access_keys_expires_at ON access_keys USING btree (expires_at);
 
 CREATE UNIQUE INDEX idx_access_keys_issued_for_name ON access_keys USING btree (organization_id, issued_for, name) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_access_keys_key_id ON access_keys USING btree (key_id) WHERE (deleted_at IS NULL);
 
+CREATE INDEX idx_access_requests_status ON access_requests USING btree (organization_id, status) WHERE (deleted_at IS NULL);
+
+CREATE INDEX idx_access_requests_user_id ON access_requests USING btree (organization_id, user_id) WHERE (deleted_at IS NULL);
+
+CREATE INDEX idx_access_review_items_access_review_id ON access_review_items USING btree (organization_id, access_review_id) WHERE (deleted_at IS NULL);
+
+CREATE INDEX idx_access_review_items_reviewer_id ON access_review_items USING btree (organization_id, reviewer_id) WHERE (deleted_at IS NULL);
+
+CREATE INDEX idx_audit_events_actor_id ON audit_events USING btree (organization_id, actor_id);
+
+CREATE INDEX idx_audit_events_target_id ON audit_events USING btree (organization_id, target_id);
+
 CREATE UNIQUE INDEX idx_credentials_identity_id ON credentials USING btree (organization_id, identity_id) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_destinations_name ON destinations USING btree (organization_id, name) WHERE (deleted_at IS NULL);
@@ -336,8 +565,12 @@ CREATE UNIQUE INDEX idx_encryption_keys_key_id ON encryption_keys USING btree (k
 
 CREATE UNIQUE INDEX idx_grant_srp ON grants USING btree (organization_id, subject, privilege, resource) WHERE (deleted_at IS NULL);
 
+CREATE INDEX idx_grants_expires_at ON grants USING btree (expires_at) WHERE (deleted_at IS NULL);
+
 CREATE INDEX idx_grants_update_index ON grants USING btree (organization_id, update_index);
 
+CREATE INDEX idx_groups_groups_member_group_id ON groups_groups USING btree (member_group_id);
+
 CREATE UNIQUE INDEX idx_groups_name ON groups USING btree (organization_id, name) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_identities_name ON identities USING btree (organization_id, name) WHERE (deleted_at IS NULL);
@@ -352,6 +585,30 @@ CREATE UNIQUE INDEX idx_password_reset_tokens_token ON password_reset_tokens USI
 
 CREATE UNIQUE INDEX idx_providers_name ON providers USING btree (organization_id, name) WHERE (deleted_at IS NULL);
 
+CREATE UNIQUE INDEX idx_roles_name ON roles USING btree (organization_id, name) WHERE (deleted_at IS NULL);
+
+CREATE UNIQUE INDEX idx_saml_assertions_assertion_id ON saml_assertions USING btree (organization_id, provider_id, assertion_id);
+
+CREATE INDEX idx_saml_assertions_expires_at ON saml_assertions USING btree (expires_at);
+
+CREATE INDEX idx_saml_requests_expires_at ON saml_requests USING btree (expires_at);
+
+CREATE UNIQUE INDEX idx_saml_requests_request_id ON saml_requests USING btree (organization_id, provider_id, request_id);
+
+CREATE INDEX idx_signing_keys_organization_id ON signing_keys USING btree (organization_id) WHERE (deleted_at IS NULL);
+
+CREATE UNIQUE INDEX idx_webauthn_credentials_credential_id ON webauthn_credentials USING btree (organization_id, credential_id) WHERE (deleted_at IS NULL);
+
+CREATE INDEX idx_webauthn_credentials_identity_id ON webauthn_credentials USING btree (organization_id, identity_id) WHERE (deleted_at IS NULL);
+
+CREATE INDEX idx_webauthn_sessions_expires_at ON webauthn_sessions USING btree (expires_at);
+
+CREATE INDEX idx_webhook_deliveries_next_attempt_at ON webhook_deliveries USING btree (next_attempt_at) WHERE (status = 'pending'::text);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries USING btree (organization_id, webhook_id);
+
+CREATE INDEX idx_webhooks_organization_id ON webhooks USING btree (organization_id) WHERE (deleted_at IS NULL);
+
 CREATE UNIQUE INDEX settings_org_id ON settings USING btree (organization_id) WHERE (deleted_at IS NULL);
 
 CREATE TRIGGER grants_notify_trigger AFTER INSERT OR UPDATE ON grants FOR EACH ROW EXECUTE FUNCTION grants_notify();
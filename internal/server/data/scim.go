@@ -2,16 +2,63 @@ package data
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/scim2/filter-parser/v2"
 
 	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/uid"
 )
 
-func filterSQL(e filter.Expression, query *querybuilder.Query) error {
+type scimAttributeType int
+
+const (
+	scimString scimAttributeType = iota
+	scimBoolean
+	scimDateTime
+	// scimReference is an infra ID, which SCIM represents as a string
+	scimReference
+)
+
+// scimAttribute is the database column that stores a SCIM attribute.
+type scimAttribute struct {
+	column string
+	kind   scimAttributeType
+	// caseExact is true for string attributes that are compared with
+	// regard to case. RFC 7644 defaults caseExact to false.
+	caseExact bool
+}
+
+// providerUserSCIMAttributes maps SCIM user attributes to provider_users columns.
+// Keys are lowercase because SCIM attribute names are case-insensitive.
+var providerUserSCIMAttributes = map[string]scimAttribute{
+	"id":                {column: "identity_id", kind: scimReference},
+	"username":          {column: "email", kind: scimString},
+	"email":             {column: "email", kind: scimString},
+	"emails":            {column: "email", kind: scimString},
+	"emails.value":      {column: "email", kind: scimString},
+	"name.givenname":    {column: "given_name", kind: scimString},
+	"name.familyname":   {column: "family_name", kind: scimString},
+	"active":            {column: "active", kind: scimBoolean},
+	"meta.lastmodified": {column: "last_update", kind: scimDateTime},
+}
+
+// groupSCIMAttributes maps SCIM group attributes to groups columns.
+// Keys are lowercase because SCIM attribute names are case-insensitive.
+var groupSCIMAttributes = map[string]scimAttribute{
+	"id":                {column: "id", kind: scimReference},
+	"displayname":       {column: "name", kind: scimString},
+	"meta.created":      {column: "created_at", kind: scimDateTime},
+	"meta.lastmodified": {column: "updated_at", kind: scimDateTime},
+}
+
+// filterSQL adds the SQL for a SCIM filter expression to query. The filter
+// grammar is described in https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.2
+func filterSQL(e filter.Expression, attrs map[string]scimAttribute, query *querybuilder.Query) error {
 	switch v := e.(type) {
 	case *filter.LogicalExpression:
-		err := filterSQL(v.Left, query)
+		err := nestedFilterSQL(v.Left, attrs, query)
 		if err != nil {
 			return fmt.Errorf("left: %w", err)
 		}
@@ -23,17 +70,36 @@ func filterSQL(e filter.Expression, query *querybuilder.Query) error {
 		default:
 			return fmt.Errorf("unsupported operator %q", v.Operator)
 		}
-		err = filterSQL(v.Right, query)
+		err = nestedFilterSQL(v.Right, attrs, query)
 		if err != nil {
 			return fmt.Errorf("right: %w", err)
 		}
 		return nil
+	case *filter.NotExpression:
+		query.B("NOT (")
+		if err := filterSQL(v.Expression, attrs, query); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+		query.B(")")
+		return nil
+	case *filter.ValuePath:
+		// emails[value eq "x"] filters on the sub-attributes of emails
+		subAttrs := scimSubAttributes(attrs, scimAttributeName(v.AttributePath))
+		if len(subAttrs) == 0 {
+			return fmt.Errorf("unsupported filter attribute: %q", v.AttributePath)
+		}
+		query.B("(")
+		if err := filterSQL(v.ValueFilter, subAttrs, query); err != nil {
+			return fmt.Errorf("value path: %w", err)
+		}
+		query.B(")")
+		return nil
 	case *filter.AttributeExpression:
-		err := sqlColumn(v.AttributePath, query)
+		attr, err := lookupSCIMAttribute(v.AttributePath, attrs)
 		if err != nil {
 			return fmt.Errorf("attribute path: %w", err)
 		}
-		err = sqlComparator(v.Operator, v.CompareValue, query)
+		err = sqlComparator(v.Operator, v.CompareValue, attr, query)
 		if err != nil {
 			return fmt.Errorf("attribute comparator: %w", err)
 		}
@@ -42,56 +108,176 @@ func filterSQL(e filter.Expression, query *querybuilder.Query) error {
 	return fmt.Errorf("unable to parse filter, unrecognized format")
 }
 
-// sqlColumns maps SCIM input filters to provider user database columns
-func sqlColumn(a filter.AttributePath, query *querybuilder.Query) error {
-	switch a.String() {
-	case "id":
-		query.B("identity_id")
-	case "userName":
-		query.B("email")
-	case "email":
-		query.B("email")
-	case "name.givenName":
-		query.B("givenName")
-	case "name.familyName":
-		query.B("familyName")
-	case "active":
-		query.B("active")
-	default:
-		return fmt.Errorf("unsupported filter attribute: %q", a)
+// nestedFilterSQL adds parentheses around nested logical expressions, so that
+// the precedence of the parsed filter is preserved.
+func nestedFilterSQL(e filter.Expression, attrs map[string]scimAttribute, query *querybuilder.Query) error {
+	if _, ok := e.(*filter.LogicalExpression); !ok {
+		return filterSQL(e, attrs, query)
 	}
+	query.B("(")
+	if err := filterSQL(e, attrs, query); err != nil {
+		return err
+	}
+	query.B(")")
 	return nil
 }
 
-func sqlComparator(c filter.CompareOperator, compare any, query *querybuilder.Query) error {
+func scimAttributeName(a filter.AttributePath) string {
+	name := strings.ToLower(a.AttributeName)
+	if a.SubAttribute != nil {
+		name += "." + strings.ToLower(*a.SubAttribute)
+	}
+	return name
+}
+
+func lookupSCIMAttribute(a filter.AttributePath, attrs map[string]scimAttribute) (scimAttribute, error) {
+	attr, ok := attrs[scimAttributeName(a)]
+	if !ok {
+		return scimAttribute{}, fmt.Errorf("unsupported filter attribute: %q", a)
+	}
+	return attr, nil
+}
+
+// scimSubAttributes returns the attributes of parent, keyed by the name of the
+// sub-attribute.
+func scimSubAttributes(attrs map[string]scimAttribute, parent string) map[string]scimAttribute {
+	result := make(map[string]scimAttribute)
+	for name, attr := range attrs {
+		if sub := strings.TrimPrefix(name, parent+"."); sub != name {
+			result[sub] = attr
+		}
+	}
+	return result
+}
+
+// sqlComparator adds the comparison of the attribute column with the compare
+// value to query. String attributes that are not case exact are compared
+// without regard to case.
+func sqlComparator(c filter.CompareOperator, compare any, attr scimAttribute, query *querybuilder.Query) error {
 	switch c {
 	case filter.PR:
-		query.B("IS NOT NULL")
+		query.B(attr.column + " IS NOT NULL")
+		return nil
+	case filter.EQ, filter.NE:
+		if compare == nil {
+			query.B(attr.column)
+			if c == filter.EQ {
+				query.B("IS NULL")
+			} else {
+				query.B("IS NOT NULL")
+			}
+			return nil
+		}
+	case filter.SW, filter.CO, filter.EW:
+		if attr.kind != scimString {
+			return fmt.Errorf("unsupported match comparator: %q", c)
+		}
+	case filter.GT, filter.GE, filter.LT, filter.LE:
+		if attr.kind != scimString && attr.kind != scimDateTime {
+			return fmt.Errorf("unsupported comparator: %q", c)
+		}
+	default:
+		return fmt.Errorf("unsupported comparator: %q", c)
+	}
+
+	value, err := scimCompareValue(attr, compare)
+	if err != nil {
+		return err
+	}
+
+	column, param := attr.column, "?"
+	if attr.kind == scimString && !attr.caseExact {
+		column, param = "lower("+attr.column+")", "lower(?)"
+	}
+	query.B(column)
+
+	switch c {
 	case filter.EQ:
-		query.B("= ?", compare)
+		query.B("= "+param, value)
 	case filter.NE:
-		query.B("!= ?", compare)
+		query.B("!= "+param, value)
 	case filter.SW:
-		cmp, ok := compare.(string)
-		if !ok {
-			return fmt.Errorf("unsupported match comparator: %q", c)
-		}
-		query.B("LIKE ?", cmp+"%")
+		query.B("LIKE "+param, escapeLike(value.(string))+"%")
 	case filter.CO:
-		cmp, ok := compare.(string)
-		if !ok {
-			return fmt.Errorf("unsupported match comparator: %q", c)
-		}
-		query.B("LIKE ?", "%"+cmp+"%")
+		query.B("LIKE "+param, "%"+escapeLike(value.(string))+"%")
 	case filter.EW:
-		cmp, ok := compare.(string)
-		if !ok {
-			return fmt.Errorf("unsupported match comparator: %q", c)
+		query.B("LIKE "+param, "%"+escapeLike(value.(string)))
+	case filter.GT:
+		query.B("> "+param, value)
+	case filter.GE:
+		query.B(">= "+param, value)
+	case filter.LT:
+		query.B("< "+param, value)
+	case filter.LE:
+		query.B("<= "+param, value)
+	}
+	return nil
+}
+
+// scimCompareValue converts the value from a filter to the type of the column.
+func scimCompareValue(attr scimAttribute, compare any) (any, error) {
+	switch attr.kind {
+	case scimBoolean:
+		if v, ok := compare.(bool); ok {
+			return v, nil
 		}
-		query.B("LIKE ?", "%"+cmp)
-	case filter.GE, filter.GT, filter.LE, filter.LT:
-		return fmt.Errorf("unsupported comparator: %q", c)
+	case scimString:
+		if v, ok := compare.(string); ok {
+			return v, nil
+		}
+	case scimDateTime:
+		if v, ok := compare.(string); ok {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dateTime %q: %w", v, err)
+			}
+			return t, nil
+		}
+	case scimReference:
+		if v, ok := compare.(string); ok {
+			id, err := uid.Parse([]byte(v))
+			if err != nil {
+				return nil, fmt.Errorf("invalid id %q: %w", v, err)
+			}
+			return id, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid value %v for %v", compare, attr.column)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes the special characters of a LIKE pattern, so that a
+// filter value is matched literally.
+func escapeLike(v string) string {
+	return likeEscaper.Replace(v)
+}
+
+// sortSQL adds the ORDER BY clause for the SCIM sortBy and sortOrder
+// parameters to query. Results are sorted by defaultColumn when sortBy is not
+// set, and to break ties.
+func sortSQL(p *SCIMParameters, attrs map[string]scimAttribute, defaultColumn string, query *querybuilder.Query) error {
+	if p == nil || p.SortBy == "" {
+		query.B("ORDER BY " + defaultColumn + " ASC")
+		return nil
+	}
+
+	path, err := filter.ParseAttrPath([]byte(p.SortBy))
+	if err != nil {
+		return fmt.Errorf("sort by %q: %w", p.SortBy, err)
+	}
+	attr, err := lookupSCIMAttribute(path, attrs)
+	if err != nil {
+		return fmt.Errorf("sort by: %w", err)
 	}
 
+	order := "ASC"
+	if p.SortOrder == "descending" {
+		order = "DESC"
+	}
+	query.B("ORDER BY " + attr.column + " " + order)
+	if attr.column != defaultColumn {
+		query.B(", " + defaultColumn + " ASC")
+	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestFilterParser(t *testing.T) {
@@ -21,9 +23,9 @@ func TestFilterParser(t *testing.T) {
 	testCases := []testCase{
 		{
 			name:          "equality",
-			expression:    "id eq \"a1234\"",
+			expression:    "id eq \"" + uid.ID(1234).String() + "\"",
 			expectedQuery: " identity_id = ? ",
-			expectedArgs:  []any{"a1234"},
+			expectedArgs:  []any{uid.ID(1234)},
 		},
 		{
 			name:          "present",
@@ -33,39 +35,93 @@ func TestFilterParser(t *testing.T) {
 		{
 			name:          "not equal",
 			expression:    "email ne \"hello@example.com\"",
-			expectedQuery: " email != ? ",
+			expectedQuery: " lower(email) != lower(?) ",
 			expectedArgs:  []any{"hello@example.com"},
 		},
 		{
 			name:          "starts with",
 			expression:    "name.givenName sw \"S\"",
-			expectedQuery: " givenName LIKE ? ",
+			expectedQuery: " lower(given_name) LIKE lower(?) ",
 			expectedArgs:  []any{"S%"},
 		},
 		{
 			name:          "contains",
 			expression:    "name.familyName co \"S\"",
-			expectedQuery: " familyName LIKE ? ",
+			expectedQuery: " lower(family_name) LIKE lower(?) ",
 			expectedArgs:  []any{"%S%"},
 		},
 		{
 			name:          "ends with",
 			expression:    "userName ew \"S\"",
-			expectedQuery: " email LIKE ? ",
+			expectedQuery: " lower(email) LIKE lower(?) ",
 			expectedArgs:  []any{"%S"},
 		},
 		{
 			name:          "logical and",
 			expression:    "(email eq \"M\") and (email eq \"W\")",
-			expectedQuery: " email = ? AND email = ? ",
+			expectedQuery: " lower(email) = lower(?) AND lower(email) = lower(?) ",
 			expectedArgs:  []any{"M", "W"},
 		},
 		{
 			name:          "logical or",
 			expression:    "(email eq \"M\") or (email eq \"W\")",
-			expectedQuery: " email = ? OR email = ? ",
+			expectedQuery: " lower(email) = lower(?) OR lower(email) = lower(?) ",
 			expectedArgs:  []any{"M", "W"},
 		},
+		{
+			name:          "case insensitive attribute names",
+			expression:    "USERNAME eq \"M\"",
+			expectedQuery: " lower(email) = lower(?) ",
+			expectedArgs:  []any{"M"},
+		},
+		{
+			name:          "attribute with schema URI",
+			expression:    "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName eq \"M\"",
+			expectedQuery: " lower(given_name) = lower(?) ",
+			expectedArgs:  []any{"M"},
+		},
+		{
+			name:          "boolean",
+			expression:    "active eq false",
+			expectedQuery: " active = ? ",
+			expectedArgs:  []any{false},
+		},
+		{
+			name:          "greater than dateTime",
+			expression:    "meta.lastModified gt \"2022-11-10T10:00:00Z\"",
+			expectedQuery: " last_update > ? ",
+			expectedArgs:  []any{time.Date(2022, 11, 10, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:          "less or equal string",
+			expression:    "userName le \"M\"",
+			expectedQuery: " lower(email) <= lower(?) ",
+			expectedArgs:  []any{"M"},
+		},
+		{
+			name:          "not",
+			expression:    "not (email eq \"M\")",
+			expectedQuery: " NOT ( lower(email) = lower(?) ) ",
+			expectedArgs:  []any{"M"},
+		},
+		{
+			name:          "precedence of nested logical expressions",
+			expression:    "(email eq \"M\" or email eq \"W\") and active eq true",
+			expectedQuery: " ( lower(email) = lower(?) OR lower(email) = lower(?) ) AND active = ? ",
+			expectedArgs:  []any{"M", "W", true},
+		},
+		{
+			name:          "value path",
+			expression:    "emails[value co \"@example.com\"]",
+			expectedQuery: " ( lower(email) LIKE lower(?) ) ",
+			expectedArgs:  []any{"%@example.com%"},
+		},
+		{
+			name:          "escape like pattern",
+			expression:    "userName sw \"a_b%\"",
+			expectedQuery: " lower(email) LIKE lower(?) ",
+			expectedArgs:  []any{`a\_b\%%`},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -73,7 +129,7 @@ func TestFilterParser(t *testing.T) {
 			exp, err := filter.ParseFilter([]byte(tc.expression))
 			assert.NilError(t, err)
 			query := querybuilder.New("")
-			err = filterSQL(exp, query)
+			err = filterSQL(exp, providerUserSCIMAttributes, query)
 			assert.NilError(t, err)
 			assert.Equal(t, query.String(), tc.expectedQuery)
 			if tc.expectedArgs != nil {
@@ -83,6 +139,28 @@ func TestFilterParser(t *testing.T) {
 	}
 }
 
+func TestFilterParser_Groups(t *testing.T) {
+	exp, err := filter.ParseFilter([]byte(`displayName eq "Engineering"`))
+	assert.NilError(t, err)
+	query := querybuilder.New("")
+	err = filterSQL(exp, groupSCIMAttributes, query)
+	assert.NilError(t, err)
+	assert.Equal(t, query.String(), " lower(name) = lower(?) ")
+	assert.DeepEqual(t, query.Args, []any{"Engineering"})
+
+	exp, err = filter.ParseFilter([]byte(`meta.created ge "2022-11-10T10:00:00Z"`))
+	assert.NilError(t, err)
+	query = querybuilder.New("")
+	err = filterSQL(exp, groupSCIMAttributes, query)
+	assert.NilError(t, err)
+	assert.Equal(t, query.String(), " created_at >= ? ")
+
+	exp, err = filter.ParseFilter([]byte(`userName eq "Engineering"`))
+	assert.NilError(t, err)
+	err = filterSQL(exp, groupSCIMAttributes, querybuilder.New(""))
+	assert.ErrorContains(t, err, "unsupported filter attribute")
+}
+
 func TestFilterParserError(t *testing.T) {
 	type testCase struct {
 		name       string
@@ -134,6 +212,26 @@ func TestFilterSQLError(t *testing.T) {
 			expression:     "id lt 123",
 			expectedErrMsg: "unsupported comparator",
 		},
+		{
+			name:           "unsupported match comparator",
+			expression:     "active sw \"t\"",
+			expectedErrMsg: "unsupported match comparator",
+		},
+		{
+			name:           "invalid value type",
+			expression:     "active eq \"yes\"",
+			expectedErrMsg: "invalid value",
+		},
+		{
+			name:           "invalid dateTime",
+			expression:     "meta.lastModified gt \"yesterday\"",
+			expectedErrMsg: "invalid dateTime",
+		},
+		{
+			name:           "unknown value path sub-attribute",
+			expression:     "emails[type eq \"work\"]",
+			expectedErrMsg: "unsupported filter attribute",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -141,7 +239,7 @@ func TestFilterSQLError(t *testing.T) {
 			exp, err := filter.ParseFilter([]byte(tc.expression))
 			assert.NilError(t, err)
 			query := querybuilder.New("")
-			err = filterSQL(exp, query)
+			err = filterSQL(exp, providerUserSCIMAttributes, query)
 			assert.ErrorContains(t, err, tc.expectedErrMsg)
 		})
 	}
@@ -167,7 +265,7 @@ func FuzzFilter_Terminates(f *testing.F) {
 			exp, _ := filter.ParseFilter([]byte(input))
 			if exp != nil {
 				// if an expression can be parsed attempt to build a query on it
-				if err := filterSQL(exp, query); err == nil {
+				if err := filterSQL(exp, providerUserSCIMAttributes, query); err == nil {
 					assert.Assert(t, query.String() != "")
 				}
 			}
@@ -181,3 +279,47 @@ func FuzzFilter_Terminates(f *testing.F) {
 		}
 	})
 }
+
+func TestSortSQL(t *testing.T) {
+	type testCase struct {
+		name          string
+		params        *SCIMParameters
+		expectedQuery string
+		expectedErr   string
+	}
+
+	testCases := []testCase{
+		{
+			name:          "default",
+			expectedQuery: "ORDER BY email ASC",
+		},
+		{
+			name:          "sort by attribute",
+			params:        &SCIMParameters{SortBy: "name.familyName"},
+			expectedQuery: "ORDER BY family_name ASC , email ASC",
+		},
+		{
+			name:          "sort descending",
+			params:        &SCIMParameters{SortBy: "userName", SortOrder: "descending"},
+			expectedQuery: "ORDER BY email DESC",
+		},
+		{
+			name:        "unknown attribute",
+			params:      &SCIMParameters{SortBy: "password"},
+			expectedErr: "unsupported filter attribute",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query := querybuilder.New("")
+			err := sortSQL(tc.params, providerUserSCIMAttributes, "email", query)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, strings.TrimSpace(query.String()), tc.expectedQuery)
+		})
+	}
+}
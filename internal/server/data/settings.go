@@ -1,13 +1,7 @@
 package data
 
 import (
-	"crypto"
-	"crypto/ed25519"
-	"crypto/rand"
-	"encoding/base64"
-
-	"gopkg.in/square/go-jose.v2"
-
+	"github.com/infrahq/infra/internal/openssh"
 	"github.com/infrahq/infra/internal/server/data/querybuilder"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -20,51 +14,35 @@ func (settingsTable) Table() string {
 }
 
 func (s settingsTable) Columns() []string {
-	return []string{"created_at", "deleted_at", "id", "length_min", "lowercase_min", "number_min", "organization_id", "private_jwk", "public_jwk", "symbol_min", "updated_at", "uppercase_min"}
+	return []string{"created_at", "deleted_at", "id", "length_min", "lowercase_min", "number_min", "organization_id", "require_mfa", "ssh_ca_private_key", "ssh_ca_public_key", "symbol_min", "updated_at", "uppercase_min"}
 }
 
 func (s settingsTable) Values() []any {
-	return []any{s.CreatedAt, s.DeletedAt, s.ID, s.LengthMin, s.LowercaseMin, s.NumberMin, s.OrganizationID, s.PrivateJWK, s.PublicJWK, s.SymbolMin, s.UpdatedAt, s.UppercaseMin}
+	return []any{s.CreatedAt, s.DeletedAt, s.ID, s.LengthMin, s.LowercaseMin, s.NumberMin, s.OrganizationID, s.RequireMFA, s.SSHCAPrivateKey, s.SSHCAPublicKey, s.SymbolMin, s.UpdatedAt, s.UppercaseMin}
 }
 
 func (s *settingsTable) ScanFields() []any {
-	return []any{&s.CreatedAt, &s.DeletedAt, &s.ID, &s.LengthMin, &s.LowercaseMin, &s.NumberMin, &s.OrganizationID, &s.PrivateJWK, &s.PublicJWK, &s.SymbolMin, &s.UpdatedAt, &s.UppercaseMin}
+	return []any{&s.CreatedAt, &s.DeletedAt, &s.ID, &s.LengthMin, &s.LowercaseMin, &s.NumberMin, &s.OrganizationID, &s.RequireMFA, &s.SSHCAPrivateKey, &s.SSHCAPublicKey, &s.SymbolMin, &s.UpdatedAt, &s.UppercaseMin}
 }
 
 func createSettings(tx WriteTxn, orgID uid.ID) error {
-	pubkey, seckey, err := ed25519.GenerateKey(rand.Reader)
-	if err != nil {
-		return err
-	}
-
-	sec := jose.JSONWebKey{Key: seckey, KeyID: "", Algorithm: string(jose.ED25519), Use: "sig"}
-
-	thumb, err := sec.Thumbprint(crypto.SHA256)
-	if err != nil {
-		return err
-	}
-
-	sec.KeyID = base64.URLEncoding.EncodeToString(thumb)
-
-	pub := jose.JSONWebKey{Key: pubkey, KeyID: sec.KeyID, Algorithm: string(jose.ED25519), Use: "sig"}
-
-	secs, err := sec.MarshalJSON()
-	if err != nil {
-		return err
-	}
-
-	pubs, err := pub.MarshalJSON()
+	sshCAPrivateKey, sshCAPublicKey, err := openssh.GenerateCertificateAuthority()
 	if err != nil {
 		return err
 	}
 
 	settings := &models.Settings{
 		OrganizationMember: models.OrganizationMember{OrganizationID: orgID},
-		PrivateJWK:         models.EncryptedAtRest(secs),
-		PublicJWK:          pubs,
+		SSHCAPrivateKey:    models.EncryptedAtRest(sshCAPrivateKey),
+		SSHCAPublicKey:     sshCAPublicKey,
 		LengthMin:          8,
 	}
-	return insert(tx, (*settingsTable)(settings))
+	if err := insert(tx, (*settingsTable)(settings)); err != nil {
+		return err
+	}
+
+	_, err = createSigningKey(tx, orgID)
+	return err
 }
 
 func GetSettings(db ReadTxn) (*models.Settings, error) {
@@ -95,3 +73,38 @@ func UpdateSettings(tx WriteTxn, settings *models.Settings) error {
 	}
 	return update(tx, (*settingsTable)(settings))
 }
+
+// GetSSHCertificateAuthority returns the settings of the organization with the
+// SSH certificate authority. Organizations created before SSH destinations
+// were supported do not have a certificate authority, so one is created the
+// first time it is used.
+func GetSSHCertificateAuthority(tx WriteTxn) (*models.Settings, error) {
+	settings, err := GetSettings(tx)
+	if err != nil {
+		return nil, err
+	}
+	if settings.SSHCAPublicKey != "" {
+		return settings, nil
+	}
+
+	privateKey, publicKey, err := openssh.GenerateCertificateAuthority()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := models.EncryptedAtRest(privateKey).Encrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only set the key if it is still empty, so that a concurrent request
+	// can not replace a key that may already have been used.
+	query := querybuilder.New("UPDATE settings")
+	query.B("SET ssh_ca_private_key = ?, ssh_ca_public_key = ?", encrypted, publicKey)
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("AND ssh_ca_public_key = ''")
+	if _, err := tx.Exec(query.String(), query.Args...); err != nil {
+		return nil, handleError(err)
+	}
+	return GetSettings(tx)
+}
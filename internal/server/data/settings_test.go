@@ -17,9 +17,12 @@ func TestCreateSettings(t *testing.T) {
 		settings, err := getSettingsForOrg(db, 145)
 		assert.NilError(t, err)
 		assert.Assert(t, settings.ID != 0)
-		assert.Assert(t, len(settings.PrivateJWK) != 0)
-		assert.Assert(t, len(settings.PublicJWK) != 0)
 		assert.Equal(t, settings.LengthMin, 8)
+
+		key, err := GetActiveSigningKey(txnForTestCase(t, db, 145))
+		assert.NilError(t, err)
+		assert.Assert(t, len(key.PrivateJWK) != 0)
+		assert.Assert(t, len(key.PublicJWK) != 0)
 	})
 }
 func TestGetSettings(t *testing.T) {
@@ -52,7 +55,6 @@ func TestUpdateSettings(t *testing.T) {
 		assert.NilError(t, err)
 
 		updated := *orig // shallow copy
-		updated.PrivateJWK = "primary-key"
 		updated.LengthMin = 2
 		updated.SymbolMin = 3
 
@@ -0,0 +1,187 @@
+package data
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+type signingKeysTable models.SigningKey
+
+func (s signingKeysTable) Table() string {
+	return "signing_keys"
+}
+
+func (s signingKeysTable) Columns() []string {
+	return []string{"created_at", "deleted_at", "expires_at", "id", "key_id", "organization_id", "private_jwk", "public_jwk", "updated_at"}
+}
+
+func (s signingKeysTable) Values() []any {
+	return []any{s.CreatedAt, s.DeletedAt, s.ExpiresAt, s.ID, s.KeyID, s.OrganizationID, s.PrivateJWK, s.PublicJWK, s.UpdatedAt}
+}
+
+func (s *signingKeysTable) ScanFields() []any {
+	return []any{&s.CreatedAt, &s.DeletedAt, &s.ExpiresAt, &s.ID, &s.KeyID, &s.OrganizationID, &s.PrivateJWK, &s.PublicJWK, &s.UpdatedAt}
+}
+
+// SigningKeyRotationInterval is the age of the active signing key at which
+// RotateSigningKeys replaces it with a new key.
+var SigningKeyRotationInterval = 30 * 24 * time.Hour
+
+// SigningKeyOverlap is how long a signing key continues to be published after
+// it is replaced by a newer key. It must be longer than the lifetime of the
+// JWTs signed with the key.
+var SigningKeyOverlap = time.Hour
+
+// newSigningKey generates a new ed25519 key for signing JWTs.
+func newSigningKey(orgID uid.ID) (*models.SigningKey, error) {
+	pubkey, seckey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sec := jose.JSONWebKey{Key: seckey, KeyID: "", Algorithm: string(jose.ED25519), Use: "sig"}
+
+	thumb, err := sec.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	sec.KeyID = base64.URLEncoding.EncodeToString(thumb)
+
+	pub := jose.JSONWebKey{Key: pubkey, KeyID: sec.KeyID, Algorithm: string(jose.ED25519), Use: "sig"}
+
+	secs, err := sec.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	pubs, err := pub.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		OrganizationMember: models.OrganizationMember{OrganizationID: orgID},
+		KeyID:              sec.KeyID,
+		PrivateJWK:         models.EncryptedAtRest(secs),
+		PublicJWK:          pubs,
+	}, nil
+}
+
+func createSigningKey(tx WriteTxn, orgID uid.ID) (*models.SigningKey, error) {
+	key, err := newSigningKey(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := insert(tx, (*signingKeysTable)(key)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetActiveSigningKey returns the key that is used to sign new JWTs for the
+// organization.
+func GetActiveSigningKey(tx ReadTxn) (*models.SigningKey, error) {
+	table := &signingKeysTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM signing_keys")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("AND expires_at is null")
+	query.B("ORDER BY id DESC LIMIT 1")
+
+	err := tx.QueryRow(query.String(), query.Args...).Scan(table.ScanFields()...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return (*models.SigningKey)(table), nil
+}
+
+// ListSigningKeys returns the keys that can be used to verify JWTs issued to
+// the organization. The active key is first, followed by the keys that were
+// replaced by a newer key, but have not yet expired.
+func ListSigningKeys(tx ReadTxn) ([]models.SigningKey, error) {
+	table := &signingKeysTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM signing_keys")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("AND (expires_at is null OR expires_at > ?)", time.Now())
+	query.B("ORDER BY expires_at DESC NULLS FIRST, id DESC")
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(key *models.SigningKey) []any {
+		return (*signingKeysTable)(key).ScanFields()
+	})
+}
+
+// RotateSigningKey creates a new active signing key for the organization. The
+// previous active key continues to be published for SigningKeyOverlap.
+func RotateSigningKey(tx WriteTxn) (*models.SigningKey, error) {
+	query := querybuilder.New("UPDATE signing_keys")
+	query.B("SET expires_at = ?, updated_at = ?", time.Now().Add(SigningKeyOverlap), time.Now())
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("AND expires_at is null")
+	if _, err := tx.Exec(query.String(), query.Args...); err != nil {
+		return nil, handleError(err)
+	}
+
+	return createSigningKey(tx, tx.OrganizationID())
+}
+
+// RotateSigningKeys rotates the signing key of every organization with an
+// active key that is older than SigningKeyRotationInterval, and deletes the
+// keys that have expired.
+func RotateSigningKeys(tx *Transaction) error {
+	query := querybuilder.New("SELECT organization_id FROM signing_keys")
+	query.B("WHERE deleted_at is null")
+	query.B("AND expires_at is null")
+	query.B("GROUP BY organization_id")
+	query.B("HAVING max(created_at) < ?", time.Now().Add(-SigningKeyRotationInterval))
+	query.B("ORDER BY organization_id")
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return err
+	}
+	orgIDs, err := scanRows(rows, func(orgID *uid.ID) []any {
+		return []any{orgID}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, orgID := range orgIDs {
+		if _, err := RotateSigningKey(tx.WithOrgID(orgID)); err != nil {
+			return fmt.Errorf("rotate signing key for org %v: %w", orgID, err)
+		}
+	}
+
+	return deleteExpiredSigningKeys(tx)
+}
+
+func deleteExpiredSigningKeys(tx WriteTxn) error {
+	query := querybuilder.New("UPDATE signing_keys")
+	query.B("SET deleted_at = ?", time.Now())
+	query.B("WHERE deleted_at is null")
+	query.B("AND expires_at <= ?", time.Now())
+
+	_, err := tx.Exec(query.String(), query.Args...)
+	return err
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestSigningKeys(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		org := &models.Organization{Name: "second", Domain: "second.example.com"}
+		assert.NilError(t, CreateOrganization(db, org))
+
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+		otherTx := tx.WithOrgID(org.ID)
+
+		keyIDs := func(t *testing.T, tx ReadTxn) []string {
+			t.Helper()
+			keys, err := ListSigningKeys(tx)
+			assert.NilError(t, err)
+			var result []string
+			for _, key := range keys {
+				result = append(result, key.KeyID)
+			}
+			return result
+		}
+
+		first, err := GetActiveSigningKey(tx)
+		assert.NilError(t, err)
+		assert.Assert(t, first.KeyID != "")
+		assert.DeepEqual(t, keyIDs(t, tx), []string{first.KeyID})
+
+		other, err := GetActiveSigningKey(otherTx)
+		assert.NilError(t, err)
+		assert.Assert(t, other.KeyID != first.KeyID)
+
+		t.Run("sign with the active key", func(t *testing.T) {
+			user := &models.Identity{Name: "alice@example.com"}
+			assert.NilError(t, CreateIdentity(tx, user))
+
+			token, err := CreateIdentityToken(tx, user.ID)
+			assert.NilError(t, err)
+
+			active, err := GetActiveSigningKey(tx)
+			assert.NilError(t, err)
+
+			parsed, err := jwt.ParseSigned(token.Token)
+			assert.NilError(t, err)
+			assert.Equal(t, parsed.Headers[0].KeyID, active.KeyID)
+
+			var pub jose.JSONWebKey
+			assert.NilError(t, pub.UnmarshalJSON(active.PublicJWK))
+			var claims jwt.Claims
+			assert.NilError(t, parsed.Claims(pub, &claims))
+		})
+
+		var second *models.SigningKey
+		t.Run("rotate", func(t *testing.T) {
+			second, err = RotateSigningKey(tx)
+			assert.NilError(t, err)
+			assert.Assert(t, second.KeyID != first.KeyID)
+
+			active, err := GetActiveSigningKey(tx)
+			assert.NilError(t, err)
+			assert.Equal(t, active.KeyID, second.KeyID)
+
+			// the previous key is still published
+			assert.DeepEqual(t, keyIDs(t, tx), []string{second.KeyID, first.KeyID})
+
+			// other organizations are not changed
+			assert.DeepEqual(t, keyIDs(t, otherTx), []string{other.KeyID})
+		})
+
+		t.Run("rotate old keys and delete expired keys", func(t *testing.T) {
+			// the first key has expired
+			_, err := tx.Exec(`UPDATE signing_keys SET expires_at = ? WHERE key_id = ?`,
+				time.Now().Add(-time.Minute), first.KeyID)
+			assert.NilError(t, err)
+			// the key of the other org is old enough to rotate
+			_, err = tx.Exec(`UPDATE signing_keys SET created_at = ? WHERE key_id = ?`,
+				time.Now().Add(-SigningKeyRotationInterval-time.Hour), other.KeyID)
+			assert.NilError(t, err)
+
+			assert.NilError(t, RotateSigningKeys(tx))
+
+			assert.DeepEqual(t, keyIDs(t, tx), []string{second.KeyID})
+
+			otherKeys := keyIDs(t, otherTx)
+			assert.Equal(t, len(otherKeys), 2)
+			assert.Assert(t, otherKeys[0] != other.KeyID)
+			assert.Equal(t, otherKeys[1], other.KeyID)
+
+			var deletedAt time.Time
+			err = tx.QueryRow(`SELECT deleted_at FROM signing_keys WHERE key_id = ?`, first.KeyID).Scan(&deletedAt)
+			assert.NilError(t, err)
+			assert.Assert(t, !deletedAt.IsZero())
+		})
+
+		t.Run("no active key", func(t *testing.T) {
+			noKeys := txnForTestCase(t, db, 12345)
+			_, err := GetActiveSigningKey(noKeys)
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+		})
+	})
+}
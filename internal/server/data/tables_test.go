@@ -15,6 +15,10 @@ import (
 
 var tables = []tabler{
 	accessKeyTable{},
+	accessRequestsTable{},
+	accessReviewItemsTable{},
+	accessReviewsTable{},
+	auditEventsTable{},
 	credentialsTable{},
 	destinationsTable{},
 	encryptionKeysTable{},
@@ -25,7 +29,13 @@ var tables = []tabler{
 	passwordResetToken{},
 	providersTable{},
 	providerUserTable{},
+	rolesTable{},
 	settingsTable{},
+	signingKeysTable{},
+	webAuthnCredentialsTable{},
+	webAuthnSessionsTable{},
+	webhookDeliveriesTable{},
+	webhooksTable{},
 }
 
 type tabler interface {
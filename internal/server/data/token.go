@@ -17,13 +17,13 @@ var signatureAlgorithmFromKeyAlgorithm = map[string]string{
 }
 
 func createJWT(db ReadTxn, identity *models.Identity, groups []string, expires time.Time) (string, error) {
-	settings, err := GetSettings(db)
+	key, err := GetActiveSigningKey(db)
 	if err != nil {
 		return "", err
 	}
 
 	var sec jose.JSONWebKey
-	if err := sec.UnmarshalJSON([]byte(settings.PrivateJWK)); err != nil {
+	if err := sec.UnmarshalJSON([]byte(key.PrivateJWK)); err != nil {
 		return "", err
 	}
 
@@ -66,7 +66,7 @@ func CreateIdentityToken(db ReadTxn, identityID uid.ID) (token *models.Token, er
 		return nil, err
 	}
 
-	identityGroups, err := ListGroups(db, ListGroupsOptions{ByGroupMember: identityID})
+	identityGroups, err := ListGroups(db, ListGroupsOptions{ByGroupMember: identityID, IncludeNestedGroups: true})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,199 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+type webAuthnCredentialsTable models.WebAuthnCredential
+
+func (w webAuthnCredentialsTable) Table() string {
+	return "webauthn_credentials"
+}
+
+func (w webAuthnCredentialsTable) Columns() []string {
+	return []string{"created_at", "credential_id", "deleted_at", "id", "identity_id", "last_used_at", "name", "organization_id", "public_key", "sign_count", "updated_at"}
+}
+
+func (w webAuthnCredentialsTable) Values() []any {
+	return []any{w.CreatedAt, w.CredentialID, w.DeletedAt, w.ID, w.IdentityID, w.LastUsedAt, w.Name, w.OrganizationID, w.PublicKey, w.SignCount, w.UpdatedAt}
+}
+
+func (w *webAuthnCredentialsTable) ScanFields() []any {
+	return []any{&w.CreatedAt, &w.CredentialID, &w.DeletedAt, &w.ID, &w.IdentityID, &w.LastUsedAt, &w.Name, &w.OrganizationID, &w.PublicKey, &w.SignCount, &w.UpdatedAt}
+}
+
+func CreateWebAuthnCredential(tx WriteTxn, credential *models.WebAuthnCredential) error {
+	switch {
+	case credential.IdentityID == 0:
+		return fmt.Errorf("identityID is required")
+	case credential.CredentialID == "":
+		return fmt.Errorf("credentialID is required")
+	case len(credential.PublicKey) == 0:
+		return fmt.Errorf("publicKey is required")
+	}
+	return insert(tx, (*webAuthnCredentialsTable)(credential))
+}
+
+type GetWebAuthnCredentialOptions struct {
+	// ByID instructs GetWebAuthnCredential to return the credential with this ID.
+	ByID uid.ID
+	// ByCredentialID instructs GetWebAuthnCredential to return the credential
+	// with this ID assigned by the authenticator.
+	ByCredentialID string
+}
+
+func GetWebAuthnCredential(tx ReadTxn, opts GetWebAuthnCredentialOptions) (*models.WebAuthnCredential, error) {
+	table := &webAuthnCredentialsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM webauthn_credentials")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+
+	switch {
+	case opts.ByID != 0:
+		query.B("AND id = ?", opts.ByID)
+	case opts.ByCredentialID != "":
+		query.B("AND credential_id = ?", opts.ByCredentialID)
+	default:
+		return nil, fmt.Errorf("GetWebAuthnCredential requires an ID")
+	}
+
+	err := tx.QueryRow(query.String(), query.Args...).Scan(table.ScanFields()...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return (*models.WebAuthnCredential)(table), nil
+}
+
+type ListWebAuthnCredentialsOptions struct {
+	// ByIdentityID instructs ListWebAuthnCredentials to return only the
+	// credentials registered by this user.
+	ByIdentityID uid.ID
+}
+
+func ListWebAuthnCredentials(tx ReadTxn, opts ListWebAuthnCredentialsOptions) ([]models.WebAuthnCredential, error) {
+	table := &webAuthnCredentialsTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM webauthn_credentials")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	if opts.ByIdentityID != 0 {
+		query.B("AND identity_id = ?", opts.ByIdentityID)
+	}
+	query.B("ORDER BY id ASC")
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(credential *models.WebAuthnCredential) []any {
+		return (*webAuthnCredentialsTable)(credential).ScanFields()
+	})
+}
+
+func UpdateWebAuthnCredential(tx WriteTxn, credential *models.WebAuthnCredential) error {
+	return update(tx, (*webAuthnCredentialsTable)(credential))
+}
+
+type DeleteWebAuthnCredentialsOptions struct {
+	// ByID instructs DeleteWebAuthnCredentials to delete the credential with
+	// this ID.
+	ByID uid.ID
+	// ByIdentityID instructs DeleteWebAuthnCredentials to delete the
+	// credentials registered by this user. When used with ByID the credential
+	// is only deleted if it belongs to this user.
+	ByIdentityID uid.ID
+}
+
+func DeleteWebAuthnCredentials(tx WriteTxn, opts DeleteWebAuthnCredentialsOptions) error {
+	if opts.ByID == 0 && opts.ByIdentityID == 0 {
+		return fmt.Errorf("DeleteWebAuthnCredentials requires an ID")
+	}
+
+	query := querybuilder.New("UPDATE webauthn_credentials")
+	query.B("SET deleted_at = ?", time.Now())
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	if opts.ByID != 0 {
+		query.B("AND id = ?", opts.ByID)
+	}
+	if opts.ByIdentityID != 0 {
+		query.B("AND identity_id = ?", opts.ByIdentityID)
+	}
+
+	_, err := tx.Exec(query.String(), query.Args...)
+	return handleError(err)
+}
+
+type webAuthnSessionsTable models.WebAuthnSession
+
+func (w webAuthnSessionsTable) Table() string {
+	return "webauthn_sessions"
+}
+
+func (w webAuthnSessionsTable) Columns() []string {
+	return []string{"challenge", "expires_at", "id", "identity_id", "kind", "organization_id", "rp_id"}
+}
+
+func (w webAuthnSessionsTable) Values() []any {
+	return []any{w.Challenge, w.ExpiresAt, w.ID, w.IdentityID, w.Kind, w.OrganizationID, w.RPID}
+}
+
+func (w *webAuthnSessionsTable) ScanFields() []any {
+	return []any{&w.Challenge, &w.ExpiresAt, &w.ID, &w.IdentityID, &w.Kind, &w.OrganizationID, &w.RPID}
+}
+
+func (w *webAuthnSessionsTable) OnInsert() error {
+	if w.ID == 0 {
+		w.ID = uid.New()
+	}
+	return nil
+}
+
+func CreateWebAuthnSession(tx WriteTxn, session *models.WebAuthnSession) error {
+	switch {
+	case session.Kind == "":
+		return fmt.Errorf("kind is required")
+	case session.Challenge == "":
+		return fmt.Errorf("challenge is required")
+	case session.ExpiresAt.IsZero():
+		return fmt.Errorf("expiresAt is required")
+	}
+	return insert(tx, (*webAuthnSessionsTable)(session))
+}
+
+// ClaimWebAuthnSession deletes the session, and returns it. Returns an error if
+// the session does not exist, is not of this kind, or has expired.
+func ClaimWebAuthnSession(tx WriteTxn, id uid.ID, kind string) (*models.WebAuthnSession, error) {
+	table := &webAuthnSessionsTable{}
+	query := querybuilder.New("DELETE FROM webauthn_sessions")
+	query.B("WHERE id = ? AND organization_id = ? AND kind = ?", id, tx.OrganizationID(), kind)
+	query.B("RETURNING")
+	query.B(columnsForSelect(table))
+
+	err := tx.QueryRow(query.String(), query.Args...).Scan(table.ScanFields()...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	if table.ExpiresAt.Before(time.Now()) {
+		return nil, internal.ErrExpired
+	}
+	return (*models.WebAuthnSession)(table), nil
+}
+
+func RemoveExpiredWebAuthnSessions(tx WriteTxn) error {
+	query := querybuilder.New("DELETE FROM webauthn_sessions")
+	query.B("WHERE expires_at <= ?", time.Now().UTC())
+
+	_, err := tx.Exec(query.String(), query.Args...)
+	return err
+}
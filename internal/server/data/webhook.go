@@ -0,0 +1,453 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data/querybuilder"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+type webhooksTable models.Webhook
+
+func (w webhooksTable) Table() string {
+	return "webhooks"
+}
+
+func (w webhooksTable) Columns() []string {
+	return []string{"created_at", "created_by", "deleted_at", "events", "id", "organization_id", "secret", "updated_at", "url"}
+}
+
+func (w webhooksTable) Values() []any {
+	return []any{w.CreatedAt, w.CreatedBy, w.DeletedAt, w.Events, w.ID, w.OrganizationID, w.Secret, w.UpdatedAt, w.URL}
+}
+
+func (w *webhooksTable) ScanFields() []any {
+	return []any{&w.CreatedAt, &w.CreatedBy, &w.DeletedAt, &w.Events, &w.ID, &w.OrganizationID, &w.Secret, &w.UpdatedAt, &w.URL}
+}
+
+func CreateWebhook(tx WriteTxn, webhook *models.Webhook) error {
+	switch {
+	case webhook.URL == "":
+		return fmt.Errorf("url is required")
+	case webhook.Secret == "":
+		return fmt.Errorf("secret is required")
+	}
+	return insert(tx, (*webhooksTable)(webhook))
+}
+
+type GetWebhookOptions struct {
+	// ByID instructs GetWebhook to return the webhook with this ID.
+	ByID uid.ID
+}
+
+func GetWebhook(tx ReadTxn, opts GetWebhookOptions) (*models.Webhook, error) {
+	if opts.ByID == 0 {
+		return nil, fmt.Errorf("GetWebhook requires an ID")
+	}
+
+	table := &webhooksTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM webhooks")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+	query.B("AND id = ?", opts.ByID)
+
+	err := tx.QueryRow(query.String(), query.Args...).Scan(table.ScanFields()...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return (*models.Webhook)(table), nil
+}
+
+type ListWebhooksOptions struct {
+	Pagination *Pagination
+}
+
+func ListWebhooks(tx ReadTxn, opts ListWebhooksOptions) ([]models.Webhook, error) {
+	table := &webhooksTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	if opts.Pagination != nil {
+		query.B(", count(*) OVER()")
+	}
+	query.B("FROM webhooks")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", tx.OrganizationID())
+
+	query.B("ORDER BY id ASC")
+	if opts.Pagination != nil {
+		opts.Pagination.PaginateQuery(query)
+	}
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(webhook *models.Webhook) []any {
+		fields := (*webhooksTable)(webhook).ScanFields()
+		if opts.Pagination != nil {
+			fields = append(fields, &opts.Pagination.TotalCount)
+		}
+		return fields
+	})
+}
+
+func UpdateWebhook(tx WriteTxn, webhook *models.Webhook) error {
+	return update(tx, (*webhooksTable)(webhook))
+}
+
+// DeleteWebhook deletes the webhook, and any of its deliveries that are still
+// pending.
+func DeleteWebhook(tx WriteTxn, id uid.ID) error {
+	stmt := `
+		UPDATE webhooks SET deleted_at = ?
+		WHERE id = ? AND organization_id = ? AND deleted_at is null
+	`
+	if _, err := tx.Exec(stmt, time.Now(), id, tx.OrganizationID()); err != nil {
+		return handleError(err)
+	}
+
+	stmt = `
+		UPDATE webhook_deliveries SET status = ?, last_error = ?
+		WHERE webhook_id = ? AND organization_id = ? AND status = ?
+	`
+	_, err := tx.Exec(stmt, models.WebhookDeliveryStatusFailed, "webhook was deleted",
+		id, tx.OrganizationID(), models.WebhookDeliveryStatusPending)
+	return handleError(err)
+}
+
+type webhookDeliveriesTable models.WebhookDelivery
+
+func (w webhookDeliveriesTable) Table() string {
+	return "webhook_deliveries"
+}
+
+func (w webhookDeliveriesTable) Columns() []string {
+	return []string{"attempts", "created_at", "deleted_at", "event_id", "event_type", "id", "last_attempt_at", "last_error", "next_attempt_at", "organization_id", "payload", "response_status", "status", "updated_at", "webhook_id"}
+}
+
+func (w webhookDeliveriesTable) Values() []any {
+	return []any{w.Attempts, w.CreatedAt, w.DeletedAt, w.EventID, w.EventType, w.ID, w.LastAttemptAt, w.LastError, w.NextAttemptAt, w.OrganizationID, w.Payload, w.ResponseStatus, w.Status, w.UpdatedAt, w.WebhookID}
+}
+
+func (w *webhookDeliveriesTable) ScanFields() []any {
+	return []any{&w.Attempts, &w.CreatedAt, &w.DeletedAt, &w.EventID, &w.EventType, &w.ID, &w.LastAttemptAt, &w.LastError, &w.NextAttemptAt, &w.OrganizationID, &w.Payload, &w.ResponseStatus, &w.Status, &w.UpdatedAt, &w.WebhookID}
+}
+
+func CreateWebhookDelivery(tx WriteTxn, delivery *models.WebhookDelivery) error {
+	switch {
+	case delivery.WebhookID == 0:
+		return fmt.Errorf("webhookID is required")
+	case delivery.EventType == "":
+		return fmt.Errorf("eventType is required")
+	}
+	if delivery.Status == "" {
+		delivery.Status = models.WebhookDeliveryStatusPending
+	}
+	if delivery.NextAttemptAt.IsZero() {
+		delivery.NextAttemptAt = time.Now()
+	}
+	return insert(tx, (*webhookDeliveriesTable)(delivery))
+}
+
+type ListWebhookDeliveriesOptions struct {
+	// ByWebhookID instructs ListWebhookDeliveries to return only the
+	// deliveries to this webhook.
+	ByWebhookID uid.ID
+	// ByStatus instructs ListWebhookDeliveries to return only the deliveries
+	// with this status.
+	ByStatus models.WebhookDeliveryStatus
+
+	Pagination *Pagination
+}
+
+// ListWebhookDeliveries returns deliveries ordered from newest to oldest.
+func ListWebhookDeliveries(tx ReadTxn, opts ListWebhookDeliveriesOptions) ([]models.WebhookDelivery, error) {
+	table := &webhookDeliveriesTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	if opts.Pagination != nil {
+		query.B(", count(*) OVER()")
+	}
+	query.B("FROM webhook_deliveries")
+	query.B("WHERE organization_id = ?", tx.OrganizationID())
+
+	if opts.ByWebhookID != 0 {
+		query.B("AND webhook_id = ?", opts.ByWebhookID)
+	}
+	if opts.ByStatus != "" {
+		query.B("AND status = ?", opts.ByStatus)
+	}
+
+	query.B("ORDER BY id DESC")
+	if opts.Pagination != nil {
+		opts.Pagination.PaginateQuery(query)
+	}
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(delivery *models.WebhookDelivery) []any {
+		fields := (*webhookDeliveriesTable)(delivery).ScanFields()
+		if opts.Pagination != nil {
+			fields = append(fields, &opts.Pagination.TotalCount)
+		}
+		return fields
+	})
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries, from any
+// organization, that are ready to be attempted. The next attempt of each
+// delivery is moved to claimUntil, so that once the transaction is committed
+// no other server attempts the delivery until the claim expires. Rows locked
+// by another transaction are skipped.
+//
+// The transaction should be committed before attempting the deliveries, and
+// the result of each attempt saved with UpdateWebhookDelivery.
+func ClaimWebhookDeliveries(tx WriteTxn, limit int, claimUntil time.Time) ([]models.WebhookDelivery, error) {
+	table := &webhookDeliveriesTable{}
+	query := querybuilder.New("UPDATE webhook_deliveries")
+	query.B("SET next_attempt_at = ?", claimUntil)
+	query.B("WHERE id IN (")
+	query.B("SELECT id FROM webhook_deliveries")
+	query.B("WHERE status = ?", models.WebhookDeliveryStatusPending)
+	query.B("AND next_attempt_at <= ?", time.Now())
+	query.B("ORDER BY next_attempt_at ASC")
+	query.B("LIMIT ?", limit)
+	query.B("FOR UPDATE SKIP LOCKED")
+	query.B(")")
+	query.B("RETURNING")
+	query.B(columnsForSelect(table))
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows, func(delivery *models.WebhookDelivery) []any {
+		return (*webhookDeliveriesTable)(delivery).ScanFields()
+	})
+}
+
+func UpdateWebhookDelivery(tx WriteTxn, delivery *models.WebhookDelivery) error {
+	return update(tx, (*webhookDeliveriesTable)(delivery))
+}
+
+const (
+	grantCreatedEvent = "grant.created"
+	grantUpdatedEvent = "grant.updated"
+	grantDeletedEvent = "grant.deleted"
+
+	userCreatedEvent = "user.created"
+	userUpdatedEvent = "user.updated"
+	userDeletedEvent = "user.deleted"
+
+	groupCreatedEvent = "group.created"
+	groupUpdatedEvent = "group.updated"
+	groupDeletedEvent = "group.deleted"
+)
+
+// webhookTarget is the user, group, or grant changed by a webhook event.
+// Summary is the API representation of the target, which is used as the
+// Before or After of the event.
+type webhookTarget struct {
+	OrganizationID uid.ID
+	ID             uid.ID
+	Summary        any
+}
+
+// createGrantWebhookDeliveries creates a pending delivery of an event for each
+// of the grants to every webhook subscribed to eventType.
+func createGrantWebhookDeliveries(tx WriteTxn, eventType string, grants []models.Grant) error {
+	targets := make([]webhookTarget, 0, len(grants))
+	for _, grant := range grants {
+		targets = append(targets, webhookTarget{
+			OrganizationID: grant.OrganizationID,
+			ID:             grant.ID,
+			Summary:        grant.ToAPI(),
+		})
+	}
+	return createWebhookDeliveries(tx, eventType, targets)
+}
+
+// createIdentityWebhookDeliveries creates a pending delivery of an event for
+// each of the identities to every webhook subscribed to eventType.
+func createIdentityWebhookDeliveries(tx WriteTxn, eventType string, identities ...*models.Identity) error {
+	targets := make([]webhookTarget, 0, len(identities))
+	for _, identity := range identities {
+		targets = append(targets, webhookTarget{
+			OrganizationID: identity.OrganizationID,
+			ID:             identity.ID,
+			Summary:        identity.ToAPI(),
+		})
+	}
+	return createWebhookDeliveries(tx, eventType, targets)
+}
+
+// createGroupWebhookDeliveries creates a pending delivery of an event for each
+// of the groups to every webhook subscribed to eventType.
+func createGroupWebhookDeliveries(tx WriteTxn, eventType string, groups ...*models.Group) error {
+	targets := make([]webhookTarget, 0, len(groups))
+	for _, group := range groups {
+		targets = append(targets, webhookTarget{
+			OrganizationID: group.OrganizationID,
+			ID:             group.ID,
+			Summary:        group.ToAPI(),
+		})
+	}
+	return createWebhookDeliveries(tx, eventType, targets)
+}
+
+// createGroupUpdatedWebhookDeliveries creates a group.updated event for each
+// of the groups, identified by ID, after their members changed. The groups are
+// only loaded when a webhook is subscribed to the event.
+func createGroupUpdatedWebhookDeliveries(tx WriteTxn, groupIDs ...uid.ID) error {
+	if ok, err := hasSubscribedWebhooks(tx, groupUpdatedEvent); err != nil || !ok {
+		return err
+	}
+
+	groups := make([]*models.Group, 0, len(groupIDs))
+	for _, id := range groupIDs {
+		group, err := GetGroup(tx, GetGroupOptions{ByID: id})
+		if err != nil {
+			return fmt.Errorf("get group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return createGroupWebhookDeliveries(tx, groupUpdatedEvent, groups...)
+}
+
+// createIdentityUpdatedWebhookDeliveries creates a user.updated event for the
+// identity with ID identityID, after its providers changed. The identity is
+// only loaded when a webhook is subscribed to the event.
+func createIdentityUpdatedWebhookDeliveries(tx WriteTxn, identityID uid.ID) error {
+	if ok, err := hasSubscribedWebhooks(tx, userUpdatedEvent); err != nil || !ok {
+		return err
+	}
+
+	identity, err := GetIdentity(tx, GetIdentityOptions{ByID: identityID, LoadProviders: true})
+	if err != nil {
+		return fmt.Errorf("get identity: %w", err)
+	}
+	return createIdentityWebhookDeliveries(tx, userUpdatedEvent, identity)
+}
+
+// hasSubscribedWebhooks returns true if any webhook in the organization of tx
+// is subscribed to eventType.
+func hasSubscribedWebhooks(tx ReadTxn, eventType string) (bool, error) {
+	webhooks, err := listSubscribedWebhooks(tx, tx.OrganizationID(), eventType)
+	if err != nil {
+		return false, fmt.Errorf("list webhooks: %w", err)
+	}
+	return len(webhooks) > 0, nil
+}
+
+// createWebhookDeliveries creates a pending delivery of an event for each of
+// the targets to every webhook subscribed to eventType. User, group, and grant
+// events are created by the data layer, instead of from the audit event of a
+// request, so that changes made by logins, background jobs, config, and
+// provider syncs are also delivered to webhooks. The targets may belong to
+// different organizations.
+func createWebhookDeliveries(tx WriteTxn, eventType string, targets []webhookTarget) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	actorID, actorName := uid.ID(models.CreatedBySystem), ""
+	if t, ok := tx.(interface{ actor() (uid.ID, string) }); ok {
+		if id, name := t.actor(); id != 0 {
+			actorID, actorName = id, name
+		}
+	}
+
+	webhooksByOrg := make(map[uid.ID][]models.Webhook)
+	now := time.Now()
+	for _, target := range targets {
+		webhooks, ok := webhooksByOrg[target.OrganizationID]
+		if !ok {
+			var err error
+			webhooks, err = listSubscribedWebhooks(tx, target.OrganizationID, eventType)
+			if err != nil {
+				return fmt.Errorf("list webhooks: %w", err)
+			}
+			webhooksByOrg[target.OrganizationID] = webhooks
+		}
+		if len(webhooks) == 0 {
+			continue
+		}
+
+		summary, err := json.Marshal(target.Summary)
+		if err != nil {
+			return fmt.Errorf("webhook payload: %w", err)
+		}
+		event := api.WebhookEvent{
+			ID:           uid.New(),
+			Type:         eventType,
+			Created:      api.Time(now),
+			Organization: target.OrganizationID,
+			Actor:        actorID,
+			ActorName:    actorName,
+			Target:       target.ID,
+		}
+		switch eventType {
+		case grantDeletedEvent, userDeletedEvent, groupDeletedEvent:
+			event.Before = summary
+		default:
+			event.After = summary
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("webhook payload: %w", err)
+		}
+
+		for _, webhook := range webhooks {
+			delivery := &models.WebhookDelivery{
+				OrganizationMember: models.OrganizationMember{OrganizationID: target.OrganizationID},
+				WebhookID:          webhook.ID,
+				EventID:            event.ID,
+				EventType:          eventType,
+				Payload:            string(payload),
+			}
+			if err := CreateWebhookDelivery(tx, delivery); err != nil {
+				return fmt.Errorf("create webhook delivery: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// listSubscribedWebhooks returns the webhooks in the organization that are
+// subscribed to eventType.
+func listSubscribedWebhooks(tx ReadTxn, orgID uid.ID, eventType string) ([]models.Webhook, error) {
+	table := &webhooksTable{}
+	query := querybuilder.New("SELECT")
+	query.B(columnsForSelect(table))
+	query.B("FROM webhooks")
+	query.B("WHERE deleted_at is null")
+	query.B("AND organization_id = ?", orgID)
+	query.B("ORDER BY id ASC")
+
+	rows, err := tx.Query(query.String(), query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := scanRows(rows, func(webhook *models.Webhook) []any {
+		return (*webhooksTable)(webhook).ScanFields()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed := webhooks[:0]
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	return subscribed, nil
+}
@@ -0,0 +1,226 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestWebhooks(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		otherOrg := &models.Organization{Name: "other", Domain: "other.example.org"}
+		assert.NilError(t, CreateOrganization(db, otherOrg))
+
+		tx := txnForTestCase(t, db, db.DefaultOrg.ID)
+
+		webhook := &models.Webhook{
+			URL:       "https://hooks.example.com/infra",
+			Events:    []string{"grant.*"},
+			Secret:    "the-secret",
+			CreatedBy: uid.ID(100),
+		}
+		assert.NilError(t, CreateWebhook(tx, webhook))
+
+		other := &models.Webhook{URL: "https://other.example.com", Secret: "other-secret"}
+		assert.NilError(t, CreateWebhook(tx.WithOrgID(otherOrg.ID), other))
+
+		t.Run("get", func(t *testing.T) {
+			actual, err := GetWebhook(tx, GetWebhookOptions{ByID: webhook.ID})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, webhook, cmpTimeWithDBPrecision)
+
+			_, err = GetWebhook(tx, GetWebhookOptions{ByID: other.ID})
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+		})
+
+		t.Run("list", func(t *testing.T) {
+			actual, err := ListWebhooks(tx, ListWebhooksOptions{})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Webhook{{Model: models.Model{ID: webhook.ID}}}, cmpModelByID)
+		})
+
+		t.Run("deliveries", func(t *testing.T) {
+			ready := &models.WebhookDelivery{
+				WebhookID: webhook.ID,
+				EventID:   uid.ID(200),
+				EventType: "grant.created",
+				Payload:   `{}`,
+			}
+			later := &models.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				EventID:       uid.ID(201),
+				EventType:     "grant.deleted",
+				Payload:       `{}`,
+				NextAttemptAt: time.Now().Add(time.Hour),
+			}
+			for _, delivery := range []*models.WebhookDelivery{ready, later} {
+				assert.NilError(t, CreateWebhookDelivery(tx, delivery))
+			}
+			assert.Equal(t, ready.Status, models.WebhookDeliveryStatusPending)
+
+			claimUntil := time.Now().Add(time.Minute)
+			pending, err := ClaimWebhookDeliveries(tx, 10, claimUntil)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, pending, []models.WebhookDelivery{{Model: models.Model{ID: ready.ID}}}, cmpModelByID)
+			assert.Assert(t, pending[0].NextAttemptAt.Round(time.Millisecond).Equal(claimUntil.Round(time.Millisecond)))
+
+			// claimed deliveries are not returned again until the claim expires
+			pending, err = ClaimWebhookDeliveries(tx, 10, claimUntil)
+			assert.NilError(t, err)
+			assert.Equal(t, len(pending), 0)
+
+			ready.Status = models.WebhookDeliveryStatusSucceeded
+			ready.Attempts = 1
+			assert.NilError(t, UpdateWebhookDelivery(tx, ready))
+
+			actual, err := ListWebhookDeliveries(tx, ListWebhookDeliveriesOptions{
+				ByWebhookID: webhook.ID,
+				ByStatus:    models.WebhookDeliveryStatusSucceeded,
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, len(actual), 1)
+			assert.Equal(t, actual[0].ID, ready.ID)
+			assert.Equal(t, actual[0].Attempts, 1)
+		})
+
+		t.Run("grant changes create deliveries", func(t *testing.T) {
+			actorTx := tx.WithActor(uid.ID(300), "admin@example.com")
+			grant := &models.Grant{Subject: uid.NewIdentityPolymorphicID(400), Privilege: "view", Resource: "prod"}
+			assert.NilError(t, CreateGrant(actorTx, grant))
+
+			expired := &models.Grant{
+				Subject:   uid.NewIdentityPolymorphicID(401),
+				Privilege: "view",
+				Resource:  "prod",
+				ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+			}
+			assert.NilError(t, CreateGrant(tx, expired))
+			// background jobs do not have an actor
+			assert.NilError(t, DeleteExpiredGrants(tx))
+
+			deliveries, err := ListWebhookDeliveries(tx, ListWebhookDeliveriesOptions{
+				ByWebhookID: webhook.ID,
+				ByStatus:    models.WebhookDeliveryStatusPending,
+			})
+			assert.NilError(t, err)
+
+			var events []api.WebhookEvent
+			for _, delivery := range deliveries {
+				if delivery.EventID == uid.ID(201) {
+					continue // created by the deliveries test
+				}
+				var event api.WebhookEvent
+				assert.NilError(t, json.Unmarshal([]byte(delivery.Payload), &event))
+				assert.Equal(t, event.ID, delivery.EventID)
+				assert.Equal(t, event.Type, delivery.EventType)
+				events = append(events, event)
+			}
+			// deliveries are listed from newest to oldest
+			assert.Equal(t, len(events), 3)
+			assert.Equal(t, events[0].Type, "grant.deleted")
+			assert.Equal(t, events[0].Target, expired.ID)
+			assert.Equal(t, events[0].Actor, uid.ID(models.CreatedBySystem))
+			assert.Assert(t, len(events[0].Before) > 0)
+			assert.Equal(t, events[1].Type, "grant.created")
+			assert.Equal(t, events[1].Target, expired.ID)
+			assert.Equal(t, events[2].Type, "grant.created")
+			assert.Equal(t, events[2].Target, grant.ID)
+			assert.Equal(t, events[2].Actor, uid.ID(300))
+			assert.Equal(t, events[2].ActorName, "admin@example.com")
+			assert.Assert(t, len(events[2].After) > 0)
+
+			// the other organization's webhook does not receive the events
+			deliveries, err = ListWebhookDeliveries(tx.WithOrgID(otherOrg.ID), ListWebhookDeliveriesOptions{})
+			assert.NilError(t, err)
+			assert.Equal(t, len(deliveries), 0)
+		})
+
+		t.Run("user and group changes create deliveries", func(t *testing.T) {
+			identities := &models.Webhook{
+				URL:    "https://hooks.example.com/identities",
+				Events: []string{"user.*", "group.*"},
+				Secret: "identities-secret",
+			}
+			assert.NilError(t, CreateWebhook(tx, identities))
+
+			provider := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+			assert.NilError(t, CreateProvider(tx, provider))
+
+			user := &models.Identity{Name: "member@example.com"}
+			assert.NilError(t, CreateIdentity(tx, user))
+			_, err := CreateProviderUser(tx, provider, user)
+			assert.NilError(t, err)
+
+			ops := &models.Group{Name: "ops"}
+			assert.NilError(t, CreateGroup(tx, ops))
+
+			// provider syncs add the user to existing and new groups
+			assert.NilError(t, AssignIdentityToGroups(tx, user, provider, []string{"ops", "dev"}))
+			// the last seen time does not create an event
+			user.LastSeenAt = time.Now()
+			assert.NilError(t, UpdateIdentityLastSeenAt(tx, user))
+			assert.NilError(t, DeleteGroup(tx, ops.ID))
+
+			deliveries, err := ListWebhookDeliveries(tx, ListWebhookDeliveriesOptions{
+				ByWebhookID: identities.ID,
+			})
+			assert.NilError(t, err)
+
+			type target struct {
+				Type string
+				ID   uid.ID
+			}
+			var actual []target
+			for _, delivery := range deliveries {
+				var event api.WebhookEvent
+				assert.NilError(t, json.Unmarshal([]byte(delivery.Payload), &event))
+				assert.Equal(t, event.Actor, uid.ID(models.CreatedBySystem))
+				actual = append(actual, target{Type: event.Type, ID: event.Target})
+			}
+
+			dev, err := GetGroup(tx, GetGroupOptions{ByName: "dev"})
+			assert.NilError(t, err)
+
+			// deliveries are listed from newest to oldest
+			expected := []target{
+				{Type: "group.deleted", ID: ops.ID},
+				{Type: "group.updated", ID: ops.ID},
+				{Type: "group.created", ID: dev.ID},
+				{Type: "group.created", ID: ops.ID},
+				{Type: "user.created", ID: user.ID},
+			}
+			assert.DeepEqual(t, actual, expected)
+
+			// the grant webhook is not subscribed to user and group events
+			deliveries, err = ListWebhookDeliveries(tx, ListWebhookDeliveriesOptions{ByWebhookID: webhook.ID})
+			assert.NilError(t, err)
+			for _, delivery := range deliveries {
+				assert.Assert(t, strings.HasPrefix(delivery.EventType, "grant."), delivery.EventType)
+			}
+		})
+
+		t.Run("delete", func(t *testing.T) {
+			assert.NilError(t, DeleteWebhook(tx, webhook.ID))
+
+			_, err := GetWebhook(tx, GetWebhookOptions{ByID: webhook.ID})
+			assert.Assert(t, errors.Is(err, internal.ErrNotFound))
+
+			pending, err := ListWebhookDeliveries(tx, ListWebhookDeliveriesOptions{
+				ByWebhookID: webhook.ID,
+				ByStatus:    models.WebhookDeliveryStatusPending,
+			})
+			assert.NilError(t, err)
+			assert.Equal(t, len(pending), 0)
+		})
+	})
+}
@@ -126,6 +126,7 @@ func TestAPI_UpdateDestination(t *testing.T) {
 		UniqueID: "unique-id",
 	}
 	assert.NilError(t, data.CreateDestination(srv.db, dest))
+	hostKey := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBnhlEzJfX3SaR5FRHqU1Xq0hbrnWcYt9bOKeH8WZHBC"
 
 	type testCase struct {
 		name     string
@@ -172,7 +173,7 @@ func TestAPI_UpdateDestination(t *testing.T) {
 					UniqueID: "unique-id",
 					Connection: api.DestinationConnection{
 						URL: "10.10.10.10:12345",
-						CA:  "the-ca-or-fingerprint",
+						CA:  api.PEM(hostKey),
 					},
 				}
 			},
@@ -184,6 +185,66 @@ func TestAPI_UpdateDestination(t *testing.T) {
 				assert.Equal(t, resp.Code, http.StatusForbidden, (*responseDebug)(resp))
 			},
 		},
+		{
+			name: "rename from a destination the user can not write",
+			body: func(t *testing.T) api.UpdateDestinationRequest {
+				return api.UpdateDestinationRequest{
+					Name:       "new-name",
+					UniqueID:   "unique-id",
+					Connection: api.DestinationConnection{CA: api.PEM(hostKey)},
+				}
+			},
+			setup: func(t *testing.T, req *http.Request) {
+				token, user := createAccessKey(t, srv.db, "new-name-writer@example.com")
+				assert.NilError(t, data.CreateGrant(srv.db, &models.Grant{
+					Subject:   user.PolyID(),
+					Privilege: "destinations:write",
+					Resource:  "new-name",
+				}))
+				req.Header.Set("Authorization", "Bearer "+token)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusForbidden, (*responseDebug)(resp))
+			},
+		},
+		{
+			name: "rename to a destination the user can not write",
+			body: func(t *testing.T) api.UpdateDestinationRequest {
+				return api.UpdateDestinationRequest{
+					Name:       "other-name",
+					UniqueID:   "unique-id",
+					Connection: api.DestinationConnection{CA: api.PEM(hostKey)},
+				}
+			},
+			setup: func(t *testing.T, req *http.Request) {
+				token, user := createAccessKey(t, srv.db, "the-dest-writer@example.com")
+				assert.NilError(t, data.CreateGrant(srv.db, &models.Grant{
+					Subject:   user.PolyID(),
+					Privilege: "destinations:write",
+					Resource:  "the-dest",
+				}))
+				req.Header.Set("Authorization", "Bearer "+token)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusForbidden, (*responseDebug)(resp))
+			},
+		},
+		{
+			name: "invalid ssh connection",
+			body: func(t *testing.T) api.UpdateDestinationRequest {
+				return api.UpdateDestinationRequest{
+					Name:     "the-dest",
+					UniqueID: "unique-id",
+					Connection: api.DestinationConnection{
+						URL: "10.10.10.10:12345\n    ProxyCommand sh",
+						CA:  api.PEM(hostKey),
+					},
+				}
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, (*responseDebug)(resp))
+			},
+		},
 		{
 			name: "missing required fields",
 			body: func(t *testing.T) api.UpdateDestinationRequest {
@@ -211,7 +272,7 @@ func TestAPI_UpdateDestination(t *testing.T) {
 					UniqueID: "unique-id",
 					Connection: api.DestinationConnection{
 						URL: "10.10.10.10:12345",
-						CA:  "the-ca-or-fingerprint",
+						CA:  api.PEM(hostKey),
 					},
 					Roles: []string{"one", "two"},
 				}
@@ -232,7 +293,7 @@ func TestAPI_UpdateDestination(t *testing.T) {
 						"version": "",
 						"connection": {
 							"url": "10.10.10.10:12345",
-							"ca": "the-ca-or-fingerprint"
+							"ca": "%[3]v"
 						},
 						"connected": true,
 						"lastSeen": "%[1]v",
@@ -241,7 +302,7 @@ func TestAPI_UpdateDestination(t *testing.T) {
 						"created": "%[1]v",
 						"updated": "%[1]v"
 					}
-				`, time.Now().UTC().Format(time.RFC3339), dest.ID))
+				`, time.Now().UTC().Format(time.RFC3339), dest.ID, hostKey))
 
 				actualBody := jsonUnmarshal(t, resp.Body.String())
 				assert.DeepEqual(t, actualBody, expectedBody, cmpAPIDestinationJSON)
@@ -255,7 +316,7 @@ func TestAPI_UpdateDestination(t *testing.T) {
 					UniqueID:      "unique-id",
 					Kind:          models.DestinationKindSSH,
 					ConnectionURL: "10.10.10.10:12345",
-					ConnectionCA:  "the-ca-or-fingerprint",
+					ConnectionCA:  hostKey,
 					LastSeenAt:    time.Now(),
 					Roles:         []string{"one", "two"},
 				}
@@ -7,13 +7,17 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 )
 
 func (a *API) ListDestinations(c *gin.Context, r *api.ListDestinationsRequest) (*api.ListResponse[api.Destination], error) {
-	p := PaginationFromRequest(r.PaginationRequest)
+	p, err := CursorPaginationFromRequest(r.CursorPaginationRequest, r.PaginationRequest)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := data.ListDestinationsOptions{
 		ByUniqueID: r.UniqueID,
@@ -57,6 +61,9 @@ func (a *API) CreateDestination(c *gin.Context, r *api.CreateDestinationRequest)
 	if destination.Kind == "" {
 		destination.Kind = "kubernetes"
 	}
+	if err := validateSSHConnection(destination.Kind, r.Connection); err != nil {
+		return nil, err
+	}
 
 	// set LastSeenAt if this request came from a connector. The middleware
 	// can't do this update in the case where the destination did not exist yet
@@ -83,6 +90,11 @@ func (a *API) UpdateDestination(c *gin.Context, r *api.UpdateDestinationRequest)
 	if err != nil {
 		return nil, err
 	}
+	rCtx.Response.SetAuditBefore(destination.ToAPI())
+
+	if err := validateSSHConnection(destination.Kind, r.Connection); err != nil {
+		return nil, err
+	}
 
 	destination.Name = r.Name
 	destination.UniqueID = r.UniqueID
@@ -102,3 +114,16 @@ func (a *API) UpdateDestination(c *gin.Context, r *api.UpdateDestinationRequest)
 func (a *API) DeleteDestination(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
 	return nil, access.DeleteDestination(c, r.ID)
 }
+
+// validateSSHConnection returns an error if the connection of an ssh
+// destination can not be safely written to the ssh config of a client. The
+// connection may be empty until the connector reports it.
+func validateSSHConnection(kind models.DestinationKind, conn api.DestinationConnection) error {
+	if kind != models.DestinationKindSSH || conn.URL == "" {
+		return nil
+	}
+	if _, err := conn.ParseSSH(); err != nil {
+		return fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	}
+	return nil
+}
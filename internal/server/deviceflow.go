@@ -12,6 +12,7 @@ import (
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/server/authn"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 )
@@ -117,8 +118,14 @@ func (a *API) ApproveDeviceAdd(c *gin.Context, req *api.ApproveDeviceFlowRequest
 		return nil, nil
 	}
 
-	// create access key
 	user := rctx.Authenticated.User
+	if req.WebAuthn != nil {
+		if err := verifyDeviceApprovalWebAuthn(rctx, user, req.WebAuthn); err != nil {
+			return nil, err
+		}
+	}
+
+	// create access key
 	accessKey := &models.AccessKey{
 		OrganizationMember:  models.OrganizationMember{OrganizationID: rctx.Authenticated.Organization.ID},
 		IssuedFor:           user.ID,
@@ -138,3 +145,25 @@ func (a *API) ApproveDeviceAdd(c *gin.Context, req *api.ApproveDeviceFlowRequest
 	err = access.SetDeviceFlowAuthRequestAccessKey(rctx, dfar.ID, accessKey)
 	return nil, err
 }
+
+// verifyDeviceApprovalWebAuthn checks that the WebAuthn assertion sent with a
+// device flow approval is valid, and belongs to the user approving the device.
+func verifyDeviceApprovalWebAuthn(rctx access.RequestContext, user *models.Identity, r *api.LoginRequestWebAuthn) error {
+	assertion := r.Credential
+	method := authn.NewWebAuthnAuthentication(
+		r.SessionID,
+		assertion.ID,
+		assertion.Response.ClientDataJSON,
+		assertion.Response.AuthenticatorData,
+		assertion.Response.Signature,
+		assertion.Response.UserHandle,
+	)
+	authenticated, err := method.Authenticate(rctx.Request.Context(), rctx.DBTxn, time.Time{})
+	if err != nil {
+		return fmt.Errorf("%w: webauthn: %v", internal.ErrUnauthorized, err)
+	}
+	if authenticated.Identity.ID != user.ID {
+		return fmt.Errorf("%w: webauthn credential does not belong to the user", internal.ErrUnauthorized)
+	}
+	return nil
+}
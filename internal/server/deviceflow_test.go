@@ -139,6 +139,39 @@ func TestDeviceFlow(t *testing.T) {
 
 		assert.Equal(t, statusResp.LoginResponse.UserID, user.ID)
 	})
+
+	t.Run("approve with an invalid webauthn assertion", func(t *testing.T) {
+		dfResp := &api.DeviceFlowResponse{}
+		doPost(t, "", "http://"+org.Domain+"/api/device", api.EmptyRequest{}, dfResp)
+
+		body := api.ApproveDeviceFlowRequest{
+			UserCode: dfResp.UserCode,
+			WebAuthn: &api.LoginRequestWebAuthn{
+				SessionID: 12345,
+				Credential: api.WebAuthnAssertion{
+					ID:   "cred-1",
+					Type: "public-key",
+					Response: api.WebAuthnAssertionResponse{
+						ClientDataJSON:    "e30",
+						AuthenticatorData: "AAAA",
+						Signature:         "AAAA",
+					},
+				},
+			},
+		}
+		req := httptest.NewRequest(http.MethodPost, "http://"+org.Domain+"/api/device/approve", jsonBody(t, body))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+		req.Header.Set("Authorization", "Bearer "+key)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		statusResp := &api.DeviceFlowStatusResponse{}
+		doPost(t, "", "http://"+org.Domain+"/api/device/status", api.DeviceFlowStatusRequest{
+			DeviceCode: dfResp.DeviceCode,
+		}, statusResp)
+		assert.Equal(t, statusResp.Status, "pending")
+	})
 }
 
 func TestAPI_StartDeviceFlow(t *testing.T) {
@@ -1,10 +1,14 @@
 package server
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
@@ -12,6 +16,7 @@ import (
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -47,12 +52,17 @@ func (a *API) ListGrants(c *gin.Context, r *api.ListGrantsRequest) (*ListGrantsR
 		ByDestination:              r.Destination,
 		ExcludeConnectorGrant:      !r.ShowSystem,
 		IncludeInheritedFromGroups: r.ShowInherited,
+		IncludeSubjectNames:        r.ShowSubjectNames,
 	}
 	if r.Privilege != "" {
 		opts.ByPrivileges = []string{r.Privilege}
 	}
 	if !r.IsBlockingRequest() {
-		p = PaginationFromRequest(r.PaginationRequest)
+		var err error
+		p, err = CursorPaginationFromRequest(r.CursorPaginationRequest, r.PaginationRequest)
+		if err != nil {
+			return nil, err
+		}
 		opts.Pagination = &p
 	}
 
@@ -73,6 +83,94 @@ func (a *API) ListGrants(c *gin.Context, r *api.ListGrantsRequest) (*ListGrantsR
 	return (*ListGrantsResponse)(result), nil
 }
 
+// WatchGrants writes a stream of server-sent events, one for each grant of the
+// destination that is created or deleted, until the request timeout is
+// reached. The id of each event is the update index of the grant, so a client
+// may also resume the stream using the Last-Event-ID header.
+//
+// The api.GrantEvent response type is only used to document the event. The
+// events are written directly to the response.
+func (a *API) WatchGrants(c *gin.Context, r *api.WatchGrantsRequest) (*api.GrantEvent, error) {
+	rCtx := getRequestContext(c)
+
+	lastUpdateIndex := r.LastUpdateIndex
+	if id := c.GetHeader("Last-Event-ID"); id != "" && lastUpdateIndex == 0 {
+		var err error
+		lastUpdateIndex, err = strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid Last-Event-ID header", internal.ErrBadRequest)
+		}
+	}
+
+	rCtx.Response.AddLogFields(func(event *zerolog.Event) {
+		event.Int64("lastUpdateIndex", lastUpdateIndex)
+	})
+
+	watcher, err := access.WatchGrants(c, r.Destination)
+	if err != nil {
+		return nil, err
+	}
+	defer watcher.Release()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for {
+		grants, err := watcher.Next(ctx, lastUpdateIndex)
+		switch {
+		case ctx.Err() != nil:
+			// the request timeout was reached, or the client disconnected
+			return nil, nil
+		case err != nil:
+			logging.L.Error().Err(err).Msg("failed to watch grants")
+			return nil, nil
+		}
+
+		events := make([]api.GrantEvent, 0, len(grants)+1)
+		for _, grant := range grants {
+			event := api.GrantEvent{
+				Type:        api.GrantEventAdd,
+				UpdateIndex: grant.UpdateIndex,
+				Grant:       grant.ToAPI(),
+			}
+			if grant.DeletedAt.Valid {
+				event.Type = api.GrantEventDelete
+			}
+			events = append(events, event)
+			lastUpdateIndex = grant.UpdateIndex
+		}
+		events = append(events, api.GrantEvent{Type: api.GrantEventSync, UpdateIndex: lastUpdateIndex})
+
+		if err := writeGrantEvents(c.Writer, events); err != nil {
+			logging.L.Debug().Err(err).Msg("failed to write grant events")
+			return nil, nil
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeGrantEvents writes each event in the format of a server-sent event.
+func writeGrantEvents(w io.Writer, events []api.GrantEvent) error {
+	for _, event := range events {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		eventName := "grant"
+		if event.Type == api.GrantEventSync {
+			eventName = "sync"
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.UpdateIndex, eventName, raw)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (a *API) GetGrant(c *gin.Context, r *api.Resource) (*api.Grant, error) {
 	grant, err := access.GetGrant(c, r.ID)
 	if err != nil {
@@ -96,9 +194,10 @@ func (a *API) CreateGrant(c *gin.Context, r *api.GrantRequest) (*api.CreateGrant
 		Subject:   subject,
 		Resource:  r.Resource,
 		Privilege: r.Privilege,
+		ExpiresAt: grantExpiresAt(*r),
 	}
 
-	err := access.CreateGrant(c, grant)
+	err := access.CreateGrant(c, grant, r.Force)
 	var ucerr data.UniqueConstraintError
 
 	if errors.As(err, &ucerr) {
@@ -133,6 +232,10 @@ func (a *API) DeleteGrant(c *gin.Context, r *api.Resource) (*api.EmptyResponse,
 	if err != nil {
 		return nil, err
 	}
+	getRequestContext(c).Response.SetAuditBefore(grant.ToAPI())
+	if err := a.checkConfigManaged(c, "grant", grant.ID.String(), grant.CreatedBy); err != nil {
+		return nil, err
+	}
 
 	if grant.Resource == access.ResourceInfraAPI && grant.Privilege == models.InfraAdminRole {
 		opts := data.ListGrantsOptions{
@@ -170,6 +273,9 @@ func (a *API) UpdateGrants(c *gin.Context, r *api.UpdateGrantsRequest) (*api.Emp
 		if err != nil {
 			return nil, err
 		}
+		if err := a.checkConfigManagedGrant(c, grant); err != nil {
+			return nil, err
+		}
 		rmGrants = append(rmGrants, grant)
 	}
 
@@ -218,5 +324,19 @@ func getGrantFromGrantRequest(c *gin.Context, r api.GrantRequest) (*models.Grant
 		Subject:   subject,
 		Resource:  r.Resource,
 		Privilege: r.Privilege,
+		ExpiresAt: grantExpiresAt(r),
 	}, nil
 }
+
+// grantExpiresAt returns the expiry time requested by r, or an invalid
+// sql.NullTime if the grant should not expire.
+func grantExpiresAt(r api.GrantRequest) sql.NullTime {
+	switch {
+	case r.Expiry > 0:
+		return sql.NullTime{Time: time.Now().Add(time.Duration(r.Expiry)), Valid: true}
+	case !r.Expires.Time().IsZero():
+		return sql.NullTime{Time: r.Expires.Time(), Valid: true}
+	default:
+		return sql.NullTime{}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	gocmp "github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/errgroup"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/opt"
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
@@ -377,6 +378,23 @@ func TestAPI_ListGrants(t *testing.T) {
 				assert.DeepEqual(t, grants.Items, expected, cmpAPIGrantShallow)
 			},
 		},
+		"show subject names": {
+			urlPath: "/api/grants?resource=res1&showSubjectNames=true",
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+				var grants api.ListResponse[api.Grant]
+				err = json.NewDecoder(resp.Body).Decode(&grants)
+				assert.NilError(t, err)
+
+				assert.Equal(t, len(grants.Items), 1)
+				assert.Equal(t, grants.Items[0].User, idInGroup)
+				assert.Equal(t, grants.Items[0].SubjectName, "inagroup@example.com")
+				assert.Equal(t, grants.Items[0].SubjectKind, api.GrantSubjectKindUser)
+			},
+		},
 		"filter by destination": {
 			urlPath: "/api/grants?destination=res1",
 			setup: func(t *testing.T, req *http.Request) {
@@ -636,7 +654,7 @@ func TestAPI_ListGrants_InheritedGrants(t *testing.T) {
 				assert.NilError(t, err)
 
 				expected := []api.FieldError{
-					{FieldName: "showInherited", Errors: []string{"requires a user ID"}},
+					{FieldName: "showInherited", Errors: []string{"requires a user or group ID"}},
 				}
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
@@ -822,6 +840,77 @@ func TestAPI_ListGrants_BlockingRequest_BlocksUntilUpdate(t *testing.T) {
 	assert.Equal(t, len(respBody.Items), 2)
 }
 
+func TestAPI_WatchGrants(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too long for short run")
+	}
+
+	srv := setupServer(t, withAdminUser)
+	httpSrv := httptest.NewServer(srv.GenerateRoutes())
+	t.Cleanup(httpSrv.Close)
+
+	first := &models.Grant{Subject: "i:abcd", Privilege: "view", Resource: "watched"}
+	assert.NilError(t, data.CreateGrant(srv.db, first))
+
+	client := api.Client{URL: httpSrv.URL, AccessKey: adminAccessKey(srv), HTTP: *httpSrv.Client()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	var events []api.GrantEvent
+	var second *models.Grant
+	var deleted bool
+	errStop := fmt.Errorf("stop watching")
+	err := client.WatchGrants(ctx, api.WatchGrantsRequest{Destination: "watched"}, func(event api.GrantEvent) error {
+		events = append(events, event)
+		switch {
+		case event.Type == api.GrantEventDelete:
+			deleted = true
+		case event.Type != api.GrantEventSync:
+		case deleted:
+			return errStop
+		case second == nil:
+			// unrelated grant
+			err := data.CreateGrant(srv.db, &models.Grant{Subject: "i:abcd", Privilege: "view", Resource: "other"})
+			assert.NilError(t, err)
+
+			second = &models.Grant{Subject: "i:efgh", Privilege: "edit", Resource: "watched.ns1"}
+			assert.NilError(t, data.CreateGrant(srv.db, second))
+			assert.NilError(t, data.DeleteGrants(srv.db, data.DeleteGrantsOptions{ByID: first.ID}))
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errStop)
+
+	var changes []api.GrantEvent
+	var lastIndex int64
+	for _, event := range events {
+		assert.Assert(t, event.UpdateIndex >= lastIndex, "events out of order: %v", events)
+		lastIndex = event.UpdateIndex
+		if event.Type != api.GrantEventSync {
+			changes = append(changes, event)
+		}
+	}
+	assert.Equal(t, events[len(events)-1].Type, api.GrantEventSync)
+
+	expected := []api.GrantEvent{
+		{Type: api.GrantEventAdd, Grant: &api.Grant{ID: first.ID}},
+		{Type: api.GrantEventAdd, Grant: &api.Grant{ID: second.ID}},
+		{Type: api.GrantEventDelete, Grant: &api.Grant{ID: first.ID}},
+	}
+	assert.DeepEqual(t, changes, expected, cmpGrantEventShallow)
+}
+
+var cmpGrantEventShallow = gocmp.Options{
+	gocmp.FilterPath(opt.PathField(api.GrantEvent{}, "UpdateIndex"), gocmp.Ignore()),
+	gocmp.Comparer(func(x, y *api.Grant) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return x.ID == y.ID
+	}),
+}
+
 func isBlocked[T any](t *testing.T, ch chan T) {
 	t.Helper()
 	select {
@@ -929,6 +1018,16 @@ func TestAPI_CreateGrant(t *testing.T) {
 
 	otherOrg := createOtherOrg(t, srv.db)
 
+	destination := &models.Destination{
+		Name:      "connected-cluster",
+		Kind:      "kubernetes",
+		UniqueID:  "connected-cluster-id",
+		Resources: []string{"default", "team-a"},
+		Roles:     []string{"admin", "view", "team-a/deployer"},
+	}
+	err = data.CreateDestination(srv.DB(), destination)
+	assert.NilError(t, err)
+
 	type testCase struct {
 		setup    func(t *testing.T, req *http.Request)
 		expected func(t *testing.T, resp *httptest.ResponseRecorder)
@@ -1017,6 +1116,73 @@ func TestAPI_CreateGrant(t *testing.T) {
 				assert.DeepEqual(t, actual, expected, cmpAPIGrantJSON)
 			},
 		},
+		"cluster role on connected destination": {
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			body: api.GrantRequest{
+				User:      someUser.ID,
+				Privilege: "view",
+				Resource:  "connected-cluster.default",
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+			},
+		},
+		"namespaced role on connected destination": {
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			body: api.GrantRequest{
+				User:      someUser.ID,
+				Privilege: "deployer",
+				Resource:  "connected-cluster.team-a",
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+			},
+		},
+		"namespaced role in a different namespace": {
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			body: api.GrantRequest{
+				User:      someUser.ID,
+				Privilege: "deployer",
+				Resource:  "connected-cluster.default",
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+				assert.Assert(t, strings.Contains(resp.Body.String(), `role \"deployer\" does not exist`), resp.Body.String())
+			},
+		},
+		"unknown role on connected destination": {
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			body: api.GrantRequest{
+				User:      someUser.ID,
+				Privilege: "cluster-owner",
+				Resource:  "connected-cluster",
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+			},
+		},
+		"unknown role on connected destination with force": {
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			body: api.GrantRequest{
+				User:      someUser.ID,
+				Privilege: "cluster-owner",
+				Resource:  "connected-cluster",
+				Force:     true,
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+			},
+		},
 		"admin can not grant infra support admin role": {
 			setup: func(t *testing.T, req *http.Request) {
 				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
@@ -10,7 +10,10 @@ import (
 )
 
 func (a *API) ListGroups(c *gin.Context, r *api.ListGroupsRequest) (*api.ListResponse[api.Group], error) {
-	p := PaginationFromRequest(r.PaginationRequest)
+	p, err := CursorPaginationFromRequest(r.CursorPaginationRequest, r.PaginationRequest)
+	if err != nil {
+		return nil, err
+	}
 	groups, err := access.ListGroups(c, r.Name, r.UserID, &p)
 	if err != nil {
 		return nil, err
@@ -57,3 +60,19 @@ func (a *API) DeleteGroup(c *gin.Context, r *api.Resource) (*api.EmptyResponse,
 func (a *API) UpdateUsersInGroup(c *gin.Context, r *api.UpdateUsersInGroupRequest) (*api.EmptyResponse, error) {
 	return nil, access.UpdateUsersInGroup(c, r.GroupID, r.UserIDsToAdd, r.UserIDsToRemove)
 }
+
+func (a *API) UpdateGroupsInGroup(c *gin.Context, r *api.UpdateGroupsInGroupRequest) (*api.EmptyResponse, error) {
+	return nil, access.UpdateGroupsInGroup(c, r.GroupID, r.GroupIDsToAdd, r.GroupIDsToRemove)
+}
+
+func (a *API) ListNestedGroups(c *gin.Context, r *api.Resource) (*api.ListResponse[api.Group], error) {
+	groups, err := access.ListNestedGroups(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(groups, api.PaginationResponse{}, func(group models.Group) api.Group {
+		return *group.ToAPI()
+	})
+	return result, nil
+}
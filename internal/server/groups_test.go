@@ -13,6 +13,7 @@ import (
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -448,8 +449,109 @@ func TestAPI_UpdateUsersInGroup(t *testing.T) {
 			run(t, tc)
 		})
 	}
+
+	t.Run("groups write can not update a group with infra grants", func(t *testing.T) {
+		admins := models.Group{Name: "admins"}
+		leads := models.Group{Name: "leads"}
+		createGroups(t, srv.DB(), &admins, &leads)
+		assert.NilError(t, data.AddGroupsToGroup(srv.DB(), admins.ID, []uid.ID{leads.ID}))
+		assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   admins.PolyID(),
+			Privilege: models.InfraAdminRole,
+			Resource:  access.ResourceInfraAPI,
+		}))
+
+		writer, user := createAccessKey(t, srv.DB(), "group-writer@example.com")
+		assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   user.PolyID(),
+			Privilege: "groups:write",
+			Resource:  access.ResourceInfraAPI,
+		}))
+
+		update := func(groupID uid.ID) *httptest.ResponseRecorder {
+			body := jsonBody(t, api.UpdateUsersInGroupRequest{UserIDsToAdd: []uid.ID{user.ID}})
+			// nolint:noctx
+			req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/groups/%s/users", groupID), body)
+			req.Header.Set("Authorization", "Bearer "+writer)
+			req.Header.Add("Infra-Version", apiVersionLatest)
+			resp := httptest.NewRecorder()
+			routes.ServeHTTP(resp, req)
+			return resp
+		}
+
+		resp := update(admins.ID)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		// leads inherits the grants of admins
+		resp = update(leads.ID)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = update(humans.ID)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
 }
 
 var cmpModelsIdentityShallow = cmp.Comparer(func(x, y models.Identity) bool {
 	return x.Name == y.Name
 })
+
+func TestAPI_NestedGroups(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	sre := models.Group{Name: "sre"}
+	oncall := models.Group{Name: "sre-oncall"}
+	createGroups(t, srv.DB(), &sre, &oncall)
+
+	do := func(t *testing.T, method, path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	path := fmt.Sprintf("/api/groups/%s/groups", sre.ID)
+
+	t.Run("add a group", func(t *testing.T) {
+		body := api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{oncall.ID}}
+		resp := do(t, http.MethodPatch, path, body)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = do(t, http.MethodGet, path, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var groups api.ListResponse[api.Group]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&groups))
+		assert.Equal(t, groups.Count, 1)
+		assert.Equal(t, groups.Items[0].ID, oncall.ID)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		body := api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{sre.ID}}
+		resp := do(t, http.MethodPatch, fmt.Sprintf("/api/groups/%s/groups", oncall.ID), body)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		body := api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{uid.ID(1234)}}
+		resp := do(t, http.MethodPatch, path, body)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("remove a group", func(t *testing.T) {
+		body := api.UpdateGroupsInGroupRequest{GroupIDsToRemove: []uid.ID{oncall.ID}}
+		resp := do(t, http.MethodPatch, path, body)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = do(t, http.MethodGet, path, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var groups api.ListResponse[api.Group]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&groups))
+		assert.Equal(t, groups.Count, 0)
+	})
+}
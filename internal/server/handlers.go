@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -201,6 +202,70 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 		}
 
 		loginMethod = authn.NewPasswordCredentialAuthentication(r.PasswordCredentials.Name, r.PasswordCredentials.Password)
+	case r.MFA != nil:
+		// limit attempts at guessing the code for each user, so that starting
+		// a new login does not allow more guesses
+		challengeID, _, _ := strings.Cut(r.MFA.Challenge, ".")
+		challenge, err := data.GetAccessKeyByKeyID(rCtx.DBTxn, challengeID)
+		switch {
+		case errors.Is(err, internal.ErrNotFound):
+			return nil, fmt.Errorf("%w: login failed: invalid mfa challenge", internal.ErrUnauthorized)
+		case err != nil:
+			return nil, err
+		}
+		userWithOrganization := fmt.Sprintf("mfa:%s:%s", challenge.IssuedFor, rCtx.Authenticated.Organization.ID)
+		limiter := redis.NewLimiter(a.server.redis)
+		if err := limiter.LoginOK(userWithOrganization); err != nil {
+			return nil, err
+		}
+
+		onSuccess = func() {
+			limiter.LoginGood(userWithOrganization)
+		}
+
+		onFailure = func() {
+			limiter.LoginBad(userWithOrganization, 5)
+		}
+
+		loginMethod = authn.NewMFAAuthentication(r.MFA.Challenge, r.MFA.Code)
+	case r.WebAuthn != nil:
+		assertion := r.WebAuthn.Credential
+		loginMethod = authn.NewWebAuthnAuthentication(
+			r.WebAuthn.SessionID,
+			assertion.ID,
+			assertion.Response.ClientDataJSON,
+			assertion.Response.AuthenticatorData,
+			assertion.Response.Signature,
+			assertion.Response.UserHandle,
+		)
+	case r.LDAP != nil:
+		if err := redis.NewLimiter(a.server.redis).RateOK(r.LDAP.Name, 10); err != nil {
+			return nil, err
+		}
+
+		provider, err := access.GetProvider(c, r.LDAP.ProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity provider: %w", err)
+		}
+
+		usernameWithProvider := fmt.Sprintf("ldap:%s:%s:%s", r.LDAP.Name, provider.ID, rCtx.Authenticated.Organization.ID)
+		limiter := redis.NewLimiter(a.server.redis)
+		if err := limiter.LoginOK(usernameWithProvider); err != nil {
+			return nil, err
+		}
+
+		onSuccess = func() {
+			limiter.LoginGood(usernameWithProvider)
+		}
+
+		onFailure = func() {
+			limiter.LoginBad(usernameWithProvider, 10)
+		}
+
+		loginMethod, err = authn.NewLDAPAuthentication(provider, r.LDAP.Name, r.LDAP.Password)
+		if err != nil {
+			return nil, err
+		}
 	case r.OIDC != nil:
 		var provider *models.Provider
 		if r.OIDC.ProviderID == 0 {
@@ -215,6 +280,10 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 			if err != nil {
 				return nil, fmt.Errorf("invalid identity provider: %w", err)
 			}
+			switch provider.Kind {
+			case models.ProviderKindSAML, models.ProviderKindLDAP:
+				return nil, fmt.Errorf("%w: %v providers do not support oidc login", internal.ErrBadRequest, provider.Kind)
+			}
 		}
 
 		providerClient, err := a.providerClient(c, provider, r.OIDC.RedirectURL)
@@ -258,6 +327,32 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 		onSuccess()
 	}
 
+	if result.MFAChallenge != "" {
+		// the login is not complete until the user provides a second factor
+		return &api.LoginResponse{
+			UserID:       result.User.ID,
+			Name:         result.User.Name,
+			MFAChallenge: result.MFAChallenge,
+		}, nil
+	}
+
+	a.startLoginSession(c, result, loginMethod.Name())
+
+	key := result.AccessKey
+	return &api.LoginResponse{
+		UserID:                 key.IssuedFor,
+		Name:                   key.IssuedForName,
+		AccessKey:              result.Bearer,
+		Expires:                api.Time(key.ExpiresAt),
+		PasswordUpdateRequired: result.CredentialUpdateRequired,
+		OrganizationName:       result.OrganizationName,
+		MFAEnrollmentRequired:  result.MFAEnrollmentRequired,
+	}, nil
+}
+
+// startLoginSession sets the session cookie for a successful login, and
+// records the login.
+func (a *API) startLoginSession(c *gin.Context, result authn.LoginResult, method string) {
 	cookie := cookieConfig{
 		Name:    cookieAuthorizationName,
 		Value:   result.Bearer,
@@ -269,20 +364,12 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 	key := result.AccessKey
 	a.t.User(key.IssuedFor.String(), result.User.Name)
 	a.t.OrgMembership(key.OrganizationID.String(), key.IssuedFor.String())
-	a.t.Event("login", key.IssuedFor.String(), key.OrganizationID.String(), Properties{"method": loginMethod.Name()})
+	a.t.Event("login", key.IssuedFor.String(), key.OrganizationID.String(), Properties{"method": method})
 
 	// Update the request context so that logging middleware can include the userID
+	rCtx := getRequestContext(c)
 	rCtx.Authenticated.User = result.User
 	c.Set(access.RequestContextKey, rCtx)
-
-	return &api.LoginResponse{
-		UserID:                 key.IssuedFor,
-		Name:                   key.IssuedForName,
-		AccessKey:              result.Bearer,
-		Expires:                api.Time(key.ExpiresAt),
-		PasswordUpdateRequired: result.CredentialUpdateRequired,
-		OrganizationName:       result.OrganizationName,
-	}, nil
 }
 
 func (a *API) Logout(c *gin.Context, _ *api.EmptyRequest) (*api.EmptyResponse, error) {
@@ -306,7 +393,14 @@ func (a *API) UpdateIdentityInfoFromProvider(rCtx access.RequestContext) error {
 		return err
 	}
 
-	if provider.Kind == models.ProviderKindInfra {
+	switch provider.Kind {
+	case models.ProviderKindInfra:
+		return nil
+	case models.ProviderKindSAML:
+		// SAML users are only updated when they login
+		return nil
+	case models.ProviderKindLDAP:
+		// LDAP users are updated when they login, and by a background job
 		return nil
 	}
 
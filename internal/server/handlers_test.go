@@ -31,19 +31,19 @@ func TestWellKnownJWKs(t *testing.T) {
 	otherOrg := &models.Organization{Name: "Other", Domain: "other.example.org"}
 	createOrgs(t, srv.db, otherOrg)
 
-	settings, err := data.GetSettings(srv.db)
+	signingKey, err := data.GetActiveSigningKey(srv.db)
 	assert.NilError(t, err)
 
 	var defaultKey jose.JSONWebKey
-	err = defaultKey.UnmarshalJSON(settings.PublicJWK)
+	err = defaultKey.UnmarshalJSON(signingKey.PublicJWK)
 	assert.NilError(t, err)
 
 	otherOrgTx := txnForTestCase(t, srv.db, otherOrg.ID)
-	settings, err = data.GetSettings(otherOrgTx)
+	signingKey, err = data.GetActiveSigningKey(otherOrgTx)
 	assert.NilError(t, err)
 
 	var otherOrgKey jose.JSONWebKey
-	err = otherOrgKey.UnmarshalJSON(settings.PublicJWK)
+	err = otherOrgKey.UnmarshalJSON(signingKey.PublicJWK)
 	assert.NilError(t, err)
 
 	connector := data.InfraConnectorIdentity(otherOrgTx)
@@ -161,6 +161,27 @@ func TestWellKnownJWKs(t *testing.T) {
 				assert.DeepEqual(t, response.Keys[0], otherOrgKey)
 			},
 		},
+		{
+			name: "after the key is rotated",
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+
+				_, err := data.RotateSigningKey(srv.db)
+				assert.NilError(t, err)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+				var response WellKnownJWKResponse
+				assert.NilError(t, json.NewDecoder(resp.Body).Decode(&response))
+				assert.Equal(t, len(response.Keys), 2)
+
+				active, err := data.GetActiveSigningKey(srv.db)
+				assert.NilError(t, err)
+				assert.Equal(t, response.Keys[0].KeyID, active.KeyID)
+				assert.DeepEqual(t, response.Keys[1], defaultKey)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2,8 +2,10 @@ package jobs
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/uid"
 )
 
 func RemoveOldDeviceFlowRequests(ctx context.Context, tx *data.Transaction) error {
@@ -17,3 +19,38 @@ func RemoveExpiredAccessKeys(ctx context.Context, tx *data.Transaction) error {
 func RemoveExpiredPasswordResetTokens(ctx context.Context, tx *data.Transaction) error {
 	return data.RemoveExpiredPasswordResetTokens(tx)
 }
+
+func RemoveExpiredGrants(ctx context.Context, tx *data.Transaction) error {
+	return data.DeleteExpiredGrants(tx)
+}
+
+func RemoveExpiredWebAuthnSessions(ctx context.Context, tx *data.Transaction) error {
+	return data.RemoveExpiredWebAuthnSessions(tx)
+}
+
+func RemoveExpiredSAMLAssertions(ctx context.Context, tx *data.Transaction) error {
+	return data.RemoveExpiredSAMLAssertions(tx)
+}
+
+func RemoveExpiredSAMLRequests(ctx context.Context, tx *data.Transaction) error {
+	return data.RemoveExpiredSAMLRequests(tx)
+}
+
+func RotateSigningKeys(ctx context.Context, tx *data.Transaction) error {
+	return data.RotateSigningKeys(tx)
+}
+
+// inOrgTransaction runs fn in a new transaction scoped to the organization. The
+// transaction is committed if fn returns nil.
+func inOrgTransaction(ctx context.Context, db *data.DB, orgID uid.ID, fn func(tx *data.Transaction) error) error {
+	tx, err := db.Begin(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx.WithOrgID(orgID)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
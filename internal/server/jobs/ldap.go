@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+// SyncLDAPGroups updates the groups of the users of all LDAP providers from
+// their directory. Users that no longer exist in the directory are removed
+// from all of the groups from the provider.
+//
+// Each user is synced in its own transaction, so that a slow directory does
+// not hold a transaction open for the sync of every provider.
+func SyncLDAPGroups(ctx context.Context, db *data.DB) error {
+	ldapProviders, err := data.ListAllProvidersByKind(db, models.ProviderKindLDAP)
+	if err != nil {
+		return fmt.Errorf("list ldap providers: %w", err)
+	}
+
+	for i := range ldapProviders {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		provider := &ldapProviders[i]
+		if err := syncLDAPProvider(ctx, db, provider); err != nil {
+			// continue with the other providers, so that one directory that
+			// is unavailable does not stop the sync of the others
+			logging.L.Warn().Err(err).Str("provider", provider.Name).Msg("failed to sync ldap groups")
+		}
+	}
+	return nil
+}
+
+func syncLDAPProvider(ctx context.Context, db *data.DB, provider *models.Provider) error {
+	client := providers.NewLDAPClient(*provider)
+
+	var providerUsers []models.ProviderUser
+	err := inOrgTransaction(ctx, db, provider.OrganizationID, func(tx *data.Transaction) error {
+		var err error
+		providerUsers, err = data.ListProviderUsers(tx, data.ListProviderUsersOptions{ByProviderID: provider.ID})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("list provider users: %w", err)
+	}
+
+	for i := range providerUsers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		pu := &providerUsers[i]
+		err := inOrgTransaction(ctx, db, provider.OrganizationID, func(tx *data.Transaction) error {
+			return syncLDAPProviderUser(tx, client, provider, pu)
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, internal.ErrBadGateway):
+			// the directory is unavailable, try again at the next sync
+			return err
+		default:
+			logging.L.Warn().Err(err).Str("user", pu.Email).Msg("failed to sync ldap user")
+		}
+	}
+	return nil
+}
+
+func syncLDAPProviderUser(tx *data.Transaction, client *providers.LDAPClient, provider *models.Provider, pu *models.ProviderUser) error {
+	identity, err := data.GetIdentity(tx, data.GetIdentityOptions{ByID: pu.IdentityID, LoadGroups: true})
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("get user: %w", err)
+	}
+
+	groups := []string{}
+	user, err := client.LookupUser(identity.Name)
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		// remove the user from all groups from the provider
+	case err != nil:
+		return fmt.Errorf("lookup ldap user: %w", err)
+	default:
+		groups = user.Groups
+	}
+
+	if err := data.AssignIdentityToGroups(tx, identity, provider, groups); err != nil {
+		return fmt.Errorf("assign identity to groups: %w", err)
+	}
+	return nil
+}
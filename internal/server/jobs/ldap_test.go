@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/database"
+	"github.com/infrahq/infra/internal/testing/ldap"
+	"github.com/infrahq/infra/internal/testing/patch"
+)
+
+func setupDB(t *testing.T) *data.DB {
+	t.Helper()
+	patch.ModelsSymmetricKey(t)
+	db, err := data.NewDB(data.NewDBOptions{DSN: database.PostgresDriver(t, "_jobs").DSN})
+	assert.NilError(t, err)
+	return db
+}
+
+func TestSyncLDAPGroups(t *testing.T) {
+	db := setupDB(t)
+
+	server := ldap.NewServer(t,
+		ldap.Entry{
+			DN:         "cn=infra,dc=example,dc=com",
+			Attributes: map[string][]string{"userPassword": {"service-password"}},
+		},
+		ldap.Entry{
+			DN:         "uid=ada,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"objectClass": {"person"}, "uid": {"ada"}, "mail": {"ada@example.com"}},
+		},
+		ldap.Entry{
+			DN:         "uid=grace,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"objectClass": {"person"}, "uid": {"grace"}, "mail": {"grace@example.com"}},
+		},
+		ldap.Entry{
+			DN: "cn=developers,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"developers"},
+				"member":      {"uid=ada,ou=people,dc=example,dc=com", "uid=grace,ou=people,dc=example,dc=com"},
+			},
+		},
+	)
+
+	provider := &models.Provider{
+		Name:             "ldap",
+		Kind:             models.ProviderKindLDAP,
+		URL:              server.URL,
+		LDAPBindDN:       "cn=infra,dc=example,dc=com",
+		LDAPBindPassword: "service-password",
+		LDAPUserBaseDN:   "dc=example,dc=com",
+	}
+	assert.NilError(t, data.CreateProvider(db, provider))
+
+	createUser := func(t *testing.T, name string, groups ...string) *models.Identity {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		assert.NilError(t, data.CreateIdentity(db, user))
+		_, err := data.CreateProviderUser(db, provider, user)
+		assert.NilError(t, err)
+		assert.NilError(t, data.AssignIdentityToGroups(db, user, provider, groups))
+		return user
+	}
+	ada := createUser(t, "ada@example.com", "developers")
+	grace := createUser(t, "grace@example.com", "developers")
+
+	// ada joins a group, and grace leaves the directory
+	server.Add(ldap.Entry{
+		DN: "cn=admins,ou=groups,dc=example,dc=com",
+		Attributes: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"cn":          {"admins"},
+			"member":      {"uid=ada,ou=people,dc=example,dc=com"},
+		},
+	})
+	server.Delete("uid=grace,ou=people,dc=example,dc=com")
+
+	assert.NilError(t, SyncLDAPGroups(context.Background(), db))
+
+	pu, err := data.GetProviderUser(db, provider.ID, ada.ID)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string(pu.Groups), []string{"developers", "admins"})
+
+	pu, err = data.GetProviderUser(db, provider.ID, grace.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pu.Groups), 0)
+
+	t.Run("directory is unavailable", func(t *testing.T) {
+		server.Close()
+		assert.NilError(t, SyncLDAPGroups(context.Background(), db))
+
+		// groups are not changed
+		pu, err := data.GetProviderUser(db, provider.ID, ada.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"developers", "admins"})
+	})
+}
@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+// ProviderUserSyncFailures counts the provider users that could not be synced
+// by SyncOIDCProviderUsers, by the kind of provider.
+var ProviderUserSyncFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "infra",
+	Name:      "provider_user_sync_failures_total",
+	Help:      "The total number of provider users that failed to sync from their identity provider",
+}, []string{"kind"})
+
+// oidcProviderKinds are the kinds of providers which store a refresh token
+// for their users, which can be used to sync the users in the background.
+var oidcProviderKinds = []models.ProviderKind{
+	models.ProviderKindOIDC,
+	models.ProviderKindOkta,
+	models.ProviderKindAzure,
+	models.ProviderKindGoogle,
+	models.ProviderKindGitHub,
+	models.ProviderKindGitLab,
+}
+
+// SyncOIDCProviderUsers refreshes the groups of all the active provider users
+// with a refresh token, so that changes made at the identity provider apply
+// without waiting for the user to make a request. Users the identity provider
+// no longer returns are deactivated, removed from the groups from the
+// provider, and their sessions from the provider are revoked.
+//
+// Each user is synced in its own transaction, so that a slow identity provider
+// or a failure to sync one user does not hold or roll back the changes to
+// other users.
+func SyncOIDCProviderUsers(ctx context.Context, db *data.DB) error {
+	oidcProviders, err := data.ListAllProvidersByKind(db, oidcProviderKinds...)
+	if err != nil {
+		return fmt.Errorf("list oidc providers: %w", err)
+	}
+
+	for i := range oidcProviders {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		provider := &oidcProviders[i]
+		if err := syncOIDCProvider(ctx, db, provider); err != nil {
+			// continue with the other providers, so that one identity provider
+			// that is unavailable does not stop the sync of the others
+			logging.L.Warn().Err(err).Str("provider", provider.Name).Msg("failed to sync provider users")
+		}
+	}
+	return nil
+}
+
+func syncOIDCProvider(ctx context.Context, db *data.DB, provider *models.Provider) error {
+	var providerUsers []models.ProviderUser
+	err := inOrgTransaction(ctx, db, provider.OrganizationID, func(tx *data.Transaction) error {
+		var err error
+		providerUsers, err = data.ListProviderUsers(tx, data.ListProviderUsersOptions{
+			ByProviderID: provider.ID,
+			HideInactive: true,
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("list provider users: %w", err)
+	}
+
+	for i := range providerUsers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		pu := &providerUsers[i]
+		if pu.RefreshToken == "" {
+			continue
+		}
+
+		err := inOrgTransaction(ctx, db, provider.OrganizationID, func(tx *data.Transaction) error {
+			return syncOIDCProviderUser(ctx, tx, provider, pu)
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, internal.ErrBadGateway), errors.Is(err, context.DeadlineExceeded):
+			// the identity provider is unavailable, try again at the next sync
+			ProviderUserSyncFailures.WithLabelValues(provider.Kind.String()).Inc()
+			return err
+		default:
+			ProviderUserSyncFailures.WithLabelValues(provider.Kind.String()).Inc()
+			logging.L.Warn().Err(err).Str("user", pu.Email).Msg("failed to sync provider user")
+		}
+	}
+	return nil
+}
+
+func syncOIDCProviderUser(ctx context.Context, tx *data.Transaction, provider *models.Provider, pu *models.ProviderUser) error {
+	identity, err := data.GetIdentity(tx, data.GetIdentityOptions{ByID: pu.IdentityID, LoadGroups: true})
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("get user: %w", err)
+	}
+
+	client := providers.OIDCClientFromContext(ctx)
+	if client == nil {
+		client = providers.NewOIDCClient(*provider, string(provider.ClientSecret), pu.RedirectURL)
+	}
+
+	err = data.SyncProviderUser(ctx, tx, identity, provider, client)
+	if isUserRemovedFromProvider(err) {
+		logging.L.Info().Str("provider", provider.Name).Str("user", identity.Name).Msg("user was removed from the identity provider")
+		if err := deactivateProviderUser(tx, identity, provider); err != nil {
+			return fmt.Errorf("deactivate provider user: %w", err)
+		}
+		return nil
+	}
+	return err
+}
+
+// isUserRemovedFromProvider returns true if the identity provider rejected the
+// refresh token of the user with an invalid_grant error, which happens when the
+// user was deactivated or deleted, or their access to the client was revoked.
+// Other errors, like an invalid_client error from a misconfigured provider, or
+// a server error, are not included, those are counted as sync failures.
+func isUserRemovedFromProvider(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+	return retrieveErrorCode(retrieveErr) == "invalid_grant"
+}
+
+// retrieveErrorCode returns the error code from the body of an error response
+// to a token request, as described by RFC 6749 section 5.2. Some identity
+// providers respond with a form encoded body instead of JSON.
+func retrieveErrorCode(err *oauth2.RetrieveError) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(err.Body, &body) == nil {
+		return body.Error
+	}
+	values, parseErr := url.ParseQuery(string(err.Body))
+	if parseErr != nil {
+		return ""
+	}
+	return values.Get("error")
+}
+
+func deactivateProviderUser(tx *data.Transaction, identity *models.Identity, provider *models.Provider) error {
+	if err := data.AssignIdentityToGroups(tx, identity, provider, []string{}); err != nil {
+		return fmt.Errorf("remove groups: %w", err)
+	}
+
+	pu := &models.ProviderUser{IdentityID: identity.ID, ProviderID: provider.ID, Active: false}
+	if _, err := data.PatchProviderUserActiveStatus(tx, pu); err != nil {
+		return err
+	}
+
+	opts := data.DeleteAccessKeysOptions{ByIssuedForID: identity.ID, ByProviderID: provider.ID}
+	if err := data.DeleteAccessKeys(tx, opts); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return nil
+}
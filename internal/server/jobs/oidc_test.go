@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+// fakeOIDCClient returns the groups for each user by email, and rejects the
+// refresh token of the users in removed. When invalidClient is set every
+// refresh is rejected because of the client credentials.
+type fakeOIDCClient struct {
+	providers.OIDCClient
+	groups        map[string][]string
+	removed       map[string]bool
+	unavailable   bool
+	invalidClient bool
+}
+
+func (f *fakeOIDCClient) RefreshAccessToken(_ context.Context, pu *models.ProviderUser) (string, *time.Time, error) {
+	switch {
+	case f.unavailable:
+		return "", nil, fmt.Errorf("%w: connection refused", internal.ErrBadGateway)
+	case f.invalidClient:
+		resp := &http.Response{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}
+		return "", nil, fmt.Errorf("refresh user token: %w", &oauth2.RetrieveError{
+			Response: resp,
+			Body:     []byte(`{"error":"invalid_client"}`),
+		})
+	case f.removed[pu.Email]:
+		resp := &http.Response{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"}
+		return "", nil, fmt.Errorf("refresh user token: %w", &oauth2.RetrieveError{
+			Response: resp,
+			Body:     []byte(`{"error":"invalid_grant","error_description":"refresh token expired"}`),
+		})
+	}
+	return string(pu.AccessToken), &pu.ExpiresAt, nil
+}
+
+func (f *fakeOIDCClient) GetUserInfo(_ context.Context, pu *models.ProviderUser) (*providers.UserInfoClaims, error) {
+	return &providers.UserInfoClaims{Email: pu.Email, Groups: f.groups[pu.Email]}, nil
+}
+
+func TestSyncOIDCProviderUsers(t *testing.T) {
+	db := setupDB(t)
+
+	provider := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+	assert.NilError(t, data.CreateProvider(db, provider))
+
+	createUser := func(t *testing.T, name string, refreshToken string, groups ...string) *models.Identity {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		assert.NilError(t, data.CreateIdentity(db, user))
+		pu, err := data.CreateProviderUser(db, provider, user)
+		assert.NilError(t, err)
+		pu.AccessToken = "access-token"
+		pu.RefreshToken = models.EncryptedAtRest(refreshToken)
+		pu.ExpiresAt = time.Now().Add(time.Hour)
+		assert.NilError(t, data.UpdateProviderUser(db, pu))
+		assert.NilError(t, data.AssignIdentityToGroups(db, user, provider, groups))
+		return user
+	}
+	ada := createUser(t, "ada@example.com", "refresh-token", "developers")
+	grace := createUser(t, "grace@example.com", "refresh-token", "developers")
+	// without a refresh token the user can not be synced
+	alan := createUser(t, "alan@example.com", "", "developers")
+
+	key := &models.AccessKey{
+		IssuedFor:  grace.ID,
+		ProviderID: provider.ID,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	_, err := data.CreateAccessKey(db, key)
+	assert.NilError(t, err)
+
+	client := &fakeOIDCClient{
+		groups:  map[string][]string{"ada@example.com": {"developers", "admins"}},
+		removed: map[string]bool{"grace@example.com": true},
+	}
+	ctx := providers.WithOIDCClient(context.Background(), client)
+	assert.NilError(t, SyncOIDCProviderUsers(ctx, db))
+
+	pu, err := data.GetProviderUser(db, provider.ID, ada.ID)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string(pu.Groups), []string{"developers", "admins"})
+	assert.Assert(t, pu.Active)
+
+	pu, err = data.GetProviderUser(db, provider.ID, grace.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pu.Groups), 0)
+	assert.Assert(t, !pu.Active)
+
+	_, err = data.GetAccessKeyByKeyID(db, key.KeyID)
+	assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+
+	pu, err = data.GetProviderUser(db, provider.ID, alan.ID)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string(pu.Groups), []string{"developers"})
+
+	t.Run("client credentials are rejected", func(t *testing.T) {
+		client := &fakeOIDCClient{invalidClient: true}
+		ctx := providers.WithOIDCClient(context.Background(), client)
+		assert.NilError(t, SyncOIDCProviderUsers(ctx, db))
+
+		// users are not deactivated
+		pu, err := data.GetProviderUser(db, provider.ID, ada.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"developers", "admins"})
+		assert.Assert(t, pu.Active)
+	})
+
+	t.Run("identity provider is unavailable", func(t *testing.T) {
+		client := &fakeOIDCClient{unavailable: true}
+		ctx := providers.WithOIDCClient(context.Background(), client)
+		assert.NilError(t, SyncOIDCProviderUsers(ctx, db))
+
+		// groups are not changed
+		pu, err := data.GetProviderUser(db, provider.ID, ada.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"developers", "admins"})
+		assert.Assert(t, pu.Active)
+	})
+}
+
+func TestIsUserRemovedFromProvider(t *testing.T) {
+	retrieveErr := func(code int, body string) error {
+		return fmt.Errorf("refresh user token: %w", &oauth2.RetrieveError{
+			Response: &http.Response{StatusCode: code},
+			Body:     []byte(body),
+		})
+	}
+
+	assert.Assert(t, isUserRemovedFromProvider(retrieveErr(http.StatusBadRequest, `{"error":"invalid_grant"}`)))
+	assert.Assert(t, isUserRemovedFromProvider(retrieveErr(http.StatusBadRequest, `error=invalid_grant&error_description=revoked`)))
+	assert.Assert(t, !isUserRemovedFromProvider(retrieveErr(http.StatusUnauthorized, `{"error":"invalid_client"}`)))
+	assert.Assert(t, !isUserRemovedFromProvider(retrieveErr(http.StatusBadRequest, `{"error":"invalid_request"}`)))
+	assert.Assert(t, !isUserRemovedFromProvider(retrieveErr(http.StatusBadRequest, ``)))
+	assert.Assert(t, !isUserRemovedFromProvider(retrieveErr(http.StatusBadGateway, `<html>bad gateway</html>`)))
+	assert.Assert(t, !isUserRemovedFromProvider(fmt.Errorf("connection refused")))
+	assert.Assert(t, !isUserRemovedFromProvider(&oauth2.RetrieveError{}))
+}
@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	// webhookBatchSize is the maximum number of deliveries attempted by each
+	// run of DeliverWebhooks.
+	webhookBatchSize = 50
+	// webhookMaxAttempts is the number of attempts to deliver an event before
+	// the delivery is marked as failed.
+	webhookMaxAttempts = 8
+	// webhookRetryDelay is the delay before the first retry. The delay doubles
+	// after each failed attempt, up to webhookMaxRetryDelay.
+	webhookRetryDelay    = 30 * time.Second
+	webhookMaxRetryDelay = time.Hour
+	// webhookClaimDuration is how long a delivery is claimed by a server before
+	// another server may attempt it. It must be longer than the time it takes
+	// to attempt a full batch of deliveries.
+	webhookClaimDuration = 15 * time.Minute
+)
+
+// webhookClient is the HTTP client used to deliver webhook events. It refuses
+// to connect to loopback, private, and link-local addresses, so that webhooks
+// can not be used to send requests to services on the server's network.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: rejectPrivateAddress,
+		}).DialContext,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// rejectPrivateAddress is a net.Dialer.Control function that returns an error
+// when the address is not a public IP address. It is called after the hostname
+// is resolved, so it also applies to hostnames that resolve to a private
+// address, and to redirects.
+func rejectPrivateAddress(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid IP address %q", host)
+	}
+	if !validate.IsPublicIP(ip) {
+		return fmt.Errorf("webhook address %v is not a public IP address", ip)
+	}
+	return nil
+}
+
+// DeliverWebhooks sends pending webhook deliveries. Failed deliveries are
+// retried with exponential backoff. Deliveries are stored in the database, so
+// pending deliveries are retried after the server restarts.
+//
+// Deliveries are claimed in a short transaction, and sent after that
+// transaction is committed, so that no transaction or row lock is held while
+// waiting on the webhook receiver. The result of each delivery is saved in its
+// own transaction.
+func DeliverWebhooks(ctx context.Context, db *data.DB) error {
+	deliveries, webhooks, err := claimWebhookDeliveries(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delivery := &deliveries[i]
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if ok {
+			attemptWebhookDelivery(ctx, webhook, delivery, time.Now())
+		} else {
+			delivery.Status = models.WebhookDeliveryStatusFailed
+			delivery.LastError = "webhook was deleted"
+		}
+
+		if err := saveWebhookDelivery(ctx, db, delivery); err != nil {
+			logging.L.Warn().Err(err).Str("delivery", delivery.ID.String()).Msg("failed to update webhook delivery")
+		}
+	}
+	return nil
+}
+
+// claimWebhookDeliveries claims a batch of pending deliveries, and returns
+// them along with their webhooks. Deliveries for a webhook that no longer
+// exists are returned without a webhook.
+func claimWebhookDeliveries(ctx context.Context, db *data.DB) ([]models.WebhookDelivery, map[uid.ID]*models.Webhook, error) {
+	tx, err := db.Begin(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	deliveries, err := data.ClaimWebhookDeliveries(tx, webhookBatchSize, time.Now().Add(webhookClaimDuration))
+	if err != nil {
+		return nil, nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+
+	webhooks := make(map[uid.ID]*models.Webhook)
+	for _, delivery := range deliveries {
+		if _, ok := webhooks[delivery.WebhookID]; ok {
+			continue
+		}
+		orgTx := tx.WithOrgID(delivery.OrganizationID)
+		webhook, err := data.GetWebhook(orgTx, data.GetWebhookOptions{ByID: delivery.WebhookID})
+		switch {
+		case errors.Is(err, internal.ErrNotFound):
+			continue
+		case err != nil:
+			return nil, nil, fmt.Errorf("get webhook: %w", err)
+		}
+		webhooks[delivery.WebhookID] = webhook
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("commit claimed webhook deliveries: %w", err)
+	}
+	return deliveries, webhooks, nil
+}
+
+func saveWebhookDelivery(ctx context.Context, db *data.DB, delivery *models.WebhookDelivery) error {
+	tx, err := db.Begin(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := data.UpdateWebhookDelivery(tx.WithOrgID(delivery.OrganizationID), delivery); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// attemptWebhookDelivery sends the delivery to the webhook, and updates the
+// status of the delivery with the result.
+func attemptWebhookDelivery(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+	delivery.LastAttemptAt = now
+
+	status, err := postWebhook(ctx, webhook, delivery, now)
+	delivery.ResponseStatus = status
+	if err == nil {
+		delivery.Status = models.WebhookDeliveryStatusSucceeded
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	logging.L.Debug().Err(err).
+		Str("webhook", webhook.ID.String()).
+		Str("delivery", delivery.ID.String()).
+		Int("attempts", delivery.Attempts).
+		Msg("webhook delivery failed")
+
+	if delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = models.WebhookDeliveryStatusFailed
+		return
+	}
+	delivery.NextAttemptAt = now.Add(retryDelay(delivery.Attempts))
+}
+
+func postWebhook(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Infra/"+internal.FullVersion())
+	req.Header.Set(api.HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(api.HeaderWebhookDelivery, delivery.ID.String())
+	req.Header.Set(api.HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(api.HeaderWebhookSignature, signWebhookPayload(string(webhook.Secret), timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// read some of the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status: %v", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the signature of a webhook delivery. The timestamp
+// is included in the signature so that a receiver can reject old deliveries.
+func signWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay returns the delay before the next attempt, after attempts
+// failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := webhookRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxRetryDelay {
+			return webhookMaxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,143 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAttemptWebhookDelivery(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   string
+	}
+
+	setup := func(t *testing.T, status int) (*models.Webhook, chan request) {
+		requests := make(chan request, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(req.Body)
+			assert.Check(t, err)
+			requests <- request{header: req.Header, body: string(body)}
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(srv.Close)
+
+		// the test server listens on a loopback address, which webhookClient
+		// refuses to connect to.
+		origClient := webhookClient
+		webhookClient = srv.Client()
+		t.Cleanup(func() {
+			webhookClient = origClient
+		})
+
+		webhook := &models.Webhook{
+			Model:  models.Model{ID: uid.ID(12)},
+			URL:    srv.URL,
+			Secret: "the-secret",
+		}
+		return webhook, requests
+	}
+
+	now := time.Date(2022, 12, 2, 9, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		webhook, requests := setup(t, http.StatusNoContent)
+		delivery := &models.WebhookDelivery{
+			Model:     models.Model{ID: uid.ID(34)},
+			WebhookID: webhook.ID,
+			EventType: "grant.created",
+			Payload:   `{"type":"grant.created"}`,
+			Status:    models.WebhookDeliveryStatusPending,
+		}
+
+		attemptWebhookDelivery(ctx, webhook, delivery, now)
+		assert.Equal(t, delivery.Status, models.WebhookDeliveryStatusSucceeded)
+		assert.Equal(t, delivery.Attempts, 1)
+		assert.Equal(t, delivery.ResponseStatus, http.StatusNoContent)
+		assert.Equal(t, delivery.LastAttemptAt, now)
+		assert.Equal(t, delivery.LastError, "")
+
+		req := <-requests
+		assert.Equal(t, req.body, `{"type":"grant.created"}`)
+		assert.Equal(t, req.header.Get(api.HeaderWebhookEvent), "grant.created")
+		assert.Equal(t, req.header.Get(api.HeaderWebhookDelivery), delivery.ID.String())
+		assert.Equal(t, req.header.Get(api.HeaderWebhookTimestamp), "1669971600")
+		expected := signWebhookPayload("the-secret", "1669971600", []byte(req.body))
+		assert.Equal(t, req.header.Get(api.HeaderWebhookSignature), expected)
+	})
+
+	t.Run("failure is retried", func(t *testing.T) {
+		webhook, requests := setup(t, http.StatusBadGateway)
+		delivery := &models.WebhookDelivery{
+			Model:     models.Model{ID: uid.ID(35)},
+			WebhookID: webhook.ID,
+			EventType: "grant.created",
+			Payload:   `{}`,
+			Status:    models.WebhookDeliveryStatusPending,
+			Attempts:  2,
+		}
+
+		attemptWebhookDelivery(ctx, webhook, delivery, now)
+		<-requests
+		assert.Equal(t, delivery.Status, models.WebhookDeliveryStatusPending)
+		assert.Equal(t, delivery.Attempts, 3)
+		assert.Equal(t, delivery.ResponseStatus, http.StatusBadGateway)
+		assert.Equal(t, delivery.NextAttemptAt, now.Add(2*time.Minute))
+		assert.Equal(t, delivery.LastError, "unexpected response status: 502 Bad Gateway")
+	})
+
+	t.Run("fails after max attempts", func(t *testing.T) {
+		webhook, requests := setup(t, http.StatusInternalServerError)
+		delivery := &models.WebhookDelivery{
+			Model:     models.Model{ID: uid.ID(36)},
+			WebhookID: webhook.ID,
+			EventType: "grant.created",
+			Payload:   `{}`,
+			Status:    models.WebhookDeliveryStatusPending,
+			Attempts:  webhookMaxAttempts - 1,
+		}
+
+		attemptWebhookDelivery(ctx, webhook, delivery, now)
+		<-requests
+		assert.Equal(t, delivery.Status, models.WebhookDeliveryStatusFailed)
+		assert.Equal(t, delivery.Attempts, webhookMaxAttempts)
+	})
+}
+
+func TestWebhookClient_RejectsPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Error("unexpected request to private address")
+	}))
+	t.Cleanup(srv.Close)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL, nil)
+	assert.NilError(t, err)
+	_, err = webhookClient.Do(req)
+	assert.ErrorContains(t, err, "is not a public IP address")
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// printf '1669971600.{}' | openssl dgst -sha256 -hmac the-secret
+	expected := "sha256=507281c0dd9efbedd2ce5ac7728a60d47b8b18d40dbea667de9530f11050bb26"
+	actual := signWebhookPayload("the-secret", "1669971600", []byte("{}"))
+	assert.Equal(t, actual, expected)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, retryDelay(1), 30*time.Second)
+	assert.Equal(t, retryDelay(2), time.Minute)
+	assert.Equal(t, retryDelay(3), 2*time.Minute)
+	assert.Equal(t, retryDelay(7), 32*time.Minute)
+	assert.Equal(t, retryDelay(8), time.Hour)
+	assert.Equal(t, retryDelay(20), time.Hour)
+}
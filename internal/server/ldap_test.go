@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/testing/ldap"
+)
+
+func runLDAPTestServer(t *testing.T) *ldap.Server {
+	return ldap.NewServer(t,
+		ldap.Entry{
+			DN:         "cn=infra,dc=example,dc=com",
+			Attributes: map[string][]string{"userPassword": {"service-password"}},
+		},
+		ldap.Entry{
+			DN: "uid=ada,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass":  {"person"},
+				"uid":          {"ada"},
+				"mail":         {"ada@example.com"},
+				"userPassword": {"password"},
+			},
+		},
+		ldap.Entry{
+			DN: "cn=developers,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"developers"},
+				"member":      {"uid=ada,ou=people,dc=example,dc=com"},
+			},
+		},
+	)
+}
+
+func TestAPI_LDAP(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+	ldapServer := runLDAPTestServer(t)
+
+	do := func(t *testing.T, method, path string, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createRequest := api.CreateProviderRequest{
+		Name: "corp",
+		Kind: "ldap",
+		URL:  ldapServer.URL,
+		LDAP: &api.ProviderLDAP{
+			BindDN:       "cn=infra,dc=example,dc=com",
+			BindPassword: "service-password",
+			UserBaseDN:   "dc=example,dc=com",
+		},
+	}
+
+	var provider api.Provider
+	t.Run("create provider", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/providers", adminAccessKey(srv), createRequest)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &provider))
+		assert.Equal(t, provider.Kind, "ldap")
+		assert.Equal(t, provider.URL, ldapServer.URL)
+		// the bind password is never included in a response
+		expected := &api.ProviderLDAP{
+			BindDN:     "cn=infra,dc=example,dc=com",
+			UserBaseDN: "dc=example,dc=com",
+		}
+		assert.DeepEqual(t, provider.LDAP, expected)
+	})
+
+	t.Run("create provider with wrong bind password", func(t *testing.T) {
+		req := createRequest
+		req.Name = "wrong"
+		req.LDAP = &api.ProviderLDAP{
+			BindDN:       "cn=infra,dc=example,dc=com",
+			BindPassword: "wrong",
+			UserBaseDN:   "dc=example,dc=com",
+		}
+		resp := do(t, http.MethodPost, "/api/providers", adminAccessKey(srv), req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("update provider keeps the bind password", func(t *testing.T) {
+		req := api.UpdateProviderRequest{
+			Name: "corp",
+			Kind: "ldap",
+			URL:  ldapServer.URL,
+			LDAP: &api.ProviderLDAP{
+				BindDN:            "cn=infra,dc=example,dc=com",
+				UserBaseDN:        "dc=example,dc=com",
+				UserNameAttribute: "mail",
+			},
+		}
+		resp := do(t, http.MethodPut, "/api/providers/"+provider.ID.String(), adminAccessKey(srv), req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("login", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/login", "", api.LoginRequest{
+			LDAP: &api.LoginRequestLDAP{ProviderID: provider.ID, Name: "ada", Password: "password"},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &loginResp))
+		assert.Equal(t, loginResp.Name, "ada@example.com")
+
+		pu, err := data.GetProviderUser(srv.DB(), provider.ID, loginResp.UserID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"developers"})
+	})
+
+	t.Run("login with wrong password", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/login", "", api.LoginRequest{
+			LDAP: &api.LoginRequestLDAP{ProviderID: provider.ID, Name: "ada", Password: "wrong"},
+		})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("oidc login with an ldap provider", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/login", "", api.LoginRequest{
+			OIDC: &api.LoginRequestOIDC{ProviderID: provider.ID, RedirectURL: "https://example.com", Code: "code"},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+}
@@ -66,6 +66,9 @@ func loggingMiddleware(enableSampling bool) gin.HandlerFunc {
 		}
 
 		rCtx := access.GetRequestContext(c)
+		if rCtx.RequestID != 0 {
+			event = event.Str("requestID", rCtx.RequestID.String())
+		}
 		if user := rCtx.Authenticated.User; user != nil {
 			event = event.Str("userID", user.ID.String())
 		}
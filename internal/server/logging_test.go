@@ -42,7 +42,8 @@ func TestLoggingMiddleware(t *testing.T) {
 					User:         &models.Identity{Model: models.Model{ID: 12345}},
 					Organization: &models.Organization{Model: models.Model{ID: 2323}},
 				},
-				Response: &access.ResponseMetadata{},
+				RequestID: uid.ID(777),
+				Response:  &access.ResponseMetadata{},
 			})
 		})
 
@@ -95,7 +96,7 @@ func TestLoggingMiddleware(t *testing.T) {
 		assert.DeepEqual(t, actual, expected)
 	})
 
-	t.Run("with userID and orgID", func(t *testing.T) {
+	t.Run("with userID, orgID, and requestID", func(t *testing.T) {
 		b := &bytes.Buffer{}
 		router := setup(t, b)
 		resp := httptest.NewRecorder()
@@ -111,6 +112,7 @@ func TestLoggingMiddleware(t *testing.T) {
 				Level:      "info",
 				UserID:     uid.ID(12345),
 				OrgID:      uid.ID(2323),
+				RequestID:  uid.ID(777),
 			},
 		}
 		assert.DeepEqual(t, actual, expected)
@@ -139,4 +141,5 @@ type logEntry struct {
 	Level      string `json:"level"`
 	UserID     uid.ID `json:"userID"`
 	OrgID      uid.ID `json:"orgID"`
+	RequestID  uid.ID `json:"requestID"`
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/jobs"
 	"github.com/infrahq/infra/metrics"
 )
 
@@ -123,5 +124,29 @@ func setupMetrics(db *data.DB) *prometheus.Registry {
 		}
 	}))
 
+	registry.MustRegister(metrics.NewCollector(prometheus.Opts{
+		Namespace: "infra",
+		Name:      "provider_user_sync_lag_seconds",
+		Help:      "The number of seconds since the least recently synced provider user was synced",
+	}, []string{"kind"}, func() []metrics.Metric {
+		results, err := data.ProviderUsersSyncLagByKind(db)
+		if err != nil {
+			logging.L.Warn().Err(err).Msg("provider user sync lag")
+			return []metrics.Metric{}
+		}
+
+		values := make([]metrics.Metric, 0, len(results))
+		for _, result := range results {
+			values = append(values, metrics.Metric{
+				Count:       result.Seconds,
+				LabelValues: []string{result.Kind},
+			})
+		}
+
+		return values
+	}))
+
+	registry.MustRegister(jobs.ProviderUserSyncFailures)
+
 	return registry
 }
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+)
+
+// totpIssuer is the name of the account issuer shown by authenticator apps.
+const totpIssuer = "Infra"
+
+func (a *API) StartTOTPEnrollment(c *gin.Context, r *api.Resource) (*api.StartTOTPEnrollmentResponse, error) {
+	identity, err := access.GetIdentity(c, data.GetIdentityOptions{ByID: r.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := access.StartTOTPEnrollment(c, identity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.StartTOTPEnrollmentResponse{
+		Secret:    secret,
+		SecretURI: authn.TOTPProvisioningURI(totpIssuer, identity.Name, secret),
+	}, nil
+}
+
+func (a *API) ConfirmTOTPEnrollment(c *gin.Context, r *api.ConfirmTOTPEnrollmentRequest) (*api.ConfirmTOTPEnrollmentResponse, error) {
+	codes, err := access.ConfirmTOTPEnrollment(c, r.ID, r.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ConfirmTOTPEnrollmentResponse{RecoveryCodes: codes}, nil
+}
+
+func (a *API) DisableTOTP(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DisableTOTP(c, r.ID)
+}
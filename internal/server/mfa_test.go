@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestAPI_MFA(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	userKey, user := createAccessKey(t, srv.DB(), "goten@example.com")
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	assert.NilError(t, err)
+	err = data.CreateCredential(srv.DB(), &models.Credential{IdentityID: user.ID, PasswordHash: hash})
+	assert.NilError(t, err)
+
+	do := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	passwordLogin := api.LoginRequest{
+		PasswordCredentials: &api.LoginRequestPasswordCredentials{Name: user.Name, Password: "hunter2"},
+	}
+	totpPath := "/api/users/" + user.ID.String() + "/totp"
+
+	var secret string
+	t.Run("enroll", func(t *testing.T) {
+		resp := do(t, http.MethodPost, totpPath, userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var enrollment api.StartTOTPEnrollmentResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+		assert.Assert(t, enrollment.Secret != "")
+		secret = enrollment.Secret
+
+		resp = do(t, http.MethodPut, totpPath, userKey, api.ConfirmTOTPEnrollmentRequest{Code: "000000"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		code, err := authn.GenerateTOTPCode(secret, time.Now())
+		assert.NilError(t, err)
+		resp = do(t, http.MethodPut, totpPath, userKey, api.ConfirmTOTPEnrollmentRequest{Code: code})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var confirmed api.ConfirmTOTPEnrollmentResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&confirmed))
+		assert.Equal(t, len(confirmed.RecoveryCodes), 10)
+	})
+
+	t.Run("login with mfa", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/login", "", passwordLogin)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Equal(t, len(resp.Result().Cookies()), 0)
+
+		var loginResp api.LoginResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&loginResp))
+		assert.Equal(t, loginResp.AccessKey, "")
+		assert.Assert(t, loginResp.MFAChallenge != "")
+
+		// the challenge can not be used as an access key
+		resp = do(t, http.MethodGet, "/api/users/"+user.ID.String(), loginResp.MFAChallenge, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		// the code used to confirm enrollment can not be used again, so use
+		// the code from the next period, which is accepted for clock drift.
+		code, err := authn.GenerateTOTPCode(secret, time.Now().Add(30*time.Second))
+		assert.NilError(t, err)
+		mfaLogin := api.LoginRequest{MFA: &api.LoginRequestMFA{Challenge: loginResp.MFAChallenge, Code: code}}
+		resp = do(t, http.MethodPost, "/api/login", "", mfaLogin)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		loginResp = api.LoginResponse{}
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&loginResp))
+		assert.Assert(t, loginResp.AccessKey != "")
+		assert.Equal(t, loginResp.UserID, user.ID)
+
+		resp = do(t, http.MethodGet, "/api/users/"+user.ID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("login with wrong code", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/login", "", passwordLogin)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&loginResp))
+
+		mfaLogin := api.LoginRequest{MFA: &api.LoginRequestMFA{Challenge: loginResp.MFAChallenge, Code: "000000"}}
+		resp = do(t, http.MethodPost, "/api/login", "", mfaLogin)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("disable requires self or admin", func(t *testing.T) {
+		otherKey, _ := createAccessKey(t, srv.DB(), "trunks@example.com")
+		resp := do(t, http.MethodDelete, totpPath, otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = do(t, http.MethodDelete, totpPath, adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		credential, err := data.GetCredentialByUserID(srv.DB(), user.ID)
+		assert.NilError(t, err)
+		assert.Assert(t, !credential.TOTPEnabled)
+	})
+
+	t.Run("org requires mfa", func(t *testing.T) {
+		settings, err := data.GetSettings(srv.DB())
+		assert.NilError(t, err)
+		settings.RequireMFA = true
+		assert.NilError(t, data.UpdateSettings(srv.DB(), settings))
+
+		resp := do(t, http.MethodPost, "/api/login", "", passwordLogin)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&loginResp))
+		assert.Assert(t, loginResp.MFAEnrollmentRequired)
+
+		// the access key can only be used to enroll
+		resp = do(t, http.MethodGet, "/api/users/"+user.ID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		resp = do(t, http.MethodPost, totpPath, loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var enrollment api.StartTOTPEnrollmentResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&enrollment))
+
+		code, err := authn.GenerateTOTPCode(enrollment.Secret, time.Now())
+		assert.NilError(t, err)
+		resp = do(t, http.MethodPut, totpPath, loginResp.AccessKey, api.ConfirmTOTPEnrollmentRequest{Code: code})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = do(t, http.MethodGet, "/api/users/"+user.ID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+}
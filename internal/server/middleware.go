@@ -167,11 +167,21 @@ func requireAccessKey(c *gin.Context, db *data.Transaction, srv *Server) (access
 		return u, fmt.Errorf("%w: invalid token: %s", internal.ErrUnauthorized, err)
 	}
 
-	if accessKey.Scopes.Includes(models.ScopePasswordReset) {
+	userPath := "/api/users/" + accessKey.IssuedFor.String()
+	switch {
+	case accessKey.Scopes.Includes(models.ScopeMFAChallenge):
+		// challenges can only be used to complete a login
+		return u, fmt.Errorf("%w: mfa challenges can only be used to login", internal.ErrUnauthorized)
+	case accessKey.Scopes.Includes(models.ScopePasswordReset):
 		// PUT /api/users/:id only
-		if c.Request.URL.Path != "/api/users/"+accessKey.IssuedFor.String() || c.Request.Method != http.MethodPut {
+		if c.Request.URL.Path != userPath || c.Request.Method != http.MethodPut {
 			return u, fmt.Errorf("%w: temporary passwords can only be used to set new passwords", internal.ErrUnauthorized)
 		}
+	case accessKey.Scopes.Includes(models.ScopeMFAEnrollment):
+		// /api/users/:id/totp only
+		if c.Request.URL.Path != userPath+"/totp" {
+			return u, fmt.Errorf("%w: multi-factor authentication must be enabled before using this access key", internal.ErrUnauthorized)
+		}
 	}
 
 	org, err := data.GetOrganization(db, data.GetOrganizationOptions{ByID: accessKey.OrganizationID})
@@ -200,7 +210,7 @@ func requireAccessKey(c *gin.Context, db *data.Transaction, srv *Server) (access
 
 		if time.Since(identity.LastSeenAt) > lastSeenUpdateThreshold {
 			identity.LastSeenAt = time.Now().UTC()
-			if err = data.UpdateIdentity(db, identity); err != nil {
+			if err = data.UpdateIdentityLastSeenAt(db, identity); err != nil {
 				return u, fmt.Errorf("identity update fail: %w", err)
 			}
 		}
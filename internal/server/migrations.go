@@ -41,6 +41,38 @@ func (a *API) addRequestRewrites() {
 			InactivityTimeout: o.ExtensionDeadline,
 		}
 	})
+
+	// Clients before 0.18.2 expect page and limit pagination when they do not
+	// request a page. Newer clients get cursor pagination by default.
+	addRequestRewrite(a, http.MethodGet, "/api/users", "0.18.1", func(r api.ListUsersRequest) api.ListUsersRequest {
+		r.PaginationRequest = pageOrFirstPage(r.PaginationRequest)
+		return r
+	})
+	addRequestRewrite(a, http.MethodGet, "/api/groups", "0.18.1", func(r api.ListGroupsRequest) api.ListGroupsRequest {
+		r.PaginationRequest = pageOrFirstPage(r.PaginationRequest)
+		return r
+	})
+	addRequestRewrite(a, http.MethodGet, "/api/grants", "0.18.1", func(r api.ListGrantsRequest) api.ListGrantsRequest {
+		if !r.IsBlockingRequest() {
+			r.PaginationRequest = pageOrFirstPage(r.PaginationRequest)
+		}
+		return r
+	})
+	addRequestRewrite(a, http.MethodGet, "/api/destinations", "0.18.1", func(r api.ListDestinationsRequest) api.ListDestinationsRequest {
+		r.PaginationRequest = pageOrFirstPage(r.PaginationRequest)
+		return r
+	})
+	addRequestRewrite(a, http.MethodGet, "/api/access-keys", "0.18.1", func(r api.ListAccessKeysRequest) api.ListAccessKeysRequest {
+		r.PaginationRequest = pageOrFirstPage(r.PaginationRequest)
+		return r
+	})
+}
+
+func pageOrFirstPage(p api.PaginationRequest) api.PaginationRequest {
+	if p.Page == 0 {
+		p.Page = 1
+	}
+	return p
 }
 
 func (a *API) addResponseRewrites() {
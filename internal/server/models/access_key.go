@@ -15,6 +15,13 @@ var (
 const (
 	ScopePasswordReset        string = "password-reset"
 	ScopeAllowCreateAccessKey string = "create-key"
+	// ScopeMFAChallenge is the scope of the access key returned by the first
+	// step of a login that requires MFA. It can only be used to complete the
+	// login.
+	ScopeMFAChallenge string = "mfa-challenge"
+	// ScopeMFAEnrollment restricts an access key to enrolling in MFA, when the
+	// organization requires MFA.
+	ScopeMFAEnrollment string = "mfa-enrollment"
 )
 
 // AccessKey is a session token presented to the Infra server as proof of authentication
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+type AccessRequestStatus string
+
+const (
+	AccessRequestStatusPending  AccessRequestStatus = "pending"
+	AccessRequestStatusApproved AccessRequestStatus = "approved"
+	AccessRequestStatusDenied   AccessRequestStatus = "denied"
+)
+
+// AccessRequest is a request from a user for a privilege on a resource. An
+// admin approves or denies the request. An approved request creates a Grant
+// that expires after Duration.
+type AccessRequest struct {
+	Model
+	OrganizationMember
+
+	// UserID is the ID of the user who requested access.
+	UserID    uid.ID
+	Privilege string
+	Resource  string
+	// Duration is how long the grant created by approving the request will
+	// be valid.
+	Duration time.Duration
+	// Reason is the justification given by the user for requesting access.
+	Reason string
+
+	Status AccessRequestStatus
+	// DecidedBy is the ID of the user who approved or denied the request.
+	DecidedBy uid.ID
+	// DecidedAt is the time the request was approved or denied.
+	DecidedAt      time.Time
+	DecisionReason string
+	// GrantID is the ID of the grant created when the request was approved.
+	GrantID uid.ID
+}
+
+func (r *AccessRequest) ToAPI() *api.AccessRequest {
+	return &api.AccessRequest{
+		ID:             r.ID,
+		Created:        api.Time(r.CreatedAt),
+		Updated:        api.Time(r.UpdatedAt),
+		User:           r.UserID,
+		Privilege:      r.Privilege,
+		Resource:       r.Resource,
+		Duration:       api.Duration(r.Duration),
+		Reason:         r.Reason,
+		Status:         string(r.Status),
+		DecidedBy:      r.DecidedBy,
+		Decided:        api.Time(r.DecidedAt),
+		DecisionReason: r.DecisionReason,
+		Grant:          r.GrantID,
+	}
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+type AccessReviewStatus string
+
+const (
+	AccessReviewStatusOpen   AccessReviewStatus = "open"
+	AccessReviewStatusClosed AccessReviewStatus = "closed"
+)
+
+// AccessReview is a campaign to re-certify the grants on a set of destinations,
+// or the grants of a set of groups and their members. When the review is
+// created each of the grants in scope becomes an AccessReviewItem. When the
+// review is closed the grants that were revoked, or that were not reviewed,
+// are deleted.
+type AccessReview struct {
+	Model
+	OrganizationMember
+
+	Name string
+	// Destinations limits the review to grants on these destinations.
+	Destinations CommaSeparatedStrings
+	// Groups limits the review to grants of these groups, the groups nested in
+	// them, and their members. Each value is a group ID.
+	Groups CommaSeparatedStrings
+
+	Status AccessReviewStatus
+	// CreatedBy is the ID of the user who started the review.
+	CreatedBy uid.ID
+	// ClosedBy is the ID of the user who closed the review.
+	ClosedBy uid.ID
+	ClosedAt time.Time
+}
+
+func (r *AccessReview) ToAPI() *api.AccessReview {
+	groups := make([]uid.ID, 0, len(r.Groups))
+	for _, raw := range r.Groups {
+		if id, err := uid.Parse([]byte(raw)); err == nil {
+			groups = append(groups, id)
+		}
+	}
+	return &api.AccessReview{
+		ID:           r.ID,
+		Created:      api.Time(r.CreatedAt),
+		Updated:      api.Time(r.UpdatedAt),
+		Name:         r.Name,
+		Destinations: r.Destinations,
+		Groups:       groups,
+		Status:       string(r.Status),
+		CreatedBy:    r.CreatedBy,
+		ClosedBy:     r.ClosedBy,
+		Closed:       api.Time(r.ClosedAt),
+	}
+}
+
+type AccessReviewDecision string
+
+const (
+	AccessReviewDecisionPending AccessReviewDecision = "pending"
+	AccessReviewDecisionKeep    AccessReviewDecision = "keep"
+	AccessReviewDecisionRevoke  AccessReviewDecision = "revoke"
+	// AccessReviewDecisionUnreviewed is the decision of an item that was still
+	// pending when the review was closed. The grant is deleted.
+	AccessReviewDecisionUnreviewed AccessReviewDecision = "unreviewed"
+)
+
+// AccessReviewItem is a single grant in an AccessReview. The subject, privilege,
+// and resource are copied from the grant when the review is created, so that
+// the review remains a record of the access after the grant is deleted.
+type AccessReviewItem struct {
+	Model
+	OrganizationMember
+
+	AccessReviewID uid.ID
+	GrantID        uid.ID
+	Subject        uid.PolymorphicID
+	Privilege      string
+	Resource       string
+
+	// ReviewerID is the ID of the user who is assigned to review the grant.
+	ReviewerID uid.ID
+	Decision   AccessReviewDecision
+	// DecidedBy is the ID of the user who decided to keep or revoke the grant.
+	DecidedBy uid.ID
+	DecidedAt time.Time
+	Reason    string
+}
+
+func (i *AccessReviewItem) ToAPI() *api.AccessReviewItem {
+	item := &api.AccessReviewItem{
+		ID:           i.ID,
+		AccessReview: i.AccessReviewID,
+		Grant:        i.GrantID,
+		Privilege:    i.Privilege,
+		Resource:     i.Resource,
+		Reviewer:     i.ReviewerID,
+		Decision:     string(i.Decision),
+		DecidedBy:    i.DecidedBy,
+		Decided:      api.Time(i.DecidedAt),
+		Reason:       i.Reason,
+	}
+	switch {
+	case i.Subject.IsIdentity():
+		item.User, _ = i.Subject.ID()
+	case i.Subject.IsGroup():
+		item.Group, _ = i.Subject.ID()
+	}
+	return item
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// AuditEvent is a record of a successful API call that changed the state of
+// an organization.
+type AuditEvent struct {
+	Model
+	OrganizationMember
+
+	// ActorID is the ID of the user who performed the action.
+	ActorID uid.ID
+	// ActorName is the name of the user at the time of the action. It is
+	// stored so that the event remains readable after the user is deleted.
+	ActorName string
+	// Action is the name of the API operation, ex: CreateGrant.
+	Action string
+	// TargetType is the kind of resource that was changed, ex: grants.
+	TargetType string
+	// TargetID is the ID of the resource that was changed, if known.
+	TargetID uid.ID
+	// RequestID identifies the API request that caused the event.
+	RequestID uid.ID
+	// BeforeSummary is a JSON summary of the resource before the change. It is
+	// empty for creates, and for updates where the previous value was not loaded.
+	BeforeSummary string
+	// AfterSummary is a JSON summary of the resource after the change, or
+	// of the request when the response has no body.
+	AfterSummary string
+}
+
+func (e *AuditEvent) ToAPI() *api.AuditEvent {
+	return &api.AuditEvent{
+		ID:         e.ID,
+		Created:    api.Time(e.CreatedAt),
+		Actor:      e.ActorID,
+		ActorName:  e.ActorName,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		Target:     e.TargetID,
+		RequestID:  e.RequestID,
+		Before:     e.BeforeSummary,
+		After:      e.AfterSummary,
+	}
+}
@@ -9,4 +9,17 @@ type Credential struct {
 	IdentityID      uid.ID
 	PasswordHash    []byte
 	OneTimePassword bool
+
+	// TOTPSecret is the base32 encoded secret used to verify time-based
+	// one-time passwords. The secret is set when enrollment starts, and
+	// TOTPEnabled is set once the user has verified a code from the secret.
+	TOTPSecret  EncryptedAtRest
+	TOTPEnabled bool
+	// TOTPLastCounter is the time step of the last TOTP code that was accepted.
+	// Codes from the same or an earlier time step are rejected, so that a code
+	// can only be used once.
+	TOTPLastCounter int64
+	// RecoveryCodes are the SHA256 hashes of the recovery codes that have not
+	// been used yet. A recovery code can be used once instead of a TOTP code.
+	RecoveryCodes CommaSeparatedStrings
 }
@@ -3,6 +3,9 @@ package models
 import (
 	"database/sql/driver"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/infrahq/secrets"
 )
@@ -14,6 +17,79 @@ type EncryptedAtRest string
 // SymmetricKey is the key used to encrypt and decrypt this field.
 var SymmetricKey *secrets.SymmetricKey
 
+// SymmetricKeyID identifies SymmetricKey. When it is non-zero the encrypted
+// value is prefixed with the ID, so that the value can still be decrypted
+// after the key is rotated.
+var SymmetricKeyID int32
+
+// LoadDecryptionKey is called to load the key for a value that was encrypted
+// with a key that has not been added with AddDecryptionKey. This happens when
+// the key is rotated by another process.
+var LoadDecryptionKey func(keyID int32) (*secrets.SymmetricKey, error)
+
+var decryptionKeys = struct {
+	sync.RWMutex
+	byID map[int32]*secrets.SymmetricKey
+}{byID: map[int32]*secrets.SymmetricKey{}}
+
+// AddDecryptionKey adds a key that can decrypt values encrypted with the key
+// identified by keyID. Values encrypted before keys were identified have no
+// prefix, and are decrypted with the key added with a keyID of 0.
+func AddDecryptionKey(keyID int32, key *secrets.SymmetricKey) {
+	decryptionKeys.Lock()
+	defer decryptionKeys.Unlock()
+	decryptionKeys.byID[keyID] = key
+}
+
+// ResetDecryptionKeys removes all the keys added with AddDecryptionKey.
+func ResetDecryptionKeys() {
+	decryptionKeys.Lock()
+	defer decryptionKeys.Unlock()
+	decryptionKeys.byID = map[int32]*secrets.SymmetricKey{}
+}
+
+func decryptionKey(keyID int32) (*secrets.SymmetricKey, error) {
+	decryptionKeys.RLock()
+	key, ok := decryptionKeys.byID[keyID]
+	decryptionKeys.RUnlock()
+	switch {
+	case ok:
+		return key, nil
+	case keyID == SymmetricKeyID || keyID == 0:
+		return SymmetricKey, nil
+	case LoadDecryptionKey == nil:
+		return nil, fmt.Errorf("no key to decrypt value encrypted with key %d", keyID)
+	}
+
+	key, err := LoadDecryptionKey(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("load key %d: %w", keyID, err)
+	}
+	AddDecryptionKey(keyID, key)
+	return key, nil
+}
+
+// KeyIDPrefix returns the prefix of values encrypted with the key identified
+// by keyID.
+func KeyIDPrefix(keyID int32) string {
+	return "k" + strconv.FormatInt(int64(keyID), 10) + ":"
+}
+
+// splitKeyID returns the ID of the key used to encrypt s, and the encrypted
+// value without the key ID prefix. Encrypted values are base64 encoded, so
+// they never contain a colon.
+func splitKeyID(s string) (int32, string) {
+	prefix, value, ok := strings.Cut(s, ":")
+	if !ok || !strings.HasPrefix(prefix, "k") {
+		return 0, s
+	}
+	keyID, err := strconv.ParseInt(prefix[1:], 10, 32)
+	if err != nil {
+		return 0, s
+	}
+	return int32(keyID), value
+}
+
 // SkipSymmetricKey is used for tests that specifically want to avoid field encryption
 var SkipSymmetricKey bool
 
@@ -31,6 +107,9 @@ func (s EncryptedAtRest) Encrypt() (string, error) {
 		return "", fmt.Errorf("sealing secret field: %w", err)
 	}
 
+	if SymmetricKeyID != 0 {
+		return KeyIDPrefix(SymmetricKeyID) + string(b), nil
+	}
 	return string(b), err
 }
 
@@ -47,7 +126,13 @@ func decrypt(s string) (string, error) {
 		return "", fmt.Errorf("models.SymmetricKey is not set")
 	}
 
-	b, err := secrets.Unseal(SymmetricKey, []byte(s))
+	keyID, s := splitKeyID(s)
+	key, err := decryptionKey(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := secrets.Unseal(key, []byte(s))
 	if err != nil {
 		return "", fmt.Errorf("unsealing secret field: %w", err)
 	}
@@ -3,12 +3,12 @@ package models
 type EncryptionKey struct {
 	Model
 
-	// KeyID is not used yet. KeyID is intended to be a short identifier for the key
-	// that can be embedded with the encrypted payload. Today we use the first 4
-	// bytes of a checksum of the encrypted data key instead of this identifier.
-	// In the future we will use this identifier to support key rotation.
+	// KeyID is a short identifier for the key that is embedded with the
+	// encrypted payload of EncryptedAtRest fields, so that values encrypted
+	// with an older version of the key can be decrypted after the key is rotated.
 	KeyID int32
-	// TODO: missing a unique index on name
+	// Name identifies the key. Rotating a key creates a new row with the same
+	// name, the row with the highest ID is the current version.
 	Name      string
 	Encrypted []byte
 	Algorithm string
@@ -1,8 +1,10 @@
 package models_test
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/infrahq/secrets"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 
@@ -67,25 +69,89 @@ func TestEncryptedAtRest_WithBytes(t *testing.T) {
 	db, err := data.NewDB(data.NewDBOptions{DSN: database.PostgresDriver(t, "_models").DSN})
 	assert.NilError(t, err)
 
-	settings, err := data.GetSettings(db)
+	key, err := data.GetActiveSigningKey(db)
 	assert.NilError(t, err)
 
 	t.Run("Scan", func(t *testing.T) {
 		var newEncrypted models.EncryptedAtRest
-		err := db.QueryRow(`SELECT private_jwk FROM settings WHERE id = ?`, settings.ID).Scan(&newEncrypted)
+		err := db.QueryRow(`SELECT private_jwk FROM signing_keys WHERE id = ?`, key.ID).Scan(&newEncrypted)
 		assert.NilError(t, err)
 
-		assert.Equal(t, string(settings.PrivateJWK), string(newEncrypted))
+		assert.Equal(t, string(key.PrivateJWK), string(newEncrypted))
 	})
 	t.Run("Value", func(t *testing.T) {
-		newEncrypted := settings.PrivateJWK
+		newEncrypted := key.PrivateJWK
 
-		_, err := db.Exec(`UPDATE settings SET private_jwk = ? WHERE id = ?`, newEncrypted, settings.ID)
+		_, err := db.Exec(`UPDATE signing_keys SET private_jwk = ? WHERE id = ?`, newEncrypted, key.ID)
 		assert.NilError(t, err)
 
-		updated, err := data.GetSettings(db)
+		updated, err := data.GetActiveSigningKey(db)
 		assert.NilError(t, err)
 
-		assert.Equal(t, string(updated.PrivateJWK), string(settings.PrivateJWK))
+		assert.Equal(t, string(updated.PrivateJWK), string(key.PrivateJWK))
+	})
+}
+
+func TestEncryptedAtRest_KeyRotation(t *testing.T) {
+	patch.ModelsSymmetricKey(t)
+
+	kp := secrets.NewNativeKeyProvider(secrets.NewFileSecretProviderFromConfig(secrets.FileConfig{Path: t.TempDir()}))
+	newKey := func(t *testing.T) *secrets.SymmetricKey {
+		key, err := kp.GenerateDataKey("db_at_rest")
+		assert.NilError(t, err)
+		return key
+	}
+
+	scan := func(t *testing.T, encrypted string) string {
+		t.Helper()
+		var value models.EncryptedAtRest
+		assert.NilError(t, value.Scan(encrypted))
+		return string(value)
+	}
+
+	// encrypted before keys were versioned
+	legacyKey := models.SymmetricKey
+	legacy, err := models.EncryptedAtRest("legacy").Encrypt()
+	assert.NilError(t, err)
+	models.AddDecryptionKey(0, legacyKey)
+
+	first := newKey(t)
+	models.AddDecryptionKey(11, first)
+	models.SymmetricKey, models.SymmetricKeyID = first, 11
+	fromFirst, err := models.EncryptedAtRest("first").Encrypt()
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(fromFirst, "k11:"), fromFirst)
+
+	second := newKey(t)
+	models.AddDecryptionKey(12, second)
+	models.SymmetricKey, models.SymmetricKeyID = second, 12
+
+	t.Run("decrypt older versions", func(t *testing.T) {
+		assert.Equal(t, scan(t, legacy), "legacy")
+		assert.Equal(t, scan(t, fromFirst), "first")
+	})
+
+	t.Run("load unknown version", func(t *testing.T) {
+		third := newKey(t)
+		models.SymmetricKey, models.SymmetricKeyID = third, 13
+		fromThird, err := models.EncryptedAtRest("third").Encrypt()
+		assert.NilError(t, err)
+		models.SymmetricKey, models.SymmetricKeyID = second, 12
+
+		var loaded []int32
+		models.LoadDecryptionKey = func(keyID int32) (*secrets.SymmetricKey, error) {
+			loaded = append(loaded, keyID)
+			return third, nil
+		}
+		assert.Equal(t, scan(t, fromThird), "third")
+		assert.Equal(t, scan(t, fromThird), "third")
+		assert.DeepEqual(t, loaded, []int32{13})
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		models.LoadDecryptionKey = nil
+		var value models.EncryptedAtRest
+		err := value.Scan("k99:" + legacy)
+		assert.ErrorContains(t, err, "no key to decrypt value encrypted with key 99")
 	})
 }
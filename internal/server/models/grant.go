@@ -1,6 +1,8 @@
 package models
 
 import (
+	"database/sql"
+
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/uid"
 )
@@ -37,7 +39,7 @@ const BasePermissionConnect = "connect"
 //
 // Expiry
 //
-//	time you want the grant to expire at
+//	ExpiresAt is the time you want the grant to expire at
 type Grant struct {
 	Model
 	OrganizationMember
@@ -50,6 +52,12 @@ type Grant struct {
 	Resource    string
 	CreatedBy   uid.ID
 	UpdateIndex int64 `db:"-"`
+	// SubjectName is the name of the user or group. It is only set when the
+	// grant was queried with the subject name.
+	SubjectName string `db:"-"`
+	// ExpiresAt is the time after which the grant no longer applies. A grant
+	// with a null ExpiresAt does not expire.
+	ExpiresAt sql.NullTime
 }
 
 func (r *Grant) ToAPI() *api.Grant {
@@ -62,6 +70,10 @@ func (r *Grant) ToAPI() *api.Grant {
 		Resource:  r.Resource,
 	}
 
+	if r.ExpiresAt.Valid {
+		grant.Expires = api.Time(r.ExpiresAt.Time)
+	}
+
 	switch {
 	case r.Subject.IsIdentity():
 		identity, err := r.Subject.ID()
@@ -70,6 +82,10 @@ func (r *Grant) ToAPI() *api.Grant {
 		}
 
 		grant.User = identity
+		if r.SubjectName != "" {
+			grant.SubjectName = r.SubjectName
+			grant.SubjectKind = api.GrantSubjectKindUser
+		}
 	case r.Subject.IsGroup():
 		group, err := r.Subject.ID()
 		if err != nil {
@@ -77,6 +93,10 @@ func (r *Grant) ToAPI() *api.Grant {
 		}
 
 		grant.Group = group
+		if r.SubjectName != "" {
+			grant.SubjectName = r.SubjectName
+			grant.SubjectKind = api.GrantSubjectKindGroup
+		}
 	}
 
 	return grant
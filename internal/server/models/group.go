@@ -29,3 +29,24 @@ func (g *Group) ToAPI() *api.Group {
 func (g *Group) PolyID() uid.PolymorphicID {
 	return uid.NewGroupPolymorphicID(g.ID)
 }
+
+// ToSCIM returns the SCIM representation of the group, with members as the
+// list of users in the group.
+func (g *Group) ToSCIM(members []Identity) *api.SCIMGroup {
+	result := &api.SCIMGroup{
+		Schemas:     []string{api.GroupSchema},
+		ID:          g.ID.String(),
+		DisplayName: g.Name,
+		Members:     []api.SCIMGroupMember{},
+		Meta: api.SCIMMetadata{
+			ResourceType: "Group",
+		},
+	}
+	for _, member := range members {
+		result.Members = append(result.Members, api.SCIMGroupMember{
+			Value:   member.ID.String(),
+			Display: member.Name,
+		})
+	}
+	return result
+}
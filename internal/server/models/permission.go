@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/infrahq/infra/api"
+)
+
+const (
+	PermissionActionRead  = "read"
+	PermissionActionWrite = "write"
+)
+
+const (
+	PermissionResourceDestinations = "destinations"
+	PermissionResourceGrants       = "grants"
+	PermissionResourceGroups       = "groups"
+	PermissionResourceProviders    = "providers"
+	PermissionResourceUsers        = "users"
+)
+
+// InfraPermission is a permission to perform an Action on one type of
+// Resource in the infra API. Permissions are granted with a grant where the
+// privilege is the name of the permission (ex: grants:write), and the
+// resource is either the infra resource, or the name of a destination.
+type InfraPermission struct {
+	Resource string
+	Action   string
+	// Destination limits the permission to the resources of a single
+	// destination. An empty Destination requires a grant on the infra resource.
+	Destination string
+}
+
+// Name returns the name of the permission, used as the privilege of a grant.
+func (p InfraPermission) Name() string {
+	return p.Resource + ":" + p.Action
+}
+
+func (p InfraPermission) ToAPI() api.Permission {
+	return api.Permission{Permission: p.Name(), Destination: p.Destination}
+}
+
+// Privileges returns the names of the permissions that include p. The write
+// permission of a resource includes the read permission.
+func (p InfraPermission) Privileges() []string {
+	if p.Action == PermissionActionRead {
+		write := InfraPermission{Resource: p.Resource, Action: PermissionActionWrite}
+		return []string{p.Name(), write.Name()}
+	}
+	return []string{p.Name()}
+}
+
+// InfraPermissionsForRole returns the names of the infra permissions included
+// in one of the built-in infra roles.
+func InfraPermissionsForRole(role string) []string {
+	switch role {
+	case InfraAdminRole, InfraSupportAdminRole:
+		return api.InfraPermissions
+	case InfraViewRole:
+		var result []string
+		for _, perm := range api.InfraPermissions {
+			if strings.HasSuffix(perm, ":"+PermissionActionRead) {
+				result = append(result, perm)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -17,6 +17,10 @@ const (
 	ProviderKindOkta   ProviderKind = "okta"
 	ProviderKindAzure  ProviderKind = "azure"
 	ProviderKindGoogle ProviderKind = "google"
+	ProviderKindGitHub ProviderKind = "github"
+	ProviderKindGitLab ProviderKind = "gitlab"
+	ProviderKindSAML   ProviderKind = "saml"
+	ProviderKindLDAP   ProviderKind = "ldap"
 )
 
 func (p ProviderKind) String() string {
@@ -30,6 +34,10 @@ var providerKindMap = map[string]ProviderKind{
 	ProviderKindOkta.String():   ProviderKindOkta,
 	ProviderKindAzure.String():  ProviderKindAzure,
 	ProviderKindGoogle.String(): ProviderKindGoogle,
+	ProviderKindGitHub.String(): ProviderKindGitHub,
+	ProviderKindGitLab.String(): ProviderKindGitLab,
+	ProviderKindSAML.String():   ProviderKindSAML,
+	ProviderKindLDAP.String():   ProviderKindLDAP,
 }
 
 // ParseProviderKind validates that a string is valid kind then returns the ProviderKind
@@ -59,6 +67,32 @@ type Provider struct {
 	PrivateKey       EncryptedAtRest
 	ClientEmail      string
 	DomainAdminEmail string
+
+	// fields used by SAML providers
+	SAMLMetadataURL string
+	// SAMLMetadata is the XML metadata of the identity provider, either
+	// imported directly or fetched from SAMLMetadataURL.
+	SAMLMetadata string
+	// SAMLNameAttribute is the assertion attribute used as the name of the
+	// user. The NameID of the assertion subject is used when it is empty.
+	SAMLNameAttribute   string
+	SAMLGroupsAttribute string
+
+	// fields used by LDAP providers, URL is the ldap:// or ldaps:// URL of the
+	// directory server.
+	LDAPBindDN       string
+	LDAPBindPassword EncryptedAtRest
+	LDAPStartTLS     bool
+	LDAPUserBaseDN   string
+	// LDAPUserFilter finds a user by the name they login with, {username} is
+	// replaced by the escaped name.
+	LDAPUserFilter        string
+	LDAPUserNameAttribute string
+	LDAPGroupBaseDN       string
+	// LDAPGroupFilter finds the groups of a user, {dn} is replaced by the
+	// escaped DN of the user.
+	LDAPGroupFilter        string
+	LDAPGroupNameAttribute string
 }
 
 func (p *Provider) ToAPI() *api.Provider {
@@ -73,5 +107,34 @@ func (p *Provider) ToAPI() *api.Provider {
 		Kind:     p.Kind.String(),
 		AuthURL:  p.AuthURL,
 		Scopes:   p.Scopes,
+		SAML:     p.samlToAPI(),
+		LDAP:     p.ldapToAPI(),
+	}
+}
+
+func (p *Provider) samlToAPI() *api.ProviderSAML {
+	if p.Kind != ProviderKindSAML {
+		return nil
+	}
+	return &api.ProviderSAML{
+		MetadataURL:     p.SAMLMetadataURL,
+		NameAttribute:   p.SAMLNameAttribute,
+		GroupsAttribute: p.SAMLGroupsAttribute,
+	}
+}
+
+func (p *Provider) ldapToAPI() *api.ProviderLDAP {
+	if p.Kind != ProviderKindLDAP {
+		return nil
+	}
+	return &api.ProviderLDAP{
+		BindDN:             p.LDAPBindDN,
+		StartTLS:           p.LDAPStartTLS,
+		UserBaseDN:         p.LDAPUserBaseDN,
+		UserFilter:         p.LDAPUserFilter,
+		UserNameAttribute:  p.LDAPUserNameAttribute,
+		GroupBaseDN:        p.LDAPGroupBaseDN,
+		GroupFilter:        p.LDAPGroupFilter,
+		GroupNameAttribute: p.LDAPGroupNameAttribute,
 	}
 }
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// Role is a named set of privileges defined by an organization. A grant with
+// the name of the role as its Privilege grants each of the privileges of the
+// role on the Resource of the grant.
+type Role struct {
+	Model
+	OrganizationMember
+
+	Name string
+	// KubernetesRoles are the ClusterRoles bound by the connector when the
+	// role is granted on a destination.
+	KubernetesRoles CommaSeparatedStrings
+	// InfraRoles are the infra roles (admin, view) that apply when the role
+	// is granted on the infra resource.
+	InfraRoles CommaSeparatedStrings
+	// Permissions are the permissions, ex: connect, included in the role.
+	Permissions CommaSeparatedStrings
+	// CreatedBy is the ID of the user who created the role.
+	CreatedBy uid.ID
+}
+
+func (r *Role) ToAPI() *api.Role {
+	return &api.Role{
+		ID:              r.ID,
+		Name:            r.Name,
+		Created:         api.Time(r.CreatedAt),
+		Updated:         api.Time(r.UpdatedAt),
+		KubernetesRoles: r.KubernetesRoles,
+		InfraRoles:      r.InfraRoles,
+		Permissions:     r.Permissions,
+	}
+}
+
+// HasInfraRole returns true if the role includes any of the infra roles.
+func (r *Role) HasInfraRole(roles ...string) bool {
+	for _, have := range r.InfraRoles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermission returns true if the role includes any of the permissions.
+func (r *Role) HasPermission(permissions ...string) bool {
+	for _, have := range r.Permissions {
+		for _, want := range permissions {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -8,14 +8,22 @@ type Settings struct {
 	Model
 	OrganizationMember
 
-	PrivateJWK EncryptedAtRest
-	PublicJWK  []byte
+	// SSHCAPrivateKey is the private key of the certificate authority that
+	// signs certificates for SSH destinations, as a PKCS8 PEM block.
+	SSHCAPrivateKey EncryptedAtRest
+	// SSHCAPublicKey is the public key of the certificate authority in the
+	// authorized_keys format.
+	SSHCAPublicKey string
 
 	LowercaseMin int
 	UppercaseMin int
 	NumberMin    int
 	SymbolMin    int
 	LengthMin    int
+
+	// RequireMFA requires users who login with a password to use a second
+	// factor.
+	RequireMFA bool
 }
 
 func (s *Settings) ToAPI() *api.Settings {
@@ -27,6 +35,7 @@ func (s *Settings) ToAPI() *api.Settings {
 			SymbolMin:    s.SymbolMin,
 			LengthMin:    s.LengthMin,
 		},
+		RequireMFA: s.RequireMFA,
 	}
 }
 
@@ -36,4 +45,5 @@ func (s *Settings) SetFromAPI(a *api.Settings) {
 	s.LowercaseMin = a.PasswordRequirements.LowercaseMin
 	s.SymbolMin = a.PasswordRequirements.SymbolMin
 	s.NumberMin = a.PasswordRequirements.NumberMin
+	s.RequireMFA = a.RequireMFA
 }
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// SigningKey is a key used to sign the JWTs that the server issues to users.
+// The newest key is used to sign new JWTs. When a key is rotated the older key
+// continues to be published until ExpiresAt, so that JWTs signed with it can
+// still be verified.
+type SigningKey struct {
+	Model
+	OrganizationMember
+
+	// KeyID is the kid of the JWK.
+	KeyID      string
+	PrivateJWK EncryptedAtRest
+	PublicJWK  []byte
+
+	// ExpiresAt is null for the active key. It is set when the key is replaced
+	// by a newer key.
+	ExpiresAt sql.NullTime
+}
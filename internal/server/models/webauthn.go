@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// WebAuthnCredential is a public key credential, like a passkey or security
+// key, that a user registered to login with WebAuthn.
+type WebAuthnCredential struct {
+	Model
+	OrganizationMember
+
+	IdentityID uid.ID
+	// Name is a label chosen by the user to identify the authenticator.
+	Name string
+	// CredentialID is the base64url encoded ID of the credential, assigned by
+	// the authenticator.
+	CredentialID string
+	// PublicKey is the COSE encoded public key of the credential.
+	PublicKey []byte
+	// SignCount is the signature counter from the last assertion. It is used to
+	// detect cloned authenticators.
+	SignCount  int64
+	LastUsedAt time.Time
+}
+
+func (c *WebAuthnCredential) ToAPI() *api.WebAuthnCredential {
+	return &api.WebAuthnCredential{
+		ID:       c.ID,
+		Name:     c.Name,
+		Created:  api.Time(c.CreatedAt),
+		LastUsed: api.Time(c.LastUsedAt),
+	}
+}
+
+const (
+	WebAuthnSessionRegistration = "registration"
+	WebAuthnSessionLogin        = "login"
+)
+
+// WebAuthnSession stores the challenge of a WebAuthn registration or login
+// ceremony between the begin and finish requests.
+type WebAuthnSession struct {
+	ID uid.ID
+	OrganizationMember
+
+	// IdentityID is the user who is registering a credential, or the user who
+	// is logging in. It is empty for a login with a discoverable credential.
+	IdentityID uid.ID
+	Kind       string
+	// Challenge is the base64url encoded random challenge that must be signed
+	// by the authenticator.
+	Challenge string
+	// RPID is the relying party ID used by the ceremony.
+	RPID      string
+	ExpiresAt time.Time
+}
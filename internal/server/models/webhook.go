@@ -0,0 +1,103 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// Webhook is a subscription to the events of an organization. Each event is
+// delivered by an HTTP POST request to URL, signed with Secret.
+type Webhook struct {
+	Model
+	OrganizationMember
+
+	URL string
+	// Events are the types of events delivered to the webhook. An event type
+	// may use a wildcard, ex: grant.* or *. All events are delivered when
+	// Events is empty.
+	Events CommaSeparatedStrings
+	// Secret is the key used to sign the body of each delivery.
+	Secret EncryptedAtRest
+	// CreatedBy is the ID of the user who created the webhook.
+	CreatedBy uid.ID
+}
+
+func (w *Webhook) ToAPI() *api.Webhook {
+	return &api.Webhook{
+		ID:      w.ID,
+		Created: api.Time(w.CreatedAt),
+		Updated: api.Time(w.UpdatedAt),
+		URL:     w.URL,
+		Events:  w.Events,
+	}
+}
+
+// Subscribed returns true if events of eventType should be delivered to the
+// webhook.
+func (w *Webhook) Subscribed(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	resource, _, _ := strings.Cut(eventType, ".")
+	for _, event := range w.Events {
+		if event == "*" || event == eventType || event == resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event that is delivered to a webhook. A delivery is
+// retried until it succeeds, or until it fails too many times.
+type WebhookDelivery struct {
+	Model
+	OrganizationMember
+
+	WebhookID uid.ID
+	// EventID is the ID of the event that caused the delivery. It is the ID
+	// of the audit event, except for user, group, and grant events which are
+	// created by the data layer and do not have an audit event.
+	EventID   uid.ID
+	EventType string
+	// Payload is the JSON body of the request sent to the webhook.
+	Payload string
+
+	Status   WebhookDeliveryStatus
+	Attempts int
+	// NextAttemptAt is the time of the next attempt to deliver a pending event.
+	NextAttemptAt time.Time
+	LastAttemptAt time.Time
+	// ResponseStatus is the HTTP status code of the response to the last
+	// attempt, or 0 if the request failed without a response.
+	ResponseStatus int
+	// LastError is the error from the last attempt, if it failed.
+	LastError string
+}
+
+func (d *WebhookDelivery) ToAPI() *api.WebhookDelivery {
+	return &api.WebhookDelivery{
+		ID:             d.ID,
+		Created:        api.Time(d.CreatedAt),
+		Webhook:        d.WebhookID,
+		Event:          d.EventID,
+		EventType:      d.EventType,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		LastAttempt:    api.Time(d.LastAttemptAt),
+		NextAttempt:    api.Time(d.NextAttemptAt),
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.LastError,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWebhook_Subscribed(t *testing.T) {
+	tests := []struct {
+		events    []string
+		eventType string
+		expected  bool
+	}{
+		{nil, "grant.created", true},
+		{[]string{"*"}, "user.deleted", true},
+		{[]string{"grant.created"}, "grant.created", true},
+		{[]string{"grant.created"}, "grant.deleted", false},
+		{[]string{"grant.*"}, "grant.deleted", true},
+		{[]string{"grant.*"}, "group.deleted", false},
+		{[]string{"user.deleted", "group.*"}, "group.updated", true},
+	}
+	for _, test := range tests {
+		webhook := &Webhook{Events: test.events}
+		assert.Equal(t, webhook.Subscribed(test.eventType), test.expected,
+			"events=%v eventType=%v", test.events, test.eventType)
+	}
+}
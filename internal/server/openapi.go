@@ -41,6 +41,7 @@ var funcPartialNameToTagNames = []struct {
 	{partial: "Password", tag: "Authentication"},
 	{partial: "Destination", tag: "Destinations"},
 	{partial: "Token", tag: "Destinations"},
+	{partial: "SSH", tag: "Destinations"},
 	{partial: "Grant", tag: "Grants"},
 	{partial: "Group", tag: "Groups"},
 	{partial: "Provider", tag: "Providers"},
@@ -1,10 +1,14 @@
 package server
 
 import (
+	"encoding/base64"
+	"fmt"
 	"math"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/uid"
 )
 
 // PaginationFromRequest translates an api.PaginationRequest into the internal
@@ -26,12 +30,58 @@ func PaginationFromRequest(pr api.PaginationRequest) data.Pagination {
 	}
 }
 
+// CursorPaginationFromRequest translates the pagination fields of a list
+// request into the internal Pagination type. Requests that set a page number
+// use page and limit pagination. All other requests use cursor pagination.
+func CursorPaginationFromRequest(cr api.CursorPaginationRequest, pr api.PaginationRequest) (data.Pagination, error) {
+	if cr.After == "" && pr.Page != 0 {
+		return PaginationFromRequest(pr), nil
+	}
+
+	p := data.Pagination{Cursor: true, Limit: 100}
+	if pr.Limit != 0 {
+		p.Limit = pr.Limit
+	}
+	if cr.After != "" {
+		after, err := decodeCursor(cr.After)
+		if err != nil {
+			return p, err
+		}
+		p.After = after
+	}
+	return p, nil
+}
+
+// encodeCursor returns the opaque cursor used to select the rows after id.
+func encodeCursor(id uid.ID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id.String()))
+}
+
+func decodeCursor(cursor string) (uid.ID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid cursor for after", internal.ErrBadRequest)
+	}
+	id, err := uid.Parse(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid cursor for after", internal.ErrBadRequest)
+	}
+	return id, nil
+}
+
 // PaginationToResponse translates an internal Pagination type into the pagination
 // response.
 func PaginationToResponse(p data.Pagination) api.PaginationResponse {
 	if p.Limit == 0 {
 		return api.PaginationResponse{}
 	}
+	if p.Cursor {
+		resp := api.PaginationResponse{Limit: p.Limit}
+		if p.Next != 0 {
+			resp.Next = encodeCursor(p.Next)
+		}
+		return resp
+	}
 	return api.PaginationResponse{
 		Page:       p.Page,
 		Limit:      p.Limit,
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestCursorPaginationFromRequest(t *testing.T) {
+	t.Run("page and limit", func(t *testing.T) {
+		p, err := CursorPaginationFromRequest(api.CursorPaginationRequest{}, api.PaginationRequest{Page: 2, Limit: 10})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, p, data.Pagination{Page: 2, Limit: 10})
+	})
+	t.Run("first page with cursor", func(t *testing.T) {
+		p, err := CursorPaginationFromRequest(api.CursorPaginationRequest{}, api.PaginationRequest{})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, p, data.Pagination{Cursor: true, Limit: 100})
+	})
+	t.Run("after cursor", func(t *testing.T) {
+		id := uid.New()
+		cr := api.CursorPaginationRequest{After: encodeCursor(id)}
+		p, err := CursorPaginationFromRequest(cr, api.PaginationRequest{Limit: 5})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, p, data.Pagination{Cursor: true, Limit: 5, After: id})
+	})
+	t.Run("invalid cursor", func(t *testing.T) {
+		cr := api.CursorPaginationRequest{After: "***"}
+		_, err := CursorPaginationFromRequest(cr, api.PaginationRequest{})
+		assert.Assert(t, errors.Is(err, internal.ErrBadRequest), err)
+	})
+}
+
+func TestPaginationToResponse_Cursor(t *testing.T) {
+	id := uid.New()
+	actual := PaginationToResponse(data.Pagination{Cursor: true, Limit: 5, Next: id})
+	assert.DeepEqual(t, actual, api.PaginationResponse{Limit: 5, Next: encodeCursor(id)})
+
+	actual = PaginationToResponse(data.Pagination{Cursor: true, Limit: 5})
+	assert.DeepEqual(t, actual, api.PaginationResponse{Limit: 5})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/infrahq/infra/internal/generate"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
 )
 
 // caution: this endpoint is unauthenticated, do not return sensitive info
@@ -107,7 +109,15 @@ func (a *API) CreateProvider(c *gin.Context, r *api.CreateProviderRequest) (*api
 		}
 	}
 
-	if err := a.setProviderInfoFromServer(c, provider); err != nil {
+	switch provider.Kind {
+	case models.ProviderKindSAML:
+		err = setProviderInfoFromSAMLMetadata(c, provider, r.SAML)
+	case models.ProviderKindLDAP:
+		err = setProviderInfoFromLDAP(provider, r.URL, r.LDAP)
+	default:
+		err = a.setProviderInfoFromServer(c, provider)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -123,6 +133,11 @@ func (a *API) PatchProvider(c *gin.Context, r *api.PatchProviderRequest) (*api.P
 	if err != nil {
 		return nil, err
 	}
+	getRequestContext(c).Response.SetAuditBefore(provider.ToAPI())
+	if err := a.checkConfigManaged(c, "provider", provider.Name, provider.CreatedBy); err != nil {
+		return nil, err
+	}
+
 	if r.Name != "" {
 		provider.Name = r.Name
 	}
@@ -136,6 +151,15 @@ func (a *API) PatchProvider(c *gin.Context, r *api.PatchProviderRequest) (*api.P
 }
 
 func (a *API) UpdateProvider(c *gin.Context, r *api.UpdateProviderRequest) (*api.Provider, error) {
+	existing, err := access.GetProvider(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	getRequestContext(c).Response.SetAuditBefore(existing.ToAPI())
+	if err := a.checkConfigManaged(c, "provider", existing.Name, existing.CreatedBy); err != nil {
+		return nil, err
+	}
+
 	provider := &models.Provider{
 		Model: models.Model{
 			ID: r.ID,
@@ -159,7 +183,20 @@ func (a *API) UpdateProvider(c *gin.Context, r *api.UpdateProviderRequest) (*api
 	}
 	provider.Kind = kind
 
-	if err := a.setProviderInfoFromServer(c, provider); err != nil {
+	switch provider.Kind {
+	case models.ProviderKindSAML:
+		err = setProviderInfoFromSAMLMetadata(c, provider, r.SAML)
+	case models.ProviderKindLDAP:
+		if existing.Kind == models.ProviderKindLDAP {
+			// the bind password is not included in responses, so keep the
+			// existing password when it is not changed
+			provider.LDAPBindPassword = existing.LDAPBindPassword
+		}
+		err = setProviderInfoFromLDAP(provider, r.URL, r.LDAP)
+	default:
+		err = a.setProviderInfoFromServer(c, provider)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -171,6 +208,14 @@ func (a *API) UpdateProvider(c *gin.Context, r *api.UpdateProviderRequest) (*api
 }
 
 func (a *API) DeleteProvider(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	provider, err := access.GetProvider(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	getRequestContext(c).Response.SetAuditBefore(provider.ToAPI())
+	if err := a.checkConfigManaged(c, "provider", provider.Name, provider.CreatedBy); err != nil {
+		return nil, err
+	}
 	return nil, access.DeleteProvider(c, r.ID)
 }
 
@@ -200,3 +245,57 @@ func (a *API) setProviderInfoFromServer(c *gin.Context, provider *models.Provide
 
 	return nil
 }
+
+// setProviderInfoFromSAMLMetadata checks the metadata of a SAML identity
+// provider, which is fetched from the metadata URL when one is set.
+func setProviderInfoFromSAMLMetadata(ctx context.Context, provider *models.Provider, r *api.ProviderSAML) error {
+	metadata := []byte(r.Metadata)
+	if r.MetadataURL != "" {
+		var err error
+		metadata, err = providers.FetchSAMLMetadata(ctx, r.MetadataURL)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("%w: %s", internal.ErrBadGateway, err)
+			}
+			return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
+		}
+	}
+
+	idp, err := providers.ParseSAMLMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
+	}
+
+	ssoURL, err := url.Parse(idp.SSOURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid saml single sign-on url: %s", internal.ErrBadRequest, err)
+	}
+
+	provider.URL = ssoURL.Host
+	provider.AuthURL = idp.SSOURL
+	provider.SAMLMetadataURL = r.MetadataURL
+	provider.SAMLMetadata = string(metadata)
+	provider.SAMLNameAttribute = r.NameAttribute
+	provider.SAMLGroupsAttribute = r.GroupsAttribute
+	return nil
+}
+
+// setProviderInfoFromLDAP checks that the directory of an LDAP identity
+// provider accepts the credentials of the service account.
+func setProviderInfoFromLDAP(provider *models.Provider, rawURL string, r *api.ProviderLDAP) error {
+	// the scheme of the URL selects ldap:// or ldaps://, so it is not removed
+	provider.URL = strings.TrimSpace(rawURL)
+	provider.LDAPBindDN = r.BindDN
+	if r.BindPassword != "" {
+		provider.LDAPBindPassword = models.EncryptedAtRest(r.BindPassword)
+	}
+	provider.LDAPStartTLS = r.StartTLS
+	provider.LDAPUserBaseDN = r.UserBaseDN
+	provider.LDAPUserFilter = r.UserFilter
+	provider.LDAPUserNameAttribute = r.UserNameAttribute
+	provider.LDAPGroupBaseDN = r.GroupBaseDN
+	provider.LDAPGroupFilter = r.GroupFilter
+	provider.LDAPGroupNameAttribute = r.GroupNameAttribute
+
+	return providers.NewLDAPClient(*provider).Validate()
+}
@@ -0,0 +1,188 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const githubMediaType = "application/vnd.github+json"
+
+// read:org is required to list the teams of the user
+var githubScopes = []string{"read:user", "user:email", "read:org"}
+
+// github is an identity provider for GitHub, or GitHub Enterprise Server when
+// Domain is not github.com. GitHub does not support OpenID Connect for users,
+// so user info is read from the GitHub API.
+type github struct {
+	Domain       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+type githubTeam struct {
+	Slug         string `json:"slug"`
+	Organization struct {
+		Login string `json:"login"`
+	} `json:"organization"`
+}
+
+func (g *github) config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     g.ClientID,
+		ClientSecret: g.ClientSecret,
+		RedirectURL:  g.RedirectURL,
+		Scopes:       githubScopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://" + g.Domain + "/login/oauth/authorize",
+			TokenURL: "https://" + g.Domain + "/login/oauth/access_token",
+		},
+	}
+}
+
+// apiURL returns the URL of the GitHub API. GitHub Enterprise Server serves
+// the API from a path on the same domain.
+func (g *github) apiURL() string {
+	if g.Domain == "github.com" {
+		return "https://api.github.com"
+	}
+	return "https://" + g.Domain + "/api/v3"
+}
+
+func (g *github) Validate(ctx context.Context) error {
+	return validateOAuthClient(ctx, g.config())
+}
+
+func (g *github) AuthServerInfo(_ context.Context) (*AuthServerInfo, error) {
+	return &AuthServerInfo{
+		AuthURL:         g.config().Endpoint.AuthURL,
+		ScopesSupported: githubScopes,
+	}, nil
+}
+
+func (g *github) ExchangeAuthCodeForProviderTokens(ctx context.Context, code string) (*IdentityProviderAuth, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcProviderRequestTimeout)
+	defer cancel()
+
+	token, client, err := exchangeOAuthCode(ctx, g.config(), code)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := g.primaryEmail(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentityProviderAuth{
+		AccessToken:       token.AccessToken,
+		RefreshToken:      token.RefreshToken,
+		AccessTokenExpiry: token.Expiry,
+		Email:             email,
+	}, nil
+}
+
+func (g *github) RefreshAccessToken(ctx context.Context, providerUser *models.ProviderUser) (accessToken string, expiry *time.Time, err error) {
+	return refreshOAuthToken(ctx, g.config(), providerUser)
+}
+
+func (g *github) GetUserInfo(ctx context.Context, providerUser *models.ProviderUser) (*UserInfoClaims, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcProviderRequestTimeout)
+	defer cancel()
+
+	client := oauthClient(ctx, g.config(), providerUser)
+
+	// this checks if the user still exists
+	email, err := g.primaryEmail(ctx, client)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s", internal.ErrBadGateway, err.Error())
+		}
+		return nil, fmt.Errorf("could not get user info from provider: %w", err)
+	}
+
+	groups, err := g.checkTeams(ctx, client)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s", internal.ErrBadGateway, err.Error())
+		}
+		return nil, fmt.Errorf("could not check github user teams: %w", err)
+	}
+
+	logging.Debugf("user synchronized with %q groups from github provider", &groups)
+
+	return &UserInfoClaims{Email: email, Groups: groups}, nil
+}
+
+// primaryEmail returns the primary email address of the user, which must be
+// verified.
+func (g *github) primaryEmail(ctx context.Context, client *http.Client) (string, error) {
+	var emails []githubEmail
+	if _, err := getJSON(ctx, client, g.apiURL()+"/user/emails", githubMediaType, &emails); err != nil {
+		return "", fmt.Errorf("get github user emails: %w", err)
+	}
+
+	for _, email := range emails {
+		if email.Primary {
+			if !email.Verified {
+				return "", fmt.Errorf("github user primary email %v is not verified", email.Email)
+			}
+			if email.Email == "" || strings.ContainsAny(email.Email, ` '`) {
+				return "", fmt.Errorf("github user has invalid email address")
+			}
+			return email.Email, nil
+		}
+	}
+	return "", fmt.Errorf("github user does not have a primary email address")
+}
+
+// checkTeams returns the teams of the user as groups named org/team, where
+// team is the slug of the team.
+func (g *github) checkTeams(ctx context.Context, client *http.Client) ([]string, error) {
+	apiURL, err := url.Parse(g.apiURL())
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []string{}
+	next := g.apiURL() + "/user/teams?per_page=100"
+	for page := 0; next != ""; page++ {
+		if page == oauthMaxPages {
+			return nil, fmt.Errorf("github user is a member of too many teams")
+		}
+
+		// the next page URL comes from the response, make sure the token is
+		// only sent to the API
+		nextURL, err := url.Parse(next)
+		if err != nil || nextURL.Scheme != "https" || nextURL.Host != apiURL.Host {
+			return nil, fmt.Errorf("unexpected github next page url %q", next)
+		}
+
+		var teams []githubTeam
+		next, err = getJSON(ctx, client, next, githubMediaType, &teams)
+		if err != nil {
+			return nil, fmt.Errorf("get github user teams: %w", err)
+		}
+		for _, team := range teams {
+			groups = append(groups, team.Organization.Login+"/"+team.Slug)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,170 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+type githubTestServer struct {
+	emails   string
+	nextPage string
+}
+
+func (ts *githubTestServer) run(t *testing.T) (string, context.Context) {
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, req *http.Request) {
+		assert.Check(t, req.ParseForm())
+		if req.PostForm.Get("code") != "the-code" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"bad_verification_code"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"gho_token","token_type":"bearer","scope":"read:user,user:email,read:org"}`))
+	})
+
+	authorized := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "Bearer gho_token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Check(t, req.Header.Get("Accept") == githubMediaType)
+			handler(w, req)
+		}
+	}
+
+	mux.HandleFunc("/api/v3/user/emails", authorized(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(ts.emails))
+	}))
+	mux.HandleFunc("/api/v3/user/teams", authorized(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`[{"slug":"sre","organization":{"login":"other"}}]`))
+			return
+		}
+		nextPage := ts.nextPage
+		if nextPage == "" {
+			nextPage = server.URL + "/api/v3/user/teams?per_page=100&page=2"
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%v>; rel="next", <%v>; rel="last"`, nextPage, nextPage))
+		_, _ = w.Write([]byte(`[
+			{"name":"Developers","slug":"developers","organization":{"login":"infrahq"}},
+			{"name":"Admins","slug":"admins","organization":{"login":"infrahq"}}
+		]`))
+	}))
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	return host, ctx
+}
+
+const githubEmails = `[
+	{"email":"old@example.com","primary":false,"verified":true},
+	{"email":"octocat@example.com","primary":true,"verified":true}
+]`
+
+func TestGitHub_ExchangeAuthCodeForProviderTokens(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ts := &githubTestServer{emails: githubEmails}
+		host, ctx := ts.run(t)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitHub, URL: host, ClientID: "client-id"}, "secret", "https://example.com/callback")
+
+		auth, err := client.ExchangeAuthCodeForProviderTokens(ctx, "the-code")
+		assert.NilError(t, err)
+		assert.Equal(t, auth.AccessToken, "gho_token")
+		assert.Equal(t, auth.Email, "octocat@example.com")
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		ts := &githubTestServer{emails: githubEmails}
+		host, ctx := ts.run(t)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitHub, URL: host, ClientID: "client-id"}, "secret", "https://example.com/callback")
+
+		_, err := client.ExchangeAuthCodeForProviderTokens(ctx, "wrong-code")
+		assert.ErrorContains(t, err, "code exchange")
+	})
+
+	t.Run("primary email is not verified", func(t *testing.T) {
+		ts := &githubTestServer{emails: `[{"email":"octocat@example.com","primary":true,"verified":false}]`}
+		host, ctx := ts.run(t)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitHub, URL: host, ClientID: "client-id"}, "secret", "https://example.com/callback")
+
+		_, err := client.ExchangeAuthCodeForProviderTokens(ctx, "the-code")
+		assert.ErrorContains(t, err, "github user primary email octocat@example.com is not verified")
+	})
+}
+
+func TestGitHub_GetUserInfo(t *testing.T) {
+	providerUser := &models.ProviderUser{AccessToken: "gho_token"}
+
+	t.Run("teams are groups", func(t *testing.T) {
+		ts := &githubTestServer{emails: githubEmails}
+		host, ctx := ts.run(t)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitHub, URL: host}, "secret", "")
+
+		info, err := client.GetUserInfo(ctx, providerUser)
+		assert.NilError(t, err)
+		expected := &UserInfoClaims{
+			Email:  "octocat@example.com",
+			Groups: []string{"infrahq/developers", "infrahq/admins", "other/sre"},
+		}
+		assert.DeepEqual(t, info, expected)
+	})
+
+	t.Run("next page on another host", func(t *testing.T) {
+		ts := &githubTestServer{emails: githubEmails, nextPage: "https://example.com/user/teams?page=2"}
+		host, ctx := ts.run(t)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitHub, URL: host}, "secret", "")
+
+		_, err := client.GetUserInfo(ctx, providerUser)
+		assert.ErrorContains(t, err, `unexpected github next page url "https://example.com/user/teams?page=2"`)
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		ts := &githubTestServer{emails: githubEmails}
+		host, ctx := ts.run(t)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitHub, URL: host}, "secret", "")
+
+		_, err := client.GetUserInfo(ctx, &models.ProviderUser{AccessToken: "revoked", ExpiresAt: time.Now().Add(time.Hour)})
+		assert.ErrorContains(t, err, "unexpected status 401 Unauthorized")
+	})
+}
+
+func TestGitHub_AuthServerInfo(t *testing.T) {
+	client := &github{Domain: "github.com"}
+	info, err := client.AuthServerInfo(context.Background())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, info, &AuthServerInfo{
+		AuthURL:         "https://github.com/login/oauth/authorize",
+		ScopesSupported: []string{"read:user", "user:email", "read:org"},
+	})
+	assert.Equal(t, client.apiURL(), "https://api.github.com")
+
+	enterprise := &github{Domain: "github.example.com"}
+	assert.Equal(t, enterprise.apiURL(), "https://github.example.com/api/v3")
+}
+
+func TestNextPageURL(t *testing.T) {
+	testCases := map[string]string{
+		"": "",
+		`<https://api.github.com/user/teams?page=2>; rel="next", <https://api.github.com/user/teams?page=5>; rel="last"`:  "https://api.github.com/user/teams?page=2",
+		`<https://api.github.com/user/teams?page=1>; rel="prev", <https://api.github.com/user/teams?page=1>; rel="first"`: "",
+	}
+	for link, expected := range testCases {
+		assert.Equal(t, nextPageURL(link), expected, "link %q", link)
+	}
+}
@@ -0,0 +1,125 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// GitLab includes the groups of the user in the user info response for the
+// openid scope, so read_api is not required to list the groups.
+var gitlabScopes = []string{"openid", "email"}
+
+// gitlab is an identity provider for gitlab.com or a self-managed GitLab
+// instance at Domain.
+type gitlab struct {
+	Domain       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+type gitlabUserInfo struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	// Groups are the full paths of the groups the user is a member of,
+	// directly or through a parent group.
+	Groups []string `json:"groups"`
+}
+
+func (g *gitlab) config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     g.ClientID,
+		ClientSecret: g.ClientSecret,
+		RedirectURL:  g.RedirectURL,
+		Scopes:       gitlabScopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://" + g.Domain + "/oauth/authorize",
+			TokenURL: "https://" + g.Domain + "/oauth/token",
+		},
+	}
+}
+
+func (g *gitlab) Validate(ctx context.Context) error {
+	return validateOAuthClient(ctx, g.config())
+}
+
+func (g *gitlab) AuthServerInfo(_ context.Context) (*AuthServerInfo, error) {
+	return &AuthServerInfo{
+		AuthURL:         g.config().Endpoint.AuthURL,
+		ScopesSupported: gitlabScopes,
+	}, nil
+}
+
+func (g *gitlab) ExchangeAuthCodeForProviderTokens(ctx context.Context, code string) (*IdentityProviderAuth, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcProviderRequestTimeout)
+	defer cancel()
+
+	token, client, err := exchangeOAuthCode(ctx, g.config(), code)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := g.userInfo(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentityProviderAuth{
+		AccessToken:       token.AccessToken,
+		RefreshToken:      token.RefreshToken,
+		AccessTokenExpiry: token.Expiry,
+		Email:             info.Email,
+	}, nil
+}
+
+func (g *gitlab) RefreshAccessToken(ctx context.Context, providerUser *models.ProviderUser) (accessToken string, expiry *time.Time, err error) {
+	return refreshOAuthToken(ctx, g.config(), providerUser)
+}
+
+func (g *gitlab) GetUserInfo(ctx context.Context, providerUser *models.ProviderUser) (*UserInfoClaims, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcProviderRequestTimeout)
+	defer cancel()
+
+	// this checks if the user still exists
+	info, err := g.userInfo(ctx, oauthClient(ctx, g.config(), providerUser))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s", internal.ErrBadGateway, err.Error())
+		}
+		return nil, fmt.Errorf("could not get user info from provider: %w", err)
+	}
+
+	groups := info.Groups
+	if groups == nil {
+		groups = []string{} // set the groups empty to clear them
+	}
+
+	logging.Debugf("user synchronized with %q groups from gitlab provider", &groups)
+
+	return &UserInfoClaims{Email: info.Email, Groups: groups}, nil
+}
+
+func (g *gitlab) userInfo(ctx context.Context, client *http.Client) (*gitlabUserInfo, error) {
+	var info gitlabUserInfo
+	if _, err := getJSON(ctx, client, "https://"+g.Domain+"/oauth/userinfo", "application/json", &info); err != nil {
+		return nil, fmt.Errorf("get gitlab user info: %w", err)
+	}
+
+	if info.Email == "" || strings.ContainsAny(info.Email, ` '`) {
+		return nil, fmt.Errorf("gitlab user has invalid email address")
+	}
+	if !info.EmailVerified {
+		return nil, fmt.Errorf("gitlab user email %v is not verified", info.Email)
+	}
+	return &info, nil
+}
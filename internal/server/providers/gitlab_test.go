@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func runGitLabTestServer(t *testing.T, userInfo string) (string, context.Context) {
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, req *http.Request) {
+		assert.Check(t, req.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch req.PostForm.Get("grant_type") {
+		case "authorization_code":
+			_, _ = w.Write([]byte(`{"access_token":"glpat","refresh_token":"glrt","token_type":"Bearer","expires_in":7200}`))
+		case "refresh_token":
+			_, _ = w.Write([]byte(`{"access_token":"glpat-refreshed","refresh_token":"glrt-2","token_type":"Bearer","expires_in":7200}`))
+		}
+	})
+	mux.HandleFunc("/oauth/userinfo", func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer glpat") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(userInfo))
+	})
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	return strings.TrimPrefix(server.URL, "https://"), ctx
+}
+
+func TestGitLab_ExchangeAuthCodeForProviderTokens(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		host, ctx := runGitLabTestServer(t, `{"sub":"1","email":"ada@example.com","email_verified":true,"groups":["infrahq"]}`)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitLab, URL: host, ClientID: "client-id"}, "secret", "https://example.com/callback")
+
+		auth, err := client.ExchangeAuthCodeForProviderTokens(ctx, "the-code")
+		assert.NilError(t, err)
+		assert.Equal(t, auth.AccessToken, "glpat")
+		assert.Equal(t, auth.RefreshToken, "glrt")
+		assert.Equal(t, auth.Email, "ada@example.com")
+		assert.Assert(t, !auth.AccessTokenExpiry.IsZero())
+	})
+
+	t.Run("email is not verified", func(t *testing.T) {
+		host, ctx := runGitLabTestServer(t, `{"sub":"1","email":"ada@example.com","email_verified":false}`)
+		client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitLab, URL: host, ClientID: "client-id"}, "secret", "https://example.com/callback")
+
+		_, err := client.ExchangeAuthCodeForProviderTokens(ctx, "the-code")
+		assert.ErrorContains(t, err, "gitlab user email ada@example.com is not verified")
+	})
+}
+
+func TestGitLab_GetUserInfo(t *testing.T) {
+	host, ctx := runGitLabTestServer(t, `{"sub":"1","email":"ada@example.com","email_verified":true,"groups":["infrahq","infrahq/platform"]}`)
+	client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitLab, URL: host, ClientID: "client-id"}, "secret", "")
+
+	// the access token is expired, so it is refreshed
+	providerUser := &models.ProviderUser{AccessToken: "expired", RefreshToken: "glrt", ExpiresAt: time.Now().Add(-time.Minute)}
+	accessToken, expiry, err := client.RefreshAccessToken(ctx, providerUser)
+	assert.NilError(t, err)
+	assert.Equal(t, accessToken, "glpat-refreshed")
+	assert.Assert(t, !expiry.IsZero())
+
+	providerUser.AccessToken = models.EncryptedAtRest(accessToken)
+	providerUser.ExpiresAt = *expiry
+
+	info, err := client.GetUserInfo(ctx, providerUser)
+	assert.NilError(t, err)
+	expected := &UserInfoClaims{
+		Email:  "ada@example.com",
+		Groups: []string{"infrahq", "infrahq/platform"},
+	}
+	assert.DeepEqual(t, info, expected)
+}
+
+func TestGitLab_Validate(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"Client authentication failed"}`))
+	})
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+
+	client := NewOIDCClient(models.Provider{Kind: models.ProviderKindGitLab, URL: strings.TrimPrefix(server.URL, "https://"), ClientID: "wrong"}, "secret", "")
+	err := client.Validate(ctx)
+	assert.Error(t, err, "validation failed: clientID: invalid provider clientID")
+}
@@ -0,0 +1,290 @@
+package providers
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+)
+
+// Defaults for the LDAP provider fields that are optional. The defaults work
+// with the inetOrgPerson and groupOfNames object classes used by OpenLDAP.
+const (
+	DefaultLDAPUserFilter         = "(&(objectClass=person)(uid={username}))"
+	DefaultLDAPUserNameAttribute  = "mail"
+	DefaultLDAPGroupFilter        = "(&(objectClass=groupOfNames)(member={dn}))"
+	DefaultLDAPGroupNameAttribute = "cn"
+)
+
+const ldapRequestTimeout = 10 * time.Second
+
+// LDAPUser is a user found in an LDAP directory.
+type LDAPUser struct {
+	DN     string
+	Name   string
+	Groups []string
+}
+
+// LDAPClient authenticates users with a bind to an LDAP directory, and finds
+// the groups of the users with a search.
+type LDAPClient struct {
+	URL          string
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+
+	UserBaseDN        string
+	UserFilter        string
+	UserNameAttribute string
+
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupNameAttribute string
+
+	// TLSConfig is used for ldaps:// and StartTLS connections. When it is nil
+	// the server certificate is verified with the system roots.
+	TLSConfig *tls.Config
+}
+
+// NewLDAPClient returns a client for the directory of an LDAP provider, with
+// defaults for the optional fields.
+func NewLDAPClient(provider models.Provider) *LDAPClient {
+	c := &LDAPClient{
+		URL:                provider.URL,
+		StartTLS:           provider.LDAPStartTLS,
+		BindDN:             provider.LDAPBindDN,
+		BindPassword:       string(provider.LDAPBindPassword),
+		UserBaseDN:         provider.LDAPUserBaseDN,
+		UserFilter:         provider.LDAPUserFilter,
+		UserNameAttribute:  provider.LDAPUserNameAttribute,
+		GroupBaseDN:        provider.LDAPGroupBaseDN,
+		GroupFilter:        provider.LDAPGroupFilter,
+		GroupNameAttribute: provider.LDAPGroupNameAttribute,
+	}
+	if c.UserFilter == "" {
+		c.UserFilter = DefaultLDAPUserFilter
+	}
+	if c.UserNameAttribute == "" {
+		c.UserNameAttribute = DefaultLDAPUserNameAttribute
+	}
+	if c.GroupBaseDN == "" {
+		c.GroupBaseDN = c.UserBaseDN
+	}
+	if c.GroupFilter == "" {
+		c.GroupFilter = DefaultLDAPGroupFilter
+	}
+	if c.GroupNameAttribute == "" {
+		c.GroupNameAttribute = DefaultLDAPGroupNameAttribute
+	}
+	return c
+}
+
+// Validate checks the configuration of the client by binding to the
+// directory with the service account.
+func (c *LDAPClient) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return validate.Error{"url": {"must be an ldap:// or ldaps:// URL"}}
+	}
+	if c.StartTLS && u.Scheme == "ldaps" {
+		return validate.Error{"ldap.startTLS": {"can not be used with an ldaps:// URL"}}
+	}
+	if _, err := ldap.CompileFilter(c.userFilter("*")); err != nil {
+		return validate.Error{"ldap.userFilter": {"invalid ldap filter"}}
+	}
+	if _, err := ldap.CompileFilter(c.groupFilter("cn=user")); err != nil {
+		return validate.Error{"ldap.groupFilter": {"invalid ldap filter"}}
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		logging.Debugf("error validating ldap provider: %s", err)
+		return newValidationError("url")
+	}
+	defer conn.Close()
+
+	if err := c.bindServiceAccount(conn); err != nil {
+		logging.Debugf("error validating ldap provider service account: %s", err)
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return newValidationError("ldap.bindPassword")
+		}
+		return newValidationError("ldap.bindDN")
+	}
+	return nil
+}
+
+// Authenticate checks the password of the user by binding to the directory
+// as the user, and returns the user with their groups.
+func (c *LDAPClient) Authenticate(username, password string) (*LDAPUser, error) {
+	// a bind without a password is an unauthenticated bind, which most
+	// servers allow for any name
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("%w: username and password are required", internal.ErrUnauthorized)
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := c.bindServiceAccount(conn); err != nil {
+		return nil, fmt.Errorf("bind ldap service account: %w", err)
+	}
+
+	entry, err := c.searchUser(conn, c.userFilter(ldap.EscapeFilter(username)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf("%w: invalid ldap credentials", internal.ErrUnauthorized)
+		}
+		return nil, fmt.Errorf("bind ldap user: %w", err)
+	}
+
+	// the user may not be allowed to search for groups, so bind as the
+	// service account again
+	if err := c.bindServiceAccount(conn); err != nil {
+		return nil, fmt.Errorf("bind ldap service account: %w", err)
+	}
+
+	return c.user(conn, entry)
+}
+
+// LookupUser finds the user with the name in the directory, and returns the
+// user with their groups. It returns an error that wraps internal.ErrNotFound
+// when the user no longer exists.
+func (c *LDAPClient) LookupUser(name string) (*LDAPUser, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := c.bindServiceAccount(conn); err != nil {
+		return nil, fmt.Errorf("bind ldap service account: %w", err)
+	}
+
+	// the user filter may not use the name attribute, so match any user and
+	// filter by name
+	filter := "(&" + c.userFilter("*") + "(" + c.UserNameAttribute + "=" + ldap.EscapeFilter(name) + "))"
+	entry, err := c.searchUser(conn, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.user(conn, entry)
+}
+
+func (c *LDAPClient) userFilter(username string) string {
+	return strings.ReplaceAll(c.UserFilter, "{username}", username)
+}
+
+func (c *LDAPClient) groupFilter(dn string) string {
+	return strings.ReplaceAll(c.GroupFilter, "{dn}", ldap.EscapeFilter(dn))
+}
+
+func (c *LDAPClient) tlsConfig() *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.TLSConfig != nil {
+		config = c.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		if u, err := url.Parse(c.URL); err == nil {
+			config.ServerName = u.Hostname()
+		}
+	}
+	return config
+}
+
+func (c *LDAPClient) connect() (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: ldapRequestTimeout}
+	conn, err := ldap.DialURL(c.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(c.tlsConfig()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: connect to ldap server: %v", internal.ErrBadGateway, err)
+	}
+	conn.SetTimeout(ldapRequestTimeout)
+
+	if c.StartTLS {
+		if err := conn.StartTLS(c.tlsConfig()); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: ldap start tls: %v", internal.ErrBadGateway, err)
+		}
+	}
+	return conn, nil
+}
+
+func (c *LDAPClient) bindServiceAccount(conn *ldap.Conn) error {
+	if c.BindDN == "" {
+		return conn.UnauthenticatedBind("")
+	}
+	return conn.Bind(c.BindDN, c.BindPassword)
+}
+
+// searchUser returns the only user that matches the filter.
+func (c *LDAPClient) searchUser(conn *ldap.Conn, filter string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		c.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapRequestTimeout.Seconds()), false,
+		filter, []string{c.UserNameAttribute}, nil)
+
+	result, err := conn.Search(req)
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded):
+		return nil, fmt.Errorf("more than one ldap user matches the filter %v", filter)
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		return nil, fmt.Errorf("%w: ldap user", internal.ErrNotFound)
+	case err != nil:
+		return nil, fmt.Errorf("search ldap users: %w", err)
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return nil, fmt.Errorf("%w: ldap user", internal.ErrNotFound)
+	case 1:
+		return result.Entries[0], nil
+	default:
+		return nil, fmt.Errorf("more than one ldap user matches the filter %v", filter)
+	}
+}
+
+func (c *LDAPClient) user(conn *ldap.Conn, entry *ldap.Entry) (*LDAPUser, error) {
+	name := entry.GetEqualFoldAttributeValue(c.UserNameAttribute)
+	if name == "" || strings.ContainsAny(name, ` '`) {
+		return nil, fmt.Errorf("ldap user %v has an invalid %v attribute", entry.DN, c.UserNameAttribute)
+	}
+
+	req := ldap.NewSearchRequest(
+		c.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(ldapRequestTimeout.Seconds()), false,
+		c.groupFilter(entry.DN), []string{c.GroupNameAttribute}, nil)
+
+	result, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, fmt.Errorf("search ldap groups: %w", err)
+	}
+
+	groups := []string{}
+	if result != nil {
+		for _, group := range result.Entries {
+			if groupName := group.GetEqualFoldAttributeValue(c.GroupNameAttribute); groupName != "" {
+				groups = append(groups, groupName)
+			}
+		}
+	}
+
+	logging.Debugf("user synchronized with %q groups from ldap provider", &groups)
+
+	return &LDAPUser{DN: entry.DN, Name: name, Groups: groups}, nil
+}
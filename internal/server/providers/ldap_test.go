@@ -0,0 +1,232 @@
+package providers
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/models"
+	ldaptest "github.com/infrahq/infra/internal/testing/ldap"
+	"github.com/infrahq/infra/internal/validate"
+)
+
+func ldapTestEntries() []ldaptest.Entry {
+	return []ldaptest.Entry{
+		{
+			DN: "cn=infra,ou=services,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass":  {"person"},
+				"userPassword": {"service-password"},
+			},
+		},
+		{
+			DN: "uid=ada,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass":  {"person", "inetOrgPerson"},
+				"uid":          {"ada"},
+				"mail":         {"ada@example.com"},
+				"userPassword": {"ada-password"},
+			},
+		},
+		{
+			DN: "uid=grace,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass":  {"person", "inetOrgPerson"},
+				"uid":          {"grace"},
+				"mail":         {"grace@example.com"},
+				"userPassword": {"grace-password"},
+			},
+		},
+		{
+			DN: "cn=developers,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"developers"},
+				"member":      {"uid=ada,ou=people,dc=example,dc=com", "uid=grace,ou=people,dc=example,dc=com"},
+			},
+		},
+		{
+			DN: "cn=admins,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"objectClass": {"groupOfNames"},
+				"cn":          {"admins"},
+				"member":      {"uid=ada,ou=people,dc=example,dc=com"},
+			},
+		},
+	}
+}
+
+func newTestLDAPClient(server *ldaptest.Server) *LDAPClient {
+	client := NewLDAPClient(models.Provider{
+		Kind:             models.ProviderKindLDAP,
+		URL:              server.URL,
+		LDAPBindDN:       "cn=infra,ou=services,dc=example,dc=com",
+		LDAPBindPassword: "service-password",
+		LDAPUserBaseDN:   "dc=example,dc=com",
+	})
+	client.TLSConfig = &tls.Config{RootCAs: server.RootCAs(), MinVersion: tls.VersionTLS12}
+	return client
+}
+
+func TestLDAPClient_Authenticate(t *testing.T) {
+	server := ldaptest.NewServer(t, ldapTestEntries()...)
+
+	t.Run("success", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		user, err := client.Authenticate("ada", "ada-password")
+		assert.NilError(t, err)
+		expected := &LDAPUser{
+			DN:     "uid=ada,ou=people,dc=example,dc=com",
+			Name:   "ada@example.com",
+			Groups: []string{"developers", "admins"},
+		}
+		assert.DeepEqual(t, user, expected)
+	})
+
+	t.Run("with StartTLS", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		client.StartTLS = true
+		user, err := client.Authenticate("grace", "grace-password")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, user.Groups, []string{"developers"})
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		_, err := client.Authenticate("ada", "grace-password")
+		assert.Assert(t, errors.Is(err, internal.ErrUnauthorized), err)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		_, err := client.Authenticate("ada", "")
+		assert.Assert(t, errors.Is(err, internal.ErrUnauthorized), err)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		_, err := client.Authenticate("linus", "linus-password")
+		assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+
+	t.Run("filter injection", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		_, err := client.Authenticate("*", "ada-password")
+		assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+
+	t.Run("filter matches more than one user", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		client.UserFilter = "(objectClass=inetOrgPerson)"
+		_, err := client.Authenticate("ada", "ada-password")
+		assert.ErrorContains(t, err, "more than one ldap user matches the filter")
+	})
+
+	t.Run("wrong service account password", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		client.BindPassword = "wrong"
+		_, err := client.Authenticate("ada", "ada-password")
+		assert.ErrorContains(t, err, "bind ldap service account")
+	})
+
+	t.Run("server unavailable", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		client.URL = "ldap://127.0.0.1:1"
+		_, err := client.Authenticate("ada", "ada-password")
+		assert.Assert(t, errors.Is(err, internal.ErrBadGateway), err)
+	})
+}
+
+func TestLDAPClient_AuthenticateLDAPS(t *testing.T) {
+	server := ldaptest.NewTLSServer(t, ldapTestEntries()...)
+
+	client := newTestLDAPClient(server)
+	user, err := client.Authenticate("ada", "ada-password")
+	assert.NilError(t, err)
+	assert.Equal(t, user.Name, "ada@example.com")
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		client.TLSConfig = nil
+		_, err := client.Authenticate("ada", "ada-password")
+		assert.Assert(t, errors.Is(err, internal.ErrBadGateway), err)
+	})
+}
+
+func TestLDAPClient_LookupUser(t *testing.T) {
+	server := ldaptest.NewServer(t, ldapTestEntries()...)
+	client := newTestLDAPClient(server)
+
+	user, err := client.LookupUser("grace@example.com")
+	assert.NilError(t, err)
+	expected := &LDAPUser{
+		DN:     "uid=grace,ou=people,dc=example,dc=com",
+		Name:   "grace@example.com",
+		Groups: []string{"developers"},
+	}
+	assert.DeepEqual(t, user, expected)
+
+	server.Delete("cn=developers,ou=groups,dc=example,dc=com")
+	user, err = client.LookupUser("grace@example.com")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, user.Groups, []string{})
+
+	server.Delete("uid=grace,ou=people,dc=example,dc=com")
+	_, err = client.LookupUser("grace@example.com")
+	assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+}
+
+func TestLDAPClient_Validate(t *testing.T) {
+	server := ldaptest.NewServer(t, ldapTestEntries()...)
+
+	t.Run("success", func(t *testing.T) {
+		client := newTestLDAPClient(server)
+		assert.NilError(t, client.Validate())
+	})
+
+	type testCase struct {
+		name     string
+		setup    func(client *LDAPClient)
+		expected validate.Error
+	}
+	testCases := []testCase{
+		{
+			name:     "not an ldap url",
+			setup:    func(client *LDAPClient) { client.URL = "https://ldap.example.com" },
+			expected: validate.Error{"url": {"must be an ldap:// or ldaps:// URL"}},
+		},
+		{
+			name:     "StartTLS with ldaps",
+			setup:    func(client *LDAPClient) { client.URL = "ldaps://ldap.example.com"; client.StartTLS = true },
+			expected: validate.Error{"ldap.startTLS": {"can not be used with an ldaps:// URL"}},
+		},
+		{
+			name:     "invalid user filter",
+			setup:    func(client *LDAPClient) { client.UserFilter = "(uid={username}" },
+			expected: validate.Error{"ldap.userFilter": {"invalid ldap filter"}},
+		},
+		{
+			name:     "server unavailable",
+			setup:    func(client *LDAPClient) { client.URL = "ldap://127.0.0.1:1" },
+			expected: validate.Error{"url": {"invalid provider url"}},
+		},
+		{
+			name:     "wrong service account password",
+			setup:    func(client *LDAPClient) { client.BindPassword = "wrong" },
+			expected: validate.Error{"ldap.bindPassword": {"invalid provider ldap.bindPassword"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestLDAPClient(server)
+			tc.setup(client)
+			err := client.Validate()
+			var verr validate.Error
+			assert.Assert(t, errors.As(err, &verr), err)
+			assert.DeepEqual(t, verr, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// oauthMaxPages limits the number of pages read from a paginated API
+// response, so that a misbehaving API can not keep a request running forever.
+const oauthMaxPages = 20
+
+// oauthMaxResponseSize limits the size of each API response.
+const oauthMaxResponseSize = 10 * 1024 * 1024
+
+// validateOAuthClient checks the client credentials of an OAuth 2.0 identity
+// provider by exchanging an invalid code. The exchange always fails, but the
+// error is different when the client credentials are invalid.
+func validateOAuthClient(ctx context.Context, conf *oauth2.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, oidcProviderRequestTimeout)
+	defer cancel()
+
+	_, err := conf.Exchange(ctx, "test-code")
+	if err == nil {
+		return nil
+	}
+
+	var errRetrieve *oauth2.RetrieveError
+	if errors.As(err, &errRetrieve) {
+		body := string(errRetrieve.Body)
+		if strings.Contains(body, "invalid_client") || strings.Contains(body, "client_id") {
+			logging.Debugf("error validating oauth provider client: %s", err)
+			return newValidationError("clientID")
+		}
+	}
+
+	var errURL *url.Error
+	if errors.As(err, &errURL) {
+		logging.Debugf("error validating oauth provider: %s", err)
+		return newValidationError("url")
+	}
+
+	logging.L.Trace().Err(err).Msg("error validating oauth provider, this is expected")
+	return nil
+}
+
+// exchangeOAuthCode exchanges the authorization code a user received on login
+// for tokens, and returns an HTTP client that uses the tokens.
+func exchangeOAuthCode(ctx context.Context, conf *oauth2.Config, code string) (*oauth2.Token, *http.Client, error) {
+	token, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return nil, nil, fmt.Errorf("code exchange: %w", err)
+	}
+	return token, conf.Client(ctx, token), nil
+}
+
+// oauthClient returns an HTTP client that uses the tokens of the provider user.
+func oauthClient(ctx context.Context, conf *oauth2.Config, providerUser *models.ProviderUser) *http.Client {
+	return oauth2.NewClient(ctx, oauthTokenSource(ctx, conf, providerUser))
+}
+
+func oauthTokenSource(ctx context.Context, conf *oauth2.Config, providerUser *models.ProviderUser) oauth2.TokenSource {
+	return conf.TokenSource(ctx, &oauth2.Token{
+		AccessToken:  string(providerUser.AccessToken),
+		RefreshToken: string(providerUser.RefreshToken),
+		Expiry:       providerUser.ExpiresAt,
+	})
+}
+
+// refreshOAuthToken uses the refresh token to get a new access token if it is
+// expired. Access tokens without an expiry are returned as is.
+func refreshOAuthToken(ctx context.Context, conf *oauth2.Config, providerUser *models.ProviderUser) (string, *time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcProviderRequestTimeout)
+	defer cancel()
+
+	token, err := oauthTokenSource(ctx, conf, providerUser).Token()
+	if err != nil {
+		return "", nil, fmt.Errorf("refresh user token: %w", err)
+	}
+	return token.AccessToken, &token.Expiry, nil
+}
+
+// getJSON decodes the JSON response to a GET request to the API of an identity
+// provider. It returns the URL of the next page of results from the Link
+// header of the response, or an empty string when there are no more pages.
+func getJSON(ctx context.Context, client *http.Client, apiURL string, accept string, v interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return "", fmt.Errorf("%w: %s", internal.ErrBadGateway, err)
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %v from %v", resp.Status, req.URL.Path)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oauthMaxResponseSize)).Decode(v); err != nil {
+		return "", fmt.Errorf("decode response from %v: %w", req.URL.Path, err)
+	}
+	return nextPageURL(resp.Header.Get("Link")), nil
+}
+
+// nextPageURL returns the URL with rel="next" from a Link header.
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				target = strings.TrimSpace(target)
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+	return ""
+}
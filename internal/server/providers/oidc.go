@@ -90,6 +90,20 @@ func NewOIDCClient(provider models.Provider, clientSecret, redirectURL string) O
 				DomainAdminEmail: provider.DomainAdminEmail,
 			},
 		}
+	case models.ProviderKindGitHub:
+		return &github{
+			Domain:       provider.URL,
+			ClientID:     provider.ClientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+		}
+	case models.ProviderKindGitLab:
+		return &gitlab{
+			Domain:       provider.URL,
+			ClientID:     provider.ClientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+		}
 	default:
 		return oidcClient
 	}
@@ -0,0 +1,532 @@
+package providers
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+
+	"github.com/infrahq/infra/internal/generate"
+)
+
+const (
+	samlAssertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlProtocolNamespace  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlMetadataNamespace  = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	samlHTTPRedirectBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	samlHTTPPostBinding     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlStatusSuccess       = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlBearerConfirmation  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	samlNameIDFormatEmail   = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+
+	// samlClockSkew is the difference allowed between the clock of the identity
+	// provider and the clock of the server when validating assertions.
+	samlClockSkew = 3 * time.Minute
+	// samlMetadataMaxSize is the maximum size of metadata fetched from a URL.
+	samlMetadataMaxSize = 1024 * 1024
+)
+
+// samlMetadataClient is the HTTP client used to fetch identity provider
+// metadata.
+var samlMetadataClient = &http.Client{Timeout: oidcProviderRequestTimeout}
+
+// SAMLIdentityProvider is the configuration of a SAML identity provider, read
+// from its metadata.
+type SAMLIdentityProvider struct {
+	EntityID string
+	// SSOURL is the location of the single sign-on service that uses the
+	// HTTP-Redirect binding.
+	SSOURL string
+	// Certificates are used to verify the signature of responses from the
+	// identity provider.
+	Certificates []*x509.Certificate
+}
+
+type samlEntityDescriptor struct {
+	XMLName           xml.Name
+	EntityID          string                 `xml:"entityID,attr"`
+	IDPSSODescriptors []samlIDPSSODescriptor `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
+	// EntityDescriptors is set when the root element is an EntitiesDescriptor
+	EntityDescriptors []samlEntityDescriptor `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+}
+
+type samlIDPSSODescriptor struct {
+	KeyDescriptors []struct {
+		Use          string   `xml:"use,attr"`
+		Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:metadata KeyDescriptor"`
+	SingleSignOnServices []struct {
+		Binding  string `xml:"Binding,attr"`
+		Location string `xml:"Location,attr"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:metadata SingleSignOnService"`
+}
+
+// ParseSAMLMetadata reads the configuration of an identity provider from its
+// XML metadata. The metadata is trusted, so any signature on the metadata is
+// not verified.
+func ParseSAMLMetadata(raw []byte) (*SAMLIdentityProvider, error) {
+	var root samlEntityDescriptor
+	if err := xml.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("invalid saml metadata: %w", err)
+	}
+	if root.XMLName.Space != samlMetadataNamespace {
+		return nil, fmt.Errorf("invalid saml metadata: unexpected element %v", root.XMLName.Local)
+	}
+
+	entities := []samlEntityDescriptor{root}
+	if root.XMLName.Local == "EntitiesDescriptor" {
+		entities = root.EntityDescriptors
+	}
+
+	for _, entity := range entities {
+		if len(entity.IDPSSODescriptors) == 0 {
+			continue
+		}
+		descriptor := entity.IDPSSODescriptors[0]
+
+		idp := &SAMLIdentityProvider{EntityID: entity.EntityID}
+		if idp.EntityID == "" {
+			return nil, errors.New("invalid saml metadata: missing entityID")
+		}
+
+		for _, service := range descriptor.SingleSignOnServices {
+			if service.Binding == samlHTTPRedirectBinding {
+				idp.SSOURL = service.Location
+				break
+			}
+		}
+		if idp.SSOURL == "" {
+			return nil, errors.New("invalid saml metadata: identity provider does not support the HTTP-Redirect binding")
+		}
+
+		for _, key := range descriptor.KeyDescriptors {
+			if key.Use != "" && key.Use != "signing" {
+				continue
+			}
+			for _, encoded := range key.Certificates {
+				der, err := decodeXMLBase64(encoded)
+				if err != nil {
+					return nil, fmt.Errorf("invalid saml metadata: decode certificate: %w", err)
+				}
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, fmt.Errorf("invalid saml metadata: parse certificate: %w", err)
+				}
+				idp.Certificates = append(idp.Certificates, cert)
+			}
+		}
+		if len(idp.Certificates) == 0 {
+			return nil, errors.New("invalid saml metadata: no signing certificates")
+		}
+		return idp, nil
+	}
+	return nil, errors.New("invalid saml metadata: no identity provider descriptor")
+}
+
+// FetchSAMLMetadata fetches the XML metadata of an identity provider from a URL.
+// The certificates in the metadata are trusted, so the URL must use https.
+func FetchSAMLMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	u, err := url.Parse(metadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid saml metadata url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, errors.New("saml metadata url must use https")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := samlMetadataClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch saml metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch saml metadata: unexpected response status: %v", resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, samlMetadataMaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("fetch saml metadata: %w", err)
+	}
+	return raw, nil
+}
+
+// SAMLServiceProvider is the configuration of Infra as the service provider
+// for a SAML identity provider.
+type SAMLServiceProvider struct {
+	EntityID string
+	// ACSURL is the URL of the assertion consumer service, where the identity
+	// provider sends the response with the HTTP-POST binding.
+	ACSURL string
+}
+
+type samlSPEntityDescriptor struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string   `xml:"entityID,attr"`
+	SPSSODescriptor struct {
+		AuthnRequestsSigned        bool   `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool   `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string `xml:"protocolSupportEnumeration,attr"`
+		NameIDFormat               string `xml:"urn:oasis:names:tc:SAML:2.0:metadata NameIDFormat"`
+		AssertionConsumerService   struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+			Index    int    `xml:"index,attr"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata AssertionConsumerService"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:metadata SPSSODescriptor"`
+}
+
+// Metadata returns the XML metadata of the service provider, which is imported
+// by the identity provider.
+func (sp SAMLServiceProvider) Metadata() ([]byte, error) {
+	descriptor := samlSPEntityDescriptor{EntityID: sp.EntityID}
+	descriptor.SPSSODescriptor.WantAssertionsSigned = true
+	descriptor.SPSSODescriptor.ProtocolSupportEnumeration = samlProtocolNamespace
+	descriptor.SPSSODescriptor.NameIDFormat = samlNameIDFormatEmail
+	descriptor.SPSSODescriptor.AssertionConsumerService.Binding = samlHTTPPostBinding
+	descriptor.SPSSODescriptor.AssertionConsumerService.Location = sp.ACSURL
+
+	raw, err := xml.MarshalIndent(descriptor, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), raw...), nil
+}
+
+type samlAuthnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+}
+
+// AuthnRequestURL returns the URL that starts a login at the identity provider,
+// using the HTTP-Redirect binding. The identity provider sends the relayState
+// back to the assertion consumer service with the response.
+//
+// The requestID must be stored by the caller, so that the response can be
+// checked against the requests issued by the server.
+func (sp SAMLServiceProvider) AuthnRequestURL(idp *SAMLIdentityProvider, relayState string, now time.Time) (redirectURL string, requestID string, err error) {
+	id, err := generate.CryptoRandom(32, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return "", "", err
+	}
+
+	request := samlAuthnRequest{
+		// IDs must not start with a number
+		ID:                          "_" + id,
+		Version:                     "2.0",
+		IssueInstant:                now.UTC().Format(time.RFC3339),
+		Destination:                 idp.SSOURL,
+		AssertionConsumerServiceURL: sp.ACSURL,
+		ProtocolBinding:             samlHTTPPostBinding,
+		Issuer:                      sp.EntityID,
+	}
+	raw, err := xml.Marshal(request)
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+
+	u, err := url.Parse(idp.SSOURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid saml sso url: %w", err)
+	}
+	query := u.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), request.ID, nil
+}
+
+// SAMLAssertion is the verified assertion from a SAML response.
+type SAMLAssertion struct {
+	ID     string
+	NameID string
+	// InResponseTo is the ID of the AuthnRequest that the assertion was issued
+	// for.
+	InResponseTo string
+	// Attributes are the values of the assertion attributes, by attribute
+	// name.
+	Attributes map[string][]string
+	// ExpiresAt is the time after which the assertion can no longer be used.
+	ExpiresAt time.Time
+}
+
+type samlResponse struct {
+	Destination  string `xml:"Destination,attr"`
+	InResponseTo string `xml:"InResponseTo,attr"`
+	Issuer       string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Status       struct {
+		StatusCode struct {
+			Value string `xml:"Value,attr"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:protocol StatusCode"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:protocol Status"`
+	Assertions []samlAssertion `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+}
+
+type samlAssertion struct {
+	ID      string `xml:"ID,attr"`
+	Issuer  string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Subject struct {
+		NameID               string `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+		SubjectConfirmations []struct {
+			Method string `xml:"Method,attr"`
+			Data   struct {
+				Recipient    string    `xml:"Recipient,attr"`
+				InResponseTo string    `xml:"InResponseTo,attr"`
+				NotBefore    time.Time `xml:"NotBefore,attr"`
+				NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+			} `xml:"urn:oasis:names:tc:SAML:2.0:assertion SubjectConfirmationData"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:assertion SubjectConfirmation"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Subject"`
+	Conditions *struct {
+		NotBefore    time.Time `xml:"NotBefore,attr"`
+		NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+		Audiences    []string  `xml:"urn:oasis:names:tc:SAML:2.0:assertion AudienceRestriction>Audience"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Conditions"`
+	Attributes []struct {
+		Name         string   `xml:"Name,attr"`
+		FriendlyName string   `xml:"FriendlyName,attr"`
+		Values       []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion AttributeValue"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion AttributeStatement>Attribute"`
+}
+
+// ParseResponse verifies a base64 encoded SAML response that was sent to the
+// assertion consumer service with the HTTP-POST binding, and returns the
+// assertion from the response.
+//
+// The response or the assertion must be signed by one of the certificates of
+// the identity provider. Only values from the signed element are used, and
+// responses with more than one assertion are rejected, to prevent signature
+// wrapping attacks. Encrypted assertions are not supported.
+//
+// The assertion must be in response to an AuthnRequest. The caller must check
+// that SAMLAssertion.InResponseTo is the ID of a request it issued.
+func (sp SAMLServiceProvider) ParseResponse(idp *SAMLIdentityProvider, encoded string, now time.Time) (*SAMLAssertion, error) {
+	raw, err := decodeXMLBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode saml response: %w", err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, fmt.Errorf("parse saml response: %w", err)
+	}
+	root := doc.Root()
+	if root == nil {
+		return nil, errors.New("parse saml response: missing root element")
+	}
+	if root.NamespaceURI() != samlProtocolNamespace || root.Tag != "Response" {
+		return nil, fmt.Errorf("unexpected saml message %v", root.Tag)
+	}
+	if len(samlChildElements(root, samlAssertionNamespace, "EncryptedAssertion")) > 0 {
+		return nil, errors.New("encrypted saml assertions are not supported")
+	}
+	assertionEls := samlChildElements(root, samlAssertionNamespace, "Assertion")
+	if len(assertionEls) != 1 {
+		return nil, fmt.Errorf("expected one Assertion element in Response, found %d", len(assertionEls))
+	}
+	assertionEl := assertionEls[0]
+
+	var response samlResponse
+	var assertion samlAssertion
+	var signed bool
+	if len(samlChildElements(root, dsig.Namespace, dsig.SignatureTag)) > 0 {
+		if err := verifySAMLSignature(root, idp, now, &response); err != nil {
+			return nil, fmt.Errorf("verify response signature: %w", err)
+		}
+		if len(response.Assertions) != 1 {
+			return nil, errors.New("saml response must contain exactly one assertion")
+		}
+		assertion = response.Assertions[0]
+		signed = true
+	} else {
+		// the unsigned response is only used for its status and destination
+		if err := xml.Unmarshal(raw, &response); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(samlChildElements(assertionEl, dsig.Namespace, dsig.SignatureTag)) > 0 {
+		assertion = samlAssertion{}
+		if err := verifySAMLSignature(assertionEl, idp, now, &assertion); err != nil {
+			return nil, fmt.Errorf("verify assertion signature: %w", err)
+		}
+		signed = true
+	}
+	if !signed {
+		return nil, errors.New("saml response is not signed")
+	}
+
+	if code := response.Status.StatusCode.Value; code != samlStatusSuccess {
+		return nil, fmt.Errorf("saml response status is %q", code)
+	}
+	if response.Destination != "" && response.Destination != sp.ACSURL {
+		return nil, fmt.Errorf("saml response destination %q does not match %q", response.Destination, sp.ACSURL)
+	}
+	if issuer := strings.TrimSpace(response.Issuer); issuer != "" && issuer != idp.EntityID {
+		return nil, fmt.Errorf("saml response issuer %q does not match %q", issuer, idp.EntityID)
+	}
+
+	result, err := sp.validateAssertion(idp, assertion, now)
+	if err != nil {
+		return nil, err
+	}
+	if response.InResponseTo != "" && response.InResponseTo != result.InResponseTo {
+		return nil, fmt.Errorf("saml response is in response to %q, but the assertion is in response to %q", response.InResponseTo, result.InResponseTo)
+	}
+	return result, nil
+}
+
+// verifySAMLSignature verifies the enveloped signature of el, and unmarshals
+// the signed element into v.
+func verifySAMLSignature(el *etree.Element, idp *SAMLIdentityProvider, now time.Time, v interface{}) error {
+	// declare the namespaces of ancestors on the element, so that the signed
+	// element can be used on its own
+	nsCtx, err := etreeutils.NSBuildParentContext(el)
+	if err != nil {
+		return err
+	}
+	detached, err := etreeutils.NSDetatch(nsCtx, el)
+	if err != nil {
+		return err
+	}
+
+	validator := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: idp.Certificates})
+	validator.Clock = dsig.NewFakeClockAt(now)
+	signed, err := validator.Validate(detached)
+	if err != nil {
+		return err
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(signed)
+	raw, err := doc.WriteToBytes()
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(raw, v)
+}
+
+func samlChildElements(el *etree.Element, namespace, tag string) []*etree.Element {
+	var result []*etree.Element
+	for _, child := range el.ChildElements() {
+		if child.NamespaceURI() == namespace && child.Tag == tag {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// decodeXMLBase64 decodes base64 content that may be wrapped with whitespace.
+func decodeXMLBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+}
+
+func (sp SAMLServiceProvider) validateAssertion(idp *SAMLIdentityProvider, assertion samlAssertion, now time.Time) (*SAMLAssertion, error) {
+	if assertion.ID == "" {
+		return nil, errors.New("saml assertion is missing an ID")
+	}
+	if issuer := strings.TrimSpace(assertion.Issuer); issuer != idp.EntityID {
+		return nil, fmt.Errorf("saml assertion issuer %q does not match %q", issuer, idp.EntityID)
+	}
+
+	var expiresAt time.Time
+	var inResponseTo string
+	for _, confirmation := range assertion.Subject.SubjectConfirmations {
+		data := confirmation.Data
+		switch {
+		case confirmation.Method != samlBearerConfirmation:
+		case data.Recipient != sp.ACSURL:
+		// logins started by the identity provider are not supported
+		case data.InResponseTo == "":
+		case data.NotOnOrAfter.IsZero(), !now.Before(data.NotOnOrAfter.Add(samlClockSkew)):
+		case !data.NotBefore.IsZero() && now.Before(data.NotBefore.Add(-samlClockSkew)):
+		default:
+			expiresAt = data.NotOnOrAfter
+			inResponseTo = data.InResponseTo
+		}
+		if !expiresAt.IsZero() {
+			break
+		}
+	}
+	if expiresAt.IsZero() {
+		return nil, errors.New("saml assertion does not have a valid bearer subject confirmation")
+	}
+
+	conditions := assertion.Conditions
+	switch {
+	case conditions == nil:
+		return nil, errors.New("saml assertion is missing conditions")
+	case !conditions.NotBefore.IsZero() && now.Before(conditions.NotBefore.Add(-samlClockSkew)):
+		return nil, errors.New("saml assertion is not valid yet")
+	case !conditions.NotOnOrAfter.IsZero() && !now.Before(conditions.NotOnOrAfter.Add(samlClockSkew)):
+		return nil, errors.New("saml assertion has expired")
+	}
+	if !conditions.NotOnOrAfter.IsZero() && conditions.NotOnOrAfter.Before(expiresAt) {
+		expiresAt = conditions.NotOnOrAfter
+	}
+
+	var audience bool
+	for _, value := range conditions.Audiences {
+		if strings.TrimSpace(value) == sp.EntityID {
+			audience = true
+		}
+	}
+	if !audience {
+		return nil, fmt.Errorf("saml assertion audience does not include %q", sp.EntityID)
+	}
+
+	result := &SAMLAssertion{
+		ID:           assertion.ID,
+		NameID:       strings.TrimSpace(assertion.Subject.NameID),
+		InResponseTo: inResponseTo,
+		Attributes:   map[string][]string{},
+		ExpiresAt:    expiresAt.Add(samlClockSkew),
+	}
+	for _, attr := range assertion.Attributes {
+		values := make([]string, 0, len(attr.Values))
+		for _, value := range attr.Values {
+			values = append(values, strings.TrimSpace(value))
+		}
+		result.Attributes[attr.Name] = append(result.Attributes[attr.Name], values...)
+		if attr.FriendlyName != "" && attr.FriendlyName != attr.Name {
+			result.Attributes[attr.FriendlyName] = append(result.Attributes[attr.FriendlyName], values...)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,396 @@
+package providers
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/testing/saml"
+)
+
+func TestParseSAMLMetadata(t *testing.T) {
+	idp := saml.NewIdP(t)
+
+	t.Run("entity descriptor", func(t *testing.T) {
+		actual, err := ParseSAMLMetadata([]byte(idp.Metadata()))
+		assert.NilError(t, err)
+		assert.Equal(t, actual.EntityID, idp.EntityID)
+		assert.Equal(t, actual.SSOURL, "https://idp.example.com/sso?tenant=1")
+		assert.Equal(t, len(actual.Certificates), 1)
+		assert.Assert(t, actual.Certificates[0].Equal(idp.Certificate()))
+	})
+
+	t.Run("entities descriptor", func(t *testing.T) {
+		metadata := strings.Replace(idp.Metadata(), `<?xml version="1.0"?>`, "", 1)
+		metadata = `<md:EntitiesDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata">
+  <md:EntityDescriptor entityID="https://sp.example.com"><md:SPSSODescriptor/></md:EntityDescriptor>
+  ` + metadata + `
+</md:EntitiesDescriptor>`
+		actual, err := ParseSAMLMetadata([]byte(metadata))
+		assert.NilError(t, err)
+		assert.Equal(t, actual.EntityID, idp.EntityID)
+	})
+
+	t.Run("not metadata", func(t *testing.T) {
+		_, err := ParseSAMLMetadata([]byte(`<html></html>`))
+		assert.ErrorContains(t, err, "unexpected element html")
+	})
+
+	t.Run("no redirect binding", func(t *testing.T) {
+		metadata := strings.Replace(idp.Metadata(), "HTTP-Redirect", "SOAP", 1)
+		_, err := ParseSAMLMetadata([]byte(metadata))
+		assert.ErrorContains(t, err, "does not support the HTTP-Redirect binding")
+	})
+}
+
+func TestFetchSAMLMetadata(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/metadata" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("<metadata/>"))
+	}))
+	t.Cleanup(srv.Close)
+
+	orig := samlMetadataClient
+	samlMetadataClient = srv.Client()
+	t.Cleanup(func() {
+		samlMetadataClient = orig
+	})
+
+	ctx := context.Background()
+	raw, err := FetchSAMLMetadata(ctx, srv.URL+"/metadata")
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "<metadata/>")
+
+	_, err = FetchSAMLMetadata(ctx, srv.URL+"/missing")
+	assert.ErrorContains(t, err, "unexpected response status: 404 Not Found")
+
+	_, err = FetchSAMLMetadata(ctx, "http://idp.example.com/metadata")
+	assert.ErrorContains(t, err, "must use https")
+}
+
+func TestSAMLServiceProvider_Metadata(t *testing.T) {
+	sp := SAMLServiceProvider{
+		EntityID: "https://infra.example.com/api/providers/123/saml/metadata",
+		ACSURL:   "https://infra.example.com/api/providers/123/saml/acs",
+	}
+	raw, err := sp.Metadata()
+	assert.NilError(t, err)
+
+	var actual samlSPEntityDescriptor
+	assert.NilError(t, xml.Unmarshal(raw, &actual))
+	assert.Equal(t, actual.EntityID, sp.EntityID)
+	assert.Equal(t, actual.SPSSODescriptor.WantAssertionsSigned, true)
+	assert.Equal(t, actual.SPSSODescriptor.AssertionConsumerService.Binding, samlHTTPPostBinding)
+	assert.Equal(t, actual.SPSSODescriptor.AssertionConsumerService.Location, sp.ACSURL)
+}
+
+func TestSAMLServiceProvider_AuthnRequestURL(t *testing.T) {
+	sp := SAMLServiceProvider{
+		EntityID: "https://infra.example.com/api/providers/123/saml/metadata",
+		ACSURL:   "https://infra.example.com/api/providers/123/saml/acs",
+	}
+	idp := &SAMLIdentityProvider{EntityID: "https://idp.example.com", SSOURL: "https://idp.example.com/sso?tenant=1"}
+	now := time.Date(2022, 12, 8, 9, 0, 0, 0, time.UTC)
+
+	raw, requestID, err := sp.AuthnRequestURL(idp, "/destinations", now)
+	assert.NilError(t, err)
+
+	u, err := url.Parse(raw)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Host, "idp.example.com")
+	assert.Equal(t, u.Path, "/sso")
+	assert.Equal(t, u.Query().Get("tenant"), "1")
+	assert.Equal(t, u.Query().Get("RelayState"), "/destinations")
+
+	compressed, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	assert.NilError(t, err)
+	decoded, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	assert.NilError(t, err)
+
+	var request samlAuthnRequest
+	assert.NilError(t, xml.Unmarshal(decoded, &request))
+	assert.Assert(t, strings.HasPrefix(request.ID, "_"))
+	assert.Equal(t, request.ID, requestID)
+	assert.Equal(t, request.Version, "2.0")
+	assert.Equal(t, request.IssueInstant, "2022-12-08T09:00:00Z")
+	assert.Equal(t, request.Destination, idp.SSOURL)
+	assert.Equal(t, request.AssertionConsumerServiceURL, sp.ACSURL)
+	assert.Equal(t, request.Issuer, sp.EntityID)
+}
+
+func TestSAMLServiceProvider_ParseResponse(t *testing.T) {
+	idp := saml.NewIdP(t)
+	metadata, err := ParseSAMLMetadata([]byte(idp.Metadata()))
+	assert.NilError(t, err)
+
+	sp := SAMLServiceProvider{
+		EntityID: "https://infra.example.com/api/providers/123/saml/metadata",
+		ACSURL:   "https://infra.example.com/api/providers/123/saml/acs",
+	}
+	valid := saml.Assertion{
+		ID:           "_assertion",
+		NameID:       "alice@example.com",
+		Audience:     sp.EntityID,
+		Recipient:    sp.ACSURL,
+		Destination:  sp.ACSURL,
+		InResponseTo: "_request",
+		Attributes: []saml.Attribute{
+			{Name: "http://schemas.xmlsoap.org/claims/Group", FriendlyName: "groups", Values: []string{"developers", "Domain Users"}},
+			{Name: "email", Values: []string{"alice@example.com"}},
+		},
+		SignAssertion: true,
+	}
+	encode := func(doc string) string {
+		return base64.StdEncoding.EncodeToString([]byte(doc))
+	}
+
+	type testCase struct {
+		name      string
+		response  func(t *testing.T) string
+		expectErr string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		actual, err := sp.ParseResponse(metadata, encode(tc.response(t)), time.Now())
+		if tc.expectErr != "" {
+			assert.ErrorContains(t, err, tc.expectErr)
+			return
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, actual.ID, "_assertion")
+		assert.Equal(t, actual.NameID, "alice@example.com")
+		assert.Equal(t, actual.InResponseTo, "_request")
+		assert.DeepEqual(t, actual.Attributes["groups"], []string{"developers", "Domain Users"})
+		assert.DeepEqual(t, actual.Attributes["http://schemas.xmlsoap.org/claims/Group"], []string{"developers", "Domain Users"})
+		assert.DeepEqual(t, actual.Attributes["email"], []string{"alice@example.com"})
+		assert.Assert(t, actual.ExpiresAt.After(time.Now().Add(5*time.Minute)))
+	}
+
+	modify := func(f func(a *saml.Assertion)) func(t *testing.T) string {
+		return func(t *testing.T) string {
+			a := valid
+			f(&a)
+			return idp.Response(t, a)
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name:     "signed assertion",
+			response: modify(func(a *saml.Assertion) {}),
+		},
+		{
+			name: "signed response",
+			response: modify(func(a *saml.Assertion) {
+				a.SignAssertion = false
+				a.SignResponse = true
+			}),
+		},
+		{
+			name: "signed response and assertion",
+			response: modify(func(a *saml.Assertion) {
+				a.SignResponse = true
+			}),
+		},
+		{
+			name: "not signed",
+			response: modify(func(a *saml.Assertion) {
+				a.SignAssertion = false
+			}),
+			expectErr: "saml response is not signed",
+		},
+		{
+			name: "modified after signing",
+			response: func(t *testing.T) string {
+				doc := idp.Response(t, valid)
+				return strings.Replace(doc, "alice@example.com", "admin@example.com", 1)
+			},
+			expectErr: "Signature could not be verified",
+		},
+		{
+			name: "signed by another identity provider",
+			response: func(t *testing.T) string {
+				other := saml.NewIdP(t)
+				other.EntityID = idp.EntityID
+				return other.Response(t, valid)
+			},
+			expectErr: "Could not verify certificate against trusted certs",
+		},
+		{
+			name: "wrong issuer",
+			response: modify(func(a *saml.Assertion) {
+				a.Issuer = "https://other.example.com"
+			}),
+			expectErr: `issuer "https://other.example.com" does not match`,
+		},
+		{
+			name: "wrong audience",
+			response: modify(func(a *saml.Assertion) {
+				a.Audience = "https://other.example.com"
+			}),
+			expectErr: "saml assertion audience does not include",
+		},
+		{
+			name: "wrong recipient",
+			response: modify(func(a *saml.Assertion) {
+				a.Recipient = "https://other.example.com/acs"
+			}),
+			expectErr: "does not have a valid bearer subject confirmation",
+		},
+		{
+			name: "wrong destination",
+			response: modify(func(a *saml.Assertion) {
+				a.Destination = "https://other.example.com/acs"
+			}),
+			expectErr: "saml response destination",
+		},
+		{
+			name: "not in response to a request",
+			response: modify(func(a *saml.Assertion) {
+				a.InResponseTo = ""
+			}),
+			expectErr: "does not have a valid bearer subject confirmation",
+		},
+		{
+			name: "response and assertion in response to different requests",
+			response: func(t *testing.T) string {
+				doc := idp.Response(t, valid)
+				return strings.Replace(doc, `ID="_response" InResponseTo="_request"`, `ID="_response" InResponseTo="_other"`, 1)
+			},
+			expectErr: `saml response is in response to "_other"`,
+		},
+		{
+			name: "expired",
+			response: modify(func(a *saml.Assertion) {
+				a.NotOnOrAfter = time.Now().Add(-5 * time.Minute)
+			}),
+			expectErr: "does not have a valid bearer subject confirmation",
+		},
+		{
+			name: "failed status",
+			response: modify(func(a *saml.Assertion) {
+				a.Status = "urn:oasis:names:tc:SAML:2.0:status:Responder"
+			}),
+			expectErr: `saml response status is "urn:oasis:names:tc:SAML:2.0:status:Responder"`,
+		},
+		{
+			name: "additional unsigned assertion",
+			response: func(t *testing.T) string {
+				doc := idp.Response(t, valid)
+				evil := `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_evil"><saml:Issuer>` + idp.EntityID + `</saml:Issuer></saml:Assertion></samlp:Response>`
+				return strings.Replace(doc, "</samlp:Response>", evil, 1)
+			},
+			expectErr: "expected one Assertion element in Response, found 2",
+		},
+		{
+			name: "signed assertion moved out of the response",
+			response: func(t *testing.T) string {
+				doc := idp.Response(t, valid)
+				start := strings.Index(doc, "<saml:Assertion ")
+				end := strings.Index(doc, "</saml:Assertion>") + len("</saml:Assertion>")
+				signed := doc[start:end]
+				evil := strings.Replace(signed, "alice@example.com", "admin@example.com", -1)
+				evil = evil[:strings.Index(evil, "<ds:Signature")] + evil[strings.Index(evil, "</ds:Signature>")+len("</ds:Signature>"):]
+				return doc[:start] + "<samlp:Extensions>" + signed + "</samlp:Extensions>" + evil + doc[end:]
+			},
+			expectErr: "saml response is not signed",
+		},
+		{
+			name: "encrypted assertion",
+			response: func(t *testing.T) string {
+				doc := idp.Response(t, valid)
+				return strings.Replace(doc, "</samlp:Response>", `<saml:EncryptedAssertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"/></samlp:Response>`, 1)
+			},
+			expectErr: "encrypted saml assertions are not supported",
+		},
+		{
+			name: "not a response",
+			response: func(t *testing.T) string {
+				return `<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"/>`
+			},
+			expectErr: "unexpected saml message LogoutRequest",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+// Identity providers often declare namespaces on the response, and not on the
+// signed assertion.
+func TestSAMLServiceProvider_ParseResponse_NamespaceDeclaredByResponse(t *testing.T) {
+	ks := dsig.RandomKeyStoreForTest()
+	_, der, err := ks.GetKeyPair()
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+
+	idp := &SAMLIdentityProvider{EntityID: "https://idp.example.com/saml", Certificates: []*x509.Certificate{cert}}
+	sp := SAMLServiceProvider{
+		EntityID: "https://infra.example.com/api/providers/123/saml/metadata",
+		ACSURL:   "https://infra.example.com/api/providers/123/saml/acs",
+	}
+	notOnOrAfter := time.Now().Add(5 * time.Minute).UTC().Format(time.RFC3339)
+
+	doc := etree.NewDocument()
+	assert.NilError(t, doc.ReadFromString(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_response" InResponseTo="_request">
+  <saml:Issuer>`+idp.EntityID+`</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  <saml:Assertion ID="_assertion">
+    <saml:Issuer>`+idp.EntityID+`</saml:Issuer>
+    <saml:Subject>
+      <saml:NameID>alice@example.com</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData InResponseTo="_request" NotOnOrAfter="`+notOnOrAfter+`" Recipient="`+sp.ACSURL+`"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotOnOrAfter="`+notOnOrAfter+`">
+      <saml:AudienceRestriction><saml:Audience>`+sp.EntityID+`</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+  </saml:Assertion>
+</samlp:Response>`))
+
+	root := doc.Root()
+	assertionEl := root.SelectElement("Assertion")
+	nsCtx, err := etreeutils.NSBuildParentContext(assertionEl)
+	assert.NilError(t, err)
+	detached, err := etreeutils.NSDetatch(nsCtx, assertionEl)
+	assert.NilError(t, err)
+	signed, err := dsig.NewDefaultSigningContext(ks).SignEnveloped(detached)
+	assert.NilError(t, err)
+	// remove the declaration added by detaching, so that the namespace is
+	// only declared by the response
+	signed.RemoveAttr("xmlns:saml")
+	root.RemoveChild(assertionEl)
+	root.AddChild(signed)
+
+	raw, err := doc.WriteToBytes()
+	assert.NilError(t, err)
+
+	actual, err := sp.ParseResponse(idp, base64.StdEncoding.EncodeToString(raw), time.Now())
+	assert.NilError(t, err)
+	assert.Equal(t, actual.ID, "_assertion")
+	assert.Equal(t, actual.NameID, "alice@example.com")
+	assert.Equal(t, actual.InResponseTo, "_request")
+}
@@ -14,6 +14,7 @@ import (
 	"k8s.io/utils/strings/slices"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/internal/server/providers"
@@ -298,6 +299,30 @@ func TestAPI_CreateProvider(t *testing.T) {
 				assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
 			},
 		},
+		{
+			name: "providers write permission is not enough",
+			body: api.CreateProviderRequest{
+				Name:         "olive",
+				URL:          "https://example.com",
+				ClientID:     "client-id",
+				ClientSecret: "client-secret",
+			},
+			setup: func(t *testing.T, req *http.Request) {
+				accessKey, user := createAccessKey(t, srv.DB(), "provider-writer@example.com")
+				assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+					Subject:   user.PolyID(),
+					Privilege: "providers:write",
+					Resource:  access.ResourceInfraAPI,
+				}))
+				req.Header.Set("Authorization", "Bearer "+accessKey)
+
+				ctx := providers.WithOIDCClient(req.Context(), &fakeOIDCImplementation{})
+				*req = *req.WithContext(ctx)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+			},
+		},
 		{
 			name: "missing required fields",
 			body: api.CreateProviderRequest{},
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func (a *API) ListRoles(c *gin.Context, r *api.ListRolesRequest) (*api.ListResponse[api.Role], error) {
+	p := PaginationFromRequest(r.PaginationRequest)
+	roles, err := access.ListRoles(c, data.ListRolesOptions{ByName: r.Name, Pagination: &p})
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(roles, PaginationToResponse(p), func(role models.Role) api.Role {
+		return *role.ToAPI()
+	})
+	return result, nil
+}
+
+func (a *API) GetRole(c *gin.Context, r *api.Resource) (*api.Role, error) {
+	role, err := access.GetRole(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	return role.ToAPI(), nil
+}
+
+func (a *API) CreateRole(c *gin.Context, r *api.CreateRoleRequest) (*api.Role, error) {
+	role := &models.Role{
+		Name:            r.Name,
+		KubernetesRoles: r.KubernetesRoles,
+		InfraRoles:      r.InfraRoles,
+		Permissions:     r.Permissions,
+	}
+	if err := access.CreateRole(c, role); err != nil {
+		return nil, err
+	}
+	return role.ToAPI(), nil
+}
+
+func (a *API) DeleteRole(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteRole(c, r.ID)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestAPI_Roles(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	userKey, user := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	do := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createReq := api.CreateRoleRequest{
+		Name:            "developer",
+		KubernetesRoles: []string{"edit", "logs"},
+		InfraRoles:      []string{"view"},
+		Permissions:     []string{"connect"},
+	}
+	resp := do(t, http.MethodPost, "/api/roles", adminAccessKey(srv), createReq)
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var role api.Role
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&role))
+	assert.Equal(t, role.Name, "developer")
+	assert.DeepEqual(t, role.KubernetesRoles, []string{"edit", "logs"})
+
+	t.Run("invalid request", func(t *testing.T) {
+		req := api.CreateRoleRequest{Name: "admin", InfraRoles: []string{"support-admin"}}
+		resp := do(t, http.MethodPost, "/api/roles", adminAccessKey(srv), req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		var apiErr api.Error
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&apiErr))
+		expected := []api.FieldError{
+			{FieldName: "infraRoles", Errors: []string{`"support-admin" must be one of (admin, view)`}},
+			{FieldName: "name", Errors: []string{`"admin" is a reserved role name`}},
+		}
+		assert.DeepEqual(t, apiErr.FieldErrors, expected)
+	})
+
+	t.Run("name is the privilege of existing grants", func(t *testing.T) {
+		grantReq := api.GrantRequest{User: user.ID, Privilege: "logs", Resource: "prod"}
+		resp := do(t, http.MethodPost, "/api/grants", adminAccessKey(srv), grantReq)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		req := api.CreateRoleRequest{Name: "logs", KubernetesRoles: []string{"logs"}}
+		resp = do(t, http.MethodPost, "/api/roles", adminAccessKey(srv), req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/roles", adminAccessKey(srv), createReq)
+		assert.Equal(t, resp.Code, http.StatusConflict, resp.Body.String())
+	})
+
+	t.Run("requires admin role", func(t *testing.T) {
+		resp := do(t, http.MethodPost, "/api/roles", userKey, createReq)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = do(t, http.MethodGet, "/api/roles", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("grant of a role includes its infra roles", func(t *testing.T) {
+		grantReq := api.GrantRequest{User: user.ID, Privilege: "developer", Resource: "infra"}
+		resp := do(t, http.MethodPost, "/api/grants", adminAccessKey(srv), grantReq)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		// the role includes view
+		resp = do(t, http.MethodGet, "/api/roles?name=developer", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var roles api.ListResponse[api.Role]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&roles))
+		assert.Equal(t, roles.Count, 1)
+		assert.Equal(t, roles.Items[0].ID, role.ID)
+
+		// but not admin
+		resp = do(t, http.MethodPost, "/api/roles", userKey, createReq)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, "/api/roles/"+role.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = do(t, http.MethodGet, "/api/roles/"+role.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+
+		// the grants of the role were removed
+		resp = do(t, http.MethodGet, "/api/roles", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
@@ -11,12 +11,15 @@ import (
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/metrics"
+	"github.com/infrahq/infra/uid"
 )
 
 // Routes is the return value of GenerateRoutes.
@@ -59,6 +62,14 @@ func (s *Server) GenerateRoutes() Routes {
 	get(a, authn, "/api/users/:id", a.GetUser)
 	put(a, authn, "/api/users/:id", a.UpdateUser)
 	del(a, authn, "/api/users/:id", a.DeleteUser)
+	get(a, authn, "/api/users/:id/permissions", a.ListUserPermissions)
+	post(a, authn, "/api/users/:id/totp", a.StartTOTPEnrollment)
+	put(a, authn, "/api/users/:id/totp", a.ConfirmTOTPEnrollment)
+	del(a, authn, "/api/users/:id/totp", a.DisableTOTP)
+	post(a, authn, "/api/users/:id/webauthn/registration", a.BeginWebAuthnRegistration)
+	put(a, authn, "/api/users/:id/webauthn/registration", a.FinishWebAuthnRegistration)
+	get(a, authn, "/api/users/:id/webauthn/credentials", a.ListWebAuthnCredentials)
+	del(a, authn, "/api/users/:id/webauthn/credentials/:credentialID", a.DeleteWebAuthnCredential)
 
 	get(a, authn, "/api/access-keys", a.ListAccessKeys)
 	post(a, authn, "/api/access-keys", a.CreateAccessKey)
@@ -70,6 +81,8 @@ func (s *Server) GenerateRoutes() Routes {
 	get(a, authn, "/api/groups/:id", a.GetGroup)
 	del(a, authn, "/api/groups/:id", a.DeleteGroup)
 	patch(a, authn, "/api/groups/:id/users", a.UpdateUsersInGroup)
+	get(a, authn, "/api/groups/:id/groups", a.ListNestedGroups)
+	patch(a, authn, "/api/groups/:id/groups", a.UpdateGroupsInGroup)
 
 	get(a, authn, "/api/organizations", a.ListOrganizations)
 	post(a, authn, "/api/organizations", a.CreateOrganization)
@@ -77,11 +90,47 @@ func (s *Server) GenerateRoutes() Routes {
 	del(a, authn, "/api/organizations/:id", a.DeleteOrganization)
 
 	get(a, authn, "/api/grants", a.ListGrants)
+	add(a, authn, http.MethodGet, "/api/grants/watch", route[api.WatchGrantsRequest, *api.GrantEvent]{
+		handler: a.WatchGrants,
+		routeSettings: routeSettings{
+			omitFromTelemetry: true,
+			txnOptions:        &sql.TxOptions{ReadOnly: true},
+		},
+	})
 	get(a, authn, "/api/grants/:id", a.GetGrant)
 	post(a, authn, "/api/grants", a.CreateGrant)
 	del(a, authn, "/api/grants/:id", a.DeleteGrant)
 	patch(a, authn, "/api/grants", a.UpdateGrants)
 
+	get(a, authn, "/api/roles", a.ListRoles)
+	get(a, authn, "/api/roles/:id", a.GetRole)
+	post(a, authn, "/api/roles", a.CreateRole)
+	del(a, authn, "/api/roles/:id", a.DeleteRole)
+
+	get(a, authn, "/api/audit-events", a.ListAuditEvents)
+
+	get(a, authn, "/api/webhooks", a.ListWebhooks)
+	get(a, authn, "/api/webhooks/:id", a.GetWebhook)
+	post(a, authn, "/api/webhooks", a.CreateWebhook)
+	put(a, authn, "/api/webhooks/:id", a.UpdateWebhook)
+	del(a, authn, "/api/webhooks/:id", a.DeleteWebhook)
+	get(a, authn, "/api/webhooks/:id/deliveries", a.ListWebhookDeliveries)
+
+	get(a, authn, "/api/access-requests", a.ListAccessRequests)
+	get(a, authn, "/api/access-requests/:id", a.GetAccessRequest)
+	post(a, authn, "/api/access-requests", a.CreateAccessRequest)
+	post(a, authn, "/api/access-requests/:id/approve", a.ApproveAccessRequest)
+	post(a, authn, "/api/access-requests/:id/deny", a.DenyAccessRequest)
+
+	get(a, authn, "/api/access-reviews", a.ListAccessReviews)
+	get(a, authn, "/api/access-reviews/:id", a.GetAccessReview)
+	post(a, authn, "/api/access-reviews", a.CreateAccessReview)
+	post(a, authn, "/api/access-reviews/:id/close", a.CloseAccessReview)
+	get(a, authn, "/api/access-reviews/:id/items", a.ListAccessReviewItems)
+	get(a, authn, "/api/access-reviews/:id/export", a.ExportAccessReview)
+	post(a, authn, "/api/access-review-items/:id/keep", a.KeepAccessReviewItem)
+	post(a, authn, "/api/access-review-items/:id/revoke", a.RevokeAccessReviewItem)
+
 	post(a, authn, "/api/providers", a.CreateProvider)
 	patch(a, authn, "/api/providers/:id", a.PatchProvider)
 	put(a, authn, "/api/providers/:id", a.UpdateProvider)
@@ -93,8 +142,21 @@ func (s *Server) GenerateRoutes() Routes {
 	put(a, authn, "/api/destinations/:id", a.UpdateDestination)
 	del(a, authn, "/api/destinations/:id", a.DeleteDestination)
 
-	post(a, authn, "/api/tokens", a.CreateToken)
-	post(a, authn, "/api/logout", a.Logout)
+	post(a, authn, "/api/ssh/certificates", a.CreateSSHCertificate)
+	// not read-only, the certificate authority is created the first time it is used
+	add(a, authn, http.MethodGet, "/api/ssh/certificate-authority", route[api.EmptyRequest, *api.SSHCertificateAuthority]{
+		handler:       a.GetSSHCertificateAuthority,
+		routeSettings: routeSettings{omitFromTelemetry: true},
+	})
+
+	add(a, authn, http.MethodPost, "/api/tokens", route[api.EmptyRequest, *api.CreateTokenResponse]{
+		handler:       a.CreateToken,
+		routeSettings: routeSettings{omitFromAudit: true},
+	})
+	add(a, authn, http.MethodPost, "/api/logout", route[api.EmptyRequest, *api.EmptyResponse]{
+		handler:       a.Logout,
+		routeSettings: routeSettings{omitFromAudit: true},
+	})
 
 	// SCIM inbound provisioning
 	add(a, authn, http.MethodGet, "/api/scim/v2/Users/:id", getProviderUsersRoute)
@@ -103,6 +165,12 @@ func (s *Server) GenerateRoutes() Routes {
 	add(a, authn, http.MethodPut, "/api/scim/v2/Users/:id", updateProviderUserRoute)
 	add(a, authn, http.MethodPatch, "/api/scim/v2/Users/:id", patchProviderUserRoute)
 	add(a, authn, http.MethodDelete, "/api/scim/v2/Users/:id", deleteProviderUserRoute)
+	add(a, authn, http.MethodGet, "/api/scim/v2/Groups/:id", getProviderGroupRoute)
+	add(a, authn, http.MethodGet, "/api/scim/v2/Groups", listProviderGroupsRoute)
+	add(a, authn, http.MethodPost, "/api/scim/v2/Groups", createProviderGroupRoute)
+	add(a, authn, http.MethodPut, "/api/scim/v2/Groups/:id", updateProviderGroupRoute)
+	add(a, authn, http.MethodPatch, "/api/scim/v2/Groups/:id", patchProviderGroupRoute)
+	add(a, authn, http.MethodDelete, "/api/scim/v2/Groups/:id", deleteProviderGroupRoute)
 
 	put(a, authn, "/api/settings", a.UpdateSettings)
 
@@ -115,6 +183,13 @@ func (s *Server) GenerateRoutes() Routes {
 	get(a, noAuthnNoOrg, "/api/server-configuration", a.GetServerConfiguration)
 	post(a, noAuthnNoOrg, "/api/forgot-domain-request", a.RequestForgotDomains)
 
+	// SCIM discovery
+	add(a, noAuthnNoOrg, http.MethodGet, "/api/scim/v2/ServiceProviderConfig", getSCIMServiceProviderConfigRoute)
+	add(a, noAuthnNoOrg, http.MethodGet, "/api/scim/v2/ResourceTypes", listSCIMResourceTypesRoute)
+	add(a, noAuthnNoOrg, http.MethodGet, "/api/scim/v2/ResourceTypes/:id", getSCIMResourceTypeRoute)
+	add(a, noAuthnNoOrg, http.MethodGet, "/api/scim/v2/Schemas", listSCIMSchemasRoute)
+	add(a, noAuthnNoOrg, http.MethodGet, "/api/scim/v2/Schemas/:id", getSCIMSchemaRoute)
+
 	// Device flow
 	post(a, noAuthnNoOrg, "/api/device", a.StartDeviceFlow)
 	post(a, noAuthnNoOrg, "/api/device/status", a.GetDeviceFlowStatus)
@@ -124,11 +199,15 @@ func (s *Server) GenerateRoutes() Routes {
 	noAuthnWithOrg := &routeGroup{RouterGroup: apiGroup.Group("/"), noAuthentication: true}
 
 	post(a, noAuthnWithOrg, "/api/login", a.Login)
+	post(a, noAuthnWithOrg, "/api/login/webauthn", a.BeginWebAuthnLogin)
 	post(a, noAuthnWithOrg, "/api/password-reset-request", a.RequestPasswordReset)
 	post(a, noAuthnWithOrg, "/api/password-reset", a.VerifiedPasswordReset)
 
 	get(a, noAuthnWithOrg, "/api/providers/:id", a.GetProvider)
 	get(a, noAuthnWithOrg, "/api/providers", a.ListProviders)
+	add(a, noAuthnWithOrg, http.MethodGet, "/api/providers/:id/saml/metadata", a.SAMLMetadataRoute())
+	add(a, noAuthnWithOrg, http.MethodGet, "/api/providers/:id/saml/login", a.SAMLLoginRoute())
+	add(a, noAuthnWithOrg, http.MethodPost, "/api/providers/:id/saml/acs", a.SAMLAssertionConsumerServiceRoute())
 	get(a, noAuthnWithOrg, "/api/settings", a.GetSettings)
 	add(a, noAuthnWithOrg, http.MethodGet, "/link", verifyAndRedirectRoute)
 
@@ -155,6 +234,7 @@ type route[Req, Res any] struct {
 type routeSettings struct {
 	omitFromDocs               bool
 	omitFromTelemetry          bool
+	omitFromAudit              bool
 	infraVersionHeaderOptional bool
 	authenticationOptional     bool
 	organizationOptional       bool
@@ -200,9 +280,11 @@ func add[Req, Res any](a *API, group *routeGroup, method, urlPath string, route
 // (similar to middleware).
 // The returned function handles validation of the infra version header, manages
 // a request scoped database transaction, authenticates the request, reads the
-// request fields into a request struct, and returns an HTTP response with a
+// request fields into a request struct, records an audit event for requests
+// that change state, and returns an HTTP response with a
 // status code and response body built from the response type.
 func wrapRoute[Req, Res any](a *API, routeID routeIdentifier, route route[Req, Res]) func(*gin.Context) error {
+	action := getFuncName(route.handler)
 	return func(c *gin.Context) error {
 		origRequestContext := c.Request.Context()
 		ctx, cancel := context.WithTimeout(origRequestContext, a.server.options.API.RequestTimeout)
@@ -241,11 +323,15 @@ func wrapRoute[Req, Res any](a *API, routeID routeIdentifier, route route[Req, R
 		if org := authned.Organization; org != nil {
 			tx = tx.WithOrgID(org.ID)
 		}
+		if user := authned.User; user != nil {
+			tx = tx.WithActor(user.ID, user.Name)
+		}
 		rCtx := access.RequestContext{
 			Request:       c.Request,
 			DBTxn:         tx,
 			Authenticated: authned,
 			DataDB:        a.server.db,
+			RequestID:     uid.New(),
 			Response:      &access.ResponseMetadata{},
 		}
 		c.Set(access.RequestContextKey, rCtx)
@@ -255,6 +341,12 @@ func wrapRoute[Req, Res any](a *API, routeID routeIdentifier, route route[Req, R
 			return err
 		}
 
+		if shouldAudit(routeID, route.routeSettings, authned) {
+			if err := recordAuditEvent(c, rCtx, routeID, action, req, resp); err != nil {
+				return err
+			}
+		}
+
 		completeTx := tx.Commit
 		if route.txnOptions != nil && route.txnOptions.ReadOnly {
 			// use rollback to avoid an error when the request handler already completed the txn
@@ -268,13 +360,21 @@ func wrapRoute[Req, Res any](a *API, routeID routeIdentifier, route route[Req, R
 			a.t.RouteEvent(c, routeID.path, Properties{"method": strings.ToLower(routeID.method)})
 		}
 
+		if c.Writer.Written() {
+			// the handler already wrote the response, for example a stream of events
+			return nil
+		}
+
 		// TODO: extract all response header/status/body writing to another function
 		if respHeaders, ok := any(resp).(hasResponseHeaders); ok {
 			respHeaders.SetHeaders(c.Writer.Header())
 		}
-		if r, ok := any(resp).(isRedirect); ok {
-			c.Redirect(http.StatusPermanentRedirect, r.RedirectURL())
-		} else {
+		switch r := any(resp).(type) {
+		case isRedirect:
+			c.Redirect(redirectStatusCode(resp), r.RedirectURL())
+		case hasRawBody:
+			c.Data(responseStatusCode(routeID.method, resp), r.ContentType(), r.RawBody())
+		default:
 			c.JSON(responseStatusCode(routeID.method, resp), resp)
 		}
 		return nil
@@ -293,6 +393,19 @@ type statusCoder interface {
 	StatusCode() int
 }
 
+// hasRawBody is implemented by responses that are written as is, instead of
+// being encoded as JSON.
+type hasRawBody interface {
+	ContentType() string
+	RawBody() []byte
+}
+
+// isFormRequest is implemented by requests that read the request body from an
+// HTML form, instead of JSON.
+type isFormRequest interface {
+	IsFormRequest() bool
+}
+
 type isBlockingRequest interface {
 	IsBlockingRequest() bool
 }
@@ -313,6 +426,15 @@ func trimWhitespace(req interface{}) {
 	}
 }
 
+func redirectStatusCode(resp any) int {
+	if c, ok := resp.(statusCoder); ok {
+		if code := c.StatusCode(); code != 0 {
+			return code
+		}
+	}
+	return http.StatusPermanentRedirect
+}
+
 func responseStatusCode(method string, resp any) int {
 	if c, ok := resp.(statusCoder); ok {
 		if code := c.StatusCode(); code != 0 {
@@ -365,7 +487,11 @@ func readRequest(c *gin.Context, req interface{}) error {
 	}
 
 	if c.Request.Body != nil && c.Request.ContentLength > 0 {
-		if err := c.ShouldBindJSON(req); err != nil {
+		bind := binding.Binding(binding.JSON)
+		if r, ok := req.(isFormRequest); ok && r.IsFormRequest() {
+			bind = binding.FormPost
+		}
+		if err := c.ShouldBindWith(req, bind); err != nil {
 			return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
 		}
 	}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// samlRelayStateMaxLength is the maximum length of the RelayState allowed by
+// the SAML bindings specification.
+const samlRelayStateMaxLength = 80
+
+// samlRequestExpiry is the time allowed for a user to login at the identity
+// provider, after the login is started.
+const samlRequestExpiry = 15 * time.Minute
+
+func (a *API) SAMLMetadataRoute() route[api.Resource, *samlMetadataResponse] {
+	return route[api.Resource, *samlMetadataResponse]{
+		handler: a.SAMLMetadata,
+		routeSettings: routeSettings{
+			omitFromDocs:               true,
+			omitFromTelemetry:          true,
+			infraVersionHeaderOptional: true,
+			txnOptions:                 &sql.TxOptions{ReadOnly: true},
+		},
+	}
+}
+
+func (a *API) SAMLLoginRoute() route[samlLoginRequest, *samlRedirectResponse] {
+	return route[samlLoginRequest, *samlRedirectResponse]{
+		handler: a.SAMLLogin,
+		routeSettings: routeSettings{
+			omitFromDocs:               true,
+			omitFromTelemetry:          true,
+			infraVersionHeaderOptional: true,
+		},
+	}
+}
+
+func (a *API) SAMLAssertionConsumerServiceRoute() route[samlACSRequest, *samlACSResponse] {
+	return route[samlACSRequest, *samlACSResponse]{
+		handler: a.SAMLAssertionConsumerService,
+		routeSettings: routeSettings{
+			omitFromDocs:               true,
+			omitFromTelemetry:          true,
+			infraVersionHeaderOptional: true,
+		},
+	}
+}
+
+type samlMetadataResponse struct {
+	metadata []byte
+}
+
+func (r samlMetadataResponse) ContentType() string {
+	return "application/samlmetadata+xml"
+}
+
+func (r samlMetadataResponse) RawBody() []byte {
+	return r.metadata
+}
+
+// SAMLMetadata returns the metadata of the service provider, which is used to
+// configure the SAML identity provider.
+func (a *API) SAMLMetadata(c *gin.Context, r *api.Resource) (*samlMetadataResponse, error) {
+	provider, err := getSAMLProvider(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := samlServiceProvider(c, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := sp.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	return &samlMetadataResponse{metadata: metadata}, nil
+}
+
+type samlLoginRequest struct {
+	api.Resource
+	// Redirect is the path to redirect to after login.
+	Redirect string `form:"redirect"`
+}
+
+type samlRedirectResponse struct {
+	RedirectTo string `json:"-"`
+}
+
+func (r samlRedirectResponse) RedirectURL() string {
+	return r.RedirectTo
+}
+
+func (r samlRedirectResponse) StatusCode() int {
+	// the redirect is a new request for every login, so it must not be cached
+	return http.StatusFound
+}
+
+// SAMLLogin redirects the user to the identity provider to login.
+func (a *API) SAMLLogin(c *gin.Context, r *samlLoginRequest) (*samlRedirectResponse, error) {
+	provider, err := getSAMLProvider(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	idp, err := providers.ParseSAMLMetadata([]byte(provider.SAMLMetadata))
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := samlServiceProvider(c, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	redirectURL, requestID, err := sp.AuthnRequestURL(idp, safeRedirectPath(r.Redirect), now)
+	if err != nil {
+		return nil, err
+	}
+
+	rCtx := getRequestContext(c)
+	if err := data.CreateSAMLRequest(rCtx.DBTxn, provider.ID, requestID, now.Add(samlRequestExpiry)); err != nil {
+		return nil, err
+	}
+	return &samlRedirectResponse{RedirectTo: redirectURL}, nil
+}
+
+type samlACSRequest struct {
+	api.Resource
+	SAMLResponse string `form:"SAMLResponse"`
+	RelayState   string `form:"RelayState"`
+}
+
+func (r samlACSRequest) ValidationRules() []validate.ValidationRule {
+	return append(r.Resource.ValidationRules(),
+		validate.Required("SAMLResponse", r.SAMLResponse))
+}
+
+// The identity provider sends the response from the browser of the user,
+// using an HTML form.
+func (r samlACSRequest) IsFormRequest() bool {
+	return true
+}
+
+type samlACSResponse struct {
+	redirectTo string
+}
+
+func (r samlACSResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r samlACSResponse) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+// RawBody returns a page that navigates to the redirect path. A redirect
+// response can not be used, because the session cookie is not sent when the
+// redirect follows a POST from the identity provider.
+func (r samlACSResponse) RawBody() []byte {
+	target := html.EscapeString(r.redirectTo)
+	return []byte(`<!DOCTYPE html>
+<html><head><meta http-equiv="refresh" content="0;url=` + target + `"></head>
+<body><a href="` + target + `">Continue</a></body></html>
+`)
+}
+
+// SAMLAssertionConsumerService logs in the user with the SAML response sent by
+// the identity provider.
+func (a *API) SAMLAssertionConsumerService(c *gin.Context, r *samlACSRequest) (*samlACSResponse, error) {
+	rCtx := getRequestContext(c)
+
+	provider, err := getSAMLProvider(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := samlServiceProvider(c, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	loginMethod, err := authn.NewSAMLAuthentication(provider, sp, r.SAMLResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	expires := time.Now().UTC().Add(a.server.options.SessionDuration)
+	result, err := authn.Login(rCtx.Request.Context(), rCtx.DBTxn, loginMethod, expires, a.server.options.SessionInactivityTimeout)
+	if err != nil {
+		if errors.Is(err, internal.ErrBadGateway) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: login failed: %v", internal.ErrUnauthorized, err)
+	}
+
+	a.startLoginSession(c, result, loginMethod.Name())
+	return &samlACSResponse{redirectTo: safeRedirectPath(r.RelayState)}, nil
+}
+
+func getSAMLProvider(c *gin.Context, id uid.ID) (*models.Provider, error) {
+	provider, err := access.GetProvider(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Kind != models.ProviderKindSAML {
+		return nil, fmt.Errorf("%w: provider %v is not a saml provider", internal.ErrNotFound, provider.Name)
+	}
+	return provider, nil
+}
+
+// samlServiceProvider returns the service provider used with a SAML identity
+// provider. The URLs use the configured domain of the organization, and not
+// the Host header, which is controlled by the client. Identity providers
+// require https, so the URLs always use https, even when TLS is terminated
+// before the request reaches the server.
+func samlServiceProvider(c *gin.Context, providerID uid.ID) (providers.SAMLServiceProvider, error) {
+	var host string
+	if org := getRequestContext(c).Authenticated.Organization; org != nil {
+		host = org.Domain
+	}
+	if host == "" {
+		return providers.SAMLServiceProvider{}, fmt.Errorf("%w: saml is not configured, the organization must have a domain", internal.ErrBadRequest)
+	}
+
+	base := "https://" + host + "/api/providers/" + providerID.String() + "/saml"
+	return providers.SAMLServiceProvider{
+		EntityID: base + "/metadata",
+		ACSURL:   base + "/acs",
+	}, nil
+}
+
+// safeRedirectPath returns path if it is a path on this server, and / if it
+// is not, so that login can not be used to redirect to another site.
+func safeRedirectPath(path string) string {
+	switch {
+	case !strings.HasPrefix(path, "/"),
+		strings.HasPrefix(path, "//"),
+		strings.HasPrefix(path, `/\`),
+		// browsers remove some control characters from URLs, which could
+		// turn the path into a URL of another site
+		strings.IndexFunc(path, unicode.IsControl) >= 0,
+		len(path) > samlRelayStateMaxLength:
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/saml"
+)
+
+func TestAPI_CreateProvider_SAML(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+	idp := saml.NewIdP(t)
+
+	body := jsonBody(t, api.CreateProviderRequest{
+		Name: "corp",
+		Kind: "saml",
+		SAML: &api.ProviderSAML{
+			Metadata:        idp.Metadata(),
+			GroupsAttribute: "groups",
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/providers", body)
+	req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+	req.Header.Set("Infra-Version", apiVersionLatest)
+
+	resp := httptest.NewRecorder()
+	routes.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var provider api.Provider
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &provider))
+	assert.Equal(t, provider.Kind, "saml")
+	assert.Equal(t, provider.URL, "idp.example.com")
+	assert.Equal(t, provider.AuthURL, idp.SSOURL)
+	assert.DeepEqual(t, provider.SAML, &api.ProviderSAML{GroupsAttribute: "groups"})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		body := jsonBody(t, api.CreateProviderRequest{
+			Name: "invalid",
+			Kind: "saml",
+			SAML: &api.ProviderSAML{Metadata: "<md:EntityDescriptor/>"},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/providers", body)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+}
+
+func TestAPI_SAML(t *testing.T) {
+	srv := setupServer(t, func(_ *testing.T, opts *Options) {
+		opts.DefaultOrganizationDomain = "example.com"
+	})
+	routes := srv.GenerateRoutes()
+	idp := saml.NewIdP(t)
+
+	provider := &models.Provider{
+		Name:                "corp",
+		Kind:                models.ProviderKindSAML,
+		SAMLMetadata:        idp.Metadata(),
+		SAMLGroupsAttribute: "groups",
+	}
+	assert.NilError(t, data.CreateProvider(srv.DB(), provider))
+	oidcProvider := &models.Provider{Name: "oidc", Kind: models.ProviderKindOIDC}
+	assert.NilError(t, data.CreateProvider(srv.DB(), oidcProvider))
+
+	base := "/api/providers/" + provider.ID.String() + "/saml"
+	entityID := "https://example.com" + base + "/metadata"
+	acsURL := "https://example.com" + base + "/acs"
+
+	t.Run("metadata", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, base+"/metadata", nil)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, resp.Header().Get("Content-Type"), "application/samlmetadata+xml")
+		assert.Assert(t, is.Contains(resp.Body.String(), `entityID="`+entityID+`"`))
+		assert.Assert(t, is.Contains(resp.Body.String(), `Location="`+acsURL+`"`))
+	})
+
+	t.Run("metadata uses the organization domain", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, base+"/metadata", nil)
+		req.Host = "evil.example.org"
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, is.Contains(resp.Body.String(), `entityID="`+entityID+`"`))
+	})
+
+	t.Run("metadata of an oidc provider", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/providers/"+oidcProvider.ID.String()+"/saml/metadata", nil)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+
+	// login starts a login and returns the ID of the AuthnRequest
+	login := func(t *testing.T) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, base+"/login?redirect=/destinations", nil)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+		location, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Host, "idp.example.com")
+		assert.Equal(t, location.Query().Get("tenant"), "1")
+		assert.Equal(t, location.Query().Get("RelayState"), "/destinations")
+
+		compressed, err := base64.StdEncoding.DecodeString(location.Query().Get("SAMLRequest"))
+		assert.NilError(t, err)
+		decoded, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+		assert.NilError(t, err)
+
+		var request struct {
+			ID     string `xml:"ID,attr"`
+			Issuer string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+		}
+		assert.NilError(t, xml.Unmarshal(decoded, &request))
+		assert.Equal(t, request.Issuer, entityID)
+		assert.Assert(t, request.ID != "")
+		return request.ID
+	}
+
+	t.Run("login", func(t *testing.T) {
+		login(t)
+	})
+
+	acs := func(t *testing.T, requestID string, assertionID string, relayState string) *httptest.ResponseRecorder {
+		t.Helper()
+		response := idp.Response(t, saml.Assertion{
+			ID:            assertionID,
+			NameID:        "ada@example.com",
+			Audience:      entityID,
+			Recipient:     acsURL,
+			Destination:   acsURL,
+			InResponseTo:  requestID,
+			Attributes:    []saml.Attribute{{Name: "groups", Values: []string{"developers"}}},
+			SignResponse:  true,
+			SignAssertion: true,
+		})
+		form := url.Values{
+			"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte(response))},
+			"RelayState":   {relayState},
+		}
+		req := httptest.NewRequest(http.MethodPost, base+"/acs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("assertion consumer service", func(t *testing.T) {
+		resp := acs(t, login(t), "_first", "/destinations")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, is.Contains(resp.Body.String(), `content="0;url=/destinations"`))
+
+		var cookie *http.Cookie
+		for _, c := range resp.Result().Cookies() {
+			if c.Name == cookieAuthorizationName {
+				cookie = c
+			}
+		}
+		assert.Assert(t, cookie != nil)
+		assert.Assert(t, cookie.Value != "")
+
+		user, err := data.GetIdentity(srv.DB(), data.GetIdentityOptions{ByName: "ada@example.com", LoadGroups: true})
+		assert.NilError(t, err)
+		assert.Equal(t, len(user.Groups), 1)
+		assert.Equal(t, user.Groups[0].Name, "developers")
+	})
+
+	t.Run("relay state to another site", func(t *testing.T) {
+		resp := acs(t, login(t), "_second", "//evil.example.com")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, is.Contains(resp.Body.String(), `content="0;url=/"`))
+	})
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		resp := acs(t, login(t), "_first", "/")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("request was not issued by the server", func(t *testing.T) {
+		resp := acs(t, "_unknown", "_third", "/")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("request was already used", func(t *testing.T) {
+		requestID := login(t)
+		resp := acs(t, requestID, "_fourth", "/")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = acs(t, requestID, "_fifth", "/")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+}
+
+func TestAPI_SAML_NotConfigured(t *testing.T) {
+	srv := setupServer(t)
+	routes := srv.GenerateRoutes()
+	idp := saml.NewIdP(t)
+
+	provider := &models.Provider{Name: "corp", Kind: models.ProviderKindSAML, SAMLMetadata: idp.Metadata()}
+	assert.NilError(t, data.CreateProvider(srv.DB(), provider))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/providers/"+provider.ID.String()+"/saml/metadata", nil)
+	resp := httptest.NewRecorder()
+	routes.ServeHTTP(resp, req)
+
+	assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	assert.Assert(t, is.Contains(resp.Body.String(), "the organization must have a domain"))
+}
+
+func TestSafeRedirectPath(t *testing.T) {
+	testCases := map[string]string{
+		"":                            "/",
+		"/":                           "/",
+		"/destinations?page=2":        "/destinations?page=2",
+		"https://example.com/":        "/",
+		"//example.com":               "/",
+		`/\example.com`:               "/",
+		"/\t/example.com":             "/",
+		"/" + strings.Repeat("a", 80): "/",
+	}
+	for input, expected := range testCases {
+		assert.Equal(t, safeRedirectPath(input), expected, "input %q", input)
+	}
+}
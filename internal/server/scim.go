@@ -1,16 +1,20 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/scim2/filter-parser/v2"
+	"github.com/ssoroka/slice"
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 var getProviderUsersRoute = route[api.Resource, *api.SCIMUser]{
@@ -67,6 +71,60 @@ var deleteProviderUserRoute = route[api.Resource, *api.EmptyResponse]{
 	},
 }
 
+var getProviderGroupRoute = route[api.Resource, *api.SCIMGroup]{
+	handler: GetProviderGroup,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var listProviderGroupsRoute = route[api.SCIMParametersRequest, *api.ListProviderGroupsResponse]{
+	handler: ListProviderGroups,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var createProviderGroupRoute = route[api.SCIMGroupCreateRequest, *api.SCIMGroup]{
+	handler: CreateProviderGroup,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var updateProviderGroupRoute = route[api.SCIMGroupUpdateRequest, *api.SCIMGroup]{
+	handler: UpdateProviderGroup,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var patchProviderGroupRoute = route[api.SCIMGroupPatchRequest, *api.SCIMGroup]{
+	handler: PatchProviderGroup,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var deleteProviderGroupRoute = route[api.Resource, *api.EmptyResponse]{
+	handler: DeleteProviderGroup,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
 func GetProviderUser(c *gin.Context, r *api.Resource) (*api.SCIMUser, error) {
 	user, err := access.GetProviderUser(c, r.ID)
 	if err != nil {
@@ -76,16 +134,9 @@ func GetProviderUser(c *gin.Context, r *api.Resource) (*api.SCIMUser, error) {
 }
 
 func ListProviderUsers(c *gin.Context, r *api.SCIMParametersRequest) (*api.ListProviderUsersResponse, error) {
-	p := data.SCIMParameters{
-		StartIndex: r.StartIndex,
-		Count:      r.Count,
-	}
-	if r.Filter != "" {
-		exp, err := filter.ParseFilter([]byte(r.Filter))
-		if err != nil {
-			return nil, fmt.Errorf("parse SCIM filter expression: %w", err)
-		}
-		p.Filter = exp
+	p, err := scimParametersFromRequest(r)
+	if err != nil {
+		return nil, err
 	}
 	users, err := access.ListProviderUsers(c, &p)
 	if err != nil {
@@ -163,6 +214,227 @@ func PatchProviderUser(c *gin.Context, r *api.SCIMUserPatchRequest) (*api.SCIMUs
 	return result.ToAPI(), nil
 }
 
+// scimMaxResults is the maximum number of resources returned by a SCIM list
+// request.
+const scimMaxResults = 1000
+
+func scimParametersFromRequest(r *api.SCIMParametersRequest) (data.SCIMParameters, error) {
+	p := data.SCIMParameters{
+		StartIndex: r.StartIndex,
+		Count:      r.Count,
+		SortBy:     r.SortBy,
+		SortOrder:  r.SortOrder,
+	}
+	if p.Count > scimMaxResults {
+		p.Count = scimMaxResults
+	}
+	if r.Filter != "" {
+		exp, err := filter.ParseFilter([]byte(r.Filter))
+		if err != nil {
+			return p, fmt.Errorf("%w: parse SCIM filter expression: %v", internal.ErrBadRequest, err)
+		}
+		p.Filter = exp
+	}
+	return p, nil
+}
+
 func DeleteProviderUser(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
 	return nil, access.DeleteProviderUser(c, r.ID)
 }
+
+func GetProviderGroup(c *gin.Context, r *api.Resource) (*api.SCIMGroup, error) {
+	group, err := access.GetProviderGroup(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	return providerGroupToAPI(c, group)
+}
+
+func ListProviderGroups(c *gin.Context, r *api.SCIMParametersRequest) (*api.ListProviderGroupsResponse, error) {
+	p, err := scimParametersFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := access.ListProviderGroups(c, &p)
+	if err != nil {
+		return nil, err
+	}
+	result := &api.ListProviderGroupsResponse{
+		Schemas:      []string{api.ListResponseSchema},
+		TotalResults: p.TotalCount,
+		StartIndex:   p.StartIndex,
+		ItemsPerPage: p.Count,
+	}
+	for i := range groups {
+		group, err := providerGroupToAPI(c, &groups[i])
+		if err != nil {
+			return nil, err
+		}
+		result.Resources = append(result.Resources, *group)
+	}
+	return result, nil
+}
+
+func CreateProviderGroup(c *gin.Context, r *api.SCIMGroupCreateRequest) (*api.SCIMGroup, error) {
+	memberIDs, err := scimMemberIDs(r.Members)
+	if err != nil {
+		return nil, err
+	}
+	group := &models.Group{Name: r.DisplayName}
+	if err := access.CreateProviderGroup(c, group, memberIDs); err != nil {
+		return nil, err
+	}
+	return providerGroupToAPI(c, group)
+}
+
+func UpdateProviderGroup(c *gin.Context, r *api.SCIMGroupUpdateRequest) (*api.SCIMGroup, error) {
+	memberIDs, err := scimMemberIDs(r.Members)
+	if err != nil {
+		return nil, err
+	}
+	group := &models.Group{Model: models.Model{ID: r.ID}, Name: r.DisplayName}
+	if err := access.UpdateProviderGroup(c, group, memberIDs); err != nil {
+		return nil, err
+	}
+	return providerGroupToAPI(c, group)
+}
+
+func PatchProviderGroup(c *gin.Context, r *api.SCIMGroupPatchRequest) (*api.SCIMGroup, error) {
+	patch := &models.Group{Model: models.Model{ID: r.ID}}
+	var addIDs, removeIDs []uid.ID
+	for _, op := range r.Operations {
+		name, add, remove, err := parseGroupPatchOperation(c, r.ID, op)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			patch.Name = name
+		}
+		addIDs = append(addIDs, add...)
+		removeIDs = append(removeIDs, remove...)
+	}
+
+	group, err := access.PatchProviderGroup(c, patch, addIDs, removeIDs)
+	if err != nil {
+		return nil, err
+	}
+	return providerGroupToAPI(c, group)
+}
+
+func DeleteProviderGroup(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteProviderGroup(c, r.ID)
+}
+
+// parseGroupPatchOperation returns the new name of the group, and the members
+// to add and remove from the group for a single PATCH operation. Identity
+// providers use different forms of the operations, for example Okta replaces
+// the whole group when renaming it, and Azure AD removes members with a value
+// instead of a path filter.
+func parseGroupPatchOperation(c *gin.Context, groupID uid.ID, op api.SCIMGroupPatchOperation) (name string, add, remove []uid.ID, err error) {
+	var path filter.Path
+	if op.Path != "" {
+		path, err = filter.ParsePath([]byte(op.Path))
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("%w: invalid path %q: %v", internal.ErrBadRequest, op.Path, err)
+		}
+	}
+
+	var value struct {
+		DisplayName string                `json:"displayName"`
+		Members     []api.SCIMGroupMember `json:"members"`
+	}
+	attribute := path.AttributePath.AttributeName
+	switch attribute {
+	case "":
+		err = decodePatchValue(op.Value, &value)
+	case "displayName":
+		err = decodePatchValue(op.Value, &value.DisplayName)
+	case "members":
+		if len(op.Value) > 0 {
+			err = decodePatchValue(op.Value, &value.Members)
+		}
+	default:
+		return "", nil, nil, fmt.Errorf("%w: unsupported path %q", internal.ErrBadRequest, op.Path)
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if expr, ok := path.ValueExpression.(*filter.AttributeExpression); ok && attribute == "members" {
+		// members[value eq "ID"]
+		member, ok := expr.CompareValue.(string)
+		if expr.AttributePath.String() != "value" || expr.Operator != filter.EQ || !ok {
+			return "", nil, nil, fmt.Errorf("%w: unsupported path %q", internal.ErrBadRequest, op.Path)
+		}
+		value.Members = append(value.Members, api.SCIMGroupMember{Value: member})
+	}
+
+	members, err := scimMemberIDs(value.Members)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	switch strings.ToLower(op.Op) {
+	case "add":
+		return value.DisplayName, members, nil, nil
+	case "remove":
+		if attribute == "members" && len(members) == 0 {
+			// no value or filter removes all the members
+			members, err = providerGroupMemberIDs(c, groupID)
+			if err != nil {
+				return "", nil, nil, err
+			}
+		}
+		return "", nil, members, nil
+	case "replace":
+		if attribute == "displayName" || (attribute == "" && value.Members == nil) {
+			return value.DisplayName, nil, nil, nil
+		}
+		current, err := providerGroupMemberIDs(c, groupID)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return value.DisplayName, slice.Subtract(members, current), slice.Subtract(current, members), nil
+	default:
+		return "", nil, nil, fmt.Errorf("%w: unsupported operation %q", internal.ErrBadRequest, op.Op)
+	}
+}
+
+func decodePatchValue(raw json.RawMessage, target any) error {
+	if err := json.Unmarshal(raw, target); err != nil {
+		return fmt.Errorf("%w: invalid operation value: %v", internal.ErrBadRequest, err)
+	}
+	return nil
+}
+
+func scimMemberIDs(members []api.SCIMGroupMember) ([]uid.ID, error) {
+	ids := make([]uid.ID, 0, len(members))
+	for _, member := range members {
+		id, err := uid.Parse([]byte(member.Value))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid member %q", internal.ErrBadRequest, member.Value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func providerGroupMemberIDs(c *gin.Context, groupID uid.ID) ([]uid.ID, error) {
+	members, err := access.ListProviderGroupMembers(c, groupID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uid.ID, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.ID)
+	}
+	return ids, nil
+}
+
+func providerGroupToAPI(c *gin.Context, group *models.Group) (*api.SCIMGroup, error) {
+	members, err := access.ListProviderGroupMembers(c, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	return group.ToSCIM(members), nil
+}
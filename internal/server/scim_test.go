@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/assert/opt"
 
@@ -898,3 +899,271 @@ func createTestSCIMUserIdentity(t *testing.T, db data.WriteTxn, provider *models
 
 	return testProviderUser
 }
+
+func TestAPI_ProviderGroups(t *testing.T) {
+	s := setupServer(t, withAdminUser)
+	bearer, users, routes := createTestSCIMProvider(t, s, "david@example.com", "erin@example.com")
+
+	do := func(t *testing.T, method, path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		// nolint:noctx
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Add("Authorization", "Bearer "+bearer)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(t *testing.T, resp *httptest.ResponseRecorder) api.SCIMGroup {
+		t.Helper()
+		var group api.SCIMGroup
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &group))
+		return group
+	}
+	memberIDs := func(group api.SCIMGroup) []string {
+		var ids []string
+		for _, m := range group.Members {
+			ids = append(ids, m.Value)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	sortedIDs := func(users ...models.ProviderUser) []string {
+		var ids []string
+		for _, u := range users {
+			ids = append(ids, u.IdentityID.String())
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	var created api.SCIMGroup
+	t.Run("create", func(t *testing.T) {
+		body := api.SCIMGroupCreateRequest{
+			Schemas:     []string{api.GroupSchema},
+			DisplayName: "Engineering",
+			Members:     []api.SCIMGroupMember{{Value: users[0].IdentityID.String()}},
+		}
+		resp := do(t, http.MethodPost, "/api/scim/v2/Groups", body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		created = decode(t, resp)
+		assert.Equal(t, created.DisplayName, "Engineering")
+		assert.DeepEqual(t, memberIDs(created), sortedIDs(users[0]))
+
+		id, err := uid.Parse([]byte(created.ID))
+		assert.NilError(t, err)
+		group, err := data.GetGroup(s.DB(), data.GetGroupOptions{ByID: id})
+		assert.NilError(t, err)
+		assert.Equal(t, group.CreatedByProvider, uid.ID(1234))
+	})
+
+	t.Run("create with unknown member", func(t *testing.T) {
+		body := api.SCIMGroupCreateRequest{
+			Schemas:     []string{api.GroupSchema},
+			DisplayName: "Product",
+			Members:     []api.SCIMGroupMember{{Value: uid.New().String()}},
+		}
+		resp := do(t, http.MethodPost, "/api/scim/v2/Groups", body)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("get", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/scim/v2/Groups/"+created.ID, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.DeepEqual(t, decode(t, resp), created)
+	})
+
+	t.Run("list with filter", func(t *testing.T) {
+		resp := do(t, http.MethodGet, `/api/scim/v2/Groups?filter=displayName%20eq%20%22Engineering%22`, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListProviderGroupsResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+		assert.Equal(t, list.TotalResults, 1)
+		assert.DeepEqual(t, list.Resources, []api.SCIMGroup{created})
+	})
+
+	t.Run("list sorted", func(t *testing.T) {
+		body := api.SCIMGroupCreateRequest{Schemas: []string{api.GroupSchema}, DisplayName: "Design"}
+		resp := do(t, http.MethodPost, "/api/scim/v2/Groups", body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		design := decode(t, resp)
+
+		resp = do(t, http.MethodGet, "/api/scim/v2/Groups?sortBy=displayName&sortOrder=descending", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListProviderGroupsResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+		assert.DeepEqual(t, list.Resources, []api.SCIMGroup{created, design})
+
+		resp = do(t, http.MethodDelete, "/api/scim/v2/Groups/"+design.ID, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+	})
+
+	t.Run("list with invalid filter", func(t *testing.T) {
+		resp := do(t, http.MethodGet, `/api/scim/v2/Groups?filter=password%20eq%20%22x%22`, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("patch add and remove members", func(t *testing.T) {
+		body := map[string]any{
+			"schemas": []string{api.PatchOperationSchema},
+			"Operations": []map[string]any{
+				{"op": "add", "path": "members", "value": []map[string]any{
+					{"value": users[1].IdentityID.String()},
+					{"value": users[2].IdentityID.String()},
+				}},
+				{"op": "remove", "path": `members[value eq "` + users[0].IdentityID.String() + `"]`},
+			},
+		}
+		resp := do(t, http.MethodPatch, "/api/scim/v2/Groups/"+created.ID, body)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.DeepEqual(t, memberIDs(decode(t, resp)), sortedIDs(users[1], users[2]))
+	})
+
+	t.Run("patch rename", func(t *testing.T) {
+		body := map[string]any{
+			"schemas": []string{api.PatchOperationSchema},
+			"Operations": []map[string]any{
+				{"op": "replace", "value": map[string]any{"id": created.ID, "displayName": "Platform"}},
+			},
+		}
+		resp := do(t, http.MethodPatch, "/api/scim/v2/Groups/"+created.ID, body)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		group := decode(t, resp)
+		assert.Equal(t, group.DisplayName, "Platform")
+		assert.DeepEqual(t, memberIDs(group), sortedIDs(users[1], users[2]))
+	})
+
+	t.Run("put replaces members", func(t *testing.T) {
+		body := api.SCIMGroupUpdateRequest{
+			Schemas:     []string{api.GroupSchema},
+			DisplayName: "Platform",
+			Members:     []api.SCIMGroupMember{{Value: users[0].IdentityID.String()}},
+		}
+		resp := do(t, http.MethodPut, "/api/scim/v2/Groups/"+created.ID, body)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.DeepEqual(t, memberIDs(decode(t, resp)), sortedIDs(users[0]))
+	})
+
+	t.Run("groups not created by the provider are not found", func(t *testing.T) {
+		other := &models.Group{Name: "Everyone"}
+		assert.NilError(t, data.CreateGroup(s.DB(), other))
+
+		resp := do(t, http.MethodGet, "/api/scim/v2/Groups/"+other.ID.String(), nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+
+		resp = do(t, http.MethodDelete, "/api/scim/v2/Groups/"+other.ID.String(), nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, "/api/scim/v2/Groups/"+created.ID, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = do(t, http.MethodGet, "/api/scim/v2/Groups/"+created.ID, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+}
+
+func TestParseGroupPatchOperation(t *testing.T) {
+	first, second := uid.ID(1001), uid.ID(1002)
+
+	type testCase struct {
+		name           string
+		op             api.SCIMGroupPatchOperation
+		expectedName   string
+		expectedAdd    []uid.ID
+		expectedRemove []uid.ID
+		expectedErr    string
+	}
+
+	testCases := []testCase{
+		{
+			name: "add members",
+			op: api.SCIMGroupPatchOperation{
+				Op:    "add",
+				Path:  "members",
+				Value: json.RawMessage(`[{"value":"` + first.String() + `"},{"value":"` + second.String() + `"}]`),
+			},
+			expectedAdd: []uid.ID{first, second},
+		},
+		{
+			name: "remove member by filter",
+			op: api.SCIMGroupPatchOperation{
+				Op:   "Remove",
+				Path: `members[value eq "` + first.String() + `"]`,
+			},
+			expectedRemove: []uid.ID{first},
+		},
+		{
+			name: "remove members by value",
+			op: api.SCIMGroupPatchOperation{
+				Op:    "remove",
+				Path:  "members",
+				Value: json.RawMessage(`[{"value":"` + second.String() + `"}]`),
+			},
+			expectedRemove: []uid.ID{second},
+		},
+		{
+			name: "replace display name with path",
+			op: api.SCIMGroupPatchOperation{
+				Op:    "replace",
+				Path:  "displayName",
+				Value: json.RawMessage(`"Platform"`),
+			},
+			expectedName: "Platform",
+		},
+		{
+			name: "replace display name without path",
+			op: api.SCIMGroupPatchOperation{
+				Op:    "replace",
+				Value: json.RawMessage(`{"id":"abcd","displayName":"Platform"}`),
+			},
+			expectedName: "Platform",
+		},
+		{
+			name: "unsupported path",
+			op: api.SCIMGroupPatchOperation{
+				Op:    "replace",
+				Path:  "externalId",
+				Value: json.RawMessage(`"1234"`),
+			},
+			expectedErr: "unsupported path",
+		},
+		{
+			name: "unsupported operation",
+			op: api.SCIMGroupPatchOperation{
+				Op:    "move",
+				Path:  "members",
+				Value: json.RawMessage(`[]`),
+			},
+			expectedErr: "unsupported operation",
+		},
+		{
+			name: "invalid member",
+			op: api.SCIMGroupPatchOperation{
+				Op:    "add",
+				Path:  "members",
+				Value: json.RawMessage(`[{"value":"not-an-id"}]`),
+			},
+			expectedErr: "invalid member",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, add, remove, err := parseGroupPatchOperation(nil, 1, tc.op)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, name, tc.expectedName)
+			assert.DeepEqual(t, add, tc.expectedAdd, cmpopts.EquateEmpty())
+			assert.DeepEqual(t, remove, tc.expectedRemove, cmpopts.EquateEmpty())
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+)
+
+// The SCIM discovery endpoints describe the SCIM features and resources
+// supported by infra. They are static, and do not require authentication.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-4
+
+var getSCIMServiceProviderConfigRoute = route[api.EmptyRequest, *api.SCIMServiceProviderConfig]{
+	handler: GetSCIMServiceProviderConfig,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		omitFromAudit:              true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var listSCIMResourceTypesRoute = route[api.EmptyRequest, *api.ListSCIMResourceTypesResponse]{
+	handler: ListSCIMResourceTypes,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		omitFromAudit:              true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var getSCIMResourceTypeRoute = route[api.SCIMDiscoveryRequest, *api.SCIMResourceType]{
+	handler: GetSCIMResourceType,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		omitFromAudit:              true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var listSCIMSchemasRoute = route[api.EmptyRequest, *api.ListSCIMSchemasResponse]{
+	handler: ListSCIMSchemas,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		omitFromAudit:              true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+var getSCIMSchemaRoute = route[api.SCIMDiscoveryRequest, *api.SCIMSchema]{
+	handler: GetSCIMSchema,
+	routeSettings: routeSettings{
+		omitFromTelemetry:          true,
+		omitFromDocs:               true,
+		omitFromAudit:              true,
+		infraVersionHeaderOptional: true,
+	},
+}
+
+func GetSCIMServiceProviderConfig(c *gin.Context, _ *api.EmptyRequest) (*api.SCIMServiceProviderConfig, error) {
+	return &api.SCIMServiceProviderConfig{
+		Schemas:          []string{api.ServiceProviderConfigSchema},
+		DocumentationURI: "https://infrahq.com/docs",
+		Patch:            api.SCIMSupported{Supported: true},
+		Bulk:             api.SCIMBulkSupported{Supported: false},
+		Filter:           api.SCIMFilterSupported{Supported: true, MaxResults: scimMaxResults},
+		ChangePassword:   api.SCIMSupported{Supported: false},
+		Sort:             api.SCIMSupported{Supported: true},
+		ETag:             api.SCIMSupported{Supported: false},
+		AuthenticationSchemes: []api.SCIMAuthenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "Authentication with an access key issued for the identity provider",
+				Primary:     true,
+			},
+		},
+		Meta: api.SCIMMetadata{
+			ResourceType: "ServiceProviderConfig",
+			Location:     "/api/scim/v2/ServiceProviderConfig",
+		},
+	}, nil
+}
+
+func ListSCIMResourceTypes(c *gin.Context, _ *api.EmptyRequest) (*api.ListSCIMResourceTypesResponse, error) {
+	return &api.ListSCIMResourceTypesResponse{
+		Schemas:      []string{api.ListResponseSchema},
+		TotalResults: len(scimResourceTypes),
+		Resources:    scimResourceTypes,
+		StartIndex:   1,
+		ItemsPerPage: len(scimResourceTypes),
+	}, nil
+}
+
+func GetSCIMResourceType(c *gin.Context, r *api.SCIMDiscoveryRequest) (*api.SCIMResourceType, error) {
+	for i := range scimResourceTypes {
+		if scimResourceTypes[i].ID == r.ID {
+			return &scimResourceTypes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: resource type %q", internal.ErrNotFound, r.ID)
+}
+
+func ListSCIMSchemas(c *gin.Context, _ *api.EmptyRequest) (*api.ListSCIMSchemasResponse, error) {
+	return &api.ListSCIMSchemasResponse{
+		Schemas:      []string{api.ListResponseSchema},
+		TotalResults: len(scimSchemas),
+		Resources:    scimSchemas,
+		StartIndex:   1,
+		ItemsPerPage: len(scimSchemas),
+	}, nil
+}
+
+func GetSCIMSchema(c *gin.Context, r *api.SCIMDiscoveryRequest) (*api.SCIMSchema, error) {
+	for i := range scimSchemas {
+		if scimSchemas[i].ID == r.ID {
+			return &scimSchemas[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: schema %q", internal.ErrNotFound, r.ID)
+}
+
+var scimResourceTypes = []api.SCIMResourceType{
+	{
+		Schemas:     []string{api.ResourceTypeSchema},
+		ID:          "User",
+		Name:        "User",
+		Endpoint:    "/Users",
+		Description: "User Account",
+		Schema:      api.UserSchema,
+		Meta: api.SCIMMetadata{
+			ResourceType: "ResourceType",
+			Location:     "/api/scim/v2/ResourceTypes/User",
+		},
+	},
+	{
+		Schemas:     []string{api.ResourceTypeSchema},
+		ID:          "Group",
+		Name:        "Group",
+		Endpoint:    "/Groups",
+		Description: "Group",
+		Schema:      api.GroupSchema,
+		Meta: api.SCIMMetadata{
+			ResourceType: "ResourceType",
+			Location:     "/api/scim/v2/ResourceTypes/Group",
+		},
+	},
+}
+
+var scimSchemas = []api.SCIMSchema{
+	{
+		Schemas:     []string{api.SchemaSchema},
+		ID:          api.UserSchema,
+		Name:        "User",
+		Description: "User Account",
+		Attributes: []api.SCIMSchemaAttribute{
+			{
+				Name:        "userName",
+				Type:        "string",
+				Description: "Unique identifier for the User, the email address of the user.",
+				Required:    true,
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "server",
+			},
+			{
+				Name:        "name",
+				Type:        "complex",
+				Description: "The components of the user's name.",
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "none",
+				SubAttributes: []api.SCIMSchemaAttribute{
+					{
+						Name:        "givenName",
+						Type:        "string",
+						Description: "The given name of the User.",
+						Mutability:  "readWrite",
+						Returned:    "default",
+						Uniqueness:  "none",
+					},
+					{
+						Name:        "familyName",
+						Type:        "string",
+						Description: "The family name of the User.",
+						Mutability:  "readWrite",
+						Returned:    "default",
+						Uniqueness:  "none",
+					},
+				},
+			},
+			{
+				Name:        "emails",
+				Type:        "complex",
+				MultiValued: true,
+				Description: "Email addresses for the user. The primary email is used as the userName.",
+				Required:    true,
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "none",
+				SubAttributes: []api.SCIMSchemaAttribute{
+					{
+						Name:        "value",
+						Type:        "string",
+						Description: "Email address for the User.",
+						Mutability:  "readWrite",
+						Returned:    "default",
+						Uniqueness:  "none",
+					},
+					{
+						Name:        "primary",
+						Type:        "boolean",
+						Description: "Indicates the primary email address of the User.",
+						Mutability:  "readWrite",
+						Returned:    "default",
+						Uniqueness:  "none",
+					},
+				},
+			},
+			{
+				Name:        "active",
+				Type:        "boolean",
+				Description: "The administrative status of the User.",
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "none",
+			},
+		},
+		Meta: api.SCIMMetadata{
+			ResourceType: "Schema",
+			Location:     "/api/scim/v2/Schemas/" + api.UserSchema,
+		},
+	},
+	{
+		Schemas:     []string{api.SchemaSchema},
+		ID:          api.GroupSchema,
+		Name:        "Group",
+		Description: "Group",
+		Attributes: []api.SCIMSchemaAttribute{
+			{
+				Name:        "displayName",
+				Type:        "string",
+				Description: "A human-readable name for the Group.",
+				Required:    true,
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "server",
+			},
+			{
+				Name:        "members",
+				Type:        "complex",
+				MultiValued: true,
+				Description: "A list of members of the Group.",
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "none",
+				SubAttributes: []api.SCIMSchemaAttribute{
+					{
+						Name:        "value",
+						Type:        "string",
+						Description: "Identifier of the member of this Group.",
+						Mutability:  "immutable",
+						Returned:    "default",
+						Uniqueness:  "none",
+					},
+					{
+						Name:        "display",
+						Type:        "string",
+						Description: "The name of the member of this Group.",
+						Mutability:  "readOnly",
+						Returned:    "default",
+						Uniqueness:  "none",
+					},
+				},
+			},
+		},
+		Meta: api.SCIMMetadata{
+			ResourceType: "Schema",
+			Location:     "/api/scim/v2/Schemas/" + api.GroupSchema,
+		},
+	},
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+)
+
+func TestAPI_SCIMDiscovery(t *testing.T) {
+	srv := setupServer(t)
+	routes := srv.GenerateRoutes()
+
+	get := func(t *testing.T, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		// nolint:noctx
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("service provider config", func(t *testing.T) {
+		resp := get(t, "/api/scim/v2/ServiceProviderConfig")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var config api.SCIMServiceProviderConfig
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &config))
+		assert.DeepEqual(t, config.Schemas, []string{api.ServiceProviderConfigSchema})
+		assert.Assert(t, config.Filter.Supported)
+		assert.Assert(t, config.Sort.Supported)
+	})
+
+	t.Run("resource types", func(t *testing.T) {
+		resp := get(t, "/api/scim/v2/ResourceTypes")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListSCIMResourceTypesResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+		assert.Equal(t, list.TotalResults, 2)
+
+		resp = get(t, "/api/scim/v2/ResourceTypes/Group")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("schemas", func(t *testing.T) {
+		resp := get(t, "/api/scim/v2/Schemas")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = get(t, "/api/scim/v2/Schemas/"+api.UserSchema)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = get(t, "/api/scim/v2/Schemas/unknown")
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+}
+
+func TestSCIMDiscovery_ResourceTypesHaveSchemas(t *testing.T) {
+	for _, rt := range scimResourceTypes {
+		schema, err := GetSCIMSchema(nil, &api.SCIMDiscoveryRequest{ID: rt.Schema})
+		assert.NilError(t, err, rt.ID)
+		assert.Equal(t, schema.Name, rt.Name)
+
+		actual, err := GetSCIMResourceType(nil, &api.SCIMDiscoveryRequest{ID: rt.ID})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *actual, rt)
+	}
+
+	_, err := GetSCIMResourceType(nil, &api.SCIMDiscoveryRequest{ID: "Device"})
+	assert.ErrorIs(t, err, internal.ErrNotFound)
+}
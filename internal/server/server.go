@@ -75,6 +75,12 @@ type Options struct {
 	// create a unique hostname for each organization.
 	BaseDomain string
 
+	// WebAuthnRPID is the relying party ID used for WebAuthn credentials. It
+	// must be the hostname used to access the UI, or a parent domain of that
+	// hostname. Defaults to BaseDomain, so that credentials can be used with
+	// every organization.
+	WebAuthnRPID string
+
 	Keys    []KeyProvider
 	Secrets []SecretProvider
 
@@ -153,33 +159,9 @@ func New(options Options) (*Server, error) {
 	}
 
 	server := newServer(options)
-
-	if err := importSecrets(options.Secrets, server.secrets); err != nil {
-		return nil, fmt.Errorf("secrets config: %w", err)
-	}
-
-	if err := importKeyProviders(options.Keys, server.secrets, server.keys); err != nil {
-		return nil, fmt.Errorf("key config: %w", err)
-	}
-
-	dsn, err := getPostgresConnectionString(options, server.secrets)
-	if err != nil {
-		return nil, fmt.Errorf("postgres dsn: %w", err)
-	}
-	options.DB.DSN = dsn
-
-	dbKeyProvider, ok := server.keys[options.DBEncryptionKeyProvider]
-	if !ok {
-		return nil, fmt.Errorf("key provider %s not configured", options.DBEncryptionKeyProvider)
-	}
-	options.DB.EncryptionKeyProvider = dbKeyProvider
-	options.DB.RootKeyID = options.DBEncryptionKey
-
-	db, err := data.NewDB(options.DB)
-	if err != nil {
-		return nil, fmt.Errorf("db: %w", err)
+	if err := server.openDB(); err != nil {
+		return nil, err
 	}
-	server.db = db
 	server.metricsRegistry = setupMetrics(server.db)
 
 	redisPassword, err := secrets.GetSecret(options.Redis.Password, server.secrets)
@@ -195,7 +177,7 @@ func New(options Options) (*Server, error) {
 	server.redis = redis
 
 	if options.EnableTelemetry {
-		server.tel = NewTelemetry(server.db, db.DefaultOrgSettings.ID)
+		server.tel = NewTelemetry(server.db, server.db.DefaultOrgSettings.ID)
 	}
 
 	if options.GoogleClientID != "" {
@@ -224,6 +206,76 @@ func New(options Options) (*Server, error) {
 	return server, nil
 }
 
+// PlanConfig returns the changes to the default organization that would be
+// made by loading options.Config, without applying them. PlanConfig connects
+// to the database without running migrations. The config is planned with the
+// queries of this version of the server, which require its schema, so the
+// migrations must be applied, by starting the server once, before the config
+// can be validated. PlanConfig returns an error that wraps
+// data.ErrMigrationsPending if the database has pending migrations.
+func PlanConfig(options Options) ([]ConfigChange, error) {
+	server := newServer(options)
+	if err := server.openDBWith(data.OpenDB); err != nil {
+		return nil, err
+	}
+	defer logError(server.db.Close, "failed to close database connection")
+
+	return server.planConfig(options.Config)
+}
+
+// RotateDBKey creates a new version of the database key, and re-encrypts every
+// encrypted value in the database with the new key. RotateDBKey returns the
+// number of values that were re-encrypted.
+func RotateDBKey(options Options) (int, error) {
+	server := newServer(options)
+	if err := server.openDB(); err != nil {
+		return 0, err
+	}
+	defer logError(server.db.Close, "failed to close database connection")
+
+	provider := server.keys[options.DBEncryptionKeyProvider]
+	return data.RotateDBKey(server.db, provider, options.DBEncryptionKey)
+}
+
+// openDB imports the secret and key providers, and opens the database. Any
+// pending migrations are applied to the database.
+func (s *Server) openDB() error {
+	return s.openDBWith(data.NewDB)
+}
+
+// openDBWith imports the secret and key providers, and opens the database
+// using open.
+func (s *Server) openDBWith(open func(data.NewDBOptions) (*data.DB, error)) error {
+	options := s.options
+	if err := importSecrets(options.Secrets, s.secrets); err != nil {
+		return fmt.Errorf("secrets config: %w", err)
+	}
+
+	if err := importKeyProviders(options.Keys, s.secrets, s.keys); err != nil {
+		return fmt.Errorf("key config: %w", err)
+	}
+
+	dsn, err := getPostgresConnectionString(options, s.secrets)
+	if err != nil {
+		return fmt.Errorf("postgres dsn: %w", err)
+	}
+	options.DB.DSN = dsn
+
+	dbKeyProvider, ok := s.keys[options.DBEncryptionKeyProvider]
+	if !ok {
+		return fmt.Errorf("key provider %s not configured", options.DBEncryptionKeyProvider)
+	}
+	options.DB.EncryptionKeyProvider = dbKeyProvider
+	options.DB.RootKeyID = options.DBEncryptionKey
+
+	db, err := open(options.DB)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+	s.db = db
+	return nil
+}
+
 // DB returns an instance of a database connection pool that is used by the server.
 // It is primarily used by tests to create fixture data.
 func (s *Server) DB() *data.DB {
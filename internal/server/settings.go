@@ -23,6 +23,7 @@ func (a *API) UpdateSettings(c *gin.Context, s *api.Settings) (*api.Settings, er
 	if err != nil {
 		return nil, err
 	}
+	getRequestContext(c).Response.SetAuditBefore(settings.ToAPI())
 
 	settings.SetFromAPI(s)
 	if err = access.SaveSettings(c, settings); err != nil {
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+)
+
+func (a *API) CreateSSHCertificate(c *gin.Context, r *api.CreateSSHCertificateRequest) (*api.SSHCertificate, error) {
+	cert, err := access.CreateSSHCertificate(c, r.Destination, r.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SSHCertificate{
+		Certificate: cert.Certificate,
+		Username:    cert.Username,
+		Expires:     api.Time(cert.ValidBefore),
+	}, nil
+}
+
+func (a *API) GetSSHCertificateAuthority(c *gin.Context, _ *api.EmptyRequest) (*api.SSHCertificateAuthority, error) {
+	publicKey, err := access.GetSSHCertificateAuthority(c)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SSHCertificateAuthority{PublicKey: publicKey}, nil
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/openssh"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_CreateSSHCertificate(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	userKey, user := createAccessKey(t, srv.DB(), "alice@example.com")
+	otherKey, _ := createAccessKey(t, srv.DB(), "other@example.com")
+
+	sshDest := &models.Destination{Name: "web-1", Kind: models.DestinationKindSSH, UniqueID: "web-1"}
+	assert.NilError(t, data.CreateDestination(srv.DB(), sshDest))
+	kubeDest := &models.Destination{Name: "cluster", Kind: models.DestinationKindKubernetes, UniqueID: "cluster"}
+	assert.NilError(t, data.CreateDestination(srv.DB(), kubeDest))
+
+	for _, resource := range []string{"web-1", "cluster"} {
+		err := data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   uid.NewIdentityPolymorphicID(user.ID),
+			Privilege: "user",
+			Resource:  resource,
+		})
+		assert.NilError(t, err)
+	}
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	assert.NilError(t, err)
+	publicKey := string(ssh.MarshalAuthorizedKey(sshPub))
+
+	do := func(t *testing.T, key string, body api.CreateSSHCertificateRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/ssh/certificates", jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("success", func(t *testing.T) {
+		resp := do(t, userKey, api.CreateSSHCertificateRequest{Destination: "web-1", PublicKey: publicKey})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var cert api.SSHCertificate
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&cert))
+		username := openssh.Username(user.ID, "alice@example.com")
+		assert.Equal(t, cert.Username, username)
+
+		parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cert.Certificate))
+		assert.NilError(t, err)
+		sshCert, ok := parsed.(*ssh.Certificate)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, sshCert.ValidPrincipals, []string{username})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/ssh/certificate-authority", nil)
+		req.Header.Set("Authorization", "Bearer "+userKey)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+		caResp := httptest.NewRecorder()
+		routes.ServeHTTP(caResp, req)
+		assert.Equal(t, caResp.Code, http.StatusOK, caResp.Body.String())
+
+		var ca api.SSHCertificateAuthority
+		assert.NilError(t, json.NewDecoder(caResp.Body).Decode(&ca))
+		caKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(ca.PublicKey))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, sshCert.SignatureKey.Marshal(), caKey.Marshal())
+	})
+
+	t.Run("no grant for the destination", func(t *testing.T) {
+		resp := do(t, otherKey, api.CreateSSHCertificateRequest{Destination: "web-1", PublicKey: publicKey})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("custom role with the connect permission", func(t *testing.T) {
+		roleKey, roleUser := createAccessKey(t, srv.DB(), "role@example.com")
+		role := &models.Role{Name: "ssh-users", Permissions: []string{"connect"}}
+		assert.NilError(t, data.CreateRole(srv.DB(), role))
+		err := data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   uid.NewIdentityPolymorphicID(roleUser.ID),
+			Privilege: "ssh-users",
+			Resource:  "web-1",
+		})
+		assert.NilError(t, err)
+
+		resp := do(t, roleKey, api.CreateSSHCertificateRequest{Destination: "web-1", PublicKey: publicKey})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("custom role without the connect permission", func(t *testing.T) {
+		roleKey, roleUser := createAccessKey(t, srv.DB(), "viewer@example.com")
+		role := &models.Role{Name: "cluster-viewers", KubernetesRoles: []string{"view"}}
+		assert.NilError(t, data.CreateRole(srv.DB(), role))
+		err := data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   uid.NewIdentityPolymorphicID(roleUser.ID),
+			Privilege: "cluster-viewers",
+			Resource:  "web-1",
+		})
+		assert.NilError(t, err)
+
+		resp := do(t, roleKey, api.CreateSSHCertificateRequest{Destination: "web-1", PublicKey: publicKey})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("not an ssh destination", func(t *testing.T) {
+		resp := do(t, userKey, api.CreateSSHCertificateRequest{Destination: "cluster", PublicKey: publicKey})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("invalid public key", func(t *testing.T) {
+		resp := do(t, userKey, api.CreateSSHCertificateRequest{Destination: "web-1", PublicKey: "not-a-key"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+}
@@ -16,7 +16,10 @@ import (
 )
 
 func (a *API) ListUsers(c *gin.Context, r *api.ListUsersRequest) (*api.ListResponse[api.User], error) {
-	p := PaginationFromRequest(r.PaginationRequest)
+	p, err := CursorPaginationFromRequest(r.CursorPaginationRequest, r.PaginationRequest)
+	if err != nil {
+		return nil, err
+	}
 	users, err := access.ListIdentities(c, r.Name, r.Group, r.IDs, r.ShowSystem, &p)
 	if err != nil {
 		return nil, err
@@ -111,6 +114,7 @@ func (a *API) UpdateUser(c *gin.Context, r *api.UpdateUserRequest) (*api.User, e
 	if err != nil {
 		return nil, err
 	}
+	getRequestContext(c).Response.SetAuditBefore(identity.ToAPI())
 
 	err = access.UpdateCredential(c, identity, r.OldPassword, r.Password)
 	if err != nil {
@@ -120,5 +124,26 @@ func (a *API) UpdateUser(c *gin.Context, r *api.UpdateUserRequest) (*api.User, e
 }
 
 func (a *API) DeleteUser(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	if a.server.options.Reconcile {
+		user, err := access.GetIdentity(c, data.GetIdentityOptions{ByID: r.ID})
+		if err != nil {
+			return nil, err
+		}
+		if err := a.checkConfigManaged(c, "user", user.Name, user.CreatedBy); err != nil {
+			return nil, err
+		}
+	}
 	return nil, access.DeleteIdentity(c, r.ID)
 }
+
+func (a *API) ListUserPermissions(c *gin.Context, r *api.Resource) (*api.ListResponse[api.Permission], error) {
+	permissions, err := access.ListUserPermissions(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(permissions, api.PaginationResponse{}, func(perm models.InfraPermission) api.Permission {
+		return perm.ToAPI()
+	})
+	return result, nil
+}
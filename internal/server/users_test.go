@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"runtime"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -380,6 +382,60 @@ func TestAPI_ListUsers(t *testing.T) {
 				assert.DeepEqual(t, actual, expected, cmpAPIUserShallow)
 			},
 		},
+		"cursor pagination": {
+			urlPath: "/api/users?limit=3&showSystem=true",
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Infra-Version", apiVersionLatest)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+				var names []string
+				for pages := 1; ; pages++ {
+					var actual api.ListResponse[api.User]
+					err := json.NewDecoder(resp.Body).Decode(&actual)
+					assert.NilError(t, err)
+					assert.Equal(t, actual.Limit, 3)
+					assert.Equal(t, actual.TotalCount, 0)
+					for _, user := range actual.Items {
+						names = append(names, user.Name)
+					}
+					if actual.Next == "" {
+						assert.Equal(t, pages, 3)
+						break
+					}
+
+					// nolint:noctx
+					req := httptest.NewRequest(http.MethodGet, "/api/users?limit=3&showSystem=true&after="+actual.Next, nil)
+					req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+					req.Header.Add("Infra-Version", apiVersionLatest)
+					resp = httptest.NewRecorder()
+					routes.ServeHTTP(resp, req)
+					assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+				}
+
+				sort.Strings(names)
+				expected := []string{
+					"AnotherUser@example.com",
+					"HAL@example.com",
+					"admin@example.com",
+					"connector",
+					"me@example.com",
+					"other-HAL@example.com",
+					"other@example.com",
+				}
+				assert.DeepEqual(t, names, expected)
+			},
+		},
+		"invalid cursor": {
+			urlPath: "/api/users?after=***",
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Infra-Version", apiVersionLatest)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+			},
+		},
 		"user in group": {
 			urlPath: fmt.Sprintf("/api/users?group=%s", humans.ID),
 			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
@@ -787,6 +843,70 @@ func TestAPI_DeleteUser(t *testing.T) {
 			run(t, tc)
 		})
 	}
+
+	otherAdmin := &models.Identity{Name: "other-admin@example.com"}
+	assert.NilError(t, data.CreateIdentity(srv.DB(), otherAdmin))
+	assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+		Subject:   otherAdmin.PolyID(),
+		Privilege: models.InfraAdminRole,
+		Resource:  access.ResourceInfraAPI,
+	}))
+
+	t.Run("users write can not delete an infra admin", func(t *testing.T) {
+		writerKey, writer := createAccessKey(t, srv.DB(), "user-writer@example.com")
+		assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   writer.PolyID(),
+			Privilege: "users:write",
+			Resource:  access.ResourceInfraAPI,
+		}))
+		member := &models.Identity{Name: "member@example.com"}
+		assert.NilError(t, data.CreateIdentity(srv.DB(), member))
+
+		setup := func(t *testing.T, req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+writerKey)
+		}
+		run(t, testCase{
+			urlPath: "/api/users/" + otherAdmin.ID.String(),
+			setup:   setup,
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusForbidden, resp.Code, resp.Body.String())
+			},
+		})
+		run(t, testCase{
+			urlPath: "/api/users/" + member.ID.String(),
+			setup:   setup,
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+			},
+		})
+	})
+
+	t.Run("can not delete the last infra admin", func(t *testing.T) {
+		role := &models.Role{Name: "super-admin", InfraRoles: []string{models.InfraAdminRole}}
+		assert.NilError(t, data.CreateRole(srv.DB(), role))
+		roleKey, roleAdmin := createAccessKey(t, srv.DB(), "role-admin@example.com")
+		assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   roleAdmin.PolyID(),
+			Privilege: role.Name,
+			Resource:  access.ResourceInfraAPI,
+		}))
+
+		admin, err := data.GetIdentity(srv.DB(), data.GetIdentityOptions{ByName: "admin@example.com"})
+		assert.NilError(t, err)
+		err = data.DeleteGrants(srv.DB(), data.DeleteGrantsOptions{BySubject: admin.PolyID()})
+		assert.NilError(t, err)
+
+		run(t, testCase{
+			urlPath: "/api/users/" + otherAdmin.ID.String(),
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+roleKey)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+				assert.Assert(t, strings.Contains(resp.Body.String(), "last infra admin grant"))
+			},
+		})
+	})
 }
 
 func TestAPI_UpdateUser(t *testing.T) {
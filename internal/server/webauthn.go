@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// webAuthnRPName is the name of the relying party shown by authenticators.
+const webAuthnRPName = "Infra"
+
+// webAuthnRPID returns the relying party ID from the server options. The ID is
+// not read from the request, because the Host header is controlled by the
+// client.
+func (a *API) webAuthnRPID() (string, error) {
+	rpID := a.server.options.WebAuthnRPID
+	if rpID == "" {
+		rpID = a.server.options.BaseDomain
+	}
+	if rpID == "" {
+		return "", fmt.Errorf("%w: webauthn is not configured, the server must set webAuthnRPID", internal.ErrBadRequest)
+	}
+	return rpID, nil
+}
+
+func webAuthnCredentialDescriptors(credentials []models.WebAuthnCredential) []api.WebAuthnCredentialDescriptor {
+	result := make([]api.WebAuthnCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, api.WebAuthnCredentialDescriptor{Type: "public-key", ID: credential.CredentialID})
+	}
+	return result
+}
+
+func (a *API) BeginWebAuthnRegistration(c *gin.Context, r *api.Resource) (*api.BeginWebAuthnRegistrationResponse, error) {
+	identity, err := access.GetIdentity(c, data.GetIdentityOptions{ByID: r.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	rpID, err := a.webAuthnRPID()
+	if err != nil {
+		return nil, err
+	}
+	session, existing, err := access.BeginWebAuthnRegistration(c, identity.ID, rpID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]api.WebAuthnCredentialParameter, 0, len(authn.WebAuthnAlgorithms))
+	for _, alg := range authn.WebAuthnAlgorithms {
+		params = append(params, api.WebAuthnCredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return &api.BeginWebAuthnRegistrationResponse{
+		SessionID: session.ID,
+		PublicKey: api.WebAuthnCreationOptions{
+			RP: api.WebAuthnRelyingParty{ID: rpID, Name: webAuthnRPName},
+			User: api.WebAuthnUser{
+				ID:          authn.WebAuthnUserHandle(identity.ID),
+				Name:        identity.Name,
+				DisplayName: identity.Name,
+			},
+			Challenge:          session.Challenge,
+			PubKeyCredParams:   params,
+			Timeout:            authn.WebAuthnTimeout.Milliseconds(),
+			ExcludeCredentials: webAuthnCredentialDescriptors(existing),
+			AuthenticatorSelection: api.WebAuthnAuthenticatorSelection{
+				ResidentKey:      "preferred",
+				UserVerification: "required",
+			},
+			Attestation: "none",
+		},
+	}, nil
+}
+
+func (a *API) FinishWebAuthnRegistration(c *gin.Context, r *api.FinishWebAuthnRegistrationRequest) (*api.WebAuthnCredential, error) {
+	clientDataJSON, err := authn.DecodeWebAuthnBase64(r.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid clientDataJSON: %v", internal.ErrBadRequest, err)
+	}
+	attestationObject, err := authn.DecodeWebAuthnBase64(r.Credential.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid attestationObject: %v", internal.ErrBadRequest, err)
+	}
+
+	credential, err := access.FinishWebAuthnRegistration(c, r.ID, r.SessionID, r.Name, clientDataJSON, attestationObject)
+	if err != nil {
+		return nil, err
+	}
+	return credential.ToAPI(), nil
+}
+
+func (a *API) ListWebAuthnCredentials(c *gin.Context, r *api.Resource) (*api.ListResponse[api.WebAuthnCredential], error) {
+	credentials, err := access.ListWebAuthnCredentials(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(credentials, api.PaginationResponse{}, func(credential models.WebAuthnCredential) api.WebAuthnCredential {
+		return *credential.ToAPI()
+	})
+	return result, nil
+}
+
+func (a *API) DeleteWebAuthnCredential(c *gin.Context, r *api.DeleteWebAuthnCredentialRequest) (*api.EmptyResponse, error) {
+	return nil, access.DeleteWebAuthnCredential(c, r.ID, r.CredentialID)
+}
+
+func (a *API) BeginWebAuthnLogin(c *gin.Context, r *api.BeginWebAuthnLoginRequest) (*api.BeginWebAuthnLoginResponse, error) {
+	rpID, err := a.webAuthnRPID()
+	if err != nil {
+		return nil, err
+	}
+	session, allowed, err := access.BeginWebAuthnLogin(c, r.Name, rpID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.BeginWebAuthnLoginResponse{
+		SessionID: session.ID,
+		PublicKey: api.WebAuthnRequestOptions{
+			Challenge:        session.Challenge,
+			Timeout:          authn.WebAuthnTimeout.Milliseconds(),
+			RPID:             rpID,
+			AllowCredentials: webAuthnCredentialDescriptors(allowed),
+			UserVerification: "required",
+		},
+	}, nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestAPI_WebAuthn(t *testing.T) {
+	srv := setupServer(t, withAdminUser, func(_ *testing.T, options *Options) {
+		options.WebAuthnRPID = "example.com"
+	})
+	routes := srv.GenerateRoutes()
+
+	userKey, user := createAccessKey(t, srv.DB(), "pan@example.com")
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	assert.NilError(t, err)
+	err = data.CreateCredential(srv.DB(), &models.Credential{IdentityID: user.ID, PasswordHash: hash})
+	assert.NilError(t, err)
+
+	do := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	registrationPath := "/api/users/" + user.ID.String() + "/webauthn/registration"
+
+	t.Run("begin registration", func(t *testing.T) {
+		resp := do(t, http.MethodPost, registrationPath, userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var begin api.BeginWebAuthnRegistrationResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&begin))
+		assert.Assert(t, begin.SessionID != 0)
+		assert.Assert(t, begin.PublicKey.Challenge != "")
+		assert.Equal(t, begin.PublicKey.RP.ID, "example.com")
+		assert.Equal(t, begin.PublicKey.User.ID, authn.WebAuthnUserHandle(user.ID))
+		assert.Equal(t, begin.PublicKey.User.Name, user.Name)
+		assert.Equal(t, begin.PublicKey.AuthenticatorSelection.UserVerification, "required")
+		assert.Equal(t, len(begin.PublicKey.PubKeyCredParams), 3)
+
+		t.Run("finish with an invalid response", func(t *testing.T) {
+			req := api.FinishWebAuthnRegistrationRequest{
+				SessionID: begin.SessionID,
+				Name:      "laptop",
+				Credential: api.WebAuthnAttestation{
+					ID:   "abcd",
+					Type: "public-key",
+					Response: api.WebAuthnAttestationResponse{
+						ClientDataJSON:    authn.EncodeWebAuthnBase64([]byte(`{"type":"webauthn.create","challenge":"wrong"}`)),
+						AttestationObject: authn.EncodeWebAuthnBase64([]byte{0xa0}),
+					},
+				},
+			}
+			resp := do(t, http.MethodPut, registrationPath, userKey, req)
+			assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		})
+	})
+
+	t.Run("registration for another user", func(t *testing.T) {
+		otherKey, _ := createAccessKey(t, srv.DB(), "bra@example.com")
+		resp := do(t, http.MethodPost, registrationPath, otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("list credentials", func(t *testing.T) {
+		credential := &models.WebAuthnCredential{
+			IdentityID:   user.ID,
+			Name:         "yubikey",
+			CredentialID: "cred-1",
+			PublicKey:    []byte{0xa0},
+		}
+		assert.NilError(t, data.CreateWebAuthnCredential(srv.DB(), credential))
+
+		resp := do(t, http.MethodGet, "/api/users/"+user.ID.String()+"/webauthn/credentials", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.WebAuthnCredential]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&list))
+		assert.Equal(t, len(list.Items), 1)
+		assert.Equal(t, list.Items[0].Name, "yubikey")
+
+		t.Run("begin login", func(t *testing.T) {
+			resp := do(t, http.MethodPost, "/api/login/webauthn", "", api.BeginWebAuthnLoginRequest{Name: user.Name})
+			assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+			var begin api.BeginWebAuthnLoginResponse
+			assert.NilError(t, json.NewDecoder(resp.Body).Decode(&begin))
+			assert.Equal(t, begin.PublicKey.RPID, "example.com")
+			expected := []api.WebAuthnCredentialDescriptor{{Type: "public-key", ID: "cred-1"}}
+			assert.DeepEqual(t, begin.PublicKey.AllowCredentials, expected)
+		})
+
+		t.Run("begin login for unknown user", func(t *testing.T) {
+			resp := do(t, http.MethodPost, "/api/login/webauthn", "", api.BeginWebAuthnLoginRequest{Name: "nobody@example.com"})
+			assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+			var begin api.BeginWebAuthnLoginResponse
+			assert.NilError(t, json.NewDecoder(resp.Body).Decode(&begin))
+			assert.Equal(t, len(begin.PublicKey.AllowCredentials), 0)
+		})
+
+		t.Run("delete", func(t *testing.T) {
+			path := "/api/users/" + user.ID.String() + "/webauthn/credentials/" + credential.ID.String()
+			resp := do(t, http.MethodDelete, path, userKey, nil)
+			assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+			_, err := data.GetWebAuthnCredential(srv.DB(), data.GetWebAuthnCredentialOptions{ByID: credential.ID})
+			assert.ErrorContains(t, err, "not found")
+		})
+	})
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func (a *API) ListWebhooks(c *gin.Context, r *api.ListWebhooksRequest) (*api.ListResponse[api.Webhook], error) {
+	p := PaginationFromRequest(r.PaginationRequest)
+	webhooks, err := access.ListWebhooks(c, data.ListWebhooksOptions{Pagination: &p})
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(webhooks, PaginationToResponse(p), func(webhook models.Webhook) api.Webhook {
+		return *webhook.ToAPI()
+	})
+	return result, nil
+}
+
+func (a *API) GetWebhook(c *gin.Context, r *api.Resource) (*api.Webhook, error) {
+	webhook, err := access.GetWebhook(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	return webhook.ToAPI(), nil
+}
+
+func (a *API) CreateWebhook(c *gin.Context, r *api.CreateWebhookRequest) (*api.CreateWebhookResponse, error) {
+	secret := r.Secret
+	if secret == "" {
+		var err error
+		secret, err = generate.CryptoRandom(32, generate.CharsetAlphaNumeric)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &models.Webhook{
+		URL:    r.URL,
+		Events: r.Events,
+		Secret: models.EncryptedAtRest(secret),
+	}
+	if err := access.CreateWebhook(c, webhook); err != nil {
+		return nil, err
+	}
+	return &api.CreateWebhookResponse{Webhook: *webhook.ToAPI(), Secret: secret}, nil
+}
+
+func (a *API) UpdateWebhook(c *gin.Context, r *api.UpdateWebhookRequest) (*api.Webhook, error) {
+	rCtx := getRequestContext(c)
+
+	// Start with the existing value, so that the secret is not changed.
+	webhook, err := access.GetWebhook(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	rCtx.Response.SetAuditBefore(webhook.ToAPI())
+
+	webhook.URL = r.URL
+	webhook.Events = r.Events
+	if err := access.UpdateWebhook(c, webhook); err != nil {
+		return nil, err
+	}
+	return webhook.ToAPI(), nil
+}
+
+func (a *API) DeleteWebhook(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteWebhook(c, r.ID)
+}
+
+func (a *API) ListWebhookDeliveries(c *gin.Context, r *api.ListWebhookDeliveriesRequest) (*api.ListResponse[api.WebhookDelivery], error) {
+	p := PaginationFromRequest(r.PaginationRequest)
+	opts := data.ListWebhookDeliveriesOptions{
+		ByWebhookID: r.ID,
+		ByStatus:    models.WebhookDeliveryStatus(r.Status),
+		Pagination:  &p,
+	}
+	deliveries, err := access.ListWebhookDeliveries(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(deliveries, PaginationToResponse(p), func(delivery models.WebhookDelivery) api.WebhookDelivery {
+		return *delivery.ToAPI()
+	})
+	return result, nil
+}
+
+// webhookEventTypes maps the action of an audit event to the type of the
+// webhook event delivered for the action. Actions that are not in this map do
+// not produce webhook events. User, group, and grant events are not in this
+// map, because they are created by the data layer whenever users, groups, or
+// grants are changed, including by logins, background jobs, and config.
+var webhookEventTypes = map[string]string{
+	"CreateDestination": "destination.created",
+	"UpdateDestination": "destination.updated",
+	"DeleteDestination": "destination.deleted",
+}
+
+// createWebhookDeliveries creates a pending delivery of the event for every
+// webhook subscribed to it. The deliveries are created in the same transaction
+// as the event, and are sent by the DeliverWebhooks background job.
+func createWebhookDeliveries(tx data.WriteTxn, event *models.AuditEvent) error {
+	eventType, ok := webhookEventTypes[event.Action]
+	if !ok {
+		return nil
+	}
+
+	webhooks, err := data.ListWebhooks(tx, data.ListWebhooksOptions{})
+	if err != nil {
+		return fmt.Errorf("list webhooks: %w", err)
+	}
+
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(eventType) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(webhookEvent(eventType, event))
+			if err != nil {
+				return fmt.Errorf("webhook payload: %w", err)
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			EventType: eventType,
+			Payload:   string(payload),
+		}
+		if err := data.CreateWebhookDelivery(tx, delivery); err != nil {
+			return fmt.Errorf("create webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+func webhookEvent(eventType string, event *models.AuditEvent) api.WebhookEvent {
+	result := api.WebhookEvent{
+		ID:           event.ID,
+		Type:         eventType,
+		Created:      api.Time(event.CreatedAt),
+		Organization: event.OrganizationID,
+		Actor:        event.ActorID,
+		ActorName:    event.ActorName,
+		Target:       event.TargetID,
+	}
+	// the summaries are already JSON, with sensitive fields redacted
+	if event.BeforeSummary != "" {
+		result.Before = json.RawMessage(event.BeforeSummary)
+	}
+	if event.AfterSummary != "" && event.AfterSummary != "null" {
+		result.After = json.RawMessage(event.AfterSummary)
+	}
+	return result
+}
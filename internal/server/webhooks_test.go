@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestAPI_Webhooks(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	userKey, user := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	do := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createReq := api.CreateWebhookRequest{
+		URL:    "https://hooks.example.com/infra",
+		Events: []string{"grant.*"},
+	}
+	resp := do(t, http.MethodPost, "/api/webhooks", adminAccessKey(srv), createReq)
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var webhook api.CreateWebhookResponse
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&webhook))
+	assert.Equal(t, webhook.URL, "https://hooks.example.com/infra")
+	assert.Equal(t, len(webhook.Secret), 32)
+
+	t.Run("secret is not returned after create", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/webhooks/"+webhook.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var raw map[string]any
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&raw))
+		_, ok := raw["secret"]
+		assert.Assert(t, !ok)
+	})
+
+	t.Run("invalid event type", func(t *testing.T) {
+		req := api.CreateWebhookRequest{URL: "https://hooks.example.com", Events: []string{"grants"}}
+		resp := do(t, http.MethodPost, "/api/webhooks", adminAccessKey(srv), req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("private address", func(t *testing.T) {
+		for _, url := range []string{"http://localhost:8080", "http://127.0.0.1/hook", "http://169.254.169.254/latest", "https://10.0.0.2", "https://100.64.0.1", "http://[::ffff:10.0.0.1]/hook"} {
+			req := api.CreateWebhookRequest{URL: url}
+			resp := do(t, http.MethodPost, "/api/webhooks", adminAccessKey(srv), req)
+			assert.Equal(t, resp.Code, http.StatusBadRequest, url)
+		}
+	})
+
+	t.Run("requires admin role", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/api/webhooks", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = do(t, http.MethodPost, "/api/webhooks", userKey, createReq)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("subscribed events create deliveries", func(t *testing.T) {
+		grantReq := api.GrantRequest{User: user.ID, Privilege: "view", Resource: "production"}
+		resp := do(t, http.MethodPost, "/api/grants", adminAccessKey(srv), grantReq)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var grant api.CreateGrantResponse
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&grant))
+
+		// not subscribed to group events
+		groupReq := api.CreateGroupRequest{Name: "ops"}
+		resp = do(t, http.MethodPost, "/api/groups", adminAccessKey(srv), groupReq)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		path := "/api/webhooks/" + webhook.ID.String() + "/deliveries"
+		resp = do(t, http.MethodGet, path, adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.WebhookDelivery]
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&list))
+		assert.Equal(t, len(list.Items), 1)
+
+		delivery := list.Items[0]
+		assert.Equal(t, delivery.Webhook, webhook.ID)
+		assert.Equal(t, delivery.EventType, "grant.created")
+		assert.Equal(t, delivery.Status, "pending")
+		assert.Equal(t, delivery.Attempts, 0)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		req := api.UpdateWebhookRequest{URL: "https://hooks.example.com/other", Events: []string{"*"}}
+		resp := do(t, http.MethodPut, "/api/webhooks/"+webhook.ID.String(), adminAccessKey(srv), req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var updated api.Webhook
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&updated))
+		assert.Equal(t, updated.URL, "https://hooks.example.com/other")
+		assert.DeepEqual(t, updated.Events, []string{"*"})
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, "/api/webhooks/"+webhook.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = do(t, http.MethodGet, "/api/webhooks/"+webhook.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+}
@@ -0,0 +1,421 @@
+/*
+Package ldap provides an in-process LDAP server for tests.
+
+The server supports simple bind, search, and the StartTLS extended operation,
+which is enough to test login and group sync with an LDAP directory. Search
+filters are limited to and, or, not, equality, and presence filters, which
+are matched without case sensitivity.
+*/
+package ldap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"gotest.tools/v3/assert"
+)
+
+type TestingT interface {
+	assert.TestingT
+	Helper()
+	Cleanup(func())
+}
+
+// PasswordAttribute is the attribute that holds the password of an entry.
+// It is used to bind, and is never returned by a search.
+const PasswordAttribute = "userPassword"
+
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// Entry is an entry in the directory.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Server is an LDAP server that listens on the loopback interface.
+type Server struct {
+	// URL is the ldap:// or ldaps:// URL of the server.
+	URL string
+
+	listener  net.Listener
+	tlsConfig *tls.Config
+	rootCAs   *x509.CertPool
+
+	mu      sync.Mutex
+	entries []Entry
+	conns   map[net.Conn]struct{}
+}
+
+// NewServer starts a server that accepts ldap:// connections, which may be
+// upgraded with StartTLS. The server is stopped when the test ends.
+func NewServer(t TestingT, entries ...Entry) *Server {
+	t.Helper()
+	s := newServer(t, entries)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	s.start(t, listener, "ldap://")
+	return s
+}
+
+// NewTLSServer starts a server that accepts ldaps:// connections. The server
+// is stopped when the test ends.
+func NewTLSServer(t TestingT, entries ...Entry) *Server {
+	t.Helper()
+	s := newServer(t, entries)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig)
+	assert.NilError(t, err)
+	s.start(t, listener, "ldaps://")
+	return s
+}
+
+func newServer(t TestingT, entries []Entry) *Server {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldap.example.com"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{"localhost"},
+
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	assert.NilError(t, err)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(cert)
+
+	return &Server{
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{raw}, PrivateKey: key, Leaf: cert}},
+			MinVersion:   tls.VersionTLS12,
+		},
+		rootCAs: rootCAs,
+		entries: entries,
+		conns:   map[net.Conn]struct{}{},
+	}
+}
+
+func (s *Server) start(t TestingT, listener net.Listener, scheme string) {
+	s.listener = listener
+	s.URL = scheme + listener.Addr().String()
+	t.Cleanup(s.Close)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = struct{}{}
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+}
+
+// Close stops the server and closes all open connections.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+// RootCAs returns a pool with the self-signed certificate of the server, for
+// clients to verify TLS connections.
+func (s *Server) RootCAs() *x509.CertPool {
+	return s.rootCAs
+}
+
+// Add adds an entry to the directory, or replaces the entry with the same DN.
+func (s *Server) Add(entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.entries {
+		if equalDN(s.entries[i].DN, entry.DN) {
+			s.entries[i] = entry
+			return
+		}
+	}
+	s.entries = append(s.entries, entry)
+}
+
+// Delete removes the entry with the DN from the directory.
+func (s *Server) Delete(dn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.entries {
+		if equalDN(s.entries[i].DN, dn) {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	// boundDN is empty for anonymous connections
+	var boundDN string
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			var code uint16
+			boundDN, code = s.bind(op)
+			responses = append(responses, result(id, goldap.ApplicationBindResponse, code))
+		case goldap.ApplicationSearchRequest:
+			if boundDN == "" {
+				responses = append(responses, result(id, goldap.ApplicationSearchResultDone, goldap.LDAPResultInsufficientAccessRights))
+				break
+			}
+			responses = append(responses, s.search(id, op)...)
+		case goldap.ApplicationExtendedRequest:
+			if len(op.Children) == 0 || op.Children[0].Data.String() != startTLSOID {
+				responses = append(responses, result(id, goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError))
+				break
+			}
+			if _, err := conn.Write(result(id, goldap.ApplicationExtendedResponse, goldap.LDAPResultSuccess).Bytes()); err != nil {
+				return
+			}
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			s.mu.Lock()
+			s.conns[tlsConn] = struct{}{}
+			s.mu.Unlock()
+			conn = tlsConn
+		case goldap.ApplicationUnbindRequest:
+			return
+		default:
+			responses = append(responses, result(id, op.Tag+1, goldap.LDAPResultUnwillingToPerform))
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(response.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind checks the credentials of a simple bind request, and returns the DN
+// of the bound entry.
+func (s *Server) bind(op *ber.Packet) (string, uint16) {
+	if len(op.Children) < 3 {
+		return "", goldap.LDAPResultProtocolError
+	}
+	dn, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+
+	// a bind with a name and without a password is an unauthenticated bind,
+	// which succeeds as an anonymous connection
+	if dn == "" || password == "" {
+		return "", goldap.LDAPResultSuccess
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		if equalDN(entry.DN, dn) {
+			for _, value := range attributeValues(entry, PasswordAttribute) {
+				if value == password {
+					return entry.DN, goldap.LDAPResultSuccess
+				}
+			}
+		}
+	}
+	return "", goldap.LDAPResultInvalidCredentials
+}
+
+func (s *Server) search(id int64, op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{result(id, goldap.ApplicationSearchResultDone, goldap.LDAPResultProtocolError)}
+	}
+	baseDN, _ := op.Children[0].Value.(string)
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	var attributes []string
+	for _, attr := range op.Children[7].Children {
+		if name, ok := attr.Value.(string); ok {
+			attributes = append(attributes, name)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var responses []*ber.Packet
+	for _, entry := range s.entries {
+		if !inScope(entry.DN, baseDN, scope) || !matches(entry, filter) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			return append(responses, result(id, goldap.ApplicationSearchResultDone, goldap.LDAPResultSizeLimitExceeded))
+		}
+		responses = append(responses, searchResultEntry(id, entry, attributes))
+	}
+	return append(responses, result(id, goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess))
+}
+
+func inScope(dn, baseDN string, scope int64) bool {
+	dn, baseDN = normalizeDN(dn), normalizeDN(baseDN)
+	switch scope {
+	case goldap.ScopeBaseObject:
+		return dn == baseDN
+	case goldap.ScopeSingleLevel:
+		_, parent, _ := strings.Cut(dn, ",")
+		return parent == baseDN
+	default:
+		return dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+func matches(entry Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(entry, child) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(entry, child) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return len(filter.Children) == 1 && !matches(entry, filter.Children[0])
+	case goldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+		if strings.EqualFold(name, "dn") {
+			return equalDN(entry.DN, value)
+		}
+		for _, v := range attributeValues(entry, name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterPresent:
+		return len(attributeValues(entry, filter.Data.String())) > 0
+	default:
+		return false
+	}
+}
+
+func attributeValues(entry Entry, name string) []string {
+	for attr, values := range entry.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func searchResultEntry(id int64, entry Entry, attributes []string) *ber.Packet {
+	var names []string
+	for name := range entry.Attributes {
+		if strings.EqualFold(name, PasswordAttribute) {
+			continue
+		}
+		if len(attributes) > 0 && !containsFold(attributes, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, name := range names {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range entry.Attributes[name] {
+			values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attr.AppendChild(values)
+		attrs.AppendChild(attr)
+	}
+
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+	op.AppendChild(attrs)
+	return message(id, op)
+}
+
+func result(id int64, tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, goldap.LDAPResultCodeMap[code], "Diagnostic Message"))
+	return message(id, op)
+}
+
+func message(id int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Message")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalDN(a, b string) bool {
+	return normalizeDN(a) == normalizeDN(b)
+}
+
+func normalizeDN(dn string) string {
+	return strings.ToLower(strings.ReplaceAll(dn, ", ", ","))
+}
@@ -34,5 +34,8 @@ func ModelsSymmetricKey(t TestingT) {
 	models.SymmetricKey = key
 	t.Cleanup(func() {
 		models.SymmetricKey = nil
+		models.SymmetricKeyID = 0
+		models.LoadDecryptionKey = nil
+		models.ResetDecryptionKeys()
 	})
 }
@@ -0,0 +1,239 @@
+/*
+Package saml provides a SAML identity provider for tests, which creates signed
+SAML responses.
+
+The responses are written in the form produced by exclusive XML
+canonicalization, so that they can be signed without an XML signature library.
+*/
+package saml
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+type TestingT interface {
+	assert.TestingT
+	Helper()
+}
+
+// IdP is a SAML identity provider.
+type IdP struct {
+	EntityID string
+	SSOURL   string
+
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+// NewIdP creates an identity provider with a new signing key.
+func NewIdP(t TestingT) *IdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+
+	return &IdP{
+		EntityID: "https://idp.example.com/saml",
+		SSOURL:   "https://idp.example.com/sso?tenant=1",
+		key:      key,
+		cert:     cert,
+	}
+}
+
+// Certificate returns the certificate used to verify the signatures of the
+// identity provider.
+func (idp *IdP) Certificate() *x509.Certificate {
+	return idp.cert
+}
+
+// Metadata returns the XML metadata of the identity provider.
+func (idp *IdP) Metadata() string {
+	return `<?xml version="1.0"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="` + idp.EntityID + `">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="encryption">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:X509Data><ds:X509Certificate>not-used</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+        <ds:X509Data>
+          <ds:X509Certificate>
+            ` + base64.StdEncoding.EncodeToString(idp.cert.Raw) + `
+          </ds:X509Certificate>
+        </ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://idp.example.com/sso/post"/>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="` + idp.SSOURL + `"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+}
+
+// Assertion describes the response created by IdP.Response. Values must not
+// contain characters that are escaped in XML.
+type Assertion struct {
+	// ID of the assertion, defaults to _assertion.
+	ID string
+	// Issuer defaults to the EntityID of the identity provider.
+	Issuer string
+	NameID string
+	// Audience is the entity ID of the service provider.
+	Audience string
+	// Recipient is the assertion consumer service URL of the service provider.
+	Recipient   string
+	Destination string
+	// InResponseTo is the ID of the AuthnRequest from the service provider.
+	InResponseTo string
+	// Status defaults to success.
+	Status string
+	// NotOnOrAfter defaults to five minutes from now.
+	NotOnOrAfter time.Time
+	// Attributes are added to the assertion, in order.
+	Attributes []Attribute
+
+	SignResponse  bool
+	SignAssertion bool
+}
+
+type Attribute struct {
+	Name         string
+	FriendlyName string
+	Values       []string
+}
+
+const (
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsXMLDSig   = "http://www.w3.org/2000/09/xmldsig#"
+)
+
+// Response returns the XML of a SAML response with the assertion, signed as
+// requested by the assertion. The response is not base64 encoded.
+func (idp *IdP) Response(t TestingT, a Assertion) string {
+	t.Helper()
+	if a.ID == "" {
+		a.ID = "_assertion"
+	}
+	if a.Issuer == "" {
+		a.Issuer = idp.EntityID
+	}
+	if a.Status == "" {
+		a.Status = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	}
+	if a.NotOnOrAfter.IsZero() {
+		a.NotOnOrAfter = time.Now().Add(5 * time.Minute)
+	}
+	now := time.Now().UTC()
+	notOnOrAfter := a.NotOnOrAfter.UTC().Format(time.RFC3339)
+	var inResponseTo string
+	if a.InResponseTo != "" {
+		inResponseTo = ` InResponseTo="` + a.InResponseTo + `"`
+	}
+
+	var attributes strings.Builder
+	for _, attr := range a.Attributes {
+		attributes.WriteString(`
+      <saml:Attribute`)
+		if attr.FriendlyName != "" {
+			attributes.WriteString(` FriendlyName="` + attr.FriendlyName + `"`)
+		}
+		attributes.WriteString(` Name="` + attr.Name + `">`)
+		for _, value := range attr.Values {
+			attributes.WriteString(`<saml:AttributeValue xsi:type="xs:string">` + value + `</saml:AttributeValue>`)
+		}
+		attributes.WriteString(`</saml:Attribute>`)
+	}
+
+	// the xs and xsi prefixes are included by the InclusiveNamespaces of the
+	// signature, so that the xs:string type can be resolved
+	assertion := `<saml:Assertion xmlns:saml="` + nsAssertion + `" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" ID="` + a.ID + `" IssueInstant="` + now.Format(time.RFC3339) + `" Version="2.0">
+    <saml:Issuer>` + a.Issuer + `</saml:Issuer>
+    <saml:Subject>
+      <saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">` + a.NameID + `</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData` + inResponseTo + ` NotOnOrAfter="` + notOnOrAfter + `" Recipient="` + a.Recipient + `"></saml:SubjectConfirmationData>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="` + now.Add(-time.Minute).Format(time.RFC3339) + `" NotOnOrAfter="` + notOnOrAfter + `">
+      <saml:AudienceRestriction><saml:Audience>` + a.Audience + `</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AttributeStatement>` + attributes.String() + `
+    </saml:AttributeStatement>
+  </saml:Assertion>`
+	if a.SignAssertion {
+		assertion = idp.sign(t, assertion, a.ID, "</saml:Issuer>")
+	}
+
+	var destination string
+	if a.Destination != "" {
+		destination = ` Destination="` + a.Destination + `"`
+	}
+	response := `<samlp:Response xmlns:samlp="` + nsProtocol + `"` + destination + ` ID="_response"` + inResponseTo + ` IssueInstant="` + now.Format(time.RFC3339) + `" Version="2.0">
+  <saml:Issuer xmlns:saml="` + nsAssertion + `">` + a.Issuer + `</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="` + a.Status + `"></samlp:StatusCode></samlp:Status>
+  ` + assertion + `
+</samlp:Response>`
+	if a.SignResponse {
+		response = idp.sign(t, response, "_response", "</saml:Issuer>")
+	}
+	return response
+}
+
+// sign adds an enveloped signature to the element, which must be in canonical
+// form. The signature is added after the first occurrence of after.
+func (idp *IdP) sign(t TestingT, element string, id string, after string) string {
+	t.Helper()
+	digest := sha256.Sum256([]byte(element))
+
+	signedInfo := `<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></ds:SignatureMethod>` +
+		`<ds:Reference URI="#` + id + `">` +
+		`<ds:Transforms>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"></ds:Transform>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#">` +
+		`<ec:InclusiveNamespaces xmlns:ec="http://www.w3.org/2001/10/xml-exc-c14n#" PrefixList="xs xsi"></ec:InclusiveNamespaces>` +
+		`</ds:Transform>` +
+		`</ds:Transforms>` +
+		`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></ds:DigestMethod>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue>` +
+		`</ds:Reference>` +
+		`</ds:SignedInfo>`
+
+	// the canonical form of SignedInfo declares the ds namespace, which is
+	// declared by the Signature element in the document
+	hashed := sha256.Sum256([]byte(`<ds:SignedInfo xmlns:ds="` + nsXMLDSig + `">` + signedInfo))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hashed[:])
+	assert.NilError(t, err)
+
+	sig := `<ds:Signature xmlns:ds="` + nsXMLDSig + `">` +
+		`<ds:SignedInfo>` + signedInfo +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(signature) + `</ds:SignatureValue>` +
+		`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(idp.cert.Raw) + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo>` +
+		`</ds:Signature>`
+
+	i := strings.Index(element, after)
+	assert.Assert(t, i >= 0, "missing %v", after)
+	i += len(after)
+	return element[:i] + sig + element[i:]
+}
@@ -0,0 +1,68 @@
+package validate
+
+import (
+	"net"
+)
+
+// nonPublicNetworks are the special purpose networks from RFC 6890 that are
+// not covered by the net.IP methods used in IsPublicIP.
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // this network
+	"100.64.0.0/10",   // shared address space (CGNAT)
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, and the limited broadcast address
+	"2001:db8::/32",   // documentation
+)
+
+// embeddedIPv4Networks are IPv6 networks where the last 4 bytes of the address
+// are an IPv4 address that may be reached by the connection.
+var embeddedIPv4Networks = mustParseCIDRs(
+	"::/96",          // IPv4-compatible addresses
+	"64:ff9b::/96",   // NAT64 well-known prefix
+	"64:ff9b:1::/48", // NAT64 local-use prefix
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	result := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, network)
+	}
+	return result
+}
+
+// IsPublicIP returns false if ip is a loopback, private, link-local,
+// multicast, or other special purpose address, including the IPv4-mapped and
+// NAT64 forms of those addresses.
+func IsPublicIP(ip net.IP) bool {
+	if ip.To4() == nil && len(ip) == net.IPv6len {
+		for _, network := range embeddedIPv4Networks {
+			if network.Contains(ip) && !ip.IsUnspecified() && !ip.IsLoopback() {
+				return IsPublicIP(net.IP(ip[12:16]))
+			}
+		}
+	}
+
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package validate
+
+import (
+	"net"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	notPublic := []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1",
+		"169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::",
+		"100.64.0.1", "100.127.255.254", "224.0.0.1", "255.255.255.255",
+		"::ffff:10.0.0.1", "::ffff:127.0.0.1", "::ffff:169.254.169.254",
+		"64:ff9b::a00:1", "64:ff9b::7f00:1", "64:ff9b:1::a9fe:a9fe", "::a00:1",
+	}
+	for _, addr := range notPublic {
+		assert.Check(t, !IsPublicIP(net.ParseIP(addr)), addr)
+	}
+
+	public := []string{
+		"8.8.8.8", "93.184.216.34", "2606:4700::1111", "100.128.0.1",
+		"::ffff:8.8.8.8", "64:ff9b::808:808",
+	}
+	for _, addr := range public {
+		assert.Check(t, IsPublicIP(net.ParseIP(addr)), addr)
+	}
+}